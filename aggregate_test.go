@@ -0,0 +1,155 @@
+package main
+
+// Test suite for the two-server private aggregate statistics
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateHistogram(t *testing.T) {
+	stat := &query.Statistic{Length: 10, OneHot: true}
+	servers := newAggregators(t, stat)
+	c := client.NewContributor(utils.RandomPRG(), stat)
+
+	expected := make([]uint32, stat.Length)
+	for _, bucket := range []int{0, 3, 3, 9, 5, 3} {
+		bits, err := client.HistogramContribution(bucket, stat.Length)
+		require.NoError(t, err)
+		contributions, err := c.ContributeBytes(bits)
+		require.NoError(t, err)
+		require.NoError(t, verifyContribution(servers, contributions))
+		expected[bucket]++
+	}
+
+	// a contribution setting two buckets is rejected
+	bits, err := client.HistogramContribution(1, stat.Length)
+	require.NoError(t, err)
+	contributions, err := c.Contribute(bits)
+	require.NoError(t, err)
+	contributions[0].Shares[2]++
+	require.ErrorIs(t, verifyContribution(servers, encodeContributions(t, contributions)), server.ErrInvalidContribution)
+	_, err = c.Contribute(make([]uint32, stat.Length))
+	require.Error(t, err)
+
+	shares, counts := publish(servers)
+	require.Equal(t, []int{6, 6}, counts)
+	aggregate, err := client.ReconstructAggregate(stat, shares)
+	require.NoError(t, err)
+	require.Equal(t, expected, aggregate)
+
+	// the next epoch starts from an empty aggregate
+	shares, counts = publish(servers)
+	require.Equal(t, []int{0, 0}, counts)
+	aggregate, err = client.ReconstructAggregate(stat, shares)
+	require.NoError(t, err)
+	require.Equal(t, make([]uint32, stat.Length), aggregate)
+}
+
+func TestAggregateSum(t *testing.T) {
+	stat := &query.Statistic{Length: 16}
+	servers := newAggregators(t, stat)
+	c := client.NewContributor(utils.RandomPRG(), stat)
+
+	sum := uint64(0)
+	for _, v := range []uint64{0, 1, 1000, 65535, 4242} {
+		bits, err := client.SumContribution(v, stat.Length)
+		require.NoError(t, err)
+		contributions, err := c.ContributeBytes(bits)
+		require.NoError(t, err)
+		require.NoError(t, verifyContribution(servers, contributions))
+		sum += v
+	}
+	_, err := client.SumContribution(1<<16, stat.Length)
+	require.Error(t, err)
+
+	// elements that are not bits and tampered proofs are rejected
+	bits, err := client.SumContribution(7, stat.Length)
+	require.NoError(t, err)
+	for _, tamper := range []func(c *query.Contribution){
+		func(c *query.Contribution) { c.Shares[0] += 2 },
+		func(c *query.Contribution) { c.H[5]++ },
+		func(c *query.Contribution) { c.F0++ },
+		func(c *query.Contribution) { c.Triple[2]++ },
+	} {
+		contributions, err := c.Contribute(bits)
+		require.NoError(t, err)
+		tamper(contributions[1])
+		require.ErrorIs(t, verifyContribution(servers, encodeContributions(t, contributions)), server.ErrInvalidContribution)
+	}
+
+	// malformed contributions are rejected before the verification
+	contributions, err := c.ContributeBytes(bits)
+	require.NoError(t, err)
+	_, _, err = servers[0].VerifyBytes(contributions[0][1:])
+	require.Error(t, err)
+
+	shares, counts := publish(servers)
+	require.Equal(t, []int{5, 5}, counts)
+	aggregate, err := client.ReconstructAggregate(stat, shares)
+	require.NoError(t, err)
+	require.Equal(t, sum, client.DecodeSum(aggregate))
+}
+
+func newAggregators(t *testing.T, stat *query.Statistic) []*server.Aggregator {
+	seed := utils.RandomPRGKey()
+	servers := make([]*server.Aggregator, 2)
+	for k := range servers {
+		var err error
+		servers[k], err = server.NewAggregator(stat, byte(k), seed)
+		require.NoError(t, err)
+	}
+	return servers
+}
+
+// verifyContribution runs the verification of the contribution between the
+// two servers, which add it to their aggregate if it succeeds
+func verifyContribution(servers []*server.Aggregator, contributions [][]byte) error {
+	checks := make([]*server.ContributionCheck, 2)
+	first := make([][]byte, 2)
+	for k, s := range servers {
+		var err error
+		checks[k], first[k], err = s.VerifyBytes(contributions[k])
+		if err != nil {
+			return err
+		}
+	}
+	second := make([][]byte, 2)
+	for k, c := range checks {
+		var err error
+		second[k], err = c.CheckBytes(first[1-k])
+		if err != nil {
+			return err
+		}
+	}
+	for k, c := range checks {
+		if err := c.Commit(second[1-k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeContributions(t *testing.T, contributions []*query.Contribution) [][]byte {
+	out := make([][]byte, len(contributions))
+	for k, c := range contributions {
+		var err error
+		out[k], err = c.Encode()
+		require.NoError(t, err)
+	}
+	return out
+}
+
+func publish(servers []*server.Aggregator) ([][]byte, []int) {
+	shares := make([][]byte, len(servers))
+	counts := make([]int, len(servers))
+	for k, s := range servers {
+		shares[k], counts[k] = s.Publish()
+	}
+	return shares, counts
+}
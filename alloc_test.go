@@ -0,0 +1,74 @@
+//go:build !race
+
+package main
+
+// The race detector makes the instrumented code allocate, so that the
+// allocations of the answers are only counted without it.
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIRAppendAnswerAllocs(t *testing.T) {
+	db := database.CreateRandomBytes(utils.RandomPRG(), oneKB*64, 16, testBlockLength)
+	c := client.NewPIR(utils.RandomPRG(), &db.Info)
+	s := server.NewPIR(db)
+
+	// the buffer is reused without allocating once large enough
+	queries, err := c.QueryBytes([]byte{0, 0, 0, 5}, 2)
+	require.NoError(t, err)
+	dst, err := s.AppendAnswer(nil, queries[0])
+	require.NoError(t, err)
+	require.Equal(t, 0.0, testing.AllocsPerRun(10, func() {
+		dst, err = s.AppendAnswer(dst[:0], queries[0])
+	}))
+	require.NoError(t, err)
+
+	r := server.NewPIRReplicated(db)
+	rc := client.NewPIRReplicated(utils.RandomPRG(), &db.Info)
+	queries, err = rc.QueryBytes([]byte{0, 0, 0, 5}, client.ReplicatedServers)
+	require.NoError(t, err)
+	dst, err = r.AppendAnswer(dst[:0], queries[0])
+	require.NoError(t, err)
+	require.Equal(t, 0.0, testing.AllocsPerRun(10, func() {
+		dst, err = r.AppendAnswer(dst[:0], queries[0])
+	}))
+	require.NoError(t, err)
+}
+
+func TestPredicateAPIRAppendAnswerAllocs(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s := server.NewPredicateAPIR(db, 0)
+
+	for _, b := range []*query.Builder{
+		query.NewBuilder().TargetUserID().Domain("example.org"),
+		query.NewBuilder().TargetUserID().Domain("example.org").Aggregate(query.Count, query.SumBitLength),
+		query.NewBuilder().TargetKeySize().Below(4096),
+	} {
+		encoded, err := b.Encode()
+		require.NoError(t, err)
+		queries, err := c.QueryBytes(encoded, 2)
+		require.NoError(t, err)
+		dst, err := s.AppendAnswer(nil, queries[0])
+		require.NoError(t, err)
+
+		// at steady state, only the decoding of the query allocates
+		decode := testing.AllocsPerRun(10, func() {
+			_, err = query.DecodeFSS(queries[0])
+		})
+		require.NoError(t, err)
+		require.Equal(t, decode, testing.AllocsPerRun(10, func() {
+			dst, err = s.AppendAnswer(dst[:0], queries[0])
+		}))
+		require.NoError(t, err)
+	}
+}
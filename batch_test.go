@@ -0,0 +1,78 @@
+package main
+
+// Test suite for the retrieval of batches of blocks from a db encoded with a
+// cuckoo batch code
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchPIR(t *testing.T) {
+	dbLen := oneKB * 64
+	blockLen := testBlockLength
+	numBlocks, err := utils.NumBlocks(dbLen, blockLen)
+	require.NoError(t, err)
+	batchSize := 16
+
+	for _, rebalanced := range []bool{false, true} {
+		numRows, _ := database.CalculateNumRowsAndColumns(numBlocks, rebalanced)
+		db := database.CreateRandomBytes(utils.RandomPRG(), dbLen, numRows, blockLen)
+
+		servers := make([]server.Server, 2)
+		for k := range servers {
+			s, err := server.NewBatch(db, batchSize, func(b *database.Bytes) server.Server {
+				return server.NewPIR(b)
+			})
+			require.NoError(t, err)
+			servers[k] = s
+		}
+		c, err := client.NewBatch(utils.RandomPRG(), &db.Info, batchSize, func(info *database.Info) client.Client {
+			return client.NewPIR(utils.RandomPRG(), info)
+		})
+		require.NoError(t, err)
+
+		for _, size := range []int{1, batchSize / 2, batchSize} {
+			indices := make([]int, 0, size)
+			seen := make(map[int]bool)
+			for len(indices) < size {
+				i := int(utils.MathRand().Int63n(int64(numBlocks)))
+				if !seen[i] {
+					seen[i] = true
+					indices = append(indices, i)
+				}
+			}
+			blocks, err := retrieveBatch(c, servers, indices)
+			require.NoError(t, err)
+			require.Len(t, blocks, size)
+			for k, i := range indices {
+				require.Equal(t, db.Entries[i*blockLen:(i+1)*blockLen], blocks[k])
+			}
+		}
+
+		// too large batches and repeated blocks are rejected
+		_, err = c.QueryBytes(make([]int, batchSize+1), 2)
+		require.Error(t, err)
+		_, err = c.QueryBytes([]int{3, 3}, 2)
+		require.Error(t, err)
+	}
+}
+
+func retrieveBatch(c *client.Batch, servers []server.Server, indices []int) ([][]byte, error) {
+	queries, err := c.QueryBytes(indices, len(servers))
+	if err != nil {
+		return nil, err
+	}
+	answers := make([][]byte, len(servers))
+	for k, s := range servers {
+		if answers[k], err = s.AnswerBytes(queries[k]); err != nil {
+			return nil, err
+		}
+	}
+	return c.ReconstructBytes(answers)
+}
@@ -1 +1,2 @@
-go test -bench=. > bench_time.txt
\ No newline at end of file
+go test -bench=. > bench_time.txt
+go run ./cmd/apir-bench -out bench
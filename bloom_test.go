@@ -0,0 +1,65 @@
+package main
+
+// Test suite for the private membership test in a Bloom filter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomFilter(t *testing.T) {
+	keywords := make([]string, 500)
+	for i := range keywords {
+		keywords[i] = fmt.Sprintf("user%d@example.org", i)
+	}
+	filter, err := database.NewBloom(keywords, 0.01)
+	require.NoError(t, err)
+	servers := []*server.Bloom{server.NewBloom(filter), server.NewBloom(filter)}
+	c := client.NewBloom(&filter.Info)
+
+	for _, k := range keywords[:50] {
+		found, err := retrieveBloom(c, servers, k)
+		require.NoError(t, err)
+		require.True(t, found)
+	}
+
+	// the missing keywords are rejected but for the false positives
+	falsePositives := 0
+	for i := 0; i < 200; i++ {
+		k := fmt.Sprintf("nobody%d@example.org", i)
+		found, err := retrieveBloom(c, servers, k)
+		require.NoError(t, err)
+		require.Equal(t, filter.Contains(k), found)
+		if found {
+			falsePositives++
+		}
+	}
+	require.Less(t, falsePositives, 10)
+
+	// the test needs two servers
+	_, err = c.QueryBytes([]byte(keywords[0]), 3)
+	require.Error(t, err)
+}
+
+func retrieveBloom(c *client.Bloom, servers []*server.Bloom, keyword string) (bool, error) {
+	queries, err := c.QueryBytes([]byte(keyword), len(servers))
+	if err != nil {
+		return false, err
+	}
+	answers := make([][]byte, len(servers))
+	for k, s := range servers {
+		if answers[k], err = s.AnswerBytes(queries[k]); err != nil {
+			return false, err
+		}
+	}
+	found, err := c.ReconstructBytes(answers)
+	if err != nil {
+		return false, err
+	}
+	return found.(bool), nil
+}
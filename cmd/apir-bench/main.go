@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/si-co/vpir-code/lib/bench"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+func main() {
+	schemes := flag.String("schemes", strings.Join(bench.SchemeNames(), ","), "comma-separated schemes to benchmark")
+	dbLens := flag.String("dblens", "8192,8388608", "comma-separated db lengths in bits")
+	blockLens := flag.String("blocklens", "16", "comma-separated block lengths in bytes, for the schemes with blocks")
+	servers := flag.String("servers", "2", "comma-separated numbers of servers, for the schemes with any number of them")
+	repetitions := flag.Int("n", 10, "number of retrievals per benchmark")
+	rebalanced := flag.Bool("rebalanced", true, "matrix instead of vector representation of the db")
+	tECC := flag.Int("tecc", 0, "repetitions of the integrity amplification, the tuned values if zero")
+	layoutName := flag.String("layout", "row", "memory layout of the dbs of the PIR schemes in GF(2): row, column or tiled")
+	out := flag.String("out", "bench", "prefix of the report files")
+	format := flag.String("format", "json,csv", "comma-separated report formats: json, csv")
+	baseline := flag.String("baseline", "", "JSON report to compare the run with, failing on regressions")
+	threshold := flag.Float64("threshold", 0.1, "relative growth of the query and answer CPU time and bandwidth tolerated by -baseline")
+	flag.Parse()
+
+	lens, err := parseInts(*dbLens)
+	if err != nil {
+		log.Fatalf("invalid db lengths: %v", err)
+	}
+	blocks, err := parseInts(*blockLens)
+	if err != nil {
+		log.Fatalf("invalid block lengths: %v", err)
+	}
+	numServers, err := parseInts(*servers)
+	if err != nil {
+		log.Fatalf("invalid numbers of servers: %v", err)
+	}
+	layout, err := database.ParseLayout(*layoutName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *repetitions <= 0 {
+		log.Fatal("the number of retrievals must be positive")
+	}
+	var base *bench.Report
+	if *baseline != "" {
+		if base, err = readReport(*baseline); err != nil {
+			log.Fatalf("reading the baseline: %v", err)
+		}
+	}
+
+	report := &bench.Report{Metadata: bench.NewMetadata()}
+	for _, name := range strings.Split(*schemes, ",") {
+		s, err := bench.Lookup(strings.TrimSpace(name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		// the parameters that the scheme ignores are not swept
+		sBlocks, sServers := blocks, numServers
+		if !s.Blocks {
+			sBlocks = blocks[:1]
+		}
+		if s.Servers > 0 {
+			sServers = []int{s.Servers}
+		}
+		for _, dbLen := range lens {
+			for _, blockLen := range sBlocks {
+				for _, n := range sServers {
+					p := bench.Params{DBLen: dbLen, BlockLen: blockLen, NumServers: n, Rebalanced: *rebalanced, TECC: *tECC, Layout: layout}
+					log.Printf("%s: db length %d, block length %d, %d servers", s.Name, dbLen, blockLen, n)
+					res, err := bench.Run(s, utils.RandomPRG(), p, *repetitions)
+					if err != nil {
+						log.Fatalf("%s: %v", s.Name, err)
+					}
+					log.Printf("answer CPU %.3gs, wall %.3gs, %.0f bytes",
+						res.CPU.Answer.Mean, res.Wall.Answer.Mean, res.Bandwidth.Query.Mean+res.Bandwidth.Answer.Mean)
+					report.Results = append(report.Results, res)
+					runtime.GC()
+				}
+			}
+		}
+	}
+
+	for _, f := range strings.Split(*format, ",") {
+		if err := write(report, *out, strings.TrimSpace(f)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if base != nil {
+		regressions, compared := report.Compare(base, *threshold)
+		if compared == 0 {
+			log.Fatalf("no result of %s has the benchmarked schemes and parameters", *baseline)
+		}
+		log.Printf("%d results compared with %s", compared, *baseline)
+		for _, r := range regressions {
+			log.Printf("regression: %s", r)
+		}
+		if len(regressions) > 0 {
+			log.Fatalf("%d metrics regressed by more than %g%%", len(regressions), 100**threshold)
+		}
+	}
+}
+
+func readReport(fileName string) (*bench.Report, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return bench.ReadReport(f)
+}
+
+// write writes the report in the given format to prefix.format
+func write(r *bench.Report, prefix, format string) error {
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	fileName := prefix + "." + format
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		err = r.WriteJSON(f)
+	} else {
+		err = r.WriteCSV(f)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %v", fileName, err)
+	}
+	log.Printf("report written to %s", fileName)
+	return f.Close()
+}
+
+func parseInts(s string) ([]int, error) {
+	parts := strings.Split(s, ",")
+	out := make([]int, len(parts))
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		if v <= 0 {
+			return nil, fmt.Errorf("%d is not positive", v)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
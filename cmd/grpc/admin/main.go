@@ -0,0 +1,199 @@
+// Command admin runs the admin calls of the operators on running servers:
+// reloading the db, e.g., after an import, querying the epoch and the digest
+// of the db served, running the integrity self-checks of the db and dumping
+// the occupancy of its hash table. The calls carry the admin token of the
+// servers, which serve the admin service when run with -admin.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/proto"
+	"github.com/si-co/vpir-code/lib/utils"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+)
+
+const (
+	configEnvKey = "VPIR_CONFIG"
+
+	defaultConfigFile = "config.toml"
+)
+
+// tokenCredentials attaches the admin token to every call
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{utils.AdminTokenKey: string(t)}, nil
+}
+
+// RequireTransportSecurity requires TLS, so that the token is never sent in
+// the clear
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+func main() {
+	cmd := flag.String("cmd", "status", "admin command: status, reload, check or occupancy")
+	sid := flag.Int("id", -1, "ID of the server, all the servers if -1")
+	tokenFile := flag.String("token", "admin.token", "file of the admin token of the servers")
+	files := flag.Int("files", 0, "number of key files of the reloaded db, the current one if 0")
+	timeout := flag.Duration("timeout", time.Hour, "timeout of the calls, which includes loading the db for a reload")
+	configFlags := utils.RegisterConfigFlags(flag.CommandLine)
+	flag.Parse()
+
+	log.SetOutput(os.Stderr)
+	log.SetPrefix("[Admin] ")
+
+	configPath := os.Getenv(configEnvKey)
+	if configPath == "" {
+		configPath = defaultConfigFile
+	}
+	config, err := utils.LoadLayeredConfig(configPath, configFlags)
+	if err != nil {
+		log.Fatalf("could not load the config: %v", err)
+	}
+	token, err := utils.ReadAdminToken(*tokenFile)
+	if err != nil {
+		log.Fatalf("could not read the admin token: %v", err)
+	}
+
+	addresses := config.Addresses
+	if *sid != -1 {
+		if *sid < 0 || *sid >= len(addresses) {
+			log.Fatalf("no server %d in the config", *sid)
+		}
+		addresses = addresses[*sid : *sid+1]
+	}
+
+	ok := true
+	digests := make(map[string]bool)
+	for _, addr := range addresses {
+		out, digest, err := run(*cmd, addr, tokenCredentials(token), *files, *timeout)
+		if err != nil {
+			log.Printf("%s: %v", addr, err)
+			ok = false
+			continue
+		}
+		fmt.Printf("%s:\n%s", addr, out)
+		if digest != "" {
+			digests[digest] = true
+		}
+	}
+	if len(digests) > 1 {
+		log.Printf("the servers serve %d different dbs: the clients cannot combine their answers", len(digests))
+		ok = false
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// run runs the command on the server at the address and returns its output,
+// along with the digest of the db served for the status and reload commands
+func run(cmd, addr string, token tokenCredentials, files int, timeout time.Duration) (string, string, error) {
+	conn, err := connectToServer(addr, token)
+	if err != nil {
+		return "", "", err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := proto.NewAdminClient(conn)
+
+	switch cmd {
+	case "status":
+		s, err := c.Status(ctx, &proto.StatusRequest{})
+		if err != nil {
+			return "", "", err
+		}
+		return formatStatus(s), fmt.Sprintf("%x", s.GetDigest()), nil
+	case "reload":
+		s, err := c.Reload(ctx, &proto.ReloadRequest{Files: uint32(files)})
+		if err != nil {
+			return "", "", err
+		}
+		return formatStatus(s), fmt.Sprintf("%x", s.GetDigest()), nil
+	case "check":
+		r, err := c.SelfCheck(ctx, &proto.SelfCheckRequest{})
+		if err != nil {
+			return "", "", err
+		}
+		if len(r.GetFailures()) > 0 {
+			return "", "", fmt.Errorf("%d failed checks:\n  %s", len(r.GetFailures()),
+				strings.Join(r.GetFailures(), "\n  "))
+		}
+		return "  all checks passed\n", "", nil
+	case "occupancy":
+		o, err := c.Occupancy(ctx, &proto.OccupancyRequest{})
+		if err != nil {
+			return "", "", err
+		}
+		return formatOccupancy(o), "", nil
+	default:
+		return "", "", fmt.Errorf("unknown admin command: %s", cmd)
+	}
+}
+
+func formatStatus(s *proto.StatusResponse) string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "  scheme: %s\n", s.GetScheme())
+	fmt.Fprintf(b, "  epoch: %d\n", s.GetEpoch())
+	fmt.Fprintf(b, "  digest: %x\n", s.GetDigest())
+	fmt.Fprintf(b, "  key files: %d\n", s.GetFiles())
+	fmt.Fprintf(b, "  loaded at: %s\n", time.Unix(s.GetLoadedAt(), 0).Format(time.RFC3339))
+	if s.GetTreeSize() > 0 {
+		fmt.Fprintf(b, "  transparency log: size %d, root %x\n", s.GetTreeSize(), s.GetTreeRoot())
+	}
+	return b.String()
+}
+
+func formatOccupancy(o *proto.OccupancyResponse) string {
+	b := new(strings.Builder)
+	if o.GetBuckets() > 0 {
+		fmt.Fprintf(b, "  buckets: %d, empty: %d (%.1f%%)\n", o.GetBuckets(), o.GetEmptyBuckets(),
+			100*float64(o.GetEmptyBuckets())/float64(o.GetBuckets()))
+		fmt.Fprintf(b, "  blocks: %d, overflow: %d\n", o.GetBlocks(), o.GetOverflowBlocks())
+		fmt.Fprintf(b, "  entries: %d, mean %.2f and max %d per bucket\n", o.GetEntries(),
+			float64(o.GetEntries())/float64(o.GetBuckets()), o.GetMaxEntries())
+		if size := uint64(o.GetBlocks()) * uint64(o.GetBlockSize()); size > 0 {
+			fmt.Fprintf(b, "  fill: %d of %d bytes (%.1f%%)\n", o.GetUsedBytes(), size,
+				100*float64(o.GetUsedBytes())/float64(size))
+		}
+		fmt.Fprintf(b, "  entries per bucket:\n")
+		for n, c := range o.GetHistogram() {
+			if c > 0 {
+				fmt.Fprintf(b, "    %4d: %d\n", n, c)
+			}
+		}
+	}
+	if o.GetKeys() > 0 {
+		fmt.Fprintf(b, "  keys of the metadata db: %d\n", o.GetKeys())
+	}
+	return b.String()
+}
+
+func connectToServer(address string, token tokenCredentials) (*grpc.ClientConn, error) {
+	creds, err := utils.LoadServersCertificates()
+	if err != nil {
+		return nil, xerrors.Errorf("could not load servers certificates: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(token), grpc.WithBlock())
+	if err != nil {
+		return nil, xerrors.Errorf("did not connect to %s: %v", address, err)
+	}
+
+	return conn, nil
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/si-co/vpir-code/cmd/grpc/client/manager"
 	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/logging"
 	"github.com/si-co/vpir-code/lib/query"
 	"github.com/si-co/vpir-code/lib/utils"
 	"golang.org/x/xerrors"
@@ -139,7 +141,7 @@ func loadPointManager() (manager.Manager, error) {
 		}
 	}
 
-	manager := manager.NewManager(*config, grpcOpts)
+	manager := manager.NewManager(*config, grpcOpts, logging.Default())
 
 	return manager, nil
 }
@@ -163,7 +165,11 @@ func loadComplexManager() (manager.Manager, error) {
 		}
 	}
 
-	manager := manager.NewManager(*config, grpcOpts)
+	if err := fss.SetPrfKeysFromConfig(config); err != nil {
+		return manager.Manager{}, xerrors.Errorf("failed to set FSS keys: %v", err)
+	}
+
+	manager := manager.NewManager(*config, grpcOpts, logging.Default())
 
 	return manager, nil
 }
@@ -454,7 +460,7 @@ func executeStatsQuery(clientQuery *query.ClientFSS, actor manager.Actor) (uint3
 		return 0, xerrors.Errorf("failed to query bytes: %v", err)
 	}
 
-	answers := actor.RunQueries(queries)
+	answers := actor.RunPredicateQueries(queries)
 
 	result, err := client.ReconstructBytes(answers)
 	if err != nil {
@@ -1,23 +1,31 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/si-co/vpir-code/lib/client"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/discovery"
 	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/logging"
 	"github.com/si-co/vpir-code/lib/pgp"
 	"github.com/si-co/vpir-code/lib/proto"
 	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/transparency"
 	"github.com/si-co/vpir-code/lib/utils"
 	"golang.org/x/xerrors"
 	"google.golang.org/grpc"
@@ -36,11 +44,16 @@ type localClient struct {
 	callOptions []grpc.CallOption
 	connections map[string]*grpc.ClientConn
 
-	prg        *utils.PRGReader
+	prg        utils.PRG
+	log        *logging.Logger
 	config     *utils.Config
 	flags      *flags
 	dbInfo     *database.Info
 	vpirClient client.Client
+
+	// public keys of the issuers of the anonymous tokens of the servers,
+	// by address, empty for the servers not requiring tokens
+	tokenKeys map[string][]byte
 }
 
 type flags struct {
@@ -59,6 +72,34 @@ type flags struct {
 	fromEnd   int
 	and       bool
 	avg       bool
+	domain    string
+	sql       string
+
+	// private contact discovery
+	contacts  string
+	batchSize int
+
+	// file of the transparency log head trusted by the client
+	translog string
+	// hex-encoded digest of the db held by the client
+	digest string
+
+	// anonymous tokens: prefix of the files of the tokens of the servers,
+	// number of tokens to get from each server and file of the issuance
+	// token authorizing it
+	tokens   string
+	issue    int
+	issuance string
+
+	// GnuPG integration
+	importKey bool
+	gpg       string
+	gpgHome   string
+
+	// overrides of the config
+	config *utils.ConfigFlags
+	// level and format of the logs
+	log *logging.Flags
 }
 
 func newLocalClient() *localClient {
@@ -80,9 +121,16 @@ func newLocalClient() *localClient {
 		defer utils.StopProfiling()
 	}
 
-	// set logs to stdout
+	// set logs to stdout, the logger of the client being the default one
+	// of the libraries too
 	log.SetOutput(os.Stdout)
 	log.SetPrefix(fmt.Sprintf("[Client] "))
+	logger, err := lc.flags.log.Logger(os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lc.log = logger
+	logging.SetDefault(logger)
 
 	// load configs
 	configPath := os.Getenv(configEnvKey)
@@ -90,9 +138,12 @@ func newLocalClient() *localClient {
 		configPath = defaultConfigFile
 	}
 
-	config, err := utils.LoadConfig(configPath)
+	config, err := utils.LoadLayeredConfig(configPath, lc.flags.config)
 	if err != nil {
-		log.Fatalf("could not load the config file: %v", err)
+		lc.log.Fatal("could not load the config", logging.F("err", err))
+	}
+	if err := fss.SetPrfKeysFromConfig(config); err != nil {
+		lc.log.Fatal("could not set the FSS keys", logging.F("err", err))
 	}
 	lc.config = config
 
@@ -106,12 +157,12 @@ func main() {
 	defer lc.closeConnections()
 
 	if err != nil {
-		log.Fatal(err)
+		lc.log.Fatal("could not connect to the servers", logging.F("err", err))
 	}
 
 	_, err = lc.exec()
 	if err != nil {
-		log.Fatal(err)
+		lc.log.Fatal("query failed", logging.F("err", err))
 	}
 
 	os.Exit(0)
@@ -142,7 +193,7 @@ func (lc *localClient) closeConnections() {
 	for _, conn := range lc.connections {
 		err := conn.Close()
 		if err != nil {
-			log.Printf("failed to close conn: %v", err)
+			lc.log.Warn("failed to close conn", logging.F("err", err))
 		}
 	}
 }
@@ -154,10 +205,23 @@ func (lc *localClient) exec() (string, error) {
 	// information in the client.
 	lc.retrieveDBInfo()
 
+	if lc.flags.issue > 0 {
+		return lc.issueTokens()
+	}
+
 	// start correct client, which can be either IT or DPF.
 	switch lc.flags.scheme {
-	case "pointPIR", "pointVPIR":
-		lc.vpirClient = client.NewPIR(lc.prg, lc.dbInfo)
+	case "pointPIR", "pointVPIR", "pointPIRDPF", "pointPIRReplicated":
+		if lc.flags.scheme == "pointPIRDPF" {
+			lc.vpirClient = client.NewPIRDPF(lc.prg, lc.dbInfo)
+		} else if lc.flags.scheme == "pointPIRReplicated" {
+			lc.vpirClient = client.NewPIRReplicated(lc.prg, lc.dbInfo)
+		} else {
+			lc.vpirClient = client.NewPIR(lc.prg, lc.dbInfo)
+		}
+		if lc.flags.contacts != "" {
+			return lc.fetchContacts()
+		}
 
 		// get id
 		if lc.flags.id == "" {
@@ -165,7 +229,7 @@ func (lc *localClient) exec() (string, error) {
 			fmt.Print("please enter the id: ")
 			fmt.Scanln(&id)
 			if id == "" {
-				log.Fatal("id not provided")
+				lc.log.Fatal("id not provided")
 			}
 			lc.flags.id = id
 		}
@@ -174,6 +238,9 @@ func (lc *localClient) exec() (string, error) {
 		return lc.retrieveKeyGivenId(lc.flags.id)
 	case "complexPIR":
 		lc.vpirClient = client.NewPredicatePIR(lc.prg, lc.dbInfo)
+		if lc.flags.contacts != "" {
+			return lc.discoverContacts()
+		}
 		out, err := lc.retrieveComplexQuery()
 		if err != nil {
 			return "", err
@@ -181,6 +248,9 @@ func (lc *localClient) exec() (string, error) {
 		return strconv.FormatUint(uint64(out), 10), nil
 	case "complexVPIR":
 		lc.vpirClient = client.NewPredicateAPIR(lc.prg, lc.dbInfo)
+		if lc.flags.contacts != "" {
+			return lc.discoverContacts()
+		}
 		out, err := lc.retrieveComplexQuery()
 		if err != nil {
 			return "", err
@@ -195,7 +265,14 @@ func (lc *localClient) retrieveComplexQuery() (uint32, error) {
 	t := time.Now()
 
 	var clientQuery *query.ClientFSS
-	if !lc.flags.and && !lc.flags.avg {
+	if lc.flags.sql != "" {
+		var err error
+		if clientQuery, err = query.Parse(lc.flags.sql); err != nil {
+			return 0, err
+		}
+	} else if lc.flags.domain != "" {
+		clientQuery = query.DomainClientFSS(lc.flags.domain)
+	} else if !lc.flags.and && !lc.flags.avg {
 		switch lc.flags.target {
 		case "email":
 			info := &query.Info{
@@ -246,7 +323,7 @@ func (lc *localClient) retrieveComplexQuery() (uint32, error) {
 	if err != nil {
 		return 0, xerrors.Errorf("error when executing query: %v", err)
 	}
-	log.Printf("done with queries computation")
+	lc.log.Debug("done with queries computation")
 
 	// send queries to servers
 	answers := lc.runQueries(queries)
@@ -256,7 +333,7 @@ func (lc *localClient) retrieveComplexQuery() (uint32, error) {
 	if err != nil {
 		return 0, xerrors.Errorf("error during reconstruction: %v", err)
 	}
-	log.Printf("done with block reconstruction")
+	lc.log.Debug("done with block reconstruction")
 
 	fmt.Println(result)
 
@@ -271,6 +348,10 @@ func (lc *localClient) retrieveComplexQuery() (uint32, error) {
 	}
 	fmt.Printf("Wall-clock time to retrieve complex output: %v\n", elapsedTime)
 
+	// the first aggregate of a query with several of them
+	if aggregates, ok := result.([]uint32); ok {
+		return aggregates[0], nil
+	}
 	return result.(uint32), nil
 
 }
@@ -278,18 +359,114 @@ func (lc *localClient) retrieveComplexQuery() (uint32, error) {
 func (lc *localClient) retrieveKeyGivenId(id string) (string, error) {
 	t := time.Now()
 
-	// compute hash key for id
-	hashKey := database.HashToIndex(id, lc.dbInfo.NumRows*lc.dbInfo.NumColumns)
-	log.Printf("id: %s, hashKey: %d", id, hashKey)
+	// get all the keys from the bucket with the id of the search, e.g., the
+	// old and new keys of an email, the most recent first
+	index, id := pgp.ParseSearch(id)
+	retrievedKeys, bw, err := lc.retrieveKeys(index, id)
+	if err != nil {
+		return "", err
+	}
+	if len(retrievedKeys) == 0 {
+		return "", xerrors.Errorf("no key with the given %s id is found", index)
+	}
+	lc.log.Debug("PGP keys retrieved from block", logging.F("keys", len(retrievedKeys)))
+
+	// verify the keys and return the most recent valid one, or the most
+	// recent one if none is valid
+	var armored string
+	trusted := false
+	now := time.Now()
+	for i, key := range retrievedKeys {
+		a, err := pgp.ArmorKey(key.Entity)
+		if err != nil {
+			return "", xerrors.Errorf("error armor-encoding the key: %v", err)
+		}
+		verdict, reason := pgp.VerifyKey(key.Entity, index, id, now)
+		if i == 0 || (verdict == pgp.Valid && !trusted) {
+			armored = a
+			trusted = verdict == pgp.Valid
+		}
+		fmt.Printf("Key %X created on %s:\n%s\n", key.Entity.PrimaryKey.Fingerprint,
+			key.CreationTime.Format(time.RFC3339), a)
+		fmt.Printf("Verdict: %s (%s)\n\n", verdict, reason)
+	}
+	if !trusted {
+		fmt.Printf("WARNING: no valid key found for %s %s, do not use the retrieved keys\n", index, id)
+	}
 
+	// import the key into the GnuPG keyring, only if it can be trusted
+	if lc.flags.importKey {
+		if !trusted {
+			return "", xerrors.Errorf("refusing to import the key into the GnuPG keyring: no valid key")
+		}
+		out, err := pgp.ImportIntoKeyring(armored, lc.flags.gpg, lc.flags.gpgHome)
+		if err != nil {
+			return "", xerrors.Errorf("error importing the key: %v", err)
+		}
+		fmt.Print(out)
+	}
+
+	elapsedTime := time.Since(t)
+	if lc.flags.experiment {
+		log.Printf("stats,%d,%d,%f", lc.flags.cores, bw, elapsedTime.Seconds())
+	}
+	fmt.Printf("Wall-clock time to retrieve the key: %v\n", elapsedTime)
+
+	return armored, nil
+}
+
+// retrieveKeys retrieves the bucket of the id in the given index and returns
+// the keys matching the id, the most recent first, along with the byte length
+// of the queries. No key is returned if the id is not in the db.
+func (lc *localClient) retrieveKeys(index pgp.Index, id string) ([]pgp.RecoveredKey, int, error) {
+	// compute hash key for id in the index it belongs to
+	hashKey := int(database.HashToIndex(pgp.HashID(index, id), lc.dbInfo.NumBuckets()))
+	lc.log.Debug("key looked up", logging.F("index", index), logging.F("id", id), logging.F("hashKey", hashKey))
+
+	// retrieve the blocks of the bucket, following the chain of overflow
+	// blocks of the buckets too large for one block
+	bw := 0
+	result := make([]byte, 0)
+	for i := 0; ; i++ {
+		if i == lc.dbInfo.NumRows*lc.dbInfo.NumColumns {
+			return nil, 0, xerrors.Errorf("cycle in the chain of blocks of %s %s", index, id)
+		}
+		block, queryBytes, err := lc.retrieveBlock(hashKey)
+		if err != nil {
+			return nil, 0, err
+		}
+		bw += queryBytes
+		next, chunk, err := database.SplitBlock(block)
+		if err != nil {
+			return nil, 0, xerrors.Errorf("error parsing block %d: %v", hashKey, err)
+		}
+		result = append(result, chunk...)
+		if next == 0 {
+			break
+		}
+		lc.log.Debug("bucket continues", logging.F("block", next))
+		hashKey = next
+	}
+
+	keys, err := pgp.RecoverKeysFromBlock(result, index, id)
+	if err != nil {
+		return nil, 0, xerrors.Errorf("error retrieving key from the block: %v", err)
+	}
+
+	return keys, bw, nil
+}
+
+// retrieveBlock retrieves and unpads the block at the given index, and
+// returns it along with the byte length of the queries
+func (lc *localClient) retrieveBlock(hashKey int) ([]byte, int, error) {
 	// query given hash key
 	in := make([]byte, 4)
 	binary.BigEndian.PutUint32(in, uint32(hashKey))
 	queries, err := lc.vpirClient.QueryBytes(in, len(lc.connections))
 	if err != nil {
-		return "", xerrors.Errorf("error when executing query: %v", err)
+		return nil, 0, xerrors.Errorf("error when executing query: %v", err)
 	}
-	log.Printf("done with queries computation")
+	lc.log.Debug("done with queries computation")
 
 	// send queries to servers
 	answers := lc.runQueries(queries)
@@ -297,9 +474,9 @@ func (lc *localClient) retrieveKeyGivenId(id string) (string, error) {
 	// reconstruct block
 	resultField, err := lc.vpirClient.ReconstructBytes(answers)
 	if err != nil {
-		return "", xerrors.Errorf("error during reconstruction: %v", err)
+		return nil, 0, xerrors.Errorf("error during reconstruction: %v", err)
 	}
-	log.Printf("done with block reconstruction")
+	lc.log.Debug("done with block reconstruction")
 
 	var result []byte
 	if lc.flags.scheme == "it" || lc.flags.scheme == "dpf" {
@@ -308,126 +485,267 @@ func (lc *localClient) retrieveKeyGivenId(id string) (string, error) {
 	} else {
 		result = resultField.([]byte)
 	}
+
+	// query bw
+	bw := 0
+	for _, q := range queries {
+		bw += len(q)
+	}
+
 	// unpad result in both cases
-	result = database.UnPadBlock(result)
+	return database.UnPadBlock(result), bw, nil
+}
 
-	// get a key from the block with the id of the search
-	retrievedKey, err := pgp.RecoverKeyFromBlock(result, id)
+// discoverContacts prints the contacts of the contacts file with a key in the
+// db, without revealing the contacts to the servers
+func (lc *localClient) discoverContacts() (string, error) {
+	t := time.Now()
+
+	contacts, err := readContacts(lc.flags.contacts)
 	if err != nil {
-		return "", xerrors.Errorf("error retrieving key from the block: %v", err)
+		return "", err
 	}
-	log.Printf("PGP key retrieved from block")
-
-	armored, err := pgp.ArmorKey(retrievedKey)
+	found, err := discovery.Discover(lc.vpirClient, lc.runQueries, contacts, lc.flags.batchSize, lc.prg)
 	if err != nil {
-		return "", xerrors.Errorf("error armor-encoding the key: %v", err)
+		return "", err
 	}
 
-	fmt.Println(armored)
+	fmt.Printf("%d of %d contacts have a key:\n", len(found), len(contacts))
+	for _, c := range found {
+		fmt.Println(c)
+	}
+	fmt.Printf("Wall-clock time to discover the contacts: %v\n", time.Since(t))
 
-	elapsedTime := time.Since(t)
-	if lc.flags.experiment {
-		// query bw
-		bw := 0
-		for _, q := range queries {
-			bw += len(q)
+	return strings.Join(found, "\n"), nil
+}
+
+// fetchContacts prints the keys of the contacts of the contacts file, along
+// with their verdicts, without revealing the contacts to the servers
+func (lc *localClient) fetchContacts() (string, error) {
+	t := time.Now()
+
+	contacts, err := readContacts(lc.flags.contacts)
+	if err != nil {
+		return "", err
+	}
+	retrieve := func(email string) ([]pgp.RecoveredKey, error) {
+		keys, _, err := lc.retrieveKeys(pgp.IndexEmail, email)
+		return keys, err
+	}
+	keys, err := discovery.Fetch(retrieve, contacts, lc.flags.batchSize, lc.prg)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("%d of %d contacts have a key:\n", len(keys), len(contacts))
+	found := make([]string, 0, len(keys))
+	now := time.Now()
+	for _, c := range contacts {
+		c = pgp.NormalizeEmail(c)
+		if _, ok := keys[c]; !ok {
+			continue
+		}
+		found = append(found, c)
+		for _, key := range keys[c] {
+			verdict, reason := pgp.VerifyKey(key.Entity, pgp.IndexEmail, c, now)
+			fmt.Printf("%s: key %s created on %s, %s (%s)\n", c, pgp.Fingerprint(key.Entity),
+				key.CreationTime.Format(time.RFC3339), verdict, reason)
 		}
-		log.Printf("stats,%d,%d,%f", lc.flags.cores, bw, elapsedTime.Seconds())
 	}
-	fmt.Printf("Wall-clock time to retrieve the key: %v\n", elapsedTime)
+	fmt.Printf("Wall-clock time to fetch the contacts: %v\n", time.Since(t))
 
-	return armored, nil
+	return strings.Join(found, "\n"), nil
+}
+
+// readContacts returns the emails of a contacts file, one per line, skipping
+// empty lines and lines starting with #
+func readContacts(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("could not read the contacts file: %v", err)
+	}
+	contacts := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		contacts = append(contacts, line)
+	}
+	return contacts, nil
 }
 
 func (lc *localClient) retrieveDBInfo() {
 	subCtx, cancel := context.WithTimeout(lc.ctx, time.Hour)
 	defer cancel()
 
+	// size of the transparency log trusted so far
+	var verifier *transparency.Verifier
+	knownSize := uint64(0)
+	if lc.flags.translog != "" {
+		var err error
+		verifier, err = transparency.NewVerifier(lc.flags.translog)
+		if err != nil {
+			lc.log.Fatal("could not load the trusted transparency log head", logging.F("err", err))
+		}
+		knownSize = verifier.KnownSize()
+	}
+
+	type result struct {
+		addr         string
+		info         *database.Info
+		transparency []byte
+		tokenKey     []byte
+	}
+
 	wg := sync.WaitGroup{}
-	resCh := make(chan *database.Info, len(lc.connections))
-	for _, conn := range lc.connections {
+	resCh := make(chan result, len(lc.connections))
+	for addr, conn := range lc.connections {
 		wg.Add(1)
-		go func(conn *grpc.ClientConn) {
-			resCh <- dbInfo(subCtx, conn, lc.callOptions)
+		go func(addr string, conn *grpc.ClientConn) {
+			info, tr, key := dbInfo(subCtx, conn, lc.callOptions, knownSize)
+			resCh <- result{addr: addr, info: info, transparency: tr, tokenKey: key}
 			wg.Done()
-		}(conn)
+		}(addr, conn)
 	}
 	wg.Wait()
 	close(resCh)
 
 	dbInfo := make([]*database.Info, 0)
-	for i := range resCh {
-		dbInfo = append(dbInfo, i)
+	transparencies := make(map[string][]byte)
+	lc.tokenKeys = make(map[string][]byte)
+	for r := range resCh {
+		dbInfo = append(dbInfo, r.info)
+		transparencies[r.addr] = r.transparency
+		lc.tokenKeys[r.addr] = r.tokenKey
 	}
 
 	// check if db info are all equal before returning
 	if !equalDBInfo(dbInfo) {
-		log.Fatal("got different database info from servers")
+		lc.log.Fatal("got different database info from servers")
 	}
 
-	log.Printf("databaseInfo: %#v", dbInfo[0])
+	lc.log.Debug("databaseInfo", logging.F("info", fmt.Sprintf("%#v", dbInfo[0])))
 
 	lc.dbInfo = dbInfo[0]
+
+	// the servers cannot forge the db, even all together
+	if lc.flags.digest != "" {
+		held, err := hex.DecodeString(lc.flags.digest)
+		if err != nil {
+			lc.log.Fatal("malformed digest", logging.F("err", err))
+		}
+		if err := client.HeldDigest(held).Pin(lc.dbInfo); err != nil {
+			lc.log.Fatal("the db of the servers does not match the digest", logging.F("err", err))
+		}
+	}
+
+	if verifier != nil {
+		if err := lc.verifyTransparency(verifier, transparencies); err != nil {
+			lc.log.Fatal("transparency log verification failed", logging.F("err", err))
+		}
+	}
+}
+
+// verifyTransparency checks that all the servers serve the same transparency
+// log, extending the one trusted so far, and that the db served is the last
+// epoch of the log
+func (lc *localClient) verifyTransparency(v *transparency.Verifier, transparencies map[string][]byte) error {
+	responses := make([]*transparency.Response, 0, len(transparencies))
+	keys := make([]crypto.PublicKey, 0, len(transparencies))
+	for i, addr := range lc.config.Addresses {
+		tr, ok := transparencies[addr]
+		if !ok {
+			continue
+		}
+		if len(tr) == 0 {
+			return xerrors.Errorf("server %s does not serve a transparency log", addr)
+		}
+		r, err := transparency.DecodeResponse(tr)
+		if err != nil {
+			return xerrors.Errorf("server %s: %v", addr, err)
+		}
+		key, err := utils.ServerPublicKey(i)
+		if err != nil {
+			return err
+		}
+		responses = append(responses, r)
+		keys = append(keys, key)
+	}
+
+	epoch, err := v.Update(responses, keys)
+	if err != nil {
+		return err
+	}
+	// the digest of the authenticated dbs is the root known to the client
+	if lc.dbInfo.Merkle != nil && len(lc.dbInfo.Root) > 0 && !bytes.Equal(epoch.Digest, lc.dbInfo.Root) {
+		return xerrors.Errorf("the root of the db is not the one of epoch %d", epoch.Number)
+	}
+	lc.log.Info("db verified in the transparency log", logging.F("epoch", epoch.Number), logging.F("size", v.KnownSize()))
+
+	return nil
 }
 
-func dbInfo(ctx context.Context, conn *grpc.ClientConn, opts []grpc.CallOption) *database.Info {
+func dbInfo(ctx context.Context, conn *grpc.ClientConn, opts []grpc.CallOption, knownTreeSize uint64) (*database.Info, []byte, []byte) {
 	c := proto.NewVPIRClient(conn)
-	q := &proto.DatabaseInfoRequest{}
+	q := &proto.DatabaseInfoRequest{KnownTreeSize: knownTreeSize}
 	answer, err := c.DatabaseInfo(ctx, q, opts...)
 	if err != nil {
-		log.Fatalf("could not send database info request to %s: %v",
-			conn.Target(), err)
+		logging.Default().Fatal("could not send database info request", logging.F("addr", conn.Target()), logging.F("err", err))
 	}
-	log.Printf("sent databaseInfo request to %s", conn.Target())
+	logging.Default().Debug("sent databaseInfo request", logging.F("addr", conn.Target()))
 
 	dbInfo := &database.Info{
-		NumRows:    int(answer.GetNumRows()),
-		NumColumns: int(answer.GetNumColumns()),
-		BlockSize:  int(answer.GetBlockLength()),
-		PIRType:    answer.GetPirType(),
-		Merkle:     &database.Merkle{Root: answer.GetRoot(), ProofLen: int(answer.GetProofLen())},
+		NumRows:      int(answer.GetNumRows()),
+		NumColumns:   int(answer.GetNumColumns()),
+		BlockSize:    int(answer.GetBlockLength()),
+		PIRType:      answer.GetPirType(),
+		HashTableLen: int(answer.GetHashTableLen()),
+		Merkle:       &database.Merkle{Root: answer.GetRoot(), ProofLen: int(answer.GetProofLen())},
 	}
 
-	return dbInfo
+	return dbInfo, answer.GetTransparency(), answer.GetTokenKey()
 }
 
 func (lc *localClient) runQueries(queries [][]byte) [][]byte {
 	subCtx, cancel := context.WithTimeout(lc.ctx, time.Hour)
 	defer cancel()
 
+	// the queries of the complex schemes are predicate queries, also
+	// answered by the servers of the point schemes run with -predicate
+	predicate := lc.flags.scheme == "complexPIR" || lc.flags.scheme == "complexVPIR"
+
+	// the answers are in the order of the queries, which matters for the
+	// replicated scheme
 	wg := sync.WaitGroup{}
-	resCh := make(chan []byte, len(lc.connections))
+	answers := make([][]byte, len(queries))
 	j := 0
-	for _, conn := range lc.connections {
+	for addr, conn := range lc.connections {
+		// a query redeems an anonymous token of the server requiring them
+		ctx, err := lc.withToken(subCtx, addr)
+		if err != nil {
+			lc.log.Fatal("could not query", logging.F("addr", addr), logging.F("err", err))
+		}
 		wg.Add(1)
-		go func(j int, conn *grpc.ClientConn) {
-			resCh <- queryServer(subCtx, conn, lc.callOptions, queries[j])
+		go func(ctx context.Context, j int, conn *grpc.ClientConn) {
+			answers[j] = queryServer(ctx, conn, lc.callOptions, queries[j], predicate)
 			wg.Done()
-		}(j, conn)
+		}(ctx, j, conn)
 		j++
 	}
 	wg.Wait()
-	close(resCh)
-
-	// combinate answers of all the servers
-	q := make([][]byte, 0)
-	for v := range resCh {
-		q = append(q, v)
-	}
 
-	return q
+	return answers
 }
 
-func queryServer(ctx context.Context, conn *grpc.ClientConn, opts []grpc.CallOption, query []byte) []byte {
+func queryServer(ctx context.Context, conn *grpc.ClientConn, opts []grpc.CallOption, query []byte, predicate bool) []byte {
 	c := proto.NewVPIRClient(conn)
-	q := &proto.QueryRequest{Query: query}
+	q := &proto.QueryRequest{Query: query, Predicate: predicate}
 	answer, err := c.Query(ctx, q, opts...)
 	if err != nil {
-		log.Fatalf("could not query %s: %v",
-			conn.Target(), err)
+		logging.Default().Fatal("could not query", logging.F("addr", conn.Target()), logging.F("err", err))
 	}
-	log.Printf("sent query to %s", conn.Target())
-	log.Printf("query size in bytes %d", len(query))
+	logging.Default().Debug("sent query", logging.F("addr", conn.Target()), logging.F("bytes", len(query)))
 
 	return answer.GetAnswer()
 }
@@ -471,12 +789,36 @@ func parseFlags() *flags {
 
 	// scheme flags
 	flag.StringVar(&f.scheme, "scheme", "", "scheme to use: it, dpf or pit-it, pir-dpf")
-	flag.StringVar(&f.id, "id", "", "id of key to retrieve")
+	flag.StringVar(&f.id, "id", "", "id of key to retrieve: email, or 0x-prefixed fingerprint or key ID")
 	flag.StringVar(&f.target, "target", "", "target for complex query")
 	flag.IntVar(&f.fromStart, "from-start", 0, "from start parameter for complex query")
 	flag.IntVar(&f.fromEnd, "from-end", 0, "from end parameter for complex query")
 	flag.BoolVar(&f.and, "and", false, "and clause for complex query")
 	flag.BoolVar(&f.avg, "avg", false, "avg clause for complex query")
+	flag.StringVar(&f.domain, "domain", "", "count the keys of the domain with a complex query, e.g., example.org")
+	flag.StringVar(&f.sql, "sql", "", "complex query in the SQL-like syntax, e.g., \"SELECT COUNT(*) WHERE email ENDS WITH 'epfl.ch'\"")
+
+	// contact discovery flags
+	flag.StringVar(&f.contacts, "contacts", "", "file of contact emails, one per line, to discover with a complex scheme or fetch with a point scheme")
+	flag.IntVar(&f.batchSize, "batch", discovery.DefaultBatchSize, "number of contacts per batch, padded with dummy contacts")
+
+	// transparency flags
+	flag.StringVar(&f.translog, "translog", "", "if set, verify the transparency log of the servers against the head trusted in this file, updated after each run")
+	flag.StringVar(&f.digest, "digest", "", "if set, hex-encoded digest of the authenticated db, verified against the info of the servers so that they cannot forge the db even if all of them are malicious")
+
+	// anonymous token flags
+	flag.StringVar(&f.tokens, "tokens", "tokens", "prefix of the files of the anonymous tokens redeemed by the queries, one file per server id")
+	flag.IntVar(&f.issue, "issue", 0, "if positive, get this number of anonymous tokens from each server requiring them and exit")
+	flag.StringVar(&f.issuance, "issuance", "issuance.token", "file of the issuance token authorizing -issue")
+
+	// GnuPG flags
+	flag.BoolVar(&f.importKey, "import", false, "import the retrieved key into the GnuPG keyring if it is valid")
+	flag.StringVar(&f.gpg, "gpg", pgp.DefaultGPG, "GnuPG executable used by -import")
+	flag.StringVar(&f.gpgHome, "gpg-homedir", "", "GnuPG home directory used by -import, the default one if empty")
+
+	// config flags, overriding the config file and the environment
+	f.config = utils.RegisterConfigFlags(flag.CommandLine)
+	f.log = logging.RegisterFlags(flag.CommandLine)
 
 	flag.Parse()
 
@@ -2,26 +2,46 @@ package manager
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/binary"
 	"fmt"
-	"log"
+	mrand "math/rand"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/nikirill/go-crypto/openpgp"
 	"github.com/si-co/vpir-code/lib/client"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/idempotency"
+	"github.com/si-co/vpir-code/lib/logging"
 	"github.com/si-co/vpir-code/lib/pgp"
 	"github.com/si-co/vpir-code/lib/proto"
+	"github.com/si-co/vpir-code/lib/query"
 	"github.com/si-co/vpir-code/lib/utils"
 	"golang.org/x/xerrors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// NewManager returns a new initialized manager
-func NewManager(config utils.Config, opts []grpc.CallOption) Manager {
+const (
+	// maxQueryAttempts bounds the attempts of a query to a server
+	// unavailable
+	maxQueryAttempts = 3
+	// retryBackoff is the wait before the first retry of a query, which
+	// grows linearly with the attempts
+	retryBackoff = time.Second
+)
+
+// NewManager returns a new initialized manager, logging to the logger
+func NewManager(config utils.Config, opts []grpc.CallOption, logger *logging.Logger) Manager {
 	return Manager{
 		config: config,
 		opts:   opts,
+		log:    logger,
 	}
 }
 
@@ -29,116 +49,204 @@ func NewManager(config utils.Config, opts []grpc.CallOption) Manager {
 type Manager struct {
 	config utils.Config
 	opts   []grpc.CallOption
+	log    *logging.Logger
 }
 
-// Connect connects to the server and returns an Actor that can query the
-// servers.
+// Connect connects to the servers and returns an Actor that can query the
+// servers. A replica that cannot be reached is skipped, as long as its group
+// has another primary server.
 func (m *Manager) Connect() (Actor, error) {
-	servers := make([]server, len(m.config.Addresses))
+	groups := make([]group, 0, len(m.config.Groups()))
+	for g, indices := range m.config.Groups() {
+		grp := group{log: m.log.With(logging.F("group", g))}
+		for _, i := range indices {
+			srv, err := m.connect(m.config.Server(i))
+			if err != nil {
+				if len(indices) == 1 {
+					return Actor{}, err
+				}
+				grp.log.Warn("replica skipped", logging.F("err", err))
+				continue
+			}
+			grp.replicas = append(grp.replicas, srv)
+		}
+		if !grp.hasPrimary() {
+			return Actor{}, xerrors.Errorf("no primary server of group %d reachable", g)
+		}
+		groups = append(groups, grp)
+	}
+
+	return Actor{
+		groups: groups,
+		opts:   m.opts,
+		log:    m.log,
+	}, nil
+}
 
-	// load servers certificates
-	creds, err := utils.LoadServersCertificates()
+// connect connects to the server of the config entry, authenticated with
+// its own TLS material
+func (m *Manager) connect(s utils.Server) (server, error) {
+	addr := net.JoinHostPort(s.IP, strconv.Itoa(s.Port))
+	creds, err := utils.ServerCredentials(s)
 	if err != nil {
-		return Actor{}, xerrors.Errorf("failed to load servers certificates: %v", err)
+		return server{}, xerrors.Errorf("failed to load certificates of %s: %v", addr, err)
 	}
 
-	for i, addr := range m.config.Addresses {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-
-		conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds),
-			grpc.WithBlock())
-		if err != nil {
-			return Actor{}, xerrors.Errorf("failed to connect to %s: %v", addr, err)
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
 
-		servers[i] = server{conn: conn, opts: m.opts, addr: addr}
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds),
+		grpc.WithBlock())
+	if err != nil {
+		return server{}, xerrors.Errorf("failed to connect to %s: %v", addr, err)
 	}
 
-	return Actor{
-		servers: servers,
+	weight := s.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	return server{
+		conn:    conn,
 		opts:    m.opts,
+		addr:    addr,
+		standby: s.Role == utils.RoleStandby,
+		weight:  weight,
+		log:     m.log.With(logging.F("addr", addr)),
 	}, nil
 }
 
 // Actor allows to perform operations on the servers.
 type Actor struct {
-	servers []server
-	opts    []grpc.CallOption
+	groups []group
+	opts   []grpc.CallOption
+	log    *logging.Logger
 }
 
-// GetKey performs a simple query that return a key from an email
+// GetKey performs a simple query that return a key from an email, or from a
+// 0x-prefixed fingerprint or 64-bit key ID
 func (a *Actor) GetKey(id string, dbInfo database.Info, client *client.PIR) (string, error) {
 	t := time.Now()
 
-	// compute hash key for id
-	hashKey := database.HashToIndex(id, dbInfo.NumRows*dbInfo.NumColumns)
-	log.Printf("id: %s, hashKey: %d", id, hashKey)
-
-	// query given hash key
-	in := make([]byte, 4)
-	binary.BigEndian.PutUint32(in, uint32(hashKey))
+	index, id := pgp.ParseSearch(id)
+	retrievedKey, err := a.GetEntityByIndex(index, id, dbInfo, client)
+	if err != nil {
+		return "", err
+	}
 
-	queries, err := client.QueryBytes(in, len(a.servers))
+	armored, err := pgp.ArmorKey(retrievedKey)
 	if err != nil {
-		return "", xerrors.Errorf("error when executing query: %v", err)
+		return "", xerrors.Errorf("error armor-encoding the key: %v", err)
 	}
 
-	log.Printf("done with queries computation")
+	// fmt.Println(armored)
 
-	// send queries to servers
-	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
-	defer cancel()
+	elapsedTime := time.Since(t)
 
-	wg := sync.WaitGroup{}
-	resCh := make(chan []byte, len(a.servers))
+	fmt.Printf("Wall-clock time to retrieve the key: %v\n", elapsedTime)
 
-	for i, srv := range a.servers {
-		wg.Add(1)
-		go func(srv server, query []byte) {
-			resCh <- srv.query(ctx, query)
-			wg.Done()
-		}(srv, queries[i])
-	}
+	return armored, nil
+}
 
-	wg.Wait()
-	close(resCh)
+// GetEntity performs a simple query that returns the PGP entity of an email
+func (a *Actor) GetEntity(id string, dbInfo database.Info, client *client.PIR) (*openpgp.Entity, error) {
+	return a.GetEntityByIndex(pgp.IndexEmail, id, dbInfo, client)
+}
 
-	// combinate answers of all the servers
-	answers := make([][]byte, 0)
-	for v := range resCh {
-		answers = append(answers, v)
+// GetEntityByIndex performs a simple query that returns the most recent PGP
+// entity looked up with the id in the given index
+func (a *Actor) GetEntityByIndex(index pgp.Index, id string, dbInfo database.Info, client *client.PIR) (*openpgp.Entity, error) {
+	result, err := a.retrieveBlock(index, id, dbInfo, client)
+	if err != nil {
+		return nil, err
 	}
 
-	// reconstruct block
-	resultField, err := client.ReconstructBytes(answers)
+	// get a key from the block with the id of the search
+	retrievedKey, err := pgp.RecoverKeyFromBlockByIndex(result, index, id)
 	if err != nil {
-		return "", xerrors.Errorf("error during reconstruction: %v", err)
+		return nil, xerrors.Errorf("error retrieving key from the block: %v", err)
 	}
-	log.Printf("done with block reconstruction")
+	a.log.Debug("PGP key retrieved from block")
 
-	result := resultField.([]byte)
-	result = database.UnPadBlock(result)
+	return retrievedKey, nil
+}
 
-	// get a key from the block with the id of the search
-	retrievedKey, err := pgp.RecoverKeyFromBlock(result, id)
+// GetEntitiesByIndex performs a simple query that returns all the PGP
+// entities looked up with the id in the given index, the most recent first,
+// so that the caller can choose among the old and new keys of an identity
+func (a *Actor) GetEntitiesByIndex(index pgp.Index, id string, dbInfo database.Info, client *client.PIR) ([]pgp.RecoveredKey, error) {
+	result, err := a.retrieveBlock(index, id, dbInfo, client)
 	if err != nil {
-		return "", xerrors.Errorf("error retrieving key from the block: %v", err)
+		return nil, err
 	}
-	log.Printf("PGP key retrieved from block")
 
-	armored, err := pgp.ArmorKey(retrievedKey)
+	keys, err := pgp.RecoverKeysFromBlock(result, index, id)
 	if err != nil {
-		return "", xerrors.Errorf("error armor-encoding the key: %v", err)
+		return nil, xerrors.Errorf("error retrieving keys from the block: %v", err)
 	}
+	if len(keys) == 0 {
+		return nil, xerrors.Errorf("no key with the given %s id is found", index)
+	}
+	a.log.Debug("PGP keys retrieved from block", logging.F("keys", len(keys)))
 
-	// fmt.Println(armored)
+	return keys, nil
+}
 
-	elapsedTime := time.Since(t)
+// retrieveBlock retrieves the data of the bucket of the hash table in which
+// the id is stored in the given index, following the chain of overflow blocks
+// of the buckets too large for one block. The number of queries thus depends
+// on the length of the bucket.
+func (a *Actor) retrieveBlock(index pgp.Index, id string, dbInfo database.Info, client *client.PIR) ([]byte, error) {
+	// compute hash key for id
+	hashKey := int(database.HashToIndex(pgp.HashID(index, id), dbInfo.NumBuckets()))
+	a.log.Debug("key looked up", logging.F("index", index), logging.F("id", id), logging.F("hashKey", hashKey))
 
-	fmt.Printf("Wall-clock time to retrieve the key: %v\n", elapsedTime)
+	data := make([]byte, 0)
+	for i := 0; i < dbInfo.NumRows*dbInfo.NumColumns; i++ {
+		block, err := a.queryBlock(hashKey, client)
+		if err != nil {
+			return nil, err
+		}
+		next, chunk, err := database.SplitBlock(block)
+		if err != nil {
+			return nil, xerrors.Errorf("error parsing block %d: %v", hashKey, err)
+		}
+		data = append(data, chunk...)
+		if next == 0 {
+			return data, nil
+		}
+		a.log.Debug("bucket continues", logging.F("block", next))
+		hashKey = next
+	}
 
-	return armored, nil
+	return nil, xerrors.Errorf("cycle in the chain of blocks of %s %s", index, id)
+}
+
+// queryBlock retrieves and unpads the block at the given index
+func (a *Actor) queryBlock(hashKey int, client *client.PIR) ([]byte, error) {
+	// query given hash key
+	in := make([]byte, 4)
+	binary.BigEndian.PutUint32(in, uint32(hashKey))
+
+	queries, err := client.QueryBytes(in, len(a.groups))
+	if err != nil {
+		return nil, xerrors.Errorf("error when executing query: %v", err)
+	}
+
+	a.log.Debug("done with queries computation")
+
+	// send queries to servers, whose vectors are reused once answered
+	answers := a.runQueries(queries, false)
+	client.Release()
+
+	// reconstruct block
+	resultField, err := client.ReconstructBytes(answers)
+	if err != nil {
+		return nil, xerrors.Errorf("error during reconstruction: %v", err)
+	}
+	a.log.Debug("done with block reconstruction")
+
+	result := resultField.([]byte)
+	return database.UnPadBlock(result), nil
 }
 
 // GetDBInfos returns infos about the servers dbs.
@@ -147,9 +255,11 @@ func (a *Actor) GetDBInfos() ([]database.Info, error) {
 	defer cancel()
 
 	wg := sync.WaitGroup{}
-	resCh := make(chan database.Info, len(a.servers))
+	servers := a.allServers()
+	resCh := make(chan database.Info, len(servers))
 
-	for _, srv := range a.servers {
+	// the replicas of a group must load the same db too
+	for _, srv := range servers {
 		wg.Add(1)
 		go func(srv server) {
 			defer wg.Done()
@@ -178,7 +288,7 @@ func (a *Actor) GetDBInfos() ([]database.Info, error) {
 		}
 	}
 
-	log.Printf("databaseInfo: %#v", dbInfo[0])
+	a.log.Debug("databaseInfo", logging.F("info", fmt.Sprintf("%#v", dbInfo[0])))
 
 	return dbInfo, nil
 }
@@ -186,30 +296,181 @@ func (a *Actor) GetDBInfos() ([]database.Info, error) {
 // RunQueries dispatch queries in parallel to all servers. It then combines the
 // answers.
 func (a *Actor) RunQueries(queries [][]byte) [][]byte {
+	return a.runQueries(queries, false)
+}
+
+// RunPredicateQueries is the same as RunQueries for the FSS predicate
+// queries, answered over the key metadata by the servers of both the complex
+// schemes and the point schemes run with -predicate
+func (a *Actor) RunPredicateQueries(queries [][]byte) [][]byte {
+	return a.runQueries(queries, true)
+}
+
+// CountDomainKeys privately counts the keys whose email is in the given
+// domain, e.g., example.org
+func (a *Actor) CountDomainKeys(domain string, client *client.PredicateAPIR) (uint32, error) {
+	in, err := query.DomainClientFSS(domain).Encode()
+	if err != nil {
+		return 0, xerrors.Errorf("failed to encode query: %v", err)
+	}
+
+	queries, err := client.QueryBytes(in, len(a.groups))
+	if err != nil {
+		return 0, xerrors.Errorf("error when executing query: %v", err)
+	}
+
+	result, err := client.ReconstructBytes(a.RunPredicateQueries(queries))
+	if err != nil {
+		return 0, xerrors.Errorf("error during reconstruction: %v", err)
+	}
+
+	count, ok := result.(uint32)
+	if !ok {
+		return 0, xerrors.Errorf("failed to cast result, wrong type %T", result)
+	}
+
+	return count, nil
+}
+
+// RunReplicatedQueries dispatches the queries of the replicated scheme in
+// parallel to the three servers. A server that fails to answer does not stop
+// the retrieval: its answer is nil, so that the client reconstructs the
+// block from the two others. An error is returned if more servers fail.
+func (a *Actor) RunReplicatedQueries(queries [][]byte) ([][]byte, error) {
+	if len(a.groups) != client.ReplicatedServers {
+		return nil, xerrors.Errorf("the replicated scheme needs %d servers, got %d",
+			client.ReplicatedServers, len(a.groups))
+	}
+	answers, errs := a.fanOut(queries, false)
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			a.groups[i].log.Warn("server did not answer", logging.F("err", err))
+			failed++
+		}
+	}
+	if failed > 1 {
+		return nil, xerrors.Errorf("%d servers out of %d did not answer", failed, len(a.groups))
+	}
+	return answers, nil
+}
+
+// runQueries dispatches the queries to all the servers and returns their
+// answers, in the order of the servers
+func (a *Actor) runQueries(queries [][]byte, predicate bool) [][]byte {
+	answers, errs := a.fanOut(queries, predicate)
+	for i, err := range errs {
+		if err != nil {
+			a.groups[i].log.Fatal("could not query", logging.F("err", err))
+		}
+	}
+	return answers
+}
+
+// fanOut sends the i-th query to the i-th group, in parallel, and returns
+// the answers and the errors of the groups in the same order. Every query
+// carries a fresh idempotency key, distinct per group so that the servers
+// cannot link the queries by their keys.
+func (a *Actor) fanOut(queries [][]byte, predicate bool) ([][]byte, []error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
 	defer cancel()
 
+	answers := make([][]byte, len(a.groups))
+	errs := make([]error, len(a.groups))
 	wg := sync.WaitGroup{}
-	resCh := make(chan []byte, len(a.servers))
-
-	for i, srv := range a.servers {
+	for i, grp := range a.groups {
+		key, err := idempotency.NewKey(rand.Reader)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
 		wg.Add(1)
-		go func(srv server, query []byte) {
-			resCh <- srv.query(ctx, query)
-			wg.Done()
-		}(srv, queries[i])
+		go func(i int, grp group) {
+			defer wg.Done()
+			answers[i], errs[i] = grp.query(ctx, queries[i], key, predicate)
+		}(i, grp)
 	}
-
 	wg.Wait()
-	close(resCh)
 
-	// combinate answers of all the servers
-	q := make([][]byte, 0)
-	for v := range resCh {
-		q = append(q, v)
+	return answers, errs
+}
+
+// allServers returns the servers of all the groups
+func (a *Actor) allServers() []server {
+	var servers []server
+	for _, grp := range a.groups {
+		servers = append(servers, grp.replicas...)
 	}
+	return servers
+}
+
+// group is a replica group, whose servers answer the same share of the
+// queries
+type group struct {
+	replicas []server
+	log      *logging.Logger
+}
 
-	return q
+func (g group) hasPrimary() bool {
+	for _, srv := range g.replicas {
+		if !srv.standby {
+			return true
+		}
+	}
+	return false
+}
+
+// query sends the query to a primary server of the group, drawn in
+// proportion to the weights, and then to the other servers, the standby
+// ones last, until one of them answers. The idempotency key is the same,
+// since the replicas answer the same share of the query.
+func (g group) query(ctx context.Context, query, key []byte, predicate bool) ([]byte, error) {
+	var err error
+	for _, srv := range g.order() {
+		var answer []byte
+		answer, err = srv.query(ctx, query, key, predicate)
+		if err == nil || ctx.Err() != nil {
+			return answer, err
+		}
+		if len(g.replicas) > 1 {
+			srv.log.Warn("replica failed", logging.F("err", err))
+		}
+	}
+	return nil, err
+}
+
+// order returns the servers of the group in the order in which they are
+// queried: the primary ones, drawn without replacement in proportion to
+// their weight, then the standby ones, drawn likewise
+func (g group) order() []server {
+	var primary, standby []server
+	for _, srv := range g.replicas {
+		if srv.standby {
+			standby = append(standby, srv)
+		} else {
+			primary = append(primary, srv)
+		}
+	}
+	return append(weightedOrder(primary), weightedOrder(standby)...)
+}
+
+// weightedOrder shuffles the servers, a server coming first with a
+// probability proportional to its weight
+func weightedOrder(servers []server) []server {
+	total := 0
+	for _, srv := range servers {
+		total += srv.weight
+	}
+	for i := range servers {
+		r := mrand.Intn(total)
+		j := i
+		for ; r >= servers[j].weight; j++ {
+			r -= servers[j].weight
+		}
+		servers[i], servers[j] = servers[j], servers[i]
+		total -= servers[i].weight
+	}
+	return servers
 }
 
 // server represents a remote server
@@ -217,23 +478,36 @@ type server struct {
 	addr string
 	conn *grpc.ClientConn
 	opts []grpc.CallOption
+	log  *logging.Logger
+	// role and weight of the server in its group
+	standby bool
+	weight  int
 }
 
-// query performs a query on the server
-func (s server) query(ctx context.Context, query []byte) []byte {
+// query performs a query on the server, retried with the same idempotency
+// key if the server is unavailable, so that the server answers it once
+func (s server) query(ctx context.Context, query, key []byte, predicate bool) ([]byte, error) {
 	c := proto.NewVPIRClient(s.conn)
-	q := &proto.QueryRequest{Query: query}
-
-	answer, err := c.Query(ctx, q, s.opts...)
-	if err != nil {
-		log.Fatalf("could not query %s: %v",
-			s.conn.Target(), err)
+	q := &proto.QueryRequest{Query: query, Predicate: predicate}
+	id := idempotency.QueryID(query)
+	ctx = metadata.AppendToOutgoingContext(ctx, idempotency.MetadataKey, string(key))
+
+	for attempt := 1; ; attempt++ {
+		answer, err := c.Query(ctx, q, s.opts...)
+		if err == nil {
+			s.log.Debug("sent query", logging.F("query", id), logging.F("bytes", len(query)))
+			return answer.GetAnswer(), nil
+		}
+		if status.Code(err) != codes.Unavailable || attempt == maxQueryAttempts {
+			return nil, err
+		}
+		s.log.Warn("retrying query", logging.F("query", id), logging.F("attempt", attempt), logging.F("err", err))
+		select {
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
-
-	log.Printf("sent query to %s", s.conn.Target())
-	log.Printf("query size in bytes %d", len(query))
-
-	return answer.GetAnswer()
 }
 
 // getDBInfo returns DB info about the server
@@ -243,18 +517,18 @@ func (s server) getDBInfo(ctx context.Context) database.Info {
 
 	answer, err := c.DatabaseInfo(ctx, q, s.opts...)
 	if err != nil {
-		log.Fatalf("could not send database info request to %s: %v",
-			s.conn.Target(), err)
+		s.log.Fatal("could not send database info request", logging.F("err", err))
 	}
 
-	log.Printf("sent databaseInfo request to %s", s.conn.Target())
+	s.log.Debug("sent databaseInfo request")
 
 	dbInfo := database.Info{
-		NumRows:    int(answer.GetNumRows()),
-		NumColumns: int(answer.GetNumColumns()),
-		BlockSize:  int(answer.GetBlockLength()),
-		PIRType:    answer.GetPirType(),
-		Merkle:     &database.Merkle{Root: answer.GetRoot(), ProofLen: int(answer.GetProofLen())},
+		NumRows:      int(answer.GetNumRows()),
+		NumColumns:   int(answer.GetNumColumns()),
+		BlockSize:    int(answer.GetBlockLength()),
+		PIRType:      answer.GetPirType(),
+		HashTableLen: int(answer.GetHashTableLen()),
+		Merkle:       &database.Merkle{Root: answer.GetRoot(), ProofLen: int(answer.GetProofLen())},
 	}
 
 	return dbInfo
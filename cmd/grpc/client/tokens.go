@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/proto"
+	"github.com/si-co/vpir-code/lib/token"
+	"github.com/si-co/vpir-code/lib/utils"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// issuanceCredentials attaches the issuance token to the calls issuing
+// anonymous tokens
+type issuanceCredentials string
+
+func (t issuanceCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{utils.AdminTokenKey: string(t)}, nil
+}
+
+// RequireTransportSecurity requires TLS, so that the token is never sent in
+// the clear
+func (t issuanceCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// issueTokens gets anonymous tokens from every server requiring them and
+// appends them to the token file of the server. The tokens are blinded, so
+// that the servers cannot link the queries redeeming them to the issuance.
+func (lc *localClient) issueTokens() (string, error) {
+	issuance, err := utils.ReadAdminToken(lc.flags.issuance)
+	if err != nil {
+		return "", xerrors.Errorf("could not read the issuance token: %v", err)
+	}
+	opts := append([]grpc.CallOption{grpc.PerRPCCredentials(issuanceCredentials(issuance))}, lc.callOptions...)
+
+	ctx, cancel := context.WithTimeout(lc.ctx, time.Minute)
+	defer cancel()
+
+	issued := 0
+	for i, addr := range lc.config.Addresses {
+		if len(lc.tokenKeys[addr]) == 0 {
+			log.Printf("server %s does not require tokens", addr)
+			continue
+		}
+		req, err := token.NewRequest(lc.prg, lc.flags.issue)
+		if err != nil {
+			return "", err
+		}
+		c := proto.NewAdminClient(lc.connections[addr])
+		resp, err := c.IssueTokens(ctx, &proto.IssueTokensRequest{Blinded: req.Bytes()}, opts...)
+		if err != nil {
+			return "", xerrors.Errorf("could not get tokens from %s: %v", addr, err)
+		}
+		tokens, err := req.Finalize(lc.tokenKeys[addr], resp.GetEvaluated(), resp.GetProof())
+		if err != nil {
+			return "", xerrors.Errorf("invalid tokens of %s: %v", addr, err)
+		}
+		if err := appendTokens(tokenFile(lc.flags.tokens, i), tokens); err != nil {
+			return "", err
+		}
+		issued += len(tokens)
+	}
+
+	out := fmt.Sprintf("%d anonymous tokens issued", issued)
+	fmt.Println(out)
+	return out, nil
+}
+
+// withToken returns the context of a query to the server at the address,
+// carrying a token taken from the token file of the server if the server
+// requires tokens
+func (lc *localClient) withToken(ctx context.Context, addr string) (context.Context, error) {
+	if len(lc.tokenKeys[addr]) == 0 {
+		return ctx, nil
+	}
+	for i, a := range lc.config.Addresses {
+		if a == addr {
+			t, err := takeToken(tokenFile(lc.flags.tokens, i))
+			if err != nil {
+				return nil, err
+			}
+			return metadata.AppendToOutgoingContext(ctx, token.MetadataKey, string(t)), nil
+		}
+	}
+	return nil, xerrors.Errorf("unknown server %s", addr)
+}
+
+// tokenFile returns the file of the tokens of the server with the given id
+func tokenFile(prefix string, id int) string {
+	return fmt.Sprintf("%s.%d", prefix, id)
+}
+
+// readTokens returns the hex-encoded tokens of the file, one per line
+func readTokens(path string) ([][]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([][]byte, 0)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		t, err := hex.DecodeString(string(line))
+		if err != nil {
+			return nil, xerrors.Errorf("malformed token file %s: %v", path, err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, s.Err()
+}
+
+func writeTokens(path string, tokens [][]byte) error {
+	b := new(bytes.Buffer)
+	for _, t := range tokens {
+		fmt.Fprintln(b, hex.EncodeToString(t))
+	}
+	return ioutil.WriteFile(path, b.Bytes(), 0600)
+}
+
+func appendTokens(path string, tokens [][]byte) error {
+	old, err := readTokens(path)
+	if err != nil {
+		return err
+	}
+	return writeTokens(path, append(old, tokens...))
+}
+
+// takeToken removes the first token of the file and returns it. The token is
+// spent even if the query fails, as the server may have redeemed it.
+func takeToken(path string) ([]byte, error) {
+	tokens, err := readTokens(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, xerrors.Errorf("no token left in %s, get some with -issue", path)
+	}
+	return tokens[0], writeTokens(path, tokens[1:])
+}
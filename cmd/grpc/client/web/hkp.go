@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/si-co/vpir-code/cmd/grpc/client/manager"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// hkp serves the lookups of the HKP interface (draft-shaw-openpgp-hkp) with
+// PIR queries, so that GnuPG fetches keys privately with
+// --keyserver hkp://<listen-addr>. Emails, fingerprints and 64-bit key IDs
+// are looked up in the indices of the db of the servers, while short key IDs
+// are resolved with the keys already retrieved.
+type hkp struct {
+	actor manager.Actor
+
+	sync.Mutex
+	retrieved []*openpgp.Entity
+}
+
+func newHKP(actor manager.Actor) *hkp {
+	return &hkp{actor: actor}
+}
+
+// GET /pks/lookup?op={get|index|vindex}&search=...[&options=mr]
+func (h *hkp) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := req.URL.Query()
+	op := params.Get("op")
+	if op != "get" && op != "index" && op != "vindex" {
+		http.Error(w, "unsupported operation: "+op, http.StatusNotImplemented)
+		return
+	}
+	search := params.Get("search")
+	if search == "" {
+		http.Error(w, "search argument not found", http.StatusBadRequest)
+		return
+	}
+	mr := false
+	for _, o := range strings.Split(params.Get("options"), ",") {
+		mr = mr || o == "mr"
+	}
+
+	entities, status, err := h.lookup(search)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if op == "get" {
+		armored, err := pgp.ArmorKey(entities...)
+		if err != nil {
+			http.Error(w, "failed to armor the key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if mr {
+			w.Header().Set("Content-Type", "application/pgp-keys; charset=utf-8")
+			w.Write([]byte(armored))
+			return
+		}
+		writeHTML(w, "Public key of "+search, armored)
+		return
+	}
+
+	index := pgp.HKPIndex(entities...)
+	if mr {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(index))
+		return
+	}
+	writeHTML(w, "Search results for "+search, index)
+}
+
+// lookup returns the entities searched by email, fingerprint or key ID, the
+// most recent first, and the HTTP status of the error if any
+func (h *hkp) lookup(search string) ([]*openpgp.Entity, int, error) {
+	index := pgp.IndexEmail
+	id, isKeyID := pgp.HKPKeyID(search)
+	if isKeyID {
+		h.Lock()
+		for _, e := range h.retrieved {
+			if pgp.MatchesKeyID(e, id) {
+				h.Unlock()
+				return []*openpgp.Entity{e}, http.StatusOK, nil
+			}
+		}
+		h.Unlock()
+		if index, _ = pgp.ParseSearch(search); index == pgp.IndexEmail {
+			return nil, http.StatusNotFound, fmt.Errorf("no key with short ID %s was retrieved, search by email first", id)
+		}
+	} else {
+		email, err := pgp.HKPSearchEmail(search)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		id = email
+	}
+
+	dbInfo, err := h.actor.GetDBInfos()
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to get db info: %v", err)
+	}
+	client := client.NewPIR(utils.RandomPRG(), &dbInfo[0])
+
+	keys, err := h.actor.GetEntitiesByIndex(index, id, dbInfo[0], client)
+	if err != nil {
+		if strings.Contains(err.Error(), "no key with the given") {
+			return nil, http.StatusNotFound, fmt.Errorf("no key found for %s", id)
+		}
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to get result: %v", err)
+	}
+	log.Printf("HKP lookup of %s %s", index, id)
+
+	h.Lock()
+	defer h.Unlock()
+	entities := make([]*openpgp.Entity, len(keys))
+	for i, key := range keys {
+		entities[i] = key.Entity
+		h.remember(key.Entity)
+	}
+
+	return entities, http.StatusOK, nil
+}
+
+// remember stores the retrieved entity, replacing the previous copy of the
+// same key, if any. The caller must hold the lock.
+func (h *hkp) remember(entity *openpgp.Entity) {
+	for i, e := range h.retrieved {
+		if e.PrimaryKey.Fingerprint == entity.PrimaryKey.Fingerprint {
+			h.retrieved[i] = entity
+			return
+		}
+	}
+	h.retrieved = append(h.retrieved, entity)
+}
+
+// writeHTML writes the human-readable responses
+func writeHTML(w http.ResponseWriter, title, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><head><title>%s</title></head><body><h1>%s</h1><pre>%s</pre></body></html>\n",
+		html.EscapeString(title), html.EscapeString(title), html.EscapeString(body))
+}
@@ -15,6 +15,8 @@ import (
 
 	"github.com/si-co/vpir-code/cmd/grpc/client/manager"
 	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/logging"
 	"github.com/si-co/vpir-code/lib/query"
 	"github.com/si-co/vpir-code/lib/utils"
 	"golang.org/x/xerrors"
@@ -113,11 +115,17 @@ func main() {
 
 	mux := http.NewServeMux()
 	server := &http.Server{
-		Handler:  tracing(nextRequestID)(logging(logger)(mux)),
+		Handler:  tracing(nextRequestID)(logRequests(logger)(mux)),
 		ErrorLog: logger,
 	}
 
 	mux.HandleFunc("/retrieve", gethandleRetreive(pointActor))
+	// HKP interface for the PGP clients, e.g., gpg --keyserver hkp://host:port
+	hkp := newHKP(pointActor)
+	mux.Handle("/pks/lookup", hkp)
+	// WKD interface for the mail clients, for the well-known URLs of the
+	// domains proxied here
+	mux.Handle("/.well-known/openpgpkey/", newWKD(hkp))
 	mux.HandleFunc("/count/email", getHandleCountEmail(complexActor))
 	mux.HandleFunc("/count/algo", getHandleCountAlgo(complexActor))
 	mux.HandleFunc("/count/timestamp", getHandleCountTimestamp(complexActor))
@@ -158,7 +166,7 @@ func loadPointManager() (manager.Manager, error) {
 		}
 	}
 
-	manager := manager.NewManager(*config, grpcOpts)
+	manager := manager.NewManager(*config, grpcOpts, logging.Default())
 
 	return manager, nil
 }
@@ -182,7 +190,11 @@ func loadComplexManager() (manager.Manager, error) {
 		}
 	}
 
-	manager := manager.NewManager(*config, grpcOpts)
+	if err := fss.SetPrfKeysFromConfig(config); err != nil {
+		return manager.Manager{}, xerrors.Errorf("failed to set FSS keys: %v", err)
+	}
+
+	manager := manager.NewManager(*config, grpcOpts, logging.Default())
 
 	return manager, nil
 }
@@ -391,7 +403,7 @@ func executeStatsQuery(clientQuery *query.ClientFSS, actor manager.Actor) (uint3
 		return 0, xerrors.Errorf("failed to query bytes: %v", err)
 	}
 
-	answers := actor.RunQueries(queries)
+	answers := actor.RunPredicateQueries(queries)
 
 	result, err := client.ReconstructBytes(answers)
 	if err != nil {
@@ -410,7 +422,7 @@ func nextRequestID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
 
-func logging(logger *log.Logger) func(http.Handler) http.Handler {
+func logRequests(logger *log.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/pgp"
+)
+
+// wkd serves the lookups of the Web Key Directory
+// (draft-koch-openpgp-webkey-service) with PIR queries, so that the mail
+// clients speaking WKD fetch keys privately when the well-known URLs of their
+// domain are proxied here. Only the keys self-signed by the requested email,
+// neither expired nor revoked, are served.
+type wkd struct {
+	hkp *hkp
+}
+
+func newWKD(h *hkp) *wkd {
+	return &wkd{hkp: h}
+}
+
+// GET /.well-known/openpgpkey/[<domain>/]hu/<hash>?l=<local-part>
+// GET /.well-known/openpgpkey/[<domain>/]policy
+func (k *wkd) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "only GET and HEAD are supported", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	r, err := pgp.ParseWKDPath(req.URL.Path, req.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if r.Policy {
+		// empty policy, i.e., no submission address nor protocol version
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return
+	}
+
+	// the hash cannot be inverted, the PIR lookup needs the local part
+	email, err := r.Email(req.URL.Query().Get("l"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	entities, status, err := k.hkp.lookup(email)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	now := time.Now()
+	for _, e := range entities {
+		if verdict, reason := pgp.VerifyKey(e, pgp.IndexEmail, email, now); verdict != pgp.Valid {
+			log.Printf("WKD lookup of %s: skipping key %s, %s (%s)", email, pgp.Fingerprint(e), verdict, reason)
+			continue
+		}
+		if err := e.Serialize(buf); err != nil {
+			http.Error(w, "failed to serialize the key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if buf.Len() == 0 {
+		http.Error(w, "no valid key found for "+email, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/si-co/vpir-code/lib/logging"
+	"github.com/si-co/vpir-code/lib/proto"
+	"github.com/si-co/vpir-code/lib/token"
+	"github.com/si-co/vpir-code/lib/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// adminMethodPrefix prefixes the full names of the methods of the admin
+	// service
+	adminMethodPrefix = "/proto.Admin/"
+
+	// issueTokensMethod is the full name of the issuance of the anonymous
+	// tokens, also allowed to the calls carrying the issuance token
+	issueTokensMethod = adminMethodPrefix + "IssueTokens"
+
+	// maxIssuedTokens is the maximum number of tokens issued by a call
+	maxIssuedTokens = 1024
+)
+
+// adminServer implements the admin service over the server of the queries
+type adminServer struct {
+	proto.UnimplementedAdminServer
+	vs *vpirServer
+}
+
+// adminAuth returns the interceptor rejecting the calls to the admin service
+// that do not carry the admin token of the server, or the issuance token for
+// the issuance of anonymous tokens. A nil token is never accepted. The calls
+// to the other services are passed through.
+func adminAuth(logger *logging.Logger, token, issuance []byte) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, adminMethodPrefix) {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		tokens := md.Get(utils.AdminTokenKey)
+		ok := len(tokens) == 1 && ((token != nil && utils.CheckAdminToken(token, tokens[0])) ||
+			(info.FullMethod == issueTokensMethod && issuance != nil && utils.CheckAdminToken(issuance, tokens[0])))
+		if !ok {
+			logger.Warn("rejected unauthenticated admin call", logging.F("method", info.FullMethod))
+			return nil, status.Error(codes.Unauthenticated, "invalid admin token")
+		}
+		logger.Info("admin call", logging.F("method", info.FullMethod))
+		return handler(ctx, req)
+	}
+}
+
+// Reload loads the db again from the key files, e.g., after an import, and
+// serves it in place of the current one
+func (a *adminServer) Reload(ctx context.Context, r *proto.ReloadRequest) (*proto.StatusResponse, error) {
+	files := int(r.GetFiles())
+	if files == 0 {
+		files = a.vs.current().files
+	}
+	st, err := a.vs.load(files)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "reload failed: %v", err)
+	}
+	a.vs.log.Info("db reloaded", logging.F("files", st.files), logging.F("epoch", st.epoch), logging.F("digest", st.digest))
+
+	return a.status(st), nil
+}
+
+// Status returns the epoch and the digest of the db currently served
+func (a *adminServer) Status(ctx context.Context, r *proto.StatusRequest) (*proto.StatusResponse, error) {
+	return a.status(a.vs.current()), nil
+}
+
+func (a *adminServer) status(st *dbState) *proto.StatusResponse {
+	resp := &proto.StatusResponse{
+		Scheme:   a.vs.scheme,
+		Epoch:    st.epoch,
+		Digest:   st.digest,
+		LoadedAt: st.loadedAt.Unix(),
+		Files:    uint32(st.files),
+	}
+	if st.head != nil {
+		resp.TreeSize = st.head.Size
+		resp.TreeRoot = st.head.Root
+	}
+	return resp
+}
+
+// SelfCheck runs the integrity checks of the dbs currently served and checks
+// that their digest did not change since they were loaded
+func (a *adminServer) SelfCheck(ctx context.Context, r *proto.SelfCheckRequest) (*proto.SelfCheckResponse, error) {
+	st := a.vs.current()
+	failures := make([]string, 0)
+	var digest []byte
+	if st.dbBytes != nil {
+		for _, err := range st.dbBytes.Check() {
+			failures = append(failures, err.Error())
+		}
+		digest = st.dbBytes.Digest()
+	}
+	if st.db != nil {
+		for _, err := range st.db.Check() {
+			failures = append(failures, "metadata: "+err.Error())
+		}
+		if st.dbBytes == nil {
+			digest = st.db.Digest()
+		}
+	}
+	if !bytes.Equal(digest, st.digest) {
+		failures = append(failures, fmt.Sprintf("digest %x differs from the digest %x of the db loaded", digest, st.digest))
+	}
+	a.vs.log.Info("self-check of the db", logging.F("failures", len(failures)))
+
+	return &proto.SelfCheckResponse{Failures: failures}, nil
+}
+
+// Occupancy returns the occupancy of the hash table of the keys
+func (a *adminServer) Occupancy(ctx context.Context, r *proto.OccupancyRequest) (*proto.OccupancyResponse, error) {
+	st := a.vs.current()
+	resp := new(proto.OccupancyResponse)
+	if st.dbBytes != nil {
+		o, err := st.dbBytes.Occupancy()
+		if err != nil {
+			return nil, status.Errorf(codes.DataLoss, "corrupted db: %v", err)
+		}
+		resp.Buckets = uint32(o.Buckets)
+		resp.Blocks = uint32(o.Blocks)
+		resp.OverflowBlocks = uint32(o.OverflowBlocks)
+		resp.EmptyBuckets = uint32(o.EmptyBuckets)
+		resp.Entries = uint64(o.Entries)
+		resp.MaxEntries = uint32(o.MaxEntries)
+		resp.UsedBytes = uint64(o.UsedBytes)
+		resp.BlockSize = uint32(o.BlockSize)
+		resp.Histogram = make([]uint32, len(o.Histogram))
+		for i, n := range o.Histogram {
+			resp.Histogram[i] = uint32(n)
+		}
+	}
+	if st.db != nil {
+		resp.Keys = uint64(len(st.db.KeysInfo))
+	}
+
+	return resp, nil
+}
+
+// IssueTokens issues anonymous tokens on the blinded elements of a request of
+// the client, which the server cannot link to the tokens redeemed later
+func (a *adminServer) IssueTokens(ctx context.Context, r *proto.IssueTokensRequest) (*proto.IssueTokensResponse, error) {
+	if a.vs.issuer == nil {
+		return nil, status.Error(codes.FailedPrecondition, "anonymous tokens are not enabled on this server")
+	}
+	if n := len(r.GetBlinded()) / token.ElementSize(); n > maxIssuedTokens {
+		return nil, status.Errorf(codes.InvalidArgument, "%d tokens requested, at most %d per call", n, maxIssuedTokens)
+	}
+	evaluated, proof, err := a.vs.issuer.Issue(rand.Reader, r.GetBlinded())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	a.vs.log.Info("anonymous tokens issued", logging.F("tokens", len(evaluated)/token.ElementSize()))
+
+	return &proto.IssueTokensResponse{Evaluated: evaluated, Proof: proof}, nil
+}
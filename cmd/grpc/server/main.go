@@ -2,29 +2,43 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/si-co/vpir-code/cmd/grpc/sdnotify"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/idempotency"
+	"github.com/si-co/vpir-code/lib/logging"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/token"
+	"github.com/si-co/vpir-code/lib/transparency"
 	"github.com/si-co/vpir-code/lib/utils"
 
 	"github.com/si-co/vpir-code/lib/proto"
 	"github.com/si-co/vpir-code/lib/server"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -33,6 +47,14 @@ const (
 
 	defaultConfigFile = "config.toml"
 	defaultSksPath    = "data"
+
+	// scheme label of the metrics of the predicate queries answered next
+	// to a point scheme
+	predicateScheme = "predicate"
+
+	// time for which the answers to the queries with an idempotency key are
+	// kept for their retries
+	idempotencyTTL = 10 * time.Minute
 )
 
 func main() {
@@ -45,6 +67,17 @@ func main() {
 	logFile := flag.String("log", "", "write log to file instead of stdout/stderr")
 	prof := flag.Bool("prof", false, "Write CPU prof file")
 	mprof := flag.Bool("mprof", false, "Write memory prof file")
+	metricsAddr := flag.String("metrics", "", "if set, serve Prometheus metrics on this address, e.g., :9100")
+	keyFilters := flag.String("filters", "", "packets to strip from the keys: comma-separated photos, thirdparty, expired or all")
+	translog := flag.String("translog", "", "if set, append the digest of the db to the transparency log in this file and serve its signed head")
+	predicate := flag.Bool("predicate", false, "also answer the FSS predicate queries over the key metadata with a point scheme, e.g., to count the keys of a domain")
+	adminTokenFile := flag.String("admin", "", "if set, serve the admin service to the calls carrying the token stored in this file")
+	tokensFile := flag.String("tokens", "", "if set, answer only the queries redeeming an anonymous token of the issuer whose key is stored in this file, created if needed, with the spent tokens appended to this file with suffix .spent")
+	issuanceFile := flag.String("issuance", "", "if set with -tokens, issue anonymous tokens to the calls carrying the issuance token stored in this file")
+	idempotencySize := flag.Int("idempotency", 1024, "number of answers kept for the retries of the queries with an idempotency key, 0 to answer every retry again")
+	settingsFile := flag.String("settings", "", "if set, TOML file of the TLS certificate, the rate limit and the key files directory, reloaded on SIGHUP or when it changes")
+	configFlags := utils.RegisterConfigFlags(flag.CommandLine)
+	logFlags := logging.RegisterFlags(flag.CommandLine)
 
 	flag.Parse()
 
@@ -67,7 +100,9 @@ func main() {
 		}()
 	}
 
-	// set logs
+	// set logs, the logger of the server being the default one of the
+	// libraries too
+	var out io.Writer = os.Stdout
 	log.SetOutput(os.Stdout)
 	log.SetPrefix(fmt.Sprintf("[Server %v] ", *sid))
 	if len(*logFile) > 0 {
@@ -77,7 +112,15 @@ func main() {
 		}
 		defer f.Close()
 		log.SetOutput(f)
+		out = f
+	}
+	logger, err := logFlags.Logger(out)
+	if err != nil {
+		log.Fatal(err)
 	}
+	logger = logger.With(logging.F("server", *sid))
+	logging.SetDefault(logger)
+	logger.Info("CPU features of the kernels", logging.F("cpu", utils.CPU))
 
 	// configs
 	configPath := os.Getenv(configEnvKey)
@@ -85,95 +128,146 @@ func main() {
 		configPath = defaultConfigFile
 	}
 
-	config, err := utils.LoadConfig(configPath)
+	config, err := utils.LoadLayeredConfig(configPath, configFlags)
 	if err != nil {
-		log.Fatalf("could not load the server config file: %v", err)
+		logger.Fatal("could not load the server config", logging.F("err", err))
+	}
+	if err := fss.SetPrfKeysFromConfig(config); err != nil {
+		logger.Fatal("could not set the FSS keys", logging.F("err", err))
 	}
 	addr := config.Addresses[*sid]
+	filter, err := pgp.ParseFilter(*keyFilters)
+	if err != nil {
+		logger.Fatal("invalid filters", logging.F("err", err))
+	}
 
-	// load the db
-	var db *database.DB
-	var dbBytes *database.Bytes
-	switch *scheme {
-	case "pointPIR":
-		dbBytes, err = loadPgpBytes(*filesNumber, true)
+	// open the transparency log, to which the epoch of every db loaded is
+	// appended with the head signed by the operator of the server
+	var tlog *transparency.Log
+	if *translog != "" {
+		tlog, err = transparency.OpenLog(*translog)
 		if err != nil {
-			log.Fatalf("impossible to construct real keys bytes db: %v", err)
+			logger.Fatal("impossible to open the transparency log", logging.F("err", err))
 		}
-		log.Printf("db size in GiB: %f", dbBytes.SizeGiB())
-	case "pointVPIR":
-		dbBytes, err = loadPgpMerkle(*filesNumber, true)
+	}
+
+	// export the metrics of the queries for the dashboards
+	var metrics *monitor.Exporter
+	if *metricsAddr != "" {
+		monitor.EnableBandwidth(true)
+		monitor.EnableAnswerPhases(true)
+		metrics = monitor.NewExporter("vpir")
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		go func() {
+			logger.Info("metrics served", logging.F("addr", *metricsAddr))
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				logger.Error("metrics server stopped", logging.F("err", err))
+			}
+		}()
+	}
+
+	// the settings reloaded without restarting the server
+	settings, err := newReloader(*settingsFile, config.Server(*sid))
+	if err != nil {
+		logger.Fatal("impossible to load the settings", logging.F("err", err))
+	}
+
+	// load the db and select the servers of the scheme
+	vs := &vpirServer{
+		sid:        *sid,
+		scheme:     *scheme,
+		predicates: *predicate,
+		filter:     filter,
+		tlog:       tlog,
+		metrics:    metrics,
+		latency:    monitor.NewDefaultLatencyHistogram(),
+		settings:   settings,
+		log:        logger,
+		experiment: *experiment,
+		cores:      *cores,
+	}
+	if *idempotencySize > 0 {
+		vs.answers = idempotency.NewCache(*idempotencySize, idempotencyTTL)
+	}
+	if _, err := vs.load(*filesNumber); err != nil {
+		logger.Fatal("impossible to load the db", logging.F("err", err))
+	}
+
+	// the anonymous tokens redeemed by the queries, issued by the server
+	if *tokensFile != "" {
+		vs.issuer, err = token.LoadOrCreateIssuer(*tokensFile)
 		if err != nil {
-			log.Fatalf("impossible to construct real keys bytes db: %v", err)
+			logger.Fatal("impossible to load the token key", logging.F("err", err))
 		}
-		log.Printf("db size in GiB: %f", dbBytes.SizeGiB())
-	case "complexPIR", "complexVPIR":
-		db, err = loadPgpDB(*filesNumber, true)
+		vs.redeemer, err = token.OpenRedeemer(vs.issuer, *tokensFile+".spent")
 		if err != nil {
-			log.Fatalf("impossible to load real keys db: %v", err)
+			logger.Fatal("impossible to load the spent tokens", logging.F("err", err))
 		}
-		log.Printf("db size in GiB: %f", db.SizeGiB())
-	default:
-		log.Fatal("unknown scheme: " + string(*scheme))
+		defer vs.redeemer.Close()
 	}
 
-	// GC after db creation
-	runtime.GC()
-
-	// run server with TLS
+	// run server with TLS, with the certificate of the current settings
 	cfg := &tls.Config{
-		Certificates: []tls.Certificate{utils.ServerCertificates[*sid]},
-		ClientAuth:   tls.NoClientCert,
+		GetCertificate: settings.getCertificate,
+		ClientAuth:     tls.NoClientCert,
 	}
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logger.Fatal("failed to listen", logging.F("err", err))
 	}
-	rpcServer := grpc.NewServer(
-		grpc.MaxRecvMsgSize(1024*1024*1024),
-		grpc.MaxSendMsgSize(1024*1024*1024),
+	rpcOptions := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(1024 * 1024 * 1024),
+		grpc.MaxSendMsgSize(1024 * 1024 * 1024),
 		grpc.Creds(credentials.NewTLS(cfg)),
-	)
-
-	// select correct server
-	var s server.Server
-	switch *scheme {
-	case "pointPIR", "pointVPIR":
-		if *cores != -1 && *experiment {
-			s = server.NewPIR(dbBytes, *cores)
-		} else {
-			s = server.NewPIR(dbBytes)
+	}
+	var adminToken, issuanceToken []byte
+	if *adminTokenFile != "" {
+		adminToken, err = utils.ReadAdminToken(*adminTokenFile)
+		if err != nil {
+			logger.Fatal("impossible to read the admin token", logging.F("err", err))
 		}
-	case "complexPIR":
-		if *cores != -1 && *experiment {
-			s = server.NewPredicatePIR(db, byte(*sid), *cores)
-		} else {
-			s = server.NewPredicatePIR(db, byte(*sid))
+	}
+	if *issuanceFile != "" {
+		if vs.issuer == nil {
+			logger.Fatal("-issuance requires -tokens")
 		}
-	case "complexVPIR":
-		if *cores != -1 && *experiment {
-			s = server.NewPredicateAPIR(db, byte(*sid), *cores)
-		} else {
-			s = server.NewPredicateAPIR(db, byte(*sid))
+		issuanceToken, err = utils.ReadAdminToken(*issuanceFile)
+		if err != nil {
+			logger.Fatal("impossible to read the issuance token", logging.F("err", err))
 		}
-	default:
-		log.Fatal("unknow scheme")
 	}
+	interceptors := make([]grpc.UnaryServerInterceptor, 0)
+	if adminToken != nil || issuanceToken != nil {
+		interceptors = append(interceptors, adminAuth(logger, adminToken, issuanceToken))
+	}
+	interceptors = append(interceptors, settings.rateLimit())
+	if vs.redeemer != nil {
+		interceptors = append(interceptors, tokenAuth(logger, vs.redeemer))
+	}
+	rpcOptions = append(rpcOptions, grpc.ChainUnaryInterceptor(interceptors...))
+	rpcServer := grpc.NewServer(rpcOptions...)
 
 	// start server
-	proto.RegisterVPIRServer(rpcServer, &vpirServer{
-		Server:     s,
-		experiment: *experiment,
-		cores:      *cores,
-	})
+	proto.RegisterVPIRServer(rpcServer, vs)
+	if adminToken != nil || issuanceToken != nil {
+		proto.RegisterAdminServer(rpcServer, &adminServer{vs: vs})
+		logger.Info("admin service enabled")
+	}
+	if vs.redeemer != nil {
+		logger.Info("anonymous tokens required by the queries")
+	}
 
 	// listen signals from os
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	errCh := make(chan error, 1)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go vs.watch(hupCh)
 
 	go func() {
-		log.Println("gRPC server started at", lis.Addr())
+		logger.Info("gRPC server started", logging.F("addr", lis.Addr()))
 		if err := rpcServer.Serve(lis); err != nil {
 			errCh <- err
 		}
@@ -183,7 +277,7 @@ func main() {
 	if *experiment {
 		host, _, err := net.SplitHostPort(addr)
 		if err != nil {
-			log.Fatal("impossible to parse addr for HTTP server")
+			logger.Fatal("impossible to parse addr for HTTP server", logging.F("err", err))
 		}
 		h := func(w http.ResponseWriter, _ *http.Request) {
 			sigCh <- os.Interrupt
@@ -198,16 +292,17 @@ func main() {
 
 	_, err = sdnotify.SdNotify(false, sdnotify.SdNotifyReady)
 	if err != nil {
-		log.Fatalf("failed to sdnotify: %v", err)
+		logger.Fatal("failed to sdnotify", logging.F("err", err))
 	}
 
 	select {
 	case err := <-errCh:
-		log.Fatalf("failed to serve: %v", err)
+		logger.Fatal("failed to serve", logging.F("err", err))
 	case <-sigCh:
 		rpcServer.GracefulStop()
 		lis.Close()
-		log.Println("clean shutdown of server done")
+		vs.logLatency()
+		logger.Info("clean shutdown of server done")
 	}
 
 	sdnotify.SdNotify(false, sdnotify.SdNotifyStopping)
@@ -216,25 +311,245 @@ func main() {
 // vpirServer is used to implement VPIR Server protocol.
 type vpirServer struct {
 	proto.UnimplementedVPIRServer
-	Server server.Server // both IT and DPF-based server
+	sid    int
+	scheme string
+	// true if the predicate queries are answered next to a point scheme
+	predicates bool
+	filter     pgp.Filter
+
+	// transparency log of the epochs of the db, nil if not served
+	tlog *transparency.Log
+
+	// settings reloaded while serving
+	settings *reloader
+
+	log *logging.Logger
+
+	// db currently served, swapped as a whole by the reloads, which are run
+	// one at a time
+	mu        sync.RWMutex
+	state     *dbState
+	loads     uint64
+	reloading sync.Mutex
+
+	// issuer of the anonymous tokens and redeemer of the tokens of the
+	// queries, nil if the queries are not gated by tokens
+	issuer   *token.Issuer
+	redeemer *token.Redeemer
+
+	// answers to the queries with an idempotency key, replayed for their
+	// retries, nil if every query is answered
+	answers *idempotency.Cache
+
+	// nil if the metrics are not exported
+	metrics *monitor.Exporter
+	// per-RPC latency of the queries
+	latency *monitor.LatencyHistogram
 
 	// only for experiments
 	experiment bool
 	cores      int
 }
 
+// dbState is a db loaded by the server along with the servers answering
+// the queries over it
+type dbState struct {
+	Server server.Server // both IT and DPF-based server
+
+	// server of the FSS predicate queries, the one of the scheme for the
+	// complex schemes and nil if they are not answered
+	predicate server.Server
+
+	// dbs of the point schemes and of the predicate queries, nil if not
+	// loaded
+	dbBytes *database.Bytes
+	db      *database.DB
+
+	digest   []byte
+	files    int
+	loadedAt time.Time
+
+	// number of the epoch of the db in the transparency log and signed head
+	// of the log, nil without log
+	epoch uint64
+	head  *transparency.TreeHead
+}
+
+// current returns the db currently served
+func (s *vpirServer) current() *dbState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// load loads the db of the given number of key files and serves it in place
+// of the current one, if any. The previous db is released once the queries
+// in progress over it are answered.
+func (s *vpirServer) load(files int) (*dbState, error) {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+
+	st, err := s.loadState(files)
+	if err != nil {
+		return nil, err
+	}
+
+	// GC after db creation
+	runtime.GC()
+
+	// the digest to publish to the clients holding it, for the
+	// authenticated dbs
+	if digest, err := server.Digest(st.Server); err == nil {
+		s.log.Info("digest of the db for the clients", logging.F("digest", digest))
+	}
+
+	// the log is updated with the state, so that the head always matches
+	// the log in the responses to the clients
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.epoch = s.loads
+	if s.tlog != nil {
+		st.epoch, st.head, err = appendEpoch(s.tlog, st.digest, s.sid)
+		if err != nil {
+			return nil, fmt.Errorf("impossible to update the transparency log: %v", err)
+		}
+		s.log.Info("transparency log updated", logging.F("size", st.head.Size), logging.F("root", st.head.Root))
+	}
+	s.state = st
+	s.loads++
+
+	return st, nil
+}
+
+// loadState loads the dbs of the scheme and selects the servers answering
+// the queries over them
+func (s *vpirServer) loadState(files int) (*dbState, error) {
+	st := &dbState{files: files, loadedAt: time.Now()}
+	dir := s.settings.dataDir()
+	var err error
+
+	switch s.scheme {
+	case "pointPIR", "pointPIRDPF", "pointPIRReplicated", "pointVPIR":
+		if !s.predicates {
+			break
+		}
+		// the metadata db of the predicate queries is loaded next to the
+		// db of the point scheme
+		st.db, err = loadPgpDB(dir, files, true)
+		if err != nil {
+			return nil, fmt.Errorf("impossible to load real keys db: %v", err)
+		}
+		s.log.Info("metadata db loaded", logging.F("gib", st.db.SizeGiB()))
+	}
+	switch s.scheme {
+	case "pointPIR", "pointPIRDPF", "pointPIRReplicated":
+		st.dbBytes, err = loadPgpBytes(dir, files, true, s.filter)
+		if err != nil {
+			return nil, fmt.Errorf("impossible to construct real keys bytes db: %v", err)
+		}
+		s.log.Info("db loaded", logging.F("gib", st.dbBytes.SizeGiB()))
+	case "pointVPIR":
+		st.dbBytes, err = loadPgpMerkle(dir, files, true, s.filter)
+		if err != nil {
+			return nil, fmt.Errorf("impossible to construct real keys bytes db: %v", err)
+		}
+		s.log.Info("db loaded", logging.F("gib", st.dbBytes.SizeGiB()))
+	case "complexPIR", "complexVPIR":
+		st.db, err = loadPgpDB(dir, files, true)
+		if err != nil {
+			return nil, fmt.Errorf("impossible to load real keys db: %v", err)
+		}
+		s.log.Info("db loaded", logging.F("gib", st.db.SizeGiB()))
+	default:
+		return nil, errors.New("unknown scheme: " + s.scheme)
+	}
+	if st.dbBytes != nil {
+		st.digest = st.dbBytes.Digest()
+	} else {
+		st.digest = st.db.Digest()
+	}
+
+	// select correct server
+	switch s.scheme {
+	case "pointPIR", "pointVPIR":
+		if s.cores != -1 && s.experiment {
+			st.Server = server.NewPIR(st.dbBytes, s.cores)
+		} else {
+			st.Server = server.NewPIR(st.dbBytes)
+		}
+	case "pointPIRDPF":
+		if s.cores != -1 && s.experiment {
+			st.Server = server.NewPIRDPF(st.dbBytes, s.cores)
+		} else {
+			st.Server = server.NewPIRDPF(st.dbBytes)
+		}
+	case "pointPIRReplicated":
+		if s.cores != -1 && s.experiment {
+			st.Server = server.NewPIRReplicated(st.dbBytes, s.cores)
+		} else {
+			st.Server = server.NewPIRReplicated(st.dbBytes)
+		}
+	case "complexPIR":
+		if s.cores != -1 && s.experiment {
+			st.Server = server.NewPredicatePIR(st.db, byte(s.sid), s.cores)
+		} else {
+			st.Server = server.NewPredicatePIR(st.db, byte(s.sid))
+		}
+	case "complexVPIR":
+		if s.cores != -1 && s.experiment {
+			st.Server = server.NewPredicateAPIR(st.db, byte(s.sid), s.cores)
+		} else {
+			st.Server = server.NewPredicateAPIR(st.db, byte(s.sid))
+		}
+	}
+
+	// select the server of the predicate queries
+	switch {
+	case s.scheme == "complexPIR" || s.scheme == "complexVPIR":
+		st.predicate = st.Server
+	case s.predicates:
+		if s.cores != -1 && s.experiment {
+			st.predicate = server.NewPredicateAPIR(st.db, byte(s.sid), s.cores)
+		} else {
+			st.predicate = server.NewPredicateAPIR(st.db, byte(s.sid))
+		}
+	}
+
+	return st, nil
+}
+
 func (s *vpirServer) DatabaseInfo(ctx context.Context, r *proto.DatabaseInfoRequest) (
 	*proto.DatabaseInfoResponse, error) {
-	log.Print("got databaseInfo request")
+	s.log.Debug("got databaseInfo request")
+
+	// the log is read under the lock, as it is appended by the reloads
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := s.state
 
-	dbInfo := s.Server.DBInfo()
+	dbInfo := st.Server.DBInfo()
 	resp := &proto.DatabaseInfoResponse{
-		NumRows:     uint32(dbInfo.NumRows),
-		NumColumns:  uint32(dbInfo.NumColumns),
-		BlockLength: uint32(dbInfo.BlockSize),
-		PirType:     dbInfo.PIRType,
-		Root:        dbInfo.Root,
-		ProofLen:    uint32(dbInfo.ProofLen),
+		NumRows:      uint32(dbInfo.NumRows),
+		NumColumns:   uint32(dbInfo.NumColumns),
+		BlockLength:  uint32(dbInfo.BlockSize),
+		PirType:      dbInfo.PIRType,
+		Root:         dbInfo.Root,
+		ProofLen:     uint32(dbInfo.ProofLen),
+		HashTableLen: uint32(dbInfo.HashTableLen),
+	}
+
+	if s.issuer != nil {
+		resp.TokenKey = s.issuer.PublicKey()
+	}
+
+	if s.tlog != nil {
+		tr, err := transparency.NewResponse(s.tlog, st.head, r.GetKnownTreeSize())
+		if err != nil {
+			return nil, err
+		}
+		if resp.Transparency, err = tr.Encode(); err != nil {
+			return nil, err
+		}
 	}
 
 	return resp, nil
@@ -242,76 +557,179 @@ func (s *vpirServer) DatabaseInfo(ctx context.Context, r *proto.DatabaseInfoRequ
 
 func (s *vpirServer) Query(ctx context.Context, qr *proto.QueryRequest) (
 	*proto.QueryResponse, error) {
-	log.Print("got query request")
+	id := idempotency.QueryID(qr.GetQuery())
+	s.log.Debug("got query request", logging.F("query", id))
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(idempotency.MetadataKey)
+	if s.answers == nil || len(keys) == 0 {
+		a, err := s.answer(id, qr)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.QueryResponse{Answer: a}, nil
+	}
+	if len(keys) != 1 || len(keys[0]) != idempotency.KeyLen {
+		return nil, status.Error(codes.InvalidArgument, "malformed idempotency key")
+	}
+	a, replayed, err := s.answers.Do([]byte(keys[0]), id, func() ([]byte, error) {
+		return s.answer(id, qr)
+	})
+	if err == idempotency.ErrKeyReused {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		s.log.Info("answer replayed for a retry", logging.F("query", id))
+	}
+	return &proto.QueryResponse{Answer: a}, nil
+}
+
+// answer answers the query of the given ID with the server of the scheme or
+// the one of the predicate queries
+func (s *vpirServer) answer(id string, qr *proto.QueryRequest) ([]byte, error) {
+	st := s.current()
+	srv, scheme := st.Server, s.scheme
+	if qr.GetPredicate() {
+		if st.predicate == nil {
+			return nil, errors.New("predicate queries are not enabled on this server")
+		}
+		if st.predicate != st.Server {
+			srv, scheme = st.predicate, predicateScheme
+		}
+	}
 
-	a, err := s.Server.AnswerBytes(qr.GetQuery())
+	start := time.Now()
+	a, err := srv.AnswerBytes(qr.GetQuery())
 	if err != nil {
+		if s.metrics != nil {
+			s.metrics.ObserveError(scheme)
+		}
+		// the queries that cannot be decoded are the fault of the client
+		if errors.Is(err, query.ErrMalformed) || errors.Is(err, query.ErrVersion) ||
+			errors.Is(err, query.ErrTooLarge) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, err
 	}
+	elapsed := time.Since(start)
+	s.latency.Record(elapsed)
+	if s.metrics != nil {
+		s.metrics.ObserveQuery(scheme, elapsed, len(a))
+	}
 	answerLen := len(a)
-	log.Printf("answer size in bytes: %d", answerLen)
+	s.log.Debug("query answered", logging.F("query", id), logging.F("bytes", answerLen))
+	// the results of the experiments are plain lines parsed by the scripts
 	if s.experiment {
 		log.Printf("stats,%d,%d", s.cores, answerLen)
 	}
 
-	return &proto.QueryResponse{Answer: a}, nil
+	return a, nil
+}
+
+// logLatency logs the distribution of the latency of the queries answered
+func (s *vpirServer) logLatency() {
+	if s.latency.Count() == 0 {
+		return
+	}
+	ps := s.latency.Percentiles(50, 90, 99, 99.9)
+	s.log.Info("latency of the queries", logging.F("queries", s.latency.Count()),
+		logging.F("mean", s.latency.Mean()), logging.F("p50", ps[0]), logging.F("p90", ps[1]),
+		logging.F("p99", ps[2]), logging.F("p99.9", ps[3]), logging.F("max", s.latency.Max()))
+	if s.experiment {
+		log.Printf("latency,%d,%d,%f,%f,%f,%f", s.cores, s.latency.Count(),
+			ps[0].Seconds(), ps[1].Seconds(), ps[2].Seconds(), ps[3].Seconds())
+	}
 }
 
-func loadPgpDB(filesNumber int, rebalanced bool) (*database.DB, error) {
-	log.Println("Starting to read in the DB data")
+// appendEpoch appends the epoch of the db digest to the log and returns the
+// number of the epoch with the head of the log signed with the key of the
+// server
+func appendEpoch(l *transparency.Log, digest []byte, sid int) (uint64, *transparency.TreeHead, error) {
+	appended, err := l.Append(digest)
+	if err != nil {
+		return 0, nil, err
+	}
+	if appended {
+		logging.Default().Info("new epoch of the db appended to the transparency log", logging.F("epoch", l.Size()-1))
+	}
+
+	signer, ok := utils.ServerCertificates[sid].PrivateKey.(crypto.Signer)
+	if !ok {
+		return 0, nil, fmt.Errorf("the key of server %d cannot sign", sid)
+	}
+	head, err := transparency.SignHead(l, signer, time.Now())
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return l.Size() - 1, head, nil
+}
+
+func loadPgpDB(dir string, filesNumber int, rebalanced bool) (*database.DB, error) {
+	logging.Default().Info("starting to read in the DB data")
 
 	// take only filesNumber files
-	files := getSksFiles(filesNumber)
+	files, err := getSksFiles(dir, filesNumber)
+	if err != nil {
+		return nil, err
+	}
 
 	db, err := database.GenerateRealKeyDB(files)
 	if err != nil {
 		return nil, err
 	}
-	log.Println("DB loaded with files", files)
+	logging.Default().Info("DB loaded", logging.F("files", files))
 
 	return db, nil
 }
 
-func loadPgpBytes(filesNumber int, rebalanced bool) (*database.Bytes, error) {
-	log.Println("Starting to read in the DB data")
+func loadPgpBytes(dir string, filesNumber int, rebalanced bool, filter pgp.Filter) (*database.Bytes, error) {
+	logging.Default().Info("starting to read in the DB data")
 
 	// take only filesNumber files
-	files := getSksFiles(filesNumber)
+	files, err := getSksFiles(dir, filesNumber)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := database.GenerateRealKeyBytes(files, rebalanced)
+	db, err := database.GenerateRealKeyBytes(files, rebalanced, filter)
 	if err != nil {
 		return nil, err
 	}
-	log.Println("Bytes loaded with files", files)
+	logging.Default().Info("bytes loaded", logging.F("files", files))
 
 	return db, nil
 }
 
-func loadPgpMerkle(filesNumber int, rebalanced bool) (*database.Bytes, error) {
-	log.Println("Starting to read in the DB data")
+func loadPgpMerkle(dir string, filesNumber int, rebalanced bool, filter pgp.Filter) (*database.Bytes, error) {
+	logging.Default().Info("starting to read in the DB data")
 
 	// take only filesNumber files
-	files := getSksFiles(filesNumber)
+	files, err := getSksFiles(dir, filesNumber)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := database.GenerateRealKeyMerkle(files, rebalanced)
+	db, err := database.GenerateRealKeyMerkle(files, rebalanced, filter)
 	if err != nil {
 		return nil, err
 	}
-	log.Println("Bytes loaded with files", files)
+	logging.Default().Info("bytes loaded", logging.F("files", files))
 
 	return db, nil
 }
 
-func getSksFiles(filesNumber int) []string {
-	sksDir := os.Getenv(dataEnvKey)
-	if sksDir == "" {
-		sksDir = filepath.Join(defaultSksPath, pgp.SksParsedFolder)
-	}
-
-	files, err := pgp.GetAllFiles(sksDir)
+func getSksFiles(dir string, filesNumber int) ([]string, error) {
+	files, err := pgp.GetAllFiles(dir)
 	if err != nil {
-		log.Fatalf("impossible to get sks files: %v", err)
+		return nil, fmt.Errorf("impossible to get sks files: %v", err)
+	}
+	if filesNumber < 1 || filesNumber > len(files) {
+		return nil, fmt.Errorf("%d key files requested out of %d", filesNumber, len(files))
 	}
 	// take only filesNumber files
-	return files[:filesNumber]
+	return files[:filesNumber], nil
 }
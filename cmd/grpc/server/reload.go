@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/si-co/vpir-code/lib/keys"
+	"github.com/si-co/vpir-code/lib/logging"
+	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchInterval is the period at which the settings file and the
+// certificate files are checked for changes
+const watchInterval = 5 * time.Second
+
+// settings are the settings of the server that are reloaded, on SIGHUP or
+// when their file or the certificate files change, without closing the
+// listener: the TLS certificate of the new connections, the rate limit of
+// the queries and the directory of the key files of the next db loaded
+type settings struct {
+	// PEM file of the TLS certificate and reference of its key, e.g., an
+	// encrypted key file, the ones of the entry of the server in the config
+	// if empty
+	CertFile string
+	KeyFile  string
+	// queries per second answered, in bursts of at most Burst queries, and
+	// unlimited if zero
+	Rate  int
+	Burst int
+	// directory of the parsed key files, the one of VPIR_SKS_ROOT or the
+	// default one if empty
+	Data string
+}
+
+// reloader holds the current settings of the server, loaded from a TOML
+// file
+type reloader struct {
+	path   string
+	server utils.Server
+
+	mu       sync.RWMutex
+	settings settings
+	cert     *tls.Certificate
+	limiter  *rateLimiter
+	// modification times of the files, to detect their changes
+	modTimes map[string]time.Time
+}
+
+// newReloader returns the reloader of the settings file, if any, or of the
+// default settings of the server of the config entry
+func newReloader(path string, server utils.Server) (*reloader, error) {
+	r := &reloader{path: path, server: server}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the settings and the certificate again and applies them, or
+// keeps the current ones if they are invalid. It returns the previous
+// settings.
+func (r *reloader) reload() (settings, error) {
+	var s settings
+	if r.path != "" {
+		if _, err := toml.DecodeFile(r.path, &s); err != nil {
+			return r.current(), fmt.Errorf("invalid settings: %v", err)
+		}
+	}
+	if s.Rate < 0 || s.Burst < 0 {
+		return r.current(), fmt.Errorf("negative rate limit")
+	}
+
+	var cert tls.Certificate
+	switch {
+	case s.CertFile != "" && s.KeyFile != "":
+		var err error
+		cert, err = keys.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return r.current(), fmt.Errorf("invalid certificate: %v", err)
+		}
+	case s.CertFile != "" || s.KeyFile != "":
+		return r.current(), fmt.Errorf("certificate file without key file or vice versa")
+	default:
+		var err error
+		cert, err = utils.ServerCertificate(r.server)
+		if err != nil {
+			return r.current(), fmt.Errorf("invalid certificate: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.settings
+	r.settings = s
+	r.cert = &cert
+	if r.limiter == nil || s.Rate != old.Rate || s.Burst != old.Burst {
+		r.limiter = newRateLimiter(s.Rate, s.Burst)
+	}
+	r.modTimes = r.stat()
+	return old, nil
+}
+
+// current returns the current settings
+func (r *reloader) current() settings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.settings
+}
+
+// stat returns the modification times of the settings file and of the
+// certificate files
+func (r *reloader) stat() map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, f := range []string{r.path, r.settings.CertFile, r.settings.KeyFile,
+		r.server.CertFile, r.server.KeyFile} {
+		if f == "" {
+			continue
+		}
+		if fi, err := os.Stat(f); err == nil {
+			times[f] = fi.ModTime()
+		}
+	}
+	return times
+}
+
+// changed reports whether one of the files changed since the last reload
+func (r *reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := r.stat()
+	if len(now) != len(r.modTimes) {
+		return true
+	}
+	for f, t := range now {
+		if !t.Equal(r.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+// getCertificate returns the current certificate to the TLS handshakes, so
+// that the new connections use a rotated certificate
+func (r *reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// dataDir returns the directory of the key files of the next db loaded
+func (r *reloader) dataDir() string {
+	if dir := r.current().Data; dir != "" {
+		return dir
+	}
+	if dir := os.Getenv(dataEnvKey); dir != "" {
+		return dir
+	}
+	return filepath.Join(defaultSksPath, pgp.SksParsedFolder)
+}
+
+// rateLimit returns the interceptor rejecting the queries beyond the current
+// rate limit, before their tokens are redeemed. The admin calls are not
+// limited.
+func (r *reloader) rateLimit() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, adminMethodPrefix) {
+			return handler(ctx, req)
+		}
+		r.mu.RLock()
+		l := r.limiter
+		r.mu.RUnlock()
+		if !l.allow(time.Now()) {
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// watch reloads the settings on every signal of hup and when the files
+// change, and loads the db again from the new directory when the one of the
+// key files changes
+func (s *vpirServer) watch(hup <-chan os.Signal) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-hup:
+		case <-ticker.C:
+			if !s.settings.changed() {
+				continue
+			}
+		}
+		old, err := s.settings.reload()
+		if err != nil {
+			s.log.Error("settings not reloaded", logging.F("err", err))
+			continue
+		}
+		s.log.Info("settings reloaded")
+		if s.settings.current().Data == old.Data {
+			continue
+		}
+		st, err := s.load(s.current().files)
+		if err != nil {
+			s.log.Error("impossible to load the db of the new directory", logging.F("err", err))
+			continue
+		}
+		s.log.Info("db reloaded", logging.F("dir", s.settings.dataDir()), logging.F("epoch", st.epoch),
+			logging.F("digest", st.digest))
+	}
+}
+
+// rateLimiter is a token bucket of the queries
+type rateLimiter struct {
+	rate, burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns the limiter of rate queries per second in bursts of
+// at most burst queries, at least one, or nil if the rate is zero
+func newRateLimiter(rate, burst int) *rateLimiter {
+	if rate == 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: float64(rate), burst: float64(burst), tokens: float64(burst)}
+}
+
+// allow reports whether a query is allowed at the given time, always if the
+// limiter is nil
+func (l *rateLimiter) allow(now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
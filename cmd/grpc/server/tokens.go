@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/si-co/vpir-code/lib/logging"
+	"github.com/si-co/vpir-code/lib/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// queryMethod is the full name of the queries, which redeem a token each
+const queryMethod = "/proto.VPIR/Query"
+
+// tokenAuth returns the interceptor rejecting the queries that do not redeem
+// a valid anonymous token not spent yet. The other calls are passed through.
+func tokenAuth(logger *logging.Logger, r *token.Redeemer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if info.FullMethod != queryMethod {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		tokens := md.Get(token.MetadataKey)
+		if len(tokens) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "missing anonymous token")
+		}
+		switch err := r.Redeem([]byte(tokens[0])); {
+		case errors.Is(err, token.ErrInvalidToken) || errors.Is(err, token.ErrSpentToken):
+			logger.Warn("rejected query", logging.F("err", err))
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case errors.Is(err, token.ErrTooManySpent):
+			logger.Error("token key exhausted", logging.F("err", err))
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		case err != nil:
+			logger.Error("impossible to redeem the token", logging.F("err", err))
+			return nil, status.Error(codes.Internal, "impossible to redeem the token")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -15,18 +15,20 @@ import (
 )
 
 const hundredMb = 104857600
-const usage = `go run main.go {-rabalanced} -cmd genChunks|genDB|parseDump -path PATH -out PATH`
+const usage = `go run main.go {-rabalanced} -cmd genChunks|genDB|parseDump|importDump -path PATH -out PATH {-state PATH}`
 
 func main() {
 	var cmd string
 	var path string
 	var out string
 	var rebalanced bool
+	var state string
 
-	flag.StringVar(&cmd, "cmd", "", "genChunks|genDB|parseDump")
+	flag.StringVar(&cmd, "cmd", "", "genChunks|genDB|parseDump|importDump")
 	flag.StringVar(&path, "path", "", "input file")
 	flag.StringVar(&out, "out", "", "output file/folder")
 	flag.BoolVar(&rebalanced, "rebalanced", false, "rebalanced db or not")
+	flag.StringVar(&state, "state", "", "state file of importDump to resume an interrupted import, out/import-state.json by default")
 
 	flag.Parse()
 
@@ -52,6 +54,14 @@ func main() {
 		if err != nil {
 			log.Fatalf("failed to parse SKS key dump: %v", err)
 		}
+	case "importDump":
+		if state == "" {
+			state = filepath.Join(out, "import-state.json")
+		}
+		err := importDump(path, out, state)
+		if err != nil {
+			log.Fatalf("failed to import key dump: %v", err)
+		}
 	default:
 		log.Fatalf("unknown command: %s", cmd)
 	}
@@ -75,6 +85,31 @@ func parseSksDump(path, out string) error {
 	return nil
 }
 
+// importDump imports the binary or ASCII-armored dumps of the path, either a
+// file or a directory, into chunks ready to be embedded in the db
+func importDump(path, out, state string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = pgp.GetAllFiles(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	stats, err := pgp.ImportDumps(files, out, state)
+	if err != nil {
+		return err
+	}
+	log.Printf("Imported %d keys, %d filtered and %d malformed\n",
+		stats.Imported, stats.Filtered, stats.Malformed)
+
+	return nil
+}
+
 func splitFullDumpIntoChunks(path, out string) error {
 	f, err := os.Open(path)
 	if err != nil {
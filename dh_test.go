@@ -0,0 +1,37 @@
+package main
+
+// Test suite for the single-server authenticated scheme based on DDH
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDH(t *testing.T) {
+	db := database.CreateRandomEllipticWithDigest(utils.RandomPRG(), 64, group.P256, true)
+	s := server.NewDH(db)
+
+	// the first and the last entries too, whose scalars are selected in
+	// constant time like the others
+	indices := []int{0, db.NumRows*db.NumColumns - 1}
+	for k := 0; k < 4; k++ {
+		indices = append(indices, rand.Intn(db.NumRows*db.NumColumns))
+	}
+	for _, i := range indices {
+		c := client.NewDH(utils.RandomPRG(), &db.Info)
+		query, err := c.QueryBytes(i)
+		require.NoError(t, err)
+		answer, err := s.AnswerBytes(query)
+		require.NoError(t, err)
+		res, err := c.ReconstructBytes(answer)
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i], res.(byte), "entry %d", i)
+	}
+}
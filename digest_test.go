@@ -0,0 +1,71 @@
+package main
+
+// Test suite for the authenticated schemes against a malicious majority,
+// where the client holds the digest of the db
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeldDigestMerkle(t *testing.T) {
+	dbLen := oneKB * 8
+	numServers := 3
+	db := database.CreateRandomMerkle(utils.RandomPRG(), dbLen, 1, testBlockLength)
+	// all the servers serve a forged db, consistent with its own root
+	forged := database.CreateRandomMerkle(utils.RandomPRG(), dbLen, 1, testBlockLength)
+
+	held, err := server.Digest(server.NewPIR(db))
+	require.NoError(t, err)
+	digest := client.HeldDigest(held)
+	require.NoError(t, digest.Pin(&db.Info))
+	require.EqualError(t, digest.Pin(&forged.Info), "REJECT!")
+
+	// without the held digest, the client accepts the forged blocks
+	c := client.NewPIR(utils.RandomPRG(), &forged.Info)
+	servers := make([]*server.PIR, numServers)
+	for k := range servers {
+		servers[k] = server.NewPIR(forged)
+	}
+	in := make([]byte, 4)
+	binary.BigEndian.PutUint32(in, 5)
+	queries, err := c.QueryBytes(in, numServers)
+	require.NoError(t, err)
+	answers := make([][]byte, numServers)
+	for k, s := range servers {
+		answers[k], err = s.AnswerBytes(queries[k])
+		require.NoError(t, err)
+	}
+	_, err = c.ReconstructBytes(answers)
+	require.NoError(t, err)
+
+	// the unauthenticated dbs have no digest to hold
+	_, err = server.Digest(server.NewPIR(database.CreateRandomBytes(utils.RandomPRG(), dbLen, 1, testBlockLength)))
+	require.Error(t, err)
+}
+
+func TestHeldDigestSingleServer(t *testing.T) {
+	dh := database.CreateRandomEllipticWithDigest(utils.RandomPRG(), oneKB, group.P256, true)
+	forgedDH := database.CreateRandomEllipticWithDigest(utils.RandomPRG(), oneKB, group.P256, true)
+	held, err := database.ClientDigest(&dh.Info)
+	require.NoError(t, err)
+	require.NoError(t, client.HeldDigest(held).Pin(&dh.Info))
+	require.Error(t, client.HeldDigest(held).Pin(&forgedDH.Info))
+
+	lwe := database.CreateRandomBinaryLWEWithLength(utils.RandomPRG(), oneKB)
+	forgedLWE := database.CreateRandomBinaryLWEWithLength(utils.RandomPRG(), oneKB)
+	held, err = database.ClientDigest(&lwe.Info)
+	require.NoError(t, err)
+	require.NoError(t, client.HeldDigest(held).Pin(&lwe.Info))
+	require.Error(t, client.HeldDigest(held).Pin(&forgedLWE.Info))
+
+	// the digests of different schemes never match
+	require.Error(t, client.HeldDigest(held).Pin(&dh.Info))
+}
@@ -0,0 +1,104 @@
+package main
+
+// Test suite for the two-server distributed ORAM scheme
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDORAM(t *testing.T) {
+	numBlocks := 64
+	db := database.NewDORAM(numBlocks, testBlockLength)
+	seed := new(utils.PRGKey)
+	_, err := utils.RandomPRG().Read(seed[:])
+	require.NoError(t, err)
+	servers := make([]*server.DORAM, 2)
+	for k := range servers {
+		// the servers hold replicas of the db
+		replica := database.NewDORAM(numBlocks, testBlockLength)
+		servers[k], err = server.NewDORAM(replica, byte(k), seed)
+		require.NoError(t, err)
+	}
+	auditors := []auditor{servers[0], servers[1]}
+	c := client.NewDORAM(utils.RandomPRG(), &db.Info)
+
+	expected := make([][]byte, numBlocks)
+	for i := range expected {
+		expected[i] = make([]byte, testBlockLength)
+	}
+	for epoch := 0; epoch < 3; epoch++ {
+		// writes to distinct blocks, and a dummy write
+		for _, i := range []int{epoch, 10 + epoch, 63 - epoch} {
+			block := make([]byte, testBlockLength)
+			_, err := utils.RandomPRG().Read(block)
+			require.NoError(t, err)
+			writes, err := c.WriteBytes(i, block)
+			require.NoError(t, err)
+			require.NoError(t, audit(auditors, writes))
+			expected[i] = block
+		}
+		writes, err := c.DummyWriteBytes()
+		require.NoError(t, err)
+		require.NoError(t, audit(auditors, writes))
+		require.NoError(t, endEpoch(servers, 3, 0))
+
+		for i := 0; i < numBlocks; i += 3 {
+			require.Equal(t, expected[i], retrieveDORAM(t, c, servers, i))
+		}
+	}
+
+	// colliding writes leave the block unchanged
+	for k := 0; k < 2; k++ {
+		writes, err := c.WriteBytes(5, make([]byte, testBlockLength))
+		require.NoError(t, err)
+		require.NoError(t, audit(auditors, writes))
+	}
+	require.NoError(t, endEpoch(servers, 0, 1))
+	require.Equal(t, expected[5], retrieveDORAM(t, c, servers, 5))
+
+	// malformed writes are rejected
+	_, err = c.WriteBytes(numBlocks, expected[0])
+	require.Error(t, err)
+	_, err = c.WriteBytes(0, expected[0][1:])
+	require.Error(t, err)
+	_, _, err = servers[0].Merge(nil)
+	require.Error(t, err)
+}
+
+// endEpoch merges the writes of the epoch between the servers
+func endEpoch(servers []*server.DORAM, written, collisions int) error {
+	shares := [][]byte{servers[0].EndEpoch(), servers[1].EndEpoch()}
+	for k, s := range servers {
+		w, c, err := s.Merge(shares[1-k])
+		if err != nil {
+			return err
+		}
+		if w != written || c != collisions {
+			return fmt.Errorf("%d blocks written and %d collisions, expected %d and %d", w, c, written, collisions)
+		}
+	}
+	return nil
+}
+
+func retrieveDORAM(t *testing.T, c *client.DORAM, servers []*server.DORAM, index int) []byte {
+	in := make([]byte, 4)
+	binary.BigEndian.PutUint32(in, uint32(index))
+	queries, err := c.QueryBytes(in, 2)
+	require.NoError(t, err)
+	answers := make([][]byte, 2)
+	for k, s := range servers {
+		answers[k], err = s.AnswerBytes(queries[k])
+		require.NoError(t, err)
+	}
+	res, err := c.ReconstructBytes(answers)
+	require.NoError(t, err)
+	return res.([]byte)
+}
@@ -0,0 +1,70 @@
+package main
+
+// Test suite for the keyword-PIR scheme based on constant-weight codes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeywordPIR(t *testing.T) {
+	rnd := utils.RandomPRG()
+	records := make(map[string][]byte)
+	for i := 0; i < 200; i++ {
+		value := make([]byte, i%37)
+		_, err := rnd.Read(value)
+		require.NoError(t, err)
+		records[fmt.Sprintf("user%d@example.org", i)] = value
+	}
+
+	for _, weight := range []int{2, 3} {
+		db, err := database.NewKeyword(records, weight, 0)
+		require.NoError(t, err)
+		servers := make([]*server.Keyword, weight+1)
+		for k := range servers {
+			servers[k], err = server.NewKeyword(db, k)
+			require.NoError(t, err)
+		}
+		c := client.NewKeyword(utils.RandomPRG(), &db.Info)
+
+		for _, keyword := range []string{"user0@example.org", "user17@example.org", "user199@example.org"} {
+			value, err := retrieveKeyword(c, servers, keyword)
+			require.NoError(t, err)
+			require.Equal(t, records[keyword], value)
+		}
+		_, err = retrieveKeyword(c, servers, "nobody@example.org")
+		require.ErrorIs(t, err, client.ErrKeywordNotFound)
+
+		// the scheme needs exactly weight+1 servers
+		_, err = c.QueryBytes([]byte("user0@example.org"), weight)
+		require.Error(t, err)
+	}
+
+	// codewords too short for the records collide
+	_, err := database.NewKeyword(records, 2, 10)
+	require.Error(t, err)
+}
+
+func retrieveKeyword(c *client.Keyword, servers []*server.Keyword, keyword string) ([]byte, error) {
+	queries, err := c.QueryBytes([]byte(keyword), len(servers))
+	if err != nil {
+		return nil, err
+	}
+	answers := make([][]byte, len(servers))
+	for k, s := range servers {
+		if answers[k], err = s.AnswerBytes(queries[k]); err != nil {
+			return nil, err
+		}
+	}
+	value, err := c.ReconstructBytes(answers)
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+// Test suite for the classical PIR scheme over a db authenticated with a
+// polynomial commitment
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIRKZG(t *testing.T) {
+	dbLen := oneKB * 4
+	blockLen := 40
+	nRows := 4
+
+	db := database.CreateRandomKZG(utils.RandomPRG(), dbLen, nRows, blockLen)
+	c := client.NewPIR(utils.RandomPRG(), &db.Info)
+	servers := []*server.PIR{server.NewPIR(db), server.NewPIR(db)}
+
+	bs := db.BlockSize
+	numBlocks := db.NumRows * db.NumColumns
+	for i := 0; i < numBlocks; i += 3 {
+		res, err := retrieveKZG(c, servers, i, false)
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i*bs:i*bs+blockLen], res)
+	}
+
+	// a corrupted answer is detected
+	_, err := retrieveKZG(c, servers, 5, true)
+	require.EqualError(t, err, "REJECT!")
+}
+
+// retrieveKZG retrieves the block of the given index, flipping a bit of the
+// answer of the last server if corrupt is set
+func retrieveKZG(c *client.PIR, servers []*server.PIR, i int, corrupt bool) ([]byte, error) {
+	in := make([]byte, 4)
+	binary.BigEndian.PutUint32(in, uint32(i))
+	queries, err := c.QueryBytes(in, len(servers))
+	if err != nil {
+		return nil, err
+	}
+	answers := make([][]byte, len(servers))
+	for k, s := range servers {
+		if answers[k], err = s.AnswerBytes(queries[k]); err != nil {
+			return nil, err
+		}
+	}
+	if corrupt {
+		// every block of the answer is corrupted
+		for j := range answers[len(servers)-1] {
+			answers[len(servers)-1][j] ^= 1
+		}
+	}
+	res, err := c.ReconstructBytes(answers)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]byte), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+// Test suite for the single-server lattice PIR scheme
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/rlwe"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatticeSingle(t *testing.T) {
+	params := rlwe.DefaultParams()
+	retrieveLatticeSingle(t, params, oneKB*16)
+}
+
+func TestLatticeSingleOneDimension(t *testing.T) {
+	params, err := rlwe.NewParams(12, rlwe.DefaultParams().Q, 12, 24, 1)
+	require.NoError(t, err)
+	retrieveLatticeSingle(t, params, oneKB*4)
+}
+
+func retrieveLatticeSingle(t *testing.T, params *rlwe.Params, dbLen int) {
+	data := make([]byte, dbLen)
+	_, err := utils.RandomPRG().Read(data)
+	require.NoError(t, err)
+	db, err := database.NewLatticeSingle(data, params)
+	require.NoError(t, err)
+
+	s := server.NewLatticeSingle(db, params)
+	c := client.NewLatticeSingle(utils.RandomPRG(), &db.Info, params)
+
+	// the queries are rejected until the client registers its keys
+	q, err := c.QueryBytes(0)
+	require.NoError(t, err)
+	_, err = s.AnswerBytes(q)
+	require.Error(t, err)
+	require.NoError(t, s.AddKeys(c.EvaluationKeysBytes()))
+
+	blocks := (len(data) + params.N() - 1) / params.N()
+	for i := 0; i < blocks; i += 7 {
+		q, err := c.QueryBytes(i)
+		require.NoError(t, err)
+		a, err := s.AnswerBytes(q)
+		require.NoError(t, err)
+		block, err := c.ReconstructBytes(a)
+		require.NoError(t, err)
+		require.Equal(t, db.Block(i), block)
+	}
+
+	// malformed queries are rejected
+	_, err = s.AnswerBytes(q[:len(q)-1])
+	require.Error(t, err)
+}
+
+func TestLatticeMerkle(t *testing.T) {
+	params := rlwe.DefaultParams()
+	blockLen := 512
+	data := make([]byte, oneKB*4)
+	_, err := utils.RandomPRG().Read(data)
+	require.NoError(t, err)
+	db, err := database.NewLatticeMerkle(data, blockLen, params)
+	require.NoError(t, err)
+
+	s := server.NewLatticeSingle(db, params)
+	c := client.NewLatticeMerkle(utils.RandomPRG(), &db.Info, params)
+	require.NoError(t, s.AddKeys(c.EvaluationKeysBytes()))
+
+	for i := 0; i < len(data)/blockLen; i += 5 {
+		q, err := c.QueryBytes(i)
+		require.NoError(t, err)
+		a, err := s.AnswerBytes(q)
+		require.NoError(t, err)
+		block, err := c.ReconstructBytes(a)
+		require.NoError(t, err)
+		require.Equal(t, data[i*blockLen:(i+1)*blockLen], block)
+	}
+
+	// a block swapped with another one or tampered with is rejected
+	q, err := c.QueryBytes(3)
+	require.NoError(t, err)
+	copy(db.Block(3), db.Block(4))
+	s = server.NewLatticeSingle(db, params)
+	require.NoError(t, s.AddKeys(c.EvaluationKeysBytes()))
+	a, err := s.AnswerBytes(q)
+	require.NoError(t, err)
+	_, err = c.ReconstructBytes(a)
+	require.EqualError(t, err, "REJECT!")
+
+	q, err = c.QueryBytes(5)
+	require.NoError(t, err)
+	db.Block(5)[0] ^= 1
+	s = server.NewLatticeSingle(db, params)
+	require.NoError(t, s.AddKeys(c.EvaluationKeysBytes()))
+	a, err = s.AnswerBytes(q)
+	require.NoError(t, err)
+	_, err = c.ReconstructBytes(a)
+	require.EqualError(t, err, "REJECT!")
+
+	// the blocks must be distinct
+	_, err = database.NewLatticeMerkle(make([]byte, 2*blockLen), blockLen, params)
+	require.Error(t, err)
+}
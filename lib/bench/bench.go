@@ -0,0 +1,449 @@
+// Package bench benchmarks the schemes through their byte interface, i.e.,
+// with the exact messages exchanged by the clients and the servers. It is
+// shared by the apir-bench command, the Go benchmarks and the simulations.
+package bench
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/rlwe"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// TunedTECC are the repetitions of the integrity amplification by db length
+// in bits (found via script in /scripts/integrity_amplification.py)
+var TunedTECC = map[int]int{
+	1 << 13: 3,
+	1 << 23: 4,
+	1 << 33: 7,
+}
+
+// Params are the parameters of a benchmarked db
+type Params struct {
+	DBLen      int  // in bits
+	BlockLen   int  // in bytes, only for the schemes with blocks
+	NumServers int  // only for the schemes with any number of servers
+	Rebalanced bool // matrix instead of vector representation
+	// repetitions of the integrity amplification, TunedTECC if zero
+	TECC int
+	// memory layout of the bytes dbs of the PIR schemes in GF(2)
+	Layout database.Layout
+}
+
+// numBlocks returns the number of blocks of BlockLen bytes in the db, at
+// least one
+func (p Params) numBlocks() int {
+	n := p.DBLen / (8 * p.BlockLen)
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+// numRows returns the number of rows of a bytes db with n blocks
+func (p Params) numRows(n int) int {
+	if !p.Rebalanced {
+		return 1
+	}
+	rows, _ := database.CalculateNumRowsAndColumns(n, true)
+	return rows
+}
+
+// Scheme creates the instances of a scheme
+type Scheme struct {
+	Name string
+	// number of servers, zero if the scheme works with any number of them
+	Servers int
+	// true if the scheme retrieves blocks of Params.BlockLen bytes
+	Blocks bool
+	New    func(rnd io.Reader, p Params) (*Instance, error)
+}
+
+// Instance is a db with a client and its servers, ready to retrieve random
+// entries
+type Instance struct {
+	NumServers int
+
+	// query returns the encoded queries to all the servers for a random
+	// entry of the db
+	query       func() ([][]byte, error)
+	answer      func(server int, q []byte) ([]byte, error)
+	reconstruct func(answers [][]byte) error
+}
+
+// Schemes are all the benchmarked schemes, by name
+var Schemes = map[string]*Scheme{
+	"dh":             {Name: "dh", Servers: 1, New: newDH},
+	"lwe":            {Name: "lwe", Servers: 1, New: newLWE},
+	"lwe128":         {Name: "lwe128", Servers: 1, New: newLWE128},
+	"simplepir":      {Name: "simplepir", Servers: 1, New: newSimplePIR},
+	"lattice":        {Name: "lattice", Servers: 1, New: newLatticeSingle},
+	"lattice-merkle": {Name: "lattice-merkle", Servers: 1, Blocks: true, New: newLatticeMerkle},
+	"piano":          {Name: "piano", Servers: 1, Blocks: true, New: newPiano},
+	"amplify":        {Name: "amplify", Servers: 1, New: newAmplify},
+	"pir-classic":    {Name: "pir-classic", Blocks: true, New: newPIRClassic},
+	"pir-merkle":     {Name: "pir-merkle", Blocks: true, New: newPIRMerkle},
+	"pir-kzg":        {Name: "pir-kzg", Blocks: true, New: newPIRKZG},
+	"pir-dpf":        {Name: "pir-dpf", Servers: 2, Blocks: true, New: newPIRDPF},
+	"pir-replicated": {Name: "pir-replicated", Servers: 3, Blocks: true, New: newPIRReplicated},
+	"pir-spir":       {Name: "pir-spir", Blocks: true, New: newSPIR},
+	"offline-online": {Name: "offline-online", Servers: 2, Blocks: true, New: newOfflineOnline},
+	"predicate-pir":  {Name: "predicate-pir", Servers: 2, Blocks: true, New: newPredicatePIR},
+	"predicate-apir": {Name: "predicate-apir", Servers: 2, Blocks: true, New: newPredicateAPIR},
+}
+
+// SchemeNames returns the names of all the schemes, sorted
+func SchemeNames() []string {
+	names := make([]string, 0, len(Schemes))
+	for n := range Schemes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the scheme with the given name
+func Lookup(name string) (*Scheme, error) {
+	s, ok := Schemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheme %q, expected one of %v", name, SchemeNames())
+	}
+	return s, nil
+}
+
+// singleServer returns an instance with one server answering with the given
+// functions
+func singleServer(query func() ([]byte, error), answer func([]byte) ([]byte, error),
+	reconstruct func([]byte) error) *Instance {
+	return &Instance{
+		NumServers: 1,
+		query: func() ([][]byte, error) {
+			q, err := query()
+			return [][]byte{q}, err
+		},
+		answer:      func(_ int, q []byte) ([]byte, error) { return answer(q) },
+		reconstruct: func(a [][]byte) error { return reconstruct(a[0]) },
+	}
+}
+
+func newDH(rnd io.Reader, p Params) (*Instance, error) {
+	db := database.CreateRandomEllipticWithDigest(rnd, p.DBLen, group.P256, p.Rebalanced)
+	c := client.NewDH(rnd, &db.Info)
+	s := server.NewDH(db)
+	return singleServer(
+		func() ([]byte, error) { return c.QueryBytes(rand.Intn(db.NumRows * db.NumColumns)) },
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+func newLWE(rnd io.Reader, p Params) (*Instance, error) {
+	db := database.CreateRandomBinaryLWEWithLength(rnd, p.DBLen)
+	c := client.NewLWE(rnd, &db.Info, utils.ParamsWithDatabaseSize(db.NumRows, db.NumColumns))
+	s := server.NewLWE(db)
+	return singleServer(
+		func() ([]byte, error) { return c.QueryBytes(rand.Intn(db.NumRows * db.NumColumns)) },
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+func newLWE128(rnd io.Reader, p Params) (*Instance, error) {
+	db := database.CreateRandomBinaryLWEWithLength128(rnd, p.DBLen)
+	c := client.NewLWE128(rnd, &db.Info, utils.ParamsWithDatabaseSize128(db.NumRows, db.NumColumns))
+	s := server.NewLWE128(db)
+	return singleServer(
+		func() ([]byte, error) { return c.QueryBytes(rand.Intn(db.NumRows * db.NumColumns)) },
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+// newSimplePIR measures the download of the hint by the client as part of
+// the setup
+func newSimplePIR(rnd io.Reader, p Params) (*Instance, error) {
+	db := database.CreateRandomSimplePIR(rnd, p.DBLen)
+	params := utils.ParamsSimplePIR(db.NumRows, db.NumColumns)
+	s := server.NewSimplePIR(db, params)
+	c, err := client.NewSimplePIR(rnd, &db.Info, params, s.HintBytes())
+	if err != nil {
+		return nil, err
+	}
+	return singleServer(
+		func() ([]byte, error) { return c.QueryBytes(rand.Intn(db.NumRows * db.NumColumns)) },
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+// newLatticeSingle measures the generation of the evaluation keys of the
+// client and their registration with the server as part of the setup
+func newLatticeSingle(rnd io.Reader, p Params) (*Instance, error) {
+	params := rlwe.DefaultParams()
+	db := database.CreateRandomLatticeSingle(rnd, p.DBLen, params)
+	s := server.NewLatticeSingle(db, params)
+	c := client.NewLatticeSingle(rnd, &db.Info, params)
+	if err := s.AddKeys(c.EvaluationKeysBytes()); err != nil {
+		return nil, err
+	}
+	return singleServer(
+		func() ([]byte, error) { return c.QueryBytes(rand.Intn(db.NumRows * db.NumColumns)) },
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+// newLatticeMerkle is newLatticeSingle over a db of blocks authenticated by a
+// Merkle tree
+func newLatticeMerkle(rnd io.Reader, p Params) (*Instance, error) {
+	params := rlwe.DefaultParams()
+	db := database.CreateRandomLatticeMerkle(rnd, p.DBLen, p.BlockLen, params)
+	s := server.NewLatticeSingle(db, params)
+	c := client.NewLatticeMerkle(rnd, &db.Info, params)
+	if err := s.AddKeys(c.EvaluationKeysBytes()); err != nil {
+		return nil, err
+	}
+	return singleServer(
+		func() ([]byte, error) { return c.QueryBytes(rand.Intn(p.numBlocks())) },
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+// newPiano measures the preprocessing of the db by the client as part of the
+// setup. The client preprocesses the db again when it runs out of hints, as
+// part of the query. The db is always a square matrix, whatever the
+// representation in the params.
+func newPiano(rnd io.Reader, p Params) (*Instance, error) {
+	numRows, _ := database.CalculateNumRowsAndColumns(p.numBlocks(), true)
+	db := database.CreateRandomBytes(rnd, p.DBLen, numRows, p.BlockLen)
+	s := server.NewPiano(db)
+	c := client.NewPiano(rnd, &db.Info, 0, 0)
+	if err := c.Preprocess(s.ChunkBytes); err != nil {
+		return nil, err
+	}
+	return singleServer(
+		func() ([]byte, error) {
+			index := rand.Intn(db.NumRows * db.NumColumns)
+			q, err := c.QueryBytes(index)
+			if errors.Is(err, client.ErrHintsExhausted) {
+				if err := c.Preprocess(s.ChunkBytes); err != nil {
+					return nil, err
+				}
+				return c.QueryBytes(index)
+			}
+			return q, err
+		},
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+func newAmplify(rnd io.Reader, p Params) (*Instance, error) {
+	tECC := p.TECC
+	if tECC == 0 {
+		var ok bool
+		if tECC, ok = TunedTECC[p.DBLen]; !ok {
+			return nil, fmt.Errorf("tECC not tuned for db length %d, set it explicitly", p.DBLen)
+		}
+	}
+	db := database.CreateRandomBinaryLWEWithLength(rnd, p.DBLen)
+	c := client.NewAmplify(rnd, &db.Info, utils.ParamsWithDatabaseSize(db.NumRows, db.NumColumns), tECC)
+	s := server.NewAmplify(db)
+	return singleServer(
+		func() ([]byte, error) { return c.QueryBytes(rand.Intn(db.NumRows * db.NumColumns)) },
+		s.AnswerBytes,
+		func(a []byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		}), nil
+}
+
+// multiServer returns an instance with the given client and servers, which
+// retrieves random blocks of the db
+func multiServer(info *database.Info, c client.Client, servers []server.Server) *Instance {
+	return &Instance{
+		NumServers: len(servers),
+		query: func() ([][]byte, error) {
+			in := make([]byte, 4)
+			binary.BigEndian.PutUint32(in, uint32(rand.Intn(info.NumRows*info.NumColumns)))
+			return c.QueryBytes(in, len(servers))
+		},
+		answer: func(i int, q []byte) ([]byte, error) { return servers[i].AnswerBytes(q) },
+		reconstruct: func(a [][]byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		},
+	}
+}
+
+// numServers returns the number of servers of a scheme working with any
+// number of them, two by default
+func (p Params) numServers() int {
+	if p.NumServers == 0 {
+		return 2
+	}
+	return p.NumServers
+}
+
+// randomBytes returns a random bytes db in the layout of the params
+func (p Params) randomBytes(rnd io.Reader) (*database.Bytes, error) {
+	db := database.CreateRandomBytes(rnd, p.DBLen, p.numRows(p.numBlocks()), p.BlockLen)
+	if err := db.SetLayout(p.Layout); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func newPIRClassic(rnd io.Reader, p Params) (*Instance, error) {
+	db, err := p.randomBytes(rnd)
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]server.Server, p.numServers())
+	for i := range servers {
+		servers[i] = server.NewPIR(db)
+	}
+	return multiServer(&db.Info, client.NewPIR(rnd, &db.Info), servers), nil
+}
+
+func newPIRMerkle(rnd io.Reader, p Params) (*Instance, error) {
+	db := database.CreateRandomMerkle(rnd, p.DBLen, p.numRows(p.numBlocks()), p.BlockLen)
+	if err := db.SetLayout(p.Layout); err != nil {
+		return nil, err
+	}
+	servers := make([]server.Server, p.numServers())
+	for i := range servers {
+		servers[i] = server.NewPIR(db)
+	}
+	return multiServer(&db.Info, client.NewPIR(rnd, &db.Info), servers), nil
+}
+
+func newPIRKZG(rnd io.Reader, p Params) (*Instance, error) {
+	db := database.CreateRandomKZG(rnd, p.DBLen, p.numRows(p.numBlocks()), p.BlockLen)
+	if err := db.SetLayout(p.Layout); err != nil {
+		return nil, err
+	}
+	servers := make([]server.Server, p.numServers())
+	for i := range servers {
+		servers[i] = server.NewPIR(db)
+	}
+	return multiServer(&db.Info, client.NewPIR(rnd, &db.Info), servers), nil
+}
+
+func newPIRDPF(rnd io.Reader, p Params) (*Instance, error) {
+	db, err := p.randomBytes(rnd)
+	if err != nil {
+		return nil, err
+	}
+	servers := []server.Server{server.NewPIRDPF(db), server.NewPIRDPF(db)}
+	return multiServer(&db.Info, client.NewPIRDPF(rnd, &db.Info), servers), nil
+}
+
+// newSPIR always uses the vector representation of the db, the only one of
+// the symmetric mode
+func newSPIR(rnd io.Reader, p Params) (*Instance, error) {
+	db := database.CreateRandomBytes(rnd, p.DBLen, 1, p.BlockLen)
+	db.SPIR = true
+	seed := utils.RandomPRGKey()
+	servers := make([]server.Server, p.numServers())
+	for i := range servers {
+		s, err := server.NewSPIR(db, seed, i, len(servers))
+		if err != nil {
+			return nil, err
+		}
+		servers[i] = s
+	}
+	return multiServer(&db.Info, client.NewPIR(rnd, &db.Info), servers), nil
+}
+
+func newPIRReplicated(rnd io.Reader, p Params) (*Instance, error) {
+	db, err := p.randomBytes(rnd)
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]server.Server, client.ReplicatedServers)
+	for i := range servers {
+		servers[i] = server.NewPIRReplicated(db)
+	}
+	return multiServer(&db.Info, client.NewPIRReplicated(rnd, &db.Info), servers), nil
+}
+
+// newOfflineOnline measures the offline phase, i.e., the streaming of the
+// hints by the first server, as part of the setup. The db is always a square
+// matrix, whatever the representation in the params.
+func newOfflineOnline(rnd io.Reader, p Params) (*Instance, error) {
+	numRows, _ := database.CalculateNumRowsAndColumns(p.numBlocks(), true)
+	db := database.CreateRandomBytes(rnd, p.DBLen, numRows, p.BlockLen)
+	s := server.NewOfflineOnline(db)
+	c := client.NewOfflineOnline(rnd, &db.Info, 0)
+	hints, err := s.HintsBytes(c.OfflineQuery())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.SetHints(hints); err != nil {
+		return nil, err
+	}
+	return multiServer(&db.Info, c, []server.Server{s, server.NewOfflineOnline(db)}), nil
+}
+
+// predicate returns an instance counting the keys of a random db with one
+// key per block that use RSA
+func predicate(db *database.DB, c client.Client, servers []server.Server) (*Instance, error) {
+	info := &query.Info{Target: query.PubKeyAlgo}
+	in, err := info.ToPKAClientFSS("RSA").Encode()
+	if err != nil {
+		return nil, err
+	}
+	return &Instance{
+		NumServers: len(servers),
+		query:      func() ([][]byte, error) { return c.QueryBytes(in, len(servers)) },
+		answer:     func(i int, q []byte) ([]byte, error) { return servers[i].AnswerBytes(q) },
+		reconstruct: func(a [][]byte) error {
+			_, err := c.ReconstructBytes(a)
+			return err
+		},
+	}, nil
+}
+
+func newPredicatePIR(rnd io.Reader, p Params) (*Instance, error) {
+	db, err := database.CreateRandomKeysDB(rnd, p.numBlocks())
+	if err != nil {
+		return nil, err
+	}
+	servers := []server.Server{server.NewPredicatePIR(db, 0), server.NewPredicatePIR(db, 1)}
+	return predicate(db, client.NewPredicatePIR(rnd, &db.Info), servers)
+}
+
+func newPredicateAPIR(rnd io.Reader, p Params) (*Instance, error) {
+	db, err := database.CreateRandomKeysDB(rnd, p.numBlocks())
+	if err != nil {
+		return nil, err
+	}
+	servers := []server.Server{server.NewPredicateAPIR(db, 0), server.NewPredicateAPIR(db, 1)}
+	return predicate(db, client.NewPredicateAPIR(rnd, &db.Info), servers)
+}
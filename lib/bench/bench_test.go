@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemes(t *testing.T) {
+	p := Params{DBLen: 1 << 13, BlockLen: 16, NumServers: 3, Rebalanced: true}
+	report := new(Report)
+	for _, name := range SchemeNames() {
+		s, err := Lookup(name)
+		require.NoError(t, err)
+		res, err := Run(s, utils.RandomPRG(), p, 2)
+		require.NoError(t, err, name)
+
+		if s.Servers > 0 {
+			require.Equal(t, s.Servers, res.Params.NumServers, name)
+		} else {
+			require.Equal(t, p.NumServers, res.Params.NumServers, name)
+		}
+		require.Positive(t, res.Bandwidth.Query.Mean, name)
+		require.Positive(t, res.Bandwidth.Answer.Mean, name)
+		report.Results = append(report.Results, res)
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, report.WriteCSV(buf))
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	// header, then two setup rows and three metrics of three phases
+	require.Len(t, records, 1+len(Schemes)*(2+3*3))
+
+	_, err = Lookup("unknown")
+	require.Error(t, err)
+}
+
+func TestComputeStats(t *testing.T) {
+	s := ComputeStats([]float64{4, 1, 3, 2})
+	require.Equal(t, 2.5, s.Mean)
+	require.Equal(t, 2.5, s.Median)
+	require.InDelta(t, 1.2910, s.StdDev, 1e-4)
+	require.InDelta(t, 3.85, s.P95, 1e-9)
+
+	require.Equal(t, Stats{}, ComputeStats(nil))
+}
+
+func TestFilterOutliers(t *testing.T) {
+	values := []float64{10, 11, 9, 10, 10, 100}
+	kept, outliers := FilterOutliers(values, 3)
+	require.Equal(t, 1, outliers)
+	require.NotContains(t, kept, 100.0)
+
+	kept, outliers = FilterOutliers(values, 0)
+	require.Equal(t, values, kept)
+	require.Zero(t, outliers)
+}
+
+func TestCompare(t *testing.T) {
+	p := Params{DBLen: 1 << 13, BlockLen: 16, NumServers: 2}
+	result := func(scheme string, cpu, bandwidth float64) *Result {
+		return &Result{
+			Scheme:    scheme,
+			Params:    p,
+			CPU:       Phases{Query: Stats{Mean: cpu}, Answer: Stats{Mean: cpu}},
+			Bandwidth: Phases{Query: Stats{Mean: bandwidth}, Answer: Stats{Mean: bandwidth}},
+		}
+	}
+	baseline := &Report{Results: []*Result{result("dh", 1, 100), result("lwe", 1, 100)}}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, baseline.WriteJSON(buf))
+	read, err := ReadReport(buf)
+	require.NoError(t, err)
+	require.Equal(t, baseline.Results, read.Results)
+
+	current := &Report{Results: []*Result{result("dh", 1.05, 100), result("lwe", 1, 150), result("amplify", 5, 500)}}
+	regressions, compared := current.Compare(baseline, 0.1)
+	require.Equal(t, 2, compared)
+	require.Len(t, regressions, 2)
+	for i, phase := range []string{"query", "answer"} {
+		require.Equal(t, "lwe", regressions[i].Scheme)
+		require.Equal(t, "bandwidth", regressions[i].Metric)
+		require.Equal(t, phase, regressions[i].Phase)
+		require.Equal(t, 150.0, regressions[i].Current)
+	}
+
+	regressions, _ = current.Compare(baseline, 0.01)
+	require.Len(t, regressions, 4)
+}
@@ -0,0 +1,78 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Regression is a metric of a result that got worse than in the baseline
+type Regression struct {
+	Scheme   string
+	Params   Params
+	Metric   string
+	Phase    string
+	Baseline float64
+	Current  float64
+}
+
+func (r *Regression) String() string {
+	return fmt.Sprintf("%s (db length %d, block length %d, %d servers): %s %s %.4g -> %.4g (%+.1f%%)",
+		r.Scheme, r.Params.DBLen, r.Params.BlockLen, r.Params.NumServers, r.Metric, r.Phase,
+		r.Baseline, r.Current, 100*(r.Current-r.Baseline)/r.Baseline)
+}
+
+// ReadReport reads a report written by WriteJSON
+func ReadReport(r io.Reader) (*Report, error) {
+	report := new(Report)
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Compare compares the mean query and answer CPU time and bandwidth of the
+// results of r with the ones of the baseline results with the same scheme and
+// parameters. It returns the metrics that grew by more than threshold, relative
+// to the baseline, and the number of results found in the baseline.
+func (r *Report) Compare(baseline *Report, threshold float64) ([]*Regression, int) {
+	type key struct {
+		scheme string
+		params Params
+	}
+	base := make(map[key]*Result, len(baseline.Results))
+	for _, res := range baseline.Results {
+		base[key{res.Scheme, res.Params}] = res
+	}
+
+	var regressions []*Regression
+	compared := 0
+	for _, res := range r.Results {
+		old, ok := base[key{res.Scheme, res.Params}]
+		if !ok {
+			continue
+		}
+		compared++
+		for _, m := range []struct {
+			name     string
+			old, new Phases
+		}{{"cpu", old.CPU, res.CPU}, {"bandwidth", old.Bandwidth, res.Bandwidth}} {
+			for _, p := range []struct {
+				name     string
+				old, new Stats
+			}{{"query", m.old.Query, m.new.Query}, {"answer", m.old.Answer, m.new.Answer}} {
+				if p.old.Mean > 0 && p.new.Mean > p.old.Mean*(1+threshold) {
+					regressions = append(regressions, &Regression{
+						Scheme:   res.Scheme,
+						Params:   res.Params,
+						Metric:   m.name,
+						Phase:    p.name,
+						Baseline: p.old.Mean,
+						Current:  p.new.Mean,
+					})
+				}
+			}
+		}
+	}
+	return regressions, compared
+}
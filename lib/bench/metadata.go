@@ -0,0 +1,88 @@
+package bench
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata describes the code and the machine that produced the results of
+// the simulations and the benchmarks, so that archived results can be
+// interpreted and compared
+type Metadata struct {
+	GitRevision string // empty if not run from a git repository
+	GitDirty    bool   // uncommitted changes in the working tree
+	GoVersion   string
+	OS          string
+	Arch        string
+	Hostname    string
+	GOMAXPROCS  int
+	NumCPU      int
+	CPUModel    string
+	MemoryBytes uint64 // physical memory, zero if unknown
+	Start       time.Time
+}
+
+// NewMetadata collects the metadata of the current run. Missing information
+// is left empty rather than aborting the run.
+func NewMetadata() *Metadata {
+	m := &Metadata{
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Start:      time.Now(),
+	}
+	m.Hostname, _ = os.Hostname()
+	m.GitRevision = command("git", "rev-parse", "HEAD")
+	if m.GitRevision != "" {
+		m.GitDirty = command("git", "status", "--porcelain", "--untracked-files=no") != ""
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		m.CPUModel = procField("/proc/cpuinfo", "model name")
+		// MemTotal is in kB
+		if kb, err := strconv.ParseUint(strings.TrimSuffix(procField("/proc/meminfo", "MemTotal"), " kB"), 10, 64); err == nil {
+			m.MemoryBytes = kb * 1024
+		}
+	case "darwin":
+		m.CPUModel = command("sysctl", "-n", "machdep.cpu.brand_string")
+		m.MemoryBytes, _ = strconv.ParseUint(command("sysctl", "-n", "hw.memsize"), 10, 64)
+	}
+
+	return m
+}
+
+// command returns the trimmed output of the command, empty on error
+func command(name string, args ...string) string {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// procField returns the value of the first "key: value" line of a /proc file
+// with the given key, empty if not found
+func procField(file, key string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// phases of a retrieval, in the order of the CSV output
+var phaseNames = []string{"query", "answer", "reconstruct"}
+
+// Sample is the cost of the phases of one retrieval. The servers answer in
+// parallel, so the answer time is the one of the slowest server while the
+// answer bandwidth is summed over all the servers.
+type Sample struct {
+	CPU       [3]float64 // seconds
+	Wall      [3]float64 // seconds
+	Bandwidth [3]float64 // bytes, zero for the reconstruction
+}
+
+// Retrieve retrieves a random entry and measures every phase
+func (in *Instance) Retrieve() (*Sample, error) {
+	s := new(Sample)
+	m := monitor.NewMonitor()
+	record := func(phase int) {
+		cpu, wall := m.RecordAndResetAll()
+		// the monitor measures milliseconds
+		if s.CPU[phase] < cpu/1000 {
+			s.CPU[phase] = cpu / 1000
+		}
+		if s.Wall[phase] < wall/1000 {
+			s.Wall[phase] = wall / 1000
+		}
+	}
+
+	queries, err := in.query()
+	if err != nil {
+		return nil, err
+	}
+	record(0)
+
+	answers := make([][]byte, in.NumServers)
+	for i := range answers {
+		m.Reset()
+		if answers[i], err = in.answer(i, queries[i]); err != nil {
+			return nil, err
+		}
+		record(1)
+		s.Bandwidth[0] += float64(len(queries[i]))
+		s.Bandwidth[1] += float64(len(answers[i]))
+	}
+
+	m.Reset()
+	if err := in.reconstruct(answers); err != nil {
+		return nil, err
+	}
+	record(2)
+
+	return s, nil
+}
+
+// Phases summarizes the phases of all the retrievals
+type Phases struct {
+	Query       Stats
+	Answer      Stats
+	Reconstruct Stats
+}
+
+func newPhases(samples []*Sample, value func(*Sample) [3]float64) Phases {
+	var phases [3]Stats
+	for p := range phases {
+		values := make([]float64, len(samples))
+		for i, s := range samples {
+			values[i] = value(s)[p]
+		}
+		phases[p] = ComputeStats(values)
+	}
+	return Phases{Query: phases[0], Answer: phases[1], Reconstruct: phases[2]}
+}
+
+func (p Phases) stats() []Stats {
+	return []Stats{p.Query, p.Answer, p.Reconstruct}
+}
+
+// Result is the benchmark of a scheme for one set of parameters
+type Result struct {
+	Scheme      string
+	Params      Params
+	Repetitions int
+
+	// creation of the db, the client and the servers
+	SetupWall  float64 // seconds
+	SetupAlloc float64 // bytes allocated
+
+	CPU       Phases // seconds
+	Wall      Phases // seconds
+	Bandwidth Phases // bytes
+}
+
+// Run creates an instance of the scheme and retrieves repetitions random
+// entries
+func Run(s *Scheme, rnd io.Reader, p Params, repetitions int) (*Result, error) {
+	if s.Servers > 0 {
+		p.NumServers = s.Servers
+	} else {
+		p.NumServers = p.numServers()
+	}
+	res := &Result{Scheme: s.Name, Params: p, Repetitions: repetitions}
+
+	m := monitor.NewMonitor()
+	m.TrackMemory(monitor.DefaultSamplingInterval)
+	in, err := s.New(rnd, p)
+	res.SetupAlloc = m.RecordAllocs()
+	m.StopTracking()
+	_, wall := m.RecordAndResetAll()
+	res.SetupWall = wall / 1000
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]*Sample, repetitions)
+	for i := range samples {
+		if samples[i], err = in.Retrieve(); err != nil {
+			return nil, err
+		}
+	}
+	res.CPU = newPhases(samples, func(s *Sample) [3]float64 { return s.CPU })
+	res.Wall = newPhases(samples, func(s *Sample) [3]float64 { return s.Wall })
+	res.Bandwidth = newPhases(samples, func(s *Sample) [3]float64 { return s.Bandwidth })
+
+	return res, nil
+}
+
+// Report gathers the results of one run of the benchmarks
+type Report struct {
+	Metadata *Metadata
+	Results  []*Result
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes one line per result, metric and phase with the summary
+// statistics. The setup is reported as the "setup" metric, with the "wall"
+// and "alloc" phases and its single value as statistics.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"scheme", "dbLen", "blockLen", "servers", "repetitions", "metric", "phase", "mean", "stddev", "median", "p95"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, res := range r.Results {
+		key := []string{res.Scheme, strconv.Itoa(res.Params.DBLen), strconv.Itoa(res.Params.BlockLen),
+			strconv.Itoa(res.Params.NumServers), strconv.Itoa(res.Repetitions)}
+		write := func(metric, phase string, s Stats) error {
+			return cw.Write(append(key[:5:5], metric, phase,
+				formatFloat(s.Mean), formatFloat(s.StdDev), formatFloat(s.Median), formatFloat(s.P95)))
+		}
+
+		single := func(v float64) Stats { return Stats{Mean: v, Median: v, P95: v} }
+		if err := write("setup", "wall", single(res.SetupWall)); err != nil {
+			return err
+		}
+		if err := write("setup", "alloc", single(res.SetupAlloc)); err != nil {
+			return err
+		}
+		for _, metric := range []struct {
+			name   string
+			phases Phases
+		}{{"cpu", res.CPU}, {"wall", res.Wall}, {"bandwidth", res.Bandwidth}} {
+			for i, s := range metric.phases.stats() {
+				if err := write(metric.name, phaseNames[i], s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,87 @@
+package bench
+
+import (
+	"math"
+	"sort"
+)
+
+// Stats summarizes the measurements of one phase over all the repetitions
+type Stats struct {
+	Mean   float64
+	StdDev float64
+	Median float64
+	P95    float64
+}
+
+// ComputeStats returns the summary statistics of values. The standard
+// deviation is the sample one and the percentiles use linear interpolation.
+func ComputeStats(values []float64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+	var sq float64
+	for _, v := range sorted {
+		sq += (v - mean) * (v - mean)
+	}
+	std := 0.0
+	if len(sorted) > 1 {
+		std = math.Sqrt(sq / float64(len(sorted)-1))
+	}
+
+	return Stats{
+		Mean:   mean,
+		StdDev: std,
+		Median: percentile(sorted, 0.5),
+		P95:    percentile(sorted, 0.95),
+	}
+}
+
+// madScale makes the median absolute deviation a consistent estimator of the
+// standard deviation for normally distributed values
+const madScale = 1.4826
+
+// FilterOutliers returns the values whose distance from the median is at most
+// threshold scaled median absolute deviations, and the number of rejected
+// values. All the values are kept if threshold or the deviation is zero.
+func FilterOutliers(values []float64, threshold float64) ([]float64, int) {
+	if threshold <= 0 || len(values) == 0 {
+		return values, 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	median := percentile(sorted, 0.5)
+
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	sort.Float64s(deviations)
+	mad := madScale * percentile(deviations, 0.5)
+	if mad == 0 {
+		return values, 0
+	}
+
+	kept := make([]float64, 0, len(values))
+	for _, v := range values {
+		if math.Abs(v-median) <= threshold*mad {
+			kept = append(kept, v)
+		}
+	}
+	return kept, len(values) - len(kept)
+}
+
+// percentile returns the p-th percentile of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/query"
+)
+
+// Contributor is a client of the two-server private aggregate statistics, in
+// the style of Prio. The client splits its contribution, a vector of bits,
+// in additive shares for the two servers, along with a secret-shared
+// non-interactive proof that every element is a bit, which the servers
+// verify together before adding the share to their share of the aggregate.
+// The servers learn the aggregate of the valid contributions only, as long
+// as they do not collude.
+type Contributor struct {
+	rnd  io.Reader
+	stat *query.Statistic
+	fl   *field.Field
+}
+
+// NewContributor returns a client contributing to the aggregate
+func NewContributor(rnd io.Reader, stat *query.Statistic) *Contributor {
+	return &Contributor{rnd: rnd, stat: stat, fl: field.Default()}
+}
+
+// HistogramContribution returns the contribution to the histogram of the
+// given number of buckets, which counts the bucket of the client
+func HistogramContribution(bucket, buckets int) ([]uint32, error) {
+	if bucket < 0 || bucket >= buckets {
+		return nil, fmt.Errorf("bucket %d out of range", bucket)
+	}
+	bits := make([]uint32, buckets)
+	bits[bucket] = 1
+	return bits, nil
+}
+
+// SumContribution returns the contribution of the value, of the given
+// number of bits, to the sum of the values of the clients
+func SumContribution(value uint64, numBits int) ([]uint32, error) {
+	if numBits < 64 && value>>uint(numBits) != 0 {
+		return nil, fmt.Errorf("value %d does not fit in %d bits", value, numBits)
+	}
+	bits := make([]uint32, numBits)
+	for i := range bits {
+		bits[i] = uint32(value>>uint(i)) & 1
+	}
+	return bits, nil
+}
+
+// ContributeBytes executes Contribute and encodes the shares in bytes
+func (c *Contributor) ContributeBytes(bits []uint32) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	contributions, err := c.Contribute(bits)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, len(contributions))
+	for i, ct := range contributions {
+		if data[i], err = ct.Encode(); err != nil {
+			return nil, err
+		}
+	}
+	monitor.CountQuery(data...)
+
+	return data, nil
+}
+
+// Contribute returns the shares of the contribution for the two servers,
+// along with the shares of the proof of its validity
+func (c *Contributor) Contribute(bits []uint32) ([]*query.Contribution, error) {
+	n := c.stat.Length
+	if len(bits) != n {
+		return nil, fmt.Errorf("contribution of %d bits, expected %d", len(bits), n)
+	}
+	set := 0
+	for _, b := range bits {
+		if b > 1 {
+			return nil, errors.New("contribution element not a bit")
+		}
+		set += int(b)
+	}
+	if c.stat.OneHot && set != 1 {
+		return nil, errors.New("contribution not one-hot")
+	}
+	fl := c.fl
+
+	// f and g interpolate the inputs of the gates, x_t and x_t - 1, at
+	// t = 1, ..., n, and random values at 0
+	f := make([]uint32, 2*n+1)
+	g := make([]uint32, 2*n+1)
+	f[0], g[0] = fl.RandElementWithPRG(c.rnd), fl.RandElementWithPRG(c.rnd)
+	for t, b := range bits {
+		f[t+1], g[t+1] = b, fl.Sub(b, 1)
+	}
+	for x := n + 1; x <= 2*n; x++ {
+		l, err := fl.RangeLagrangeCoefficients(n, uint32(x))
+		if err != nil {
+			return nil, err
+		}
+		for k, lk := range l {
+			f[x] = fl.Add(f[x], fl.Mul(lk, f[k]))
+			g[x] = fl.Add(g[x], fl.Mul(lk, g[k]))
+		}
+	}
+	h := make([]uint32, 2*n+1)
+	for x := range h {
+		h[x] = fl.Mul(f[x], g[x])
+	}
+	a, b := fl.RandElementWithPRG(c.rnd), fl.RandElementWithPRG(c.rnd)
+
+	id := make([]byte, query.ContributionIDLen)
+	if _, err := io.ReadFull(c.rnd, id); err != nil {
+		return nil, err
+	}
+	out := []*query.Contribution{
+		{ID: id, Shares: make([]uint32, n), H: make([]uint32, 2*n+1)},
+		{ID: id, Shares: make([]uint32, n), H: make([]uint32, 2*n+1)},
+	}
+	share := func(v uint32, s0, s1 *uint32) {
+		*s0 = fl.RandElementWithPRG(c.rnd)
+		*s1 = fl.Sub(v, *s0)
+	}
+	for t, bit := range bits {
+		share(bit, &out[0].Shares[t], &out[1].Shares[t])
+	}
+	share(f[0], &out[0].F0, &out[1].F0)
+	share(g[0], &out[0].G0, &out[1].G0)
+	for x, v := range h {
+		share(v, &out[0].H[x], &out[1].H[x])
+	}
+	for i, v := range []uint32{a, b, fl.Mul(a, b)} {
+		share(v, &out[0].Triple[i], &out[1].Triple[i])
+	}
+
+	return out, nil
+}
+
+// ReconstructAggregate returns the aggregate published by the two servers,
+// i.e., the sum of their shares, element by element
+func ReconstructAggregate(stat *query.Statistic, shares [][]byte) ([]uint32, error) {
+	if len(shares) != 2 {
+		return nil, errors.New("the aggregate needs two shares")
+	}
+	fl := field.Default()
+	out := make([]uint32, stat.Length)
+	for _, s := range shares {
+		elements, err := fl.DecodeElements(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements) != stat.Length {
+			return nil, errors.New("malformed share")
+		}
+		fl.AddVectors(out, out, elements)
+	}
+	return out, nil
+}
+
+// DecodeSum returns the sum of the values of the clients from the aggregate
+// of their SumContribution
+func DecodeSum(aggregate []uint32) uint64 {
+	sum := uint64(0)
+	for i, count := range aggregate {
+		sum += uint64(count) << uint(i)
+	}
+	return sum
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/ecc"
 	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/utils"
 )
 
@@ -38,11 +39,14 @@ func (a *Amplify) Query(i, j int) []*matrix.Matrix {
 }
 
 func (a *Amplify) QueryBytes(index int) ([]byte, error) {
+	defer monitor.Region("query").End()
 	i, j := utils.VectorToMatrixIndices(index, a.lwes[0].dbInfo.NumColumns)
 	ms := a.Query(i, j)
 
 	// encode
-	return matrix.MatricesToBytes(ms), nil
+	q := matrix.MatricesToBytes(ms)
+	monitor.CountQuery(q)
+	return q, nil
 }
 
 func (a *Amplify) Reconstruct(answers []*matrix.Matrix) (uint32, error) {
@@ -61,5 +65,7 @@ func (a *Amplify) Reconstruct(answers []*matrix.Matrix) (uint32, error) {
 }
 
 func (a *Amplify) ReconstructBytes(answers []byte) (uint32, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(answers)
 	return a.Reconstruct(matrix.BytesToMatrices(answers))
 }
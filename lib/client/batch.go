@@ -0,0 +1,203 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// maxCuckooEvictions bounds the evictions of the cuckoo hashing of a batch
+const maxCuckooEvictions = 500
+
+// Batch is the client retrieving several blocks at once from a db encoded
+// with a cuckoo batch code. The blocks of a batch are cuckoo-hashed to
+// distinct buckets, and the client sends a query of the wrapped scheme for
+// every bucket, for a dummy block if no block of the batch is in the bucket,
+// so that the servers learn nothing but the size of the batch.
+type Batch struct {
+	rnd     io.Reader
+	dbInfo  *database.Info
+	layout  *database.BatchLayout
+	clients []Client
+
+	// index in the batch of the block retrieved from every bucket, -1 for
+	// the dummies
+	state []int
+}
+
+// NewBatch returns a client of the db with the given info for batches of the
+// given number of blocks, retrieving the blocks of every bucket with the
+// client returned by newClient for the info of the db of the bucket
+func NewBatch(rnd io.Reader, info *database.Info, batchSize int, newClient func(*database.Info) Client) (*Batch, error) {
+	layout, err := database.NewBatchLayout(info, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	clients := make([]Client, len(layout.Infos))
+	for b := range clients {
+		clients[b] = newClient(layout.Infos[b])
+	}
+	return &Batch{
+		rnd:     rnd,
+		dbInfo:  info,
+		layout:  layout,
+		clients: clients,
+	}, nil
+}
+
+// QueryBytes returns the batch queries of the blocks of the given indices,
+// one per server, with the length-prefixed queries of all the buckets
+func (c *Batch) QueryBytes(indices []int, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	assignment, err := c.assign(indices)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketQueries := make([][][]byte, numServers)
+	for k := range bucketQueries {
+		bucketQueries[k] = make([][]byte, len(assignment))
+	}
+	in := make([]byte, 4)
+	for b, i := range assignment {
+		var position int
+		if i >= 0 {
+			position = c.layout.Position(indices[i], b)
+		} else {
+			info := c.layout.Infos[b]
+			if position, err = randIndex(c.rnd, info.NumRows*info.NumColumns); err != nil {
+				return nil, err
+			}
+		}
+		binary.BigEndian.PutUint32(in, uint32(position))
+		queries, err := c.clients[b].QueryBytes(in, numServers)
+		if err != nil {
+			return nil, err
+		}
+		for k := range queries {
+			bucketQueries[k][b] = queries[k]
+		}
+	}
+	c.state = assignment
+
+	out := make([][]byte, numServers)
+	for k := range out {
+		out[k] = utils.JoinMessages(bucketQueries[k])
+	}
+	return out, nil
+}
+
+// ReconstructBytes returns the blocks of the last batch, in the order of
+// their indices, from the batch answers of all the servers
+func (c *Batch) ReconstructBytes(a [][]byte) ([][]byte, error) {
+	defer monitor.Region("reconstruct").End()
+	if c.state == nil {
+		return nil, errors.New("no batch to reconstruct")
+	}
+	bucketAnswers := make([][][]byte, len(a))
+	for k := range a {
+		var err error
+		if bucketAnswers[k], err = utils.SplitMessages(a[k], len(c.state)); err != nil {
+			return nil, err
+		}
+	}
+
+	numBlocks := 0
+	for _, i := range c.state {
+		if i >= 0 {
+			numBlocks++
+		}
+	}
+	blocks := make([][]byte, numBlocks)
+	answers := make([][]byte, len(a))
+	for b, i := range c.state {
+		for k := range answers {
+			answers[k] = bucketAnswers[k][b]
+		}
+		// the dummy queries are reconstructed too, so that the state of
+		// every client is consumed
+		res, err := c.clients[b].ReconstructBytes(answers)
+		if err != nil {
+			return nil, fmt.Errorf("bucket %d: %v", b, err)
+		}
+		if i >= 0 {
+			block, ok := res.([]byte)
+			if !ok {
+				return nil, errors.New("the client does not retrieve blocks")
+			}
+			blocks[i] = block
+		}
+	}
+	c.state = nil
+	return blocks, nil
+}
+
+// assign returns the index in the batch of the block retrieved from every
+// bucket, or -1 if a dummy block is retrieved, with cuckoo hashing
+func (c *Batch) assign(indices []int) ([]int, error) {
+	if len(indices) > c.layout.BatchSize {
+		return nil, fmt.Errorf("batch of %d blocks, at most %d", len(indices), c.layout.BatchSize)
+	}
+	numBlocks := c.dbInfo.NumRows * c.dbInfo.NumColumns
+	seen := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		if index < 0 || index >= numBlocks {
+			return nil, fmt.Errorf("block %d out of range", index)
+		}
+		if seen[index] {
+			return nil, fmt.Errorf("block %d twice in the batch", index)
+		}
+		seen[index] = true
+	}
+
+	assignment := make([]int, len(c.layout.Buckets))
+	for b := range assignment {
+		assignment[b] = -1
+	}
+	for i := range indices {
+		ok, err := c.cuckooInsert(assignment, indices, i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("cuckoo hashing of the batch failed")
+		}
+	}
+	return assignment, nil
+}
+
+// cuckooInsert places the i-th block of the batch in a free bucket of its
+// own, evicting the blocks in the way to one of their other buckets
+func (c *Batch) cuckooInsert(assignment, indices []int, i int) (bool, error) {
+	for e := 0; e < maxCuckooEvictions; e++ {
+		buckets := database.BatchBuckets(indices[i], len(assignment))
+		for _, b := range buckets {
+			if assignment[b] < 0 {
+				assignment[b] = i
+				return true, nil
+			}
+		}
+		k, err := randIndex(c.rnd, len(buckets))
+		if err != nil {
+			return false, err
+		}
+		b := buckets[k]
+		assignment[b], i = i, assignment[b]
+	}
+	return false, nil
+}
+
+// randIndex returns a random integer in [0, n)
+func randIndex(rnd io.Reader, n int) (int, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(rnd, buf); err != nil {
+		return 0, err
+	}
+	// the bias of the reduction is negligible for small n
+	return int(binary.BigEndian.Uint64(buf) % uint64(n)), nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// Bloom is the client of the two-server private membership test in a Bloom
+// filter of the keywords of a db. For every bit of the keyword in the
+// filter, the client sends a DPF key with single-bit outputs to each
+// server, whose answers XOR to the bit, so that the client learns whether
+// the keyword may be in the db before retrieving its block. Skipping the
+// retrieval of a missing keyword reveals to the servers that the keyword
+// is missing, but not the keyword itself. The answers are not
+// authenticated.
+type Bloom struct {
+	dbInfo *database.Info
+	fss    *fss.Fss
+	// number of bits of the last query
+	state int
+}
+
+// NewBloom returns a client of the Bloom filter with the given info
+func NewBloom(info *database.Info) *Bloom {
+	return &Bloom{
+		dbInfo: info,
+		fss:    fss.ClientInitialize(1),
+	}
+}
+
+// QueryBytes returns the encoded DPF keys of the bits of the keyword given
+// as input, one set per server
+func (c *Bloom) QueryBytes(in []byte, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	if invalidQueryInputsFSS(numServers) {
+		return nil, errors.New("the Bloom filter test needs two servers")
+	}
+	keys := c.Query(string(in))
+
+	data := make([][]byte, numServers)
+	for k := range data {
+		data[k] = fss.EncodeKeys(keys[k])
+	}
+	monitor.CountQuery(data...)
+	return data, nil
+}
+
+// Query returns the DPF keys of the bits of the keyword in the filter, one
+// set per server
+func (c *Bloom) Query(keyword string) [][]fss.FssKeyEq2P {
+	positions := database.BloomPositions(keyword, c.dbInfo)
+	numBits := fss.NumBitsForDomain(c.dbInfo.NumColumns)
+	keys := make([][]fss.FssKeyEq2P, 2)
+	f := c.fss.Current()
+	for _, j := range positions {
+		k := f.GenerateTreeBit(fss.IndexToBits(j, numBits))
+		keys[0] = append(keys[0], k[0])
+		keys[1] = append(keys[1], k[1])
+	}
+	c.state = len(positions)
+	return keys
+}
+
+// ReconstructBytes returns true if the keyword may be in the db, and false
+// if it is not
+func (c *Bloom) ReconstructBytes(a [][]byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a...)
+	return c.Reconstruct(a)
+}
+
+// Reconstruct returns true if all the bits of the keyword are set
+func (c *Bloom) Reconstruct(answers [][]byte) (bool, error) {
+	if c.state == 0 {
+		return false, errors.New("no query to reconstruct")
+	}
+	if len(answers) != 2 || len(answers[0]) != c.state || len(answers[1]) != c.state {
+		return false, errors.New("malformed answers")
+	}
+	c.state = 0
+	for i := range answers[0] {
+		if answers[0][i]^answers[1][i] != 1 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/cloudflare/circl/group"
 	"github.com/lukechampine/fastxor"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/kzg"
 	"github.com/si-co/vpir-code/lib/merkle"
 	"github.com/si-co/vpir-code/lib/utils"
 )
@@ -27,18 +29,43 @@ type state struct {
 	alphas []uint32 // four alphas to meet desired soundness
 	a      []uint32 // cointains [1, alpha_i], i = 0, .., 3
 
+	// for multi-server in the 64-bit field
+	alphas64 []uint64
+	a64      []uint64 // cointains [1, alpha_i], i = 0, 1
+
+	// for FSS-based statistics, number of aggregates in the answers
+	aggregates int
+
+	// for SPIR, nonce of the query echoed by the servers
+	nonce []byte
+
+	// pooled vectors of the queries, put back by release
+	buffers []*utils.Buffer
+
 	// for single-server (DH)
 	r  group.Scalar
 	ht group.Element
 }
 
-// decodeAnswer decodes the gob-encoded answers from the servers and return
-// them as slices of field elements.
-func decodeAnswer(in [][]byte) ([][]uint32, error) {
+// release puts the pooled vectors of the queries back to the pool
+func (s *state) release() {
+	for _, b := range s.buffers {
+		b.Release()
+	}
+	s.buffers = nil
+}
+
+// decodeAnswer decodes the answers from the servers and return them as
+// slices of elements of f. Non-canonical encodings are rejected.
+func decodeAnswer(in [][]byte, f *field.Field) ([][]uint32, error) {
 	// decode all the answers one by one
 	answer := make([][]uint32, len(in))
 	for i, a := range in {
-		answer[i] = utils.ByteSliceToUint32Slice(a)
+		var err error
+		answer[i], err = f.DecodeElements(a)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return answer, nil
@@ -69,6 +96,24 @@ func reconstructPIR(answers [][]byte, dbInfo *database.Info, state *state) ([]by
 			return nil, errors.New("REJECT!")
 		}
 
+		return data, nil
+	case "kzg":
+		block, err := reconstructValuePIR(answers, dbInfo, state)
+		if err != nil {
+			return block, err
+		}
+		data := block[:len(block)-kzg.ProofLen]
+
+		// check the opening of the polynomial commitment
+		index := state.ix*dbInfo.NumColumns + state.iy
+		verified, err := kzg.Verify(dbInfo.KZG, index, data, block[len(block)-kzg.ProofLen:])
+		if err != nil {
+			return nil, err
+		}
+		if !verified {
+			return nil, errors.New("REJECT!")
+		}
+
 		return data, nil
 	default:
 		panic("unknown PIRType")
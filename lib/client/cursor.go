@@ -0,0 +1,176 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/query"
+)
+
+// Exchange sends the encoded queries to the servers, one per server, and
+// returns their encoded answers in the same order
+type Exchange func(queries [][]byte) ([][]byte, error)
+
+// Match is a key matching the query of a cursor
+type Match struct {
+	// Index is the index of the key in the db
+	Index int
+	// Values are the values for the key of the aggregates of the query, in
+	// the same order, e.g., its bit length for query.SumBitLength
+	Values []uint32
+}
+
+// Cursor iterates over the keys matching a query to the servers of the
+// authenticated scheme. The first round returns the authenticated count of
+// the matching keys of every bucket of consecutive keys, from which the
+// client learns the number of matches, and every following round fetches
+// the page of the next non-empty bucket, i.e., whether each key of the
+// bucket matches along with its aggregates. The bucket of a page is hidden
+// in the FSS keys, so that the servers learn nothing but the number of
+// pages fetched.
+type Cursor struct {
+	c        *PredicateAPIR
+	q        *query.ClientFSS
+	exchange Exchange
+
+	// authenticated count of the matching keys of every bucket
+	counts []uint32
+	total  int
+	// next bucket to fetch and matches of the last page not returned yet
+	bucket int
+	page   []*Match
+}
+
+// NewCursor returns the cursor over the keys matching the equality query,
+// after the first round of queries sent with the exchange. The servers
+// answer per bucket of bucketSize keys: larger buckets mean fewer pages,
+// each of them larger.
+func (c *PredicateAPIR) NewCursor(q *query.ClientFSS, bucketSize int, exchange Exchange) (*Cursor, error) {
+	switch {
+	case c.dbInfo.UseField64() || c.dbInfo.NoiseEpsilon > 0:
+		return nil, errors.New("cursors not implemented for this db")
+	case q.And || q.Avg || q.Sum || q.Lt || q.Not:
+		return nil, errors.New("cursors not implemented for this query")
+	case bucketSize <= 0 || bucketSize > c.dbInfo.NumColumns:
+		return nil, errors.New("invalid bucket size")
+	}
+
+	info := *q.Info
+	info.BucketSize = bucketSize
+	cur := &Cursor{
+		c:        c,
+		q:        &query.ClientFSS{Info: &info, Input: q.Input},
+		exchange: exchange,
+	}
+
+	// the first round counts the matching keys, the aggregates are in the
+	// pages
+	counting := info
+	counting.Aggregates = nil
+	numBuckets := (c.dbInfo.NumColumns + bucketSize - 1) / bucketSize
+	counts, err := cur.round(&query.ClientFSS{Info: &counting, Input: q.Input}, numBuckets)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		if int(count) > bucketSize {
+			return nil, errors.New("REJECT count")
+		}
+		cur.total += int(count)
+	}
+	cur.counts = counts
+	return cur, nil
+}
+
+// Count returns the number of keys matching the query
+func (cur *Cursor) Count() int {
+	return cur.total
+}
+
+// Next returns the next key matching the query, fetching the page of the
+// next non-empty bucket if needed, and io.EOF after the last one
+func (cur *Cursor) Next() (*Match, error) {
+	for len(cur.page) == 0 {
+		for cur.bucket < len(cur.counts) && cur.counts[cur.bucket] == 0 {
+			cur.bucket++
+		}
+		if cur.bucket == len(cur.counts) {
+			return nil, io.EOF
+		}
+		if err := cur.fetch(cur.bucket); err != nil {
+			return nil, err
+		}
+		cur.bucket++
+	}
+	m := cur.page[0]
+	cur.page = cur.page[1:]
+	return m, nil
+}
+
+// fetch retrieves the page of the bucket, whose matches must be as many as
+// in the count of the first round
+func (cur *Cursor) fetch(bucket int) error {
+	in := cur.q.PageClientFSS(bucket)
+	size := cur.q.BucketSize
+	values, err := cur.round(in, size*len(in.Aggregates))
+	if err != nil {
+		return err
+	}
+
+	var page []*Match
+	for p := 0; p < size; p++ {
+		v := values[p*len(in.Aggregates) : (p+1)*len(in.Aggregates)]
+		switch v[0] {
+		case 0:
+			continue
+		case 1:
+			page = append(page, &Match{Index: bucket*size + p, Values: v[1:]})
+		default:
+			return errors.New("REJECT page")
+		}
+	}
+	if len(page) != int(cur.counts[bucket]) {
+		return fmt.Errorf("page of bucket %d inconsistent with its count", bucket)
+	}
+	cur.page = page
+	return nil
+}
+
+// round sends the queries of the input to the servers and returns the n
+// values of their answers, after checking the tag of each of them
+func (cur *Cursor) round(in *query.ClientFSS, n int) ([]uint32, error) {
+	queries, err := cur.c.query(in, 2)
+	if err != nil {
+		return nil, err
+	}
+	data := make([][]byte, len(queries))
+	for i, q := range queries {
+		if data[i], err = q.Encode(); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := cur.exchange(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) != len(data) {
+		return nil, errors.New("wrong number of answers")
+	}
+	answers, err := decodeAnswer(encoded, cur.c.Fss.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	e := cur.c.executions
+	if len(answers[0]) != n*e || len(answers[1]) != n*e {
+		return nil, errors.New("wrong answer length")
+	}
+	out := make([]uint32, n)
+	for k := range out {
+		if out[k], err = cur.c.reconstructValue(answers[0][k*e:(k+1)*e], answers[1][k*e:(k+1)*e]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"errors"
 	"io"
-	"log"
 
 	"github.com/cloudflare/circl/group"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/utils"
 )
 
@@ -37,6 +37,7 @@ func NewDH(rnd io.Reader, info *database.Info) *DH {
 // QueryBytes takes as input the index of an entry in the database and returns
 // the query for the server encoded in bytes
 func (c *DH) QueryBytes(index int) ([]byte, error) {
+	defer monitor.Region("query").End()
 	g := c.dbInfo.Group
 
 	// sample two random scalars
@@ -50,26 +51,40 @@ func (c *DH) QueryBytes(index int) ([]byte, error) {
 	st.ix, st.iy = utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
 	st.r = r
 
+	// Add the additional blinding t to the retrieval index.
+	// See Construction 9 of the paper. The scalar of every column is r+t
+	// or r+0, selected in constant time, so that the retrieval index does
+	// not leak through the time of the query.
+	tb, err := t.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	zero := make([]byte, len(tb))
+	sb := make([]byte, len(tb))
 	query := make([]group.Element, 0, c.dbInfo.NumColumns*c.dbInfo.BlockSize)
 	for j := 0; j < c.dbInfo.NumColumns; j++ {
-		query = append(query, database.CommitScalarToIndex(r, uint64(j), c.dbInfo.Group))
+		utils.ConstantTimeSelectBytes(utils.ConstantTimeCompare(j, st.iy), sb, tb, zero)
+		s := g.NewScalar()
+		if err := s.UnmarshalBinary(sb); err != nil {
+			return nil, err
+		}
+		query = append(query, database.CommitScalarToIndex(s.Add(s, r), uint64(j), c.dbInfo.Group))
 	}
-
-	// Add the additional blinding t to the retrieval index.
-	// See Construction 9 of the paper.
 	st.ht = database.CommitScalarToIndex(t, uint64(st.iy), g)
-	query[st.iy].Add(query[st.iy], st.ht)
 	c.state = st
 
 	encodedQuery, err := database.MarshalGroupElements(query, c.dbInfo.ElementSize)
 	if err != nil {
 		return nil, err
 	}
+	monitor.CountQuery(encodedQuery)
 
 	return encodedQuery, nil
 }
 
 func (c *DH) ReconstructBytes(a []byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a)
 	g := c.dbInfo.Group
 	digSize := c.dbInfo.ElementSize
 	rneg := g.NewScalar().Neg(c.state.r)
@@ -79,7 +94,7 @@ func (c *DH) ReconstructBytes(a []byte) (interface{}, error) {
 		return nil, err
 	}
 	m := g.Identity()
-	var res byte
+	var res int
 	for i := 0; i < c.dbInfo.NumRows; i++ {
 		// get the row digest and raise it to a power r
 		d := g.NewElement()
@@ -92,17 +107,14 @@ func (c *DH) ReconstructBytes(a []byte) (interface{}, error) {
 		if !m.IsIdentity() && !m.IsEqual(c.state.ht) {
 			return nil, errors.New("reject")
 		}
-		if i == c.state.ix {
-			switch {
-			case m.IsIdentity():
-				res = 0
-			case m.IsEqual(c.state.ht):
-				res = 1
-			default:
-				log.Printf("something wrong, accepted %v\n", m)
-			}
+		// the bit of every row is computed, and the one of the retrieval
+		// row selected in constant time
+		bit := 0
+		if m.IsEqual(c.state.ht) {
+			bit = 1
 		}
+		res = utils.ConstantTimeSelect(utils.ConstantTimeCompare(i, c.state.ix), bit, res)
 	}
 
-	return res, nil
+	return byte(res), nil
 }
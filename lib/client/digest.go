@@ -0,0 +1,30 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/si-co/vpir-code/lib/database"
+)
+
+// HeldDigest is the short digest of an authenticated db, as returned by
+// database.ClientDigest, which the client obtains once from a source it
+// trusts, e.g., the owner of the db or a transparency log, and stores. The
+// clients of the authenticated schemes verify the answers against the info
+// of the db, which the servers could forge consistently if all of them are
+// malicious: pinning the info to the held digest makes the verification
+// sound against any number of malicious servers.
+type HeldDigest []byte
+
+// Pin checks that the info of the db returned by the servers matches the
+// digest, before creating a client over it. It rejects the info otherwise.
+func (d HeldDigest) Pin(info *database.Info) error {
+	digest, err := database.ClientDigest(info)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(digest, d) {
+		return errors.New("REJECT!")
+	}
+	return nil
+}
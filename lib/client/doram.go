@@ -0,0 +1,54 @@
+package client
+
+import (
+	"errors"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/database"
+)
+
+// DORAM is the client of the two-server distributed ORAM scheme over a db
+// created by database.NewDORAM. The client reads a block as the client of
+// the DPF-based classical PIR scheme, and overwrites a block with a write of
+// the private write scheme, applied by the servers at the end of the epoch.
+// A dummy write, to a random block and leaving it unchanged, hides from the
+// servers whether the client writes at all.
+type DORAM struct {
+	*PIRDPF
+	writer *Writer
+}
+
+// NewDORAM returns a client of the distributed ORAM db with the given info
+func NewDORAM(rnd io.Reader, info *database.Info) *DORAM {
+	return &DORAM{
+		PIRDPF: NewPIRDPF(rnd, info),
+		writer: NewWriter(rnd, database.DORAMWriteInfo(info)),
+	}
+}
+
+// WriteBytes returns the encoded writes of the block at the given index, one
+// per server
+func (c *DORAM) WriteBytes(index int, block []byte) ([][]byte, error) {
+	if block == nil {
+		return nil, errors.New("missing block")
+	}
+	return c.write(index, block)
+}
+
+// DummyWriteBytes returns the encoded writes of a dummy write, one per
+// server, which the servers cannot distinguish from a write of a block
+func (c *DORAM) DummyWriteBytes() ([][]byte, error) {
+	index, err := randIndex(c.rnd, c.dbInfo.NumRows*c.dbInfo.NumColumns)
+	if err != nil {
+		return nil, err
+	}
+	return c.write(index, nil)
+}
+
+func (c *DORAM) write(index int, block []byte) ([][]byte, error) {
+	slot, err := database.EncodeDORAMWrite(block, c.dbInfo)
+	if err != nil {
+		return nil, err
+	}
+	return c.writer.WriteBytes(index, slot)
+}
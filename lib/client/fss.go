@@ -1,8 +1,6 @@
 package client
 
 import (
-	"bytes"
-	"encoding/gob"
 	"errors"
 	"io"
 	"log"
@@ -10,6 +8,7 @@ import (
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/field"
 	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/query"
 )
 
@@ -23,63 +22,172 @@ type clientFSS struct {
 }
 
 func (c *clientFSS) queryBytes(in []byte, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
 	inQuery, err := query.DecodeClientFSS(in)
 	if err != nil {
 		return nil, err
 	}
 
-	queries := c.query(inQuery, numServers)
+	queries, err := c.query(inQuery, numServers)
+	if err != nil {
+		return nil, err
+	}
 
 	// encode all the queries in bytes
 	data := make([][]byte, len(queries))
 	for i, q := range queries {
-		buf := new(bytes.Buffer)
-		enc := gob.NewEncoder(buf)
-		if err := enc.Encode(q); err != nil {
+		if data[i], err = q.Encode(); err != nil {
 			return nil, err
 		}
-		data[i] = buf.Bytes()
 	}
+	monitor.CountQuery(data...)
 
 	return data, nil
 }
 
-func (c *clientFSS) query(q *query.ClientFSS, numServers int) []*query.FSS {
+func (c *clientFSS) query(q *query.ClientFSS, numServers int) ([]*query.FSS, error) {
 	if invalidQueryInputsFSS(numServers) {
 		log.Fatal("invalid query inputs")
 	}
 
+	if c.dbInfo.UseField64() {
+		return c.query64(q)
+	}
+
 	// set client state
 	c.state = &state{}
 	c.state.alphas = make([]uint32, c.executions)
 	c.state.a = make([]uint32, c.executions)
 	c.state.a[0] = 1 // to retrieve data
+	c.state.aggregates = len(q.Aggregates)
 	// below executed only for authenticated. The -1 is to ignore
 	// the value for the data already initialized
 	for i := 0; i < c.executions-1; i++ {
-		c.state.alphas[i] = field.RandElementWithPRG(c.rnd)
+		c.state.alphas[i] = c.Fss.Field.RandElementWithPRG(c.rnd)
 		// c.state.a contains [1, alpha_i] for i = 0, .., 3
 		c.state.a[i+1] = c.state.alphas[i]
 	}
 
 	// generate FSS keys
-	fssKeys := c.Fss.GenerateTreePF(q.Input, c.state.a)
+	f := c.Fss.Current()
+	var queries []*query.FSS
+	if q.Lt {
+		fssKeys := f.GenerateTreeLt(q.Input, c.state.a)
+		queries = []*query.FSS{
+			{Info: q.Info, FssKeyLt: fssKeys[0]},
+			{Info: q.Info, FssKeyLt: fssKeys[1]},
+		}
+	} else {
+		fssKeys := f.GenerateTreePF(q.Input, c.state.a)
+		queries = []*query.FSS{
+			{Info: q.Info, FssKey: fssKeys[0]},
+			{Info: q.Info, FssKey: fssKeys[1]},
+		}
+	}
+
+	if c.dbInfo.NoiseEpsilon > 0 || q.Not {
+		c.shareMACKeys(queries)
+	}
+
+	return queries, nil
+}
+
+// shareMACKeys adds to the queries to a db with noisy answers, or negated, a
+// fresh nonce and additive shares of the MAC keys, with which the servers
+// authenticate the values they add to the data, i.e., the noise or the
+// totals. Every share alone is uniform, so that a server learns nothing
+// about the MAC keys.
+func (c *clientFSS) shareMACKeys(queries []*query.FSS) {
+	nonce := make([]byte, database.NoiseNonceLen)
+	if _, err := io.ReadFull(c.rnd, nonce); err != nil {
+		panic(err)
+	}
+	alphas := c.state.alphas[:c.executions-1]
+	first := make([]uint32, len(alphas))
+	second := make([]uint32, len(alphas))
+	for i, alpha := range alphas {
+		first[i] = c.Fss.Field.RandElementWithPRG(c.rnd)
+		second[i] = c.Fss.Field.Sub(alpha, first[i])
+	}
+	queries[0].Nonce, queries[0].MACShares = nonce, first
+	queries[1].Nonce, queries[1].MACShares = nonce, second
+}
+
+// query64 generates the FSS keys for databases working in the 64-bit field
+func (c *clientFSS) query64(q *query.ClientFSS) ([]*query.FSS, error) {
+	if q.And || q.Avg || q.Sum || q.Lt || q.Not || q.BucketSize != 0 {
+		return nil, errors.New("query not implemented for the 64-bit field")
+	}
+	c.state = &state{aggregates: len(q.Aggregates)}
+	c.state.alphas64 = make([]uint64, c.executions-1)
+	c.state.a64 = make([]uint64, c.executions)
+	c.state.a64[0] = 1 // to retrieve data
+	for i := range c.state.alphas64 {
+		c.state.alphas64[i] = field.RandElement64WithPRG(c.rnd)
+		c.state.a64[i+1] = c.state.alphas64[i]
+	}
+
+	f := c.Fss.Current()
+	fssKeys := f.GenerateTreePF64(q.Input, c.state.a64)
 
 	return []*query.FSS{
 		{Info: q.Info, FssKey: fssKeys[0]},
 		{Info: q.Info, FssKey: fssKeys[1]},
-	}
+	}, nil
 }
 
 func (c *clientFSS) reconstructBytes(answers [][]byte) (interface{}, error) {
-	answer, err := decodeAnswer(answers)
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(answers...)
+	if c.dbInfo.UseField64() {
+		answer := make([][]uint64, len(answers))
+		for i, a := range answers {
+			var err error
+			answer[i], err = field.DecodeElements64(a)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if c.state.aggregates > 0 {
+			return c.reconstructAggregates64(answer)
+		}
+		return c.reconstruct64(answer)
+	}
+
+	answer, err := decodeAnswer(answers, c.Fss.Field)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.state.aggregates > 0 {
+		return c.reconstructAggregates(answer)
+	}
+
 	return c.reconstruct(answer)
 }
 
+// reconstructAggregates returns the value of all the aggregates requested in
+// the query, in the same order, after checking the tag of each of them.
+func (c *clientFSS) reconstructAggregates(answers [][]uint32) ([]uint32, error) {
+	if len(answers[0]) != c.state.aggregates*c.executions ||
+		len(answers[1]) != c.state.aggregates*c.executions {
+		return nil, errors.New("wrong answer length")
+	}
+
+	out := make([]uint32, c.state.aggregates)
+	for a := range out {
+		first := answers[0][a*c.executions : (a+1)*c.executions]
+		second := answers[1][a*c.executions : (a+1)*c.executions]
+		value, err := c.reconstructValue(first, second)
+		if err != nil {
+			return nil, err
+		}
+		out[a] = value
+	}
+
+	return out, nil
+}
+
 func (c *clientFSS) reconstruct(answers [][]uint32) (uint32, error) {
 	// AVG case
 	if len(answers[0]) == 2*c.executions {
@@ -88,24 +196,21 @@ func (c *clientFSS) reconstruct(answers [][]uint32) (uint32, error) {
 		sumFirst := answers[0][c.executions:]
 		sumSecond := answers[1][c.executions:]
 
-		dataCount := (countFirst[0] + countSecond[0]) % field.ModP
-		dataCountCasted := uint64(dataCount)
-		sumCount := (sumFirst[0] + sumSecond[0]) % field.ModP
-		sumCountCasted := uint64(sumCount)
+		fl := c.Fss.Field
+		dataCount := fl.Add(countFirst[0], countSecond[0])
+		sumCount := fl.Add(sumFirst[0], sumSecond[0])
 
 		// check tags, executed only for authenticated. The -1 is to ignore
 		// the value for the data already initialized
 		for i := 0; i < c.executions-1; i++ {
-			tmpCount := (dataCountCasted * uint64(c.state.alphas[i])) % uint64(field.ModP)
-			tagCount := uint32(tmpCount)
-			reconstructedTagCount := (countFirst[i+1] + countSecond[i+1]) % field.ModP
+			tagCount := fl.Mul(dataCount, c.state.alphas[i])
+			reconstructedTagCount := fl.Add(countFirst[i+1], countSecond[i+1])
 			if tagCount != reconstructedTagCount {
 				return 0, errors.New("REJECT count")
 			}
 
-			tmpSum := (sumCountCasted * uint64(c.state.alphas[i])) % uint64(field.ModP)
-			tagSum := uint32(tmpSum)
-			reconstructedTagSum := (sumFirst[i+1] + sumSecond[i+1]) % field.ModP
+			tagSum := fl.Mul(sumCount, c.state.alphas[i])
+			reconstructedTagSum := fl.Add(sumFirst[i+1], sumSecond[i+1])
 			if tagSum != reconstructedTagSum {
 				return 0, errors.New("REJECT sum")
 			}
@@ -114,22 +219,75 @@ func (c *clientFSS) reconstruct(answers [][]uint32) (uint32, error) {
 		return sumCount / dataCount, nil
 
 	} else {
-		// compute data
-		data := (answers[0][0] + answers[1][0]) % field.ModP
-		dataCasted := uint64(data)
+		return c.reconstructValue(answers[0], answers[1])
+	}
+}
 
-		// check tags, executed only for authenticated. The -1 is to ignore
-		// the value for the data already initialized
-		for i := 0; i < c.executions-1; i++ {
-			tmp := (dataCasted * uint64(c.state.alphas[i])) % uint64(field.ModP)
-			tag := uint32(tmp)
-			reconstructedTag := (answers[0][i+1] + answers[1][i+1]) % field.ModP
-			if tag != reconstructedTag {
-				return 0, errors.New("REJECT")
-			}
+// reconstructValue sums the shares of the data and, for the authenticated
+// scheme, checks the reconstructed tags against the data. The noise of the
+// servers of a db with noisy answers is authenticated with the data, so
+// that the tags of the noisy data are checked as the ones of exact data.
+func (c *clientFSS) reconstructValue(first, second []uint32) (uint32, error) {
+	// reconstruct data and tags at once
+	sum := make([]uint32, c.executions)
+	c.Fss.Field.AddVectors(sum, first, second)
+	data := sum[0]
+
+	// check tags, executed only for authenticated. The -1 is to ignore
+	// the value for the data already initialized
+	tags := make([]uint32, c.executions-1)
+	c.Fss.Field.AddMulVector(tags, c.state.alphas[:c.executions-1], data)
+	// do not leak which tag is wrong
+	if !field.ConstantTimeEq(tags, sum[1:]) {
+		return 0, errors.New("REJECT")
+	}
+
+	return data, nil
+}
+
+// reconstruct64 returns the data for answers in the 64-bit field, after
+// checking the tags
+func (c *clientFSS) reconstruct64(answers [][]uint64) (uint64, error) {
+	if len(answers[0]) != c.executions || len(answers[1]) != c.executions {
+		return 0, errors.New("wrong answer length")
+	}
+	return c.reconstructValue64(answers[0], answers[1])
+}
+
+// reconstructAggregates64 is the same as reconstructAggregates for answers in
+// the 64-bit field
+func (c *clientFSS) reconstructAggregates64(answers [][]uint64) ([]uint64, error) {
+	if len(answers[0]) != c.state.aggregates*c.executions ||
+		len(answers[1]) != c.state.aggregates*c.executions {
+		return nil, errors.New("wrong answer length")
+	}
+
+	out := make([]uint64, c.state.aggregates)
+	for a := range out {
+		first := answers[0][a*c.executions : (a+1)*c.executions]
+		second := answers[1][a*c.executions : (a+1)*c.executions]
+		value, err := c.reconstructValue64(first, second)
+		if err != nil {
+			return nil, err
 		}
+		out[a] = value
+	}
 
-		return data, nil
+	return out, nil
+}
+
+func (c *clientFSS) reconstructValue64(first, second []uint64) (uint64, error) {
+	data := field.Add64(first[0], second[0])
+	tags := make([]uint64, len(c.state.alphas64))
+	reconstructedTags := make([]uint64, len(c.state.alphas64))
+	for i := range c.state.alphas64 {
+		tags[i] = field.Mul64(data, c.state.alphas64[i])
+		reconstructedTags[i] = field.Add64(first[i+1], second[i+1])
+	}
+	// do not leak which tag is wrong
+	if !field.ConstantTimeEq64(tags, reconstructedTags) {
+		return 0, errors.New("REJECT")
 	}
 
+	return data, nil
 }
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// Hybrid is the client of the two-server hybrid mode over a SimplePIR db,
+// with everlasting privacy. The selection vector of a row is split in two
+// shares, additive in Z_P like the XOR shares of the IT scheme in GF(2):
+// the first server gets a uniform share in the clear, and the second server
+// the other share encrypted as a SimplePIR query. Both servers answer with
+// the product of their share and the db, as the server of SimplePIR, and the
+// client adds the plaintext answer to the decrypted one. Each share alone is
+// uniform, so that a single server learns nothing about the row even with
+// unbounded computation, e.g., by breaking LWE in the future, while
+// colluding servers must break LWE to learn it. The mode is selected per
+// query: a query that is not everlasting is a plain SimplePIR query to the
+// second server only.
+type Hybrid struct {
+	*SimplePIR
+	// plaintext share of the last everlasting query and its encoding, nil
+	// if the last query is a SimplePIR query
+	share        []uint32
+	encodedShare []byte
+}
+
+// NewHybrid returns a client of the db with the given info and encoded hint,
+// as returned by the second server
+func NewHybrid(rnd io.Reader, info *database.Info, params *utils.ParamsLWE, hint []byte) (*Hybrid, error) {
+	c, err := NewSimplePIR(rnd, info, params, hint)
+	if err != nil {
+		return nil, err
+	}
+	return &Hybrid{SimplePIR: c}, nil
+}
+
+// QueryBytes returns the encoded queries of the entry of the given index to
+// the two servers. If the query is not everlasting, the query to the first
+// server is nil and the first server must not be queried.
+func (c *Hybrid) QueryBytes(index int, everlasting bool) ([][]byte, error) {
+	if !everlasting {
+		c.share, c.encodedShare = nil, nil
+		q, err := c.SimplePIR.QueryBytes(index)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{nil, q}, nil
+	}
+
+	defer monitor.Region("query").End()
+	i, j := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
+
+	// the shares of the selection vector of row i: share + other = e_i
+	b := make([]byte, 4*c.params.L)
+	if _, err := io.ReadFull(c.rnd, b); err != nil {
+		return nil, err
+	}
+	share := make([]uint32, c.params.L)
+	other := make([]uint32, c.params.L)
+	for k := range share {
+		share[k] = binary.LittleEndian.Uint32(b[4*k:]) % c.params.P
+		other[k] = (c.params.P - share[k]) % c.params.P
+	}
+	other[i] = (other[i] + 1) % c.params.P
+
+	encrypted := matrix.MatrixToBytes(c.encrypt(other, i, j))
+	c.state.query = encrypted
+	c.share = share
+	c.encodedShare = matrix.MatrixToBytes(matrix.NewWithData(1, c.params.L, share))
+	monitor.CountQuery(c.encodedShare, encrypted)
+
+	return [][]byte{c.encodedShare, encrypted}, nil
+}
+
+// ReconstructBytes returns the entry retrieved by the last query, given the
+// answers of the two servers, the first one being ignored if the query is
+// not everlasting
+func (c *Hybrid) ReconstructBytes(answers [][]byte) (byte, error) {
+	if len(answers) != 2 {
+		return 0, errors.New("expected two answers")
+	}
+	if c.share == nil {
+		return c.SimplePIR.ReconstructBytes(answers[1])
+	}
+
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(answers...)
+	plain, err := c.verify(c.encodedShare, answers[0])
+	if err != nil {
+		return 0, err
+	}
+	if plain.Rows() != 1 || plain.Cols() != c.params.M || plain.Len() != c.params.M {
+		return 0, errors.New("malformed answer")
+	}
+	encrypted, err := c.verify(c.state.query, answers[1])
+	if err != nil {
+		return 0, err
+	}
+	row, err := c.Reconstruct(encrypted)
+	if err != nil {
+		return 0, err
+	}
+
+	j := c.state.j
+	return byte((uint32(row[j]) + plain.Get(0, j)) % c.params.P), nil
+}
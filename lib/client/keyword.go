@@ -0,0 +1,134 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// ErrKeywordNotFound is returned when the retrieved keyword is not in the db
+var ErrKeywordNotFound = errors.New("keyword not found")
+
+// Keyword is the client of the keyword-PIR scheme based on constant-weight
+// codes, in the style of Mahdavi and Kerschbaum. The query is the codeword
+// of the keyword, shared bit by bit with Shamir's scheme of degree one among
+// CodeWeight+1 servers, so that no server alone learns anything about it.
+// Every server evaluates the equality check of the codeword of each record,
+// the product of the query at the ones of the codeword, and answers with the
+// sum of the records weighted by their checks, a share of degree CodeWeight
+// of the record of the keyword. The answers are not authenticated.
+type Keyword struct {
+	rnd    io.Reader
+	dbInfo *database.Info
+	field  *field.Field
+	state  *stateKeyword
+}
+
+type stateKeyword struct {
+	keyword string
+}
+
+// NewKeyword returns a client of the keyword db with the given info
+func NewKeyword(rnd io.Reader, info *database.Info) *Keyword {
+	return &Keyword{
+		rnd:    rnd,
+		dbInfo: info,
+		field:  info.Field(),
+	}
+}
+
+// QueryBytes returns the encoded queries of the keyword given as input
+func (c *Keyword) QueryBytes(in []byte, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	queries, err := c.Query(string(in), numServers)
+	if err != nil {
+		return nil, err
+	}
+	data := make([][]byte, len(queries))
+	for i, q := range queries {
+		data[i] = c.field.EncodeElements(q)
+	}
+	monitor.CountQuery(data...)
+	return data, nil
+}
+
+// Query returns the shares of the codeword of the keyword, one per server.
+// Server k evaluates the sharing polynomials at k+1.
+func (c *Keyword) Query(keyword string, numServers int) ([][]uint32, error) {
+	if c.dbInfo.ConstantWeight == nil {
+		return nil, errors.New("not a keyword db")
+	}
+	if numServers != c.dbInfo.CodeWeight+1 {
+		return nil, fmt.Errorf("codewords of weight %d need %d servers", c.dbInfo.CodeWeight, c.dbInfo.CodeWeight+1)
+	}
+	codeword, _ := database.KeywordToCodeword(keyword, c.dbInfo)
+	c.state = &stateKeyword{keyword: keyword}
+
+	bits := make([]uint32, c.dbInfo.CodeLength)
+	for _, p := range codeword {
+		bits[p] = 1
+	}
+	queries := make([][]uint32, numServers)
+	for k := range queries {
+		queries[k] = make([]uint32, len(bits))
+	}
+	// the share of server k of bit b is b + a(k+1) for a random slope a
+	for i, b := range bits {
+		a := c.field.RandElementWithPRG(c.rnd)
+		for k := range queries {
+			queries[k][i] = c.field.Add(b, c.field.Mul(a, uint32(k+1)))
+		}
+	}
+	return queries, nil
+}
+
+// ReconstructBytes returns the value of the keyword as []byte
+func (c *Keyword) ReconstructBytes(a [][]byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a...)
+	answers := make([][]uint32, len(a))
+	for k := range a {
+		var err error
+		if answers[k], err = c.field.DecodeElements(a[k]); err != nil {
+			return nil, err
+		}
+	}
+	return c.Reconstruct(answers)
+}
+
+// Reconstruct interpolates the record of the keyword from the answers of all
+// the servers, in their order, and returns its value. ErrKeywordNotFound is
+// returned if the keyword is not in the db.
+func (c *Keyword) Reconstruct(answers [][]uint32) ([]byte, error) {
+	if c.state == nil {
+		return nil, errors.New("no query to reconstruct")
+	}
+	if len(answers) != c.dbInfo.CodeWeight+1 {
+		return nil, errors.New("missing answers")
+	}
+	xs := make([]uint32, len(answers))
+	for k := range xs {
+		if len(answers[k]) != c.dbInfo.BlockSize {
+			return nil, errors.New("malformed answer")
+		}
+		xs[k] = uint32(k + 1)
+	}
+	coeffs, err := c.field.LagrangeCoefficients(xs, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	block := make([]uint32, c.dbInfo.BlockSize)
+	for k, a := range answers {
+		c.field.AddMulVector(block, a, coeffs[k])
+	}
+	value, ok := database.DecodeKeywordRecord(block, c.state.keyword, c.dbInfo)
+	if !ok {
+		return nil, ErrKeywordNotFound
+	}
+	return value, nil
+}
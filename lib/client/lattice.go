@@ -0,0 +1,211 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/merkle"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/rlwe"
+)
+
+// LatticeSingle is the client of the single-server lattice PIR scheme, in
+// the style of SealPIR. A query is a compressed RLWE ciphertext per dimension
+// of the db, which the server expands with the evaluation keys registered by
+// the client beforehand. The answers are not authenticated.
+type LatticeSingle struct {
+	dbInfo *database.Info
+	params *rlwe.Params
+	rnd    io.Reader
+
+	sk    *rlwe.SecretKey
+	keys  []byte
+	keyID []byte
+}
+
+// NewLatticeSingle returns a client of the db with the given info, with a
+// fresh secret key and the evaluation keys to register with the server
+func NewLatticeSingle(rnd io.Reader, info *database.Info, params *rlwe.Params) *LatticeSingle {
+	levels := 0
+	for _, dim := range database.LatticeDimensions(info) {
+		for 1<<levels < dim {
+			levels++
+		}
+	}
+	sk := rlwe.NewSecretKey(params, rnd)
+	keys := sk.NewEvaluationKeys(levels, rnd).Bytes()
+
+	return &LatticeSingle{
+		dbInfo: info,
+		params: params,
+		rnd:    rnd,
+		sk:     sk,
+		keys:   keys,
+		keyID:  rlwe.KeyID(keys),
+	}
+}
+
+// EvaluationKeysBytes returns the encoded evaluation keys of the client,
+// which the server needs to answer its queries
+func (c *LatticeSingle) EvaluationKeysBytes() []byte {
+	return c.keys
+}
+
+// Query returns the query of the block at row i and column j, one ciphertext
+// per dimension of the db
+func (c *LatticeSingle) Query(i, j int) []*rlwe.SeededCiphertext {
+	dims := database.LatticeDimensions(c.dbInfo)
+	indices := []int{i, j}
+	query := make([]*rlwe.SeededCiphertext, len(dims))
+	for k, dim := range dims {
+		logM := 0
+		for 1<<logM < dim {
+			logM++
+		}
+		query[k] = c.sk.EncryptIndex(indices[k], logM, c.rnd)
+	}
+	return query
+}
+
+// QueryBytes returns the encoded query of the block of the given index, in
+// row-major order
+func (c *LatticeSingle) QueryBytes(index int) ([]byte, error) {
+	defer monitor.Region("query").End()
+	if index < 0 || index >= c.dbInfo.NumRows*c.dbInfo.NumColumns {
+		return nil, fmt.Errorf("block %d out of range", index)
+	}
+	out := append([]byte{}, c.keyID...)
+	for _, ct := range c.Query(index/c.dbInfo.NumColumns, index%c.dbInfo.NumColumns) {
+		out = append(out, ct.Bytes()...)
+	}
+	monitor.CountQuery(out)
+	return out, nil
+}
+
+// Reconstruct returns the block retrieved by the answer. The plaintexts
+// decrypted from the answer are recomposed in the ciphertexts of the
+// previous dimension, up to the one of the block.
+func (c *LatticeSingle) Reconstruct(answer []*rlwe.Ciphertext) ([]byte, error) {
+	p := c.params
+	pts := make([][]byte, len(answer))
+	for k, ct := range answer {
+		pts[k] = c.sk.DecryptPow2(ct, rlwe.LogQAnswer)
+	}
+
+	// every ciphertext of the previous dimension is decomposed in 2 * LogQ1
+	// / LogT plaintexts
+	group := 2 * p.LogQ1 / rlwe.LogT
+	for len(pts) > 1 {
+		if len(pts)%group != 0 {
+			return nil, errors.New("malformed answer")
+		}
+		prev := make([][]byte, len(pts)/group)
+		for k := range prev {
+			ct, err := p.Recompose(pts[k*group:(k+1)*group], p.LogQ1)
+			if err != nil {
+				return nil, err
+			}
+			prev[k] = c.sk.DecryptPow2(ct, p.LogQ1)
+		}
+		pts = prev
+	}
+
+	return pts[0], nil
+}
+
+// ReconstructBytes returns the block retrieved by the last query
+func (c *LatticeSingle) ReconstructBytes(a []byte) ([]byte, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a)
+	num := 1
+	for range database.LatticeDimensions(c.dbInfo)[1:] {
+		num *= 2 * c.params.LogQ1 / rlwe.LogT
+	}
+	answer, err := c.params.DecodeCiphertexts(a, num)
+	if err != nil {
+		return nil, err
+	}
+	return c.Reconstruct(answer)
+}
+
+// LatticeMerkle is the client of the single-server lattice PIR scheme over a
+// db of blocks authenticated by a Merkle tree, as created by
+// database.NewLatticeMerkle. The plaintext retrieved by a query holds the
+// block and its Merkle proof, which the client verifies against the public
+// root of the tree, so that the single-round retrievals from a single server
+// are authenticated.
+type LatticeMerkle struct {
+	*LatticeSingle
+	// index of the block of the last query
+	index int
+}
+
+// NewLatticeMerkle returns a client of the Merkle-authenticated db with the
+// given info
+func NewLatticeMerkle(rnd io.Reader, info *database.Info, params *rlwe.Params) *LatticeMerkle {
+	return &LatticeMerkle{LatticeSingle: NewLatticeSingle(rnd, info, params), index: -1}
+}
+
+// QueryBytes returns the encoded query of the block of the given index, in
+// row-major order
+func (c *LatticeMerkle) QueryBytes(index int) ([]byte, error) {
+	q, err := c.LatticeSingle.QueryBytes(index)
+	if err != nil {
+		return nil, err
+	}
+	c.index = index
+	return q, nil
+}
+
+// ReconstructBytes returns the block retrieved by the last query, after
+// verifying its Merkle proof
+func (c *LatticeMerkle) ReconstructBytes(a []byte) ([]byte, error) {
+	if c.index < 0 {
+		return nil, errors.New("no query to reconstruct")
+	}
+	pt, err := c.LatticeSingle.ReconstructBytes(a)
+	if err != nil {
+		return nil, err
+	}
+	index := c.index
+	c.index = -1
+	return verifyMerkleBlock(pt, c.dbInfo, index)
+}
+
+// verifyMerkleBlock returns the block of the padded plaintext made of a block
+// and its Merkle proof, if the proof is the one of the given index with
+// respect to the root of the db
+func verifyMerkleBlock(pt []byte, info *database.Info, index int) ([]byte, error) {
+	// the padded plaintext ends with the signal byte and zeros
+	trimmed := len(pt)
+	for trimmed > 0 && pt[trimmed-1] == 0 {
+		trimmed--
+	}
+	if trimmed <= info.ProofLen || pt[trimmed-1] != 0x80 {
+		return nil, errors.New("REJECT!")
+	}
+	block := database.UnPadBlock(pt)
+	data := block[:len(block)-info.ProofLen]
+
+	// the encoded proof is the number of hashes, the hashes of 32 bytes and
+	// the index, of 4 bytes each
+	encoded := block[len(block)-info.ProofLen:]
+	if 8+32*int(binary.LittleEndian.Uint32(encoded)) != len(encoded) {
+		return nil, errors.New("REJECT!")
+	}
+	proof := merkle.DecodeProof(encoded)
+	if int(proof.Index) != index {
+		return nil, errors.New("REJECT!")
+	}
+	verified, err := merkle.VerifyProof(data, proof, info.Root)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, errors.New("REJECT!")
+	}
+	return data, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/utils"
 )
 
@@ -66,9 +67,12 @@ func (c *LWE) Query(i, j int) *matrix.Matrix {
 }
 
 func (c *LWE) QueryBytes(index int) ([]byte, error) {
+	defer monitor.Region("query").End()
 	i, j := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
 	m := c.Query(i, j)
-	return matrix.MatrixToBytes(m), nil
+	q := matrix.MatrixToBytes(m)
+	monitor.CountQuery(q)
+	return q, nil
 }
 
 func (c *LWE) Reconstruct(answers *matrix.Matrix) (uint32, error) {
@@ -91,6 +95,8 @@ func (c *LWE) Reconstruct(answers *matrix.Matrix) (uint32, error) {
 }
 
 func (c *LWE) ReconstructBytes(a []byte) (uint32, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a)
 	return c.Reconstruct(matrix.BytesToMatrix(a))
 }
 
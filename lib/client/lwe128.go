@@ -6,6 +6,7 @@ import (
 
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/utils"
 	"lukechampine.com/uint128"
 )
@@ -67,9 +68,12 @@ func (c *LWE128) Query(i, j int) *matrix.Matrix128 {
 }
 
 func (c *LWE128) QueryBytes(index int) ([]byte, error) {
+	defer monitor.Region("query").End()
 	i, j := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
 	m := c.Query(i, j)
-	return matrix.Matrix128ToBytes(m), nil
+	q := matrix.Matrix128ToBytes(m)
+	monitor.CountQuery(q)
+	return q, nil
 }
 
 func (c *LWE128) Reconstruct(answers *matrix.Matrix128) (uint32, error) {
@@ -92,6 +96,8 @@ func (c *LWE128) Reconstruct(answers *matrix.Matrix128) (uint32, error) {
 }
 
 func (c *LWE128) ReconstructBytes(a []byte) (uint32, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a)
 	return c.Reconstruct(matrix.BytesToMatrix128(a))
 }
 
@@ -0,0 +1,253 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lukechampine/fastxor"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// OfflineOnline is the client of the two-server offline/online PIR scheme of
+// Corrigan-Gibbs and Kogan. In the offline phase, the first server streams
+// the parities of pseudorandom sets of blocks, one per row of the db, which
+// the client keeps as hints. In the online phase, the client sends a hint
+// containing the retrieved block, without that block, to the second server,
+// and a fresh set with the block, without it, to the first server. The answer
+// of the second server retrieves the block, and the one of the first server
+// replaces the used hint, so that no set is ever sent twice. The answers are
+// not authenticated.
+type OfflineOnline struct {
+	dbInfo *database.Info
+	rnd    io.Reader
+
+	// the sets of the hints are expanded with the offline key, sent to the
+	// first server, and the refreshed ones with a key which is never sent
+	offlineKey *utils.PRGKey
+	offline    *utils.SetPRF
+	refresh    *utils.SetPRF
+
+	hints     []*offlineHint
+	refreshed uint32
+
+	state *stateOfflineOnline
+}
+
+// offlineHint is the parity of the blocks of a set. The block of a refreshed
+// set in the row of the block it was refreshed for is overridden.
+type offlineHint struct {
+	refreshed bool
+	id        uint32
+	row       int
+	column    int
+	parity    []byte
+}
+
+// offset returns the column of the block of the set of the hint in the row,
+// expanded with the PRF of the offline or of the refreshed sets
+func (h *offlineHint) offset(offline, refresh *utils.SetPRF, row int) int {
+	if row == h.row {
+		return h.column
+	}
+	if h.refreshed {
+		return refresh.Offset(h.id, row)
+	}
+	return offline.Offset(h.id, row)
+}
+
+type stateOfflineOnline struct {
+	ix   int
+	iy   int
+	hint int
+	next *offlineHint
+}
+
+// NewOfflineOnline returns a client of the db with the given info, keeping
+// the given number of hints. With no hints, the client keeps enough hints so
+// that no block is in none of them except with probability 2^-40.
+func NewOfflineOnline(rnd io.Reader, info *database.Info, numHints int) *OfflineOnline {
+	if numHints == 0 {
+		numHints = defaultNumHints(info)
+	}
+	offlineKey, refreshKey := new(utils.PRGKey), new(utils.PRGKey)
+	if _, err := io.ReadFull(rnd, offlineKey[:]); err != nil {
+		panic(err)
+	}
+	if _, err := io.ReadFull(rnd, refreshKey[:]); err != nil {
+		panic(err)
+	}
+
+	return &OfflineOnline{
+		dbInfo:     info,
+		rnd:        rnd,
+		offlineKey: offlineKey,
+		offline:    utils.NewSetPRF(offlineKey, info.NumColumns),
+		refresh:    utils.NewSetPRF(refreshKey, info.NumColumns),
+		hints:      make([]*offlineHint, numHints),
+	}
+}
+
+// defaultNumHints returns the number of sets, with one block per row of the
+// db, such that no block is in none of them except with probability 2^-40
+func defaultNumHints(info *database.Info) int {
+	// a block is in none of m sets with probability (1 - 1/columns)^m
+	n := float64(info.NumRows * info.NumColumns)
+	return info.NumColumns * int(math.Ceil(math.Log(n)+40*math.Ln2))
+}
+
+// OfflineQuery returns the query of the offline phase, to send to the first
+// server, i.e., the key of the sets and their number
+func (c *OfflineOnline) OfflineQuery() []byte {
+	q := make([]byte, len(c.offlineKey)+4)
+	copy(q, c.offlineKey[:])
+	binary.BigEndian.PutUint32(q[len(c.offlineKey):], uint32(len(c.hints)))
+	return q
+}
+
+// SetHints stores the hints of the offline phase, as returned by the first
+// server
+func (c *OfflineOnline) SetHints(a []byte) error {
+	bs := c.dbInfo.BlockSize
+	if len(a) != len(c.hints)*bs {
+		return fmt.Errorf("%d bytes of hints, expected %d", len(a), len(c.hints)*bs)
+	}
+	for id := range c.hints {
+		c.hints[id] = &offlineHint{
+			id:     uint32(id),
+			row:    -1,
+			parity: append([]byte{}, a[id*bs:(id+1)*bs]...),
+		}
+	}
+	c.refreshed = 0
+	return nil
+}
+
+// Clone returns a copy of the client with the same hints, which are updated
+// independently by the two clients
+func (c *OfflineOnline) Clone() *OfflineOnline {
+	clone := *c
+	clone.hints = cloneHints(c.hints)
+	clone.state = nil
+	return &clone
+}
+
+// cloneHints returns a deep copy of the hints
+func cloneHints(hints []*offlineHint) []*offlineHint {
+	clone := make([]*offlineHint, len(hints))
+	for i, h := range hints {
+		if h != nil {
+			hint := *h
+			hint.parity = append([]byte{}, h.parity...)
+			clone[i] = &hint
+		}
+	}
+	return clone
+}
+
+// QueryBytes is wrapper around Query to implement the Client interface
+func (c *OfflineOnline) QueryBytes(in []byte, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	if numServers != 2 {
+		return nil, errors.New("the offline/online scheme needs two servers")
+	}
+	index := int(binary.BigEndian.Uint32(in))
+	queries, err := c.Query(index)
+	if err != nil {
+		return nil, err
+	}
+	monitor.CountQuery(queries...)
+	return queries, nil
+}
+
+// Query returns the queries of the block of the given index, in row-major
+// order: the first one, for the first server, refreshes the used hint and the
+// second one retrieves the block. If no hint contains the block, nothing
+// is sent and an error is returned, which the default number of hints makes
+// negligible.
+func (c *OfflineOnline) Query(index int) ([][]byte, error) {
+	if index < 0 || index >= c.dbInfo.NumRows*c.dbInfo.NumColumns {
+		return nil, fmt.Errorf("block %d out of range", index)
+	}
+	ix, iy := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
+
+	used := -1
+	for k, h := range c.hints {
+		if h == nil {
+			return nil, errors.New("missing hints of the offline phase")
+		}
+		if c.offset(h, ix) == iy {
+			used = k
+			break
+		}
+	}
+	if used < 0 {
+		return nil, fmt.Errorf("no hint contains block %d", index)
+	}
+
+	// the used hint is replaced by a fresh set, overridden with the block
+	next := &offlineHint{refreshed: true, id: c.refreshed, row: ix, column: iy}
+	c.refreshed++
+	c.state = &stateOfflineOnline{ix: ix, iy: iy, hint: used, next: next}
+
+	return [][]byte{c.puncture(next, ix), c.puncture(c.hints[used], ix)}, nil
+}
+
+// ReconstructBytes returns []byte
+func (c *OfflineOnline) ReconstructBytes(a [][]byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a...)
+	return c.Reconstruct(a)
+}
+
+// Reconstruct returns the block retrieved by the last query and refreshes
+// the used hint
+func (c *OfflineOnline) Reconstruct(answers [][]byte) ([]byte, error) {
+	if c.state == nil {
+		return nil, errors.New("no query to reconstruct")
+	}
+	bs := c.dbInfo.BlockSize
+	if len(answers) != 2 {
+		return nil, errors.New("the offline/online scheme needs two answers")
+	}
+	for _, a := range answers {
+		if len(a) != c.dbInfo.NumRows*bs {
+			return nil, errors.New("malformed answer")
+		}
+	}
+
+	row := c.state.ix * bs
+	block := make([]byte, bs)
+	fastxor.Bytes(block, c.hints[c.state.hint].parity, answers[1][row:row+bs])
+
+	next := c.state.next
+	next.parity = make([]byte, bs)
+	fastxor.Bytes(next.parity, answers[0][row:row+bs], block)
+	c.hints[c.state.hint] = next
+	c.state = nil
+
+	return block, nil
+}
+
+// offset returns the column of the block of the set of the hint in the row
+func (c *OfflineOnline) offset(h *offlineHint, row int) int {
+	return h.offset(c.offline, c.refresh, row)
+}
+
+// puncture returns the encoded columns of the set of the hint in all the
+// rows but the given one
+func (c *OfflineOnline) puncture(h *offlineHint, row int) []byte {
+	out := make([]byte, 4*(c.dbInfo.NumRows-1))
+	k := 0
+	for r := 0; r < c.dbInfo.NumRows; r++ {
+		if r != row {
+			binary.BigEndian.PutUint32(out[4*k:], uint32(c.offset(h, r)))
+			k++
+		}
+	}
+	return out
+}
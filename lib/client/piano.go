@@ -0,0 +1,302 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lukechampine/fastxor"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+var (
+	// ErrStaleHints is returned when the db changed since the client
+	// preprocessed it, which must be done again
+	ErrStaleHints = errors.New("hints of a previous epoch of the db")
+	// ErrHintsExhausted is returned when the client used all the backup
+	// hints of a row of the db, which must be preprocessed again
+	ErrHintsExhausted = errors.New("no backup hint left")
+)
+
+// Piano is the client of the single-server client-preprocessing PIR scheme
+// in the style of Piano. The client streams the db once, row by row, and
+// keeps the parities of pseudorandom sets with a block per row as primary
+// hints, the parities of backup sets without a given row, and random blocks
+// of every row as replacements. A query is a primary hint containing the
+// block, in which the block is replaced by a replacement of its row, so that
+// the server sees a fresh random set and answers with its parity. The used
+// hint is replaced by a backup set completed with the block, so that the
+// client retrieves about as many blocks per row as it keeps backup hints
+// before preprocessing the db again. The answers are not authenticated.
+type Piano struct {
+	dbInfo    *database.Info
+	rnd       io.Reader
+	numHints  int
+	numBackup int
+
+	// digest of the preprocessed db, nil before the preprocessing
+	digest  []byte
+	primary *utils.SetPRF
+	backup  *utils.SetPRF
+	hints   []*offlineHint
+	// backup hints and replacements not used yet, by row
+	backups      [][]*offlineHint
+	replacements [][]pianoEntry
+
+	state *statePiano
+}
+
+// pianoEntry is a block of the db and its column
+type pianoEntry struct {
+	column int
+	block  []byte
+}
+
+type statePiano struct {
+	ix          int
+	iy          int
+	hint        *offlineHint
+	replacement []byte
+}
+
+// NewPiano returns a client of the db with the given info, keeping the given
+// number of primary hints and of backup hints per row. With no primary
+// hints, the client keeps enough of them so that no block is in none of them
+// except with probability 2^-40; with no backup hints, it keeps one per bit
+// of the number of blocks in every row.
+func NewPiano(rnd io.Reader, info *database.Info, numHints, numBackup int) *Piano {
+	if numHints == 0 {
+		numHints = defaultNumHints(info)
+	}
+	if numBackup == 0 {
+		numBackup = int(math.Ceil(math.Log2(float64(info.NumRows*info.NumColumns)))) + 1
+	}
+	return &Piano{
+		dbInfo:    info,
+		rnd:       rnd,
+		numHints:  numHints,
+		numBackup: numBackup,
+	}
+}
+
+// Preprocess streams the rows of the db with the given function, which
+// answers the index of a row with the digest of the db followed by the row,
+// and computes fresh hints. The previous hints are discarded.
+func (c *Piano) Preprocess(chunk func([]byte) ([]byte, error)) error {
+	defer monitor.Region("preprocess").End()
+	primaryKey, backupKey := new(utils.PRGKey), new(utils.PRGKey)
+	if _, err := io.ReadFull(c.rnd, primaryKey[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(c.rnd, backupKey[:]); err != nil {
+		return err
+	}
+	primary := utils.NewSetPRF(primaryKey, c.dbInfo.NumColumns)
+	backup := utils.NewSetPRF(backupKey, c.dbInfo.NumColumns)
+
+	rows, bs := c.dbInfo.NumRows, c.dbInfo.BlockSize
+	hints := make([]*offlineHint, c.numHints)
+	for id := range hints {
+		hints[id] = &offlineHint{id: uint32(id), row: -1, parity: make([]byte, bs)}
+	}
+	// the backup sets of a row have no block in that row
+	backups := make([][]*offlineHint, rows)
+	for r := range backups {
+		backups[r] = make([]*offlineHint, c.numBackup)
+		for k := range backups[r] {
+			id := uint32(r*c.numBackup + k)
+			backups[r][k] = &offlineHint{refreshed: true, id: id, row: r, parity: make([]byte, bs)}
+		}
+	}
+	replacements := make([][]pianoEntry, rows)
+
+	var digest []byte
+	q := make([]byte, 4)
+	random := make([]byte, 8)
+	for r := 0; r < rows; r++ {
+		binary.BigEndian.PutUint32(q, uint32(r))
+		a, err := chunk(q)
+		if err != nil {
+			return err
+		}
+		if len(a) != sha256.Size+c.dbInfo.NumColumns*bs {
+			return fmt.Errorf("row %d of %d bytes", r, len(a))
+		}
+		if digest == nil {
+			digest = a[:sha256.Size]
+		} else if !bytes.Equal(digest, a[:sha256.Size]) {
+			// the db changed during the preprocessing
+			return ErrStaleHints
+		}
+		row := a[sha256.Size:]
+
+		for _, h := range hints {
+			o := h.offset(primary, backup, r)
+			fastxor.Bytes(h.parity, h.parity, row[o*bs:(o+1)*bs])
+		}
+		for other := range backups {
+			if other == r {
+				continue
+			}
+			for _, h := range backups[other] {
+				o := h.offset(primary, backup, r)
+				fastxor.Bytes(h.parity, h.parity, row[o*bs:(o+1)*bs])
+			}
+		}
+		replacements[r] = make([]pianoEntry, c.numBackup)
+		for k := range replacements[r] {
+			if _, err := io.ReadFull(c.rnd, random); err != nil {
+				return err
+			}
+			o := int(binary.BigEndian.Uint64(random) % uint64(c.dbInfo.NumColumns))
+			replacements[r][k] = pianoEntry{column: o, block: append([]byte{}, row[o*bs:(o+1)*bs]...)}
+		}
+	}
+
+	c.digest = append([]byte{}, digest...)
+	c.primary, c.backup = primary, backup
+	c.hints, c.backups, c.replacements = hints, backups, replacements
+	c.state = nil
+	return nil
+}
+
+// StorageBytes returns the number of bytes of the hints kept by the client
+func (c *Piano) StorageBytes() int {
+	// the sets are stored as their identifier and their overridden block
+	const setLen = 4 + 2*4
+	bs := c.dbInfo.BlockSize
+	n := len(c.digest) + len(c.hints)*(setLen+bs)
+	for r := range c.backups {
+		n += len(c.backups[r])*(setLen+bs) + len(c.replacements[r])*(4+bs)
+	}
+	return n
+}
+
+// Clone returns a copy of the client with the same hints, which are used
+// independently by the two clients
+func (c *Piano) Clone() *Piano {
+	clone := *c
+	clone.hints = cloneHints(c.hints)
+	clone.backups = make([][]*offlineHint, len(c.backups))
+	for r := range c.backups {
+		clone.backups[r] = cloneHints(c.backups[r])
+	}
+	clone.replacements = make([][]pianoEntry, len(c.replacements))
+	for r := range c.replacements {
+		clone.replacements[r] = append([]pianoEntry{}, c.replacements[r]...)
+	}
+	clone.state = nil
+	return &clone
+}
+
+// QueryBytes returns the encoded query of the block of the given index, in
+// row-major order
+func (c *Piano) QueryBytes(index int) ([]byte, error) {
+	defer monitor.Region("query").End()
+	q, err := c.Query(index)
+	if err != nil {
+		return nil, err
+	}
+	monitor.CountQuery(q)
+	return q, nil
+}
+
+// Query returns the query of the block of the given index, i.e., the digest
+// of the preprocessed db followed by the column of a block in every row. The
+// used hint and replacement are discarded, even if the answer is never
+// reconstructed, since the server has seen them.
+func (c *Piano) Query(index int) ([]byte, error) {
+	if c.digest == nil {
+		return nil, errors.New("the db is not preprocessed")
+	}
+	if index < 0 || index >= c.dbInfo.NumRows*c.dbInfo.NumColumns {
+		return nil, fmt.Errorf("block %d out of range", index)
+	}
+	ix, iy := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
+	if len(c.replacements[ix]) == 0 || len(c.backups[ix]) == 0 {
+		return nil, fmt.Errorf("%w for row %d", ErrHintsExhausted, ix)
+	}
+
+	used := -1
+	for k, h := range c.hints {
+		if c.offset(h, ix) == iy {
+			used = k
+			break
+		}
+	}
+	if used < 0 {
+		return nil, fmt.Errorf("no hint contains block %d", index)
+	}
+	h := c.hints[used]
+	c.hints[used] = c.hints[len(c.hints)-1]
+	c.hints = c.hints[:len(c.hints)-1]
+	last := len(c.replacements[ix]) - 1
+	replacement := c.replacements[ix][last]
+	c.replacements[ix] = c.replacements[ix][:last]
+	c.state = &statePiano{ix: ix, iy: iy, hint: h, replacement: replacement.block}
+
+	q := make([]byte, sha256.Size+4*c.dbInfo.NumRows)
+	copy(q, c.digest)
+	for r := 0; r < c.dbInfo.NumRows; r++ {
+		o := replacement.column
+		if r != ix {
+			o = c.offset(h, r)
+		}
+		binary.BigEndian.PutUint32(q[sha256.Size+4*r:], uint32(o))
+	}
+	return q, nil
+}
+
+// ReconstructBytes returns the block retrieved by the last query
+func (c *Piano) ReconstructBytes(a []byte) ([]byte, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a)
+	return c.Reconstruct(a)
+}
+
+// Reconstruct returns the block retrieved by the last query and replaces the
+// used hint with a backup hint of the row of the block. ErrStaleHints is
+// returned if the db changed since the preprocessing.
+func (c *Piano) Reconstruct(a []byte) ([]byte, error) {
+	if c.state == nil {
+		return nil, errors.New("no query to reconstruct")
+	}
+	bs := c.dbInfo.BlockSize
+	if len(a) < sha256.Size {
+		return nil, errors.New("malformed answer")
+	}
+	if !bytes.Equal(a[:sha256.Size], c.digest) {
+		c.state = nil
+		return nil, ErrStaleHints
+	}
+	if len(a) != sha256.Size+bs {
+		return nil, errors.New("malformed answer")
+	}
+
+	block := make([]byte, bs)
+	fastxor.Bytes(block, c.state.hint.parity, a[sha256.Size:])
+	fastxor.Bytes(block, block, c.state.replacement)
+
+	// the backup set of the row is completed with the block
+	ix := c.state.ix
+	last := len(c.backups[ix]) - 1
+	next := c.backups[ix][last]
+	c.backups[ix] = c.backups[ix][:last]
+	next.column = c.state.iy
+	fastxor.Bytes(next.parity, next.parity, block)
+	c.hints = append(c.hints, next)
+	c.state = nil
+
+	return block, nil
+}
+
+// offset returns the column of the block of the set of the hint in the row
+func (c *Piano) offset(h *offlineHint, row int) int {
+	return h.offset(c.primary, c.backup, row)
+}
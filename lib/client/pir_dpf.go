@@ -0,0 +1,79 @@
+package client
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// PIRDPF is the client for the two-server computational classical PIR scheme
+// working in GF(2). The query vector of the PIR client is compressed using a
+// DPF with single-bit outputs, reducing the upload from O(sqrt(N)) to O(log N)
+// at the price of computational instead of information-theoretic security.
+type PIRDPF struct {
+	rnd    io.Reader
+	dbInfo *database.Info
+	state  *state
+	fss    *fss.Fss
+}
+
+// NewPIRDPF returns a client for the DPF-based classical PIR scheme in GF(2),
+// working both with the vector and the rebalanced representation of the
+// database.
+func NewPIRDPF(rnd io.Reader, info *database.Info) *PIRDPF {
+	return &PIRDPF{
+		rnd:    rnd,
+		dbInfo: info,
+		state:  nil,
+		fss:    fss.ClientInitialize(1),
+	}
+}
+
+// QueryBytes executes Query and encodes the DPF keys in bytes
+func (c *PIRDPF) QueryBytes(in []byte, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	index := int(binary.BigEndian.Uint32(in))
+	keys := c.Query(index, numServers)
+
+	data := make([][]byte, len(keys))
+	for i := range keys {
+		data[i] = fss.EncodeKey(&keys[i])
+	}
+	monitor.CountQuery(data...)
+
+	return data, nil
+}
+
+// Query outputs the DPF keys selecting the column of the given database
+// index. The DPF implementation assumes two servers.
+func (c *PIRDPF) Query(index int, numServers int) []fss.FssKeyEq2P {
+	if invalidQueryInputsFSS(numServers) {
+		log.Fatal("invalid query inputs")
+	}
+	ix, iy := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
+	c.state = &state{
+		ix: ix,
+		iy: iy,
+	}
+
+	numBits := fss.NumBitsForDomain(c.dbInfo.NumColumns)
+	f := c.fss.Current()
+	return f.GenerateTreeBit(fss.IndexToBits(iy, numBits))
+}
+
+// ReconstructBytes returns []byte
+func (c *PIRDPF) ReconstructBytes(a [][]byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a...)
+	return c.Reconstruct(a)
+}
+
+// Reconstruct reconstruct the entry of the database from answers
+func (c *PIRDPF) Reconstruct(answers [][]byte) ([]byte, error) {
+	return reconstructPIR(answers, c.dbInfo, c.state)
+}
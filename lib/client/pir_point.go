@@ -1,12 +1,16 @@
 package client
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
 	"log"
+	"time"
 
 	"github.com/lukechampine/fastxor"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/utils"
 )
 
@@ -34,8 +38,11 @@ func NewPIR(rnd io.Reader, info *database.Info) *PIR {
 
 // QueryBytes is wrapper around Query to implement the Client interface
 func (c *PIR) QueryBytes(in []byte, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
 	index := int(binary.BigEndian.Uint32(in))
-	return c.Query(index, numServers), nil
+	queries := c.Query(index, numServers)
+	monitor.CountQuery(queries...)
+	return queries, nil
 }
 
 // Query performs a client query for the given database index to numServers
@@ -51,48 +58,106 @@ func (c *PIR) Query(index int, numServers int) [][]byte {
 		ix: ix,
 		iy: iy,
 	}
-	vectors, err := c.secretShare(numServers)
+	if !c.dbInfo.SPIR {
+		vectors, err := c.secretShare(numServers, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return vectors
+	}
+
+	// the nonce selects the masks of the servers, and prefixes the vectors
+	vectors, err := c.secretShare(numServers, database.SPIRNonceLen)
 	if err != nil {
 		log.Fatal(err)
 	}
-
+	c.state.nonce = make([]byte, database.SPIRNonceLen)
+	binary.BigEndian.PutUint64(c.state.nonce, database.SPIREpoch(time.Now()))
+	if _, err := io.ReadFull(c.rnd, c.state.nonce[8:]); err != nil {
+		log.Fatal(err)
+	}
+	for k := range vectors {
+		vectors[k] = c.state.buffers[k].B
+		copy(vectors[k], c.state.nonce)
+	}
 	return vectors
 }
 
+// Release puts the vectors of the last query back to the pool, once they
+// are sent to the servers. The queries must not be used afterwards.
+func (c *PIR) Release() {
+	if c.state != nil {
+		c.state.release()
+	}
+}
+
 // ReconstructBytes returns []byte
 func (c *PIR) ReconstructBytes(a [][]byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a...)
 	return c.Reconstruct(a)
 }
 
 // Reconstruct reconstruct the entry of the database from answers
 func (c *PIR) Reconstruct(answers [][]byte) ([]byte, error) {
+	if c.dbInfo.SPIR {
+		var err error
+		if answers, err = c.unmaskSPIR(answers); err != nil {
+			return nil, err
+		}
+	}
 	return reconstructPIR(answers, c.dbInfo, c.state)
 }
 
-func (c *PIR) secretShare(numServers int) ([][]byte, error) {
+// unmaskSPIR checks that all the masked answers of the symmetric mode
+// answer the nonce of the query, so that their masks cancel out in their
+// sum, and returns them without the nonce
+func (c *PIR) unmaskSPIR(answers [][]byte) ([][]byte, error) {
+	answerLen := c.dbInfo.NumRows * c.dbInfo.BlockSize
+	out := make([][]byte, len(answers))
+	for k, a := range answers {
+		if len(a) != database.SPIRNonceLen+answerLen {
+			return nil, errors.New("malformed answer")
+		}
+		if !bytes.Equal(a[:database.SPIRNonceLen], c.state.nonce) {
+			return nil, errors.New("REJECT!")
+		}
+		out[k] = a[database.SPIRNonceLen:]
+	}
+	return out, nil
+}
+
+// secretShare returns the query vectors of the servers, taken from the pool
+// after offset bytes kept for a prefix
+func (c *PIR) secretShare(numServers, offset int) ([][]byte, error) {
 	// length of query vector
 	// one query bit per column
 	vectorLen := c.dbInfo.NumColumns/8 + 1
 
 	// create query vectors for all the servers
 	vectors := make([][]byte, numServers)
+	c.state.buffers = make([]*utils.Buffer, numServers)
 	for k := range vectors {
-		vectors[k] = make([]byte, vectorLen)
+		c.state.buffers[k] = utils.GetBuffer(offset + vectorLen)
+		vectors[k] = c.state.buffers[k].B[offset:]
 	}
 
 	// Get random elements for all numServers-1 vectors.
 	// This is faster than extracting single bits
-	rand := make([]byte, (numServers-1)*vectorLen)
+	buf := utils.GetBuffer((numServers - 1) * vectorLen)
+	defer buf.Release()
+	rand := buf.B
 	if _, err := c.rnd.Read(rand); err != nil {
 		return nil, err
 	}
 
 	// perform secret sharing
 	// find the byte corresponding to the retrieval bit
-	// what value this byte should get
+	// what value this byte should get. All the bytes are written, so that
+	// the index does not leak through the memory accesses.
 	index := c.state.iy / 8
 	value := 1 << (c.state.iy % 8)
-	vectors[numServers-1][index] = byte(value)
+	utils.ConstantTimeSetByte(vectors[numServers-1], index, byte(value))
 	for k := 0; k < numServers-1; k++ {
 		copy(vectors[k], rand[k*vectorLen:(k+1)*vectorLen])
 		fastxor.Bytes(vectors[numServers-1], vectors[numServers-1], vectors[k])
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lukechampine/fastxor"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// ReplicatedServers is the number of servers of the replicated PIR scheme
+const ReplicatedServers = 3
+
+// PIRReplicated is the client for the honest-majority three-server PIR
+// scheme working in GF(2). The query vector is split in three additive
+// shares and every server receives the two shares that are not its own, so
+// that any two servers answer all the shares and a single server learns
+// nothing about the index. Every share is answered by two servers: the
+// duplicated answers are compared to detect a corrupted server, without the
+// MACs over a large field of the VPIR schemes.
+type PIRReplicated struct {
+	rnd    io.Reader
+	dbInfo *database.Info
+	state  *state
+}
+
+// NewPIRReplicated returns a client for the replicated PIR scheme, working
+// both with the vector and the rebalanced representation of the database.
+func NewPIRReplicated(rnd io.Reader, info *database.Info) *PIRReplicated {
+	return &PIRReplicated{
+		rnd:    rnd,
+		dbInfo: info,
+		state:  nil,
+	}
+}
+
+// QueryBytes is wrapper around Query to implement the Client interface
+func (c *PIRReplicated) QueryBytes(in []byte, numServers int) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	if numServers != ReplicatedServers {
+		return nil, fmt.Errorf("the replicated scheme needs %d servers", ReplicatedServers)
+	}
+	index := int(binary.BigEndian.Uint32(in))
+	queries, err := c.Query(index)
+	if err != nil {
+		return nil, err
+	}
+	monitor.CountQuery(queries...)
+	return queries, nil
+}
+
+// Query returns the queries of the given database index to the three
+// servers: the query of server k holds shares k+1 and k+2, modulo 3.
+func (c *PIRReplicated) Query(index int) ([][]byte, error) {
+	if index < 0 || index >= c.dbInfo.NumRows*c.dbInfo.NumColumns {
+		return nil, fmt.Errorf("index %d out of range", index)
+	}
+	ix, iy := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
+	c.state = &state{ix: ix, iy: iy}
+
+	// one query bit per column
+	vectorLen := c.dbInfo.NumColumns/8 + 1
+	shares := make([][]byte, ReplicatedServers)
+	buf := utils.GetBuffer(ReplicatedServers * vectorLen)
+	defer buf.Release()
+	random := buf.B[:(ReplicatedServers-1)*vectorLen]
+	if _, err := io.ReadFull(c.rnd, random); err != nil {
+		return nil, err
+	}
+	last := buf.B[(ReplicatedServers-1)*vectorLen:]
+	last[iy/8] = 1 << (iy % 8)
+	for k := 0; k < ReplicatedServers-1; k++ {
+		shares[k] = random[k*vectorLen : (k+1)*vectorLen]
+		fastxor.Bytes(last, last, shares[k])
+	}
+	shares[ReplicatedServers-1] = last
+
+	queries := make([][]byte, ReplicatedServers)
+	c.state.buffers = make([]*utils.Buffer, ReplicatedServers)
+	for k := range queries {
+		c.state.buffers[k] = utils.GetBuffer(2 * vectorLen)
+		queries[k] = c.state.buffers[k].B[:0]
+		for _, j := range replicatedShares(k) {
+			queries[k] = append(queries[k], shares[j]...)
+		}
+	}
+	return queries, nil
+}
+
+// Release puts the vectors of the last queries back to the pool, once they
+// are sent to the servers. The queries must not be used afterwards.
+func (c *PIRReplicated) Release() {
+	if c.state != nil {
+		c.state.release()
+	}
+}
+
+// ReconstructBytes returns []byte
+func (c *PIRReplicated) ReconstructBytes(a [][]byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a...)
+	return c.Reconstruct(a)
+}
+
+// Reconstruct returns the entry of the database from the answers of the
+// servers, in the order of the queries. The answer of a server that did not
+// answer is nil: the entry is reconstructed from the two others, which
+// cross-check a single share instead of all of them. The answers are
+// rejected if two servers answer a share differently.
+func (c *PIRReplicated) Reconstruct(answers [][]byte) ([]byte, error) {
+	if c.state == nil {
+		return nil, errors.New("no query to reconstruct")
+	}
+	if len(answers) != ReplicatedServers {
+		return nil, fmt.Errorf("%d answers for %d servers", len(answers), ReplicatedServers)
+	}
+	answerLen := c.dbInfo.NumRows * c.dbInfo.BlockSize
+
+	// answers of every share, the first one of the servers holding it
+	shares := make([][]byte, ReplicatedServers)
+	received := 0
+	for k, a := range answers {
+		if a == nil {
+			continue
+		}
+		received++
+		if len(a) != 2*answerLen {
+			return nil, errors.New("malformed answer")
+		}
+		for pos, j := range replicatedShares(k) {
+			share := a[pos*answerLen : (pos+1)*answerLen]
+			if shares[j] == nil {
+				shares[j] = share
+			} else if !bytes.Equal(shares[j], share) {
+				return nil, errors.New("REJECT!")
+			}
+		}
+	}
+	if received < ReplicatedServers-1 {
+		return nil, fmt.Errorf("%d answers, at least %d needed", received, ReplicatedServers-1)
+	}
+
+	return reconstructPIR(shares, c.dbInfo, c.state)
+}
+
+// replicatedShares returns the shares of the query of server k
+func replicatedShares(k int) []int {
+	return []int{(k + 1) % ReplicatedServers, (k + 2) % ReplicatedServers}
+}
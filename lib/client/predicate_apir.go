@@ -19,13 +19,20 @@ type PredicateAPIR struct {
 // NewFSS returns a new client for the FSS-based single- and multi-bit schemes
 func NewPredicateAPIR(rnd io.Reader, info *database.Info) *PredicateAPIR {
 	executions := 1 + field.ConcurrentExecutions
+	if info.UseField64() {
+		// fewer tags are needed in the 64-bit field for the same soundness
+		executions = 1 + field.ConcurrentExecutions64
+	}
+	// one value for the data, four values for the info-theoretic MAC
+	f := fss.ClientInitialize(executions)
+	f.SetField(info.Field())
+
 	return &PredicateAPIR{
 		&clientFSS{
-			rnd:    rnd,
-			dbInfo: info,
-			state:  nil,
-			// one value for the data, four values for the info-theoretic MAC
-			Fss:        fss.ClientInitialize(executions),
+			rnd:        rnd,
+			dbInfo:     info,
+			state:      nil,
+			Fss:        f,
 			executions: executions,
 		},
 	}
@@ -39,7 +46,7 @@ func (c *PredicateAPIR) QueryBytes(in []byte, numServers int) ([][]byte, error)
 
 // Query takes as input the index of the entry to be retrieved and the number
 // of servers (= 2 in the DPF case). It returns the two FSS keys.
-func (c *PredicateAPIR) Query(q *query.ClientFSS, numServers int) []*query.FSS {
+func (c *PredicateAPIR) Query(q *query.ClientFSS, numServers int) ([]*query.FSS, error) {
 	return c.query(q, numServers)
 }
 
@@ -54,3 +61,33 @@ func (c *PredicateAPIR) ReconstructBytes(a [][]byte) (interface{}, error) {
 func (c *PredicateAPIR) Reconstruct(answers [][]uint32) (uint32, error) {
 	return c.reconstruct(answers)
 }
+
+// ReconstructAggregates returns the values of all the aggregates requested in
+// the query, in the same order as in query.Info.Aggregates.
+func (c *PredicateAPIR) ReconstructAggregates(answers [][]uint32) ([]uint32, error) {
+	return c.reconstructAggregates(answers)
+}
+
+// ReconstructNoisyAggregates is the same as ReconstructAggregates for a db
+// with noisy answers, whose aggregates are returned as signed integers since
+// the noise can make them negative
+func (c *PredicateAPIR) ReconstructNoisyAggregates(answers [][]uint32) ([]int64, error) {
+	values, err := c.reconstructAggregates(answers)
+	if err != nil {
+		return nil, err
+	}
+	p := c.Fss.Field.Modulus()
+	out := make([]int64, len(values))
+	for i, v := range values {
+		out[i] = int64(v)
+		if v > p/2 {
+			out[i] -= int64(p)
+		}
+	}
+	return out, nil
+}
+
+// Reconstruct64 is the same as Reconstruct for databases in the 64-bit field
+func (c *PredicateAPIR) Reconstruct64(answers [][]uint64) (uint64, error) {
+	return c.reconstruct64(answers)
+}
@@ -17,12 +17,15 @@ type PredicatePIR struct {
 // scheme
 func NewPredicatePIR(rnd io.Reader, info *database.Info) *PredicatePIR {
 	executions := 1
+	f := fss.ClientInitialize(executions) // only one value
+	f.SetField(info.Field())
+
 	return &PredicatePIR{
 		&clientFSS{
 			rnd:        rnd,
 			dbInfo:     info,
 			state:      nil,
-			Fss:        fss.ClientInitialize(executions), // only one value
+			Fss:        f,
 			executions: executions,
 		},
 	}
@@ -36,7 +39,7 @@ func (c *PredicatePIR) QueryBytes(in []byte, numServers int) ([][]byte, error) {
 
 // Query outputs the queries, i.e. DPF keys, for index i. The DPF
 // implementation assumes two servers.
-func (c *PredicatePIR) Query(q *query.ClientFSS, numServers int) []*query.FSS {
+func (c *PredicatePIR) Query(q *query.ClientFSS, numServers int) ([]*query.FSS, error) {
 	return c.query(q, numServers)
 }
 
@@ -49,3 +52,14 @@ func (c *PredicatePIR) ReconstructBytes(answers [][]byte) (interface{}, error) {
 func (c *PredicatePIR) Reconstruct(answers [][]uint32) (uint32, error) {
 	return c.reconstruct(answers)
 }
+
+// ReconstructAggregates returns the values of all the aggregates requested in
+// the query, in the same order as in query.Info.Aggregates.
+func (c *PredicatePIR) ReconstructAggregates(answers [][]uint32) ([]uint32, error) {
+	return c.reconstructAggregates(answers)
+}
+
+// Reconstruct64 is the same as Reconstruct for databases in the 64-bit field
+func (c *PredicatePIR) Reconstruct64(answers [][]uint64) (uint64, error) {
+	return c.reconstruct64(answers)
+}
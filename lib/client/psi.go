@@ -0,0 +1,121 @@
+package client
+
+import (
+	"errors"
+	"io"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// LabeledPSI is the client of the single-server labeled private set
+// intersection protocol based on the DH oblivious PRF. The client blinds the
+// hashes of its identifiers with random scalars, the server raises them to
+// its key, and the client unblinds the PRF outputs, from which it derives the
+// tags and the keys of the labels of its identifiers in the table of the
+// server. The communication of a query is proportional to the number of
+// identifiers, and the table is retrieved once. The server learns nothing
+// about the identifiers, and the client learns the labels of its identifiers
+// in the db only.
+type LabeledPSI struct {
+	rnd    io.Reader
+	dbInfo *database.Info
+	// records of the table of the server, by tag
+	table map[string][]byte
+	state *statePSI
+}
+
+type statePSI struct {
+	ids []string
+	// inverses of the blinding scalars
+	inverses []group.Scalar
+}
+
+// NewLabeledPSI returns a client of the labeled PSI db with the given info
+func NewLabeledPSI(rnd io.Reader, info *database.Info) *LabeledPSI {
+	return &LabeledPSI{rnd: rnd, dbInfo: info}
+}
+
+// SetTable stores the table of the encrypted labels sent by the server
+func (c *LabeledPSI) SetTable(table []byte) error {
+	n := database.PSIRecordLen(c.dbInfo)
+	if len(table) != c.dbInfo.NumColumns*n {
+		return errors.New("malformed table")
+	}
+	c.table = make(map[string][]byte, c.dbInfo.NumColumns)
+	for i := 0; i < len(table); i += n {
+		c.table[string(table[i:i+database.PSITagLen])] = table[i : i+n]
+	}
+	return nil
+}
+
+// QueryBytes returns the query of the identifiers, i.e., their blinded
+// hashes to the group
+func (c *LabeledPSI) QueryBytes(ids []string) ([]byte, error) {
+	defer monitor.Region("query").End()
+	if len(ids) == 0 {
+		return nil, errors.New("no identifiers")
+	}
+	g := c.dbInfo.Group
+	st := &statePSI{ids: ids, inverses: make([]group.Scalar, len(ids))}
+	blinded := make([]group.Element, len(ids))
+	for i, id := range ids {
+		r := g.RandomScalar(c.rnd)
+		st.inverses[i] = g.NewScalar().Inv(r)
+		blinded[i] = g.NewElement().Mul(database.HashIdentifierToGroup(id, g), r)
+	}
+	q, err := database.MarshalGroupElements(blinded, c.dbInfo.ElementSize)
+	if err != nil {
+		return nil, err
+	}
+	c.state = st
+	monitor.CountQuery(q)
+	return q, nil
+}
+
+// ReconstructBytes returns the labels of the identifiers of the last query
+// that are in the db, as a map[string][]byte
+func (c *LabeledPSI) ReconstructBytes(a []byte) (interface{}, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a)
+	return c.Reconstruct(a)
+}
+
+// Reconstruct returns the labels of the identifiers of the last query that
+// are in the db
+func (c *LabeledPSI) Reconstruct(a []byte) (map[string][]byte, error) {
+	if c.state == nil {
+		return nil, errors.New("no query to reconstruct")
+	}
+	if c.table == nil {
+		return nil, errors.New("no table of the server")
+	}
+	if len(a) != len(c.state.ids)*c.dbInfo.ElementSize {
+		return nil, errors.New("malformed answer")
+	}
+	evaluations, err := database.UnmarshalGroupElements(a, c.dbInfo.Group, c.dbInfo.ElementSize)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string][]byte)
+	for i, e := range evaluations {
+		e.Mul(e, c.state.inverses[i])
+		tag, key, err := database.PSIKeys(e)
+		if err != nil {
+			return nil, err
+		}
+		record, ok := c.table[string(tag)]
+		if !ok {
+			continue
+		}
+		label, err := database.OpenPSILabel(record, key)
+		if err != nil {
+			return nil, err
+		}
+		labels[c.state.ids[i]] = label
+	}
+	c.state = nil
+	return labels, nil
+}
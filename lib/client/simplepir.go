@@ -0,0 +1,167 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/proof"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// SimplePIR based single server PIR client. The client downloads the hint of
+// the db once, then every query retrieves a row of the db with a single
+// matrix-vector product by the server. The answers are not authenticated,
+// unless a verifier of the proofs of the server is set.
+type SimplePIR struct {
+	dbInfo *database.Info
+	params *utils.ParamsLWE
+	rnd    io.Reader
+
+	// public matrix, expanded once from its seed, and hint of the db
+	a    *matrix.Matrix
+	hint *matrix.Matrix
+
+	verifier proof.Verifier
+	state    *stateSimplePIR
+}
+
+type stateSimplePIR struct {
+	secret *matrix.Matrix
+	i      int
+	j      int
+	// encoded query, to verify the proof of the answer
+	query []byte
+}
+
+// NewSimplePIR returns a client of the db with the given info and encoded
+// hint, as returned by the server
+func NewSimplePIR(rnd io.Reader, info *database.Info, params *utils.ParamsLWE, hint []byte) (*SimplePIR, error) {
+	if params.P == 0 || (1<<32)%uint64(params.P) != 0 {
+		return nil, fmt.Errorf("the plaintext modulus %d does not divide 2^32", params.P)
+	}
+	if len(hint) < 8 {
+		return nil, errors.New("truncated hint")
+	}
+	h := matrix.BytesToMatrix(hint)
+	if h.Rows() != params.N || h.Cols() != params.M || h.Len() != params.N*params.M {
+		return nil, fmt.Errorf("hint of %d x %d elements for a %d x %d db", h.Rows(), h.Cols(), params.L, params.M)
+	}
+
+	return &SimplePIR{
+		dbInfo: info,
+		params: params,
+		rnd:    rnd,
+		a:      matrix.NewRandom(utils.NewPRG(params.SeedA), params.N, params.L),
+		hint:   h,
+	}, nil
+}
+
+// SetVerifier makes the client reject the answers whose proof does not verify
+// against the digest of the verifier, which must then be appended to every
+// answer by the server
+func (c *SimplePIR) SetVerifier(v proof.Verifier) {
+	c.verifier = v
+}
+
+// Query returns the query of the entry (i, j), which retrieves the row i
+func (c *SimplePIR) Query(i, j int) *matrix.Matrix {
+	msg := make([]uint32, c.params.L)
+	msg[i] = 1
+	return c.encrypt(msg, i, j)
+}
+
+// encrypt returns the encryption of the vector of plaintexts in Z_P, one per
+// row of the db, as the query of the entry (i, j)
+func (c *SimplePIR) encrypt(plaintexts []uint32, i, j int) *matrix.Matrix {
+	c.state = &stateSimplePIR{
+		secret: matrix.NewRandom(c.rnd, 1, c.params.N),
+		i:      i,
+		j:      j,
+	}
+
+	// Query has dimension 1 x l
+	query := matrix.Mul(c.state.secret, c.a)
+
+	// Error has dimension 1 x l
+	e := matrix.NewGauss(1, c.params.L)
+
+	msg := matrix.New(1, c.params.L)
+	for k, p := range plaintexts {
+		msg.Set(0, k, p*c.delta())
+	}
+
+	query.Add(e)
+	query.Add(msg)
+
+	return query
+}
+
+func (c *SimplePIR) QueryBytes(index int) ([]byte, error) {
+	defer monitor.Region("query").End()
+	i, j := utils.VectorToMatrixIndices(index, c.dbInfo.NumColumns)
+	m := c.Query(i, j)
+	q := matrix.MatrixToBytes(m)
+	c.state.query = q
+	monitor.CountQuery(q)
+	return q, nil
+}
+
+// Reconstruct returns the row of the db retrieved by the last query, by
+// rounding the answer without the contribution of the hint
+func (c *SimplePIR) Reconstruct(answer *matrix.Matrix) ([]byte, error) {
+	if answer.Rows() != 1 || answer.Cols() != c.params.M || answer.Len() != c.params.M {
+		return nil, errors.New("malformed answer")
+	}
+	answer.Sub(matrix.Mul(c.state.secret, c.hint))
+
+	delta := c.delta()
+	row := make([]byte, c.params.M)
+	for k := range row {
+		row[k] = byte(((answer.Get(0, k) + delta/2) / delta) % c.params.P)
+	}
+
+	return row, nil
+}
+
+// ReconstructBytes returns the entry retrieved by the last query
+func (c *SimplePIR) ReconstructBytes(a []byte) (byte, error) {
+	defer monitor.Region("reconstruct").End()
+	monitor.CountReconstruct(a)
+	answer, err := c.verify(c.state.query, a)
+	if err != nil {
+		return 0, err
+	}
+	row, err := c.Reconstruct(answer)
+	if err != nil {
+		return 0, err
+	}
+	return row[c.state.j], nil
+}
+
+// verify returns the decoded answer to the encoded query, after verifying
+// its proof if the client has a verifier
+func (c *SimplePIR) verify(query, a []byte) (*matrix.Matrix, error) {
+	if c.verifier != nil {
+		msgs, err := utils.SplitMessages(a, 2)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.verifier.Verify(query, msgs[0], msgs[1]); err != nil {
+			return nil, errors.New("REJECT!")
+		}
+		a = msgs[0]
+	}
+	if len(a) < 8 {
+		return nil, errors.New("truncated answer")
+	}
+	return matrix.BytesToMatrix(a), nil
+}
+
+// delta returns the scaling factor of the plaintexts, 2^32 / P
+func (c *SimplePIR) delta() uint32 {
+	return uint32((1 << 32) / uint64(c.params.P))
+}
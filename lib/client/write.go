@@ -0,0 +1,120 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/query"
+)
+
+// Writer is the client of the two-server private write scheme in the style
+// of Riposte. The servers hold additive shares of a db of slots of BlockSize
+// field elements. A write is a DPF of the point function that is the message
+// at its slot, so that every server adds its share of the full domain to its
+// share of the db without learning the slot, and the db published at the end
+// of an epoch does not link the messages to their writers. The write also
+// carries shares of Beaver triples, with which the servers check that it is a
+// point function before applying it.
+type Writer struct {
+	rnd    io.Reader
+	dbInfo *database.Info
+	fss    *fss.Fss
+}
+
+// NewWriter returns a client writing to the db with the given info, whose
+// slots are its blocks
+func NewWriter(rnd io.Reader, info *database.Info) *Writer {
+	f := fss.ClientInitialize(info.BlockSize)
+	f.SetField(info.Field())
+	return &Writer{
+		rnd:    rnd,
+		dbInfo: info,
+		fss:    f,
+	}
+}
+
+// WriteBytes executes Write and encodes the writes in bytes
+func (c *Writer) WriteBytes(slot int, message []uint32) ([][]byte, error) {
+	defer monitor.Region("query").End()
+	writes, err := c.Write(slot, message)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, len(writes))
+	for i, w := range writes {
+		if data[i], err = w.Encode(); err != nil {
+			return nil, err
+		}
+	}
+	monitor.CountQuery(data...)
+
+	return data, nil
+}
+
+// Write returns the writes of the message, of BlockSize reduced elements, at
+// the given slot, one per server
+func (c *Writer) Write(slot int, message []uint32) ([]*query.Write, error) {
+	numSlots := c.dbInfo.NumRows * c.dbInfo.NumColumns
+	if slot < 0 || slot >= numSlots {
+		return nil, fmt.Errorf("slot %d out of range", slot)
+	}
+	if len(message) != c.dbInfo.BlockSize {
+		return nil, fmt.Errorf("message of %d elements, expected %d", len(message), c.dbInfo.BlockSize)
+	}
+	fl := c.fss.Field
+	for _, m := range message {
+		if m >= fl.Modulus() {
+			return nil, errors.New("message element not reduced")
+		}
+	}
+
+	id := make([]byte, query.WriteIDLen)
+	if _, err := io.ReadFull(c.rnd, id); err != nil {
+		return nil, err
+	}
+	f := c.fss.Current()
+	keys := f.GenerateTreePF(fss.IndexToBits(slot, fss.NumBitsForDomain(numSlots)), message)
+	writes := []*query.Write{
+		{ID: id, FssKey: keys[0]},
+		{ID: id, FssKey: keys[1]},
+	}
+
+	// a triple (a, b, ab) per product of the audit, additively shared
+	for t := range writes[0].Triples {
+		a, b := fl.RandElementWithPRG(c.rnd), fl.RandElementWithPRG(c.rnd)
+		for i, v := range []uint32{a, b, fl.Mul(a, b)} {
+			share := fl.RandElementWithPRG(c.rnd)
+			writes[0].Triples[t][i] = share
+			writes[1].Triples[t][i] = fl.Sub(v, share)
+		}
+	}
+
+	return writes, nil
+}
+
+// Reconstruct returns the db published by the servers at the end of an
+// epoch, i.e., the sum of their shares, slot by slot
+func (c *Writer) Reconstruct(shares [][]byte) ([]uint32, error) {
+	if len(shares) != 2 {
+		return nil, errors.New("the write scheme needs two shares")
+	}
+	fl := c.fss.Field
+	length := c.dbInfo.NumRows * c.dbInfo.NumColumns * c.dbInfo.BlockSize
+	db := make([]uint32, length)
+	for _, s := range shares {
+		elements, err := fl.DecodeElements(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements) != length {
+			return nil, errors.New("malformed share")
+		}
+		fl.AddVectors(db, db, elements)
+	}
+	return db, nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// NumBatchHashes is the number of buckets in which every block of a db
+// encoded with the cuckoo batch code is replicated
+const NumBatchHashes = 3
+
+// BatchLayout is the layout of a db encoded with a cuckoo batch code, in the
+// style of the probabilistic batch codes of SealPIR: every block is
+// replicated in NumBatchHashes buckets out of 1.5 times as many as the
+// blocks retrieved in a batch. A client cuckoo-hashes the blocks of a batch
+// to distinct buckets and retrieves one block per bucket, so that the servers
+// scan every block NumBatchHashes times per batch instead of once per block.
+// The layout depends only on the info of the db and the size of the batches,
+// so that the clients compute it as the servers do.
+type BatchLayout struct {
+	BatchSize int
+	// indices of the blocks of the db in every bucket, in increasing order
+	Buckets [][]int
+	// info of the db of every bucket
+	Infos []*Info
+}
+
+// NewBatchLayout returns the layout of the db with the given info for
+// batches of the given number of blocks. The dbs of the buckets have the
+// vector representation if the db has it, and a square one otherwise.
+func NewBatchLayout(info *Info, batchSize int) (*BatchLayout, error) {
+	if batchSize < 1 {
+		return nil, errors.New("the batch size must be positive")
+	}
+	numBuckets := (3*batchSize + 1) / 2
+	if numBuckets < NumBatchHashes {
+		numBuckets = NumBatchHashes
+	}
+
+	l := &BatchLayout{
+		BatchSize: batchSize,
+		Buckets:   make([][]int, numBuckets),
+		Infos:     make([]*Info, numBuckets),
+	}
+	numBlocks := info.NumRows * info.NumColumns
+	for i := 0; i < numBlocks; i++ {
+		for _, b := range BatchBuckets(i, numBuckets) {
+			l.Buckets[b] = append(l.Buckets[b], i)
+		}
+	}
+	for b, bucket := range l.Buckets {
+		// an empty bucket still answers dummy queries
+		n := len(bucket)
+		if n == 0 {
+			n = 1
+		}
+		numRows, numColumns := 1, n
+		if info.NumRows != 1 {
+			numRows, numColumns = CalculateNumRowsAndColumns(n, true)
+		}
+		l.Infos[b] = &Info{
+			NumRows:    numRows,
+			NumColumns: numColumns,
+			BlockSize:  info.BlockSize,
+			PIRType:    info.PIRType,
+			Merkle:     &Merkle{ProofLen: 0}, // only for tests compatibility
+		}
+	}
+
+	return l, nil
+}
+
+// BatchBuckets returns the distinct buckets of the block of the given index
+func BatchBuckets(index, numBuckets int) [NumBatchHashes]int {
+	var buckets [NumBatchHashes]int
+	buf := make([]byte, 12)
+	binary.BigEndian.PutUint64(buf, uint64(index))
+	found := 0
+	for counter := uint32(0); found < NumBatchHashes; counter++ {
+		binary.BigEndian.PutUint32(buf[8:], counter)
+		h := sha256.Sum256(buf)
+		b := int(binary.BigEndian.Uint64(h[:8]) % uint64(numBuckets))
+		distinct := true
+		for _, other := range buckets[:found] {
+			if other == b {
+				distinct = false
+			}
+		}
+		if distinct {
+			buckets[found] = b
+			found++
+		}
+	}
+	return buckets
+}
+
+// Position returns the index of the block of the db in the db of the bucket,
+// or -1 if the block is not in the bucket
+func (l *BatchLayout) Position(index, bucket int) int {
+	blocks := l.Buckets[bucket]
+	k := sort.SearchInts(blocks, index)
+	if k == len(blocks) || blocks[k] != index {
+		return -1
+	}
+	return k
+}
+
+// Encode returns the dbs of the buckets of the db, whose blocks keep their
+// length. The buckets are padded with empty blocks.
+func (l *BatchLayout) Encode(db *Bytes) []*Bytes {
+	// the blocks of a db of keys are not padded to the block size
+	offsets := make([]int, len(db.BlockLengths)+1)
+	for i, n := range db.BlockLengths {
+		offsets[i+1] = offsets[i] + n
+	}
+
+	out := make([]*Bytes, len(l.Buckets))
+	for b, bucket := range l.Buckets {
+		info := *l.Infos[b]
+		info.BlockLengths = make([]int, info.NumRows*info.NumColumns)
+		entries := make([]byte, 0)
+		for k, i := range bucket {
+			block := db.Block(i)
+			// only the row-major layout has blocks of variable length
+			if db.Layout == RowMajor && len(db.BlockLengths) != 0 {
+				block = db.Entries[offsets[i]:offsets[i+1]]
+			}
+			entries = append(entries, block...)
+			info.BlockLengths[k] = len(block)
+		}
+		out[b] = &Bytes{Entries: entries, Info: info}
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Bloom is a Bloom filter of the keywords of a db, queried privately before
+// retrieving the block of a keyword, so that a client does not pay for the
+// retrieval of keywords that are not in the db. The filter has NumColumns
+// bits, packed with the bit j in the bit j%8 of the byte j/8.
+type Bloom struct {
+	Info
+	Bits []byte
+}
+
+// NewBloom returns the Bloom filter of the keywords with the given rate of
+// false positives
+func NewBloom(keywords []string, falsePositives float64) (*Bloom, error) {
+	if falsePositives <= 0 || falsePositives >= 1 {
+		return nil, errors.New("the rate of false positives must be in (0, 1)")
+	}
+	n := len(keywords)
+	if n == 0 {
+		n = 1
+	}
+	// optimal number of bits and of hashes for n keywords
+	numBits := int(math.Ceil(-float64(n) * math.Log(falsePositives) / (math.Ln2 * math.Ln2)))
+	numHashes := int(math.Round(float64(numBits) / float64(n) * math.Ln2))
+	if numHashes < 1 {
+		numHashes = 1
+	}
+
+	b := &Bloom{
+		Info: Info{
+			NumRows:     1,
+			NumColumns:  numBits,
+			BloomParams: &BloomParams{NumHashes: numHashes},
+		},
+		Bits: make([]byte, numBits/8+1),
+	}
+	for _, k := range keywords {
+		for _, j := range BloomPositions(k, &b.Info) {
+			b.Bits[j/8] |= 1 << (j % 8)
+		}
+	}
+	return b, nil
+}
+
+// BloomPositions returns the positions of the bits of the keyword in the
+// Bloom filter with the given info, with double hashing
+func BloomPositions(keyword string, info *Info) []int {
+	h := sha256.Sum256([]byte(keyword))
+	m := uint64(info.NumColumns)
+	a := binary.BigEndian.Uint64(h[:8]) % m
+	// an odd step visits distinct positions when m is a power of two
+	b := binary.BigEndian.Uint64(h[8:16])%m | 1
+	positions := make([]int, info.NumHashes)
+	for i := range positions {
+		positions[i] = int((a + uint64(i)*b) % m)
+	}
+	return positions
+}
+
+// Contains returns true if the keyword is in the filter or is a false
+// positive
+func (b *Bloom) Contains(keyword string) bool {
+	for _, j := range BloomPositions(keyword, &b.Info) {
+		if (b.Bits[j/8]>>(j%8))&1 == 0 {
+			return false
+		}
+	}
+	return true
+}
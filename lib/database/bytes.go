@@ -1,6 +1,7 @@
 package database
 
 import (
+	"crypto/sha256"
 	"io"
 	"log"
 )
@@ -8,6 +9,9 @@ import (
 type Bytes struct {
 	Entries []byte
 	Info
+
+	// order of the blocks in the entries, row-major by default
+	Layout Layout
 }
 
 // CreateBitBytes return a random bytes database.
@@ -47,13 +51,16 @@ func InitBytes(numRows, numColumns, blockLen int) *Bytes {
 // CreateRandomBytes return a random bytes database.
 // blockLen must be the number of bytes in a block, as a byte is the element
 func CreateRandomBytes(rnd io.Reader, dbLen, numRows, blockLen int) *Bytes {
+	numColumns, err := randomColumns(dbLen, numRows, blockLen)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// sample random entries
-	entries := make([]byte, dbLen/8)
+	entries := make([]byte, numRows*numColumns*blockLen)
 	if _, err := rnd.Read(entries); err != nil {
 		log.Fatal(err)
 	}
-
-	numColumns := dbLen / (8 * numRows * blockLen)
 	blockLens := make([]int, numRows*numColumns)
 	for i := 0; i < numRows*numColumns; i++ {
 		blockLens[i] = blockLen
@@ -72,3 +79,21 @@ func CreateRandomBytes(rnd io.Reader, dbLen, numRows, blockLen int) *Bytes {
 func (b *Bytes) SizeGiB() float64 {
 	return float64(len(b.Entries)) * 9.313e-10
 }
+
+// Digest returns the digest of the db: the root of the Merkle tree for the
+// authenticated dbs, the hash of the entries in row-major order otherwise,
+// so that the digest does not depend on the layout
+func (b *Bytes) Digest() []byte {
+	if b.Merkle != nil && len(b.Root) > 0 {
+		return b.Root
+	}
+	if b.Layout != RowMajor {
+		h := sha256.New()
+		for i := 0; i < b.NumRows*b.NumColumns; i++ {
+			h.Write(b.Block(i))
+		}
+		return h.Sum(nil)
+	}
+	h := sha256.Sum256(b.Entries)
+	return h[:]
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/si-co/vpir-code/lib/merkle"
+	"github.com/si-co/vpir-code/lib/pgp"
+)
+
+// maxCheckFailures bounds the number of failures reported by the checks, so
+// that a corrupted db does not flood the report
+const maxCheckFailures = 100
+
+// Occupancy is the occupancy of the hash table of a keys db
+type Occupancy struct {
+	// number of buckets of the hash table and of blocks of the db, the
+	// overflow blocks included
+	Buckets        int
+	Blocks         int
+	OverflowBlocks int
+
+	EmptyBuckets int
+	// number of entries of all the buckets, and of the fullest one
+	Entries    int
+	MaxEntries int
+	// Histogram[i] is the number of buckets with i entries
+	Histogram []int
+
+	// bytes of entries stored in the blocks, out of Blocks * BlockSize
+	UsedBytes int
+	BlockSize int
+}
+
+// Occupancy returns the occupancy of the hash table of a keys db
+func (b *Bytes) Occupancy() (*Occupancy, error) {
+	blocks, err := b.blocks()
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Occupancy{
+		Buckets:   b.NumBuckets(),
+		Blocks:    len(blocks),
+		BlockSize: b.BlockSize,
+		Histogram: make([]int, 1),
+	}
+	for k := 0; k < o.Buckets; k++ {
+		bucket, chain, err := b.bucket(blocks, k)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := pgp.SplitEntries(bucket)
+		if err != nil {
+			return nil, fmt.Errorf("bucket %d: %v", k, err)
+		}
+
+		n := len(entries)
+		o.OverflowBlocks += chain - 1
+		o.Entries += n
+		o.UsedBytes += len(bucket)
+		if n == 0 {
+			o.EmptyBuckets++
+		}
+		if n > o.MaxEntries {
+			o.MaxEntries = n
+		}
+		for len(o.Histogram) <= n {
+			o.Histogram = append(o.Histogram, 0)
+		}
+		o.Histogram[n]++
+	}
+
+	return o, nil
+}
+
+// Check runs the integrity checks of a keys db: the layout of the blocks, the
+// chains of the buckets, the framing of their entries and, for the dbs with
+// Merkle proofs, the proof of every block. It returns the failures, none if
+// the db is sound.
+func (b *Bytes) Check() []error {
+	failures := make([]error, 0)
+	fail := func(format string, a ...interface{}) bool {
+		failures = append(failures, fmt.Errorf(format, a...))
+		return len(failures) < maxCheckFailures
+	}
+
+	blocks, err := b.blocks()
+	if err != nil {
+		fail("%v", err)
+		return failures
+	}
+	for k, block := range blocks {
+		if len(block) > b.BlockSize {
+			if !fail("block %d of length %d exceeds the block size %d", k, len(block), b.BlockSize) {
+				return failures
+			}
+		}
+		if b.PIRType != "merkle" {
+			continue
+		}
+		if _, err := b.verifyBlock(block); err != nil {
+			if !fail("block %d: %v", k, err) {
+				return failures
+			}
+		}
+	}
+
+	for k := 0; k < b.NumBuckets(); k++ {
+		bucket, _, err := b.bucket(blocks, k)
+		if err == nil {
+			_, err = pgp.SplitEntries(bucket)
+		}
+		if err != nil {
+			if !fail("bucket %d: %v", k, err) {
+				return failures
+			}
+		}
+	}
+
+	return failures
+}
+
+// Check runs the integrity checks of the metadata db of the predicate queries
+// and returns the failures, none if the db is sound
+func (d *DB) Check() []error {
+	failures := make([]error, 0)
+	if d.NumColumns != len(d.KeysInfo) {
+		failures = append(failures, fmt.Errorf("%d keys in a db of %d columns", len(d.KeysInfo), d.NumColumns))
+	}
+	for i, k := range d.KeysInfo {
+		if k == nil || k.UserId == nil {
+			failures = append(failures, fmt.Errorf("key %d has no user id", i))
+			if len(failures) >= maxCheckFailures {
+				break
+			}
+		}
+	}
+	return failures
+}
+
+// blocks splits the entries of the db in blocks according to their lengths
+func (b *Bytes) blocks() ([][]byte, error) {
+	if len(b.BlockLengths) != b.NumRows*b.NumColumns {
+		return nil, fmt.Errorf("%d block lengths for a db of %d blocks", len(b.BlockLengths), b.NumRows*b.NumColumns)
+	}
+	blocks := make([][]byte, len(b.BlockLengths))
+	if b.Layout != RowMajor {
+		// the blocks of the other layouts are all of the block size
+		if len(b.Entries) != len(blocks)*b.BlockSize {
+			return nil, fmt.Errorf("%d bytes of entries for a db of %d blocks in the %v layout", len(b.Entries), len(blocks), b.Layout)
+		}
+		for k := range blocks {
+			blocks[k] = b.Block(k)
+		}
+		return blocks, nil
+	}
+	pos := 0
+	for k, l := range b.BlockLengths {
+		if l < 0 || pos+l > len(b.Entries) {
+			return nil, fmt.Errorf("block %d exceeds the entries of the db", k)
+		}
+		blocks[k] = b.Entries[pos : pos+l]
+		pos += l
+	}
+	if pos != len(b.Entries) {
+		return nil, fmt.Errorf("%d bytes of entries after the last block", len(b.Entries)-pos)
+	}
+	return blocks, nil
+}
+
+// verifyBlock returns the data of a block of a db with Merkle proofs, after
+// checking its proof against the root of the db
+func (b *Bytes) verifyBlock(block []byte) ([]byte, error) {
+	if len(block) == 0 || block[len(block)-1] != 0x80 {
+		return nil, fmt.Errorf("the proof is not padded")
+	}
+	block = block[:len(block)-1]
+	if len(block) < b.ProofLen {
+		return nil, fmt.Errorf("block shorter than its proof")
+	}
+	data := block[:len(block)-b.ProofLen]
+	proof := merkle.DecodeProof(block[len(block)-b.ProofLen:])
+	verified, err := merkle.VerifyProof(data, proof, b.Root)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, fmt.Errorf("invalid Merkle proof")
+	}
+	return data, nil
+}
+
+// bucket returns the data of the k-th bucket of the hash table, following
+// the chain of its overflow blocks, along with the length of the chain
+func (b *Bytes) bucket(blocks [][]byte, k int) ([]byte, int, error) {
+	bucket := make([]byte, 0)
+	visited := make(map[int]bool)
+	for index := k; ; {
+		block := blocks[index]
+		if b.PIRType == "merkle" {
+			// drop the proof and its padding
+			if len(block) < b.ProofLen+1 {
+				return nil, 0, fmt.Errorf("block %d shorter than its proof", index)
+			}
+			block = block[:len(block)-b.ProofLen-1]
+		}
+		if len(block) == 0 || block[len(block)-1] != 0x80 {
+			return nil, 0, fmt.Errorf("block %d is not padded", index)
+		}
+		next, data, err := SplitBlock(block[:len(block)-1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("block %d: %v", index, err)
+		}
+		bucket = append(bucket, data...)
+		visited[index] = true
+		if next == 0 {
+			return bucket, len(visited), nil
+		}
+		if next < b.NumBuckets() || next >= len(blocks) || visited[next] {
+			return nil, 0, fmt.Errorf("block %d continues in invalid block %d", index, next)
+		}
+		index = next
+	}
+}
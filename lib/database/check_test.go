@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAndOccupancy(t *testing.T) {
+	rng := utils.RandomPRG()
+	keys := make([]*pgp.Key, 40)
+	for i := range keys {
+		packet := make([]byte, 3072)
+		_, err := rng.Read(packet)
+		require.NoError(t, err)
+		keys[i] = &pgp.Key{ID: fmt.Sprintf("user%d@example.org", i), Packet: packet}
+	}
+
+	bytesDB := keysToBytes(keys, true)
+	merkleDB, err := keysToMerkle(keys, true)
+	require.NoError(t, err)
+
+	for _, db := range []*Bytes{bytesDB, merkleDB} {
+		require.Empty(t, db.Check())
+
+		o, err := db.Occupancy()
+		require.NoError(t, err)
+		require.Equal(t, len(keys), o.Entries)
+		require.Equal(t, db.HashTableLen, o.Buckets)
+		require.Equal(t, db.NumRows*db.NumColumns, o.Blocks)
+		require.Greater(t, o.OverflowBlocks, 0)
+		sum, weighted := 0, 0
+		for n, c := range o.Histogram {
+			sum += c
+			weighted += n * c
+		}
+		require.Equal(t, o.Buckets, sum)
+		require.Equal(t, o.Entries, weighted)
+		require.Equal(t, o.Histogram[0], o.EmptyBuckets)
+	}
+
+	// corrupt the chain of the first bucket
+	bytesDB.Entries[0] = 0xff
+	require.NotEmpty(t, bytesDB.Check())
+
+	// corrupt the data of a block
+	merkleDB.Entries[len(merkleDB.Entries)/2] ^= 1
+	require.NotEmpty(t, merkleDB.Check())
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/si-co/vpir-code/lib/logging"
+	"github.com/si-co/vpir-code/lib/pgp"
+)
+
+// ctEntries is the response of the get-entries method of a Certificate
+// Transparency log (RFC 6962, Section 4.6), as stored in a snapshot file
+type ctEntries struct {
+	Entries []struct {
+		LeafInput []byte `json:"leaf_input"`
+		ExtraData []byte `json:"extra_data"`
+	} `json:"entries"`
+}
+
+// GenerateCTBytes returns a bytes db storing the entries of the Certificate
+// Transparency log snapshot files, indexed by their Merkle leaf hash
+func GenerateCTBytes(dataPaths []string, rebalanced bool) (*Bytes, error) {
+	logging.Default().Info("loading CT entries of the bytes db", logging.F("rebalanced", rebalanced), logging.F("files", dataPaths))
+
+	entries, err := LoadCTEntries(dataPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	return keysToBytes(entries, rebalanced), nil
+}
+
+// GenerateCTMerkle returns a db with Merkle proofs storing the entries of the
+// Certificate Transparency log snapshot files, indexed by their Merkle leaf
+// hash
+func GenerateCTMerkle(dataPaths []string, rebalanced bool) (*Bytes, error) {
+	logging.Default().Info("loading CT entries of the Merkle db", logging.F("rebalanced", rebalanced), logging.F("files", dataPaths))
+
+	entries, err := LoadCTEntries(dataPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	return keysToMerkle(entries, rebalanced)
+}
+
+// LoadCTEntries reads the entries of Certificate Transparency log snapshot
+// files, each containing the JSON output of get-entries. Every entry is
+// returned as a key whose id is the hex-encoded leaf hash of RFC 6962 and
+// whose packet is the leaf input, i.e., the timestamped certificate.
+func LoadCTEntries(files []string) ([]*pgp.Key, error) {
+	keys := make([]*pgp.Key, 0)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		ct := new(ctEntries)
+		if err := json.Unmarshal(data, ct); err != nil {
+			return nil, err
+		}
+		for _, e := range ct.Entries {
+			keys = append(keys, &pgp.Key{ID: ctLeafHash(e.LeafInput), Packet: e.LeafInput})
+		}
+	}
+	return keys, nil
+}
+
+// ctLeafHash returns the hex-encoded hash of a Merkle tree leaf of a
+// Certificate Transparency log
+func ctLeafHash(leaf []byte) string {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(leaf)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCTDatabase(t *testing.T) {
+	rng := utils.RandomPRG()
+	numEntries := 50
+
+	// write a snapshot with entries of different lengths
+	snapshot := map[string][]map[string][]byte{"entries": nil}
+	leaves := make([][]byte, numEntries)
+	for i := range leaves {
+		leaves[i] = make([]byte, 100+i)
+		_, err := rng.Read(leaves[i])
+		require.NoError(t, err)
+		snapshot["entries"] = append(snapshot["entries"], map[string][]byte{"leaf_input": leaves[i]})
+	}
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "entries.json")
+	require.NoError(t, ioutil.WriteFile(file, data, 0644))
+
+	keys, err := LoadCTEntries([]string{file})
+	require.NoError(t, err)
+	require.Len(t, keys, numEntries)
+
+	db, err := GenerateCTBytes([]string{file}, true)
+	require.NoError(t, err)
+	numBlocks := db.NumRows * db.NumColumns
+	for i, leaf := range leaves {
+		// the block of the entry contains the leaf
+		index := int(HashToIndex(ctLeafHash(leaf), numBlocks))
+		begin := 0
+		for _, l := range db.BlockLengths[:index] {
+			begin += l
+		}
+		block := db.Entries[begin : begin+db.BlockLengths[index]]
+		require.True(t, bytes.Contains(block, leaf), "entry %d not found", i)
+	}
+
+	m, err := GenerateCTMerkle([]string{file}, true)
+	require.NoError(t, err)
+	require.Equal(t, "merkle", m.PIRType)
+	require.Equal(t, numBlocks, m.NumRows*m.NumColumns)
+}
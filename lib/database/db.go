@@ -2,9 +2,9 @@ package database
 
 import (
 	"crypto"
+	"crypto/sha256"
 	"encoding/binary"
 	"io"
-	"math"
 	"math/rand"
 	"time"
 
@@ -13,6 +13,7 @@ import (
 	"github.com/cloudflare/circl/group"
 	"github.com/nikirill/go-crypto/openpgp/packet"
 	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/kzg"
 	"github.com/si-co/vpir-code/lib/matrix"
 	"github.com/si-co/vpir-code/lib/utils"
 	"golang.org/x/crypto/blake2b"
@@ -41,10 +42,57 @@ type Info struct {
 	// PIR type: classical, merkle
 	PIRType string
 
+	// symmetric PIR: the servers mask their answers with shared randomness
+	// that cancels out in the sum of the answers, so that the client learns
+	// nothing but the retrieved block. Only for the vector representation.
+	SPIR bool
+
+	// number of blocks of the hash table of a keys db, followed by the
+	// overflow blocks of the buckets that do not fit in one block. All the
+	// blocks are in the hash table when zero.
+	HashTableLen int
+
+	// bit size of the prime field used by the FSS-based schemes:
+	// field.Bits (default, also when zero) or field.Bits64
+	FieldBits int
+	// prime modulus of the field used by the FSS-based schemes for 32-bit
+	// elements, field.ModP when zero
+	Modulus uint32
+
+	// differential privacy: the servers of the FSS-based authenticated
+	// schemes add to every aggregate of an answer a noise calibrated to
+	// this epsilon. The answers are exact when zero.
+	NoiseEpsilon float64
+
 	*Auth
 	*Merkle
+	*ConstantWeight
+	*BloomParams
+
+	// digest of the polynomial commitment of the blocks, only for the
+	// KZG-based approach
+	KZG *kzg.Digest
+}
+
+// SPIRNonceLen is the length in bytes of the nonce of the SPIR queries, which
+// selects the masks of the servers. The nonce starts with the 64-bit epoch in
+// which it is sent, followed by random bytes.
+const SPIRNonceLen = 16
+
+// SPIREpochLen is the length of the epochs of the SPIR nonces. The servers
+// only answer the nonces of the current and the adjacent epochs, so that
+// they remember the nonces answered for three epochs at most.
+const SPIREpochLen = time.Hour
+
+// SPIREpoch returns the epoch of the SPIR nonces at time t
+func SPIREpoch(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(SPIREpochLen/time.Second)
 }
 
+// NoiseNonceLen is the length in bytes of the nonce of the queries to a db
+// with noisy answers, which selects the noise of the servers
+const NoiseNonceLen = 16
+
 // Auth is authentication information for the single-server setting
 type Auth struct {
 	DigestLWE    *matrix.Matrix
@@ -72,6 +120,20 @@ type Merkle struct {
 	ProofLen int
 }
 
+// ConstantWeight is the info of the constant-weight code of a keyword db
+type ConstantWeight struct {
+	// length of the codewords, i.e., of the queries
+	CodeLength int
+	// number of ones of every codeword
+	CodeWeight int
+}
+
+// BloomParams is the info of a Bloom filter, whose number of bits is the
+// number of columns of the db
+type BloomParams struct {
+	NumHashes int
+}
+
 func NewKeysDB(info Info) *DB {
 	return &DB{
 		Info:     info,
@@ -88,10 +150,13 @@ func NewBitsDB(info Info) *DB {
 }
 
 func CreateRandomBitsDB(rnd io.Reader, dbLen, numRows, blockLen int) (*DB, error) {
-	numColumns := dbLen / (8 * field.Bytes * numRows * blockLen)
-	// handle very small db
-	if numColumns == 0 {
-		numColumns = 1
+	blockBytes, err := utils.CheckedMul(field.Bytes, blockLen)
+	if err != nil {
+		return nil, err
+	}
+	numColumns, err := randomColumns(dbLen, numRows, blockBytes)
+	if err != nil {
+		return nil, err
 	}
 
 	info := Info{
@@ -120,7 +185,9 @@ func CreateRandomBitsDB(rnd io.Reader, dbLen, numRows, blockLen int) (*DB, error
 	return db, nil
 }
 
-func CreateRandomKeysDB(rnd io.Reader, numIdentifiers int) (*DB, error) {
+// CreateRandomKeysDB returns a database of random keys for the FSS-based
+// schemes. The optional fieldBits selects the field used by the schemes.
+func CreateRandomKeysDB(rnd io.Reader, numIdentifiers int, fieldBits ...int) (*DB, error) {
 	// only used for eval, so fine to init the seed for
 	// non-crypto PRG with fixed number
 	rand.Seed(int64(2<<32 - 7))
@@ -150,6 +217,9 @@ func CreateRandomKeysDB(rnd io.Reader, numIdentifiers int) (*DB, error) {
 
 	// only information needed for FSS-based schemes
 	info := Info{NumColumns: numIdentifiers}
+	if len(fieldBits) > 0 {
+		info.FieldBits = fieldBits[0]
+	}
 
 	return &DB{
 		KeysInfo: keysInfo,
@@ -166,8 +236,10 @@ func HashToIndex(id string, length int) uint32 {
 
 func CalculateNumRowsAndColumns(numBlocks int, matrix bool) (numRows, numColumns int) {
 	if matrix {
-		utils.IncreaseToNextSquare(&numBlocks)
-		numColumns = int(math.Sqrt(float64(numBlocks)))
+		numColumns = utils.ISqrt(numBlocks)
+		if numColumns*numColumns < numBlocks {
+			numColumns++
+		}
 		numRows = numColumns
 	} else {
 		numColumns = numBlocks
@@ -176,6 +248,66 @@ func CalculateNumRowsAndColumns(numBlocks int, matrix bool) (numRows, numColumns
 	return
 }
 
+// randomColumns returns the number of columns of a random db of at least
+// dbLen bits in numRows rows of blocks of blockLen bytes, the last column
+// padded with random blocks, so that the entries always fill the matrix
+func randomColumns(dbLen, numRows, blockLen int) (int, error) {
+	numBlocks, err := utils.NumBlocks(dbLen, blockLen)
+	if err != nil {
+		return 0, err
+	}
+	return utils.NumColumns(numBlocks, numRows, blockLen)
+}
+
 func (d *DB) SizeGiB() float64 {
 	return float64(len(d.Entries)*16) * 9.313e-10
 }
+
+// Digest returns the hash of the entries and of the metadata of the keys
+func (d *DB) Digest() []byte {
+	h := sha256.New()
+	buf := make([]byte, 8)
+	for _, e := range d.Entries {
+		binary.BigEndian.PutUint32(buf, e)
+		h.Write(buf[:4])
+	}
+	for _, k := range d.KeysInfo {
+		if k.UserId != nil {
+			h.Write([]byte(k.UserId.Id))
+		}
+		h.Write([]byte{0})
+		binary.BigEndian.PutUint64(buf, uint64(k.CreationTime.Unix()))
+		h.Write(buf)
+		binary.BigEndian.PutUint16(buf, k.BitLength)
+		h.Write([]byte{buf[0], buf[1], byte(k.PubKeyAlgo)})
+	}
+	return h.Sum(nil)
+}
+
+// NumBuckets returns the number of blocks of the hash table of a keys db,
+// i.e., the length to use in HashToIndex
+func (i *Info) NumBuckets() int {
+	if i.HashTableLen == 0 {
+		return i.NumRows * i.NumColumns
+	}
+	return i.HashTableLen
+}
+
+// UseField64 returns true if the FSS-based schemes must work in the 64-bit
+// field
+func (i *Info) UseField64() bool {
+	return i.FieldBits == field.Bits64
+}
+
+// Field returns the field used by the FSS-based schemes for 32-bit elements.
+// It panics if the modulus is not a valid one.
+func (i *Info) Field() *field.Field {
+	if i.Modulus == 0 {
+		return field.Default()
+	}
+	f, err := field.New(i.Modulus)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/si-co/vpir-code/lib/matrix"
+)
+
+// ClientDigest returns the short digest of the authenticated db with the
+// given info, which a client holds to verify the answers of the servers
+// even if all of them are malicious. It is the root of the Merkle tree, the
+// global digest of the elliptic scheme, or the hash of the digest of the LWE
+// and KZG-based schemes. The digests of the different schemes never collide,
+// as they are prefixed by the scheme.
+func ClientDigest(info *Info) ([]byte, error) {
+	h := sha256.New()
+	switch {
+	case info.Merkle != nil && len(info.Root) > 0:
+		h.Write([]byte("merkle"))
+		h.Write(info.Root)
+	case info.KZG != nil:
+		h.Write([]byte("kzg"))
+		h.Write(info.KZG.Commitment)
+		for _, p := range info.KZG.G1Powers {
+			h.Write(p)
+		}
+		for _, p := range info.KZG.G2Powers {
+			h.Write(p)
+		}
+	case info.Auth != nil && info.DigestLWE != nil:
+		h.Write([]byte("lwe"))
+		h.Write(matrix.MatrixToBytes(info.DigestLWE))
+	case info.Auth != nil && info.DigestLWE128 != nil:
+		h.Write([]byte("lwe128"))
+		h.Write(matrix.Matrix128ToBytes(info.DigestLWE128))
+	case info.Auth != nil && len(info.Digest) > 0:
+		// the global digest of the elliptic scheme authenticates the row
+		// digests, which the client checks against it
+		h.Write([]byte("dh"))
+		h.Write(info.Digest)
+	default:
+		return nil, errors.New("the db is not authenticated")
+	}
+	return h.Sum(nil), nil
+}
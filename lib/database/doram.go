@@ -0,0 +1,79 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// doramBytesPerElement is the number of bytes of a block packed in a field
+// element of a write
+const doramBytesPerElement = 3
+
+// NewDORAM returns an empty db of the two-server distributed ORAM scheme,
+// with numBlocks blocks of blockLen bytes in the vector representation. The
+// clients read the blocks privately with DPF keys, as in the DPF-based
+// classical PIR scheme, and overwrite them privately with the writes of the
+// private write scheme, whose slots are the blocks of the db. The slot of a
+// write is a counter followed by the block packed in field elements, three
+// bytes per element, so that the servers detect the writes colliding in an
+// epoch.
+func NewDORAM(numBlocks, blockLen int) *Bytes {
+	db := CreateZeroBytes(1, numBlocks, blockLen)
+	db.BlockLengths = make([]int, numBlocks)
+	for i := range db.BlockLengths {
+		db.BlockLengths[i] = blockLen
+	}
+	return db
+}
+
+// DORAMWriteInfo returns the info of the slots of the writes to the
+// distributed ORAM db with the given info
+func DORAMWriteInfo(info *Info) *Info {
+	return &Info{
+		NumRows:    info.NumRows,
+		NumColumns: info.NumColumns,
+		BlockSize:  1 + (info.BlockSize+doramBytesPerElement-1)/doramBytesPerElement,
+	}
+}
+
+// EncodeDORAMWrite returns the slot of the write of the block to the db
+// with the given info. The slot is zero, i.e., a dummy write that leaves the
+// db unchanged, if the block is nil.
+func EncodeDORAMWrite(block []byte, info *Info) ([]uint32, error) {
+	slot := make([]uint32, DORAMWriteInfo(info).BlockSize)
+	if block == nil {
+		return slot, nil
+	}
+	if len(block) != info.BlockSize {
+		return nil, fmt.Errorf("block of %d bytes, expected %d", len(block), info.BlockSize)
+	}
+	slot[0] = 1
+	for i, b := range block {
+		slot[1+i/doramBytesPerElement] |= uint32(b) << (8 * (2 - i%doramBytesPerElement))
+	}
+	return slot, nil
+}
+
+// DecodeDORAMWrite returns the block of the slot of the writes of an epoch
+// to the db with the given info, nil if no write overwrote it, or an error
+// if several writes collided in the slot
+func DecodeDORAMWrite(slot []uint32, info *Info) ([]byte, error) {
+	if len(slot) != DORAMWriteInfo(info).BlockSize {
+		return nil, errors.New("malformed slot")
+	}
+	switch slot[0] {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, errors.New("colliding writes")
+	}
+	block := make([]byte, 0, (len(slot)-1)*doramBytesPerElement)
+	for _, e := range slot[1:] {
+		if e >= 1<<(8*doramBytesPerElement) {
+			return nil, errors.New("colliding writes")
+		}
+		block = append(block, byte(e>>16), byte(e>>8), byte(e))
+	}
+	return block[:info.BlockSize], nil
+}
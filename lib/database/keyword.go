@@ -0,0 +1,179 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// keywordHeaderLen is the number of elements before the value of a record:
+// the tag of its keyword and the length of the value plus one, so that the
+// zero block of a missing keyword is never a record
+const keywordHeaderLen = 2
+
+// bytes of a value packed in an element of the field
+const keywordBytesPerElement = 3
+
+// Keyword is a key-value db for the keyword-PIR scheme based on
+// constant-weight codes. Every keyword is hashed to a codeword of CodeLength
+// bits with CodeWeight ones, so that two codewords are equal if and only if
+// the product of the bits of one at the ones of the other is one. A record is
+// a single block of field elements, without the buckets of the hash table of
+// the keys db, and no two keywords of the db share a codeword.
+type Keyword struct {
+	Info
+	// positions of the ones of the codeword of every record
+	Codewords [][]int
+	// records of BlockSize elements: the tag of the keyword, the length of
+	// the value plus one and the value, packed three bytes per element
+	Entries []uint32
+}
+
+// NewKeyword returns the keyword db of the records, with codewords of the
+// given weight and length. With no length, the codewords are long enough
+// so that two keywords share one with probability about 2^-10.
+func NewKeyword(records map[string][]byte, weight, codeLength int) (*Keyword, error) {
+	if weight < 1 {
+		return nil, errors.New("the codewords need a positive weight")
+	}
+	if codeLength == 0 {
+		codeLength = KeywordCodeLength(len(records), weight)
+	}
+	if _, ok := binomial(codeLength, weight); !ok {
+		return nil, fmt.Errorf("too many codewords of length %d and weight %d", codeLength, weight)
+	}
+
+	maxLen := 0
+	keywords := make([]string, 0, len(records))
+	for k, v := range records {
+		keywords = append(keywords, k)
+		if len(v) > maxLen {
+			maxLen = len(v)
+		}
+	}
+	// all the servers end up with the same db
+	sort.Strings(keywords)
+
+	info := Info{
+		NumRows:        1,
+		NumColumns:     len(records),
+		BlockSize:      keywordHeaderLen + (maxLen+keywordBytesPerElement-1)/keywordBytesPerElement,
+		ConstantWeight: &ConstantWeight{CodeLength: codeLength, CodeWeight: weight},
+	}
+	db := &Keyword{
+		Info:      info,
+		Codewords: make([][]int, len(keywords)),
+		Entries:   make([]uint32, len(keywords)*info.BlockSize),
+	}
+	seen := make(map[string]string, len(keywords))
+	for i, k := range keywords {
+		codeword, tag := KeywordToCodeword(k, &db.Info)
+		key := fmt.Sprint(codeword)
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("keywords %q and %q share a codeword", other, k)
+		}
+		seen[key] = k
+		db.Codewords[i] = codeword
+
+		block := db.Entries[i*info.BlockSize : (i+1)*info.BlockSize]
+		block[0], block[1] = tag, uint32(len(records[k])+1)
+		packKeywordValue(block[keywordHeaderLen:], records[k])
+	}
+
+	return db, nil
+}
+
+// KeywordCodeLength returns the length of the codewords of the given weight
+// such that there are at least 2^10 times as many codewords as pairs of
+// records
+func KeywordCodeLength(numRecords, weight int) int {
+	target := uint64(numRecords) * uint64(numRecords) << 9
+	m := weight
+	for {
+		c, ok := binomial(m, weight)
+		if !ok || c >= target {
+			return m
+		}
+		m++
+	}
+}
+
+// KeywordToCodeword returns the positions of the ones of the codeword of the
+// keyword, in decreasing order, and the tag of the keyword stored in its
+// record
+func KeywordToCodeword(keyword string, info *Info) ([]int, uint32) {
+	m, k := info.CodeLength, info.CodeWeight
+	total, _ := binomial(m, k)
+	h := sha256.Sum256([]byte(keyword))
+	// the bias of the reduction is negligible for less than 2^40 codewords
+	rank := binary.BigEndian.Uint64(h[:8]) % total
+	tag := binary.BigEndian.Uint32(h[8:12]) % info.Field().Modulus()
+
+	// the rank is unranked in the combinatorial number system, i.e., as the
+	// sum of C(c_i, i) for decreasing positions c_k > ... > c_1
+	positions := make([]int, k)
+	c := m - 1
+	for i := k; i >= 1; i-- {
+		for {
+			b, _ := binomial(c, i)
+			if b <= rank {
+				break
+			}
+			c--
+		}
+		b, _ := binomial(c, i)
+		rank -= b
+		positions[k-i] = c
+		c--
+	}
+	return positions, tag
+}
+
+// DecodeKeywordRecord returns the value of the record of the keyword, or
+// false if the block is not the record of the keyword
+func DecodeKeywordRecord(block []uint32, keyword string, info *Info) ([]byte, bool) {
+	if len(block) != info.BlockSize {
+		return nil, false
+	}
+	_, tag := KeywordToCodeword(keyword, info)
+	length := int(block[1]) - 1
+	maxLen := (info.BlockSize - keywordHeaderLen) * keywordBytesPerElement
+	if block[0] != tag || length < 0 || length > maxLen {
+		return nil, false
+	}
+	value := make([]byte, 0, maxLen)
+	for _, e := range block[keywordHeaderLen:] {
+		value = append(value, byte(e>>16), byte(e>>8), byte(e))
+	}
+	return value[:length], true
+}
+
+// packKeywordValue packs the value in the elements, three bytes per element
+func packKeywordValue(out []uint32, value []byte) {
+	for i, b := range value {
+		out[i/keywordBytesPerElement] |= uint32(b) << (8 * (2 - i%keywordBytesPerElement))
+	}
+}
+
+// binomial returns C(n, k), or false if it does not fit in 63 bits
+func binomial(n, k int) (uint64, bool) {
+	if k < 0 || k > n {
+		return 0, true
+	}
+	c := uint64(1)
+	for i := 0; i < k; i++ {
+		// c * (n - i) is divisible by i + 1
+		hi, lo := bits.Mul64(c, uint64(n-i))
+		if hi != 0 {
+			return 0, false
+		}
+		c = lo / uint64(i+1)
+		if c >= 1<<63 {
+			return 0, false
+		}
+	}
+	return c, true
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"io"
+	"log"
+
+	"github.com/si-co/vpir-code/lib/kzg"
+)
+
+// CreateRandomKZG returns a random bytes db whose blocks are authenticated
+// with a polynomial commitment: every block is followed by its proof, and the
+// info carries the constant-size digest of the db.
+// blockLen is the number of bytes in a block, without its proof
+func CreateRandomKZG(rnd io.Reader, dbLen, numRows, blockLen int) *Bytes {
+	numColumns, err := randomColumns(dbLen, numRows, blockLen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	blocks := make([][]byte, numRows*numColumns)
+	for i := range blocks {
+		blocks[i] = make([]byte, blockLen)
+		if _, err := io.ReadFull(rnd, blocks[i]); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	digest, proofs, err := kzg.Commit(rnd, blocks)
+	if err != nil {
+		log.Fatalf("impossible to commit to the db: %v", err)
+	}
+
+	blockLen += kzg.ProofLen
+	entries := make([]byte, 0, len(blocks)*blockLen)
+	blockLens := make([]int, len(blocks))
+	for i := range blocks {
+		entries = append(append(entries, blocks[i]...), proofs[i]...)
+		blockLens[i] = blockLen
+	}
+
+	return &Bytes{
+		Entries: entries,
+		Info: Info{
+			NumRows:      numRows,
+			NumColumns:   numColumns,
+			BlockSize:    blockLen,
+			BlockLengths: blockLens,
+			PIRType:      "kzg",
+			Merkle:       &Merkle{ProofLen: 0}, // only for tests compatibility
+			KZG:          digest,
+		},
+	}
+}
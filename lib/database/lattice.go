@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/si-co/vpir-code/lib/merkle"
+	"github.com/si-co/vpir-code/lib/rlwe"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// LatticeSingle is a db of bytes for the single-server lattice PIR scheme,
+// laid out as a matrix of blocks of N bytes, each stored in a plaintext of
+// the RLWE encryption. The queries select a row, then a column in the
+// 2-dimensional layout.
+type LatticeSingle struct {
+	Entries []byte
+	Info
+}
+
+// CreateRandomLatticeSingle returns a random lattice db of dbLen bits
+func CreateRandomLatticeSingle(rnd io.Reader, dbLen int, params *rlwe.Params) *LatticeSingle {
+	data := make([]byte, dbLen/8)
+	if _, err := rnd.Read(data); err != nil {
+		panic(err)
+	}
+	db, err := NewLatticeSingle(data, params)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// NewLatticeSingle returns the lattice db storing the data in blocks of N
+// bytes, padded with zeros. The rows and the columns of a 2-dimensional db
+// are at most N, as well as the rows of a 1-dimensional one.
+func NewLatticeSingle(data []byte, params *rlwe.Params) (*LatticeSingle, error) {
+	n := params.N()
+	blocks := (len(data) + n - 1) / n
+	if blocks == 0 {
+		blocks = 1
+	}
+	numRows, numColumns := blocks, 1
+	if params.Dimensions == 2 {
+		numRows = int(math.Ceil(math.Sqrt(float64(blocks))))
+		numColumns = (blocks + numRows - 1) / numRows
+	}
+	if numRows > n || numColumns > n {
+		return nil, fmt.Errorf("db of %d bytes too large for %d dimensions of at most %d blocks",
+			len(data), params.Dimensions, n)
+	}
+
+	entries := make([]byte, numRows*numColumns*n)
+	copy(entries, data)
+	return &LatticeSingle{
+		Entries: entries,
+		Info: Info{
+			NumRows:    numRows,
+			NumColumns: numColumns,
+			BlockSize:  n,
+		},
+	}, nil
+}
+
+// CreateRandomLatticeMerkle returns a random lattice db of dbLen bits in
+// blocks of blockLen bytes authenticated by a Merkle tree
+func CreateRandomLatticeMerkle(rnd io.Reader, dbLen, blockLen int, params *rlwe.Params) *LatticeSingle {
+	numBlocks, err := utils.NumBlocks(dbLen, blockLen)
+	if err != nil {
+		panic(err)
+	}
+	data := make([]byte, numBlocks*blockLen)
+	if _, err := rnd.Read(data); err != nil {
+		panic(err)
+	}
+	db, err := NewLatticeMerkle(data, blockLen, params)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// NewLatticeMerkle returns the lattice db of the data in blocks of blockLen
+// bytes, the last one padded with zeros, authenticated by a Merkle tree.
+// Every plaintext stores a block followed by its Merkle proof, padded as in
+// the Merkle db, so that a single-server retrieval is verified against the
+// public root in Info. The blocks must be distinct, since the tree finds
+// them by content.
+func NewLatticeMerkle(data []byte, blockLen int, params *rlwe.Params) (*LatticeSingle, error) {
+	if blockLen <= 0 {
+		return nil, errors.New("invalid block length")
+	}
+	numBlocks := (len(data) + blockLen - 1) / blockLen
+	if numBlocks == 0 {
+		numBlocks = 1
+	}
+	blocks := make([][]byte, numBlocks)
+	seen := make(map[string]int, numBlocks)
+	for i := range blocks {
+		blocks[i] = make([]byte, blockLen)
+		copy(blocks[i], data[i*blockLen:])
+		if j, ok := seen[string(blocks[i])]; ok {
+			return nil, fmt.Errorf("block %d is a duplicate of block %d", i, j)
+		}
+		seen[string(blocks[i])] = i
+	}
+	tree, err := merkle.New(blocks)
+	if err != nil {
+		return nil, err
+	}
+	proofLen := tree.EncodedProofLength()
+	n := params.N()
+	if blockLen+proofLen+1 > n {
+		return nil, fmt.Errorf("block of %d bytes and proof of %d bytes larger than a plaintext of %d bytes",
+			blockLen, proofLen, n)
+	}
+
+	entries := make([]byte, numBlocks*n)
+	for i, b := range blocks {
+		p, err := tree.GenerateProof(b)
+		if err != nil {
+			return nil, err
+		}
+		copy(entries[i*n:], append(b, PadWithSignalByte(merkle.EncodeProof(p))...))
+	}
+
+	db, err := NewLatticeSingle(entries, params)
+	if err != nil {
+		return nil, err
+	}
+	db.PIRType = "merkle"
+	db.Merkle = &Merkle{Root: tree.Root(), ProofLen: proofLen}
+	return db, nil
+}
+
+// Block returns the block of the given index, in row-major order
+func (db *LatticeSingle) Block(index int) []byte {
+	return db.Entries[index*db.BlockSize : (index+1)*db.BlockSize]
+}
+
+// LatticeDimensions returns the sizes of the dimensions of a lattice db,
+// selected one after the other by the queries
+func LatticeDimensions(info *Info) []int {
+	if info.NumColumns == 1 {
+		return []int{info.NumRows}
+	}
+	return []int{info.NumRows, info.NumColumns}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Layout is the order of the blocks of a bytes db in memory, chosen when the
+// db is created. The servers read the blocks with Block, whatever the layout,
+// and the PIR servers in GF(2) scan the db in the order of its layout.
+type Layout int
+
+const (
+	// RowMajor stores the blocks row after row, as in the matrix of the
+	// rebalanced representation. It is the default layout and the only one
+	// with blocks of variable length.
+	RowMajor Layout = iota
+	// ColumnMajor stores the blocks column after column, so that a server
+	// XORs every column selected by a query into the answer at once,
+	// without reading the other columns
+	ColumnMajor
+	// Tiled stores the rows in bands of TileBytes bytes of answer, each band
+	// column after column, so that the answer of a band stays in the cache
+	// while the selected columns of the band are XORed into it
+	Tiled
+)
+
+// TileBytes is the length in bytes of the part of an answer computed from a
+// band of rows of a tiled db, i.e., about the size of the L1 data cache
+const TileBytes = 32 << 10
+
+var layoutNames = map[Layout]string{
+	RowMajor:    "row",
+	ColumnMajor: "column",
+	Tiled:       "tiled",
+}
+
+func (l Layout) String() string {
+	if name, ok := layoutNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Layout(%d)", int(l))
+}
+
+// ParseLayout returns the layout with the given name: row, column or tiled
+func ParseLayout(name string) (Layout, error) {
+	for l, n := range layoutNames {
+		if n == name {
+			return l, nil
+		}
+	}
+	return RowMajor, fmt.Errorf("unknown layout %q, expected row, column or tiled", name)
+}
+
+// MarshalText returns the name of the layout
+func (l Layout) MarshalText() ([]byte, error) {
+	if _, ok := layoutNames[l]; !ok {
+		return nil, fmt.Errorf("unknown layout %d", int(l))
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText parses the name of a layout
+func (l *Layout) UnmarshalText(text []byte) error {
+	parsed, err := ParseLayout(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// BandRows returns the number of rows of the bands of the db, stored column
+// after column: all the rows for the column-major layout, the rows of
+// TileBytes bytes for the tiled layout and a single row for the row-major
+// layout
+func (b *Bytes) BandRows() int {
+	switch b.Layout {
+	case ColumnMajor:
+		return b.NumRows
+	case Tiled:
+		if n := TileBytes / b.BlockSize; n > 1 {
+			return n
+		}
+		return 1
+	default:
+		return 1
+	}
+}
+
+// BlockOffset returns the offset in the entries of the block of the given
+// index, in row-major order, for a db whose blocks are all of BlockSize bytes
+func (b *Bytes) BlockOffset(index int) int {
+	if b.Layout == RowMajor {
+		return index * b.BlockSize
+	}
+	row, col := index/b.NumColumns, index%b.NumColumns
+	band := b.BandRows()
+	start := row - row%band
+	// the last band holds the remaining rows
+	rows := band
+	if start+rows > b.NumRows {
+		rows = b.NumRows - start
+	}
+	return (start*b.NumColumns + col*rows + row - start) * b.BlockSize
+}
+
+// Block returns the block of the given index, in row-major order, for a db
+// whose blocks are all of BlockSize bytes
+func (b *Bytes) Block(index int) []byte {
+	off := b.BlockOffset(index)
+	return b.Entries[off : off+b.BlockSize]
+}
+
+// SetLayout reorders the entries of the db in the given layout. It is called
+// once the db is created, before the servers answer from it, and only for
+// the dbs whose blocks are all of BlockSize bytes when the layout is not
+// row-major.
+func (b *Bytes) SetLayout(l Layout) error {
+	if _, ok := layoutNames[l]; !ok {
+		return fmt.Errorf("unknown layout %v", l)
+	}
+	if l == b.Layout {
+		return nil
+	}
+	if b.BlockSize <= 0 {
+		return errors.New("the blocks of the db have no fixed size")
+	}
+	numBlocks := b.NumRows * b.NumColumns
+	if len(b.Entries) != numBlocks*b.BlockSize {
+		return errors.New("the blocks of the db are not all of the block size")
+	}
+	for _, n := range b.BlockLengths {
+		if n != b.BlockSize {
+			return errors.New("the blocks of the db are not all of the block size")
+		}
+	}
+
+	entries := make([]byte, len(b.Entries))
+	to := &Bytes{Entries: entries, Info: b.Info, Layout: l}
+	for i := 0; i < numBlocks; i++ {
+		copy(to.Block(i), b.Block(i))
+	}
+	b.Entries, b.Layout = entries, l
+	return nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLayout(t *testing.T) {
+	// bands of 2 rows of 16KiB blocks in the tiled layout, the last one with
+	// a single row
+	blockLen := TileBytes / 2
+	db := CreateRandomBytes(utils.RandomPRG(), 5*3*blockLen*8, 5, blockLen)
+	rowMajor := append([]byte{}, db.Entries...)
+
+	for _, l := range []Layout{ColumnMajor, Tiled, RowMajor} {
+		require.NoError(t, db.SetLayout(l))
+		require.Equal(t, l, db.Layout)
+		// the offsets of the blocks are a permutation of the blocks
+		seen := make(map[int]bool)
+		for i := 0; i < db.NumRows*db.NumColumns; i++ {
+			require.Equal(t, rowMajor[i*blockLen:(i+1)*blockLen], db.Block(i), l)
+			seen[db.BlockOffset(i)] = true
+		}
+		require.Len(t, seen, db.NumRows*db.NumColumns)
+		blocks, err := db.blocks()
+		require.NoError(t, err)
+		require.Len(t, blocks, db.NumRows*db.NumColumns)
+	}
+	require.Equal(t, rowMajor, db.Entries)
+	require.Error(t, db.SetLayout(Layout(3)))
+
+	// the blocks of variable length are only in the row-major layout
+	db.BlockLengths[0]--
+	require.Error(t, db.SetLayout(ColumnMajor))
+	require.Equal(t, RowMajor, db.Layout)
+
+	// nor are the blocks without a size
+	empty := CreateZeroBytes(1, 1, 0)
+	require.Error(t, empty.SetLayout(Tiled))
+	require.NoError(t, empty.SetLayout(RowMajor))
+}
+
+func TestParseLayout(t *testing.T) {
+	for _, l := range []Layout{RowMajor, ColumnMajor, Tiled} {
+		parsed, err := ParseLayout(l.String())
+		require.NoError(t, err)
+		require.Equal(t, l, parsed)
+
+		encoded, err := json.Marshal(l)
+		require.NoError(t, err)
+		var decoded Layout
+		require.NoError(t, json.Unmarshal(encoded, &decoded))
+		require.Equal(t, l, decoded)
+	}
+	_, err := ParseLayout("diagonal")
+	require.Error(t, err)
+}
@@ -12,7 +12,11 @@ import (
 // blockLen is the number of byte in a block,
 // as byte is viewed as an element in this case
 func CreateRandomMerkle(rnd io.Reader, dbLen, numRows, blockLen int) *Bytes {
-	numBlocks := dbLen / (8 * blockLen)
+	numColumns, err := randomColumns(dbLen, numRows, blockLen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	numBlocks := numRows * numColumns
 	// generate random numBlocks blocks
 	data := make([]byte, numBlocks*blockLen)
 	if _, err := rnd.Read(data); err != nil {
@@ -36,7 +40,6 @@ func CreateRandomMerkle(rnd io.Reader, dbLen, numRows, blockLen int) *Bytes {
 	runtime.GC()
 
 	// generate db
-	numColumns := numBlocks / numRows
 	proofLen := tree.EncodedProofLength()
 	// +1 is for storing the padding signal byte
 	blockLen = blockLen + proofLen + 1
@@ -29,7 +29,8 @@ func TestMerkleTree(t *testing.T) {
 	blockLen := 160
 
 	entries := make([][]byte, numRows)
-	numBlocks := dbLen / (8 * blockLen)
+	numBlocks, err := utils.NumBlocks(dbLen, blockLen)
+	require.NoError(t, err)
 	// generate random blocks
 	blocks := make([][]byte, numBlocks)
 	for i := range blocks {
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// maxBucketLength is the byte length above which a bucket of the hash
+	// table of a keys db is split into a chain of blocks, so that a few
+	// oversized buckets, e.g., with keys carrying many signatures, do not set
+	// the block size of the whole db
+	maxBucketLength = 16384
+
+	// blockHeaderLen is the byte length of the index of the next block of the
+	// chain, which heads every block of a keys db
+	blockHeaderLen = 4
+)
+
+// makeBlocks lays out the buckets of the hash table in blocks of at most
+// maxBucketLength bytes, padding excluded. The part of a bucket that does not
+// fit in its block continues in overflow blocks appended after the hash
+// table, each block pointing to the next one in its header. The overflow
+// blocks fill full rows, so that the number of rows and columns of the db is
+// returned along with the blocks.
+func makeBlocks(ht map[int][]byte, numRows, numColumns int) ([][]byte, int, int) {
+	tableLen := numRows * numColumns
+	capacity := maxBucketLength - blockHeaderLen
+	blocks := make([][]byte, tableLen)
+
+	// iterate over the buckets in order, so that all the servers end up
+	// with identical overflow blocks
+	for k := 0; k < tableLen; k++ {
+		index, data := k, ht[k]
+		for {
+			n := len(data)
+			if n > capacity {
+				n = capacity
+			}
+			next := 0
+			if n < len(data) {
+				next = len(blocks)
+				blocks = append(blocks, nil)
+			}
+			block := make([]byte, blockHeaderLen, blockHeaderLen+n+1)
+			binary.BigEndian.PutUint32(block, uint32(next))
+			// appending only 0x80 (without zeros)
+			blocks[index] = PadWithSignalByte(append(block, data[:n]...))
+			if next == 0 {
+				break
+			}
+			index, data = next, data[n:]
+		}
+	}
+
+	if len(blocks) == tableLen {
+		return blocks, numRows, numColumns
+	}
+	// a single row grows with the overflow blocks, a matrix with full rows
+	if numRows == 1 {
+		return blocks, 1, len(blocks)
+	}
+	for len(blocks)%numColumns != 0 {
+		blocks = append(blocks, PadWithSignalByte(make([]byte, blockHeaderLen)))
+	}
+	return blocks, len(blocks) / numColumns, numColumns
+}
+
+// SplitBlock returns the index of the block continuing the bucket of an
+// unpadded block of a keys db, zero if the bucket ends in this block, and the
+// data of the bucket stored in the block
+func SplitBlock(block []byte) (int, []byte, error) {
+	if len(block) < blockHeaderLen {
+		return 0, nil, errors.New("block shorter than its header")
+	}
+	return int(binary.BigEndian.Uint32(block[:blockHeaderLen])), block[blockHeaderLen:], nil
+}
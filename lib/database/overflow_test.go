@@ -0,0 +1,60 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketOverflow(t *testing.T) {
+	rng := utils.RandomPRG()
+	for _, rebalanced := range []bool{false, true} {
+		// buckets of about ten keys of 3KiB exceed maxBucketLength
+		keys := make([]*pgp.Key, 40)
+		for i := range keys {
+			packet := make([]byte, 3072)
+			_, err := rng.Read(packet)
+			require.NoError(t, err)
+			keys[i] = &pgp.Key{ID: fmt.Sprintf("user%d@example.org", i), Packet: packet}
+		}
+
+		db := keysToBytes(keys, rebalanced)
+		require.Less(t, db.HashTableLen, db.NumRows*db.NumColumns)
+		require.LessOrEqual(t, db.BlockSize, maxBucketLength+1)
+		require.Len(t, db.BlockLengths, db.NumRows*db.NumColumns)
+
+		for _, key := range keys {
+			data := make([]byte, 0)
+			index := int(HashToIndex(key.ID, db.NumBuckets()))
+			for {
+				next, chunk, err := SplitBlock(UnPadBlock(readBlock(db, index)))
+				require.NoError(t, err)
+				data = append(data, chunk...)
+				if next == 0 {
+					break
+				}
+				require.GreaterOrEqual(t, next, db.HashTableLen)
+				index = next
+			}
+			entries, err := pgp.SplitEntries(data)
+			require.NoError(t, err)
+			found := false
+			for _, e := range entries {
+				found = found || bytes.Equal(e, key.Packet)
+			}
+			require.True(t, found, "key %s not found in its bucket", key.ID)
+		}
+	}
+}
+
+func readBlock(db *Bytes, index int) []byte {
+	begin := 0
+	for _, l := range db.BlockLengths[:index] {
+		begin += l
+	}
+	return db.Entries[begin : begin+db.BlockLengths[index]]
+}
@@ -3,19 +3,21 @@ package database
 import (
 	"bytes"
 	"errors"
+	"io"
 	"log"
 	"sort"
 
+	"github.com/cloudflare/circl/group"
 	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/si-co/vpir-code/lib/logging"
 	"github.com/si-co/vpir-code/lib/merkle"
 	"github.com/si-co/vpir-code/lib/pgp"
-	"github.com/si-co/vpir-code/lib/utils"
 )
 
 const numKeysToDBLengthRatio float32 = 0.1
 
 func GenerateRealKeyDB(dataPaths []string) (*DB, error) {
-	log.Printf("Loading keys: %v\n", dataPaths)
+	logging.Default().Info("loading keys", logging.F("files", dataPaths))
 
 	keys, err := pgp.LoadKeysFromDisk(dataPaths)
 	if err != nil {
@@ -50,13 +52,22 @@ func GenerateRealKeyDB(dataPaths []string) (*DB, error) {
 	return db, nil
 }
 
-func GenerateRealKeyBytes(dataPaths []string, rebalanced bool) (*Bytes, error) {
-	log.Printf("Bytes db rebalanced: %v, loading keys: %v\n", rebalanced, dataPaths)
+// GenerateRealKeyBytes returns a bytes db storing the keys of the files. The
+// optional filters strip packets from the keys before embedding them.
+func GenerateRealKeyBytes(dataPaths []string, rebalanced bool, filters ...pgp.Filter) (*Bytes, error) {
+	logging.Default().Info("loading keys of the bytes db", logging.F("rebalanced", rebalanced), logging.F("files", dataPaths))
 
-	keys, err := pgp.LoadKeysFromDisk(dataPaths)
+	keys, err := loadIndexedKeys(dataPaths, filters...)
 	if err != nil {
 		return nil, err
 	}
+
+	return keysToBytes(keys, rebalanced), nil
+}
+
+// keysToBytes stores the keys in a hash table indexed by their id and
+// returns the corresponding bytes db
+func keysToBytes(keys []*pgp.Key, rebalanced bool) *Bytes {
 	// Sort the keys by id, higher first, to make sure that
 	// all the servers end up with an identical hash table.
 	sortById(keys)
@@ -66,36 +77,120 @@ func GenerateRealKeyBytes(dataPaths []string, rebalanced bool) (*Bytes, error) {
 	numRows, numColumns := CalculateNumRowsAndColumns(preSquareNumBlocks, rebalanced)
 
 	ht := makeHashTable(keys, numRows*numColumns)
-	// get the maximum byte length of the values in the hashTable
-	// +1 takes into account the padding 0x80 that is always added.
-	blockLen := utils.MaxBytesLength(ht) + 1
+	blocks, totalRows, totalColumns := makeBlocks(ht, numRows, numColumns)
 
-	// create all zeros db
-	db := InitBytes(numRows, numColumns, blockLen)
-
-	// order blocks because of map
-	blocks := make([][]byte, numRows*numColumns)
-	for k, v := range ht {
-		// appending only 0x80 (without zeros)
-		blocks[k] = PadWithSignalByte(v)
+	// get the maximum byte length of the blocks, padding included
+	blockLen := 0
+	for _, block := range blocks {
+		if len(block) > blockLen {
+			blockLen = len(block)
+		}
 	}
 
+	// create all zeros db
+	db := InitBytes(totalRows, totalColumns, blockLen)
+	db.HashTableLen = numRows * numColumns
+
 	// add blocks to the db with the according padding and store the length
 	for k, block := range blocks {
 		db.BlockLengths[k] = len(block)
 		db.Entries = append(db.Entries, block...)
 	}
 
-	return db, nil
+	return db
+}
+
+// GenerateRealKeyMerkle returns a db with Merkle proofs storing the keys of
+// the files. The optional filters strip packets from the keys before
+// embedding them.
+func GenerateRealKeyMerkle(dataPaths []string, rebalanced bool, filters ...pgp.Filter) (*Bytes, error) {
+	logging.Default().Info("loading keys of the Merkle db", logging.F("rebalanced", rebalanced), logging.F("files", dataPaths))
+
+	keys, err := loadIndexedKeys(dataPaths, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	return keysToMerkle(keys, rebalanced)
+}
+
+// GenerateRealKeyKeyword returns a keyword db storing the keys of the files,
+// with codewords of the given weight. Unlike the hash table of the bytes db,
+// every key is a record of its own. The optional filters strip packets from
+// the keys before embedding them.
+func GenerateRealKeyKeyword(dataPaths []string, weight int, filters ...pgp.Filter) (*Keyword, error) {
+	logging.Default().Info("loading keys of the keyword db", logging.F("weight", weight), logging.F("files", dataPaths))
+
+	keys, err := loadIndexedKeys(dataPaths, filters...)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		records[k.ID] = k.Packet
+	}
+
+	return NewKeyword(records, weight, 0)
 }
 
-func GenerateRealKeyMerkle(dataPaths []string, rebalanced bool) (*Bytes, error) {
-	log.Printf("Merkle db rebalanced: %v, loading keys: %v\n", rebalanced, dataPaths)
+// GenerateRealKeyBloom returns the Bloom filter of the ids of the keys of
+// the files, with the given rate of false positives
+func GenerateRealKeyBloom(dataPaths []string, falsePositives float64, filters ...pgp.Filter) (*Bloom, error) {
+	logging.Default().Info("loading keys of the Bloom filter", logging.F("falsePositives", falsePositives), logging.F("files", dataPaths))
 
+	keys, err := loadIndexedKeys(dataPaths, filters...)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(keys))
+	for i, k := range keys {
+		ids[i] = k.ID
+	}
+
+	return NewBloom(ids, falsePositives)
+}
+
+// GenerateRealKeyPSI returns the labeled PSI db mapping the ids of the keys
+// of the files to the keys, in the given group
+func GenerateRealKeyPSI(rnd io.Reader, dataPaths []string, g group.Group, filters ...pgp.Filter) (*LabeledPSI, error) {
+	logging.Default().Info("loading keys of the labeled PSI db", logging.F("files", dataPaths))
+
+	keys, err := loadIndexedKeys(dataPaths, filters...)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		labels[k.ID] = k.Packet
+	}
+
+	return NewLabeledPSI(rnd, labels, g)
+}
+
+// loadIndexedKeys loads the keys, sanitized with the filters, with their
+// entries in the fingerprint and key ID indices, which share the hash table
+// of the email index
+func loadIndexedKeys(dataPaths []string, filters ...pgp.Filter) ([]*pgp.Key, error) {
 	keys, err := pgp.LoadKeysFromDisk(dataPaths)
 	if err != nil {
 		return nil, err
 	}
+	// the keys written by older versions are indexed by their lower-cased
+	// email only
+	for _, key := range keys {
+		key.ID = pgp.NormalizeEmail(key.ID)
+	}
+	keys, err = pgp.SanitizeKeys(keys, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgp.IndexKeys(keys)
+}
+
+// keysToMerkle stores the keys in a hash table indexed by their id and
+// returns the corresponding db with Merkle proofs
+func keysToMerkle(keys []*pgp.Key, rebalanced bool) (*Bytes, error) {
 	// Sort the keys by id, higher first, to make sure that
 	// all the servers end up with an identical hash table.
 	sortById(keys)
@@ -106,11 +201,7 @@ func GenerateRealKeyMerkle(dataPaths []string, rebalanced bool) (*Bytes, error)
 	ht := makeHashTable(keys, numRows*numColumns)
 
 	// map into blocks
-	blocks := make([][]byte, numRows*numColumns)
-	for k, v := range ht {
-		// appending only 0x80 (without zeros)
-		blocks[k] = PadWithSignalByte(v)
-	}
+	blocks, totalRows, totalColumns := makeBlocks(ht, numRows, numColumns)
 
 	// generate tree
 	tree, err := merkle.New(blocks)
@@ -120,8 +211,8 @@ func GenerateRealKeyMerkle(dataPaths []string, rebalanced bool) (*Bytes, error)
 
 	proofLen := tree.EncodedProofLength()
 	maxBlockLen := 0
-	blockLens := make([]int, numRows*numColumns)
-	for i := 0; i < numRows*numColumns; i++ {
+	blockLens := make([]int, len(blocks))
+	for i := range blocks {
 		// we add +1 for appending 0x80 to the proof
 		blockLens[i] = len(blocks[i]) + proofLen + 1
 		if blockLens[i] > maxBlockLen {
@@ -129,13 +220,14 @@ func GenerateRealKeyMerkle(dataPaths []string, rebalanced bool) (*Bytes, error)
 		}
 	}
 
-	entries := makeMerkleEntries(blocks, tree, numRows, numColumns, maxBlockLen)
+	entries := makeMerkleEntries(blocks, tree, totalRows, totalColumns, maxBlockLen)
 
 	m := &Bytes{
 		Entries: entries,
 		Info: Info{
-			NumRows:      numRows,
-			NumColumns:   numColumns,
+			NumRows:      totalRows,
+			NumColumns:   totalColumns,
+			HashTableLen: numRows * numColumns,
 			BlockSize:    maxBlockLen,
 			BlockLengths: blockLens,
 			PIRType:      "merkle",
@@ -150,10 +242,11 @@ func makeHashTable(keys []*pgp.Key, tableLen int) map[int][]byte {
 	// prepare db
 	db := make(map[int][]byte)
 
-	// range over all id,v pairs and assign every pair to a given bucket
+	// range over all id,v pairs and assign every pair to a given bucket as a
+	// length-prefixed entry
 	for _, key := range keys {
 		hashKey := int(HashToIndex(key.ID, tableLen))
-		db[hashKey] = append(db[hashKey], key.Packet...)
+		db[hashKey] = pgp.AppendEntry(db[hashKey], key.Packet)
 	}
 
 	return db
@@ -216,8 +309,12 @@ func GetKeyInfoFromPacket(pkt []byte) (*KeyInfo, error) {
 		bl = 0
 	}
 
+	// the email is normalized as in the lookups
+	userId := *el[0].PrimaryIdentity().UserId
+	userId.Email = pgp.NormalizeEmail(userId.Email)
+
 	return &KeyInfo{
-		UserId:       el[0].PrimaryIdentity().UserId,
+		UserId:       &userId,
 		CreationTime: el[0].PrimaryKey.CreationTime,
 		PubKeyAlgo:   el[0].PrimaryKey.PubKeyAlgo,
 		BitLength:    bl,
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/cloudflare/circl/group"
+)
+
+// PSITagLen is the length in bytes of the tags of the table of a labeled PSI
+// db
+const PSITagLen = 16
+
+// psiDST separates the hashing of the identifiers to the group
+var psiDST = []byte("vpir-labeled-psi")
+
+// LabeledPSI is the db of the labeled private set intersection protocol based
+// on the DH oblivious PRF. The PRF output of an identifier is its hash to the
+// group raised to the key of the server, from which a tag and a key are
+// derived: the table maps the tags to the labels, padded to BlockSize bytes
+// and encrypted with their keys. The table is sent once to the clients, which
+// learn nothing from it without evaluating the PRF with the server, one group
+// element per identifier.
+type LabeledPSI struct {
+	Info
+	// PRF key of the server, never sent
+	Key group.Scalar
+	// records sorted by tag, each made of a tag and an encrypted label
+	Table []byte
+}
+
+// NewLabeledPSI returns the labeled PSI db of the labels of the identifiers
+// in the given group
+func NewLabeledPSI(rnd io.Reader, labels map[string][]byte, g group.Group) (*LabeledPSI, error) {
+	maxLen := 0
+	for _, l := range labels {
+		if len(l) > maxLen {
+			maxLen = len(l)
+		}
+	}
+	db := &LabeledPSI{
+		Info: Info{
+			NumRows:    1,
+			NumColumns: len(labels),
+			// the labels are prefixed by their length
+			BlockSize: 4 + maxLen,
+			Auth:      &Auth{Group: g, ElementSize: getGroupElementSize(g)},
+		},
+		Key: g.RandomScalar(rnd),
+	}
+
+	records := make([][]byte, 0, len(labels))
+	for id, l := range labels {
+		e := g.NewElement().Mul(HashIdentifierToGroup(id, g), db.Key)
+		tag, key, err := PSIKeys(e)
+		if err != nil {
+			return nil, err
+		}
+		label := make([]byte, db.BlockSize)
+		binary.BigEndian.PutUint32(label, uint32(len(l)))
+		copy(label[4:], l)
+		aead, err := psiAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		record := append([]byte{}, tag...)
+		records = append(records, aead.Seal(record, make([]byte, aead.NonceSize()), label, tag))
+	}
+	// the order of the records does not depend on the one of the labels
+	sort.Slice(records, func(i, j int) bool {
+		return bytes.Compare(records[i][:PSITagLen], records[j][:PSITagLen]) < 0
+	})
+	for _, r := range records {
+		db.Table = append(db.Table, r...)
+	}
+
+	return db, nil
+}
+
+// PSIRecordLen returns the length in bytes of a record of the table of the
+// labeled PSI db with the given info
+func PSIRecordLen(info *Info) int {
+	// the AES-GCM tag is as long as an AES block
+	return PSITagLen + info.BlockSize + aes.BlockSize
+}
+
+// HashIdentifierToGroup returns the hash of the identifier to the group
+func HashIdentifierToGroup(id string, g group.Group) group.Element {
+	return g.HashToElement([]byte(id), psiDST)
+}
+
+// PSIKeys returns the tag and the key of the label derived from the PRF
+// output of an identifier
+func PSIKeys(e group.Element) ([]byte, []byte, error) {
+	encoded, err := e.MarshalBinaryCompress()
+	if err != nil {
+		return nil, nil, err
+	}
+	tag := sha256.Sum256(append([]byte("tag"), encoded...))
+	key := sha256.Sum256(append([]byte("key"), encoded...))
+	return tag[:PSITagLen], key[:], nil
+}
+
+// OpenPSILabel decrypts the encrypted label of a record of the table with the
+// key of its identifier
+func OpenPSILabel(record, key []byte) ([]byte, error) {
+	aead, err := psiAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	tag := record[:PSITagLen]
+	label, err := aead.Open(nil, make([]byte, aead.NonceSize()), record[PSITagLen:], tag)
+	if err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(label))
+	if n > len(label)-4 {
+		return nil, errors.New("malformed label")
+	}
+	return label[4 : 4+n], nil
+}
+
+// psiAEAD returns the AES-GCM cipher of the key. A key encrypts a single
+// label, so that the nonce is always zero.
+func psiAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
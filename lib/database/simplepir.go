@@ -0,0 +1,51 @@
+package database
+
+import (
+	"io"
+
+	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// SimplePIR is a db of bytes for the single-server SimplePIR scheme, laid
+// out as a matrix whose rows are retrieved by the queries
+type SimplePIR struct {
+	Matrix *matrix.MatrixBytes
+	Info
+}
+
+// CreateRandomSimplePIR returns a random SimplePIR db of dbLen bits, in a
+// square matrix of bytes
+func CreateRandomSimplePIR(rnd io.Reader, dbLen int) *SimplePIR {
+	data := make([]byte, dbLen/8)
+	if _, err := rnd.Read(data); err != nil {
+		panic(err)
+	}
+	return NewSimplePIR(data)
+}
+
+// NewSimplePIR returns the SimplePIR db storing the data in a square matrix
+// of bytes, padded with zeros
+func NewSimplePIR(data []byte) *SimplePIR {
+	numRows, numColumns := CalculateNumRowsAndColumns(len(data), true)
+	m := matrix.NewBytes(numRows, numColumns)
+	for i, b := range data {
+		m.SetData(i, b)
+	}
+
+	return &SimplePIR{
+		Matrix: m,
+		Info: Info{
+			NumRows:    numRows,
+			NumColumns: numColumns,
+			BlockSize:  blockSizeLWE,
+		},
+	}
+}
+
+// Hint returns the hint of the db downloaded by the clients before querying,
+// i.e., the product of the public matrix A and the db
+func (db *SimplePIR) Hint(params *utils.ParamsLWE) *matrix.Matrix {
+	a := matrix.NewRandom(utils.NewPRG(params.SeedA), params.N, db.NumRows)
+	return matrix.BytesMul(a, db.Matrix)
+}
@@ -0,0 +1,124 @@
+// Package discovery implements private contact discovery: a client holding a
+// list of contacts learns which of them have a key in the db, and fetches
+// their keys, without revealing the contacts to the servers. The contacts are
+// processed in batches padded with random dummy contacts, so that the servers
+// only learn the number of batches.
+package discovery
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/query"
+)
+
+// DefaultBatchSize is the default number of contacts per batch
+const DefaultBatchSize = 16
+
+// dummyDomain is the domain of the dummy contacts, reserved by RFC 2606 so
+// that no key in the db can match them
+const dummyDomain = "@discovery.invalid"
+
+// Runner sends one query to each server and returns their answers
+type Runner func(queries [][]byte) [][]byte
+
+// Retriever retrieves with point PIR the keys stored under the email, e.g.,
+// with manager.Actor.GetEntitiesByIndex. It returns no keys and no error if
+// the email is not in the db.
+type Retriever func(email string) ([]pgp.RecoveredKey, error)
+
+// Discover returns the contacts with at least one key in the db, running one
+// FSS counting query per contact and per dummy with the predicate client c,
+// i.e., client.PredicatePIR or client.PredicateAPIR
+func Discover(c client.Client, run Runner, contacts []string, batchSize int, rnd io.Reader) ([]string, error) {
+	padded, err := pad(contacts, batchSize, rnd)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]string, 0)
+	for i, contact := range padded {
+		info := &query.Info{Target: query.UserId}
+		in, err := info.ToEmailClientFSS(contact).Encode()
+		if err != nil {
+			return nil, err
+		}
+		queries, err := c.QueryBytes(in, 2)
+		if err != nil {
+			return nil, fmt.Errorf("error when executing query: %v", err)
+		}
+		res, err := c.ReconstructBytes(run(queries))
+		if err != nil {
+			return nil, fmt.Errorf("error during reconstruction: %v", err)
+		}
+
+		var count uint64
+		switch v := res.(type) {
+		case uint32:
+			count = uint64(v)
+		case uint64:
+			count = v
+		default:
+			return nil, errors.New("the client does not count the matching keys")
+		}
+		// the answers for the dummies are discarded
+		if i < len(contacts) && count > 0 {
+			found = append(found, contact)
+		}
+	}
+
+	return found, nil
+}
+
+// Fetch retrieves the keys of the contacts, running one point PIR retrieval
+// per contact and per dummy. The contacts without keys are absent from the
+// returned map.
+func Fetch(retrieve Retriever, contacts []string, batchSize int, rnd io.Reader) (map[string][]pgp.RecoveredKey, error) {
+	padded, err := pad(contacts, batchSize, rnd)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]pgp.RecoveredKey)
+	for i, contact := range padded {
+		k, err := retrieve(contact)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving the keys of %s: %v", contact, err)
+		}
+		if i < len(contacts) && len(k) > 0 {
+			keys[contact] = k
+		}
+	}
+
+	return keys, nil
+}
+
+// pad returns the normalized contacts followed by random dummy contacts, up
+// to a multiple of batchSize
+func pad(contacts []string, batchSize int, rnd io.Reader) ([]string, error) {
+	if batchSize <= 0 {
+		return nil, errors.New("the batch size must be positive")
+	}
+	numBatches := (len(contacts) + batchSize - 1) / batchSize
+	if numBatches == 0 {
+		numBatches = 1
+	}
+
+	padded := make([]string, 0, numBatches*batchSize)
+	for _, c := range contacts {
+		padded = append(padded, pgp.NormalizeEmail(c))
+	}
+	for len(padded) < numBatches*batchSize {
+		local := make([]byte, 16)
+		if _, err := io.ReadFull(rnd, local); err != nil {
+			return nil, err
+		}
+		padded = append(padded, hex.EncodeToString(local)+dummyDomain)
+	}
+
+	return padded, nil
+}
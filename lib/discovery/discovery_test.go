@@ -0,0 +1,66 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscover(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), 100)
+	require.NoError(t, err)
+
+	servers := []server.Server{server.NewPredicatePIR(db, 0), server.NewPredicatePIR(db, 1)}
+	numQueries := 0
+	run := func(queries [][]byte) [][]byte {
+		numQueries++
+		answers := make([][]byte, len(queries))
+		for i := range queries {
+			a, err := servers[i].AnswerBytes(queries[i])
+			require.NoError(t, err)
+			answers[i] = a
+		}
+		return answers
+	}
+
+	known := db.KeysInfo[0].UserId.Email
+	contacts := []string{"nobody@example.org", known, "someone@example.org"}
+	c := client.NewPredicatePIR(utils.RandomPRG(), &db.Info)
+
+	found, err := Discover(c, run, contacts, 2, utils.RandomPRG())
+	require.NoError(t, err)
+	require.Equal(t, []string{known}, found)
+	// three contacts padded to two batches of two
+	require.Equal(t, 4, numQueries)
+}
+
+func TestFetch(t *testing.T) {
+	key := pgp.RecoveredKey{}
+	var retrieved []string
+	retrieve := func(email string) ([]pgp.RecoveredKey, error) {
+		retrieved = append(retrieved, email)
+		if email == "alice@example.org" {
+			return []pgp.RecoveredKey{key}, nil
+		}
+		return nil, nil
+	}
+
+	keys, err := Fetch(retrieve, []string{"Alice@example.org ", "bob@example.org"}, 3, utils.RandomPRG())
+	require.NoError(t, err)
+	require.Equal(t, map[string][]pgp.RecoveredKey{"alice@example.org": {key}}, keys)
+	require.Len(t, retrieved, 3)
+	require.Contains(t, retrieved[2], dummyDomain)
+
+	failing := func(string) ([]pgp.RecoveredKey, error) { return nil, errors.New("unreachable") }
+	_, err = Fetch(failing, []string{"alice@example.org"}, 1, utils.RandomPRG())
+	require.Error(t, err)
+
+	_, err = Fetch(retrieve, nil, 0, utils.RandomPRG())
+	require.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package field
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+// The scalar operations of the package run in constant time: reductions use
+// masks instead of branches, so that the timing of operations on secret data
+// (MAC keys, shares) does not depend on their value. Building with the
+// constanttime tag additionally makes the sampling of random elements run in
+// constant time, by reducing wide random integers instead of rejecting the
+// out-of-range ones, at the cost of a statistically negligible bias.
+
+// condSub32 returns a - p if a >= p and a otherwise, for a < 2^32, without
+// branches
+func condSub32(a, p uint32) uint32 {
+	d, borrow := bits.Sub32(a, p, 0)
+	// borrow is one if and only if a < p
+	return d + (p & -borrow)
+}
+
+// condSub64 is the same as condSub32 for 64-bit values
+func condSub64(a, p uint64) uint64 {
+	d, borrow := bits.Sub64(a, p, 0)
+	return d + (p & -borrow)
+}
+
+// ConstantTimeEq returns true if a and b have the same length and the same
+// elements. The running time depends only on the length of the inputs.
+func ConstantTimeEq(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var diff uint32
+	for i := range a {
+		diff |= a[i] ^ b[i]
+	}
+	return diff == 0
+}
+
+// ConstantTimeEq64 is the same as ConstantTimeEq for the 64-bit field
+func ConstantTimeEq64(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var diff uint64
+	for i := range a {
+		diff |= a[i] ^ b[i]
+	}
+	return diff == 0
+}
+
+// randElementCT samples an element of the 32-bit field in constant time,
+// reducing 64 random bits
+func randElementCT(rnd io.Reader) uint32 {
+	var buf [8]byte
+	if _, err := rnd.Read(buf[:]); err != nil {
+		panic("error in randomness")
+	}
+	return Reduce(binary.BigEndian.Uint64(buf[:]))
+}
+
+// randElement64CT samples an element of the 64-bit field in constant time,
+// reducing 122 random bits
+func randElement64CT(rnd io.Reader) uint64 {
+	var buf [16]byte
+	if _, err := rnd.Read(buf[:]); err != nil {
+		panic("error in randomness")
+	}
+	hi := binary.BigEndian.Uint64(buf[:8]) >> 6
+	lo := binary.BigEndian.Uint64(buf[8:])
+	// hi*2^64 + lo = (hi*2^3 + lo>>61)*2^61 + (lo & ModP64), with hi < 2^58
+	return Reduce64((hi<<3 | lo>>61) + (lo & ModP64))
+}
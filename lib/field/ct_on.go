@@ -0,0 +1,6 @@
+//go:build constanttime
+
+package field
+
+// ConstantTime is true if random elements are sampled in constant time
+const ConstantTime = true
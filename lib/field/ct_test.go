@@ -0,0 +1,28 @@
+package field
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstantTime(t *testing.T) {
+	for _, a := range []uint32{0, 1, ModP - 1, ModP, ModP + 1, 2*ModP - 1} {
+		require.Equal(t, a%ModP, condSub32(a, ModP))
+	}
+	for _, a := range []uint64{0, ModP64 - 1, ModP64, 2*ModP64 - 1} {
+		require.Equal(t, a%ModP64, condSub64(a, ModP64))
+	}
+
+	require.True(t, ConstantTimeEq([]uint32{1, 2}, []uint32{1, 2}))
+	require.False(t, ConstantTimeEq([]uint32{1, 2}, []uint32{1, 3}))
+	require.False(t, ConstantTimeEq([]uint32{1, 2}, []uint32{1}))
+	require.False(t, ConstantTimeEq64([]uint64{1, 2}, []uint64{0, 2}))
+
+	rnd := utils.RandomPRG()
+	for i := 0; i < 1000; i++ {
+		require.Less(t, randElementCT(rnd), ModP)
+		require.Less(t, randElement64CT(rnd), ModP64)
+	}
+}
@@ -0,0 +1,88 @@
+package field
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Canonical wire encoding of field elements: fixed-width big-endian integers
+// strictly smaller than the modulus. Decoding rejects any other input, so that
+// malformed data is never silently reduced into a valid element.
+
+var (
+	errEncodingLength = errors.New("encoding length is not a multiple of the element size")
+	errNonCanonical   = errors.New("non-canonical field element")
+)
+
+// EncodeElements returns the canonical encoding of the reduced elements of in
+func (f *Field) EncodeElements(in []uint32) []byte {
+	return f.AppendElements(make([]byte, 0, len(in)*Bytes), in)
+}
+
+// AppendElements appends the canonical encoding of the reduced elements of in
+// to dst, which does not allocate if dst has enough capacity
+func (f *Field) AppendElements(dst []byte, in []uint32) []byte {
+	n := len(dst)
+	dst = grow(dst, len(in)*Bytes)
+	for i, e := range in {
+		binary.BigEndian.PutUint32(dst[n+i*Bytes:], e)
+	}
+	return dst
+}
+
+// DecodeElements decodes the canonical encoding of a vector of elements. It
+// returns an error if the length of the input is not a multiple of Bytes or if
+// any element is not reduced.
+func (f *Field) DecodeElements(in []byte) ([]uint32, error) {
+	if len(in)%Bytes != 0 {
+		return nil, errEncodingLength
+	}
+	out := make([]uint32, len(in)/Bytes)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint32(in[i*Bytes:])
+		if out[i] >= f.p {
+			return nil, errNonCanonical
+		}
+	}
+	return out, nil
+}
+
+// EncodeElements64 returns the canonical encoding of the reduced elements of
+// the 64-bit field in in
+func EncodeElements64(in []uint64) []byte {
+	return AppendElements64(make([]byte, 0, len(in)*Bytes64), in)
+}
+
+// AppendElements64 is the same as AppendElements for the 64-bit field
+func AppendElements64(dst []byte, in []uint64) []byte {
+	n := len(dst)
+	dst = grow(dst, len(in)*Bytes64)
+	for i, e := range in {
+		binary.BigEndian.PutUint64(dst[n+i*Bytes64:], e)
+	}
+	return dst
+}
+
+// grow extends dst by n bytes, reallocating it only if its capacity is too
+// small
+func grow(dst []byte, n int) []byte {
+	if len(dst)+n <= cap(dst) {
+		return dst[:len(dst)+n]
+	}
+	return append(dst, make([]byte, n)...)
+}
+
+// DecodeElements64 is the same as DecodeElements for the 64-bit field
+func DecodeElements64(in []byte) ([]uint64, error) {
+	if len(in)%Bytes64 != 0 {
+		return nil, errEncodingLength
+	}
+	out := make([]uint64, len(in)/Bytes64)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint64(in[i*Bytes64:])
+		if out[i] >= ModP64 {
+			return nil, errNonCanonical
+		}
+	}
+	return out, nil
+}
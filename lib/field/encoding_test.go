@@ -0,0 +1,60 @@
+package field
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeElements(t *testing.T) {
+	for _, f := range []*Field{Default(), mustNew(65521)} {
+		in := make([]uint32, 10)
+		for i := range in {
+			in[i] = f.RandElement()
+		}
+		in[0], in[1] = 0, f.Modulus()-1
+
+		enc := f.EncodeElements(in)
+		require.Len(t, enc, len(in)*Bytes)
+		out, err := f.DecodeElements(enc)
+		require.NoError(t, err)
+		require.Equal(t, in, out)
+
+		_, err = f.DecodeElements(enc[1:])
+		require.Error(t, err)
+
+		binary.BigEndian.PutUint32(enc[4:], f.Modulus())
+		_, err = f.DecodeElements(enc)
+		require.Error(t, err)
+	}
+}
+
+func TestEncodeElements64(t *testing.T) {
+	in := []uint64{0, ModP64 - 1, RandElement64()}
+	enc := EncodeElements64(in)
+	out, err := DecodeElements64(enc)
+	require.NoError(t, err)
+	require.Equal(t, in, out)
+
+	_, err = DecodeElements64(enc[:len(enc)-1])
+	require.Error(t, err)
+
+	binary.BigEndian.PutUint64(enc, ModP64)
+	_, err = DecodeElements64(enc)
+	require.Error(t, err)
+}
+
+func TestAppendElements(t *testing.T) {
+	f := Default()
+	in := []uint32{1, 2, f.Modulus() - 1}
+	dst := append(make([]byte, 0, 64), 0xff)
+	enc := f.AppendElements(dst, in)
+	require.Equal(t, append([]byte{0xff}, f.EncodeElements(in)...), enc)
+	require.Equal(t, 0.0, testing.AllocsPerRun(10, func() {
+		f.AppendElements(dst, in)
+	}))
+
+	in64 := []uint64{1, ModP64 - 1}
+	require.Equal(t, append([]byte{0xff}, EncodeElements64(in64)...), AppendElements64([]byte{0xff}, in64))
+}
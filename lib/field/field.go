@@ -17,9 +17,48 @@ const (
 	ConcurrentExecutions = 4
 )
 
+// LazyProducts is the number of products of reduced elements, as returned by
+// MulNoReduce, that can be summed in a uint64 before calling Reduce
+const LazyProducts = 4
+
+// Add returns a + b mod ModP for reduced elements
+func Add(a, b uint32) uint32 {
+	return reduceSmall(a + b)
+}
+
+// Neg returns -a mod ModP for reduced elements
+func Neg(a uint32) uint32 {
+	return reduceSmall(ModP - a)
+}
+
+// Mul returns a * b mod ModP for reduced elements. The reduction uses that
+// 2^31 = 1 mod ModP and needs neither division nor modulo operations.
+func Mul(a, b uint32) uint32 {
+	return Reduce(MulNoReduce(a, b))
+}
+
+// MulNoReduce returns the unreduced product of a and b. Up to LazyProducts
+// such products can be accumulated before a single call to Reduce.
+func MulNoReduce(a, b uint32) uint64 {
+	return uint64(a) * uint64(b)
+}
+
+// Reduce reduces any uint64 modulo ModP
+func Reduce(a uint64) uint32 {
+	a = (a & uint64(ModP)) + (a >> Bits) // < 2^34
+	a = (a & uint64(ModP)) + (a >> Bits) // < 2^31 + 8
+	return reduceSmall(uint32(a))
+}
+
+// reduceSmall reduces a value smaller than 2^32 modulo ModP
+func reduceSmall(a uint32) uint32 {
+	a = (a & ModP) + (a >> Bits)
+	return condSub32(a, ModP)
+}
+
 func NegateVector(in []uint32) []uint32 {
 	for i := range in {
-		in[i] = ModP - in[i]
+		in[i] = Neg(in[i])
 	}
 
 	return in
@@ -47,6 +86,9 @@ func toElement(in []byte) uint32 {
 }
 
 func RandElementWithPRG(rnd io.Reader) uint32 {
+	if ConstantTime {
+		return randElementCT(rnd)
+	}
 	var buf [Bytes]byte
 	var out = ModP
 	// Make sure that toElement is not equal 2^31 - 1
@@ -67,21 +109,8 @@ func RandElement() uint32 {
 }
 
 func RandVectorWithPRG(length int, rnd io.Reader) []uint32 {
-	bytesLength := length * Bytes
-	buf := make([]byte, bytesLength)
-	_, err := rnd.Read(buf)
-	if err != nil {
-		panic("error in randomness")
-	}
 	out := make([]uint32, length)
-	for i := range out {
-		//Clearing the top most bit of uint32
-		buf[i*Bytes] &= Mask
-		out[i] = binary.BigEndian.Uint32(buf[i*Bytes : (i+1)*Bytes])
-		for out[i] == ModP {
-			out[i] = RandElementWithPRG(rnd)
-		}
-	}
+	FillRandVectorWithPRG(out, rnd)
 
 	return out
 }
@@ -0,0 +1,98 @@
+package field
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// 64-bit prime field, using the Mersenne prime 2^61 - 1. A larger field gives
+// the same soundness as the 32-bit field with fewer parallel MACs.
+const (
+	ModP64                 = uint64(2305843009213693951) // 2^61 - 1
+	Bytes64                = 8
+	Bits64                 = 61
+	Mask64                 = 31 // clears the three top-most bits of the first byte
+	ConcurrentExecutions64 = 2
+)
+
+// Add64 returns a + b mod ModP64
+func Add64(a, b uint64) uint64 {
+	return Reduce64(a + b)
+}
+
+// Sub64 returns a - b mod ModP64
+func Sub64(a, b uint64) uint64 {
+	return Reduce64(a + ModP64 - b)
+}
+
+// Neg64 returns -a mod ModP64
+func Neg64(a uint64) uint64 {
+	return Reduce64(ModP64 - a)
+}
+
+// Mul64 returns a * b mod ModP64
+func Mul64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// a*b = hi*2^64 + lo = (hi*2^3 + lo>>61)*2^61 + (lo & ModP64)
+	return Reduce64((hi<<3 | lo>>61) + (lo & ModP64))
+}
+
+// Reduce64 reduces a value smaller than 2^62 modulo ModP64
+func Reduce64(a uint64) uint64 {
+	a = (a & ModP64) + (a >> Bits64)
+	return condSub64(a, ModP64)
+}
+
+func NegateVector64(in []uint64) []uint64 {
+	for i := range in {
+		in[i] = Neg64(in[i])
+	}
+
+	return in
+}
+
+func RandElement64WithPRG(rnd io.Reader) uint64 {
+	if ConstantTime {
+		return randElement64CT(rnd)
+	}
+	var buf [Bytes64]byte
+	var out = ModP64
+	// Make sure that the element is not equal to 2^61 - 1
+	for out == ModP64 {
+		_, err := rnd.Read(buf[:])
+		if err != nil {
+			panic("error in randomness")
+		}
+		// Clearing the three top most bits of uint64
+		buf[0] &= Mask64
+		out = binary.BigEndian.Uint64(buf[:])
+	}
+	return out
+}
+
+func RandElement64() uint64 {
+	return RandElement64WithPRG(utils.RandomPRG())
+}
+
+func RandVector64WithPRG(length int, rnd io.Reader) []uint64 {
+	out := make([]uint64, length)
+	FillRandVector64WithPRG(out, rnd)
+
+	return out
+}
+
+// BytesToElements64 converts the input bytes into elements of the 64-bit
+// field, Bytes64 bytes per element. As for the 32-bit field, an input that
+// converts to ModP64 is replaced by a fresh random element.
+func BytesToElements64(out []uint64, in []byte) {
+	for i := range out {
+		b := in[i*Bytes64 : (i+1)*Bytes64]
+		out[i] = (uint64(b[0]&Mask64) << 56) | (binary.BigEndian.Uint64(b) & (1<<56 - 1))
+		for out[i] == ModP64 {
+			out[i] = RandElement64()
+		}
+	}
+}
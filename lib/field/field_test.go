@@ -0,0 +1,101 @@
+package field
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestField64Arithmetic(t *testing.T) {
+	p := new(big.Int).SetUint64(ModP64)
+	edge := []uint64{0, 1, 2, ModP64 - 2, ModP64 - 1}
+	for i := 0; i < 1000; i++ {
+		a, b := RandElement64(), RandElement64()
+		if i < len(edge)*len(edge) {
+			a, b = edge[i/len(edge)], edge[i%len(edge)]
+		}
+		bigA := new(big.Int).SetUint64(a)
+		bigB := new(big.Int).SetUint64(b)
+
+		sum := new(big.Int).Add(bigA, bigB)
+		require.Equal(t, sum.Mod(sum, p).Uint64(), Add64(a, b))
+
+		diff := new(big.Int).Sub(bigA, bigB)
+		require.Equal(t, diff.Mod(diff, p).Uint64(), Sub64(a, b))
+
+		prod := new(big.Int).Mul(bigA, bigB)
+		require.Equal(t, prod.Mod(prod, p).Uint64(), Mul64(a, b))
+
+		require.Equal(t, uint64(0), Add64(a, Neg64(a)))
+	}
+}
+
+func TestReduce(t *testing.T) {
+	p := uint64(ModP)
+	edge := []uint64{0, 1, p - 1, p, p + 1, 1 << 32, 1<<62 - 1, 1<<64 - 1}
+	for _, a := range edge {
+		require.Equal(t, uint32(a%p), Reduce(a))
+	}
+
+	for i := 0; i < 1000; i++ {
+		// accumulate the maximum number of lazy products
+		var acc, expected uint64
+		for j := 0; j < LazyProducts; j++ {
+			a, b := RandElement(), RandElement()
+			if i == 0 {
+				a, b = ModP-1, ModP-1
+			}
+			acc += MulNoReduce(a, b)
+			expected = (expected + uint64(a)*uint64(b)%p) % p
+			require.Equal(t, uint32(uint64(a)*uint64(b)%p), Mul(a, b))
+		}
+		require.Equal(t, uint32(expected), Reduce(acc))
+	}
+}
+
+func TestFieldModulus(t *testing.T) {
+	_, err := New(65535)
+	require.Error(t, err)
+	_, err = New(ModP + 2)
+	require.Error(t, err)
+
+	for _, m := range []uint32{3, 65521, 2147483629, ModP} {
+		f, err := New(m)
+		require.NoError(t, err)
+		p := uint64(m)
+		edge := []uint64{0, 1, p - 1, p, p + 1, 1 << 32, 1<<62 - 1, 1<<64 - 1}
+		for _, a := range edge {
+			require.Equal(t, uint32(a%p), f.Reduce(a))
+		}
+		for i := 0; i < 1000; i++ {
+			a, b := f.RandElement(), f.RandElement()
+			require.Less(t, a, m)
+			require.Equal(t, uint32((uint64(a)+uint64(b))%p), f.Add(a, b))
+			require.Equal(t, uint32((uint64(a)+p-uint64(b))%p), f.Sub(a, b))
+			require.Equal(t, uint32(uint64(a)*uint64(b)%p), f.Mul(a, b))
+		}
+	}
+}
+
+func BenchmarkInnerProductModulo(b *testing.B) {
+	x, y := RandVector(1023), RandVector(1023)
+	for i := 0; i < b.N; i++ {
+		var acc uint32
+		for j := range x {
+			acc = uint32((uint64(acc) + uint64(x[j])*uint64(y[j])) % uint64(ModP))
+		}
+	}
+}
+
+func BenchmarkInnerProductLazy(b *testing.B) {
+	x, y := RandVector(1023), RandVector(1023)
+	for i := 0; i < b.N; i++ {
+		var acc uint32
+		// the accumulator and LazyProducts-1 products fit in a uint64
+		for j := 0; j < len(x); j += 3 {
+			acc = Reduce(uint64(acc) + MulNoReduce(x[j], y[j]) +
+				MulNoReduce(x[j+1], y[j+1]) + MulNoReduce(x[j+2], y[j+2]))
+		}
+	}
+}
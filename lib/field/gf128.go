@@ -0,0 +1,157 @@
+package field
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// Bytes128 is the length in bytes of an element of GF(2^128)
+const Bytes128 = 16
+
+// Element128 is an element of GF(2^128) = GF(2)[x]/(x^128 + x^7 + x^2 + x + 1).
+// Bit i of Lo is the coefficient of x^i and bit i of Hi the coefficient of
+// x^(64+i). Lo comes first in memory, as expected by the assembly code.
+type Element128 struct {
+	Lo, Hi uint64
+}
+
+// One128 returns the multiplicative identity
+func One128() Element128 {
+	return Element128{Lo: 1}
+}
+
+// IsZero returns true if e is the zero element
+func (e Element128) IsZero() bool {
+	return e.Lo|e.Hi == 0
+}
+
+// Add128 returns a + b, i.e., a XOR b
+func Add128(a, b Element128) Element128 {
+	return Element128{Lo: a.Lo ^ b.Lo, Hi: a.Hi ^ b.Hi}
+}
+
+// Mul128 returns a * b. It uses the carry-less multiplication instruction
+// when the CPU supports it, and a constant-time generic implementation
+// otherwise.
+func Mul128(a, b Element128) Element128 {
+	var r [4]uint64
+	clmul128(&a, &b, &r)
+	return reduce128(&r)
+}
+
+// Square128 returns a * a
+func Square128(a Element128) Element128 {
+	return Mul128(a, a)
+}
+
+// Inv128 returns the inverse of a, computed as a^(2^128 - 2). The inverse of
+// zero is zero.
+func Inv128(a Element128) Element128 {
+	// a^(2^128 - 2) = prod_{i=1}^{127} a^(2^i)
+	r := One128()
+	s := a
+	for i := 1; i < 128; i++ {
+		s = Square128(s)
+		r = Mul128(r, s)
+	}
+	return r
+}
+
+// Bytes returns the little-endian encoding of e
+func (e Element128) Bytes() []byte {
+	out := make([]byte, Bytes128)
+	binary.LittleEndian.PutUint64(out[:8], e.Lo)
+	binary.LittleEndian.PutUint64(out[8:], e.Hi)
+	return out
+}
+
+// SetBytes128 decodes the little-endian encoding of an element. The input
+// must have length Bytes128.
+func SetBytes128(in []byte) Element128 {
+	return Element128{
+		Lo: binary.LittleEndian.Uint64(in[:8]),
+		Hi: binary.LittleEndian.Uint64(in[8:Bytes128]),
+	}
+}
+
+func RandElement128WithPRG(rnd io.Reader) Element128 {
+	var buf [Bytes128]byte
+	if _, err := rnd.Read(buf[:]); err != nil {
+		panic("error in randomness")
+	}
+	return SetBytes128(buf[:])
+}
+
+func RandElement128() Element128 {
+	return RandElement128WithPRG(utils.RandomPRG())
+}
+
+// reduce128 reduces the 256-bit product r, least significant word first,
+// using x^128 = x^7 + x^2 + x + 1
+func reduce128(r *[4]uint64) Element128 {
+	// fold r[3], of weight x^192, into r[1] and r[2]
+	r[1] ^= r[3] ^ r[3]<<1 ^ r[3]<<2 ^ r[3]<<7
+	r[2] ^= r[3]>>63 ^ r[3]>>62 ^ r[3]>>57
+	// fold r[2], of weight x^128, into r[0] and r[1]
+	r[0] ^= r[2] ^ r[2]<<1 ^ r[2]<<2 ^ r[2]<<7
+	r[1] ^= r[2]>>63 ^ r[2]>>62 ^ r[2]>>57
+
+	return Element128{Lo: r[0], Hi: r[1]}
+}
+
+// clmul128Kernel returns the 256-bit carry-less product of a and b, with the
+// carry-less multiplication instruction if selected at initialization. The
+// operands are passed by value so that they do not escape to the heap.
+var clmul128Kernel = func(a, b Element128) (r [4]uint64) {
+	clmul128Generic(&a, &b, &r)
+	return r
+}
+
+// clmul128 stores in r the 256-bit carry-less product of a and b
+func clmul128(a, b *Element128, r *[4]uint64) {
+	*r = clmul128Kernel(*a, *b)
+}
+
+// clmul128Generic stores in r the 256-bit carry-less product of a and b
+func clmul128Generic(a, b *Element128, r *[4]uint64) {
+	lh, ll := clmul64(a.Lo, b.Lo)
+	hh, hl := clmul64(a.Hi, b.Hi)
+	m1h, m1l := clmul64(a.Lo, b.Hi)
+	m2h, m2l := clmul64(a.Hi, b.Lo)
+	r[0] = ll
+	r[1] = lh ^ m1l ^ m2l
+	r[2] = hl ^ m1h ^ m2h
+	r[3] = hh
+}
+
+// clmul64 returns the 128-bit carry-less product of x and y. The high half is
+// computed as the bit-reversed low half of the product of the bit-reversed
+// inputs.
+func clmul64(x, y uint64) (hi, lo uint64) {
+	lo = bmul64(x, y)
+	hi = bits.Reverse64(bmul64(bits.Reverse64(x), bits.Reverse64(y))) >> 1
+	return hi, lo
+}
+
+// bmul64 returns the low 64 bits of the carry-less product of x and y, in
+// constant time. Integer multiplications of the inputs with holes every four
+// bits make the carries fall into the holes, where they are masked out
+// (BearSSL's technique).
+func bmul64(x, y uint64) uint64 {
+	const (
+		m0 = 0x1111111111111111
+		m1 = 0x2222222222222222
+		m2 = 0x4444444444444444
+		m3 = 0x8888888888888888
+	)
+	x0, x1, x2, x3 := x&m0, x&m1, x&m2, x&m3
+	y0, y1, y2, y3 := y&m0, y&m1, y&m2, y&m3
+	z0 := (x0 * y0) ^ (x1 * y3) ^ (x2 * y2) ^ (x3 * y1)
+	z1 := (x0 * y1) ^ (x1 * y0) ^ (x2 * y3) ^ (x3 * y2)
+	z2 := (x0 * y2) ^ (x1 * y1) ^ (x2 * y0) ^ (x3 * y3)
+	z3 := (x0 * y3) ^ (x1 * y2) ^ (x2 * y1) ^ (x3 * y0)
+	return (z0 & m0) | (z1 & m1) | (z2 & m2) | (z3 & m3)
+}
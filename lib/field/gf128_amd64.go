@@ -0,0 +1,19 @@
+//go:build amd64 && !purego
+
+package field
+
+import "github.com/si-co/vpir-code/lib/utils"
+
+func init() {
+	if utils.CPU.PCLMUL {
+		clmul128Kernel = clmul128Hardware
+	}
+}
+
+//go:noescape
+func clmul128CLMUL(a, b *Element128, r *[4]uint64)
+
+func clmul128Hardware(a, b Element128) (r [4]uint64) {
+	clmul128CLMUL(&a, &b, &r)
+	return r
+}
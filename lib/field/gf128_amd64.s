@@ -0,0 +1,36 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func clmul128CLMUL(a, b *Element128, r *[4]uint64)
+TEXT ·clmul128CLMUL(SB), NOSPLIT, $0-24
+	MOVQ a+0(FP), AX
+	MOVQ b+8(FP), BX
+	MOVQ r+16(FP), CX
+
+	MOVOU (AX), X0
+	MOVOU (BX), X1
+
+	// low and high products
+	MOVOU     X0, X2
+	PCLMULQDQ $0x00, X1, X2
+	MOVOU     X0, X3
+	PCLMULQDQ $0x11, X1, X3
+
+	// middle products
+	MOVOU     X0, X4
+	PCLMULQDQ $0x01, X1, X4
+	MOVOU     X0, X5
+	PCLMULQDQ $0x10, X1, X5
+	PXOR      X5, X4
+
+	// add the middle product at 64 bits of offset
+	MOVOU  X4, X5
+	PSLLDQ $8, X4
+	PSRLDQ $8, X5
+	PXOR   X4, X2
+	PXOR   X5, X3
+
+	MOVOU X2, (CX)
+	MOVOU X3, 16(CX)
+	RET
@@ -0,0 +1,50 @@
+package field
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMul128(t *testing.T) {
+	// x^127 * x = x^128 = x^7 + x^2 + x + 1
+	x := Element128{Lo: 2}
+	x127 := Element128{Hi: 1 << 63}
+	require.Equal(t, Element128{Lo: 0x87}, Mul128(x127, x))
+
+	one := One128()
+	for i := 0; i < 1000; i++ {
+		a, b, c := RandElement128(), RandElement128(), RandElement128()
+
+		// the generic and the accelerated implementations must agree
+		var r, rGeneric [4]uint64
+		clmul128(&a, &b, &r)
+		clmul128Generic(&a, &b, &rGeneric)
+		require.Equal(t, rGeneric, r)
+
+		require.Equal(t, Mul128(a, b), Mul128(b, a))
+		require.Equal(t, a, Mul128(a, one))
+		require.Equal(t, Add128(Mul128(a, b), Mul128(a, c)), Mul128(a, Add128(b, c)))
+		require.Equal(t, Mul128(Mul128(a, b), c), Mul128(a, Mul128(b, c)))
+		require.Equal(t, a, SetBytes128(a.Bytes()))
+		if !a.IsZero() {
+			require.Equal(t, one, Mul128(a, Inv128(a)))
+		}
+	}
+}
+
+func BenchmarkMul128(b *testing.B) {
+	x, y := RandElement128(), RandElement128()
+	for i := 0; i < b.N; i++ {
+		x = Mul128(x, y)
+	}
+}
+
+func BenchmarkMul128Generic(b *testing.B) {
+	x, y := RandElement128(), RandElement128()
+	var r [4]uint64
+	for i := 0; i < b.N; i++ {
+		clmul128Generic(&x, &y, &r)
+		x = reduce128(&r)
+	}
+}
@@ -0,0 +1,140 @@
+package field
+
+import "errors"
+
+// Exp returns a^e mod p. The running time depends only on the bit length of
+// e.
+func (f *Field) Exp(a uint32, e uint64) uint32 {
+	r := uint32(1)
+	for ; e > 0; e >>= 1 {
+		// multiply in any case and select the result with a mask
+		mask := -uint32(e & 1)
+		r = (f.Mul(r, a) & mask) | (r &^ mask)
+		a = f.Mul(a, a)
+	}
+	return r
+}
+
+// Inv returns the inverse of a, computed as a^(p-2). The inverse of zero is
+// zero.
+func (f *Field) Inv(a uint32) uint32 {
+	return f.Exp(a, uint64(f.p)-2)
+}
+
+// BatchInv stores the inverses of the elements of in into out, with a single
+// inversion and 3(n-1) multiplications (Montgomery's trick). out can alias
+// in. It returns an error if any element is zero.
+func (f *Field) BatchInv(out, in []uint32) error {
+	if len(in) == 0 {
+		return nil
+	}
+	// prefix[i] = in[0] * ... * in[i]
+	prefix := make([]uint32, len(in))
+	acc := uint32(1)
+	for i, a := range in {
+		if a == 0 {
+			return errors.New("batch inversion of zero")
+		}
+		acc = f.Mul(acc, a)
+		prefix[i] = acc
+	}
+
+	// inv = (in[0] * ... * in[i])^-1, peel off one element at a time
+	inv := f.Inv(acc)
+	for i := len(in) - 1; i > 0; i-- {
+		a := in[i]
+		out[i] = f.Mul(inv, prefix[i-1])
+		inv = f.Mul(inv, a)
+	}
+	out[0] = inv
+
+	return nil
+}
+
+// LagrangeCoefficients returns the Lagrange coefficients l_i(x) for the
+// distinct evaluation points xs, so that the polynomial interpolating
+// (xs[i], ys[i]) evaluates at x to sum_i l_i(x) * ys[i]. Use x = 0 to
+// reconstruct a Shamir secret.
+func (f *Field) LagrangeCoefficients(xs []uint32, x uint32) ([]uint32, error) {
+	// l_i(x) = prod_{j != i} (x - x_j) / (x_i - x_j)
+	num := make([]uint32, len(xs))
+	den := make([]uint32, len(xs))
+	for i := range xs {
+		num[i], den[i] = 1, 1
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			num[i] = f.Mul(num[i], f.Sub(x, xs[j]))
+			den[i] = f.Mul(den[i], f.Sub(xs[i], xs[j]))
+		}
+	}
+	if err := f.BatchInv(den, den); err != nil {
+		return nil, errors.New("evaluation points are not distinct")
+	}
+	for i := range num {
+		num[i] = f.Mul(num[i], den[i])
+	}
+
+	return num, nil
+}
+
+// RangeLagrangeCoefficients returns the Lagrange coefficients l_i(x) for the
+// consecutive evaluation points 0, 1, ..., m, as LagrangeCoefficients, with
+// O(m) multiplications and two inversions instead of O(m^2)
+func (f *Field) RangeLagrangeCoefficients(m int, x uint32) ([]uint32, error) {
+	if m < 0 || uint64(m) >= uint64(f.p) {
+		return nil, errors.New("evaluation points out of range")
+	}
+	out := make([]uint32, m+1)
+	if uint64(x) <= uint64(m) {
+		out[x] = 1
+		return out, nil
+	}
+
+	// l_i(x) = prod_j (x - j) / (x - i) / ((-1)^(m-i) i! (m-i)!)
+	diffs := make([]uint32, m+1)
+	all := uint32(1)
+	for i := range diffs {
+		diffs[i] = f.Sub(x, uint32(i))
+		all = f.Mul(all, diffs[i])
+	}
+	fact := make([]uint32, m+1)
+	fact[0] = 1
+	for i := 1; i <= m; i++ {
+		fact[i] = f.Mul(fact[i-1], uint32(i))
+	}
+	den := make([]uint32, m+1)
+	for i := range den {
+		den[i] = f.Mul(diffs[i], f.Mul(fact[i], fact[m-i]))
+		if (m-i)%2 == 1 {
+			den[i] = f.Neg(den[i])
+		}
+	}
+	if err := f.BatchInv(den, den); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i] = f.Mul(all, den[i])
+	}
+
+	return out, nil
+}
+
+// Interpolate evaluates at x the polynomial of degree len(xs)-1 going
+// through the points (xs[i], ys[i])
+func (f *Field) Interpolate(xs, ys []uint32, x uint32) (uint32, error) {
+	if len(xs) != len(ys) {
+		return 0, errors.New("different number of evaluation points and values")
+	}
+	coeffs, err := f.LagrangeCoefficients(xs, x)
+	if err != nil {
+		return 0, err
+	}
+	acc := uint32(0)
+	for i := range coeffs {
+		acc = f.Add(acc, f.Mul(coeffs[i], ys[i]))
+	}
+
+	return acc, nil
+}
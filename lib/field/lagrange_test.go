@@ -0,0 +1,68 @@
+package field
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchInv(t *testing.T) {
+	for _, f := range []*Field{Default(), mustNew(65521)} {
+		in := make([]uint32, 100)
+		for i := range in {
+			in[i] = 1 + f.RandElement()%(f.Modulus()-1)
+		}
+		out := make([]uint32, len(in))
+		require.NoError(t, f.BatchInv(out, in))
+		for i := range in {
+			require.Equal(t, uint32(1), f.Mul(in[i], out[i]))
+			require.Equal(t, f.Inv(in[i]), out[i])
+		}
+
+		in[42] = 0
+		require.Error(t, f.BatchInv(out, in))
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	f := Default()
+	// random polynomial of degree 4
+	poly := make([]uint32, 5)
+	for i := range poly {
+		poly[i] = f.RandElement()
+	}
+	eval := func(x uint32) uint32 {
+		return f.Horner(poly, x)
+	}
+
+	xs := []uint32{1, 2, 3, 7, 11}
+	ys := make([]uint32, len(xs))
+	for i := range xs {
+		ys[i] = eval(xs[i])
+	}
+	for _, x := range []uint32{0, 5, ModP - 1} {
+		y, err := f.Interpolate(xs, ys, x)
+		require.NoError(t, err)
+		require.Equal(t, eval(x), y)
+	}
+
+	_, err := f.Interpolate([]uint32{1, 1}, []uint32{2, 3}, 0)
+	require.Error(t, err)
+}
+
+func TestRangeLagrangeCoefficients(t *testing.T) {
+	for _, f := range []*Field{Default(), mustNew(65521)} {
+		m := 9
+		xs := make([]uint32, m+1)
+		for i := range xs {
+			xs[i] = uint32(i)
+		}
+		for _, x := range []uint32{0, 4, uint32(m), uint32(m) + 1, 1000, f.Modulus() - 1} {
+			expected, err := f.LagrangeCoefficients(xs, x)
+			require.NoError(t, err)
+			l, err := f.RangeLagrangeCoefficients(m, x)
+			require.NoError(t, err)
+			require.Equal(t, expected, l)
+		}
+	}
+}
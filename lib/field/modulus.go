@@ -0,0 +1,148 @@
+package field
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"math/bits"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// Field is a prime field whose modulus is chosen at runtime. The modulus must
+// be at most ModP, so that the sum of two elements fits in a uint32. Methods
+// reduce with Barrett reduction, since the modulus is not known at compile
+// time, and the default field uses the optimized functions of the package.
+type Field struct {
+	p  uint32
+	mu uint64 // floor(2^64 / p), for Barrett reduction
+}
+
+var defaultField = mustNew(ModP)
+
+// Default returns the field with modulus ModP
+func Default() *Field {
+	return defaultField
+}
+
+// New returns the field with the given prime modulus
+func New(p uint32) (*Field, error) {
+	if p < 3 || p > ModP {
+		return nil, errors.New("modulus out of range")
+	}
+	if !new(big.Int).SetUint64(uint64(p)).ProbablyPrime(20) {
+		return nil, errors.New("modulus is not prime")
+	}
+	mu, _ := bits.Div64(1, 0, uint64(p))
+
+	return &Field{p: p, mu: mu}, nil
+}
+
+func mustNew(p uint32) *Field {
+	f, err := New(p)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Modulus returns the modulus of the field
+func (f *Field) Modulus() uint32 {
+	return f.p
+}
+
+// IsDefault returns true if the modulus of the field is ModP
+func (f *Field) IsDefault() bool {
+	return f.p == ModP
+}
+
+// Add returns a + b mod p for reduced elements
+func (f *Field) Add(a, b uint32) uint32 {
+	return condSub32(a+b, f.p)
+}
+
+// Sub returns a - b mod p for reduced elements
+func (f *Field) Sub(a, b uint32) uint32 {
+	return f.Add(a, f.Neg(b))
+}
+
+// Neg returns -a mod p for reduced elements
+func (f *Field) Neg(a uint32) uint32 {
+	return condSub32(f.p-a, f.p)
+}
+
+// Mul returns a * b mod p for reduced elements
+func (f *Field) Mul(a, b uint32) uint32 {
+	return f.Reduce(MulNoReduce(a, b))
+}
+
+// Reduce reduces any uint64 modulo p. Up to LazyProducts products returned by
+// MulNoReduce can be accumulated before reducing.
+func (f *Field) Reduce(a uint64) uint32 {
+	if f.IsDefault() {
+		return Reduce(a)
+	}
+	q, _ := bits.Mul64(a, f.mu)
+	r := a - q*uint64(f.p) // r < 3p
+	r = condSub64(r, uint64(f.p))
+	return uint32(condSub64(r, uint64(f.p)))
+}
+
+// AddVectors stores a + b in out, element-wise
+func (f *Field) AddVectors(out, a, b []uint32) {
+	if f.IsDefault() {
+		AddVectors(out, a, b)
+		return
+	}
+	for i := range out {
+		out[i] = f.Add(a[i], b[i])
+	}
+}
+
+// AddMulVector stores out + a * c in out, element-wise
+func (f *Field) AddMulVector(out, a []uint32, c uint32) {
+	if f.IsDefault() {
+		AddMulVector(out, a, c)
+		return
+	}
+	for i := range out {
+		out[i] = f.Reduce(uint64(out[i]) + MulNoReduce(a[i], c))
+	}
+}
+
+// ElementBytes returns the number of random bytes used to sample an element
+func (f *Field) ElementBytes() int {
+	if f.IsDefault() {
+		return Bytes
+	}
+	// reducing 64 random bits makes the bias negligible for any modulus
+	return 8
+}
+
+// BytesToElements converts ElementBytes bytes of the input into each element
+// of out
+func (f *Field) BytesToElements(out []uint32, in []byte) {
+	if f.IsDefault() {
+		BytesToElements(out, in)
+		return
+	}
+	for i := range out {
+		out[i] = f.Reduce(binary.BigEndian.Uint64(in[i*8 : (i+1)*8]))
+	}
+}
+
+func (f *Field) RandElementWithPRG(rnd io.Reader) uint32 {
+	if f.IsDefault() {
+		return RandElementWithPRG(rnd)
+	}
+	var buf [8]byte
+	if _, err := rnd.Read(buf[:]); err != nil {
+		panic("error in randomness")
+	}
+	return f.Reduce(binary.BigEndian.Uint64(buf[:]))
+}
+
+func (f *Field) RandElement() uint32 {
+	return f.RandElementWithPRG(utils.RandomPRG())
+}
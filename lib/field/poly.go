@@ -0,0 +1,40 @@
+package field
+
+// Polynomial helpers for the MAC-based verification, where tags are inner
+// products with the powers of a secret point.
+
+// Horner returns sum_i coeffs[i] * x^i
+func (f *Field) Horner(coeffs []uint32, x uint32) uint32 {
+	acc := uint32(0)
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		acc = f.Add(f.Mul(acc, x), coeffs[i])
+	}
+	return acc
+}
+
+// Powers stores x^i in out[i], for i = 0, ..., len(out)-1
+func (f *Field) Powers(out []uint32, x uint32) {
+	p := uint32(1)
+	for i := range out {
+		out[i] = p
+		p = f.Mul(p, x)
+	}
+}
+
+// Horner64 is the same as Horner for the 64-bit field
+func Horner64(coeffs []uint64, x uint64) uint64 {
+	acc := uint64(0)
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		acc = Add64(Mul64(acc, x), coeffs[i])
+	}
+	return acc
+}
+
+// Powers64 is the same as Powers for the 64-bit field
+func Powers64(out []uint64, x uint64) {
+	p := uint64(1)
+	for i := range out {
+		out[i] = p
+		p = Mul64(p, x)
+	}
+}
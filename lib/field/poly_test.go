@@ -0,0 +1,38 @@
+package field
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHorner(t *testing.T) {
+	for _, f := range []*Field{Default(), mustNew(65521)} {
+		coeffs := make([]uint32, 10)
+		for i := range coeffs {
+			coeffs[i] = f.RandElement()
+		}
+		x := f.RandElement()
+
+		// Horner must agree with the inner product with the powers of x
+		powers := make([]uint32, len(coeffs))
+		f.Powers(powers, x)
+		require.Equal(t, uint32(1), powers[0])
+		expected := uint32(0)
+		for i := range coeffs {
+			expected = f.Add(expected, f.Mul(coeffs[i], powers[i]))
+		}
+		require.Equal(t, expected, f.Horner(coeffs, x))
+		require.Equal(t, uint32(0), f.Horner(nil, x))
+	}
+
+	coeffs := RandVector64WithPRG(10, NewXOF([]byte("seed")))
+	x := RandElement64()
+	powers := make([]uint64, len(coeffs))
+	Powers64(powers, x)
+	expected := uint64(0)
+	for i := range coeffs {
+		expected = Add64(expected, Mul64(coeffs[i], powers[i]))
+	}
+	require.Equal(t, expected, Horner64(coeffs, x))
+}
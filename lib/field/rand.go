@@ -0,0 +1,86 @@
+package field
+
+import (
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// randChunk is the number of random bytes read at once by the bulk samplers
+const randChunk = 4096
+
+// FillRandVectorWithPRG fills out with uniformly random elements read from
+// rnd. Random bytes are read in large chunks and out-of-range values are
+// rejected in-line, so that the cost per element is a few operations.
+func FillRandVectorWithPRG(out []uint32, rnd io.Reader) {
+	buf := make([]byte, randChunk)
+	pos := len(buf)
+	for i := 0; i < len(out); {
+		if pos == len(buf) {
+			// never read more than needed for the remaining elements, plus
+			// some slack for rejections
+			n := (len(out)-i)*Bytes + 64
+			if n < len(buf) {
+				buf = buf[:n]
+			}
+			if _, err := io.ReadFull(rnd, buf); err != nil {
+				panic("error in randomness")
+			}
+			pos = 0
+		}
+		for ; pos < len(buf) && i < len(out); pos += Bytes {
+			// clearing the top most bit of uint32
+			e := binary.BigEndian.Uint32(buf[pos:]) & ModP
+			if e != ModP {
+				out[i] = e
+				i++
+			}
+		}
+	}
+}
+
+// FillRandVector64WithPRG is the same as FillRandVectorWithPRG for the 64-bit
+// field
+func FillRandVector64WithPRG(out []uint64, rnd io.Reader) {
+	buf := make([]byte, randChunk)
+	pos := len(buf)
+	for i := 0; i < len(out); {
+		if pos == len(buf) {
+			n := (len(out)-i)*Bytes64 + 64
+			if n < len(buf) {
+				buf = buf[:n]
+			}
+			if _, err := io.ReadFull(rnd, buf); err != nil {
+				panic("error in randomness")
+			}
+			pos = 0
+		}
+		for ; pos < len(buf) && i < len(out); pos += Bytes64 {
+			// clearing the three top most bits of uint64
+			e := binary.BigEndian.Uint64(buf[pos:]) & ModP64
+			if e != ModP64 {
+				out[i] = e
+				i++
+			}
+		}
+	}
+}
+
+// NewXOF returns a reader of an unbounded stream of pseudorandom bytes
+// derived from the seed, of at most 64 bytes, with the blake2b XOF
+func NewXOF(seed []byte) io.Reader {
+	xof, err := blake2b.NewXOF(blake2b.OutputLengthUnknown, seed)
+	if err != nil {
+		panic(err)
+	}
+	return xof
+}
+
+// RandVectorFromSeed returns a vector of pseudorandom elements expanded from
+// the seed with the blake2b XOF. The same seed always gives the same vector.
+func RandVectorFromSeed(length int, seed []byte) []uint32 {
+	out := make([]uint32, length)
+	FillRandVectorWithPRG(out, NewXOF(seed))
+	return out
+}
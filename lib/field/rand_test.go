@@ -0,0 +1,54 @@
+package field
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandVector(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 1023, 10000} {
+		v := RandVector(n)
+		require.Len(t, v, n)
+		for _, e := range v {
+			require.Less(t, e, ModP)
+		}
+		v64 := RandVector64WithPRG(n, utils.RandomPRG())
+		for _, e := range v64 {
+			require.Less(t, e, ModP64)
+		}
+	}
+
+	// expanding the same seed gives the same vector
+	seed := []byte("seed")
+	require.Equal(t, RandVectorFromSeed(100, seed), RandVectorFromSeed(100, seed))
+	require.NotEqual(t, RandVectorFromSeed(100, seed), RandVectorFromSeed(100, []byte("other")))
+	// a shorter vector is a prefix of a longer one
+	require.Equal(t, RandVectorFromSeed(100, seed)[:10], RandVectorFromSeed(10, seed))
+}
+
+func BenchmarkRandVectorPerElement(b *testing.B) {
+	rnd := utils.RandomPRG()
+	out := make([]uint32, 1<<16)
+	for i := 0; i < b.N; i++ {
+		for j := range out {
+			out[j] = RandElementWithPRG(rnd)
+		}
+	}
+}
+
+func BenchmarkRandVectorBulk(b *testing.B) {
+	rnd := utils.RandomPRG()
+	out := make([]uint32, 1<<16)
+	for i := 0; i < b.N; i++ {
+		FillRandVectorWithPRG(out, rnd)
+	}
+}
+
+func BenchmarkRandVectorXOF(b *testing.B) {
+	out := make([]uint32, 1<<16)
+	for i := 0; i < b.N; i++ {
+		FillRandVectorWithPRG(out, NewXOF([]byte("seed")))
+	}
+}
@@ -0,0 +1,46 @@
+package field
+
+// Bulk arithmetic over vectors of elements of the 32-bit field. The exported
+// functions call the kernels selected at initialization, the SIMD ones when
+// utils.CPU has the features they need, and the generic implementations
+// below otherwise. All the input elements must be reduced, i.e., smaller than
+// ModP.
+
+var (
+	addVectors   = addVectorsGeneric
+	mulVectors   = mulVectorsGeneric
+	addMulVector = addMulVectorGeneric
+)
+
+// AddVectors stores a + b in out, element-wise. out can alias a or b.
+func AddVectors(out, a, b []uint32) {
+	addVectors(out, a[:len(out)], b[:len(out)])
+}
+
+// MulVectors stores a * b in out, element-wise. out can alias a or b.
+func MulVectors(out, a, b []uint32) {
+	mulVectors(out, a[:len(out)], b[:len(out)])
+}
+
+// AddMulVector stores out + a * c in out, element-wise
+func AddMulVector(out, a []uint32, c uint32) {
+	addMulVector(out, a[:len(out)], c)
+}
+
+func addVectorsGeneric(out, a, b []uint32) {
+	for i := range out {
+		out[i] = reduceSmall(a[i] + b[i])
+	}
+}
+
+func mulVectorsGeneric(out, a, b []uint32) {
+	for i := range out {
+		out[i] = Mul(a[i], b[i])
+	}
+}
+
+func addMulVectorGeneric(out, a []uint32, c uint32) {
+	for i := range out {
+		out[i] = reduceSmall(out[i] + Mul(a[i], c))
+	}
+}
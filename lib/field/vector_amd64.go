@@ -0,0 +1,55 @@
+//go:build amd64 && !purego
+
+package field
+
+import "github.com/si-co/vpir-code/lib/utils"
+
+// number of elements processed by one iteration of the AVX2 kernels
+const avx2Lanes = 8
+
+func init() {
+	if utils.CPU.AVX2 {
+		addVectors = addVectorsSIMD
+		mulVectors = mulVectorsSIMD
+		addMulVector = addMulVectorSIMD
+	}
+}
+
+// The AVX2 kernels only process len(out) elements, which must be a multiple
+// of avx2Lanes.
+
+//go:noescape
+func addVectorsAVX2(out, a, b []uint32)
+
+//go:noescape
+func mulVectorsAVX2(out, a, b []uint32)
+
+//go:noescape
+func addMulVectorAVX2(out, a []uint32, c uint32)
+
+// The SIMD kernels process the multiple of avx2Lanes first elements with
+// AVX2 and the remaining ones with the generic kernels.
+
+func addVectorsSIMD(out, a, b []uint32) {
+	n := len(out) &^ (avx2Lanes - 1)
+	if n > 0 {
+		addVectorsAVX2(out[:n], a[:n], b[:n])
+	}
+	addVectorsGeneric(out[n:], a[n:], b[n:])
+}
+
+func mulVectorsSIMD(out, a, b []uint32) {
+	n := len(out) &^ (avx2Lanes - 1)
+	if n > 0 {
+		mulVectorsAVX2(out[:n], a[:n], b[:n])
+	}
+	mulVectorsGeneric(out[n:], a[n:], b[n:])
+}
+
+func addMulVectorSIMD(out, a []uint32, c uint32) {
+	n := len(out) &^ (avx2Lanes - 1)
+	if n > 0 {
+		addMulVectorAVX2(out[:n], a[:n], c)
+	}
+	addMulVectorGeneric(out[n:], a[n:], c)
+}
@@ -0,0 +1,121 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// Y15 holds ModP in every 32-bit lane, Y14 holds ModP in every 64-bit lane.
+#define LOAD_CONSTANTS \
+	MOVQ         $0x7fffffff, AX; \
+	MOVQ         AX, X14;         \
+	VPBROADCASTD X14, Y15;        \
+	VPBROADCASTQ X14, Y14
+
+// ADDMOD computes A = A + B mod ModP in every 32-bit lane, clobbering T
+#define ADDMOD(A, B, T) \
+	VPADDD   B, A, A;    \
+	VPSRLD   $31, A, T;  \
+	VPAND    Y15, A, A;  \
+	VPADDD   T, A, A;    \
+	VPCMPEQD Y15, A, T;  \
+	VPANDN   A, T, A
+
+// REDUCE64 reduces the 64-bit lanes of R, each smaller than 2^62, modulo
+// ModP, clobbering T
+#define REDUCE64(R, T) \
+	VPSRLQ   $31, R, T;  \
+	VPAND    Y14, R, R;  \
+	VPADDQ   T, R, R;    \
+	VPSRLQ   $31, R, T;  \
+	VPAND    Y14, R, R;  \
+	VPADDQ   T, R, R;    \
+	VPCMPEQQ Y14, R, T;  \
+	VPANDN   R, T, R
+
+// MULMOD computes A = A * B mod ModP in every 32-bit lane, clobbering
+// T1, T2 and T3. Even and odd lanes are multiplied separately in 64-bit
+// lanes and then recombined.
+#define MULMOD(A, B, T1, T2, T3) \
+	VPSRLQ   $32, A, T1;  \
+	VPSRLQ   $32, B, T2;  \
+	VPMULUDQ B, A, A;     \
+	VPMULUDQ T2, T1, T1;  \
+	REDUCE64(A, T3);      \
+	REDUCE64(T1, T3);     \
+	VPSLLQ   $32, T1, T1; \
+	VPOR     T1, A, A
+
+// func addVectorsAVX2(out, a, b []uint32)
+TEXT ·addVectorsAVX2(SB), NOSPLIT, $0-72
+	MOVQ out_base+0(FP), DI
+	MOVQ out_len+8(FP), CX
+	MOVQ a_base+24(FP), SI
+	MOVQ b_base+48(FP), DX
+	LOAD_CONSTANTS
+	SHRQ $3, CX
+	JZ   addDone
+
+addLoop:
+	VMOVDQU (SI), Y0
+	VMOVDQU (DX), Y1
+	ADDMOD(Y0, Y1, Y2)
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, SI
+	ADDQ    $32, DX
+	ADDQ    $32, DI
+	DECQ    CX
+	JNZ     addLoop
+
+addDone:
+	VZEROUPPER
+	RET
+
+// func mulVectorsAVX2(out, a, b []uint32)
+TEXT ·mulVectorsAVX2(SB), NOSPLIT, $0-72
+	MOVQ out_base+0(FP), DI
+	MOVQ out_len+8(FP), CX
+	MOVQ a_base+24(FP), SI
+	MOVQ b_base+48(FP), DX
+	LOAD_CONSTANTS
+	SHRQ $3, CX
+	JZ   mulDone
+
+mulLoop:
+	VMOVDQU (SI), Y0
+	VMOVDQU (DX), Y1
+	MULMOD(Y0, Y1, Y2, Y3, Y4)
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, SI
+	ADDQ    $32, DX
+	ADDQ    $32, DI
+	DECQ    CX
+	JNZ     mulLoop
+
+mulDone:
+	VZEROUPPER
+	RET
+
+// func addMulVectorAVX2(out, a []uint32, c uint32)
+TEXT ·addMulVectorAVX2(SB), NOSPLIT, $0-52
+	MOVQ         out_base+0(FP), DI
+	MOVQ         out_len+8(FP), CX
+	MOVQ         a_base+24(FP), SI
+	MOVL         c+48(FP), AX
+	MOVQ         AX, X13
+	VPBROADCASTD X13, Y13
+	LOAD_CONSTANTS
+	SHRQ         $3, CX
+	JZ           addMulDone
+
+addMulLoop:
+	VMOVDQU (SI), Y0
+	MULMOD(Y0, Y13, Y2, Y3, Y4)
+	VMOVDQU (DI), Y1
+	ADDMOD(Y0, Y1, Y2)
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	DECQ    CX
+	JNZ     addMulLoop
+
+addMulDone:
+	VZEROUPPER
+	RET
@@ -0,0 +1,82 @@
+package field
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var benchmarkLengths = []int{1 << 10, 1 << 16}
+
+func TestVectorOperations(t *testing.T) {
+	edge := []uint32{0, 1, 2, ModP - 2, ModP - 1}
+	for _, n := range []int{0, 1, 7, 8, 9, 64, 1000} {
+		a := RandVector(n)
+		b := RandVector(n)
+		for i := 0; i < n && i < len(edge)*len(edge); i++ {
+			a[i], b[i] = edge[i/len(edge)], edge[i%len(edge)]
+		}
+		c := RandElement()
+
+		sum := make([]uint32, n)
+		prod := make([]uint32, n)
+		addMul := make([]uint32, n)
+		copy(addMul, b)
+		AddVectors(sum, a, b)
+		MulVectors(prod, a, b)
+		AddMulVector(addMul, a, c)
+
+		for i := 0; i < n; i++ {
+			require.Equal(t, (a[i]+b[i])%ModP, sum[i])
+			require.Equal(t, uint32((uint64(a[i])*uint64(b[i]))%uint64(ModP)), prod[i])
+			ac := (uint64(a[i]) * uint64(c)) % uint64(ModP)
+			require.Equal(t, uint32((uint64(b[i])+ac)%uint64(ModP)), addMul[i])
+		}
+
+		// in-place operations
+		AddVectors(a, a, b)
+		require.Equal(t, sum, a)
+	}
+}
+
+func BenchmarkAddVectors(b *testing.B) {
+	benchmarkBinary(b, AddVectors, addVectorsGeneric)
+}
+
+func BenchmarkMulVectors(b *testing.B) {
+	benchmarkBinary(b, MulVectors, mulVectorsGeneric)
+}
+
+func BenchmarkAddMulVector(b *testing.B) {
+	for _, n := range benchmarkLengths {
+		x, out := RandVector(n), RandVector(n)
+		c := RandElement()
+		b.Run(fmt.Sprintf("dispatch-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				AddMulVector(out, x, c)
+			}
+		})
+		b.Run(fmt.Sprintf("generic-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				addMulVectorGeneric(out, x, c)
+			}
+		})
+	}
+}
+
+func benchmarkBinary(b *testing.B, dispatch, generic func(out, x, y []uint32)) {
+	for _, n := range benchmarkLengths {
+		x, y, out := RandVector(n), RandVector(n), make([]uint32, n)
+		b.Run(fmt.Sprintf("dispatch-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dispatch(out, x, y)
+			}
+		})
+		b.Run(fmt.Sprintf("generic-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				generic(out, x, y)
+			}
+		})
+	}
+}
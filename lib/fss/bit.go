@@ -0,0 +1,89 @@
+package fss
+
+// This file contains the DPF variant with single-bit outputs in GF(2). The
+// shares of the two servers are combined with XOR and the output is the
+// control bit at the end of the evaluation path, which is 1 for both servers
+// only on the special point, so that no final correction word is needed.
+
+import (
+	"crypto/aes"
+	"math/bits"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// GenerateTreeBit generates the keys for a 2-party point function over GF(2)
+// that evaluates to 1 when x = a and to 0 otherwise. The FinalCW field of the
+// returned keys is not used.
+func (f Fss) GenerateTreeBit(a []bool) []FssKeyEq2P {
+	fssKeys, _, _, _ := f.generateTree(a)
+	return fssKeys
+}
+
+// EvaluateBit returns the share of the point function on input x
+func (f Fss) EvaluateBit(k FssKeyEq2P, x []bool) byte {
+	_, t := f.evaluateTree(k, x)
+	return t
+}
+
+// EvaluateFullDomainBits evaluates the key on the first n inputs of the
+// domain of numBits bits, with inputs encoded most significant bit first. The
+// shares are packed in out, which must be at least n/8+1 bytes long, with the
+// share for input j stored in bit j%8 of out[j/8]. The evaluation expands the
+// tree level by level, costing O(n) PRF evaluations instead of O(n log n).
+func (f Fss) EvaluateFullDomainBits(k FssKeyEq2P, numBits uint, n int, out []byte) {
+	// the nodes of a level are expanded from the buffers of the previous one,
+	// which are then swapped
+	seedsBuf, nextSeedsBuf := utils.GetBuffer(aes.BlockSize*(n+1)), utils.GetBuffer(aes.BlockSize*(n+1))
+	tsBuf, nextTsBuf := utils.GetBuffer(n+1), utils.GetBuffer(n+1)
+	defer func() {
+		seedsBuf.Release()
+		nextSeedsBuf.Release()
+		tsBuf.Release()
+		nextTsBuf.Release()
+	}()
+	seeds := append(seedsBuf.B[:0], k.SInit...)
+	ts := append(tsBuf.B[:0], k.TInit)
+
+	for i := uint(0); i < numBits; i++ {
+		// only expand the nodes that are prefixes of inputs smaller than n
+		shift := numBits - i - 1
+		needed := (n + (1 << shift) - 1) >> shift
+
+		nextSeeds := nextSeedsBuf.B[:0]
+		nextTs := nextTsBuf.B[:0]
+		for j := range ts {
+			f.expand(seeds[j*aes.BlockSize:(j+1)*aes.BlockSize], ts[j], k.CW[i])
+			nextSeeds = append(nextSeeds, f.Out[:aes.BlockSize]...)
+			nextTs = append(nextTs, f.Out[aes.BlockSize]%2)
+			if len(nextTs) == needed {
+				break
+			}
+			nextSeeds = append(nextSeeds, f.Out[aes.BlockSize+1:aes.BlockSize*2+1]...)
+			nextTs = append(nextTs, f.Out[aes.BlockSize*2+1]%2)
+		}
+		seeds, ts = nextSeeds, nextTs
+		seedsBuf, nextSeedsBuf = nextSeedsBuf, seedsBuf
+		tsBuf, nextTsBuf = nextTsBuf, tsBuf
+	}
+
+	for j := 0; j < n; j++ {
+		out[j/8] |= ts[j] << (j % 8)
+	}
+}
+
+// NumBitsForDomain returns the number of input bits needed to address n
+// elements
+func NumBitsForDomain(n int) uint {
+	return uint(bits.Len(uint(n - 1)))
+}
+
+// IndexToBits returns the numBits-bit representation of i, most significant
+// bit first, as used for the DPF inputs
+func IndexToBits(i int, numBits uint) []bool {
+	out := make([]bool, numBits)
+	for j := range out {
+		out[j] = (i>>(numBits-uint(j)-1))&1 == 1
+	}
+	return out
+}
@@ -16,8 +16,7 @@ import (
 // of bits to check
 func ClientInitialize(blockLength int) *Fss {
 	f := new(Fss)
-	f.BlockLength = blockLength
-	initPRFLen := 4
+	initPRFLen := NumPrfKeys
 	// Create fixed AES blocks
 	f.FixedBlocks = make([]cipher.Block, initPRFLen)
 	for i := uint(0); i < uint(initPRFLen); i++ {
@@ -27,10 +26,13 @@ func ClientInitialize(blockLength int) *Fss {
 		}
 		f.FixedBlocks[i] = block
 	}
+	f.Epoch = prfEpoch
 	f.N = 256 // maximum number of bits supported by FSS
 	f.Temp = make([]byte, aes.BlockSize)
 	f.Out = make([]byte, aes.BlockSize*initPRFLen)
-	f.OutConvertBlock = make([]byte, blockLength*field.Bytes)
+	f.Seed = make([]byte, aes.BlockSize)
+	f.BlockLength = blockLength
+	f.SetField(field.Default())
 
 	return f
 }
@@ -38,6 +40,63 @@ func ClientInitialize(blockLength int) *Fss {
 // Generate Keys for 2-party point functions It creates keys for a function
 // that evaluates to vector b when input x = a.
 func (f Fss) GenerateTreePF(a []bool, b []uint32) []FssKeyEq2P {
+	fssKeys, sCurr0, sCurr1, tCurr1 := f.generateTree(a)
+
+	bLen := uint(len(b))
+
+	// convert blocks
+	tmp0 := make([]uint32, bLen)
+	tmp1 := make([]uint32, bLen)
+	convertBlock(f, sCurr0, tmp0)
+	convertBlock(f, sCurr1, tmp1)
+
+	fssKeys[0].FinalCW = make([]uint32, bLen)
+	fssKeys[1].FinalCW = make([]uint32, bLen)
+
+	for i := range fssKeys[0].FinalCW {
+		// Need to make sure that no intermediate
+		// results under or overflow the 32-bit modulus
+
+		fssKeys[0].FinalCW[i] = f.Field.Add(f.Field.Sub(b[i], tmp0[i]), tmp1[i])
+		fssKeys[1].FinalCW[i] = fssKeys[0].FinalCW[i]
+		if tCurr1 == 1 {
+			fssKeys[0].FinalCW[i] = f.Field.Neg(fssKeys[0].FinalCW[i])
+			fssKeys[1].FinalCW[i] = fssKeys[0].FinalCW[i]
+		}
+	}
+
+	return fssKeys
+}
+
+// GenerateTreePF64 generates the keys for a 2-party point function that
+// evaluates to vector b of elements of the 64-bit field when input x = a.
+func (f Fss) GenerateTreePF64(a []bool, b []uint64) []FssKeyEq2P {
+	fssKeys, sCurr0, sCurr1, tCurr1 := f.generateTree(a)
+
+	// convert blocks
+	tmp0 := make([]uint64, len(b))
+	tmp1 := make([]uint64, len(b))
+	convertBlock64(f, sCurr0, tmp0)
+	convertBlock64(f, sCurr1, tmp1)
+
+	finalCW := make([]uint64, len(b))
+	for i := range finalCW {
+		finalCW[i] = field.Add64(field.Sub64(b[i], tmp0[i]), tmp1[i])
+		if tCurr1 == 1 {
+			finalCW[i] = field.Neg64(finalCW[i])
+		}
+	}
+	fssKeys[0].FinalCW64 = finalCW
+	fssKeys[1].FinalCW64 = finalCW
+
+	return fssKeys
+}
+
+// generateTree generates the seeds and correction words of the keys for a
+// 2-party point function with special point a. It returns the keys together
+// with the final seeds of both parties and the final control bit of the
+// second party, which are needed to compute the final correction word.
+func (f Fss) generateTree(a []bool) ([]FssKeyEq2P, []byte, []byte, byte) {
 	// reinitialize f.NumBits because we have different input lengths
 	f.NumBits = uint(len(a))
 
@@ -50,6 +109,8 @@ func (f Fss) GenerateTreePF(a []bool, b []uint32) []FssKeyEq2P {
 	fssKeys[1].SInit = make([]byte, aes.BlockSize)
 	rand.Read(fssKeys[1].SInit)
 	fssKeys[1].TInit = fssKeys[0].TInit ^ 1
+	fssKeys[0].Epoch = f.Epoch
+	fssKeys[1].Epoch = f.Epoch
 
 	// Set current seed being used
 	sCurr0 := make([]byte, aes.BlockSize)
@@ -122,31 +183,5 @@ func (f Fss) GenerateTreePF(a []bool, b []uint32) []FssKeyEq2P {
 		tCurr1 = (prfOut1[keep+aes.BlockSize] % 2) ^ tCWKeep*tCurr1
 	}
 
-	bLen := uint(len(b))
-
-	// convert blocks
-	tmp0 := make([]uint32, bLen)
-	tmp1 := make([]uint32, bLen)
-	convertBlock(f, sCurr0, tmp0)
-	convertBlock(f, sCurr1, tmp1)
-
-	fssKeys[0].FinalCW = make([]uint32, bLen)
-	fssKeys[1].FinalCW = make([]uint32, bLen)
-
-	for i := range fssKeys[0].FinalCW {
-		// Need to make sure that no intermediate
-		// results under or overflow the 32-bit modulus
-
-		//fssKeys[0].FinalCW[i] = (b[i] - tmp0[i] + tmp1[i]) % field.ModP
-		val := (b[i] + (field.ModP - tmp0[i])) % field.ModP
-		val = (val + tmp1[i]) % field.ModP
-		fssKeys[0].FinalCW[i] = val
-		fssKeys[1].FinalCW[i] = fssKeys[0].FinalCW[i]
-		if tCurr1 == 1 {
-			fssKeys[0].FinalCW[i] = field.ModP - fssKeys[0].FinalCW[i] // negation
-			fssKeys[1].FinalCW[i] = fssKeys[0].FinalCW[i]
-		}
-	}
-
-	return fssKeys
+	return fssKeys, sCurr0, sCurr1, tCurr1
 }
@@ -0,0 +1,153 @@
+package fss
+
+import (
+	"crypto/aes"
+	"errors"
+	"fmt"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// The fixed-layout encoding of the point function keys sent to the servers:
+//
+//	version   1 byte
+//	Epoch     8 bytes
+//	SInit     16 bytes
+//	TInit     1 byte
+//	CW        32-bit count, then 18 bytes per correction word
+//	FinalCW   32-bit count, then 4 bytes per element
+//	FinalCW64 32-bit count, then 8 bytes per element
+//
+// with the integers in big-endian order. The encoding of a list of keys is
+// their 32-bit count followed by the keys.
+
+// keyVersion is the version of the layout of the keys
+const keyVersion = 2
+
+// cwLen is the length of a correction word of the point function keys
+const cwLen = aes.BlockSize + 2
+
+// ErrMalformedKey is returned when the encoding of a key is malformed
+var ErrMalformedKey = errors.New("malformed FSS key")
+
+// AppendKey appends the encoding of the key, generated by GenerateTreePF or
+// GenerateTreeBit, to dst
+func AppendKey(dst []byte, k *FssKeyEq2P) []byte {
+	dst = append(dst, keyVersion)
+	dst = utils.AppendUint64(dst, k.Epoch)
+	dst = append(dst, k.SInit...)
+	dst = append(dst, k.TInit)
+	dst = utils.AppendUint32(dst, uint32(len(k.CW)))
+	for _, cw := range k.CW {
+		dst = append(dst, cw...)
+	}
+	dst = utils.AppendUint32(dst, uint32(len(k.FinalCW)))
+	for _, e := range k.FinalCW {
+		dst = utils.AppendUint32(dst, e)
+	}
+	dst = utils.AppendUint32(dst, uint32(len(k.FinalCW64)))
+	for _, e := range k.FinalCW64 {
+		dst = utils.AppendUint64(dst, e)
+	}
+	return dst
+}
+
+// EncodeKey returns the encoding of the key
+func EncodeKey(k *FssKeyEq2P) []byte {
+	return AppendKey(make([]byte, 0, keyLen(k)), k)
+}
+
+// DecodeKey decodes a key encoded with EncodeKey
+func DecodeKey(in []byte) (FssKeyEq2P, error) {
+	r := utils.NewReader(in)
+	k, err := readKey(r)
+	if err == nil {
+		err = r.Done()
+	}
+	if err != nil {
+		return FssKeyEq2P{}, fmt.Errorf("%w: %v", ErrMalformedKey, err)
+	}
+	return k, nil
+}
+
+// EncodeKeys returns the encoding of the list of keys
+func EncodeKeys(keys []FssKeyEq2P) []byte {
+	n := 4
+	for i := range keys {
+		n += keyLen(&keys[i])
+	}
+	out := utils.AppendUint32(make([]byte, 0, n), uint32(len(keys)))
+	for i := range keys {
+		out = AppendKey(out, &keys[i])
+	}
+	return out
+}
+
+// DecodeKeys decodes a list of keys encoded with EncodeKeys
+func DecodeKeys(in []byte) ([]FssKeyEq2P, error) {
+	r := utils.NewReader(in)
+	// a key takes at least its fixed fields
+	keys := make([]FssKeyEq2P, r.Count(1+8+aes.BlockSize+1+3*4))
+	for i := range keys {
+		var err error
+		if keys[i], err = readKey(r); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedKey, err)
+		}
+	}
+	if err := r.Done(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedKey, err)
+	}
+	return keys, nil
+}
+
+// keyLen returns the length of the encoding of the key
+func keyLen(k *FssKeyEq2P) int {
+	return 1 + 8 + aes.BlockSize + 1 + 4 + len(k.CW)*cwLen + 4 + 4*len(k.FinalCW) + 4 + 8*len(k.FinalCW64)
+}
+
+// readKey reads a key, whose slices are copied in a single buffer so that
+// the key does not alias the input
+func readKey(r *utils.Reader) (FssKeyEq2P, error) {
+	if v := r.Uint8(); r.Err() == nil && v != keyVersion {
+		return FssKeyEq2P{}, fmt.Errorf("unknown version %d", v)
+	}
+	epoch := r.Uint64()
+	sInit := r.Bytes(aes.BlockSize)
+	tInit := r.Uint8()
+	numCW := r.Count(cwLen)
+	cws := r.Bytes(numCW * cwLen)
+	var finalCW []uint32
+	if n := r.Count(4); n > 0 {
+		finalCW = make([]uint32, n)
+		for i := range finalCW {
+			finalCW[i] = r.Uint32()
+		}
+	}
+	var finalCW64 []uint64
+	if n := r.Count(8); n > 0 {
+		finalCW64 = make([]uint64, n)
+		for i := range finalCW64 {
+			finalCW64[i] = r.Uint64()
+		}
+	}
+	if err := r.Err(); err != nil {
+		return FssKeyEq2P{}, err
+	}
+
+	buf := make([]byte, aes.BlockSize+len(cws))
+	copy(buf, sInit)
+	copy(buf[aes.BlockSize:], cws)
+	k := FssKeyEq2P{
+		SInit:     buf[:aes.BlockSize:aes.BlockSize],
+		TInit:     tInit,
+		CW:        make([][]byte, numCW),
+		FinalCW:   finalCW,
+		FinalCW64: finalCW64,
+		Epoch:     epoch,
+	}
+	for i := range k.CW {
+		off := aes.BlockSize + i*cwLen
+		k.CW[i] = buf[off : off+cwLen : off+cwLen]
+	}
+	return k, nil
+}
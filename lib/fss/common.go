@@ -5,22 +5,30 @@ package fss
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"encoding/binary"
 
 	"github.com/lukechampine/fastxor"
 	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/utils"
 )
 
 var PrfKeys [][]byte
 
 type Fss struct {
 	FixedBlocks []cipher.Block
+	Epoch       uint64 // epoch of the PRF keys of the fixed blocks
 	N           uint
 	NumBits     uint   // number of bits in domain
 	Temp        []byte // temporary slices so that we only need to allocate memory at the beginning
 	Out         []byte
+	Seed        []byte // seed of the current node of the evaluation path
 
 	BlockLength     int    // block length in number of elements
 	OutConvertBlock []byte // to gather random bytes in convertBlock, allocate once for performance
+
+	Field *field.Field // field of the outputs, field.Default() unless set
+
+	eval *evalScratch
 }
 
 // Structs for keys
@@ -29,6 +37,12 @@ type FssKeyEq2P struct {
 	TInit   byte
 	CW      [][]byte // there are n
 	FinalCW []uint32
+
+	// only used for outputs in the 64-bit field
+	FinalCW64 []uint64
+
+	// epoch of the PRF keys the key was generated with
+	Epoch uint64
 }
 
 type CWLt struct {
@@ -54,9 +68,21 @@ func init() {
 
 // Helper functions
 
+// prf is the kernel of the fixed key PRF, selected at initialization: with
+// the AES instructions, the encryption is cheap and the XOR of the input to
+// every block is done word-wise in place, and the generic kernel is used
+// otherwise
+var prf = prfGeneric
+
+func init() {
+	if utils.CPU.AES {
+		prf = prfAES
+	}
+}
+
 // fixed key PRF (Matyas–Meyer–Oseas one way compression function)
 // numBlocks represents the number
-func prf(x []byte, aesBlocks []cipher.Block, numBlocks uint, temp, out []byte) {
+func prfGeneric(x []byte, aesBlocks []cipher.Block, numBlocks uint, temp, out []byte) {
 	for i := uint(0); i < numBlocks; i++ {
 		// get AES_k[i](x)
 		aesBlocks[i].Encrypt(temp, x)
@@ -65,8 +91,49 @@ func prf(x []byte, aesBlocks []cipher.Block, numBlocks uint, temp, out []byte) {
 	}
 }
 
+// prfAES is the fixed key PRF encrypting x in place in the output, without
+// the temporary block
+func prfAES(x []byte, aesBlocks []cipher.Block, numBlocks uint, _, out []byte) {
+	x0, x1 := binary.LittleEndian.Uint64(x), binary.LittleEndian.Uint64(x[8:aes.BlockSize])
+	for i := uint(0); i < numBlocks; i++ {
+		b := out[i*aes.BlockSize : (i+1)*aes.BlockSize]
+		aesBlocks[i].Encrypt(b, x)
+		binary.LittleEndian.PutUint64(b, binary.LittleEndian.Uint64(b)^x0)
+		binary.LittleEndian.PutUint64(b[8:], binary.LittleEndian.Uint64(b[8:])^x1)
+	}
+}
+
+// SetField sets the field of the outputs of the point functions
+func (f *Fss) SetField(fl *field.Field) {
+	f.Field = fl
+	f.OutConvertBlock = make([]byte, convertBlockLength(fl, f.BlockLength))
+	f.eval = newEvalScratch(fl, f.BlockLength)
+}
+
+// convertBlockLength returns the number of bytes, rounded up to full AES
+// blocks, needed to convert a seed to blockLength elements of fl
+func convertBlockLength(fl *field.Field, blockLength int) int {
+	numBlocks := (blockLength*fl.ElementBytes() + aes.BlockSize - 1) / aes.BlockSize
+	return numBlocks * aes.BlockSize
+}
+
 func convertBlock(f Fss, x []byte, out []uint32) {
-	// we can generate four uint32 numbers with a 16-bytes AES block
-	prf(x, f.FixedBlocks, uint(len(out)/4), f.Temp, f.OutConvertBlock)
-	field.BytesToElements(out, f.OutConvertBlock)
+	// a 16-bytes AES block gives 16/ElementBytes elements, round up so that
+	// every element of out gets fresh randomness
+	numBlocks := (len(out)*f.Field.ElementBytes() + aes.BlockSize - 1) / aes.BlockSize
+	if numBlocks > len(f.FixedBlocks) {
+		// not enough fixed keys, fall back to counter mode
+		f.convertVector(x, 0, out)
+		return
+	}
+	prf(x, f.FixedBlocks, uint(numBlocks), f.Temp, f.OutConvertBlock)
+	f.Field.BytesToElements(out, f.OutConvertBlock)
+}
+
+func convertBlock64(f Fss, x []byte, out []uint64) {
+	// we can generate two uint64 numbers with a 16-bytes AES block
+	numBlocks := (len(out) + 1) / 2
+	buf := make([]byte, numBlocks*aes.BlockSize)
+	prf(x, f.FixedBlocks, uint(numBlocks), f.Temp, buf)
+	field.BytesToElements64(out, buf)
 }
@@ -0,0 +1,260 @@
+package fss
+
+// This file contains the 2-party distributed comparison function (DCF) with
+// outputs in F^m, following the construction of Boyle et al., "Function Secret
+// Sharing for Mixed-Mode and Fixed-Point Secure Computation", Eurocrypt 2021.
+// Using the output vector [1, alpha_1, ..., alpha_k] makes the comparison
+// results authenticated exactly as for the point functions.
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+
+	"github.com/si-co/vpir-code/lib/field"
+)
+
+// tweaks used to derive the left and right value vectors of the expansion
+const (
+	leftValueTweak  = 0x01
+	rightValueTweak = 0x02
+)
+
+// FssKeyLt2P is the key of a 2-party comparison function
+type FssKeyLt2P struct {
+	SInit   []byte
+	TInit   byte
+	CW      [][]byte   // seed and control-bits correction words, one per level
+	VCW     [][]uint32 // value correction words, one per level
+	FinalCW []uint32
+
+	// epoch of the PRF keys the key was generated with
+	Epoch uint64
+}
+
+// GenerateTreeLt generates the keys for a 2-party comparison function that
+// evaluates to vector b when input x < a, and to zero otherwise. Inputs are
+// compared as unsigned integers given most significant bit first.
+func (f Fss) GenerateTreeLt(a []bool, b []uint32) []FssKeyLt2P {
+	numBits := len(a)
+	bLen := len(b)
+
+	fssKeys := make([]FssKeyLt2P, 2)
+	fssKeys[0].SInit = make([]byte, aes.BlockSize)
+	fssKeys[1].SInit = make([]byte, aes.BlockSize)
+	rand.Read(fssKeys[0].SInit)
+	rand.Read(fssKeys[1].SInit)
+	fssKeys[0].TInit = 0
+	fssKeys[1].TInit = 1
+	fssKeys[0].Epoch = f.Epoch
+	fssKeys[1].Epoch = f.Epoch
+
+	// correction words are the same for both keys
+	cw := make([][]byte, numBits)
+	vcw := make([][]uint32, numBits)
+
+	sCurr0 := make([]byte, aes.BlockSize)
+	sCurr1 := make([]byte, aes.BlockSize)
+	copy(sCurr0, fssKeys[0].SInit)
+	copy(sCurr1, fssKeys[1].SInit)
+	tCurr0 := fssKeys[0].TInit
+	tCurr1 := fssKeys[1].TInit
+
+	vAlpha := make([]uint32, bLen)
+	v0 := make([][]uint32, 2)
+	v1 := make([][]uint32, 2)
+	for i := range v0 {
+		v0[i] = make([]uint32, bLen)
+		v1[i] = make([]uint32, bLen)
+	}
+
+	leftStart := 0
+	rightStart := aes.BlockSize + 1
+	for i := 0; i < numBits; i++ {
+		// "expand" seeds into two seeds + 2 bits + 2 value vectors
+		prf(sCurr0, f.FixedBlocks, 3, f.Temp, f.Out)
+		prfOut0 := make([]byte, aes.BlockSize*3)
+		copy(prfOut0, f.Out[:aes.BlockSize*3])
+		prf(sCurr1, f.FixedBlocks, 3, f.Temp, f.Out)
+		prfOut1 := make([]byte, aes.BlockSize*3)
+		copy(prfOut1, f.Out[:aes.BlockSize*3])
+		f.convertValues(sCurr0, v0[0], v0[1])
+		f.convertValues(sCurr1, v1[0], v1[1])
+
+		t0Left := prfOut0[aes.BlockSize] % 2
+		t0Right := prfOut0[(aes.BlockSize*2)+1] % 2
+		t1Left := prfOut1[aes.BlockSize] % 2
+		t1Right := prfOut1[(aes.BlockSize*2)+1] % 2
+
+		aBit := byte(0)
+		if a[i] {
+			aBit = byte(1)
+		}
+
+		// Figure out which half of expanded seeds to keep and lose
+		keep, lose := rightStart, leftStart
+		keepSide, loseSide := 1, 0
+		if aBit == 0 {
+			keep, lose = leftStart, rightStart
+			keepSide, loseSide = 0, 1
+		}
+
+		cw[i] = make([]byte, aes.BlockSize+2)
+		for j := 0; j < aes.BlockSize; j++ {
+			cw[i][j] = prfOut0[lose+j] ^ prfOut1[lose+j]
+		}
+		cw[i][aes.BlockSize] = t0Left ^ t1Left ^ aBit ^ 1
+		cw[i][aes.BlockSize+1] = t0Right ^ t1Right ^ aBit
+
+		// value correction word
+		vcw[i] = make([]uint32, bLen)
+		for j := range vcw[i] {
+			val := f.Field.Sub(f.Field.Sub(v1[loseSide][j], v0[loseSide][j]), vAlpha[j])
+			if loseSide == 0 {
+				// x < a when x takes the left branch and a the right one
+				val = f.Field.Add(val, b[j])
+			}
+			if tCurr1 == 1 {
+				val = f.Field.Neg(val)
+			}
+			vcw[i][j] = val
+
+			// update the value accumulated on the path of a
+			va := f.Field.Add(f.Field.Sub(vAlpha[j], v1[keepSide][j]), v0[keepSide][j])
+			if tCurr1 == 1 {
+				va = f.Field.Sub(va, val)
+			} else {
+				va = f.Field.Add(va, val)
+			}
+			vAlpha[j] = va
+		}
+
+		for j := 0; j < aes.BlockSize; j++ {
+			sCurr0[j] = prfOut0[keep+j] ^ (tCurr0 * cw[i][j])
+			sCurr1[j] = prfOut1[keep+j] ^ (tCurr1 * cw[i][j])
+		}
+
+		tCWKeep := cw[i][aes.BlockSize]
+		if keep == rightStart {
+			tCWKeep = cw[i][aes.BlockSize+1]
+		}
+		tCurr0 = (prfOut0[keep+aes.BlockSize] % 2) ^ tCWKeep*tCurr0
+		tCurr1 = (prfOut1[keep+aes.BlockSize] % 2) ^ tCWKeep*tCurr1
+	}
+
+	// final correction word
+	tmp0 := make([]uint32, bLen)
+	tmp1 := make([]uint32, bLen)
+	f.convertVector(sCurr0, 0, tmp0)
+	f.convertVector(sCurr1, 0, tmp1)
+	finalCW := make([]uint32, bLen)
+	for j := range finalCW {
+		val := f.Field.Sub(f.Field.Sub(tmp1[j], tmp0[j]), vAlpha[j])
+		if tCurr1 == 1 {
+			val = f.Field.Neg(val)
+		}
+		finalCW[j] = val
+	}
+
+	for i := range fssKeys {
+		fssKeys[i].CW = cw
+		fssKeys[i].VCW = vcw
+		fssKeys[i].FinalCW = finalCW
+	}
+
+	return fssKeys
+}
+
+// EvaluateLt evaluates the comparison function key on input x and stores the
+// share of the output in out
+func (f Fss) EvaluateLt(serverNum byte, k FssKeyLt2P, x []bool, out []uint32) {
+	sCurr := f.Seed
+	copy(sCurr, k.SInit)
+	tCurr := k.TInit
+
+	sc := f.scratch(len(out))
+	vLeft, vRight, sum := sc.left, sc.right, sc.sum
+	for j := range sum {
+		sum[j] = 0
+	}
+	for i := range x {
+		f.convertValues(sCurr, vLeft, vRight)
+		f.expand(sCurr, tCurr, k.CW[i])
+
+		v := vLeft
+		if x[i] {
+			v = vRight
+		}
+		for j := range sum {
+			val := f.Field.Add(v[j], uint32(tCurr)*k.VCW[i][j])
+			sum[j] = f.Field.Add(sum[j], val)
+		}
+
+		if !x[i] {
+			copy(sCurr, f.Out[:aes.BlockSize])
+			tCurr = f.Out[aes.BlockSize] % 2
+		} else {
+			copy(sCurr, f.Out[(aes.BlockSize+1):(aes.BlockSize*2+1)])
+			tCurr = f.Out[aes.BlockSize*2+1] % 2
+		}
+	}
+
+	f.convertVector(sCurr, 0, out)
+	for j := range out {
+		val := f.Field.Add(out[j], uint32(tCurr)*k.FinalCW[j])
+		val = f.Field.Add(sum[j], val)
+		if serverNum == 0 {
+			out[j] = val
+		} else {
+			out[j] = f.Field.Neg(val)
+		}
+	}
+}
+
+// evalScratch holds the vectors of the evaluations of the comparison keys,
+// allocated once for the block length with the field of the outputs
+type evalScratch struct {
+	left, right, sum []uint32
+	in, buf          []byte
+}
+
+func newEvalScratch(fl *field.Field, n int) *evalScratch {
+	return &evalScratch{
+		left:  make([]uint32, n),
+		right: make([]uint32, n),
+		sum:   make([]uint32, n),
+		in:    make([]byte, aes.BlockSize),
+		buf:   make([]byte, convertBlockLength(fl, n)),
+	}
+}
+
+// scratch returns the vectors for outputs of n elements, the preallocated
+// ones if n is the block length
+func (f Fss) scratch(n int) *evalScratch {
+	if f.eval != nil && n == f.BlockLength {
+		return f.eval
+	}
+	return newEvalScratch(f.Field, n)
+}
+
+// convertValues derives the left and right value vectors of the expansion of
+// seed s
+func (f Fss) convertValues(s []byte, left, right []uint32) {
+	f.convertVector(s, leftValueTweak, left)
+	f.convertVector(s, rightValueTweak, right)
+}
+
+// convertVector maps the seed s to a vector of field elements of arbitrary
+// length, using the fixed-key PRF in counter mode with the given tweak for
+// domain separation
+func (f Fss) convertVector(s []byte, tweak byte, out []uint32) {
+	numBlocks := (len(out)*f.Field.ElementBytes() + aes.BlockSize - 1) / aes.BlockSize
+	sc := f.scratch(len(out))
+	buf, in := sc.buf, sc.in
+	for i := 0; i < numBlocks; i++ {
+		copy(in, s)
+		in[aes.BlockSize-1] ^= tweak
+		in[aes.BlockSize-2] ^= byte(i)
+		prf(in, f.FixedBlocks, 1, f.Temp, buf[i*aes.BlockSize:])
+	}
+	f.Field.BytesToElements(out, buf)
+}
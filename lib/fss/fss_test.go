@@ -3,8 +3,13 @@ package fss
 // Source: https://github.com/frankw2/libfss/blob/master/go/test_fss/test_fss.go
 
 import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/gob"
+	"errors"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/si-co/vpir-code/lib/field"
 	"github.com/si-co/vpir-code/lib/utils"
@@ -129,3 +134,283 @@ func equalIndices(a, b []bool) bool {
 
 	return true
 }
+
+func TestDerivePrfKeys(t *testing.T) {
+	seed := []byte("a seed shared by clients and servers")
+
+	keys := DerivePrfKeys(seed, 1)
+	require.Len(t, keys, NumPrfKeys)
+	require.Equal(t, keys, DerivePrfKeys(seed, 1))
+	require.NotEqual(t, keys, DerivePrfKeys(seed, 2))
+	require.NotEqual(t, keys, DerivePrfKeys([]byte("another seed"), 1))
+
+	// keys derived from the seed must still yield a correct point function
+	defaultKeys, defaultEpoch := PrfKeys, prfEpoch
+	defer func() { PrfKeys, prfEpoch = defaultKeys, defaultEpoch }()
+	SetPrfKeysFromSeed(seed, 1)
+
+	fClient := ClientInitialize(testBlockLength)
+	fServer := ServerInitialize(testBlockLength)
+	index := randomIndex(numBits)
+	b := field.RandVector(testBlockLength)
+	fssKeys := fClient.GenerateTreePF(index, b)
+	require.Equal(t, uint64(1), fssKeys[0].Epoch)
+
+	// without rotation, only the epoch of the keys is accepted
+	_, err := fServer.ForEpoch(2)
+	require.True(t, errors.Is(err, ErrEpoch))
+	e, err := fServer.ForEpoch(1)
+	require.NoError(t, err)
+	require.Equal(t, fServer.FixedBlocks, e.FixedBlocks)
+
+	out0 := make([]uint32, testBlockLength)
+	out1 := make([]uint32, testBlockLength)
+	fServer.EvaluatePF(0, fssKeys[0], index, out0)
+	fServer.EvaluatePF(1, fssKeys[1], index, out1)
+	for i := range out0 {
+		out0[i] = (out0[i] + out1[i]) % field.ModP
+	}
+	require.Equal(t, b, out0)
+}
+
+func TestRotate(t *testing.T) {
+	defaultKeys, defaultEpoch := PrfKeys, prfEpoch
+	defer func() {
+		PrfKeys, prfEpoch, now = defaultKeys, defaultEpoch, time.Now
+		rotation.schedule.Store((*schedule)(nil))
+	}()
+	start := time.Unix(0, 0).Add(100*time.Hour + 30*time.Minute)
+	now = func() time.Time { return start }
+	setRotation([]byte("a seed shared by clients and servers"), time.Hour)
+
+	fClient := ClientInitialize(testBlockLength)
+	fServer := ServerInitialize(testBlockLength)
+	blocks := append([]cipher.Block(nil), fServer.FixedBlocks...)
+	index := randomIndex(numBits)
+	b := field.RandVector(testBlockLength)
+	out0 := make([]uint32, testBlockLength)
+	out1 := make([]uint32, testBlockLength)
+	evaluate := func(f Fss, fssKeys []FssKeyEq2P) []uint32 {
+		f.EvaluatePF(0, fssKeys[0], index, out0)
+		f.EvaluatePF(1, fssKeys[1], index, out1)
+		for i := range out0 {
+			out0[i] = (out0[i] + out1[i]) % field.ModP
+		}
+		return out0
+	}
+
+	g := fClient.Current()
+	sent := g.GenerateTreePF(index, b)
+	require.Equal(t, uint64(100), sent[0].Epoch)
+
+	// the query is answered after the epoch advanced, with the keys of the
+	// previous epoch
+	now = func() time.Time { return start.Add(time.Hour) }
+	e, err := fServer.ForEpoch(sent[0].Epoch)
+	require.NoError(t, err)
+	require.Equal(t, b, evaluate(e, sent))
+	// the keys of the current epoch give a wrong answer
+	e, err = fServer.ForEpoch(101)
+	require.NoError(t, err)
+	require.NotEqual(t, b, evaluate(e, sent))
+
+	g = fClient.Current()
+	fssKeys := g.GenerateTreePF(index, b)
+	require.Equal(t, uint64(101), fssKeys[0].Epoch)
+	e, err = fServer.ForEpoch(fssKeys[0].Epoch)
+	require.NoError(t, err)
+	require.Equal(t, b, evaluate(e, fssKeys))
+
+	// the epochs before the previous one and the future ones are rejected
+	now = func() time.Time { return start.Add(3 * time.Hour) }
+	for _, epoch := range []uint64{100, 101, 104} {
+		_, err = fServer.ForEpoch(epoch)
+		require.True(t, errors.Is(err, ErrEpoch), epoch)
+	}
+	e, err = fServer.ForEpoch(102)
+	require.NoError(t, err)
+	require.Equal(t, uint64(102), e.Epoch)
+
+	// the evaluator of the server is never modified
+	require.Equal(t, blocks, fServer.FixedBlocks)
+	require.Equal(t, uint64(100), fServer.Epoch)
+}
+
+func TestFullDomainBits(t *testing.T) {
+	fClient := ClientInitialize(1)
+	fServer := ServerInitialize(1)
+
+	for _, n := range []int{1, 2, 13, 64, 100} {
+		numBits := NumBitsForDomain(n)
+		for a := 0; a < n; a++ {
+			fssKeys := fClient.GenerateTreeBit(IndexToBits(a, numBits))
+
+			out0 := make([]byte, n/8+1)
+			out1 := make([]byte, n/8+1)
+			fServer.EvaluateFullDomainBits(fssKeys[0], numBits, n, out0)
+			fServer.EvaluateFullDomainBits(fssKeys[1], numBits, n, out1)
+
+			for x := 0; x < n; x++ {
+				bit := ((out0[x/8] ^ out1[x/8]) >> (x % 8)) & 1
+				// full-domain evaluation must match the point evaluation
+				xBits := IndexToBits(x, numBits)
+				require.Equal(t, fServer.EvaluateBit(fssKeys[0], xBits), (out0[x/8]>>(x%8))&1)
+				if x == a {
+					require.Equal(t, byte(1), bit)
+				} else {
+					require.Equal(t, byte(0), bit)
+				}
+			}
+		}
+	}
+}
+
+func TestComparison(t *testing.T) {
+	const bits = 16
+	fClient := ClientInitialize(1 + field.ConcurrentExecutions)
+	fServer := ServerInitialize(1 + field.ConcurrentExecutions)
+
+	// authenticated output [1, alpha_1, ..., alpha_k]
+	b := make([]uint32, 1+field.ConcurrentExecutions)
+	b[0] = 1
+	for i := 1; i < len(b); i++ {
+		b[i] = field.RandElement()
+	}
+	zeros := make([]uint32, len(b))
+
+	for _, a := range []int{0, 1, 255, 256, 1234, 1<<bits - 1} {
+		fssKeys := fClient.GenerateTreeLt(IndexToBits(a, bits), b)
+		for j := 0; j < 500; j++ {
+			x := rand.Intn(1 << bits)
+			if j < 3 {
+				// always test the boundaries
+				x = a - 1 + j
+				if x < 0 || x >= 1<<bits {
+					continue
+				}
+			}
+			out0 := make([]uint32, len(b))
+			out1 := make([]uint32, len(b))
+			fServer.EvaluateLt(0, fssKeys[0], IndexToBits(x, bits), out0)
+			fServer.EvaluateLt(1, fssKeys[1], IndexToBits(x, bits), out1)
+			for i := range out0 {
+				out0[i] = (out0[i] + out1[i]) % field.ModP
+			}
+			if x < a {
+				require.Equal(t, b, out0, "a=%d, x=%d", a, x)
+			} else {
+				require.Equal(t, zeros, out0, "a=%d, x=%d", a, x)
+			}
+		}
+	}
+}
+
+func TestPRFKernels(t *testing.T) {
+	f := ClientInitialize(testBlockLength)
+	x := make([]byte, 16)
+	outAES := make([]byte, len(f.Out))
+	outGeneric := make([]byte, len(f.Out))
+	for i := 0; i < 100; i++ {
+		rand.Read(x)
+		n := uint(1 + i%len(f.FixedBlocks))
+		prfAES(x, f.FixedBlocks, n, f.Temp, outAES)
+		prfGeneric(x, f.FixedBlocks, n, f.Temp, outGeneric)
+		require.Equal(t, outGeneric[:n*16], outAES[:n*16])
+	}
+}
+
+func BenchmarkPRF(b *testing.B) {
+	f := ClientInitialize(testBlockLength)
+	x := make([]byte, 16)
+	for name, kernel := range map[string]func([]byte, []cipher.Block, uint, []byte, []byte){
+		"AES":     prfAES,
+		"Generic": prfGeneric,
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				kernel(x, f.FixedBlocks, 3, f.Temp, f.Out)
+			}
+		})
+	}
+}
+
+func TestKeyCodec(t *testing.T) {
+	f := ClientInitialize(testBlockLength)
+	f.Epoch = 1 << 40
+	b := make([]uint32, testBlockLength)
+	for i := range b {
+		b[i] = field.RandElement()
+	}
+	// the 64-bit keys only support short blocks
+	b64 := []uint64{1, rand.Uint64(), rand.Uint64()}
+	a := randomIndex(numBits)
+	keys := append(f.GenerateTreePF(a, b), f.GenerateTreePF64(a, b64)...)
+	keys = append(keys, f.GenerateTreeBit(a)...)
+
+	for i := range keys {
+		encoded := EncodeKey(&keys[i])
+		decoded, err := DecodeKey(encoded)
+		require.NoError(t, err)
+		require.Equal(t, keys[i], decoded)
+		require.Equal(t, encoded, AppendKey(nil, &decoded))
+
+		for n := 0; n < len(encoded); n++ {
+			_, err := DecodeKey(encoded[:n])
+			require.True(t, errors.Is(err, ErrMalformedKey), n)
+		}
+		_, err = DecodeKey(append(encoded, 0))
+		require.True(t, errors.Is(err, ErrMalformedKey))
+	}
+
+	decoded, err := DecodeKeys(EncodeKeys(keys))
+	require.NoError(t, err)
+	require.Equal(t, keys, decoded)
+	decoded, err = DecodeKeys(EncodeKeys(nil))
+	require.NoError(t, err)
+	require.Empty(t, decoded)
+}
+
+// BenchmarkKeyCodec compares the fixed layout of the keys to gob, which was
+// used before, and reports the length of the encoding of a key
+func BenchmarkKeyCodec(b *testing.B) {
+	f := ClientInitialize(testBlockLength)
+	key := f.GenerateTreePF(randomIndex(numBits), make([]uint32, testBlockLength))[0]
+
+	gobEncode := func() []byte {
+		buf := new(bytes.Buffer)
+		if err := gob.NewEncoder(buf).Encode(key); err != nil {
+			b.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	gobEncoded := gobEncode()
+	encoded := EncodeKey(&key)
+
+	b.Run("EncodeGob", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			gobEncode()
+		}
+		b.ReportMetric(float64(len(gobEncoded)), "B/msg")
+	})
+	b.Run("EncodeBinary", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			EncodeKey(&key)
+		}
+		b.ReportMetric(float64(len(encoded)), "B/msg")
+	})
+	b.Run("DecodeGob", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var k FssKeyEq2P
+			if err := gob.NewDecoder(bytes.NewReader(gobEncoded)).Decode(&k); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("DecodeBinary", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := DecodeKey(encoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package fss
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/utils"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/xerrors"
+)
+
+// NumPrfKeys is the number of fixed AES keys used by the PRF
+const NumPrfKeys = 4
+
+const (
+	epochKeyDomain = "vpir-fss-epoch"
+	prfKeyDomain   = "vpir-fss-prf"
+)
+
+// ErrEpoch is returned when a key was generated with the PRF keys of an
+// epoch other than the ones of the evaluator
+var ErrEpoch = errors.New("FSS key of an unknown or expired epoch")
+
+// prfEpoch is the epoch of PrfKeys
+var prfEpoch uint64
+
+// schedule is the rotation of the PRF keys derived from seed every period,
+// with the fixed blocks of the current epoch and of the previous one. A
+// schedule is never modified once published, so that it is read without
+// locking while queries are answered.
+type schedule struct {
+	seed   []byte
+	period time.Duration
+	epoch  uint64
+	// blocks of the current epoch and of the previous one
+	blocks [2][]cipher.Block
+}
+
+// rotation holds the *schedule set by SetPrfKeysFromConfig when a rotation
+// period is configured, replaced when the epoch advances
+var rotation struct {
+	sync.Mutex
+	schedule atomic.Value
+}
+
+// now is the clock of the rotation, replaced in the tests
+var now = time.Now
+
+// DerivePrfKeys deterministically derives the fixed PRF keys from a seed
+// shared between the client and the servers and an epoch number. The
+// derivation is hierarchical: an epoch key is first derived from the seed,
+// and every PRF key is then derived from the epoch key and its index. This
+// way the servers are configured once with the seed and the keys are rotated
+// by increasing the epoch, without any per-client registration.
+func DerivePrfKeys(seed []byte, epoch uint64) [][]byte {
+	// BLAKE2b keys are at most 64 bytes long
+	if len(seed) > blake2b.Size {
+		h := blake2b.Sum512(seed)
+		seed = h[:]
+	}
+	epochKey := deriveKey(seed, epochKeyDomain, epoch, blake2b.Size256)
+
+	keys := make([][]byte, NumPrfKeys)
+	for i := range keys {
+		keys[i] = deriveKey(epochKey, prfKeyDomain, uint64(i), aes.BlockSize)
+	}
+
+	return keys
+}
+
+// SetPrfKeysFromSeed replaces the fixed PRF keys used by both clients and
+// servers with the ones derived from the given seed and epoch.
+func SetPrfKeysFromSeed(seed []byte, epoch uint64) {
+	PrfKeys = DerivePrfKeys(seed, epoch)
+	prfEpoch = epoch
+}
+
+// SetPrfKeysFromConfig derives the fixed PRF keys from the seed in the
+// configuration, if any. When a rotation period is configured, the keys are
+// derived for the current epoch, as given by the clock, every time it
+// advances, otherwise the configured epoch is used.
+func SetPrfKeysFromConfig(c *utils.Config) error {
+	if c.FssSeed == "" {
+		return nil
+	}
+	seed, err := hex.DecodeString(c.FssSeed)
+	if err != nil {
+		return xerrors.Errorf("could not decode FSS seed: %v", err)
+	}
+
+	epoch := c.FssEpoch
+	if c.FssRotation != "" {
+		period, err := time.ParseDuration(c.FssRotation)
+		if err != nil {
+			return xerrors.Errorf("could not parse FSS rotation period: %v", err)
+		}
+		setRotation(seed, period)
+		return nil
+	}
+	SetPrfKeysFromSeed(seed, epoch)
+
+	return nil
+}
+
+// setRotation rotates the PRF keys derived from the seed every period, and
+// sets the keys of the current epoch as the fixed ones
+func setRotation(seed []byte, period time.Duration) {
+	rotation.Lock()
+	defer rotation.Unlock()
+	epoch := Epoch(now(), period)
+	SetPrfKeysFromSeed(seed, epoch)
+	sch := &schedule{seed: seed, period: period, epoch: epoch}
+	sch.blocks[0] = newFixedBlocks(PrfKeys)
+	sch.blocks[1] = newFixedBlocks(DerivePrfKeys(seed, epoch-1))
+	rotation.schedule.Store(sch)
+}
+
+// currentSchedule returns the schedule of the current epoch, or nil if the
+// keys are not rotated
+func currentSchedule() *schedule {
+	sch, _ := rotation.schedule.Load().(*schedule)
+	if sch == nil || sch.period <= 0 {
+		return nil
+	}
+	if epoch := Epoch(now(), sch.period); epoch > sch.epoch {
+		return advance(epoch)
+	}
+	return sch
+}
+
+// advance publishes the schedule of the given epoch, keeping the blocks of
+// the current one as the previous ones when they follow each other
+func advance(epoch uint64) *schedule {
+	rotation.Lock()
+	defer rotation.Unlock()
+	old := rotation.schedule.Load().(*schedule)
+	if epoch <= old.epoch {
+		return old
+	}
+	sch := &schedule{seed: old.seed, period: old.period, epoch: epoch}
+	sch.blocks[0] = newFixedBlocks(DerivePrfKeys(old.seed, epoch))
+	if epoch == old.epoch+1 {
+		sch.blocks[1] = old.blocks[0]
+	} else {
+		sch.blocks[1] = newFixedBlocks(DerivePrfKeys(old.seed, epoch-1))
+	}
+	rotation.schedule.Store(sch)
+	return sch
+}
+
+// Current returns a copy of f using the PRF keys of the current epoch, with
+// which the clients generate their keys. When the keys are not rotated, it is
+// f itself.
+func (f *Fss) Current() Fss {
+	g := *f
+	if sch := currentSchedule(); sch != nil {
+		g.FixedBlocks, g.Epoch = sch.blocks[0], sch.epoch
+	}
+	return g
+}
+
+// ForEpoch returns a copy of f using the PRF keys of the given epoch, with
+// which the servers evaluate the keys generated in that epoch. When the keys
+// are rotated, only the current epoch and the previous one are accepted, so
+// that the queries sent just before the epoch advances are still answered.
+// Otherwise, only the epoch of the keys of f is accepted. The fixed blocks are
+// shared and never modified, so that the keys of different epochs can be
+// evaluated concurrently.
+func (f *Fss) ForEpoch(epoch uint64) (Fss, error) {
+	sch := currentSchedule()
+	if sch == nil {
+		if epoch != f.Epoch {
+			return Fss{}, ErrEpoch
+		}
+		return *f, nil
+	}
+	g := *f
+	switch {
+	case epoch == sch.epoch:
+		g.FixedBlocks = sch.blocks[0]
+	case epoch+1 == sch.epoch:
+		g.FixedBlocks = sch.blocks[1]
+	default:
+		return Fss{}, ErrEpoch
+	}
+	g.Epoch = epoch
+	return g, nil
+}
+
+// newFixedBlocks returns the AES blocks of the PRF keys
+func newFixedBlocks(keys [][]byte) []cipher.Block {
+	blocks := make([]cipher.Block, len(keys))
+	for i := range keys {
+		block, err := aes.NewCipher(keys[i])
+		if err != nil {
+			panic(err.Error())
+		}
+		blocks[i] = block
+	}
+	return blocks
+}
+
+// Epoch returns the epoch number corresponding to time t when the keys are
+// rotated every period. A non-positive period disables the rotation.
+func Epoch(t time.Time, period time.Duration) uint64 {
+	if period <= 0 {
+		return 0
+	}
+	return uint64(t.UnixNano() / int64(period))
+}
+
+// deriveKey returns a size-byte key obtained as keyed BLAKE2b of the domain
+// separation string and the index
+func deriveKey(key []byte, domain string, index uint64, size int) []byte {
+	h, err := blake2b.New(size, key)
+	if err != nil {
+		panic(err)
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], index)
+	h.Write([]byte(domain))
+	h.Write(buf[:])
+
+	return h.Sum(nil)
+}
@@ -23,22 +23,56 @@ func ServerInitialize(blockLength int) *Fss {
 		}
 		f.FixedBlocks[i] = block
 	}
+	f.Epoch = prfEpoch
 	f.N = 256 // maximum number of bits supported by FSS
 	f.Temp = make([]byte, aes.BlockSize)
 	f.Out = make([]byte, aes.BlockSize*len(PrfKeys))
-	f.OutConvertBlock = make([]byte, blockLength*field.Bytes)
+	f.Seed = make([]byte, aes.BlockSize)
+	f.BlockLength = blockLength
+	f.SetField(field.Default())
 
 	return f
 }
 
 func (f Fss) EvaluatePF(serverNum byte, k FssKeyEq2P, x []bool, out []uint32) {
+	sCurr, tCurr := f.evaluateTree(k, x)
+
+	// convert block
+	convertBlock(f, sCurr, out)
+	for i := range out {
+		// tCurr is either 0 or 1, no need to mod
+		out[i] = f.Field.Add(out[i], uint32(tCurr)*k.FinalCW[i])
+		if serverNum != 0 {
+			out[i] = f.Field.Neg(out[i])
+		}
+	}
+}
+
+// EvaluatePF64 evaluates the key on input x and stores the share of the
+// output, in the 64-bit field, in out
+func (f Fss) EvaluatePF64(serverNum byte, k FssKeyEq2P, x []bool, out []uint64) {
+	sCurr, tCurr := f.evaluateTree(k, x)
+
+	convertBlock64(f, sCurr, out)
+	for i := range out {
+		if tCurr == 1 {
+			out[i] = field.Add64(out[i], k.FinalCW64[i])
+		}
+		if serverNum != 0 {
+			out[i] = field.Neg64(out[i])
+		}
+	}
+}
+
+// evaluateTree follows the path of x in the evaluation tree of the key and
+// returns the final seed, stored in f.Seed, and control bit
+func (f Fss) evaluateTree(k FssKeyEq2P, x []bool) ([]byte, byte) {
 	// reinitialize f.NumBits because we have different input lengths
 	f.NumBits = uint(len(x))
 
-	sCurr := make([]byte, aes.BlockSize)
+	sCurr := f.Seed
 	copy(sCurr, k.SInit)
 	tCurr := k.TInit
-	tmp := make([]uint32, len(out))
 	for i := uint(0); i < f.NumBits; i++ {
 		var xBit byte = 0
 		if i != f.N {
@@ -48,20 +82,7 @@ func (f Fss) EvaluatePF(serverNum byte, k FssKeyEq2P, x []bool, out []uint32) {
 			}
 		}
 
-		prf(sCurr, f.FixedBlocks, 3, f.Temp, f.Out)
-
-		// Keep counter to ensure we are accessing CW correctly
-		count := 0
-		for j := 0; j < aes.BlockSize*2+2; j++ {
-			// Make sure we are doing G(s) ^ (t*sCW||tLCW||sCW||tRCW)
-			if j == aes.BlockSize+1 {
-				count = 0
-			} else if j == aes.BlockSize*2+1 {
-				count = aes.BlockSize + 1
-			}
-			f.Out[j] = f.Out[j] ^ (tCurr * k.CW[i][count])
-			count++
-		}
+		f.expand(sCurr, tCurr, k.CW[i])
 
 		// Pick right seed expansion based on
 		if xBit == 0 {
@@ -73,14 +94,24 @@ func (f Fss) EvaluatePF(serverNum byte, k FssKeyEq2P, x []bool, out []uint32) {
 		}
 	}
 
-	// convert block
-	convertBlock(f, sCurr, tmp)
-	for i := range out {
-		if serverNum == 0 {
-			// tCurr is either 0 or 1, no need to mod
-			out[i] = (tmp[i] + uint32(tCurr)*k.FinalCW[i]) % field.ModP
-		} else {
-			out[i] = field.ModP - ((tmp[i] + uint32(tCurr)*k.FinalCW[i]) % field.ModP)
+	return sCurr, tCurr
+}
+
+// expand expands the seed s into two seeds and two control bits, stored in
+// f.Out, and applies the correction word cw if the control bit t is set
+func (f Fss) expand(s []byte, t byte, cw []byte) {
+	prf(s, f.FixedBlocks, 3, f.Temp, f.Out)
+
+	// Keep counter to ensure we are accessing CW correctly
+	count := 0
+	for j := 0; j < aes.BlockSize*2+2; j++ {
+		// Make sure we are doing G(s) ^ (t*sCW||tLCW||sCW||tRCW)
+		if j == aes.BlockSize+1 {
+			count = 0
+		} else if j == aes.BlockSize*2+1 {
+			count = aes.BlockSize + 1
 		}
+		f.Out[j] = f.Out[j] ^ (t * cw[count])
+		count++
 	}
 }
@@ -0,0 +1,142 @@
+// Package idempotency identifies the queries sent to the servers, so that a
+// query retried after a failure, e.g., a timeout, is answered once. The ID of
+// a query is a hash of its encoding, computed alike by the client and by the
+// server without any fresh randomness, so that their logs can be correlated.
+// It reveals nothing about the query beyond its encoding, already
+// pseudorandom to the server. The idempotency key is a random value chosen
+// by the client for a query to a server and kept across its retries, with
+// which the server returns the answer it already computed instead of
+// answering again, e.g., with a fresh noise or after a nonce already seen.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// KeyLen is the length in bytes of an idempotency key
+const KeyLen = 16
+
+// MetadataKey is the gRPC metadata key of the idempotency key of a query.
+// The suffix lets the value be binary.
+const MetadataKey = "vpir-idempotency-bin"
+
+// idLen is the length in bytes of the hash in the ID of a query
+const idLen = 8
+
+var idDST = []byte("vpir-query-id")
+
+// ErrKeyReused is returned when an idempotency key is reused for another
+// query
+var ErrKeyReused = errors.New("idempotency key reused for another query")
+
+// QueryID returns the ID of the encoded query, as the hex encoding of a
+// truncated hash
+func QueryID(query []byte) string {
+	h := sha256.New()
+	h.Write(idDST)
+	h.Write(query)
+	return hex.EncodeToString(h.Sum(nil)[:idLen])
+}
+
+// NewKey returns a fresh idempotency key
+func NewKey(rnd io.Reader) ([]byte, error) {
+	key := make([]byte, KeyLen)
+	if _, err := io.ReadFull(rnd, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Cache keeps the answers to the queries with an idempotency key, at most
+// size of them and for ttl at most, the oldest being evicted first
+type Cache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	// entries in the order of their queries
+	order []*entry
+}
+
+type entry struct {
+	key string
+	id  string
+	at  time.Time
+
+	// closed once the answer is computed
+	done   chan struct{}
+	answer []byte
+	err    error
+}
+
+// NewCache returns an empty cache of the given size and time to live
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{size: size, ttl: ttl, entries: make(map[string]*entry)}
+}
+
+// Do returns the answer to the query of the given ID, computed by answer for
+// the first query with the key and replayed for its retries, which wait for
+// the answer if it is being computed. It reports whether the answer is
+// replayed, and returns ErrKeyReused if the key is one of another query. A
+// failed answer is not kept, so that a retry computes it again.
+func (c *Cache) Do(key []byte, id string, answer func() ([]byte, error)) ([]byte, bool, error) {
+	c.mu.Lock()
+	c.expire(time.Now())
+	if e, ok := c.entries[string(key)]; ok {
+		c.mu.Unlock()
+		if e.id != id {
+			return nil, false, ErrKeyReused
+		}
+		<-e.done
+		return e.answer, e.err == nil, e.err
+	}
+	e := &entry{key: string(key), id: id, at: time.Now(), done: make(chan struct{})}
+	c.entries[e.key] = e
+	c.order = append(c.order, e)
+	for len(c.entries) > c.size {
+		c.pop()
+	}
+	c.mu.Unlock()
+
+	e.answer, e.err = answer()
+	close(e.done)
+	if e.err != nil {
+		c.mu.Lock()
+		if c.entries[e.key] == e {
+			delete(c.entries, e.key)
+		}
+		c.mu.Unlock()
+	}
+	return e.answer, false, e.err
+}
+
+// Len returns the number of answers in the cache
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire(time.Now())
+	return len(c.entries)
+}
+
+// expire evicts the entries older than the time to live
+func (c *Cache) expire(now time.Time) {
+	for len(c.order) > 0 && now.Sub(c.order[0].at) > c.ttl {
+		c.pop()
+	}
+}
+
+// pop evicts the oldest entry, unless it was already removed after failing
+func (c *Cache) pop() {
+	e := c.order[0]
+	c.order[0] = nil
+	c.order = c.order[1:]
+	if c.entries[e.key] == e {
+		delete(c.entries, e.key)
+	}
+}
@@ -0,0 +1,112 @@
+package idempotency
+
+import (
+	"crypto/rand"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryID(t *testing.T) {
+	id := QueryID([]byte("query"))
+	require.Len(t, id, 2*idLen)
+	require.Equal(t, id, QueryID([]byte("query")))
+	require.NotEqual(t, id, QueryID([]byte("querz")))
+
+	key, err := NewKey(rand.Reader)
+	require.NoError(t, err)
+	require.Len(t, key, KeyLen)
+}
+
+func TestCacheReplay(t *testing.T) {
+	c := NewCache(2, time.Hour)
+	calls := 0
+	answer := func() ([]byte, error) {
+		calls++
+		return []byte{byte(calls)}, nil
+	}
+
+	a, replayed, err := c.Do([]byte("k1"), "q1", answer)
+	require.NoError(t, err)
+	require.False(t, replayed)
+	a2, replayed, err := c.Do([]byte("k1"), "q1", answer)
+	require.NoError(t, err)
+	require.True(t, replayed)
+	require.Equal(t, a, a2)
+	require.Equal(t, 1, calls)
+
+	// the key of another query
+	_, _, err = c.Do([]byte("k1"), "q2", answer)
+	require.ErrorIs(t, err, ErrKeyReused)
+
+	// the oldest answer is evicted
+	_, _, err = c.Do([]byte("k2"), "q2", answer)
+	require.NoError(t, err)
+	_, _, err = c.Do([]byte("k3"), "q3", answer)
+	require.NoError(t, err)
+	require.Equal(t, 2, c.Len())
+	_, replayed, err = c.Do([]byte("k1"), "q1", answer)
+	require.NoError(t, err)
+	require.False(t, replayed)
+	require.Equal(t, 4, calls)
+}
+
+func TestCacheFailure(t *testing.T) {
+	c := NewCache(10, time.Hour)
+	_, _, err := c.Do([]byte("k"), "q", func() ([]byte, error) {
+		return nil, errors.New("failed")
+	})
+	require.Error(t, err)
+	require.Equal(t, 0, c.Len())
+
+	// a retry after a failure answers again
+	a, replayed, err := c.Do([]byte("k"), "q", func() ([]byte, error) {
+		return []byte{1}, nil
+	})
+	require.NoError(t, err)
+	require.False(t, replayed)
+	require.Equal(t, []byte{1}, a)
+}
+
+func TestCacheConcurrentRetries(t *testing.T) {
+	c := NewCache(10, time.Hour)
+	release := make(chan struct{})
+	calls := 0
+	answer := func() ([]byte, error) {
+		<-release
+		calls++
+		return []byte{42}, nil
+	}
+
+	var wg sync.WaitGroup
+	answers := make([][]byte, 4)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	for i := range answers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a, _, err := c.Do([]byte("k"), "q", answer)
+			require.NoError(t, err)
+			answers[i] = a
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, 1, calls)
+	for _, a := range answers {
+		require.Equal(t, []byte{42}, a)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := NewCache(10, time.Millisecond)
+	_, _, err := c.Do([]byte("k"), "q", func() ([]byte, error) { return []byte{1}, nil })
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	require.Equal(t, 0, c.Len())
+}
@@ -0,0 +1,226 @@
+// Package keys implements the providers of the private keys of the servers,
+// e.g., of their TLS certificates, so that the keys need not be stored in
+// plaintext on the disk of the servers. A key is referenced by a string: the
+// path of a PEM file, plaintext or encrypted with a passphrase as PKCS#8
+// (e.g., by `openssl pkcs8 -topk8 -v2 aes-256-cbc`), or the URI of a key of
+// another provider, whose scheme is registered with RegisterScheme. The keys
+// are only used through crypto.Signer, so that the ones of a hardware token
+// would never leave it. The opener of the PKCS#11 tokens is deferred: only
+// their URIs are parsed, by ParsePKCS11URI, and no build registers the
+// scheme pkcs11, whose keys are rejected with ErrUnsupported.
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// PassphraseEnvKey is the environment variable of the passphrase of the
+// encrypted key files
+const PassphraseEnvKey = "VPIR_KEY_PASSPHRASE"
+
+var (
+	// ErrPermissions is returned when a key file is accessible by other
+	// users than its owner
+	ErrPermissions = errors.New("key file accessible by other users")
+	// ErrPassphrase is returned when an encrypted key cannot be decrypted
+	// with the passphrase
+	ErrPassphrase = errors.New("wrong passphrase or corrupted key")
+	// ErrUnsupported is returned for the keys of an unknown provider or in
+	// an unknown format
+	ErrUnsupported = errors.New("unsupported key")
+)
+
+// Provider gives access to a private key
+type Provider interface {
+	// Signer returns the signer of the key
+	Signer() crypto.Signer
+	// Close releases the resources of the provider, e.g., the session with a
+	// token
+	Close() error
+}
+
+// Opener opens the provider of the key referenced by a URI
+type Opener func(uri string) (Provider, error)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = make(map[string]Opener)
+)
+
+// RegisterScheme registers the opener of the keys referenced by the URIs of
+// the scheme, e.g., pkcs11 for the URIs pkcs11:token=...;object=... of RFC
+// 7512
+func RegisterScheme(scheme string, open Opener) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[scheme] = open
+}
+
+// Open returns the provider of the key referenced by ref, a URI of a
+// registered scheme or the path of a PEM file, decrypted with the passphrase
+// of PassphraseEnvKey if encrypted
+func Open(ref string) (Provider, error) {
+	if i := strings.Index(ref, ":"); i > 1 {
+		scheme := ref[:i]
+		if scheme == "file" {
+			return OpenFile(strings.TrimPrefix(ref[i+1:], "//"), envPassphrase)
+		}
+		schemesMu.RLock()
+		open, ok := schemes[scheme]
+		schemesMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w: no provider of the %s keys in this build", ErrUnsupported, scheme)
+		}
+		return open(ref)
+	}
+	return OpenFile(ref, envPassphrase)
+}
+
+func envPassphrase() ([]byte, error) {
+	p, ok := os.LookupEnv(PassphraseEnvKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: the key is encrypted and %s is not set", ErrPassphrase, PassphraseEnvKey)
+	}
+	return []byte(p), nil
+}
+
+// fileProvider is the provider of a key loaded from a file
+type fileProvider struct {
+	signer crypto.Signer
+}
+
+func (p *fileProvider) Signer() crypto.Signer { return p.signer }
+
+func (p *fileProvider) Close() error { return nil }
+
+// OpenFile returns the provider of the key of the PEM file, which must not be
+// accessible by other users than its owner. The passphrase is requested only
+// if the key is encrypted.
+func OpenFile(path string, passphrase func() ([]byte, error)) (Provider, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// the permissions are not meaningful on windows
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPermissions, path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ParsePEM(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &fileProvider{signer: signer}, nil
+}
+
+// ParsePEM returns the signer of the first private key of the PEM data, in
+// PKCS#8, PKCS#1 or SEC 1 format, or encrypted PKCS#8 decrypted with the
+// passphrase
+func ParsePEM(data []byte, passphrase func() ([]byte, error)) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%w: no private key in the PEM data", ErrUnsupported)
+		}
+		der := block.Bytes
+		switch block.Type {
+		case "ENCRYPTED PRIVATE KEY":
+			p, err := passphrase()
+			if err != nil {
+				return nil, err
+			}
+			if der, err = decryptPKCS8(der, p); err != nil {
+				return nil, err
+			}
+			fallthrough
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(der)
+			if err != nil {
+				return nil, err
+			}
+			return asSigner(key)
+		case "RSA PRIVATE KEY":
+			if x509.IsEncryptedPEMBlock(block) {
+				return nil, fmt.Errorf("%w: legacy encrypted PEM, convert it to encrypted PKCS#8", ErrUnsupported)
+			}
+			return x509.ParsePKCS1PrivateKey(der)
+		case "EC PRIVATE KEY":
+			if x509.IsEncryptedPEMBlock(block) {
+				return nil, fmt.Errorf("%w: legacy encrypted PEM, convert it to encrypted PKCS#8", ErrUnsupported)
+			}
+			return x509.ParseECPrivateKey(der)
+		}
+	}
+}
+
+func asSigner(key interface{}) (crypto.Signer, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("%w: private key of type %T", ErrUnsupported, key)
+	}
+}
+
+// LoadX509KeyPair returns the TLS certificate of the PEM file of the
+// certificate chain with the key referenced by keyRef, which must match the
+// public key of the certificate
+func LoadX509KeyPair(certFile, keyRef string) (tls.Certificate, error) {
+	data, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	var cert tls.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+	if len(cert.Certificate) == 0 {
+		return tls.Certificate{}, fmt.Errorf("no certificate in %s", certFile)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	p, err := Open(keyRef)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	signer := p.Signer()
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(leaf.PublicKey) {
+		p.Close()
+		return tls.Certificate{}, fmt.Errorf("the key of %s does not match the certificate", keyRef)
+	}
+	cert.PrivateKey = signer
+	cert.Leaf = leaf
+	return cert, nil
+}
@@ -0,0 +1,162 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func writeFile(t *testing.T, name string, data []byte, perm os.FileMode) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, perm))
+	require.NoError(t, os.Chmod(path, perm))
+	return path
+}
+
+func passphrase(p string) func() ([]byte, error) {
+	return func() ([]byte, error) { return []byte(p), nil }
+}
+
+func TestEncryptedFile(t *testing.T) {
+	key := newKey(t)
+	data, err := EncryptPEM(key, []byte("secret"))
+	require.NoError(t, err)
+	path := writeFile(t, "key.pem", data, 0600)
+
+	p, err := OpenFile(path, passphrase("secret"))
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(p.Signer().Public()))
+	require.NoError(t, p.Close())
+
+	_, err = OpenFile(path, passphrase("wrong"))
+	require.True(t, errors.Is(err, ErrPassphrase), err)
+
+	// the passphrase of the environment
+	os.Setenv(PassphraseEnvKey, "secret")
+	defer os.Unsetenv(PassphraseEnvKey)
+	p, err = Open("file://" + path)
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(p.Signer().Public()))
+}
+
+func TestPlaintextFile(t *testing.T) {
+	key := newKey(t)
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	// the passphrase is not requested
+	p, err := OpenFile(writeFile(t, "key.pem", data, 0600), func() ([]byte, error) {
+		return nil, errors.New("unexpected")
+	})
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(p.Signer().Public()))
+
+	if runtime.GOOS != "windows" {
+		_, err = OpenFile(writeFile(t, "key.pem", data, 0644), nil)
+		require.True(t, errors.Is(err, ErrPermissions), err)
+	}
+
+	_, err = OpenFile(writeFile(t, "key.pem", []byte("no key"), 0600), nil)
+	require.True(t, errors.Is(err, ErrUnsupported), err)
+}
+
+type testProvider struct {
+	signer crypto.Signer
+	closed bool
+}
+
+func (p *testProvider) Signer() crypto.Signer { return p.signer }
+
+func (p *testProvider) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestSchemes(t *testing.T) {
+	_, err := Open("hsm:object=server0")
+	require.True(t, errors.Is(err, ErrUnsupported), err)
+	// no build registers the PKCS#11 tokens
+	_, err = Open("pkcs11:token=vpir;object=server0?module-path=/lib.so")
+	require.True(t, errors.Is(err, ErrUnsupported), err)
+
+	key := newKey(t)
+	RegisterScheme("hsm", func(uri string) (Provider, error) {
+		require.Equal(t, "hsm:object=server0", uri)
+		return &testProvider{signer: key}, nil
+	})
+	p, err := Open("hsm:object=server0")
+	require.NoError(t, err)
+	require.Equal(t, crypto.Signer(key), p.Signer())
+}
+
+func TestLoadX509KeyPair(t *testing.T) {
+	key := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "server0"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	certFile := writeFile(t, "cert.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+
+	data, err := EncryptPEM(key, []byte("secret"))
+	require.NoError(t, err)
+	os.Setenv(PassphraseEnvKey, "secret")
+	defer os.Unsetenv(PassphraseEnvKey)
+
+	cert, err := LoadX509KeyPair(certFile, writeFile(t, "key.pem", data, 0600))
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(cert.PrivateKey.(crypto.Signer).Public()))
+
+	// the key of another certificate
+	data, err = EncryptPEM(newKey(t), []byte("secret"))
+	require.NoError(t, err)
+	_, err = LoadX509KeyPair(certFile, writeFile(t, "key.pem", data, 0600))
+	require.Error(t, err)
+}
+
+func TestParsePKCS11URI(t *testing.T) {
+	pinFile := writeFile(t, "pin", []byte("1234\n"), 0600)
+	u, err := ParsePKCS11URI("pkcs11:token=vpir;object=server%200;id=%01" +
+		"?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-source=file:" + pinFile)
+	require.NoError(t, err)
+	require.Equal(t, "vpir", u.Token)
+	require.Equal(t, "server 0", u.Object)
+	require.Equal(t, []byte{1}, u.ID)
+	require.Equal(t, "/usr/lib/softhsm/libsofthsm2.so", u.ModulePath)
+	pin, err := u.Pin()
+	require.NoError(t, err)
+	require.Equal(t, "1234", pin)
+
+	for _, uri := range []string{
+		"file:key.pem",
+		"pkcs11:object=server0",
+		"pkcs11:token=vpir?module-path=/lib.so",
+		"pkcs11:object?module-path=/lib.so",
+	} {
+		_, err := ParsePKCS11URI(uri)
+		require.Error(t, err, uri)
+	}
+}
@@ -0,0 +1,119 @@
+package keys
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// PKCS11URI is the reference of a key of a PKCS#11 token, e.g., an HSM, in
+// the format of RFC 7512, e.g.,
+// pkcs11:token=vpir;object=server0?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-source=file:/etc/vpir/pin.
+// The opener of the keys of the tokens is deferred, so that no PKCS#11
+// library is linked: a build for the tokens would register the opener of the
+// scheme pkcs11 with RegisterScheme, parsing the URIs with ParsePKCS11URI.
+type PKCS11URI struct {
+	// label of the token and of the key object, and ID of the object
+	Token  string
+	Object string
+	ID     []byte
+	// path of the PKCS#11 library of the token
+	ModulePath string
+	// PIN of the user, given in the URI or read from a file
+	PinValue  string
+	PinSource string
+}
+
+// ParsePKCS11URI parses the PKCS#11 URI
+func ParsePKCS11URI(uri string) (*PKCS11URI, error) {
+	rest := strings.TrimPrefix(uri, "pkcs11:")
+	if rest == uri {
+		return nil, fmt.Errorf("not a PKCS#11 URI: %q", uri)
+	}
+	path, query := rest, ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		path, query = rest[:i], rest[i+1:]
+	}
+
+	u := new(PKCS11URI)
+	for _, attr := range splitAttributes(path, ';') {
+		k, v, err := unescapeAttribute(attr)
+		if err != nil {
+			return nil, err
+		}
+		switch k {
+		case "token":
+			u.Token = v
+		case "object":
+			u.Object = v
+		case "id":
+			u.ID = []byte(v)
+		}
+	}
+	for _, attr := range splitAttributes(query, '&') {
+		k, v, err := unescapeAttribute(attr)
+		if err != nil {
+			return nil, err
+		}
+		switch k {
+		case "module-path":
+			u.ModulePath = v
+		case "pin-value":
+			u.PinValue = v
+		case "pin-source":
+			u.PinSource = v
+		}
+	}
+
+	if u.ModulePath == "" {
+		return nil, fmt.Errorf("no module-path in the PKCS#11 URI")
+	}
+	if u.Object == "" && u.ID == nil {
+		return nil, fmt.Errorf("no object or id in the PKCS#11 URI")
+	}
+	return u, nil
+}
+
+func splitAttributes(s string, sep byte) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, string(sep))
+}
+
+func unescapeAttribute(attr string) (string, string, error) {
+	i := strings.IndexByte(attr, '=')
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid PKCS#11 URI attribute %q", attr)
+	}
+	v, err := url.PathUnescape(attr[i+1:])
+	if err != nil {
+		return "", "", err
+	}
+	return attr[:i], v, nil
+}
+
+// Pin returns the PIN of the URI, read from the pin-source file if any,
+// which must not be accessible by other users than its owner
+func (u *PKCS11URI) Pin() (string, error) {
+	if u.PinSource == "" {
+		return u.PinValue, nil
+	}
+	path := strings.TrimPrefix(u.PinSource, "file:")
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("%w: %s", ErrPermissions, path)
+	}
+	pin, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(pin, "\r\n")), nil
+}
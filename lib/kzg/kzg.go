@@ -0,0 +1,286 @@
+// Package kzg implements the polynomial commitment of Kate, Zaverucha and
+// Goldberg over the BN256 pairing of golang.org/x/crypto, to authenticate the
+// blocks of a db with a constant-size digest and constant-size proofs. The
+// blocks are split into chunks of ChunkLen bytes, which are the evaluations
+// of a single polynomial at consecutive integers, and the proof of a block is
+// a multi-point opening of the polynomial at the points of its chunks.
+//
+// The commitment is computed with the trapdoor of the setup, which is then
+// discarded: as for the root of a Merkle tree, the digest is trusted to come
+// from the owner of the db, and the servers, which never see the trapdoor,
+// cannot forge proofs. The BN256 curve of golang.org/x/crypto has about 100
+// bits of security.
+package kzg
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/bn256"
+)
+
+// ChunkLen is the number of bytes of a block mapped to an element of the
+// scalar field, of 256 bits
+const ChunkLen = 31
+
+// ProofLen is the length in bytes of an encoded proof, a point of G1
+const ProofLen = 64
+
+// Digest is the public information needed to verify the proofs: the
+// commitment to the polynomial and the powers of the trapdoor in G1 and G2
+// up to the number of chunks of a block, all encoded
+type Digest struct {
+	Commitment []byte
+	G1Powers   [][]byte
+	G2Powers   [][]byte
+}
+
+// Commit returns the digest of the blocks, which have the same length, and
+// the proof of every block
+func Commit(rnd io.Reader, blocks [][]byte) (*Digest, [][]byte, error) {
+	if len(blocks) == 0 {
+		return nil, nil, errors.New("no blocks to commit to")
+	}
+	numChunks := NumChunks(len(blocks[0]))
+	values := make([]*big.Int, 0, len(blocks)*numChunks)
+	for _, b := range blocks {
+		if len(b) != len(blocks[0]) {
+			return nil, nil, errors.New("blocks of different lengths")
+		}
+		values = append(values, chunks(b)...)
+	}
+
+	tau, err := rand.Int(rnd, bn256.Order)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the trapdoor is not one of the points, except with negligible
+	// probability
+	if tau.Cmp(big.NewInt(int64(len(values)))) < 0 {
+		return nil, nil, errors.New("degenerate trapdoor")
+	}
+
+	d := &Digest{
+		G1Powers: make([][]byte, numChunks),
+		G2Powers: make([][]byte, numChunks+1),
+	}
+	power := big.NewInt(1)
+	for i := range d.G2Powers {
+		if i < numChunks {
+			d.G1Powers[i] = new(bn256.G1).ScalarBaseMult(power).Marshal()
+		}
+		d.G2Powers[i] = new(bn256.G2).ScalarBaseMult(power).Marshal()
+		power = mod(new(big.Int).Mul(power, tau))
+	}
+
+	f := evaluateAt(values, tau)
+	d.Commitment = new(bn256.G1).ScalarBaseMult(f).Marshal()
+
+	proofs := make([][]byte, len(blocks))
+	for j := range blocks {
+		points := blockPoints(j, numChunks)
+		block := values[j*numChunks : (j+1)*numChunks]
+		r := evaluate(interpolate(points, block), tau)
+		z := evaluate(vanishing(points), tau)
+		// (f(tau) - r(tau)) / z(tau)
+		q := mod(new(big.Int).Mul(mod(new(big.Int).Sub(f, r)), new(big.Int).ModInverse(z, bn256.Order)))
+		proofs[j] = new(bn256.G1).ScalarBaseMult(q).Marshal()
+	}
+
+	return d, proofs, nil
+}
+
+// Verify returns true if the proof shows that the block is the one of the
+// given index committed to in the digest
+func Verify(d *Digest, index int, block, proof []byte) (bool, error) {
+	numChunks := NumChunks(len(block))
+	if len(d.G1Powers) != numChunks || len(d.G2Powers) != numChunks+1 {
+		return false, fmt.Errorf("digest for blocks of %d chunks", len(d.G1Powers))
+	}
+	c, ok := new(bn256.G1).Unmarshal(d.Commitment)
+	if !ok {
+		return false, errors.New("malformed commitment")
+	}
+	pi, ok := new(bn256.G1).Unmarshal(proof)
+	if !ok {
+		return false, nil
+	}
+
+	points := blockPoints(index, numChunks)
+	r, err := commitG1(d.G1Powers, interpolate(points, chunks(block)))
+	if err != nil {
+		return false, err
+	}
+	z, err := commitG2(d.G2Powers, vanishing(points))
+	if err != nil {
+		return false, err
+	}
+
+	// e(C - [r(tau)], g2) = e(pi, [z(tau)])
+	lhs := bn256.Pair(c.Add(c, r.Neg(r)), new(bn256.G2).ScalarBaseMult(big.NewInt(1)))
+	rhs := bn256.Pair(pi, z)
+	return string(lhs.Marshal()) == string(rhs.Marshal()), nil
+}
+
+// NumChunks returns the number of chunks of a block of the given length
+func NumChunks(blockLen int) int {
+	return (blockLen + ChunkLen - 1) / ChunkLen
+}
+
+// chunks returns the chunks of the block as scalars
+func chunks(block []byte) []*big.Int {
+	out := make([]*big.Int, NumChunks(len(block)))
+	for i := range out {
+		end := (i + 1) * ChunkLen
+		if end > len(block) {
+			end = len(block)
+		}
+		out[i] = new(big.Int).SetBytes(block[i*ChunkLen : end])
+	}
+	return out
+}
+
+// blockPoints returns the points of the chunks of the block of the index
+func blockPoints(index, numChunks int) []*big.Int {
+	points := make([]*big.Int, numChunks)
+	for i := range points {
+		points[i] = big.NewInt(int64(index*numChunks + i))
+	}
+	return points
+}
+
+// evaluateAt returns the evaluation at x of the polynomial whose evaluations
+// at 0, ..., n-1 are the values, in time linear in n. The Lagrange
+// polynomial of k is prod_{l != k} (x - l) / (k - l), whose denominator is
+// (-1)^(n-1-k) k! (n-1-k)!.
+func evaluateAt(values []*big.Int, x *big.Int) *big.Int {
+	n := len(values)
+	factorials := make([]*big.Int, n)
+	factorials[0] = big.NewInt(1)
+	for k := 1; k < n; k++ {
+		factorials[k] = mod(new(big.Int).Mul(factorials[k-1], big.NewInt(int64(k))))
+	}
+	all := big.NewInt(1)
+	dens := make([]*big.Int, n)
+	for k := 0; k < n; k++ {
+		diff := mod(new(big.Int).Sub(x, big.NewInt(int64(k))))
+		all = mod(new(big.Int).Mul(all, diff))
+		den := mod(new(big.Int).Mul(factorials[k], factorials[n-1-k]))
+		if (n-1-k)%2 == 1 {
+			den = mod(new(big.Int).Neg(den))
+		}
+		dens[k] = mod(new(big.Int).Mul(den, diff))
+	}
+	batchInvert(dens)
+
+	acc := new(big.Int)
+	for k, v := range values {
+		acc.Add(acc, new(big.Int).Mul(v, dens[k]))
+	}
+	return mod(acc.Mul(mod(acc), all))
+}
+
+// batchInvert replaces the non-zero scalars with their inverses, with a
+// single inversion
+func batchInvert(in []*big.Int) {
+	prefix := make([]*big.Int, len(in))
+	acc := big.NewInt(1)
+	for i, a := range in {
+		prefix[i] = acc
+		acc = mod(new(big.Int).Mul(acc, a))
+	}
+	inv := new(big.Int).ModInverse(acc, bn256.Order)
+	for i := len(in) - 1; i >= 0; i-- {
+		next := mod(new(big.Int).Mul(inv, in[i]))
+		in[i] = mod(new(big.Int).Mul(inv, prefix[i]))
+		inv = next
+	}
+}
+
+// interpolate returns the coefficients, from the constant one, of the
+// polynomial of degree less than the number of points taking the values at
+// the points
+func interpolate(points, values []*big.Int) []*big.Int {
+	z := vanishing(points)
+	out := zeros(len(points))
+	for i, p := range points {
+		// z / (X - p) by synthetic division
+		q := zeros(len(points))
+		carry := new(big.Int)
+		for k := len(points); k >= 1; k-- {
+			carry = mod(new(big.Int).Add(z[k], new(big.Int).Mul(carry, p)))
+			q[k-1] = carry
+		}
+		den := evaluate(q, p)
+		scale := mod(new(big.Int).Mul(values[i], new(big.Int).ModInverse(den, bn256.Order)))
+		for k := range out {
+			out[k] = mod(out[k].Add(out[k], new(big.Int).Mul(q[k], scale)))
+		}
+	}
+	return out
+}
+
+// vanishing returns the coefficients, from the constant one, of the product
+// of X - p over the points
+func vanishing(points []*big.Int) []*big.Int {
+	out := []*big.Int{big.NewInt(1)}
+	for _, p := range points {
+		next := zeros(len(out) + 1)
+		for k, c := range out {
+			next[k+1] = mod(next[k+1].Add(next[k+1], c))
+			next[k] = mod(next[k].Sub(next[k], new(big.Int).Mul(c, p)))
+		}
+		out = next
+	}
+	return out
+}
+
+// evaluate returns the evaluation of the polynomial at x
+func evaluate(coeffs []*big.Int, x *big.Int) *big.Int {
+	acc := new(big.Int)
+	for k := len(coeffs) - 1; k >= 0; k-- {
+		acc = mod(acc.Add(acc.Mul(acc, x), coeffs[k]))
+	}
+	return acc
+}
+
+// commitG1 returns the commitment in G1 to the polynomial with the powers
+func commitG1(powers [][]byte, coeffs []*big.Int) (*bn256.G1, error) {
+	acc := new(bn256.G1).ScalarBaseMult(new(big.Int))
+	for k, c := range coeffs {
+		p, ok := new(bn256.G1).Unmarshal(powers[k])
+		if !ok {
+			return nil, errors.New("malformed digest")
+		}
+		acc.Add(acc, p.ScalarMult(p, c))
+	}
+	return acc, nil
+}
+
+// commitG2 returns the commitment in G2 to the polynomial with the powers
+func commitG2(powers [][]byte, coeffs []*big.Int) (*bn256.G2, error) {
+	acc := new(bn256.G2).ScalarBaseMult(new(big.Int))
+	for k, c := range coeffs {
+		p, ok := new(bn256.G2).Unmarshal(powers[k])
+		if !ok {
+			return nil, errors.New("malformed digest")
+		}
+		acc.Add(acc, p.ScalarMult(p, c))
+	}
+	return acc, nil
+}
+
+func zeros(n int) []*big.Int {
+	out := make([]*big.Int, n)
+	for i := range out {
+		out[i] = new(big.Int)
+	}
+	return out
+}
+
+func mod(a *big.Int) *big.Int {
+	return a.Mod(a, bn256.Order)
+}
@@ -0,0 +1,43 @@
+package kzg
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitVerify(t *testing.T) {
+	blocks := make([][]byte, 20)
+	for i := range blocks {
+		blocks[i] = make([]byte, 70)
+		_, err := rand.Read(blocks[i])
+		require.NoError(t, err)
+	}
+	d, proofs, err := Commit(rand.Reader, blocks)
+	require.NoError(t, err)
+	require.Len(t, d.Commitment, ProofLen)
+
+	for i := range blocks {
+		ok, err := Verify(d, i, blocks[i], proofs[i])
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	// wrong block, index or proof
+	tampered := append([]byte{}, blocks[3]...)
+	tampered[69] ^= 1
+	ok, err := Verify(d, 3, tampered, proofs[3])
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = Verify(d, 4, blocks[3], proofs[3])
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = Verify(d, 3, blocks[3], proofs[4])
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// blocks of another length
+	_, err = Verify(d, 3, blocks[3][:31], proofs[3])
+	require.Error(t, err)
+}
@@ -0,0 +1,270 @@
+// Package logging implements the leveled logger of the servers, clients and
+// tools, whose entries carry a message and key-value fields, written as
+// text lines or as JSON objects. The loggers are injected in the
+// constructors, e.g., of the servers, so that they run quietly in
+// production and verbosely when debugging without code edits. The
+// functions of the libraries without a constructor log to the default
+// logger, which writes through the standard log package until replaced with
+// SetDefault.
+package logging
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of an entry
+type Level int8
+
+const (
+	// Debug entries detail the processing, e.g., of every query
+	Debug Level = iota - 1
+	// Info entries report the normal operation
+	Info
+	// Warn entries report the failures that are recovered
+	Warn
+	// Error entries report the failures that are not
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", l)
+	}
+}
+
+// ParseLevel returns the level of the given name, e.g., debug
+func ParseLevel(s string) (Level, error) {
+	for l := Debug; l <= Error; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// Field is a key-value field of an entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns the field of the key and value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger writes the entries of its level and above
+type Logger struct {
+	out    *output
+	level  Level
+	json   bool
+	fields []Field
+}
+
+// output is the writer shared by a logger and the loggers derived from it,
+// the standard log package if nil
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New returns the logger writing the entries of the level and above to w,
+// as JSON objects or as text lines
+func New(w io.Writer, level Level, json bool) *Logger {
+	return &Logger{out: &output{w: w}, level: level, json: json}
+}
+
+// Discard is the logger writing nothing
+var Discard = New(io.Discard, Error+1, false)
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = &Logger{out: &output{}, level: Info}
+)
+
+// Default returns the default logger
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the default logger
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// With returns a logger adding the fields to all its entries
+func (l *Logger) With(fields ...Field) *Logger {
+	out := *l
+	out.fields = append(append([]Field{}, l.fields...), fields...)
+	return &out
+}
+
+// Enabled reports whether the entries of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug writes an entry of level Debug
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.log(Debug, msg, fields)
+}
+
+// Info writes an entry of level Info
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.log(Info, msg, fields)
+}
+
+// Warn writes an entry of level Warn
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.log(Warn, msg, fields)
+}
+
+// Error writes an entry of level Error
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.log(Error, msg, fields)
+}
+
+// Fatal writes an entry of level Error and exits
+func (l *Logger) Fatal(msg string, fields ...Field) {
+	l.log(Error, msg, fields)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level Level, msg string, fields []Field) {
+	if !l.Enabled(level) {
+		return
+	}
+	all := append(append([]Field{}, l.fields...), fields...)
+
+	// the standard log package adds the time and the prefix itself
+	std := l.out.w == nil
+	var b strings.Builder
+	if l.json {
+		b.WriteByte('{')
+		if !std {
+			writeJSON(&b, "time", time.Now().UTC().Format(time.RFC3339Nano))
+			b.WriteByte(',')
+		}
+		writeJSON(&b, "level", level.String())
+		b.WriteByte(',')
+		writeJSON(&b, "msg", msg)
+		for _, f := range all {
+			b.WriteByte(',')
+			writeJSON(&b, f.Key, jsonValue(f.Value))
+		}
+		b.WriteByte('}')
+	} else {
+		if !std {
+			b.WriteString(time.Now().UTC().Format(time.RFC3339Nano))
+			b.WriteByte(' ')
+		}
+		b.WriteString(strings.ToUpper(level.String()))
+		b.WriteByte(' ')
+		b.WriteString(msg)
+		for _, f := range all {
+			b.WriteByte(' ')
+			b.WriteString(f.Key)
+			b.WriteByte('=')
+			b.WriteString(textValue(f.Value))
+		}
+	}
+	b.WriteByte('\n')
+
+	if std {
+		log.Print(b.String())
+		return
+	}
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	io.WriteString(l.out.w, b.String())
+}
+
+// writeJSON writes the key and the value of a JSON member
+func writeJSON(b *strings.Builder, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(k)
+	b.WriteByte(':')
+	b.Write(v)
+}
+
+// jsonValue returns the value encoded in JSON, the message of an error and
+// the string of a Stringer
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// textValue returns the value in a text line, quoted if it contains spaces,
+// quotes or equal signs
+func textValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case []byte:
+		s = fmt.Sprintf("%x", v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// Flags are the flags of the logger of a command
+type Flags struct {
+	level string
+	json  bool
+}
+
+// RegisterFlags defines the flags of the logger in the flag set
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := new(Flags)
+	fs.StringVar(&f.level, "log-level", "info", "minimum level of the logs: debug, info, warn or error")
+	fs.BoolVar(&f.json, "log-json", false, "write the logs as JSON objects")
+	return f
+}
+
+// Logger returns the logger of the flags writing to w, and the standard log
+// package if w is nil
+func (f *Flags) Logger(w io.Writer) (*Logger, error) {
+	level, err := ParseLevel(f.level)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return &Logger{out: &output{}, level: level, json: f.json}, nil
+	}
+	return New(w, level, f.json), nil
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevels(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, Warn, false)
+	l.Debug("debug")
+	l.Info("info")
+	require.Zero(t, b.Len())
+
+	l.Warn("warn")
+	l.Error("error")
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], "WARN warn")
+	require.Contains(t, lines[1], "ERROR error")
+
+	require.False(t, l.Enabled(Info))
+	require.True(t, l.Enabled(Error))
+}
+
+func TestText(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, Debug, false).With(F("server", 1))
+	l.Debug("query answered", F("bytes", 32), F("err", errors.New("a b")), F("id", []byte{0xab}))
+	require.True(t, strings.HasSuffix(b.String(),
+		`DEBUG query answered server=1 bytes=32 err="a b" id=ab`+"\n"), b.String())
+}
+
+func TestJSON(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, Info, true).With(F("server", 1))
+	l.Info("db loaded", F("epoch", 2), F("err", errors.New("none")), F("min", Warn))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	require.NotEmpty(t, entry["time"])
+	require.Equal(t, "info", entry["level"])
+	require.Equal(t, "db loaded", entry["msg"])
+	require.Equal(t, float64(1), entry["server"])
+	require.Equal(t, float64(2), entry["epoch"])
+	require.Equal(t, "none", entry["err"])
+	require.Equal(t, "warn", entry["min"])
+}
+
+func TestWith(t *testing.T) {
+	var b bytes.Buffer
+	parent := New(&b, Info, false)
+	child := parent.With(F("addr", "a"))
+	parent.Info("parent")
+	child.Info("child")
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasSuffix(lines[0], "INFO parent"))
+	require.True(t, strings.HasSuffix(lines[1], "INFO child addr=a"))
+}
+
+func TestParseLevel(t *testing.T) {
+	for l := Debug; l <= Error; l++ {
+		parsed, err := ParseLevel(strings.ToUpper(l.String()))
+		require.NoError(t, err)
+		require.Equal(t, l, parsed)
+	}
+	_, err := ParseLevel("verbose")
+	require.Error(t, err)
+}
+
+func TestFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-log-level", "debug", "-log-json"}))
+
+	var b bytes.Buffer
+	l, err := f.Logger(&b)
+	require.NoError(t, err)
+	l.Debug("hello")
+	require.Contains(t, b.String(), `"level":"debug"`)
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	f = RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-log-level", "verbose"}))
+	_, err = f.Logger(&b)
+	require.Error(t, err)
+}
@@ -22,6 +22,17 @@ void binary_multiply(int aRows, int aCols, int bCols, uint32_t *a, uint8_t *b, u
 	}
 }
 
+void bytes_multiply(int aRows, int aCols, int bCols, uint32_t *a, uint8_t *b, uint32_t *out) {
+   	int i, j, k;
+	for (i = 0; i < aRows; i++) {
+		for (k = 0; k < aCols; k++) {
+			for (j = 0; j < bCols; j++) {
+				out[bCols*i+j] += a[aCols*i+k] * b[bCols*k+j];
+			}
+		}
+	}
+}
+
 void multiply128(int aRows, int aCols, int bCols, __uint128_t *a, __uint128_t *b, __uint128_t *out) {
    	int i, j, k;
 	for (i = 0; i < aRows; i++) {
@@ -170,6 +170,21 @@ func BinaryMul(a *Matrix, b *MatrixBytes) *Matrix {
 	return out
 }
 
+// BytesMul multiplies a by the matrix of bytes b, whose entries are not
+// restricted to bits as in BinaryMul
+func BytesMul(a *Matrix, b *MatrixBytes) *Matrix {
+	if a.cols != b.rows {
+		panic("Dimension mismatch")
+	}
+
+	out := New(a.rows, b.cols)
+	C.bytes_multiply(C.int(a.rows), C.int(a.cols), C.int(b.cols),
+		(*C.uint32_t)(&a.data[0]), (*C.uint8_t)(&b.data[0]),
+		(*C.uint32_t)(&out.data[0]))
+
+	return out
+}
+
 func Mul(a *Matrix, b *Matrix) *Matrix {
 	if a.cols != b.rows {
 		panic("Dimension mismatch")
@@ -33,3 +33,11 @@ func (m *MatrixBytes) Get(r int, c int) byte {
 func (m *MatrixBytes) Len() int {
 	return len(m.data)
 }
+
+func (m *MatrixBytes) Rows() int {
+	return m.rows
+}
+
+func (m *MatrixBytes) Cols() int {
+	return m.cols
+}
@@ -36,6 +36,29 @@ func TestEncoding(t *testing.T) {
 	}
 }
 
+func TestBytesMul(t *testing.T) {
+	rnd := utils.RandomPRG()
+	a := NewRandom(rnd, 3, 5)
+	b := NewBytes(5, 4)
+	buff := make([]byte, b.Len())
+	_, err := rnd.Read(buff)
+	require.NoError(t, err)
+	for i := range buff {
+		b.SetData(i, buff[i])
+	}
+
+	out := BytesMul(a, b)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			var expected uint32
+			for k := 0; k < 5; k++ {
+				expected += a.Get(i, k) * uint32(b.Get(k, j))
+			}
+			require.Equal(t, expected, out.Get(i, j))
+		}
+	}
+}
+
 func BenchmarkBinaryMul32(b *testing.B) {
 	rows, columns := 1024, 1024
 	buff := make([]byte, rows*columns/8+1)
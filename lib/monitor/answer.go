@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"context"
+	"runtime/trace"
+	"sync/atomic"
+	"time"
+)
+
+// AnswerPhase is a step of the computation of an answer by a server
+type AnswerPhase int
+
+const (
+	PhaseDecode AnswerPhase = iota // decoding of the query
+	PhaseExpand                    // expansion of the DPF key into the query vector
+	PhaseScan                      // pass over the db
+	PhaseProof                     // assembly of the integrity proofs
+	PhaseEncode                    // encoding of the answer
+	numPhases
+)
+
+var phaseNames = [numPhases]string{"decode", "expand", "scan", "proof", "encode"}
+
+func (p AnswerPhase) String() string {
+	return phaseNames[p]
+}
+
+// AnswerBreakdown is the wall-clock time in seconds spent by the servers in
+// each phase of their answers. The FSS evaluation of the predicate queries is
+// interleaved with the pass over the db and is counted in Scan. The schemes
+// in this tree store their proofs in the db, so that Proof is zero for them.
+type AnswerBreakdown struct {
+	Decode float64
+	Expand float64
+	Scan   float64
+	Proof  float64
+	Encode float64
+}
+
+// the durations in nanoseconds are shared by all the servers of the process
+var answerPhases struct {
+	enabled int32
+	ns      [numPhases]int64
+}
+
+// EnableAnswerPhases enables or disables the timing of the answer phases.
+// Since the durations of concurrent answers are summed, the breakdown of a
+// single answer is only meaningful if the servers answer one after the other.
+func EnableAnswerPhases(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&answerPhases.enabled, v)
+}
+
+// Phase is a running phase of an answer, timed if enabled and annotated as a
+// region of the execution trace if tracing
+type Phase struct {
+	p      AnswerPhase
+	start  time.Time // zero if the timing is disabled
+	region *trace.Region
+}
+
+// StartPhase starts the first phase of an answer
+func StartPhase(p AnswerPhase) Phase {
+	ph := Phase{p: p, region: trace.StartRegion(context.Background(), p.String())}
+	if atomic.LoadInt32(&answerPhases.enabled) != 0 {
+		ph.start = time.Now()
+	}
+	return ph
+}
+
+// End adds the time elapsed since the start to the phase
+func (ph Phase) End() {
+	ph.region.End()
+	if !ph.start.IsZero() {
+		atomic.AddInt64(&answerPhases.ns[ph.p], int64(time.Since(ph.start)))
+	}
+}
+
+// Next ends the phase and starts the next one
+func (ph Phase) Next(p AnswerPhase) Phase {
+	ph.End()
+	return StartPhase(p)
+}
+
+// ReadAnswerPhases returns the time spent in each phase since the last reset
+func ReadAnswerPhases() AnswerBreakdown {
+	var ns [numPhases]int64
+	for p := range ns {
+		ns[p] = atomic.LoadInt64(&answerPhases.ns[p])
+	}
+	return newAnswerBreakdown(ns)
+}
+
+// ResetAnswerPhases returns the time spent in each phase since the last reset
+// and sets the durations to zero
+func ResetAnswerPhases() AnswerBreakdown {
+	var ns [numPhases]int64
+	for p := range ns {
+		ns[p] = atomic.SwapInt64(&answerPhases.ns[p], 0)
+	}
+	return newAnswerBreakdown(ns)
+}
+
+func newAnswerBreakdown(ns [numPhases]int64) AnswerBreakdown {
+	s := func(p AnswerPhase) float64 { return time.Duration(ns[p]).Seconds() }
+	return AnswerBreakdown{
+		Decode: s(PhaseDecode),
+		Expand: s(PhaseExpand),
+		Scan:   s(PhaseScan),
+		Proof:  s(PhaseProof),
+		Encode: s(PhaseEncode),
+	}
+}
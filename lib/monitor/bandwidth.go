@@ -0,0 +1,73 @@
+package monitor
+
+import "sync/atomic"
+
+// Bandwidth is the number of bytes of the marshaled messages of the schemes,
+// as counted by the QueryBytes, AnswerBytes and ReconstructBytes functions of
+// the clients and the servers
+type Bandwidth struct {
+	Query       uint64 // queries sent by the clients to all the servers
+	Answer      uint64 // answers sent by the servers
+	Reconstruct uint64 // answers received by the clients
+}
+
+// the counters are shared by all the clients and servers of the process
+var counters struct {
+	enabled                    int32
+	query, answer, reconstruct uint64
+}
+
+// EnableBandwidth enables or disables the bandwidth counters. They are
+// disabled by default, so that counting does not cost anything.
+func EnableBandwidth(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&counters.enabled, v)
+}
+
+func countBytes(counter *uint64, msgs [][]byte) {
+	if atomic.LoadInt32(&counters.enabled) == 0 {
+		return
+	}
+	n := 0
+	for _, m := range msgs {
+		n += len(m)
+	}
+	atomic.AddUint64(counter, uint64(n))
+}
+
+// CountQuery adds the bytes of the queries to the counters, if enabled
+func CountQuery(queries ...[]byte) {
+	countBytes(&counters.query, queries)
+}
+
+// CountAnswer adds the bytes of the answer to the counters, if enabled
+func CountAnswer(answer []byte) {
+	countBytes(&counters.answer, [][]byte{answer})
+}
+
+// CountReconstruct adds the bytes of the answers to the counters, if enabled
+func CountReconstruct(answers ...[]byte) {
+	countBytes(&counters.reconstruct, answers)
+}
+
+// ReadBandwidth returns the bytes counted since the last reset
+func ReadBandwidth() Bandwidth {
+	return Bandwidth{
+		Query:       atomic.LoadUint64(&counters.query),
+		Answer:      atomic.LoadUint64(&counters.answer),
+		Reconstruct: atomic.LoadUint64(&counters.reconstruct),
+	}
+}
+
+// ResetBandwidth returns the bytes counted since the last reset and sets the
+// counters to zero
+func ResetBandwidth() Bandwidth {
+	return Bandwidth{
+		Query:       atomic.SwapUint64(&counters.query, 0),
+		Answer:      atomic.SwapUint64(&counters.answer, 0),
+		Reconstruct: atomic.SwapUint64(&counters.reconstruct, 0),
+	}
+}
@@ -0,0 +1,47 @@
+package monitor
+
+// EnergyMonitor measures the energy consumed by the CPU packages between two
+// calls, for the measurement of the energy cost of operations. The counters
+// cover the whole packages, so that only the measurements of operations
+// running one after the other, on an otherwise idle machine, are meaningful.
+type EnergyMonitor struct {
+	zones  []raplZone
+	energy []uint64 // microjoules at the last reset, by zone
+}
+
+// NewEnergyMonitor returns an energy monitor reading the RAPL counters of
+// Linux, or an error if they are not available or readable. Recent kernels
+// restrict them to root.
+func NewEnergyMonitor() (*EnergyMonitor, error) {
+	zones, err := raplZones()
+	if err != nil {
+		return nil, err
+	}
+	m := &EnergyMonitor{zones: zones, energy: make([]uint64, len(zones))}
+	for i, z := range zones {
+		if m.energy[i], err = z.read(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *EnergyMonitor) Reset() {
+	m.RecordAndReset()
+}
+
+// RecordAndReset returns the energy in joules consumed since the last reset
+func (m *EnergyMonitor) RecordAndReset() float64 {
+	var uj uint64
+	for i, z := range m.zones {
+		e := z.mustRead()
+		if e >= m.energy[i] {
+			uj += e - m.energy[i]
+		} else {
+			// the counter wrapped around
+			uj += z.maxEnergy - m.energy[i] + e
+		}
+		m.energy[i] = e
+	}
+	return float64(uj) / 1e6
+}
@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultLatencyBuckets are the upper bounds, in seconds, of the
+	// histograms of the query latencies
+	DefaultLatencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+	// DefaultByteBuckets are the upper bounds, in bytes, of the histograms of
+	// the answer sizes
+	DefaultByteBuckets = []float64{1 << 6, 1 << 8, 1 << 10, 1 << 12, 1 << 14, 1 << 16, 1 << 18, 1 << 20, 1 << 22, 1 << 24, 1 << 26}
+)
+
+// Exporter publishes the measurements of a long-running server in the text
+// exposition format of Prometheus, so that they can be scraped into standard
+// dashboards. It implements http.Handler and is usually served on /metrics.
+// Besides the per-scheme metrics observed by the server, it exports the
+// bandwidth counters and the answer phases of the package, which must be
+// enabled with EnableBandwidth and EnableAnswerPhases, and the peak RSS of the
+// process.
+type Exporter struct {
+	namespace string
+
+	mu      sync.Mutex
+	queries map[string]uint64 // by scheme
+	errors  map[string]uint64
+	latency map[string]*histogram
+	answers map[string]*histogram
+	// exact quantiles of the latency, exported as a summary
+	quantiles map[string]*LatencyHistogram
+}
+
+// percentiles of the latency summary
+var summaryPercentiles = []float64{50, 90, 99, 99.9}
+
+// NewExporter returns an exporter whose metrics names are prefixed by
+// namespace, e.g., vpir_query_duration_seconds
+func NewExporter(namespace string) *Exporter {
+	return &Exporter{
+		namespace: namespace,
+		queries:   make(map[string]uint64),
+		errors:    make(map[string]uint64),
+		latency:   make(map[string]*histogram),
+		answers:   make(map[string]*histogram),
+		quantiles: make(map[string]*LatencyHistogram),
+	}
+}
+
+// ObserveQuery records a query answered with the given scheme, the time it
+// took and the length in bytes of the answer
+func (e *Exporter) ObserveQuery(scheme string, latency time.Duration, answerLen int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.queries[scheme]++
+	if e.latency[scheme] == nil {
+		e.latency[scheme] = newHistogram(DefaultLatencyBuckets)
+		e.answers[scheme] = newHistogram(DefaultByteBuckets)
+		e.quantiles[scheme] = NewDefaultLatencyHistogram()
+	}
+	e.latency[scheme].observe(latency.Seconds())
+	e.quantiles[scheme].Record(latency)
+	e.answers[scheme].observe(float64(answerLen))
+}
+
+// ObserveError records a query that could not be answered
+func (e *Exporter) ObserveError(scheme string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors[scheme]++
+}
+
+// ServeHTTP writes all the metrics
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	e.write(bw)
+	bw.Flush()
+}
+
+func (e *Exporter) write(w *bufio.Writer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.header(w, "queries_total", "counter", "Queries answered, by scheme.")
+	for _, s := range sortedKeys(e.queries) {
+		fmt.Fprintf(w, "%s%s %d\n", e.name("queries_total"), schemeLabel(s), e.queries[s])
+	}
+	e.header(w, "query_errors_total", "counter", "Queries that could not be answered, by scheme.")
+	for _, s := range sortedKeys(e.errors) {
+		fmt.Fprintf(w, "%s%s %d\n", e.name("query_errors_total"), schemeLabel(s), e.errors[s])
+	}
+
+	schemes := sortedKeys(e.queries)
+	e.header(w, "query_duration_seconds", "histogram", "Time to answer a query, by scheme.")
+	for _, s := range schemes {
+		e.latency[s].write(w, e.name("query_duration_seconds"), s)
+	}
+	e.header(w, "query_latency_seconds", "summary", "Quantiles of the time to answer a query, by scheme.")
+	for _, s := range schemes {
+		h := e.quantiles[s]
+		for i, d := range h.Percentiles(summaryPercentiles...) {
+			fmt.Fprintf(w, "%s{scheme=\"%s\",quantile=\"%s\"} %s\n", e.name("query_latency_seconds"),
+				escapeLabel(s), strconv.FormatFloat(summaryPercentiles[i]/100, 'g', 4, 64), formatFloat(d.Seconds()))
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", e.name("query_latency_seconds"), schemeLabel(s), formatFloat(e.latency[s].sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", e.name("query_latency_seconds"), schemeLabel(s), h.Count())
+	}
+	e.header(w, "answer_size_bytes", "histogram", "Length of the answers, by scheme.")
+	for _, s := range schemes {
+		e.answers[s].write(w, e.name("answer_size_bytes"), s)
+	}
+
+	b := ReadBandwidth()
+	e.header(w, "query_bytes_total", "counter", "Bytes of the queries marshaled by the clients.")
+	fmt.Fprintf(w, "%s %d\n", e.name("query_bytes_total"), b.Query)
+	e.header(w, "answer_bytes_total", "counter", "Bytes of the answers marshaled by the servers.")
+	fmt.Fprintf(w, "%s %d\n", e.name("answer_bytes_total"), b.Answer)
+	e.header(w, "reconstruct_bytes_total", "counter", "Bytes of the answers decoded by the clients.")
+	fmt.Fprintf(w, "%s %d\n", e.name("reconstruct_bytes_total"), b.Reconstruct)
+
+	a := ReadAnswerPhases()
+	e.header(w, "answer_phase_seconds_total", "counter", "Time spent by the servers in each phase of the answers, if enabled with EnableAnswerPhases.")
+	for _, p := range []struct {
+		name    string
+		seconds float64
+	}{{"decode", a.Decode}, {"expand", a.Expand}, {"scan", a.Scan}, {"proof", a.Proof}, {"encode", a.Encode}} {
+		fmt.Fprintf(w, "%s{phase=\"%s\"} %s\n", e.name("answer_phase_seconds_total"), p.name, formatFloat(p.seconds))
+	}
+
+	e.header(w, "peak_rss_bytes", "gauge", "Peak resident set size of the process.")
+	fmt.Fprintf(w, "%s %s\n", e.name("peak_rss_bytes"), formatFloat(PeakRSS()))
+}
+
+func (e *Exporter) name(metric string) string {
+	if e.namespace == "" {
+		return metric
+	}
+	return e.namespace + "_" + metric
+}
+
+func (e *Exporter) header(w *bufio.Writer, metric, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", e.name(metric), help, e.name(metric), kind)
+}
+
+// histogram counts the observations below each bucket upper bound
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // not cumulative, the last one is +Inf
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.buckets, v)]++
+	h.sum += v
+	h.count++
+}
+
+// write writes the cumulative buckets, the sum and the count of the histogram
+func (h *histogram) write(w *bufio.Writer, name, scheme string) {
+	label := escapeLabel(scheme)
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		le := "+Inf"
+		if i < len(h.buckets) {
+			le = formatFloat(h.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{scheme=\"%s\",le=\"%s\"} %d\n", name, label, le, cumulative)
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, schemeLabel(scheme), formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, schemeLabel(scheme), h.count)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func schemeLabel(scheme string) string {
+	return "{scheme=\"" + escapeLabel(scheme) + "\"}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
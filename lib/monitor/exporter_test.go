@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sampleLine is a sample of the text exposition format, without timestamp
+var sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{([a-zA-Z_][a-zA-Z0-9_]*="([^"\\]|\\.)*",?)*\})? (\S+)$`)
+
+func TestExporter(t *testing.T) {
+	e := NewExporter("vpir")
+	e.ObserveQuery("pir", 3*time.Millisecond, 100)
+	// on the upper bound of a bucket, counted in it
+	e.ObserveQuery("pir", 5*time.Millisecond, 1<<14)
+	e.ObserveQuery("pir", 20*time.Millisecond, 5000)
+	e.ObserveError("pir")
+	e.ObserveError(`a "quoted" scheme`)
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	out := w.Body.String()
+
+	// every sample follows the HELP and TYPE lines of its metric
+	typed := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			require.Len(t, fields, 4, line)
+			typed[fields[2]] = fields[3]
+			continue
+		}
+		m := sampleLine.FindStringSubmatch(line)
+		require.NotNil(t, m, line)
+		name := m[1]
+		if _, ok := typed[name]; !ok {
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				name = strings.TrimSuffix(name, suffix)
+			}
+		}
+		require.Contains(t, []string{"counter", "gauge", "histogram", "summary"}, typed[name], line)
+	}
+
+	for _, line := range []string{
+		"# HELP vpir_queries_total Queries answered, by scheme.\n# TYPE vpir_queries_total counter\n",
+		"vpir_queries_total{scheme=\"pir\"} 3\n",
+		"vpir_query_errors_total{scheme=\"a \\\"quoted\\\" scheme\"} 1\n",
+		"vpir_query_errors_total{scheme=\"pir\"} 1\n",
+		"# TYPE vpir_query_duration_seconds histogram\n",
+		"vpir_query_duration_seconds_bucket{scheme=\"pir\",le=\"0.001\"} 0\n",
+		"vpir_query_duration_seconds_bucket{scheme=\"pir\",le=\"0.0025\"} 0\n",
+		"vpir_query_duration_seconds_bucket{scheme=\"pir\",le=\"0.005\"} 2\n",
+		"vpir_query_duration_seconds_bucket{scheme=\"pir\",le=\"0.01\"} 2\n",
+		"vpir_query_duration_seconds_bucket{scheme=\"pir\",le=\"0.025\"} 3\n",
+		"vpir_query_duration_seconds_bucket{scheme=\"pir\",le=\"+Inf\"} 3\n",
+		"vpir_query_duration_seconds_sum{scheme=\"pir\"} 0.028\n",
+		"vpir_query_duration_seconds_count{scheme=\"pir\"} 3\n",
+		"# TYPE vpir_query_latency_seconds summary\n",
+		"vpir_query_latency_seconds{scheme=\"pir\",quantile=\"0.99\"} 0.02\n",
+		"vpir_query_latency_seconds{scheme=\"pir\",quantile=\"0.999\"} 0.02\n",
+		"vpir_query_latency_seconds_count{scheme=\"pir\"} 3\n",
+		"vpir_answer_size_bytes_bucket{scheme=\"pir\",le=\"256\"} 1\n",
+		"vpir_answer_size_bytes_bucket{scheme=\"pir\",le=\"4096\"} 1\n",
+		"vpir_answer_size_bytes_bucket{scheme=\"pir\",le=\"16384\"} 3\n",
+		"vpir_answer_size_bytes_sum{scheme=\"pir\"} 21484\n",
+		"# TYPE vpir_answer_bytes_total counter\n",
+		"vpir_answer_phase_seconds_total{phase=\"decode\"} ",
+		"# TYPE vpir_peak_rss_bytes gauge\n",
+	} {
+		require.Contains(t, out, line)
+	}
+	// the schemes without answered queries have no histogram
+	require.NotContains(t, out, "vpir_query_duration_seconds_count{scheme=\"a")
+}
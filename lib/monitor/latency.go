@@ -0,0 +1,242 @@
+package monitor
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// LatencyHistogram records durations with a bounded relative error, in the
+// manner of HdrHistogram: the values are counted in buckets whose width
+// doubles every half sub-bucket count, so that the memory is fixed while any
+// percentile is extracted with the requested number of significant figures.
+// It is safe for concurrent use.
+type LatencyHistogram struct {
+	unit          time.Duration // lowest distinguishable duration
+	highest       int64         // highest trackable value, in units
+	subBucketBits uint
+	subBuckets    int64 // number of linear sub-buckets, a power of two
+
+	mu     sync.Mutex
+	counts []uint64
+	total  uint64
+	min    int64
+	max    int64
+	sum    float64 // in units, for the mean
+	sumSq  float64 // in units, for the standard deviation
+}
+
+// NewLatencyHistogram returns a histogram of durations between lowest and
+// highest with significantFigures (between 1 and 5) significant figures. The
+// durations out of the range are clamped to it.
+func NewLatencyHistogram(lowest, highest time.Duration, significantFigures int) *LatencyHistogram {
+	if lowest <= 0 || highest < lowest || significantFigures < 1 || significantFigures > 5 {
+		panic("invalid latency histogram parameters")
+	}
+	// the linear sub-buckets must distinguish 2*10^significantFigures values
+	largest := 2 * int64(math.Pow10(significantFigures))
+	subBucketBits := uint(bits.Len64(uint64(largest - 1)))
+	h := &LatencyHistogram{
+		unit:          lowest,
+		highest:       int64(highest / lowest),
+		subBucketBits: subBucketBits,
+		subBuckets:    1 << subBucketBits,
+	}
+	h.counts = make([]uint64, h.index(h.highest)+1)
+	h.min = math.MaxInt64
+	return h
+}
+
+// NewDefaultLatencyHistogram returns a histogram from one microsecond to one
+// hour with three significant figures
+func NewDefaultLatencyHistogram() *LatencyHistogram {
+	return NewLatencyHistogram(time.Microsecond, time.Hour, 3)
+}
+
+// index returns the bucket of the value in units
+func (h *LatencyHistogram) index(v int64) int {
+	if v < h.subBuckets {
+		return int(v)
+	}
+	// the values of the exponent e lie in [2^(bits+e-1), 2^(bits+e)) and are
+	// counted by half the sub-buckets, each 2^e wide
+	e := uint(bits.Len64(uint64(v))) - h.subBucketBits
+	half := h.subBuckets / 2
+	return int(h.subBuckets + int64(e-1)*half + (v>>e - half))
+}
+
+// highestEquivalent returns the highest value in units counted by the bucket
+func (h *LatencyHistogram) highestEquivalent(i int) int64 {
+	if int64(i) < h.subBuckets {
+		return int64(i)
+	}
+	half := h.subBuckets / 2
+	j := int64(i) - h.subBuckets
+	e := uint(j/half) + 1
+	sub := j%half + half
+	return (sub+1)<<e - 1
+}
+
+// Record adds a duration to the histogram
+func (h *LatencyHistogram) Record(d time.Duration) {
+	v := int64(d / h.unit)
+	if v < 0 {
+		v = 0
+	} else if v > h.highest {
+		v = h.highest
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[h.index(v)]++
+	h.total++
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	f := float64(v)
+	h.sum += f
+	h.sumSq += f * f
+}
+
+// Count returns the number of recorded durations
+func (h *LatencyHistogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.total
+}
+
+// Percentile returns the duration below which p percent of the recorded
+// durations lie, p in [0, 100], or zero if nothing was recorded
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.percentile(p)
+}
+
+// Percentiles returns the percentiles of ps, as Percentile does
+func (h *LatencyHistogram) Percentiles(ps ...float64) []time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]time.Duration, len(ps))
+	for i, p := range ps {
+		out[i] = h.percentile(p)
+	}
+	return out
+}
+
+func (h *LatencyHistogram) percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	if p > 100 {
+		p = 100
+	}
+	// rank of the percentile, ignoring the rounding errors of p/100
+	x := p / 100 * float64(h.total)
+	target := uint64(x)
+	if float64(target) < x*(1-1e-12) {
+		target++
+	}
+	if target == 0 {
+		target = 1
+	}
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= target {
+			v := h.highestEquivalent(i)
+			// the exact extremes are known
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v) * h.unit
+		}
+	}
+	return time.Duration(h.max) * h.unit
+}
+
+// Min returns the shortest recorded duration, zero if nothing was recorded
+func (h *LatencyHistogram) Min() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.min) * h.unit
+}
+
+// Max returns the longest recorded duration
+func (h *LatencyHistogram) Max() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.max) * h.unit
+}
+
+// Mean returns the mean of the recorded durations
+func (h *LatencyHistogram) Mean() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.total) * float64(h.unit))
+}
+
+// StdDev returns the sample standard deviation of the recorded durations
+func (h *LatencyHistogram) StdDev() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total < 2 {
+		return 0
+	}
+	n := float64(h.total)
+	variance := (h.sumSq - h.sum*h.sum/n) / (n - 1)
+	if variance < 0 {
+		variance = 0
+	}
+	return time.Duration(math.Sqrt(variance) * float64(h.unit))
+}
+
+// Merge adds the durations recorded by o, which must have the same
+// parameters, to the histogram
+func (h *LatencyHistogram) Merge(o *LatencyHistogram) {
+	if h.unit != o.unit || h.highest != o.highest || h.subBuckets != o.subBuckets {
+		panic("merging latency histograms with different parameters")
+	}
+	o.mu.Lock()
+	counts := append([]uint64(nil), o.counts...)
+	total, min, max, sum, sumSq := o.total, o.min, o.max, o.sum, o.sumSq
+	o.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, c := range counts {
+		h.counts[i] += c
+	}
+	h.total += total
+	if min < h.min {
+		h.min = min
+	}
+	if max > h.max {
+		h.max = max
+	}
+	h.sum += sum
+	h.sumSq += sumSq
+}
+
+// Reset removes all the recorded durations
+func (h *LatencyHistogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.total, h.min, h.max, h.sum, h.sumSq = 0, math.MaxInt64, 0, 0, 0
+}
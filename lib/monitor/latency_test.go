@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	h := NewDefaultLatencyHistogram()
+	// 2*10^3 values are counted exactly, then the buckets are 2 units wide
+	require.Equal(t, int64(2048), h.subBuckets)
+	require.Equal(t, 2047, h.index(2047))
+	require.Equal(t, int64(2047), h.highestEquivalent(2047))
+	require.Equal(t, h.index(2048), h.index(2049))
+	require.Equal(t, h.index(2049)+1, h.index(2050))
+	require.Equal(t, int64(2049), h.highestEquivalent(h.index(2048)))
+	require.Equal(t, len(h.counts)-1, h.index(h.highest))
+
+	// every value lies in its bucket, whose width is within the precision
+	for v := int64(1); v <= h.highest; v = v*3/2 + 1 {
+		i := h.index(v)
+		lowest := h.highestEquivalent(i-1) + 1
+		highest := h.highestEquivalent(i)
+		require.True(t, lowest <= v && v <= highest, "value %d", v)
+		require.LessOrEqual(t, float64(highest-lowest), float64(v)*1e-3, "value %d", v)
+	}
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	h := NewDefaultLatencyHistogram()
+	require.Zero(t, h.Percentile(50))
+	require.Zero(t, h.Min())
+	require.Zero(t, h.Mean())
+
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	require.Equal(t, uint64(1000), h.Count())
+	require.Equal(t, []time.Duration{
+		time.Microsecond,
+		500 * time.Microsecond,
+		990 * time.Microsecond,
+		999 * time.Microsecond,
+		1000 * time.Microsecond,
+	}, h.Percentiles(0, 50, 99, 99.9, 100))
+	require.Equal(t, time.Microsecond, h.Min())
+	require.Equal(t, 1000*time.Microsecond, h.Max())
+	require.Equal(t, 500500*time.Nanosecond, h.Mean())
+	require.InDelta(t, 288.82, float64(h.StdDev())/float64(time.Microsecond), 0.01)
+
+	// a percentile is within the precision of the histogram, and the extremes
+	// are exact
+	h.Record(123456 * time.Microsecond)
+	p := h.Percentile(99.95)
+	require.InEpsilon(t, float64(123456*time.Microsecond), float64(p), 1e-3)
+	require.Equal(t, 123456*time.Microsecond, h.Percentile(100))
+
+	// the durations out of the range are clamped to it
+	h.Record(2 * time.Hour)
+	require.Equal(t, time.Hour, h.Max())
+
+	o := NewDefaultLatencyHistogram()
+	o.Record(time.Nanosecond)
+	h.Merge(o)
+	require.Equal(t, uint64(1003), h.Count())
+	require.Equal(t, time.Duration(0), h.Min())
+	require.Panics(t, func() { h.Merge(NewLatencyHistogram(time.Millisecond, time.Hour, 3)) })
+
+	h.Reset()
+	require.Zero(t, h.Count())
+	require.Zero(t, h.Percentile(50))
+}
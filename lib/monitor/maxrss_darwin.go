@@ -0,0 +1,4 @@
+package monitor
+
+// Maxrss is in bytes on macOS
+const maxrssUnit = 1
@@ -0,0 +1,6 @@
+//go:build !darwin
+
+package monitor
+
+// Maxrss is in kilobytes on Linux and the BSDs
+const maxrssUnit = 1024
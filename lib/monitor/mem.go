@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// MemMonitor measures the memory allocated between two calls, for the
+// measurement of the memory cost of operations. The allocations of all the
+// goroutines are counted.
+type MemMonitor struct {
+	totalAlloc uint64
+}
+
+func NewMemMonitor() *MemMonitor {
+	var m MemMonitor
+	m.totalAlloc = getTotalAlloc()
+	return &m
+}
+
+func (m *MemMonitor) Reset() {
+	m.totalAlloc = getTotalAlloc()
+}
+
+// RecordAndReset returns the number of bytes allocated since the last reset
+func (m *MemMonitor) RecordAndReset() float64 {
+	old := m.totalAlloc
+	m.totalAlloc = getTotalAlloc()
+	return float64(m.totalAlloc - old)
+}
+
+// PeakRSS returns the maximum resident set size of the process so far, in
+// bytes
+func PeakRSS() float64 {
+	rusage := &syscall.Rusage{}
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, rusage); err != nil {
+		return -1
+	}
+	return float64(rusage.Maxrss) * maxrssUnit
+}
+
+func getTotalAlloc() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.TotalAlloc
+}
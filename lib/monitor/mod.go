@@ -3,33 +3,72 @@ package monitor
 import (
 	"log"
 	"syscall"
+	"time"
 )
 
 // Helpers for measurement of CPU cost of operations
 type Monitor struct {
-	cpuTime float64
+	cpuTime  float64
+	wallTime time.Time
+	thread   bool // measure only the calling OS thread
+
+	// memory measurements, only if tracked
+	totalAlloc uint64
+	sampler    *memSampler
 }
 
 func NewMonitor() *Monitor {
 	var m Monitor
-	m.cpuTime = getCPUTime()
+	m.Reset()
+	return &m
+}
+
+// NewThreadMonitor returns a monitor measuring the CPU time of the calling OS
+// thread only, so that concurrent measurements do not interfere. The caller
+// must lock its goroutine to the thread with runtime.LockOSThread. The CPU
+// time of the workers started from the thread with NewWorkers is included if
+// enabled with EnableWorkerCPU. On systems without per-thread accounting, the
+// CPU time of the process is measured.
+func NewThreadMonitor() *Monitor {
+	m := Monitor{thread: true}
+	m.Reset()
 	return &m
 }
 
 func (m *Monitor) Reset() {
-	m.cpuTime = getCPUTime()
+	m.resetMemory()
+	m.cpuTime = m.now()
+	m.wallTime = time.Now()
 }
 
 func (m *Monitor) Record() float64 {
-	return getCPUTime() - m.cpuTime
+	return m.now() - m.cpuTime
 }
 
 func (m *Monitor) RecordAndReset() float64 {
 	old := m.cpuTime
-	m.cpuTime = getCPUTime()
+	m.resetMemory()
+	m.cpuTime = m.now()
+	m.wallTime = time.Now()
 	return m.cpuTime - old
 }
 
+// RecordAndResetAll returns both the CPU and the wall-clock time, in
+// milliseconds, elapsed since the last reset. Network-bound steps take more
+// wall-clock than CPU time.
+func (m *Monitor) RecordAndResetAll() (cpu, wall float64) {
+	oldCPU, oldWall := m.cpuTime, m.wallTime
+	m.Reset()
+	return m.cpuTime - oldCPU, float64(m.wallTime.Sub(oldWall)) / float64(time.Millisecond)
+}
+
+func (m *Monitor) now() float64 {
+	if m.thread {
+		return getThreadCPUTime() + workersCPUTime()
+	}
+	return getCPUTime()
+}
+
 func (m *Monitor) GetCpuTime() float64 {
 	return m.cpuTime
 }
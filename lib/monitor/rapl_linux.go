@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// raplRoot is the powercap directory of the RAPL zones
+var raplRoot = "/sys/class/powercap"
+
+// raplZone is the energy counter of a CPU package
+type raplZone struct {
+	path      string
+	maxEnergy uint64 // in microjoules, where the counter wraps around
+}
+
+// raplZones returns the zones of the CPU packages, whose counters include the
+// ones of their cores and memory subzones
+func raplZones() ([]raplZone, error) {
+	paths, err := filepath.Glob(filepath.Join(raplRoot, "intel-rapl:[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	var zones []raplZone
+	for _, p := range paths {
+		// subzones are named intel-rapl:package:subzone
+		if strings.Count(filepath.Base(p), ":") > 1 {
+			continue
+		}
+		max, err := readUint(filepath.Join(p, "max_energy_range_uj"))
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, raplZone{path: filepath.Join(p, "energy_uj"), maxEnergy: max})
+	}
+	if len(zones) == 0 {
+		return nil, errors.New("no RAPL zone in " + raplRoot)
+	}
+	return zones, nil
+}
+
+// read returns the energy consumed by the zone so far, in microjoules
+func (z raplZone) read() (uint64, error) {
+	return readUint(z.path)
+}
+
+func (z raplZone) mustRead() uint64 {
+	e, err := z.read()
+	if err != nil {
+		log.Fatalln("Couldn't read RAPL energy:", err)
+	}
+	return e
+}
+
+func readUint(path string) (uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(bytes.TrimSpace(b)), 10, 64)
+}
@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeZone writes the counter and the range of a RAPL zone in root
+func writeZone(t *testing.T, root, zone string, energy, maxEnergy uint64) {
+	dir := filepath.Join(root, zone)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "energy_uj"), []byte(strconv.FormatUint(energy, 10)+"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "max_energy_range_uj"), []byte(strconv.FormatUint(maxEnergy, 10)+"\n"), 0644))
+}
+
+func TestEnergyMonitor(t *testing.T) {
+	defer func(root string) { raplRoot = root }(raplRoot)
+	raplRoot = t.TempDir()
+	_, err := NewEnergyMonitor()
+	require.Error(t, err)
+
+	// the subzones are counted by their package
+	writeZone(t, raplRoot, "intel-rapl:0", 1000000, 10000000)
+	writeZone(t, raplRoot, "intel-rapl:0:0", 500000, 10000000)
+	writeZone(t, raplRoot, "intel-rapl:1", 9500000, 10000000)
+	zones, err := raplZones()
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+
+	m, err := NewEnergyMonitor()
+	require.NoError(t, err)
+	require.Zero(t, m.RecordAndReset())
+
+	// the second counter wraps around
+	writeZone(t, raplRoot, "intel-rapl:0", 3000000, 10000000)
+	writeZone(t, raplRoot, "intel-rapl:1", 500000, 10000000)
+	require.InDelta(t, 3, m.RecordAndReset(), 1e-9)
+	require.Zero(t, m.RecordAndReset())
+
+	// a counter that cannot be parsed is an error
+	writeZone(t, raplRoot, "intel-rapl:2", 0, 10000000)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(raplRoot, "intel-rapl:2", "max_energy_range_uj"), []byte("none"), 0644))
+	_, err = NewEnergyMonitor()
+	require.Error(t, err)
+}
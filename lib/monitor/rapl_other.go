@@ -0,0 +1,21 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+type raplZone struct {
+	maxEnergy uint64
+}
+
+func raplZones() ([]raplZone, error) {
+	return nil, errors.New("RAPL energy counters are only available on Linux")
+}
+
+func (z raplZone) read() (uint64, error) {
+	return 0, nil
+}
+
+func (z raplZone) mustRead() uint64 {
+	return 0
+}
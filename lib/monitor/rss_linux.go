@@ -0,0 +1,26 @@
+package monitor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// currentRSS returns the current resident set size of the process in bytes,
+// read from /proc/self/statm
+func currentRSS() uint64 {
+	statm, err := ioutil.ReadFile("/proc/self/statm")
+	if err != nil {
+		return heapRSS()
+	}
+	fields := bytes.Fields(statm)
+	if len(fields) < 2 {
+		return heapRSS()
+	}
+	pages, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return heapRSS()
+	}
+	return pages * uint64(os.Getpagesize())
+}
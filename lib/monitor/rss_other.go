@@ -0,0 +1,9 @@
+//go:build !linux
+
+package monitor
+
+// currentRSS approximates the current resident set size of the process in
+// bytes, as it cannot be read without cgo on this system
+func currentRSS() uint64 {
+	return heapRSS()
+}
@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultSamplingInterval is the interval between two samples of the resident
+// set size used by TrackMemory
+const DefaultSamplingInterval = 10 * time.Millisecond
+
+// memSampler samples the resident set size of the process in the background
+// and keeps the peak since the last reset
+type memSampler struct {
+	mu   sync.Mutex
+	peak uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newMemSampler(interval time.Duration) *memSampler {
+	s := &memSampler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.reset()
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+
+	return s
+}
+
+func (s *memSampler) sample() uint64 {
+	rss := currentRSS()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rss > s.peak {
+		s.peak = rss
+	}
+	return s.peak
+}
+
+func (s *memSampler) reset() {
+	rss := currentRSS()
+	s.mu.Lock()
+	s.peak = rss
+	s.mu.Unlock()
+}
+
+func (s *memSampler) close() {
+	close(s.stop)
+	<-s.done
+}
+
+// TrackMemory starts measuring the memory used between resets: the bytes
+// allocated by all the goroutines and the peak resident set size, sampled
+// every interval. The samples are taken by a goroutine, which must be
+// stopped with StopTracking.
+func (m *Monitor) TrackMemory(interval time.Duration) {
+	if m.sampler != nil {
+		return
+	}
+	m.sampler = newMemSampler(interval)
+	m.totalAlloc = getTotalAlloc()
+}
+
+// StopTracking stops the memory measurements started by TrackMemory
+func (m *Monitor) StopTracking() {
+	if m.sampler == nil {
+		return
+	}
+	m.sampler.close()
+	m.sampler = nil
+}
+
+// RecordAllocs returns the number of bytes allocated since the last reset,
+// zero if the memory is not tracked
+func (m *Monitor) RecordAllocs() float64 {
+	if m.sampler == nil {
+		return 0
+	}
+	return float64(getTotalAlloc() - m.totalAlloc)
+}
+
+// RecordPeakRSS returns the peak resident set size in bytes since the last
+// reset, zero if the memory is not tracked. Unlike PeakRSS, the peak is not
+// the one of the whole life of the process, but it can miss peaks shorter
+// than the sampling interval.
+func (m *Monitor) RecordPeakRSS() float64 {
+	if m.sampler == nil {
+		return 0
+	}
+	return float64(m.sampler.sample())
+}
+
+// resetMemory starts a new memory measurement, if the memory is tracked
+func (m *Monitor) resetMemory() {
+	if m.sampler == nil {
+		return
+	}
+	m.totalAlloc = getTotalAlloc()
+	m.sampler.reset()
+}
+
+// heapRSS approximates the resident set size with the memory obtained by the
+// Go runtime and not released to the system
+func heapRSS() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys - ms.HeapReleased
+}
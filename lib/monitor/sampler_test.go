@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemSampler(t *testing.T) {
+	s := newMemSampler(time.Millisecond)
+	defer s.close()
+
+	first := s.sample()
+	require.Greater(t, first, uint64(0))
+	// the peak includes the buffer, and never decreases until the sampler is
+	// reset
+	buf := make([]byte, 64<<20)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	peak := s.sample()
+	require.GreaterOrEqual(t, peak, uint64(len(buf)))
+	require.GreaterOrEqual(t, peak, first)
+	runtime.KeepAlive(buf)
+	require.GreaterOrEqual(t, s.sample(), peak)
+
+	s.reset()
+	require.Greater(t, s.sample(), uint64(0))
+}
+
+func TestTrackMemory(t *testing.T) {
+	m := NewMonitor()
+	require.Zero(t, m.RecordAllocs())
+	require.Zero(t, m.RecordPeakRSS())
+
+	m.TrackMemory(time.Millisecond)
+	defer m.StopTracking()
+	buf := make([]byte, 1<<20)
+	require.GreaterOrEqual(t, m.RecordAllocs(), float64(len(buf)))
+	require.Greater(t, m.RecordPeakRSS(), float64(0))
+	runtime.KeepAlive(buf)
+
+	m.Reset()
+	require.Less(t, m.RecordAllocs(), float64(len(buf)))
+	m.StopTracking()
+	require.Zero(t, m.RecordPeakRSS())
+}
@@ -0,0 +1,26 @@
+package monitor
+
+import (
+	"log"
+
+	"golang.org/x/sys/unix"
+)
+
+// the CPU time of every thread is accounted separately
+const threadAccounting = true
+
+func gettid() int {
+	return unix.Gettid()
+}
+
+// Returns the sum of the system and the user CPU time used by the calling
+// thread so far.
+func getThreadCPUTime() float64 {
+	rusage := &unix.Rusage{}
+	if err := unix.Getrusage(unix.RUSAGE_THREAD, rusage); err != nil {
+		log.Fatalln("Couldn't get rusage time:", err)
+		return -1
+	}
+	s, u := rusage.Stime, rusage.Utime // system and user time
+	return iiToMS(int64(s.Sec), int64(s.Usec)) + iiToMS(int64(u.Sec), int64(u.Usec))
+}
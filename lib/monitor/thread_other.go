@@ -0,0 +1,15 @@
+//go:build !linux
+
+package monitor
+
+// per-thread accounting is not available, fall back to the process
+func getThreadCPUTime() float64 {
+	return getCPUTime()
+}
+
+// the CPU time of the process already includes the one of the workers
+const threadAccounting = false
+
+func gettid() int {
+	return 0
+}
@@ -0,0 +1,14 @@
+package monitor
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// Region annotates a step of the protocol outside the answers, e.g., the
+// generation of the queries by a client, as a region of the execution trace.
+// The caller must end it on the same goroutine, e.g.,
+// defer monitor.Region("query").End(). It does nothing unless tracing.
+func Region(name string) *trace.Region {
+	return trace.StartRegion(context.Background(), name)
+}
@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the CPU time in nanoseconds of the workers, by OS thread of the goroutine
+// that started them
+var workerCPU struct {
+	enabled  int32
+	byThread sync.Map // int -> *int64
+}
+
+// EnableWorkerCPU enables or disables the accounting of the CPU time of the
+// workers. A thread monitor does not see the CPU time of the goroutines that
+// the measured operation runs in parallel, which is then credited to it by
+// the workers.
+func EnableWorkerCPU(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&workerCPU.enabled, v)
+}
+
+// Workers credits the CPU time of the goroutines of a parallel operation to
+// the thread monitor of the goroutine that started them, so that the CPU time
+// of the operation is the total over all its workers while its wall-clock
+// time is the elapsed one
+type Workers struct {
+	thread int // zero if the accounting is disabled
+}
+
+// NewWorkers must be called by the goroutine starting the workers, before
+// starting them
+func NewWorkers() Workers {
+	if !threadAccounting || atomic.LoadInt32(&workerCPU.enabled) == 0 {
+		return Workers{}
+	}
+	return Workers{thread: gettid()}
+}
+
+// Start must be called by a worker before its work and the returned function
+// after it, e.g., defer w.Start()(). The worker is locked to its OS thread in
+// between.
+func (w Workers) Start() (stop func()) {
+	if w.thread == 0 {
+		return func() {}
+	}
+	runtime.LockOSThread()
+	start := getThreadCPUTime()
+	return func() {
+		cpu := getThreadCPUTime() - start
+		runtime.UnlockOSThread()
+		v, _ := workerCPU.byThread.LoadOrStore(w.thread, new(int64))
+		atomic.AddInt64(v.(*int64), int64(cpu*float64(time.Millisecond)))
+	}
+}
+
+// workersCPUTime returns the CPU time in milliseconds of the workers started
+// from the calling thread so far
+func workersCPUTime() float64 {
+	if !threadAccounting {
+		return 0
+	}
+	v, ok := workerCPU.byThread.Load(gettid())
+	if !ok {
+		return 0
+	}
+	return float64(atomic.LoadInt64(v.(*int64))) / float64(time.Millisecond)
+}
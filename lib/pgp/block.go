@@ -0,0 +1,83 @@
+package pgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/nikirill/go-crypto/openpgp"
+)
+
+// entryLengthSize is the byte size of the big-endian length prefixing every
+// entry of a block
+const entryLengthSize = 4
+
+// RecoveredKey is a key matching a lookup in a block, along with the
+// creation time of its primary key to choose among several matching keys
+type RecoveredKey struct {
+	Entity       *openpgp.Entity
+	CreationTime time.Time
+}
+
+// AppendEntry appends the packet to the block as a length-prefixed entry
+func AppendEntry(block, packet []byte) []byte {
+	var length [entryLengthSize]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(packet)))
+	block = append(block, length[:]...)
+	return append(block, packet...)
+}
+
+// SplitEntries returns the packets of the length-prefixed entries of a block
+func SplitEntries(block []byte) ([][]byte, error) {
+	entries := make([][]byte, 0)
+	for len(block) > 0 {
+		if len(block) < entryLengthSize {
+			return nil, errors.New("truncated entry length in the block")
+		}
+		length := binary.BigEndian.Uint32(block[:entryLengthSize])
+		block = block[entryLengthSize:]
+		if uint64(length) > uint64(len(block)) {
+			return nil, errors.New("entry length exceeds the block")
+		}
+		entries = append(entries, block[:length])
+		block = block[length:]
+	}
+	return entries, nil
+}
+
+// RecoverKeysFromBlock returns all the distinct keys looked up with the id in
+// the given index from a block of length-prefixed entries, the most recent
+// first. Entries that cannot be parsed are skipped, so that a single malformed
+// key does not hide the others of the bucket.
+func RecoverKeysFromBlock(block []byte, index Index, id string) ([]RecoveredKey, error) {
+	entries, err := SplitEntries(block)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]RecoveredKey, 0)
+	seen := make(map[[20]byte]bool)
+	for _, entry := range entries {
+		el, err := openpgp.ReadKeyRing(bytes.NewReader(entry))
+		if err != nil {
+			continue
+		}
+		for _, e := range el {
+			// the same key is stored once per index and the indices may
+			// share a bucket
+			if !index.Matches(e, id) || seen[e.PrimaryKey.Fingerprint] {
+				continue
+			}
+			seen[e.PrimaryKey.Fingerprint] = true
+			keys = append(keys, RecoveredKey{Entity: e, CreationTime: e.PrimaryKey.CreationTime})
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].CreationTime.After(keys[j].CreationTime)
+	})
+
+	return keys, nil
+}
@@ -0,0 +1,60 @@
+package pgp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverKeysFromBlock(t *testing.T) {
+	now := time.Now()
+	block := make([]byte, 0)
+	entities := make([]*openpgp.Entity, 0)
+	// an old and a new key of alice, a key of bob and a malformed entry
+	for i, email := range []string{"alice@example.org", "alice@example.org", "bob@example.org"} {
+		config := &packet.Config{RSABits: 1024, Time: func() time.Time {
+			return now.Add(time.Duration(i) * time.Hour)
+		}}
+		e, err := openpgp.NewEntity("", "", email, config)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, e.Serialize(&buf))
+		block = AppendEntry(block, buf.Bytes())
+		entities = append(entities, e)
+	}
+	block = AppendEntry(block, []byte("not a key"))
+	// the key of bob is also in its fingerprint index
+	var buf bytes.Buffer
+	require.NoError(t, entities[2].Serialize(&buf))
+	block = AppendEntry(block, buf.Bytes())
+
+	entries, err := SplitEntries(block)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+
+	keys, err := RecoverKeysFromBlock(block, IndexEmail, "alice@example.org")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, entities[1].PrimaryKey.Fingerprint, keys[0].Entity.PrimaryKey.Fingerprint)
+	require.Equal(t, entities[0].PrimaryKey.Fingerprint, keys[1].Entity.PrimaryKey.Fingerprint)
+	require.True(t, keys[0].CreationTime.After(keys[1].CreationTime))
+
+	keys, err = RecoverKeysFromBlock(block, IndexEmail, "bob@example.org")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	e, err := RecoverKeyFromBlock(block, "alice@example.org")
+	require.NoError(t, err)
+	require.Equal(t, entities[1].PrimaryKey.Fingerprint, e.PrimaryKey.Fingerprint)
+	_, err = RecoverKeyFromBlock(block, "carol@example.org")
+	require.Error(t, err)
+
+	_, err = SplitEntries(block[:len(block)-1])
+	require.Error(t, err)
+	_, err = SplitEntries([]byte{0, 0})
+	require.Error(t, err)
+}
@@ -0,0 +1,394 @@
+package pgp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/nikirill/go-crypto/openpgp/armor"
+	"github.com/nikirill/go-crypto/openpgp/packet"
+)
+
+// maxPacketLength bounds the length of the packets of a dump, so that the
+// corrupted lengths of malformed packets do not exhaust the memory
+const maxPacketLength = 1 << 20
+
+const (
+	armorBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	armorEnd   = "-----END PGP PUBLIC KEY BLOCK-----"
+)
+
+// errMalformedPacket is the error of the packet headers that cannot be
+// parsed, after which the reader resynchronizes on the next key
+var errMalformedPacket = errors.New("malformed packet header")
+
+// Checkpoint is the position in a dump from which a DumpReader resumes
+// reading, i.e., right after the last key returned
+type Checkpoint struct {
+	// Offset is the byte offset of the next key of a binary dump, or of the
+	// armor block containing it in an armored dump
+	Offset int64
+	// Skip is the number of keys of the armor block at Offset already read
+	Skip int
+	// Armored is true for ASCII-armored dumps
+	Armored bool
+}
+
+// DumpReader reads the keys of a dump one at a time, either a concatenation
+// of binary keyrings, e.g., the SKS dumps, or of ASCII-armored key blocks
+// with any text around them. The keys that cannot be parsed are skipped
+// and counted, so that the malformed packets of real dumps do not abort the
+// import.
+type DumpReader struct {
+	r      *bufio.Reader
+	offset int64
+
+	armored bool
+	// current armor block of an armored dump
+	block      *keySplitter
+	blockStart int64
+	inBlock    int
+
+	// binary dump
+	keys *keySplitter
+
+	skipped int
+}
+
+// NewDumpReader returns a reader of the dump r, positioned at the offset of
+// the checkpoint, resuming from it. The zero checkpoint reads a dump from its
+// start, whose format is then detected.
+func NewDumpReader(r io.Reader, cp Checkpoint) (*DumpReader, error) {
+	d := &DumpReader{r: bufio.NewReaderSize(r, 64*1024), offset: cp.Offset, armored: cp.Armored}
+	if cp.Offset == 0 && cp.Skip == 0 {
+		head, _ := d.r.Peek(4096)
+		d.armored = bytes.Contains(head, []byte(armorBegin))
+	}
+
+	if !d.armored {
+		d.keys = newKeySplitter(d.r, cp.Offset)
+		return d, nil
+	}
+
+	// skip the keys of the current block already read
+	for i := 0; i < cp.Skip; i++ {
+		if _, err := d.nextArmored(); err != nil {
+			if err == io.EOF {
+				return nil, errors.New("the checkpoint is beyond the end of the dump")
+			}
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Next returns the next key of the dump as an entity along with its
+// serialization, skipping the malformed keys, and io.EOF at the end of the
+// dump
+func (d *DumpReader) Next() (*openpgp.Entity, []byte, error) {
+	for {
+		var raw []byte
+		var err error
+		if d.armored {
+			raw, err = d.nextArmored()
+		} else {
+			raw, err = d.keys.next()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		e, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(raw)))
+		if err != nil {
+			d.skipped++
+			continue
+		}
+		return e, raw, nil
+	}
+}
+
+// Checkpoint returns the checkpoint to resume reading after the last key
+// returned
+func (d *DumpReader) Checkpoint() Checkpoint {
+	if d.armored {
+		if d.block == nil {
+			return Checkpoint{Offset: d.offset, Armored: true}
+		}
+		return Checkpoint{Offset: d.blockStart, Skip: d.inBlock, Armored: true}
+	}
+	return Checkpoint{Offset: d.keys.keyStart()}
+}
+
+// Skipped returns the number of keys skipped because they could not be
+// parsed
+func (d *DumpReader) Skipped() int {
+	n := d.skipped
+	if d.keys != nil {
+		n += d.keys.skipped
+	}
+	if d.block != nil {
+		n += d.block.skipped
+	}
+	return n
+}
+
+// nextArmored returns the next raw key of the armor blocks, including the
+// ones that are not parsed as entities
+func (d *DumpReader) nextArmored() ([]byte, error) {
+	for {
+		if d.block != nil {
+			raw, err := d.block.next()
+			if err == nil {
+				d.inBlock++
+				return raw, nil
+			}
+			if err != io.EOF {
+				// the rest of a corrupted block is lost
+				d.skipped++
+			}
+			d.skipped += d.block.skipped
+			d.block = nil
+		}
+
+		// look for the beginning of the next block
+		start, err := d.skipToBlock()
+		if err != nil {
+			return nil, err
+		}
+		body, err := armor.Decode(&blockReader{d: d})
+		if err != nil {
+			d.skipped++
+			continue
+		}
+		d.block = newKeySplitter(body.Body, 0)
+		d.blockStart = start
+		d.inBlock = 0
+	}
+}
+
+// skipToBlock skips the lines before the next armor block and returns its
+// offset, leaving the reader at its first line
+func (d *DumpReader) skipToBlock() (int64, error) {
+	for {
+		line, err := d.r.Peek(len(armorBegin))
+		if err == nil && string(line) == armorBegin {
+			return d.offset, nil
+		}
+		skipped, err := d.r.ReadSlice('\n')
+		d.offset += int64(len(skipped))
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return 0, io.EOF
+		}
+	}
+}
+
+// blockReader reads the lines of the armor block at the position of the
+// dump reader, up to its end line included
+type blockReader struct {
+	d    *DumpReader
+	line []byte
+	done bool
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	if len(b.line) == 0 {
+		if b.done {
+			return 0, io.EOF
+		}
+		line, err := b.d.r.ReadBytes('\n')
+		b.d.offset += int64(len(line))
+		if len(line) == 0 && err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte(armorEnd)) || err != nil {
+			b.done = true
+		}
+		b.line = line
+	}
+	n := copy(p, b.line)
+	b.line = b.line[n:]
+	return n, nil
+}
+
+// keySplitter splits a stream of binary packets into keys, each starting
+// with a public-key packet, without parsing the packets
+type keySplitter struct {
+	r      *bufio.Reader
+	offset int64
+
+	// the public-key packet starting the next key, already read
+	pending      []byte
+	pendingStart int64
+
+	skipped int
+}
+
+func newKeySplitter(r io.Reader, offset int64) *keySplitter {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &keySplitter{r: br, offset: offset, pendingStart: offset}
+}
+
+// keyStart returns the offset of the next key
+func (s *keySplitter) keyStart() int64 {
+	return s.pendingStart
+}
+
+// next returns the packets of the next key, and io.EOF at the end
+func (s *keySplitter) next() ([]byte, error) {
+	key := s.pending
+	s.pending = nil
+	for {
+		start := s.offset
+		tag, p, err := s.readPacket()
+		if err == io.EOF {
+			s.pendingStart = s.offset
+			if key == nil {
+				return nil, io.EOF
+			}
+			return key, nil
+		}
+		if errors.Is(err, errMalformedPacket) {
+			// resume at the next public-key packet, the packets of the
+			// key read so far are returned as is
+			s.skipped++
+			if err := s.resync(); err != nil && err != io.EOF {
+				return nil, err
+			}
+			s.pendingStart = s.offset
+			if key != nil {
+				return key, nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tag == tagPublicKey {
+			if key != nil {
+				s.pending, s.pendingStart = p, start
+				return key, nil
+			}
+			key = p
+			continue
+		}
+		if key == nil {
+			// packets before the first key
+			continue
+		}
+		key = append(key, p...)
+	}
+}
+
+// readPacket reads a whole packet, header included, and returns its tag
+func (s *keySplitter) readPacket() (uint8, []byte, error) {
+	if _, err := s.r.Peek(1); err != nil {
+		return 0, nil, err
+	}
+	tag, headerLen, length, err := parseHeader(s.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	p := make([]byte, headerLen+length)
+	n, err := io.ReadFull(s.r, p)
+	s.offset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		return 0, nil, fmt.Errorf("%w: truncated packet", errMalformedPacket)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return tag, p, nil
+}
+
+// resync skips bytes until the header of a plausible public-key packet
+func (s *keySplitter) resync() error {
+	for {
+		if _, err := s.r.ReadByte(); err != nil {
+			return err
+		}
+		s.offset++
+		tag, headerLen, _, err := parseHeader(s.r)
+		if err != nil || tag != tagPublicKey {
+			continue
+		}
+		b, err := s.r.Peek(headerLen + 1)
+		if err != nil {
+			return err
+		}
+		// version of the public-key packet
+		if v := b[headerLen]; v >= 2 && v <= 5 {
+			return nil
+		}
+	}
+}
+
+// parseHeader peeks the header of the next packet, of the old or new format
+// of Section 4.2 of RFC 4880, and returns its tag, its length and the one of
+// the packet body
+func parseHeader(r *bufio.Reader) (uint8, int, int, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if b[0]&0x80 == 0 {
+		return 0, 0, 0, errMalformedPacket
+	}
+
+	var tag uint8
+	var headerLen, length int
+	if b[0]&0x40 == 0 {
+		// old format
+		tag = (b[0] & 0x3f) >> 2
+		sizes := [...]int{1, 2, 4}
+		lengthType := b[0] & 3
+		if lengthType == 3 {
+			return 0, 0, 0, errMalformedPacket
+		}
+		headerLen = 1 + sizes[lengthType]
+		h, err := r.Peek(headerLen)
+		if err != nil {
+			return 0, 0, 0, errMalformedPacket
+		}
+		for _, c := range h[1:] {
+			length = length<<8 | int(c)
+		}
+	} else {
+		// new format
+		tag = b[0] & 0x3f
+		h, err := r.Peek(2)
+		if err != nil {
+			return 0, 0, 0, errMalformedPacket
+		}
+		switch l0 := int(h[1]); {
+		case l0 < 192:
+			headerLen, length = 2, l0
+		case l0 < 224:
+			if h, err = r.Peek(3); err != nil {
+				return 0, 0, 0, errMalformedPacket
+			}
+			headerLen, length = 3, (l0-192)<<8+int(h[2])+192
+		case l0 == 255:
+			if h, err = r.Peek(6); err != nil {
+				return 0, 0, 0, errMalformedPacket
+			}
+			headerLen = 6
+			length = int(h[2])<<24 | int(h[3])<<16 | int(h[4])<<8 | int(h[5])
+		default:
+			// partial lengths are not allowed in keys
+			return 0, 0, 0, errMalformedPacket
+		}
+	}
+	if length > maxPacketLength {
+		return 0, 0, 0, fmt.Errorf("%w: packet of %d bytes", errMalformedPacket, length)
+	}
+
+	return tag, headerLen, length, nil
+}
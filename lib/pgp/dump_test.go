@@ -0,0 +1,136 @@
+package pgp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/nikirill/go-crypto/openpgp/armor"
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/require"
+)
+
+func dumpKeys(t *testing.T, n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		e, err := openpgp.NewEntity("", "", fmt.Sprintf("user%d@example.org", i), &packet.Config{RSABits: 1024})
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		require.NoError(t, e.Serialize(buf))
+		keys[i] = buf.Bytes()
+	}
+	return keys
+}
+
+func readEmails(t *testing.T, d *DumpReader) []string {
+	emails := make([]string, 0)
+	for {
+		e, _, err := d.Next()
+		if err == io.EOF {
+			return emails
+		}
+		require.NoError(t, err)
+		emails = append(emails, PrimaryEmail(e))
+	}
+}
+
+func TestDumpReaderBinary(t *testing.T) {
+	keys := dumpKeys(t, 4)
+	dump := new(bytes.Buffer)
+	dump.Write(keys[0])
+	dump.Write([]byte{0x01, 0x02, 0x03}) // garbage
+	dump.Write(keys[1])
+	// unparsable primary key, with a wrong version
+	corrupted := append([]byte{}, keys[2]...)
+	corrupted[2] = 9
+	dump.Write(corrupted)
+	dump.Write([]byte{0xc6, 0xff, 0x7f, 0xff}) // huge packet length
+	dump.Write(keys[3])
+
+	d, err := NewDumpReader(bytes.NewReader(dump.Bytes()), Checkpoint{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user0@example.org", "user1@example.org", "user3@example.org"}, readEmails(t, d))
+	require.NotZero(t, d.Skipped())
+
+	// resume after the first key
+	d, err = NewDumpReader(bytes.NewReader(dump.Bytes()), Checkpoint{})
+	require.NoError(t, err)
+	_, raw, err := d.Next()
+	require.NoError(t, err)
+	require.Equal(t, keys[0], raw)
+	cp := d.Checkpoint()
+	d, err = NewDumpReader(bytes.NewReader(dump.Bytes()[cp.Offset:]), cp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user1@example.org", "user3@example.org"}, readEmails(t, d))
+}
+
+func TestDumpReaderArmored(t *testing.T) {
+	keys := dumpKeys(t, 3)
+	armored := func(keys ...[]byte) string {
+		buf := new(bytes.Buffer)
+		w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+		require.NoError(t, err)
+		for _, k := range keys {
+			w.Write(k)
+		}
+		require.NoError(t, w.Close())
+		return buf.String()
+	}
+	dump := "keys exported on Monday\n" + armored(keys[0], keys[1]) + "\n\nnext block:\n" +
+		"-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nnot base64\n-----END PGP PUBLIC KEY BLOCK-----\n" +
+		armored(keys[2]) + "\n"
+
+	d, err := NewDumpReader(bytes.NewReader([]byte(dump)), Checkpoint{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user0@example.org", "user1@example.org", "user2@example.org"}, readEmails(t, d))
+	require.Equal(t, 1, d.Skipped())
+
+	// resume in the middle of the first block
+	d, err = NewDumpReader(bytes.NewReader([]byte(dump)), Checkpoint{})
+	require.NoError(t, err)
+	_, _, err = d.Next()
+	require.NoError(t, err)
+	cp := d.Checkpoint()
+	require.Equal(t, Checkpoint{Offset: int64(len("keys exported on Monday\n")), Skip: 1, Armored: true}, cp)
+	d, err = NewDumpReader(bytes.NewReader([]byte(dump)[cp.Offset:]), cp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user1@example.org", "user2@example.org"}, readEmails(t, d))
+}
+
+func TestImportDumps(t *testing.T) {
+	dir := t.TempDir()
+	keys := dumpKeys(t, 3)
+	files := []string{filepath.Join(dir, "dump-0.pgp"), filepath.Join(dir, "dump-1.pgp")}
+	require.NoError(t, ioutil.WriteFile(files[0], append(append([]byte{}, keys[0]...), keys[1]...), 0644))
+	require.NoError(t, ioutil.WriteFile(files[1], append([]byte("garbage"), keys[2]...), 0644))
+
+	out := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	stats, err := ImportDumps(files, out, statePath)
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.Imported)
+	require.Equal(t, 1, stats.Malformed)
+
+	chunks, err := GetAllFiles(out)
+	require.NoError(t, err)
+	imported, err := LoadKeysFromDisk(chunks)
+	require.NoError(t, err)
+	require.Len(t, imported, 3)
+	for _, k := range imported {
+		el, err := openpgp.ReadKeyRing(bytes.NewReader(k.Packet))
+		require.NoError(t, err)
+		require.Equal(t, k.ID, PrimaryEmail(el[0]))
+	}
+
+	// a finished import is not imported again
+	stats, err = ImportDumps(files, out, statePath)
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.Imported)
+	chunks2, err := GetAllFiles(out)
+	require.NoError(t, err)
+	require.Equal(t, chunks, chunks2)
+}
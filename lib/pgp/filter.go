@@ -0,0 +1,242 @@
+package pgp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/si-co/vpir-code/lib/logging"
+)
+
+// Filter selects the packets stripped from the keys before they are embedded
+// in a db. Filters are combined with a bitwise or.
+type Filter int
+
+const (
+	// StripPhotoIDs strips the user attributes, i.e., the photo IDs, and
+	// their signatures
+	StripPhotoIDs Filter = 1 << iota
+	// StripThirdPartySignatures strips the certifications of the user IDs
+	// not issued by the primary key
+	StripThirdPartySignatures
+	// StripExpiredSubkeys strips the subkeys whose binding signature states
+	// an expiration date in the past
+	StripExpiredSubkeys
+
+	StripAll = StripPhotoIDs | StripThirdPartySignatures | StripExpiredSubkeys
+)
+
+// tags of the packets delimiting the components of a transferable public key,
+// as defined in Section 11.1 of RFC 4880
+const (
+	tagSignature     = 2
+	tagPublicKey     = 6
+	tagUserID        = 13
+	tagPublicSubkey  = 14
+	tagUserAttribute = 17
+)
+
+var filterNames = map[string]Filter{
+	"photos":     StripPhotoIDs,
+	"thirdparty": StripThirdPartySignatures,
+	"expired":    StripExpiredSubkeys,
+	"all":        StripAll,
+}
+
+// ParseFilter returns the filter of a comma-separated list of filter names:
+// photos, thirdparty, expired or all. The empty string strips nothing.
+func ParseFilter(s string) (Filter, error) {
+	var f Filter
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		filter, ok := filterNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown key filter: %s", name)
+		}
+		f |= filter
+	}
+	return f, nil
+}
+
+// SanitizeKeys returns the keys with the packets selected by the filters
+// stripped, at the current time. The keys are returned as is without filters.
+func SanitizeKeys(keys []*Key, filters ...Filter) ([]*Key, error) {
+	var f Filter
+	for _, filter := range filters {
+		f |= filter
+	}
+	if f == 0 {
+		return keys, nil
+	}
+
+	now := time.Now()
+	sanitized := make([]*Key, len(keys))
+	before, after := 0, 0
+	for i, key := range keys {
+		p, err := SanitizeKey(key.Packet, f, now)
+		if err != nil {
+			return nil, fmt.Errorf("sanitizing key %s: %v", key.ID, err)
+		}
+		sanitized[i] = &Key{ID: key.ID, Packet: p}
+		before += len(key.Packet)
+		after += len(p)
+	}
+	if before > 0 {
+		logging.Default().Info("key filters applied", logging.F("stripped", before-after), logging.F("bytes", before),
+			logging.F("percent", fmt.Sprintf("%.1f", 100*float64(before-after)/float64(before))))
+	}
+
+	return sanitized, nil
+}
+
+// SanitizeKey returns the serialized key with the packets selected by the
+// filter stripped. The packets are processed without being parsed as an
+// entity, so that the filter applies whatever packets the key contains.
+func SanitizeKey(key []byte, f Filter, now time.Time) ([]byte, error) {
+	components, err := splitComponents(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) == 0 || components[0][0].Tag != tagPublicKey {
+		return nil, fmt.Errorf("the key does not start with a primary key")
+	}
+	primary, err := components[0][0].Parse()
+	if err != nil {
+		return nil, err
+	}
+	pk, ok := primary.(*packet.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the key does not start with a primary key")
+	}
+
+	out := new(bytes.Buffer)
+	for _, c := range components {
+		switch c[0].Tag {
+		case tagUserAttribute:
+			if f&StripPhotoIDs != 0 {
+				continue
+			}
+			if f&StripThirdPartySignatures != 0 {
+				c = selfSignedOnly(c, pk.KeyId)
+			}
+		case tagUserID:
+			if f&StripThirdPartySignatures != 0 {
+				c = selfSignedOnly(c, pk.KeyId)
+			}
+		case tagPublicSubkey:
+			if f&StripExpiredSubkeys != 0 && subkeyExpired(c, now) {
+				continue
+			}
+		}
+		for _, op := range c {
+			if err := op.Serialize(out); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// splitComponents splits the packets of a key into its components, i.e., the
+// primary key, user IDs, user attributes and subkeys, each followed by its
+// signatures
+func splitComponents(key []byte) ([][]*packet.OpaquePacket, error) {
+	components := make([][]*packet.OpaquePacket, 0)
+	r := packet.NewOpaqueReader(bytes.NewReader(key))
+	for {
+		op, err := r.Next()
+		if err == io.EOF {
+			return components, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch op.Tag {
+		case tagPublicKey, tagUserID, tagUserAttribute, tagPublicSubkey:
+			components = append(components, []*packet.OpaquePacket{op})
+		default:
+			if len(components) == 0 {
+				return nil, fmt.Errorf("packet with tag %d before the primary key", op.Tag)
+			}
+			components[len(components)-1] = append(components[len(components)-1], op)
+		}
+	}
+}
+
+// selfSignedOnly returns the component without the signatures issued by
+// other keys than the primary one. Signatures that cannot be parsed are kept.
+func selfSignedOnly(c []*packet.OpaquePacket, keyID uint64) []*packet.OpaquePacket {
+	kept := c[:1:1]
+	for _, op := range c[1:] {
+		if op.Tag == tagSignature {
+			if issuer, ok := signatureIssuer(op); ok && issuer != keyID {
+				continue
+			}
+		}
+		kept = append(kept, op)
+	}
+	return kept
+}
+
+// signatureIssuer returns the key ID of the issuer of a signature packet, and
+// false if it is unknown
+func signatureIssuer(op *packet.OpaquePacket) (uint64, bool) {
+	p, err := op.Parse()
+	if err != nil {
+		return 0, false
+	}
+	switch sig := p.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId == nil {
+			return 0, false
+		}
+		return *sig.IssuerKeyId, true
+	case *packet.SignatureV3:
+		return sig.IssuerKeyId, true
+	default:
+		return 0, false
+	}
+}
+
+// subkeyExpired returns true if the most recent binding signature of the
+// subkey sets an expiration date, counted from the creation of the subkey,
+// before now
+func subkeyExpired(c []*packet.OpaquePacket, now time.Time) bool {
+	p, err := c[0].Parse()
+	if err != nil {
+		return false
+	}
+	subkey, ok := p.(*packet.PublicKey)
+	if !ok {
+		return false
+	}
+	var binding *packet.Signature
+	for _, op := range c[1:] {
+		if op.Tag != tagSignature {
+			continue
+		}
+		p, err := op.Parse()
+		if err != nil {
+			continue
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok || sig.SigType != packet.SigTypeSubkeyBinding {
+			continue
+		}
+		if binding == nil || sig.CreationTime.After(binding.CreationTime) {
+			binding = sig
+		}
+	}
+	if binding == nil || binding.KeyLifetimeSecs == nil || *binding.KeyLifetimeSecs == 0 {
+		return false
+	}
+	expiry := subkey.CreationTime.Add(time.Duration(*binding.KeyLifetimeSecs) * time.Second)
+	return now.After(expiry)
+}
@@ -0,0 +1,85 @@
+package pgp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter("photos, expired")
+	require.NoError(t, err)
+	require.Equal(t, StripPhotoIDs|StripExpiredSubkeys, f)
+	f, err = ParseFilter("")
+	require.NoError(t, err)
+	require.Equal(t, Filter(0), f)
+	f, err = ParseFilter("all")
+	require.NoError(t, err)
+	require.Equal(t, StripAll, f)
+	_, err = ParseFilter("photos,signatures")
+	require.Error(t, err)
+}
+
+func TestSanitizeKey(t *testing.T) {
+	config := &packet.Config{RSABits: 1024}
+	alice, err := openpgp.NewEntity("Alice", "", "alice@example.org", config)
+	require.NoError(t, err)
+	bob, err := openpgp.NewEntity("Bob", "", "bob@example.org", config)
+	require.NoError(t, err)
+
+	// bob certifies the identity of alice, whose subkey expires in an hour
+	for name := range alice.Identities {
+		require.NoError(t, alice.SignIdentity(name, bob, config))
+	}
+	lifetime := uint32(3600)
+	alice.Subkeys[0].Sig.KeyLifetimeSecs = &lifetime
+	require.NoError(t, alice.Subkeys[0].Sig.SignKey(alice.Subkeys[0].PublicKey, alice.PrivateKey, config))
+
+	// the photo ID follows the key
+	var buf bytes.Buffer
+	require.NoError(t, alice.Serialize(&buf))
+	photo := packet.NewUserAttribute(&packet.OpaqueSubpacket{SubType: 1, Contents: make([]byte, 1024)})
+	require.NoError(t, photo.Serialize(&buf))
+	key := buf.Bytes()
+
+	now := time.Now()
+	unfiltered, err := SanitizeKey(key, 0, now)
+	require.NoError(t, err)
+	require.Equal(t, key, unfiltered)
+
+	photoless, err := SanitizeKey(key, StripPhotoIDs, now)
+	require.NoError(t, err)
+	require.Less(t, len(photoless), len(key)-1024)
+
+	for _, c := range []struct {
+		filter     Filter
+		now        time.Time
+		signatures int
+		subkeys    int
+	}{
+		{StripPhotoIDs, now, 1, 1},
+		{StripThirdPartySignatures, now, 0, 1},
+		{StripExpiredSubkeys, now, 1, 1},
+		{StripExpiredSubkeys, now.Add(2 * time.Hour), 1, 0},
+		{StripAll, now.Add(2 * time.Hour), 0, 0},
+	} {
+		sanitized, err := SanitizeKey(key, c.filter, c.now)
+		require.NoError(t, err)
+		el, err := openpgp.ReadKeyRing(bytes.NewReader(sanitized))
+		require.NoError(t, err)
+		require.Len(t, el, 1)
+		require.Equal(t, alice.PrimaryKey.Fingerprint, el[0].PrimaryKey.Fingerprint)
+		require.Equal(t, PrimaryEmail(alice), PrimaryEmail(el[0]))
+		require.Len(t, el[0].PrimaryIdentity().Signatures, c.signatures)
+		require.Len(t, el[0].Subkeys, c.subkeys)
+	}
+
+	keys, err := SanitizeKeys([]*Key{{ID: "alice@example.org", Packet: key}}, StripPhotoIDs, StripThirdPartySignatures)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Less(t, len(keys[0].Packet), len(photoless))
+}
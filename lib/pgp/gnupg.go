@@ -0,0 +1,35 @@
+package pgp
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DefaultGPG is the GnuPG executable used when none is given
+const DefaultGPG = "gpg"
+
+// ImportIntoKeyring imports the armored keys into the GnuPG keyring of the
+// user with gpg --import, or into the keyring of homedir if not empty, and
+// returns the status output of gpg
+func ImportIntoKeyring(armored, gpg, homedir string) (string, error) {
+	if gpg == "" {
+		gpg = DefaultGPG
+	}
+	args := []string{"--batch", "--no-tty", "--import"}
+	if homedir != "" {
+		args = append([]string{"--homedir", homedir}, args...)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(gpg, args...)
+	cmd.Stdin = strings.NewReader(armored)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return out.String(), fmt.Errorf("%s --import failed: %v: %s", gpg, err, strings.TrimSpace(out.String()))
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,99 @@
+package pgp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nikirill/go-crypto/openpgp"
+)
+
+// HKPSearchEmail returns the normalized email address searched by an HKP
+// lookup. GnuPG sends it either as is, with a leading '=' for exact matches,
+// or as a user ID of the form "Name <email>".
+func HKPSearchEmail(search string) (string, error) {
+	search = strings.TrimPrefix(strings.TrimSpace(search), "=")
+	if i := strings.LastIndex(search, "<"); i >= 0 {
+		search = strings.TrimSuffix(search[i+1:], ">")
+	}
+	if !strings.Contains(search, "@") {
+		return "", errors.New("the search is not an email address")
+	}
+	return NormalizeEmail(search), nil
+}
+
+// HKPKeyID returns the upper-cased hex fingerprint or key ID of an HKP search
+// of the form 0x..., and false if the search is not a key ID
+func HKPKeyID(search string) (string, bool) {
+	search = strings.TrimSpace(search)
+	if !strings.HasPrefix(search, "0x") && !strings.HasPrefix(search, "0X") {
+		return "", false
+	}
+	id := search[2:]
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	switch len(id) {
+	case 8, 16, 40: // short and long key IDs, v4 fingerprint
+		return strings.ToUpper(id), true
+	default:
+		return "", false
+	}
+}
+
+// MatchesKeyID returns true if the hex fingerprint or key ID identifies the
+// primary key of the entity
+func MatchesKeyID(e *openpgp.Entity, id string) bool {
+	return strings.HasSuffix(Fingerprint(e), strings.ToUpper(id))
+}
+
+// HKPIndex returns the machine-readable HKP index of the entities, as defined
+// in Section 5.2 of draft-shaw-openpgp-hkp
+func HKPIndex(entities ...*openpgp.Entity) string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "info:1:%d\n", len(entities))
+	for _, e := range entities {
+		pk := e.PrimaryKey
+		bits, _ := pk.BitLength()
+		created := pk.CreationTime.Unix()
+		expired := ""
+		if exp, _ := isExpired(e); exp {
+			expired = "e"
+		}
+		fmt.Fprintf(b, "pub:%X:%d:%d:%d:%s:%s\n", pk.Fingerprint, pk.PubKeyAlgo, bits, created,
+			keyExpiration(e, e.PrimaryIdentity()), expired)
+		for _, id := range e.Identities {
+			fmt.Fprintf(b, "uid:%s:%d:%s:\n", hkpEscape(id.Name),
+				id.SelfSignature.CreationTime.Unix(), keyExpiration(e, id))
+		}
+	}
+	return b.String()
+}
+
+// keyExpiration returns the expiration date of the primary key according to
+// the self-signature of the identity, in seconds since the epoch, or the empty
+// string if the key does not expire
+func keyExpiration(e *openpgp.Entity, id *openpgp.Identity) string {
+	expiry, ok := keyExpiry(e, id)
+	if !ok {
+		return ""
+	}
+	return strconv.FormatInt(expiry.Unix(), 10)
+}
+
+// hkpEscape escapes the colons, the percent signs and the non-printable
+// characters of a user ID
+func hkpEscape(s string) string {
+	b := new(strings.Builder)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == ':' || c == '%' || c < 0x20 || c >= 0x7f {
+			fmt.Fprintf(b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package pgp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHKPSearchEmail(t *testing.T) {
+	for _, search := range []string{"alice@example.org", "=Alice@Example.org", "Alice: Smith <alice@example.org>"} {
+		email, err := HKPSearchEmail(search)
+		require.NoError(t, err, search)
+		require.Equal(t, "alice@example.org", email)
+	}
+	_, err := HKPSearchEmail("Alice")
+	require.Error(t, err)
+}
+
+func TestHKPIndex(t *testing.T) {
+	e, err := openpgp.NewEntity("Alice: Smith", "", "alice@example.org", &packet.Config{RSABits: 1024})
+	require.NoError(t, err)
+	fingerprint := strings.ToUpper(hex.EncodeToString(e.PrimaryKey.Fingerprint[:]))
+
+	for _, search := range []string{"0x" + fingerprint, "0x" + fingerprint[24:], "0x" + strings.ToLower(fingerprint[32:])} {
+		id, ok := HKPKeyID(search)
+		require.True(t, ok, search)
+		require.True(t, MatchesKeyID(e, id), search)
+	}
+	_, ok := HKPKeyID("0x123")
+	require.False(t, ok)
+	_, ok = HKPKeyID("alice@example.org")
+	require.False(t, ok)
+
+	lines := strings.Split(strings.TrimSpace(HKPIndex(e)), "\n")
+	require.Equal(t, []string{
+		"info:1:1",
+		fmt.Sprintf("pub:%s:1:1024:%d::", fingerprint, e.PrimaryKey.CreationTime.Unix()),
+		fmt.Sprintf("uid:Alice%%3A Smith <alice@example.org>:%d::", e.PrimaryIdentity().SelfSignature.CreationTime.Unix()),
+	}, lines)
+}
@@ -0,0 +1,171 @@
+package pgp
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/si-co/vpir-code/lib/logging"
+)
+
+// importChunkKeys is the number of keys per chunk written by ImportDumps,
+// after which the import can be resumed
+const importChunkKeys = 10000
+
+// ImportStats counts the keys of an import
+type ImportStats struct {
+	// keys imported in the chunks
+	Imported int
+	// keys without email, revoked or too large
+	Filtered int
+	// keys that could not be parsed
+	Malformed int
+}
+
+// importState is the state of an import saved after each chunk
+type importState struct {
+	// index of the dump being imported
+	File       int
+	Checkpoint Checkpoint
+	// number of the next chunk
+	Chunk int
+	Stats ImportStats
+}
+
+// ImportDumps imports the keys of the dumps, binary or ASCII-armored, into
+// chunks of serialized keys in outDir, i.e., import-XXX.pgp files in the
+// format of the chunks of the SKS dumps. The state of the import is saved in
+// statePath after every chunk, and an interrupted import resumes from it. The
+// keys that cannot be parsed are skipped, as well as the keys without email,
+// revoked or larger than the size limit of the keys.
+func ImportDumps(files []string, outDir, statePath string) (*ImportStats, error) {
+	state := new(importState)
+	data, err := ioutil.ReadFile(statePath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("could not parse the import state: %v", err)
+		}
+		logging.Default().Info("resuming the import", logging.F("file", state.File), logging.F("offset", state.Checkpoint.Offset))
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	for ; state.File < len(files); state.File++ {
+		if err := importDump(files[state.File], outDir, statePath, state); err != nil {
+			return nil, fmt.Errorf("importing %s: %v", files[state.File], err)
+		}
+		state.Checkpoint = Checkpoint{}
+	}
+	if err := saveImportState(statePath, state); err != nil {
+		return nil, err
+	}
+
+	return &state.Stats, nil
+}
+
+// importDump imports the keys of a dump from the checkpoint of the state
+func importDump(file, outDir, statePath string, state *importState) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(state.Checkpoint.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	logging.Default().Info("importing", logging.F("file", file))
+
+	d, err := NewDumpReader(f, state.Checkpoint)
+	if err != nil {
+		return err
+	}
+	malformed := state.Stats.Malformed
+
+	var out *os.File
+	var encoder *gob.Encoder
+	var buf bytes.Buffer
+	inChunk := 0
+	closeChunk := func() error {
+		if out == nil {
+			return nil
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		out = nil
+		state.Chunk++
+		state.Checkpoint = d.Checkpoint()
+		state.Stats.Malformed = malformed + d.Skipped()
+		return saveImportState(statePath, state)
+	}
+
+	for {
+		e, _, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		email := PrimaryEmail(e)
+		if len(e.Revocations) > 0 || email == "" {
+			state.Stats.Filtered++
+			continue
+		}
+		buf.Reset()
+		if err := e.Serialize(&buf); err != nil || buf.Len() > keySizeLimit {
+			state.Stats.Filtered++
+			continue
+		}
+
+		if out == nil {
+			// a chunk interrupted before its state was saved is overwritten
+			name := filepath.Join(outDir, fmt.Sprintf("import-%03d.pgp", state.Chunk))
+			out, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			encoder = gob.NewEncoder(out)
+			inChunk = 0
+		}
+		if err := encoder.Encode(&Key{ID: email, Packet: buf.Bytes()}); err != nil {
+			return err
+		}
+		state.Stats.Imported++
+		inChunk++
+		if inChunk == importChunkKeys {
+			if err := closeChunk(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := closeChunk(); err != nil {
+		return err
+	}
+	state.Stats.Malformed = malformed + d.Skipped()
+	logging.Default().Info("keys imported so far", logging.F("imported", state.Stats.Imported),
+		logging.F("filtered", state.Stats.Filtered), logging.F("malformed", state.Stats.Malformed))
+
+	return nil
+}
+
+// saveImportState atomically saves the state of an import
+func saveImportState(path string, state *importState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
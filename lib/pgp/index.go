@@ -0,0 +1,113 @@
+package pgp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nikirill/go-crypto/openpgp"
+)
+
+// Index selects the hash table in which a key is looked up. Besides the
+// primary email, the keys are indexed by the full fingerprint and by the
+// 64-bit key ID of their primary key.
+type Index int
+
+const (
+	IndexEmail Index = iota
+	IndexFingerprint
+	IndexKeyID
+)
+
+const (
+	fingerprintPrefix = "fpr:"
+	keyIDPrefix       = "kid:"
+)
+
+func (i Index) String() string {
+	switch i {
+	case IndexEmail:
+		return "email"
+	case IndexFingerprint:
+		return "fingerprint"
+	case IndexKeyID:
+		return "keyid"
+	default:
+		return fmt.Sprintf("Index(%d)", int(i))
+	}
+}
+
+// HashID returns the string hashed to find the bucket of the id in the given
+// index. Emails are hashed as is, to keep the layout of the email-only dbs.
+func HashID(index Index, id string) string {
+	switch index {
+	case IndexFingerprint:
+		return fingerprintPrefix + strings.ToUpper(id)
+	case IndexKeyID:
+		return keyIDPrefix + strings.ToUpper(id)
+	default:
+		return id
+	}
+}
+
+// ParseSearch returns the index and the normalized id of a search, which is
+// either a 0x-prefixed v4 fingerprint or 64-bit key ID, or an email
+func ParseSearch(search string) (Index, string) {
+	if id, ok := HKPKeyID(search); ok {
+		switch len(id) {
+		case 40:
+			return IndexFingerprint, id
+		case 16:
+			return IndexKeyID, id
+		}
+	}
+	return IndexEmail, NormalizeEmail(search)
+}
+
+// Fingerprint returns the upper-cased hex fingerprint of the primary key
+func Fingerprint(e *openpgp.Entity) string {
+	return strings.ToUpper(hex.EncodeToString(e.PrimaryKey.Fingerprint[:]))
+}
+
+// KeyID returns the upper-cased hex 64-bit key ID of the primary key
+func KeyID(e *openpgp.Entity) string {
+	return fmt.Sprintf("%016X", e.PrimaryKey.KeyId)
+}
+
+// Matches returns true if the entity is the one looked up with the id in
+// the given index
+func (i Index) Matches(e *openpgp.Entity, id string) bool {
+	switch i {
+	case IndexFingerprint:
+		return Fingerprint(e) == strings.ToUpper(id)
+	case IndexKeyID:
+		return KeyID(e) == strings.ToUpper(id)
+	default:
+		return PrimaryEmail(e) == id
+	}
+}
+
+// IndexKeys returns the keys followed by one entry per key in the
+// fingerprint index and one in the key ID index, so that embedding the
+// result in a hash table builds all the indices at once. The packets are
+// shared with the input keys.
+func IndexKeys(keys []*Key) ([]*Key, error) {
+	indexed := make([]*Key, 0, 3*len(keys))
+	indexed = append(indexed, keys...)
+	for _, key := range keys {
+		el, err := openpgp.ReadKeyRing(bytes.NewReader(key.Packet))
+		if err != nil {
+			return nil, err
+		}
+		if len(el) != 1 {
+			return nil, errors.New("more than one openpgp entity in a key packet")
+		}
+		indexed = append(indexed,
+			&Key{ID: HashID(IndexFingerprint, Fingerprint(el[0])), Packet: key.Packet},
+			&Key{ID: HashID(IndexKeyID, KeyID(el[0])), Packet: key.Packet},
+		)
+	}
+	return indexed, nil
+}
@@ -0,0 +1,61 @@
+package pgp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSearch(t *testing.T) {
+	e, err := openpgp.NewEntity("Alice", "", "alice@example.org", &packet.Config{RSABits: 1024})
+	require.NoError(t, err)
+	fingerprint := Fingerprint(e)
+
+	index, id := ParseSearch("0x" + fingerprint)
+	require.Equal(t, IndexFingerprint, index)
+	require.Equal(t, fingerprint, id)
+	index, id = ParseSearch("0x" + fingerprint[24:])
+	require.Equal(t, IndexKeyID, index)
+	require.Equal(t, KeyID(e), id)
+	index, id = ParseSearch("Alice@Example.org")
+	require.Equal(t, IndexEmail, index)
+	require.Equal(t, "alice@example.org", id)
+}
+
+func TestIndexKeys(t *testing.T) {
+	block := make([]byte, 0)
+	keys := make([]*Key, 0)
+	entities := make([]*openpgp.Entity, 0)
+	for _, email := range []string{"alice@example.org", "bob@example.org"} {
+		e, err := openpgp.NewEntity("", "", email, &packet.Config{RSABits: 1024})
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, e.Serialize(&buf))
+		keys = append(keys, &Key{ID: email, Packet: buf.Bytes()})
+		entities = append(entities, e)
+		block = AppendEntry(block, buf.Bytes())
+	}
+
+	indexed, err := IndexKeys(keys)
+	require.NoError(t, err)
+	require.Len(t, indexed, 3*len(keys))
+
+	ids := make(map[string]bool)
+	for _, key := range indexed {
+		ids[key.ID] = true
+	}
+	for _, e := range entities {
+		require.True(t, ids[HashID(IndexEmail, PrimaryEmail(e))])
+		require.True(t, ids[HashID(IndexFingerprint, Fingerprint(e))])
+		require.True(t, ids[HashID(IndexKeyID, KeyID(e))])
+
+		for index, id := range map[Index]string{IndexEmail: PrimaryEmail(e), IndexFingerprint: Fingerprint(e), IndexKeyID: KeyID(e)} {
+			recovered, err := RecoverKeyFromBlockByIndex(block, index, id)
+			require.NoError(t, err)
+			require.Equal(t, e.PrimaryKey.Fingerprint, recovered.PrimaryKey.Fingerprint)
+		}
+	}
+}
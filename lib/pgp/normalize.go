@@ -0,0 +1,48 @@
+package pgp
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeEmail returns the canonical form of an email address, under which
+// the keys are both indexed and looked up. The address is stripped of the
+// surrounding spaces, angle brackets and mailto: scheme, its local part is
+// case-folded in Unicode normalization form C and its domain is normalized
+// with NormalizeDomain. Without an '@', the whole input is normalized as a
+// local part, so that fragments of addresses match the normalized ones.
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	if len(email) >= len("mailto:") && strings.EqualFold(email[:len("mailto:")], "mailto:") {
+		email = email[len("mailto:"):]
+	}
+	email = strings.TrimSuffix(strings.TrimPrefix(email, "<"), ">")
+
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return normalizeText(email)
+	}
+	return normalizeText(email[:i]) + "@" + NormalizeDomain(email[i+1:])
+}
+
+// NormalizeDomain returns the canonical form of a domain name: the Unicode
+// form of its IDNA labels, so that punycode and internationalized spellings
+// coincide, case-folded in normalization form C and without the trailing dot
+// of fully qualified names. Labels that are not valid punycode are kept as is.
+func NormalizeDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if u, err := idna.ToUnicode(domain); err == nil {
+		domain = u
+	}
+	return normalizeText(domain)
+}
+
+// normalizeText case-folds the text and puts it in normalization form C. The
+// text is composed before and after folding, since folding may decompose
+// characters.
+func normalizeText(s string) string {
+	return norm.NFC.String(cases.Fold().String(norm.NFC.String(strings.TrimSpace(s))))
+}
@@ -0,0 +1,32 @@
+package pgp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	require.Equal(t, "alice@example.org", NormalizeEmail(" <Alice@Example.ORG.> "))
+	require.Equal(t, "alice@example.org", NormalizeEmail("mailto:alice@example.org"))
+
+	// composed and decomposed forms of the same address
+	require.Equal(t, NormalizeEmail("josé@example.org"), NormalizeEmail("JOSÉ@example.org"))
+	// full case folding
+	require.Equal(t, "strasse@example.org", NormalizeEmail("Straße@example.org"))
+
+	// punycode and internationalized domains
+	require.Equal(t, "info@bücher.example", NormalizeEmail("info@xn--bcher-kva.example"))
+	require.Equal(t, "info@bücher.example", NormalizeEmail("info@BÜCHER.example"))
+
+	// fragments without '@' are normalized as local parts
+	require.Equal(t, "josé", NormalizeEmail("JOSÉ"))
+
+	// ASCII addresses are only lower-cased, to keep the existing dbs
+	require.Equal(t, "a.b+c@d.e", NormalizeEmail("A.B+C@D.E"))
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	require.Equal(t, "example.org", NormalizeDomain("Example.Org."))
+	require.Equal(t, "bücher.example", NormalizeDomain("xn--bcher-kva.example"))
+}
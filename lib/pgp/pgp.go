@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,6 +17,8 @@ import (
 	"github.com/nikirill/go-crypto/openpgp/armor"
 
 	"github.com/nikirill/go-crypto/openpgp"
+
+	"github.com/si-co/vpir-code/lib/logging"
 )
 
 const (
@@ -52,7 +53,7 @@ func AnalyzeKeyDump(files []string) (map[string]*openpgp.Entity, error) {
 			saveKeyIfValid(e, keys)
 		}
 		if err = in.Close(); err != nil {
-			log.Printf("Unable to close file %s\n", file)
+			logging.Default().Warn("unable to close file", logging.F("file", file))
 			return nil, err
 		}
 	}
@@ -228,8 +229,8 @@ func isExpired(e *openpgp.Entity) (expired bool, email string) {
 	return
 }
 
-// Returns the lower-cased email from the primary identity, or
-// if it is empty, the alphabetically first non-empty lower-cased email
+// Returns the normalized email from the primary identity, or
+// if it is empty, the alphabetically first non-empty normalized email
 func PrimaryEmail(e *openpgp.Entity) string {
 	email := e.PrimaryIdentity().UserId.Email
 	// iterate over identities in search for the email if
@@ -241,29 +242,31 @@ func PrimaryEmail(e *openpgp.Entity) string {
 			}
 		}
 	}
-	return strings.ToLower(email)
+	return NormalizeEmail(email)
 }
 
 // Returns an Entity with the given email in the primary ID from a block of
 // serialized entities.
 func RecoverKeyFromBlock(block []byte, email string) (*openpgp.Entity, error) {
-	// parse the input bytes as a key ring
-	reader := bytes.NewReader(block)
-	el, err := openpgp.ReadKeyRing(reader)
+	return RecoverKeyFromBlockByIndex(block, IndexEmail, email)
+}
+
+// Returns the most recent Entity looked up with the id in the given index from
+// a block of serialized entities. RecoverKeysFromBlock returns all of them.
+func RecoverKeyFromBlockByIndex(block []byte, index Index, id string) (*openpgp.Entity, error) {
+	keys, err := RecoverKeysFromBlock(block, index, id)
 	if err != nil {
 		return nil, err
 	}
-	// go over PGP entities and find the key with the given email as one of the ids
-	for _, e := range el {
-		if PrimaryEmail(e) == email {
-			return e, nil
-		}
+	if len(keys) == 0 {
+		logging.Default().Debug("key not in the block", logging.F("index", index), logging.F("id", id), logging.F("block", hex.EncodeToString(block)))
+		return nil, fmt.Errorf("no key with the given %s id is found", index)
 	}
-	log.Printf("The key with user email %s is not the block %s\n", email, hex.EncodeToString(block))
-	return nil, errors.New("no key with the given email id is found")
+	return keys[0].Entity, nil
 }
 
-func ArmorKey(entity *openpgp.Entity) (string, error) {
+// ArmorKey returns the armored serialization of the entities
+func ArmorKey(entities ...*openpgp.Entity) (string, error) {
 	var err error
 	buf := new(bytes.Buffer)
 	headers := map[string]string{"Comment": "Retrieved with Authenticated PIR"}
@@ -271,9 +274,10 @@ func ArmorKey(entity *openpgp.Entity) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	err = entity.Serialize(arm)
-	if err != nil {
-		return "", err
+	for _, entity := range entities {
+		if err = entity.Serialize(arm); err != nil {
+			return "", err
+		}
 	}
 	if err = arm.Close(); err != nil {
 		return "", err
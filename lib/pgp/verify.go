@@ -0,0 +1,100 @@
+package pgp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nikirill/go-crypto/openpgp"
+)
+
+// Verdict is the trust verdict on a retrieved key
+type Verdict int
+
+const (
+	// Valid keys are self-signed by the queried identity, neither expired
+	// nor revoked
+	Valid Verdict = iota
+	// Expired keys are past the expiration date of their self-signature
+	Expired
+	// Revoked keys carry a revocation signature of their primary key
+	Revoked
+	// Unverified keys have no valid self-signature binding the queried
+	// identity to the primary key
+	Unverified
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Valid:
+		return "valid"
+	case Expired:
+		return "expired"
+	case Revoked:
+		return "revoked"
+	case Unverified:
+		return "unverified"
+	default:
+		return fmt.Sprintf("Verdict(%d)", int(v))
+	}
+}
+
+// VerifyKey returns the trust verdict on a key retrieved with the id in the
+// given index at time now, along with a human-readable reason. The identity
+// checked for a valid self-signature is the searched email, or the primary
+// identity for a fingerprint or key ID search.
+func VerifyKey(e *openpgp.Entity, index Index, id string, now time.Time) (Verdict, string) {
+	if !index.Matches(e, id) {
+		return Unverified, fmt.Sprintf("the key does not match the %s %s", index, id)
+	}
+
+	if len(e.Revocations) > 0 {
+		reason := "no reason given"
+		if r := e.Revocations[0]; r.RevocationReasonText != "" {
+			reason = r.RevocationReasonText
+		}
+		return Revoked, fmt.Sprintf("revoked on %s: %s", e.Revocations[0].CreationTime.Format(time.RFC3339), reason)
+	}
+
+	identity := e.PrimaryIdentity()
+	if index == IndexEmail {
+		identity = nil
+		for _, i := range e.Identities {
+			if normalizedEmail(i) == id {
+				identity = i
+				break
+			}
+		}
+	}
+	if identity == nil || identity.SelfSignature == nil {
+		return Unverified, "no self-signed identity"
+	}
+	if err := e.PrimaryKey.VerifyUserIdSignature(identity.Name, e.PrimaryKey, identity.SelfSignature); err != nil {
+		return Unverified, fmt.Sprintf("invalid self-signature of %s: %v", identity.Name, err)
+	}
+
+	if expiry, ok := keyExpiry(e, identity); ok {
+		if now.After(expiry) {
+			return Expired, fmt.Sprintf("self-signed by %s, expired on %s", identity.Name, expiry.Format(time.RFC3339))
+		}
+		return Valid, fmt.Sprintf("self-signed by %s, expires on %s", identity.Name, expiry.Format(time.RFC3339))
+	}
+	return Valid, fmt.Sprintf("self-signed by %s, does not expire", identity.Name)
+}
+
+// normalizedEmail returns the normalized email of the identity
+func normalizedEmail(i *openpgp.Identity) string {
+	if i.UserId == nil {
+		return ""
+	}
+	return NormalizeEmail(i.UserId.Email)
+}
+
+// keyExpiry returns the expiration date of the primary key according to the
+// self-signature of the identity, and false if the key does not expire
+func keyExpiry(e *openpgp.Entity, id *openpgp.Identity) (time.Time, bool) {
+	sig := id.SelfSignature
+	if sig == nil || sig.KeyLifetimeSecs == nil || *sig.KeyLifetimeSecs == 0 {
+		return time.Time{}, false
+	}
+	return e.PrimaryKey.CreationTime.Add(time.Duration(*sig.KeyLifetimeSecs) * time.Second), true
+}
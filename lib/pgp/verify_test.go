@@ -0,0 +1,45 @@
+package pgp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nikirill/go-crypto/openpgp"
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyKey(t *testing.T) {
+	config := &packet.Config{RSABits: 1024}
+	e, err := openpgp.NewEntity("Alice", "", "alice@example.org", config)
+	require.NoError(t, err)
+	now := time.Now()
+
+	v, _ := VerifyKey(e, IndexEmail, "alice@example.org", now)
+	require.Equal(t, Valid, v)
+	v, _ = VerifyKey(e, IndexFingerprint, Fingerprint(e), now)
+	require.Equal(t, Valid, v)
+	v, _ = VerifyKey(e, IndexEmail, "bob@example.org", now)
+	require.Equal(t, Unverified, v)
+
+	// the key expires in an hour
+	lifetime := uint32(3600)
+	id := e.PrimaryIdentity()
+	id.SelfSignature.KeyLifetimeSecs = &lifetime
+	require.NoError(t, id.SelfSignature.SignUserId(id.UserId.Id, e.PrimaryKey, e.PrivateKey, config))
+	v, _ = VerifyKey(e, IndexKeyID, KeyID(e), now)
+	require.Equal(t, Valid, v)
+	v, reason := VerifyKey(e, IndexKeyID, KeyID(e), now.Add(2*time.Hour))
+	require.Equal(t, Expired, v, reason)
+
+	// a forged identity invalidates the self-signature
+	name := id.Name
+	id.Name = "Mallory <alice@example.org>"
+	v, _ = VerifyKey(e, IndexEmail, "alice@example.org", now)
+	require.Equal(t, Unverified, v)
+	id.Name = name
+
+	e.Revocations = append(e.Revocations, &packet.Signature{SigType: packet.SigTypeKeyRevocation, CreationTime: now})
+	v, _ = VerifyKey(e, IndexEmail, "alice@example.org", now)
+	require.Equal(t, Revoked, v)
+}
@@ -0,0 +1,101 @@
+package pgp
+
+import (
+	"crypto/sha1"
+	"errors"
+	"strings"
+)
+
+// wkdPrefix is the prefix of the paths of the Web Key Directory
+// (draft-koch-openpgp-webkey-service)
+const wkdPrefix = "/.well-known/openpgpkey/"
+
+// zbase32Alphabet is the alphabet of the z-base-32 encoding used for the
+// hashed local parts of the WKD paths
+const zbase32Alphabet = "ybndrfg8ejkmcpqxot1uwisza345h769"
+
+// WKDRequest is a WKD lookup of the key of an email address
+type WKDRequest struct {
+	// Domain is the lower-cased domain of the email, the one of the host for
+	// the direct method
+	Domain string
+	// Hash is the z-base-32 encoded SHA-1 of the lower-cased local part
+	Hash string
+	// Policy is true for the requests of the policy file of the domain
+	Policy bool
+}
+
+// WKDHash returns the z-base-32 encoded SHA-1 of the lower-cased local part
+// of an email, as in the WKD paths
+func WKDHash(localPart string) string {
+	h := sha1.Sum([]byte(strings.ToLower(localPart)))
+	return zbase32(h[:])
+}
+
+// ParseWKDPath parses the path of a WKD request, either of the advanced
+// method, /.well-known/openpgpkey/<domain>/hu/<hash>, or of the direct
+// method, /.well-known/openpgpkey/hu/<hash>, for which the domain is the
+// host of the request
+func ParseWKDPath(path, host string) (*WKDRequest, error) {
+	if !strings.HasPrefix(path, wkdPrefix) {
+		return nil, errors.New("not a WKD path")
+	}
+	parts := strings.Split(strings.TrimPrefix(path, wkdPrefix), "/")
+	domain := host
+	if len(parts) > 0 && parts[0] != "hu" && parts[0] != "policy" {
+		domain, parts = parts[0], parts[1:]
+	}
+	if i := strings.LastIndex(domain, ":"); i >= 0 {
+		domain = domain[:i]
+	}
+	if domain == "" {
+		return nil, errors.New("no domain in the WKD request")
+	}
+	domain = NormalizeDomain(domain)
+
+	switch {
+	case len(parts) == 1 && parts[0] == "policy":
+		return &WKDRequest{Domain: domain, Policy: true}, nil
+	case len(parts) == 2 && parts[0] == "hu" && len(parts[1]) == 32:
+		for _, c := range parts[1] {
+			if !strings.ContainsRune(zbase32Alphabet, c) {
+				return nil, errors.New("the WKD hash is not z-base-32 encoded")
+			}
+		}
+		return &WKDRequest{Domain: domain, Hash: parts[1]}, nil
+	default:
+		return nil, errors.New("malformed WKD path")
+	}
+}
+
+// Email returns the email of the request given the local part sent in the l
+// parameter, which must match the hash of the path
+func (r *WKDRequest) Email(localPart string) (string, error) {
+	if localPart == "" {
+		return "", errors.New("the local part is required in the l parameter")
+	}
+	if WKDHash(localPart) != r.Hash {
+		return "", errors.New("the local part does not match the hash")
+	}
+	return NormalizeEmail(localPart + "@" + r.Domain), nil
+}
+
+// zbase32 returns the z-base-32 encoding of data, as defined in
+// Section 3 of draft-koch-openpgp-webkey-service
+func zbase32(data []byte) string {
+	b := new(strings.Builder)
+	var buf uint
+	bits := 0
+	for _, d := range data {
+		buf = buf<<8 | uint(d)
+		bits += 8
+		for bits >= 5 {
+			b.WriteByte(zbase32Alphabet[(buf>>uint(bits-5))&31])
+			bits -= 5
+		}
+	}
+	if bits > 0 {
+		b.WriteByte(zbase32Alphabet[(buf<<uint(5-bits))&31])
+	}
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package pgp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWKDHash(t *testing.T) {
+	// test vector of draft-koch-openpgp-webkey-service
+	require.Equal(t, "iy9q119eutrkn8s1mk4r39qejnbu3n5q", WKDHash("Joe.Doe"))
+}
+
+func TestParseWKDPath(t *testing.T) {
+	hash := WKDHash("alice")
+
+	r, err := ParseWKDPath("/.well-known/openpgpkey/hu/"+hash, "Example.org:443")
+	require.NoError(t, err)
+	require.Equal(t, &WKDRequest{Domain: "example.org", Hash: hash}, r)
+	email, err := r.Email("Alice")
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.org", email)
+	_, err = r.Email("bob")
+	require.Error(t, err)
+	_, err = r.Email("")
+	require.Error(t, err)
+
+	r, err = ParseWKDPath("/.well-known/openpgpkey/example.org/hu/"+hash, "openpgpkey.example.org")
+	require.NoError(t, err)
+	require.Equal(t, &WKDRequest{Domain: "example.org", Hash: hash}, r)
+
+	r, err = ParseWKDPath("/.well-known/openpgpkey/example.org/policy", "openpgpkey.example.org")
+	require.NoError(t, err)
+	require.True(t, r.Policy)
+
+	for _, path := range []string{"/pks/lookup", "/.well-known/openpgpkey/hu/", "/.well-known/openpgpkey/hu/" + hash[1:] + "0"} {
+		_, err = ParseWKDPath(path, "example.org")
+		require.Error(t, err, path)
+	}
+}
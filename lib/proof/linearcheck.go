@@ -0,0 +1,194 @@
+package proof
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// linearDST separates the hashing of the generators to the group
+var linearDST = []byte("vpir-linear-proof")
+
+// LinearCheckProver is the prover of a linear check of the answers of the
+// linear schemes, in which the answer is the product modulo 2^32 of the
+// query, a vector of uint32, by the db, a matrix of bytes, such as SimplePIR.
+// The digest is made of the Pedersen commitments to the rows of the db, with
+// generators hashed to the group. Since the commitments are linearly
+// homomorphic, the combination of the commitments with the query is the
+// commitment to the product over the integers, so that the proof is the
+// vector of the carries of the reduction modulo 2^32 of every column.
+//
+// The check is not succinct: the digest has one commitment per row, the
+// proof is as long as the answer, and the client verifies it with a number of
+// additions in the group linear in the number of rows and columns of the db.
+type LinearCheckProver struct {
+	db     *matrix.MatrixBytes
+	digest []byte
+}
+
+// LinearCheckVerifier is the verifier of the linear check of the answers of
+// the linear schemes
+type LinearCheckVerifier struct {
+	g           group.Group
+	rows        int
+	cols        int
+	generators  []group.Element
+	commitments []group.Element
+}
+
+// NewLinearCheckProver returns the prover of the answers of the db, computing
+// the commitments to its rows in the given group
+func NewLinearCheckProver(g group.Group, db *matrix.MatrixBytes) (*LinearCheckProver, error) {
+	// the sum of the products of the query with a column fits in 64 bits
+	if db.Rows() >= 1<<24 {
+		return nil, fmt.Errorf("db of %d rows", db.Rows())
+	}
+	generators := linearGenerators(g, db.Cols())
+	values := make([]uint64, db.Cols())
+	digest := make([]byte, 0)
+	for i := 0; i < db.Rows(); i++ {
+		for j := range values {
+			values[j] = uint64(db.Get(i, j))
+		}
+		c, err := combine(g, generators, values, 8).MarshalBinaryCompress()
+		if err != nil {
+			return nil, err
+		}
+		digest = append(digest, c...)
+	}
+
+	return &LinearCheckProver{db: db, digest: digest}, nil
+}
+
+// Digest returns the commitments to the rows of the db
+func (p *LinearCheckProver) Digest() []byte {
+	return p.digest
+}
+
+// Prove returns the carries of the product of the query by the db, without
+// checking that the answer is the product
+func (p *LinearCheckProver) Prove(query, answer []byte) ([]byte, error) {
+	q, err := decodeVector(query, p.db.Rows())
+	if err != nil {
+		return nil, err
+	}
+	carries := make([]uint32, p.db.Cols())
+	for j := range carries {
+		var sum uint64
+		for i, qi := range q {
+			sum += uint64(qi) * uint64(p.db.Get(i, j))
+		}
+		carries[j] = uint32(sum >> 32)
+	}
+	return utils.Uint32SliceToByteSlice(carries), nil
+}
+
+// NewLinearCheckVerifier returns the verifier of the answers of a db of the
+// given dimensions from its digest
+func NewLinearCheckVerifier(g group.Group, rows, cols int, digest []byte) (*LinearCheckVerifier, error) {
+	if rows >= 1<<24 {
+		return nil, fmt.Errorf("db of %d rows", rows)
+	}
+	size := elementSize(g)
+	if len(digest) != rows*size {
+		return nil, errors.New("malformed digest")
+	}
+	commitments := make([]group.Element, rows)
+	for i := range commitments {
+		commitments[i] = g.NewElement()
+		if err := commitments[i].UnmarshalBinary(digest[i*size : (i+1)*size]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &LinearCheckVerifier{
+		g:           g,
+		rows:        rows,
+		cols:        cols,
+		generators:  linearGenerators(g, cols),
+		commitments: commitments,
+	}, nil
+}
+
+// Verify checks that the combination of the commitments to the rows with the
+// query is the commitment to the answer lifted to the integers with the
+// carries of the proof
+func (v *LinearCheckVerifier) Verify(query, answer, proof []byte) error {
+	q, err := decodeVector(query, v.rows)
+	if err != nil {
+		return err
+	}
+	a, err := decodeVector(answer, v.cols)
+	if err != nil {
+		return err
+	}
+	if len(proof) != 4*v.cols {
+		return ErrInvalidProof
+	}
+	carries := utils.ByteSliceToUint32Slice(proof)
+
+	qs := make([]uint64, len(q))
+	for i := range q {
+		qs[i] = uint64(q[i])
+	}
+	products := make([]uint64, len(a))
+	for j := range a {
+		products[j] = uint64(carries[j])<<32 | uint64(a[j])
+	}
+
+	lhs := combine(v.g, v.commitments, qs, 32)
+	rhs := combine(v.g, v.generators, products, 64)
+	if !lhs.IsEqual(rhs) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// decodeVector returns the entries of the row vector of the given length
+// encoded as a matrix
+func decodeVector(in []byte, n int) ([]uint32, error) {
+	if len(in) != 8+4*n {
+		return nil, errors.New("malformed vector")
+	}
+	if binary.BigEndian.Uint32(in) != 1 || int(binary.BigEndian.Uint32(in[4:])) != n {
+		return nil, errors.New("malformed vector")
+	}
+	return utils.ByteSliceToUint32Slice(in[8:]), nil
+}
+
+// linearGenerators returns the generators of the commitments to the rows of
+// the db, one per column
+func linearGenerators(g group.Group, n int) []group.Element {
+	out := make([]group.Element, n)
+	index := make([]byte, 4)
+	for j := range out {
+		binary.BigEndian.PutUint32(index, uint32(j))
+		out[j] = g.HashToElement(index, linearDST)
+	}
+	return out
+}
+
+// combine returns the sum of the elements multiplied by the values of the
+// given number of bits, with additions and doublings only, since the
+// encoding of the scalars depends on the group
+func combine(g group.Group, elements []group.Element, values []uint64, bits int) group.Element {
+	acc := g.Identity()
+	for b := bits - 1; b >= 0; b-- {
+		acc.Dbl(acc)
+		for k, e := range elements {
+			if values[k]>>b&1 == 1 {
+				acc.Add(acc, e)
+			}
+		}
+	}
+	return acc
+}
+
+func elementSize(g group.Group) int {
+	e, _ := g.Generator().MarshalBinaryCompress()
+	return len(e)
+}
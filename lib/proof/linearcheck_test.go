@@ -0,0 +1,49 @@
+package proof
+
+import (
+	"testing"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinearCheck(t *testing.T) {
+	for _, g := range []group.Group{group.P256, group.Ristretto255} {
+		rnd := utils.RandomPRG()
+		db := matrix.NewBytes(20, 30)
+		data := make([]byte, db.Len())
+		_, err := rnd.Read(data)
+		require.NoError(t, err)
+		for i, b := range data {
+			db.SetData(i, b)
+		}
+
+		p, err := NewLinearCheckProver(g, db)
+		require.NoError(t, err)
+		v, err := NewLinearCheckVerifier(g, db.Rows(), db.Cols(), p.Digest())
+		require.NoError(t, err)
+
+		entries := make([]byte, 4*db.Rows())
+		_, err = rnd.Read(entries)
+		require.NoError(t, err)
+		q := matrix.MatrixToBytes(matrix.NewWithData(1, db.Rows(), utils.ByteSliceToUint32Slice(entries)))
+		a := matrix.MatrixToBytes(matrix.BytesMul(matrix.BytesToMatrix(q), db))
+		pi, err := p.Prove(q, a)
+		require.NoError(t, err)
+		require.NoError(t, v.Verify(q, a, pi))
+
+		// wrong answer or carries
+		tampered := append([]byte{}, a...)
+		tampered[10] ^= 1
+		require.ErrorIs(t, v.Verify(q, tampered, pi), ErrInvalidProof)
+		tampered = append([]byte{}, pi...)
+		tampered[0] ^= 1
+		require.ErrorIs(t, v.Verify(q, a, tampered), ErrInvalidProof)
+
+		// malformed digest
+		_, err = NewLinearCheckVerifier(g, db.Rows()+1, db.Cols(), p.Digest())
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,33 @@
+// Package proof defines the proof systems with which a server shows that its
+// answer to a query was computed correctly with respect to a digest of the db
+// published beforehand, so that the answers of a single server, which cannot
+// be cross-checked against the ones of other servers, are verifiable by the
+// clients. A proof system is plugged into a scheme through the Prover, held
+// by the server, and the Verifier, built by the clients from the digest
+// only; the encoding of the queries and the answers is the one of the
+// scheme. The only system is the linear check of the answers of SimplePIR,
+// LinearCheckProver, which is not succinct: no succinct proof of the answers
+// is implemented, e.g., for the answers of the complex queries.
+package proof
+
+import "errors"
+
+// ErrInvalidProof is returned by the verifiers when the answer does not
+// match the query and the digest
+var ErrInvalidProof = errors.New("invalid proof")
+
+// Prover computes the proofs of the answers of a server
+type Prover interface {
+	// Digest returns the public digest of the db against which the proofs
+	// are verified
+	Digest() []byte
+	// Prove returns the proof that the answer is the one to the query
+	Prove(query, answer []byte) ([]byte, error)
+}
+
+// Verifier checks the proofs of the answers against the digest of the db
+type Verifier interface {
+	// Verify returns ErrInvalidProof if the proof does not show that the
+	// answer is the one to the query
+	Verify(query, answer, proof []byte) error
+}
@@ -1,13 +1,12 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.25.0
+// 	protoc-gen-go v1.26.0
 // 	protoc        v3.15.6
 // source: lib/proto/vpir.proto
 
 package proto
 
 import (
-	proto "github.com/golang/protobuf/proto"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -21,16 +20,114 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// This is a compile-time assertion that a sufficiently up-to-date version
-// of the legacy proto package is being used.
-const _ = proto.ProtoPackageIsVersion4
+type Target int32
+
+const (
+	Target_TARGET_USER_ID       Target = 0
+	Target_TARGET_CREATION_TIME Target = 1
+	Target_TARGET_PUB_KEY_ALGO  Target = 2
+	Target_TARGET_KEY_SIZE      Target = 3
+)
+
+// Enum value maps for Target.
+var (
+	Target_name = map[int32]string{
+		0: "TARGET_USER_ID",
+		1: "TARGET_CREATION_TIME",
+		2: "TARGET_PUB_KEY_ALGO",
+		3: "TARGET_KEY_SIZE",
+	}
+	Target_value = map[string]int32{
+		"TARGET_USER_ID":       0,
+		"TARGET_CREATION_TIME": 1,
+		"TARGET_PUB_KEY_ALGO":  2,
+		"TARGET_KEY_SIZE":      3,
+	}
+)
+
+func (x Target) Enum() *Target {
+	p := new(Target)
+	*p = x
+	return p
+}
+
+func (x Target) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Target) Descriptor() protoreflect.EnumDescriptor {
+	return file_lib_proto_vpir_proto_enumTypes[0].Descriptor()
+}
+
+func (Target) Type() protoreflect.EnumType {
+	return &file_lib_proto_vpir_proto_enumTypes[0]
+}
+
+func (x Target) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Target.Descriptor instead.
+func (Target) EnumDescriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{0}
+}
+
+type Aggregate int32
+
+const (
+	Aggregate_AGGREGATE_COUNT          Aggregate = 0
+	Aggregate_AGGREGATE_SUM_YEARS      Aggregate = 1
+	Aggregate_AGGREGATE_SUM_BIT_LENGTH Aggregate = 2
+)
+
+// Enum value maps for Aggregate.
+var (
+	Aggregate_name = map[int32]string{
+		0: "AGGREGATE_COUNT",
+		1: "AGGREGATE_SUM_YEARS",
+		2: "AGGREGATE_SUM_BIT_LENGTH",
+	}
+	Aggregate_value = map[string]int32{
+		"AGGREGATE_COUNT":          0,
+		"AGGREGATE_SUM_YEARS":      1,
+		"AGGREGATE_SUM_BIT_LENGTH": 2,
+	}
+)
+
+func (x Aggregate) Enum() *Aggregate {
+	p := new(Aggregate)
+	*p = x
+	return p
+}
+
+func (x Aggregate) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Aggregate) Descriptor() protoreflect.EnumDescriptor {
+	return file_lib_proto_vpir_proto_enumTypes[1].Descriptor()
+}
+
+func (Aggregate) Type() protoreflect.EnumType {
+	return &file_lib_proto_vpir_proto_enumTypes[1]
+}
+
+func (x Aggregate) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Aggregate.Descriptor instead.
+func (Aggregate) EnumDescriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{1}
+}
 
 type QueryRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Query []byte `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Query     []byte `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Predicate bool   `protobuf:"varint,2,opt,name=predicate,proto3" json:"predicate,omitempty"`
 }
 
 func (x *QueryRequest) Reset() {
@@ -72,6 +169,13 @@ func (x *QueryRequest) GetQuery() []byte {
 	return nil
 }
 
+func (x *QueryRequest) GetPredicate() bool {
+	if x != nil {
+		return x.Predicate
+	}
+	return false
+}
+
 type QueryResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -123,6 +227,8 @@ type DatabaseInfoRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	KnownTreeSize uint64 `protobuf:"varint,1,opt,name=knownTreeSize,proto3" json:"knownTreeSize,omitempty"`
 }
 
 func (x *DatabaseInfoRequest) Reset() {
@@ -157,17 +263,27 @@ func (*DatabaseInfoRequest) Descriptor() ([]byte, []int) {
 	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{2}
 }
 
+func (x *DatabaseInfoRequest) GetKnownTreeSize() uint64 {
+	if x != nil {
+		return x.KnownTreeSize
+	}
+	return 0
+}
+
 type DatabaseInfoResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	NumRows     uint32 `protobuf:"varint,1,opt,name=numRows,proto3" json:"numRows,omitempty"`
-	NumColumns  uint32 `protobuf:"varint,2,opt,name=numColumns,proto3" json:"numColumns,omitempty"`
-	BlockLength uint32 `protobuf:"varint,3,opt,name=blockLength,proto3" json:"blockLength,omitempty"`
-	PirType     string `protobuf:"bytes,4,opt,name=pirType,proto3" json:"pirType,omitempty"`
-	Root        []byte `protobuf:"bytes,5,opt,name=root,proto3" json:"root,omitempty"`
-	ProofLen    uint32 `protobuf:"varint,6,opt,name=proofLen,proto3" json:"proofLen,omitempty"`
+	NumRows      uint32 `protobuf:"varint,1,opt,name=numRows,proto3" json:"numRows,omitempty"`
+	NumColumns   uint32 `protobuf:"varint,2,opt,name=numColumns,proto3" json:"numColumns,omitempty"`
+	BlockLength  uint32 `protobuf:"varint,3,opt,name=blockLength,proto3" json:"blockLength,omitempty"`
+	PirType      string `protobuf:"bytes,4,opt,name=pirType,proto3" json:"pirType,omitempty"`
+	Root         []byte `protobuf:"bytes,5,opt,name=root,proto3" json:"root,omitempty"`
+	ProofLen     uint32 `protobuf:"varint,6,opt,name=proofLen,proto3" json:"proofLen,omitempty"`
+	HashTableLen uint32 `protobuf:"varint,7,opt,name=hashTableLen,proto3" json:"hashTableLen,omitempty"`
+	Transparency []byte `protobuf:"bytes,8,opt,name=transparency,proto3" json:"transparency,omitempty"`
+	TokenKey     []byte `protobuf:"bytes,9,opt,name=tokenKey,proto3" json:"tokenKey,omitempty"`
 }
 
 func (x *DatabaseInfoResponse) Reset() {
@@ -244,119 +360,1657 @@ func (x *DatabaseInfoResponse) GetProofLen() uint32 {
 	return 0
 }
 
-var File_lib_proto_vpir_proto protoreflect.FileDescriptor
+func (x *DatabaseInfoResponse) GetHashTableLen() uint32 {
+	if x != nil {
+		return x.HashTableLen
+	}
+	return 0
+}
 
-var file_lib_proto_vpir_proto_rawDesc = []byte{
-	0x0a, 0x14, 0x6c, 0x69, 0x62, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x70, 0x69, 0x72,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x24, 0x0a,
-	0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
-	0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x71, 0x75,
-	0x65, 0x72, 0x79, 0x22, 0x27, 0x0a, 0x0d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x61, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x22, 0x15, 0x0a, 0x13,
-	0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x22, 0xbc, 0x01, 0x0a, 0x14, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65,
-	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07,
-	0x6e, 0x75, 0x6d, 0x52, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x6e,
-	0x75, 0x6d, 0x52, 0x6f, 0x77, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x6e, 0x75, 0x6d, 0x43, 0x6f, 0x6c,
-	0x75, 0x6d, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x6e, 0x75, 0x6d, 0x43,
-	0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x4c,
-	0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x62, 0x6c, 0x6f,
-	0x63, 0x6b, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x69, 0x72, 0x54,
-	0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x69, 0x72, 0x54, 0x79,
-	0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c,
-	0x52, 0x04, 0x72, 0x6f, 0x6f, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x4c,
-	0x65, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x4c,
-	0x65, 0x6e, 0x32, 0x87, 0x01, 0x0a, 0x04, 0x56, 0x50, 0x49, 0x52, 0x12, 0x49, 0x0a, 0x0c, 0x44,
-	0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1a, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
-	0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x34, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12,
-	0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x51, 0x75, 0x65,
-	0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x2c, 0x5a, 0x2a,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x69, 0x2d, 0x63, 0x6f,
-	0x2f, 0x76, 0x70, 0x69, 0x72, 0x2d, 0x63, 0x6f, 0x64, 0x65, 0x2f, 0x6c, 0x69, 0x62, 0x2f, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+func (x *DatabaseInfoResponse) GetTransparency() []byte {
+	if x != nil {
+		return x.Transparency
+	}
+	return nil
 }
 
-var (
-	file_lib_proto_vpir_proto_rawDescOnce sync.Once
-	file_lib_proto_vpir_proto_rawDescData = file_lib_proto_vpir_proto_rawDesc
-)
+func (x *DatabaseInfoResponse) GetTokenKey() []byte {
+	if x != nil {
+		return x.TokenKey
+	}
+	return nil
+}
 
-func file_lib_proto_vpir_proto_rawDescGZIP() []byte {
-	file_lib_proto_vpir_proto_rawDescOnce.Do(func() {
-		file_lib_proto_vpir_proto_rawDescData = protoimpl.X.CompressGZIP(file_lib_proto_vpir_proto_rawDescData)
-	})
-	return file_lib_proto_vpir_proto_rawDescData
+type ReloadRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Files uint32 `protobuf:"varint,1,opt,name=files,proto3" json:"files,omitempty"`
 }
 
-var file_lib_proto_vpir_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
-var file_lib_proto_vpir_proto_goTypes = []interface{}{
-	(*QueryRequest)(nil),         // 0: proto.QueryRequest
-	(*QueryResponse)(nil),        // 1: proto.QueryResponse
-	(*DatabaseInfoRequest)(nil),  // 2: proto.DatabaseInfoRequest
-	(*DatabaseInfoResponse)(nil), // 3: proto.DatabaseInfoResponse
+func (x *ReloadRequest) Reset() {
+	*x = ReloadRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
-var file_lib_proto_vpir_proto_depIdxs = []int32{
-	2, // 0: proto.VPIR.DatabaseInfo:input_type -> proto.DatabaseInfoRequest
-	0, // 1: proto.VPIR.Query:input_type -> proto.QueryRequest
-	3, // 2: proto.VPIR.DatabaseInfo:output_type -> proto.DatabaseInfoResponse
-	1, // 3: proto.VPIR.Query:output_type -> proto.QueryResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+func (x *ReloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_lib_proto_vpir_proto_init() }
-func file_lib_proto_vpir_proto_init() {
-	if File_lib_proto_vpir_proto != nil {
-		return
+func (*ReloadRequest) ProtoMessage() {}
+
+func (x *ReloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_lib_proto_vpir_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadRequest.ProtoReflect.Descriptor instead.
+func (*ReloadRequest) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ReloadRequest) GetFiles() uint32 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_lib_proto_vpir_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{5}
+}
+
+type StatusResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Scheme   string `protobuf:"bytes,1,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Epoch    uint64 `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Digest   []byte `protobuf:"bytes,3,opt,name=digest,proto3" json:"digest,omitempty"`
+	TreeSize uint64 `protobuf:"varint,4,opt,name=treeSize,proto3" json:"treeSize,omitempty"`
+	TreeRoot []byte `protobuf:"bytes,5,opt,name=treeRoot,proto3" json:"treeRoot,omitempty"`
+	LoadedAt int64  `protobuf:"varint,6,opt,name=loadedAt,proto3" json:"loadedAt,omitempty"`
+	Files    uint32 `protobuf:"varint,7,opt,name=files,proto3" json:"files,omitempty"`
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_lib_proto_vpir_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DatabaseInfoRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StatusResponse) GetScheme() string {
+	if x != nil {
+		return x.Scheme
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetDigest() []byte {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetTreeSize() uint64 {
+	if x != nil {
+		return x.TreeSize
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetTreeRoot() []byte {
+	if x != nil {
+		return x.TreeRoot
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetLoadedAt() int64 {
+	if x != nil {
+		return x.LoadedAt
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetFiles() uint32 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+type SelfCheckRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SelfCheckRequest) Reset() {
+	*x = SelfCheckRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SelfCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SelfCheckRequest) ProtoMessage() {}
+
+func (x *SelfCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_lib_proto_vpir_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DatabaseInfoResponse); i {
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SelfCheckRequest.ProtoReflect.Descriptor instead.
+func (*SelfCheckRequest) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{7}
+}
+
+type SelfCheckResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Failures []string `protobuf:"bytes,1,rep,name=failures,proto3" json:"failures,omitempty"`
+}
+
+func (x *SelfCheckResponse) Reset() {
+	*x = SelfCheckResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SelfCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SelfCheckResponse) ProtoMessage() {}
+
+func (x *SelfCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SelfCheckResponse.ProtoReflect.Descriptor instead.
+func (*SelfCheckResponse) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SelfCheckResponse) GetFailures() []string {
+	if x != nil {
+		return x.Failures
+	}
+	return nil
+}
+
+type OccupancyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *OccupancyRequest) Reset() {
+	*x = OccupancyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OccupancyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OccupancyRequest) ProtoMessage() {}
+
+func (x *OccupancyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OccupancyRequest.ProtoReflect.Descriptor instead.
+func (*OccupancyRequest) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{9}
+}
+
+type OccupancyResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Buckets        uint32   `protobuf:"varint,1,opt,name=buckets,proto3" json:"buckets,omitempty"`
+	Blocks         uint32   `protobuf:"varint,2,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	OverflowBlocks uint32   `protobuf:"varint,3,opt,name=overflowBlocks,proto3" json:"overflowBlocks,omitempty"`
+	EmptyBuckets   uint32   `protobuf:"varint,4,opt,name=emptyBuckets,proto3" json:"emptyBuckets,omitempty"`
+	Entries        uint64   `protobuf:"varint,5,opt,name=entries,proto3" json:"entries,omitempty"`
+	MaxEntries     uint32   `protobuf:"varint,6,opt,name=maxEntries,proto3" json:"maxEntries,omitempty"`
+	Histogram      []uint32 `protobuf:"varint,7,rep,packed,name=histogram,proto3" json:"histogram,omitempty"`
+	UsedBytes      uint64   `protobuf:"varint,8,opt,name=usedBytes,proto3" json:"usedBytes,omitempty"`
+	BlockSize      uint32   `protobuf:"varint,9,opt,name=blockSize,proto3" json:"blockSize,omitempty"`
+	Keys           uint64   `protobuf:"varint,10,opt,name=keys,proto3" json:"keys,omitempty"`
+}
+
+func (x *OccupancyResponse) Reset() {
+	*x = OccupancyResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OccupancyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OccupancyResponse) ProtoMessage() {}
+
+func (x *OccupancyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OccupancyResponse.ProtoReflect.Descriptor instead.
+func (*OccupancyResponse) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *OccupancyResponse) GetBuckets() uint32 {
+	if x != nil {
+		return x.Buckets
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetBlocks() uint32 {
+	if x != nil {
+		return x.Blocks
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetOverflowBlocks() uint32 {
+	if x != nil {
+		return x.OverflowBlocks
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetEmptyBuckets() uint32 {
+	if x != nil {
+		return x.EmptyBuckets
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetEntries() uint64 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetMaxEntries() uint32 {
+	if x != nil {
+		return x.MaxEntries
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetHistogram() []uint32 {
+	if x != nil {
+		return x.Histogram
+	}
+	return nil
+}
+
+func (x *OccupancyResponse) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetBlockSize() uint32 {
+	if x != nil {
+		return x.BlockSize
+	}
+	return 0
+}
+
+func (x *OccupancyResponse) GetKeys() uint64 {
+	if x != nil {
+		return x.Keys
+	}
+	return 0
+}
+
+type IssueTokensRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Blinded []byte `protobuf:"bytes,1,opt,name=blinded,proto3" json:"blinded,omitempty"`
+}
+
+func (x *IssueTokensRequest) Reset() {
+	*x = IssueTokensRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IssueTokensRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IssueTokensRequest) ProtoMessage() {}
+
+func (x *IssueTokensRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IssueTokensRequest.ProtoReflect.Descriptor instead.
+func (*IssueTokensRequest) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *IssueTokensRequest) GetBlinded() []byte {
+	if x != nil {
+		return x.Blinded
+	}
+	return nil
+}
+
+type IssueTokensResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Evaluated []byte `protobuf:"bytes,1,opt,name=evaluated,proto3" json:"evaluated,omitempty"`
+	Proof     []byte `protobuf:"bytes,2,opt,name=proof,proto3" json:"proof,omitempty"`
+}
+
+func (x *IssueTokensResponse) Reset() {
+	*x = IssueTokensResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IssueTokensResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IssueTokensResponse) ProtoMessage() {}
+
+func (x *IssueTokensResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IssueTokensResponse.ProtoReflect.Descriptor instead.
+func (*IssueTokensResponse) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *IssueTokensResponse) GetEvaluated() []byte {
+	if x != nil {
+		return x.Evaluated
+	}
+	return nil
+}
+
+func (x *IssueTokensResponse) GetProof() []byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+type QueryInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target     Target      `protobuf:"varint,1,opt,name=target,proto3,enum=proto.Target" json:"target,omitempty"`
+	FromStart  uint32      `protobuf:"varint,2,opt,name=fromStart,proto3" json:"fromStart,omitempty"`
+	FromEnd    uint32      `protobuf:"varint,3,opt,name=fromEnd,proto3" json:"fromEnd,omitempty"`
+	And        bool        `protobuf:"varint,4,opt,name=and,proto3" json:"and,omitempty"`
+	Targets    []Target    `protobuf:"varint,5,rep,packed,name=targets,proto3,enum=proto.Target" json:"targets,omitempty"`
+	Avg        bool        `protobuf:"varint,6,opt,name=avg,proto3" json:"avg,omitempty"`
+	Sum        bool        `protobuf:"varint,7,opt,name=sum,proto3" json:"sum,omitempty"`
+	Aggregates []Aggregate `protobuf:"varint,8,rep,packed,name=aggregates,proto3,enum=proto.Aggregate" json:"aggregates,omitempty"`
+	Lt         bool        `protobuf:"varint,9,opt,name=lt,proto3" json:"lt,omitempty"`
+	Not        bool        `protobuf:"varint,10,opt,name=not,proto3" json:"not,omitempty"`
+	BucketSize uint32      `protobuf:"varint,11,opt,name=bucketSize,proto3" json:"bucketSize,omitempty"`
+	Page       bool        `protobuf:"varint,12,opt,name=page,proto3" json:"page,omitempty"`
+}
+
+func (x *QueryInfo) Reset() {
+	*x = QueryInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryInfo) ProtoMessage() {}
+
+func (x *QueryInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryInfo.ProtoReflect.Descriptor instead.
+func (*QueryInfo) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *QueryInfo) GetTarget() Target {
+	if x != nil {
+		return x.Target
+	}
+	return Target_TARGET_USER_ID
+}
+
+func (x *QueryInfo) GetFromStart() uint32 {
+	if x != nil {
+		return x.FromStart
+	}
+	return 0
+}
+
+func (x *QueryInfo) GetFromEnd() uint32 {
+	if x != nil {
+		return x.FromEnd
+	}
+	return 0
+}
+
+func (x *QueryInfo) GetAnd() bool {
+	if x != nil {
+		return x.And
+	}
+	return false
+}
+
+func (x *QueryInfo) GetTargets() []Target {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *QueryInfo) GetAvg() bool {
+	if x != nil {
+		return x.Avg
+	}
+	return false
+}
+
+func (x *QueryInfo) GetSum() bool {
+	if x != nil {
+		return x.Sum
+	}
+	return false
+}
+
+func (x *QueryInfo) GetAggregates() []Aggregate {
+	if x != nil {
+		return x.Aggregates
+	}
+	return nil
+}
+
+func (x *QueryInfo) GetLt() bool {
+	if x != nil {
+		return x.Lt
+	}
+	return false
+}
+
+func (x *QueryInfo) GetNot() bool {
+	if x != nil {
+		return x.Not
+	}
+	return false
+}
+
+func (x *QueryInfo) GetBucketSize() uint32 {
+	if x != nil {
+		return x.BucketSize
+	}
+	return 0
+}
+
+func (x *QueryInfo) GetPage() bool {
+	if x != nil {
+		return x.Page
+	}
+	return false
+}
+
+type ClientFSS struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Info      *QueryInfo `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`
+	Input     []byte     `protobuf:"bytes,2,opt,name=input,proto3" json:"input,omitempty"`
+	InputBits uint32     `protobuf:"varint,3,opt,name=inputBits,proto3" json:"inputBits,omitempty"`
+}
+
+func (x *ClientFSS) Reset() {
+	*x = ClientFSS{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientFSS) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientFSS) ProtoMessage() {}
+
+func (x *ClientFSS) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientFSS.ProtoReflect.Descriptor instead.
+func (*ClientFSS) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ClientFSS) GetInfo() *QueryInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *ClientFSS) GetInput() []byte {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *ClientFSS) GetInputBits() uint32 {
+	if x != nil {
+		return x.InputBits
+	}
+	return 0
+}
+
+type FSSKeyEq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SInit     []byte   `protobuf:"bytes,1,opt,name=sInit,proto3" json:"sInit,omitempty"`
+	TInit     uint32   `protobuf:"varint,2,opt,name=tInit,proto3" json:"tInit,omitempty"`
+	Cw        [][]byte `protobuf:"bytes,3,rep,name=cw,proto3" json:"cw,omitempty"`
+	FinalCW   []uint32 `protobuf:"varint,4,rep,packed,name=finalCW,proto3" json:"finalCW,omitempty"`
+	FinalCW64 []uint64 `protobuf:"varint,5,rep,packed,name=finalCW64,proto3" json:"finalCW64,omitempty"`
+	// epoch of the PRF keys of the key
+	Epoch uint64 `protobuf:"varint,6,opt,name=epoch,proto3" json:"epoch,omitempty"`
+}
+
+func (x *FSSKeyEq) Reset() {
+	*x = FSSKeyEq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FSSKeyEq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FSSKeyEq) ProtoMessage() {}
+
+func (x *FSSKeyEq) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FSSKeyEq.ProtoReflect.Descriptor instead.
+func (*FSSKeyEq) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *FSSKeyEq) GetSInit() []byte {
+	if x != nil {
+		return x.SInit
+	}
+	return nil
+}
+
+func (x *FSSKeyEq) GetTInit() uint32 {
+	if x != nil {
+		return x.TInit
+	}
+	return 0
+}
+
+func (x *FSSKeyEq) GetCw() [][]byte {
+	if x != nil {
+		return x.Cw
+	}
+	return nil
+}
+
+func (x *FSSKeyEq) GetFinalCW() []uint32 {
+	if x != nil {
+		return x.FinalCW
+	}
+	return nil
+}
+
+func (x *FSSKeyEq) GetFinalCW64() []uint64 {
+	if x != nil {
+		return x.FinalCW64
+	}
+	return nil
+}
+
+func (x *FSSKeyEq) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+type FSSKeyLt struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SInit   []byte   `protobuf:"bytes,1,opt,name=sInit,proto3" json:"sInit,omitempty"`
+	TInit   uint32   `protobuf:"varint,2,opt,name=tInit,proto3" json:"tInit,omitempty"`
+	Cw      [][]byte `protobuf:"bytes,3,rep,name=cw,proto3" json:"cw,omitempty"`
+	Vcw     []uint32 `protobuf:"varint,4,rep,packed,name=vcw,proto3" json:"vcw,omitempty"`
+	FinalCW []uint32 `protobuf:"varint,5,rep,packed,name=finalCW,proto3" json:"finalCW,omitempty"`
+	// epoch of the PRF keys of the key
+	Epoch uint64 `protobuf:"varint,6,opt,name=epoch,proto3" json:"epoch,omitempty"`
+}
+
+func (x *FSSKeyLt) Reset() {
+	*x = FSSKeyLt{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FSSKeyLt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FSSKeyLt) ProtoMessage() {}
+
+func (x *FSSKeyLt) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FSSKeyLt.ProtoReflect.Descriptor instead.
+func (*FSSKeyLt) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *FSSKeyLt) GetSInit() []byte {
+	if x != nil {
+		return x.SInit
+	}
+	return nil
+}
+
+func (x *FSSKeyLt) GetTInit() uint32 {
+	if x != nil {
+		return x.TInit
+	}
+	return 0
+}
+
+func (x *FSSKeyLt) GetCw() [][]byte {
+	if x != nil {
+		return x.Cw
+	}
+	return nil
+}
+
+func (x *FSSKeyLt) GetVcw() []uint32 {
+	if x != nil {
+		return x.Vcw
+	}
+	return nil
+}
+
+func (x *FSSKeyLt) GetFinalCW() []uint32 {
+	if x != nil {
+		return x.FinalCW
+	}
+	return nil
+}
+
+func (x *FSSKeyLt) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+type FSS struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Info      *QueryInfo `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`
+	Key       *FSSKeyEq  `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	KeyLt     *FSSKeyLt  `protobuf:"bytes,3,opt,name=keyLt,proto3" json:"keyLt,omitempty"`
+	Nonce     []byte     `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	MacShares []uint32   `protobuf:"varint,5,rep,packed,name=macShares,proto3" json:"macShares,omitempty"`
+}
+
+func (x *FSS) Reset() {
+	*x = FSS{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FSS) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FSS) ProtoMessage() {}
+
+func (x *FSS) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FSS.ProtoReflect.Descriptor instead.
+func (*FSS) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FSS) GetInfo() *QueryInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *FSS) GetKey() *FSSKeyEq {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *FSS) GetKeyLt() *FSSKeyLt {
+	if x != nil {
+		return x.KeyLt
+	}
+	return nil
+}
+
+func (x *FSS) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *FSS) GetMacShares() []uint32 {
+	if x != nil {
+		return x.MacShares
+	}
+	return nil
+}
+
+type Write struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      []byte    `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Key     *FSSKeyEq `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Triples []uint32  `protobuf:"varint,3,rep,packed,name=triples,proto3" json:"triples,omitempty"`
+}
+
+func (x *Write) Reset() {
+	*x = Write{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Write) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Write) ProtoMessage() {}
+
+func (x *Write) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Write.ProtoReflect.Descriptor instead.
+func (*Write) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Write) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Write) GetKey() *FSSKeyEq {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *Write) GetTriples() []uint32 {
+	if x != nil {
+		return x.Triples
+	}
+	return nil
+}
+
+type Contribution struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     []byte   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Shares []uint32 `protobuf:"varint,2,rep,packed,name=shares,proto3" json:"shares,omitempty"`
+	F0     uint32   `protobuf:"varint,3,opt,name=f0,proto3" json:"f0,omitempty"`
+	G0     uint32   `protobuf:"varint,4,opt,name=g0,proto3" json:"g0,omitempty"`
+	H      []uint32 `protobuf:"varint,5,rep,packed,name=h,proto3" json:"h,omitempty"`
+	Triple []uint32 `protobuf:"varint,6,rep,packed,name=triple,proto3" json:"triple,omitempty"`
+}
+
+func (x *Contribution) Reset() {
+	*x = Contribution{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lib_proto_vpir_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Contribution) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contribution) ProtoMessage() {}
+
+func (x *Contribution) ProtoReflect() protoreflect.Message {
+	mi := &file_lib_proto_vpir_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contribution.ProtoReflect.Descriptor instead.
+func (*Contribution) Descriptor() ([]byte, []int) {
+	return file_lib_proto_vpir_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Contribution) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Contribution) GetShares() []uint32 {
+	if x != nil {
+		return x.Shares
+	}
+	return nil
+}
+
+func (x *Contribution) GetF0() uint32 {
+	if x != nil {
+		return x.F0
+	}
+	return 0
+}
+
+func (x *Contribution) GetG0() uint32 {
+	if x != nil {
+		return x.G0
+	}
+	return 0
+}
+
+func (x *Contribution) GetH() []uint32 {
+	if x != nil {
+		return x.H
+	}
+	return nil
+}
+
+func (x *Contribution) GetTriple() []uint32 {
+	if x != nil {
+		return x.Triple
+	}
+	return nil
+}
+
+var File_lib_proto_vpir_proto protoreflect.FileDescriptor
+
+var file_lib_proto_vpir_proto_rawDesc = []byte{
+	0x0a, 0x14, 0x6c, 0x69, 0x62, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x70, 0x69, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x42, 0x0a,
+	0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x71, 0x75,
+	0x65, 0x72, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74,
+	0x65, 0x22, 0x27, 0x0a, 0x0d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x06, 0x61, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x22, 0x3b, 0x0a, 0x13, 0x44, 0x61,
+	0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x24, 0x0a, 0x0d, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x54, 0x72, 0x65, 0x65, 0x53, 0x69,
+	0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x54,
+	0x72, 0x65, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x22, 0xa0, 0x02, 0x0a, 0x14, 0x44, 0x61, 0x74, 0x61,
+	0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x6e, 0x75, 0x6d, 0x52, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x07, 0x6e, 0x75, 0x6d, 0x52, 0x6f, 0x77, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x6e, 0x75,
+	0x6d, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a,
+	0x6e, 0x75, 0x6d, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x62, 0x6c,
+	0x6f, 0x63, 0x6b, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0b, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x12, 0x18, 0x0a, 0x07,
+	0x70, 0x69, 0x72, 0x54, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70,
+	0x69, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72,
+	0x6f, 0x6f, 0x66, 0x4c, 0x65, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x72,
+	0x6f, 0x6f, 0x66, 0x4c, 0x65, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x68, 0x61, 0x73, 0x68, 0x54, 0x61,
+	0x62, 0x6c, 0x65, 0x4c, 0x65, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x68, 0x61,
+	0x73, 0x68, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x4c, 0x65, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x74, 0x72,
+	0x61, 0x6e, 0x73, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x1a,
+	0x0a, 0x08, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x4b, 0x65, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x08, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x4b, 0x65, 0x79, 0x22, 0x25, 0x0a, 0x0d, 0x52, 0x65,
+	0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x66,
+	0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65,
+	0x73, 0x22, 0x0f, 0x0a, 0x0d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x22, 0xc0, 0x01, 0x0a, 0x0e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x65, 0x70,
+	0x6f, 0x63, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x74,
+	0x72, 0x65, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x74,
+	0x72, 0x65, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x74, 0x72, 0x65, 0x65, 0x52,
+	0x6f, 0x6f, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x74, 0x72, 0x65, 0x65, 0x52,
+	0x6f, 0x6f, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x41, 0x74, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x41, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05,
+	0x66, 0x69, 0x6c, 0x65, 0x73, 0x22, 0x12, 0x0a, 0x10, 0x53, 0x65, 0x6c, 0x66, 0x43, 0x68, 0x65,
+	0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x2f, 0x0a, 0x11, 0x53, 0x65, 0x6c,
+	0x66, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a,
+	0x0a, 0x08, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x08, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x73, 0x22, 0x12, 0x0a, 0x10, 0x4f, 0x63,
+	0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xb9,
+	0x02, 0x0a, 0x11, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x6f, 0x76, 0x65, 0x72, 0x66, 0x6c,
+	0x6f, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0e,
+	0x6f, 0x76, 0x65, 0x72, 0x66, 0x6c, 0x6f, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x22,
+	0x0a, 0x0c, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x0a,
+	0x6d, 0x61, 0x78, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x0a, 0x6d, 0x61, 0x78, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09,
+	0x68, 0x69, 0x73, 0x74, 0x6f, 0x67, 0x72, 0x61, 0x6d, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0d, 0x52,
+	0x09, 0x68, 0x69, 0x73, 0x74, 0x6f, 0x67, 0x72, 0x61, 0x6d, 0x12, 0x1c, 0x0a, 0x09, 0x75, 0x73,
+	0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x75,
+	0x73, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x22, 0x2e, 0x0a, 0x12, 0x49, 0x73,
+	0x73, 0x75, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x18, 0x0a, 0x07, 0x62, 0x6c, 0x69, 0x6e, 0x64, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x07, 0x62, 0x6c, 0x69, 0x6e, 0x64, 0x65, 0x64, 0x22, 0x49, 0x0a, 0x13, 0x49, 0x73,
+	0x73, 0x75, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x76, 0x61, 0x6c, 0x75, 0x61, 0x74, 0x65, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x65, 0x76, 0x61, 0x6c, 0x75, 0x61, 0x74, 0x65, 0x64, 0x12,
+	0x14, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05,
+	0x70, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0xd1, 0x02, 0x0a, 0x09, 0x51, 0x75, 0x65, 0x72, 0x79, 0x49,
+	0x6e, 0x66, 0x6f, 0x12, 0x25, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x54, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x72,
+	0x6f, 0x6d, 0x53, 0x74, 0x61, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x66,
+	0x72, 0x6f, 0x6d, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x66, 0x72, 0x6f, 0x6d,
+	0x45, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x66, 0x72, 0x6f, 0x6d, 0x45,
+	0x6e, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x6e, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x03, 0x61, 0x6e, 0x64, 0x12, 0x27, 0x0a, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x18,
+	0x05, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x54, 0x61,
+	0x72, 0x67, 0x65, 0x74, 0x52, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x12, 0x10, 0x0a,
+	0x03, 0x61, 0x76, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x76, 0x67, 0x12,
+	0x10, 0x0a, 0x03, 0x73, 0x75, 0x6d, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x73, 0x75,
+	0x6d, 0x12, 0x30, 0x0a, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x18,
+	0x08, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x41, 0x67,
+	0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x52, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61,
+	0x74, 0x65, 0x73, 0x12, 0x0e, 0x0a, 0x02, 0x6c, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x02, 0x6c, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6e, 0x6f, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x03, 0x6e, 0x6f, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x53,
+	0x69, 0x7a, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x62, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x67, 0x65, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x04, 0x70, 0x61, 0x67, 0x65, 0x22, 0x65, 0x0a, 0x09, 0x43, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x46, 0x53, 0x53, 0x12, 0x24, 0x0a, 0x04, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x69, 0x6e, 0x66, 0x6f, 0x12, 0x14, 0x0a, 0x05,
+	0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x69, 0x6e, 0x70,
+	0x75, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x42, 0x69, 0x74, 0x73, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x42, 0x69, 0x74, 0x73,
+	0x22, 0x94, 0x01, 0x0a, 0x08, 0x46, 0x53, 0x53, 0x4b, 0x65, 0x79, 0x45, 0x71, 0x12, 0x14, 0x0a,
+	0x05, 0x73, 0x49, 0x6e, 0x69, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x49,
+	0x6e, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x49, 0x6e, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x05, 0x74, 0x49, 0x6e, 0x69, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x63, 0x77, 0x18,
+	0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x02, 0x63, 0x77, 0x12, 0x18, 0x0a, 0x07, 0x66, 0x69, 0x6e,
+	0x61, 0x6c, 0x43, 0x57, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x07, 0x66, 0x69, 0x6e, 0x61,
+	0x6c, 0x43, 0x57, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x43, 0x57, 0x36, 0x34,
+	0x18, 0x05, 0x20, 0x03, 0x28, 0x04, 0x52, 0x09, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x43, 0x57, 0x36,
+	0x34, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x05, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x22, 0x88, 0x01, 0x0a, 0x08, 0x46, 0x53, 0x53, 0x4b,
+	0x65, 0x79, 0x4c, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x49, 0x6e, 0x69, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x49, 0x6e, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x49,
+	0x6e, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x74, 0x49, 0x6e, 0x69, 0x74,
+	0x12, 0x0e, 0x0a, 0x02, 0x63, 0x77, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x02, 0x63, 0x77,
+	0x12, 0x10, 0x0a, 0x03, 0x76, 0x63, 0x77, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x03, 0x76,
+	0x63, 0x77, 0x12, 0x18, 0x0a, 0x07, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x43, 0x57, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x0d, 0x52, 0x07, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x43, 0x57, 0x12, 0x14, 0x0a, 0x05,
+	0x65, 0x70, 0x6f, 0x63, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x65, 0x70, 0x6f,
+	0x63, 0x68, 0x22, 0xa9, 0x01, 0x0a, 0x03, 0x46, 0x53, 0x53, 0x12, 0x24, 0x0a, 0x04, 0x69, 0x6e,
+	0x66, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x69, 0x6e, 0x66, 0x6f,
+	0x12, 0x21, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x53, 0x53, 0x4b, 0x65, 0x79, 0x45, 0x71, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x25, 0x0a, 0x05, 0x6b, 0x65, 0x79, 0x4c, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x53, 0x53, 0x4b, 0x65,
+	0x79, 0x4c, 0x74, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x4c, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f,
+	0x6e, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x61, 0x63, 0x53, 0x68, 0x61, 0x72, 0x65, 0x73, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x0d, 0x52, 0x09, 0x6d, 0x61, 0x63, 0x53, 0x68, 0x61, 0x72, 0x65, 0x73, 0x22, 0x54,
+	0x0a, 0x05, 0x57, 0x72, 0x69, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x02, 0x69, 0x64, 0x12, 0x21, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x53, 0x53,
+	0x4b, 0x65, 0x79, 0x45, 0x71, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x74, 0x72,
+	0x69, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x07, 0x74, 0x72, 0x69,
+	0x70, 0x6c, 0x65, 0x73, 0x22, 0x7c, 0x0a, 0x0c, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x69, 0x62, 0x75,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x72, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0d, 0x52, 0x06, 0x73, 0x68, 0x61, 0x72, 0x65, 0x73, 0x12, 0x0e, 0x0a, 0x02,
+	0x66, 0x30, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x66, 0x30, 0x12, 0x0e, 0x0a, 0x02,
+	0x67, 0x30, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x67, 0x30, 0x12, 0x0c, 0x0a, 0x01,
+	0x68, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x01, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x72,
+	0x69, 0x70, 0x6c, 0x65, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x06, 0x74, 0x72, 0x69, 0x70,
+	0x6c, 0x65, 0x2a, 0x64, 0x0a, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x12, 0x0a, 0x0e,
+	0x54, 0x41, 0x52, 0x47, 0x45, 0x54, 0x5f, 0x55, 0x53, 0x45, 0x52, 0x5f, 0x49, 0x44, 0x10, 0x00,
+	0x12, 0x18, 0x0a, 0x14, 0x54, 0x41, 0x52, 0x47, 0x45, 0x54, 0x5f, 0x43, 0x52, 0x45, 0x41, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x54, 0x49, 0x4d, 0x45, 0x10, 0x01, 0x12, 0x17, 0x0a, 0x13, 0x54, 0x41,
+	0x52, 0x47, 0x45, 0x54, 0x5f, 0x50, 0x55, 0x42, 0x5f, 0x4b, 0x45, 0x59, 0x5f, 0x41, 0x4c, 0x47,
+	0x4f, 0x10, 0x02, 0x12, 0x13, 0x0a, 0x0f, 0x54, 0x41, 0x52, 0x47, 0x45, 0x54, 0x5f, 0x4b, 0x45,
+	0x59, 0x5f, 0x53, 0x49, 0x5a, 0x45, 0x10, 0x03, 0x2a, 0x57, 0x0a, 0x09, 0x41, 0x67, 0x67, 0x72,
+	0x65, 0x67, 0x61, 0x74, 0x65, 0x12, 0x13, 0x0a, 0x0f, 0x41, 0x47, 0x47, 0x52, 0x45, 0x47, 0x41,
+	0x54, 0x45, 0x5f, 0x43, 0x4f, 0x55, 0x4e, 0x54, 0x10, 0x00, 0x12, 0x17, 0x0a, 0x13, 0x41, 0x47,
+	0x47, 0x52, 0x45, 0x47, 0x41, 0x54, 0x45, 0x5f, 0x53, 0x55, 0x4d, 0x5f, 0x59, 0x45, 0x41, 0x52,
+	0x53, 0x10, 0x01, 0x12, 0x1c, 0x0a, 0x18, 0x41, 0x47, 0x47, 0x52, 0x45, 0x47, 0x41, 0x54, 0x45,
+	0x5f, 0x53, 0x55, 0x4d, 0x5f, 0x42, 0x49, 0x54, 0x5f, 0x4c, 0x45, 0x4e, 0x47, 0x54, 0x48, 0x10,
+	0x02, 0x32, 0x87, 0x01, 0x0a, 0x04, 0x56, 0x50, 0x49, 0x52, 0x12, 0x49, 0x0a, 0x0c, 0x44, 0x61,
+	0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1a, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x44,
+	0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x34, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x13,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x32, 0xc5, 0x02, 0x0a, 0x05,
+	0x41, 0x64, 0x6d, 0x69, 0x6e, 0x12, 0x37, 0x0a, 0x06, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x12,
+	0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x37,
+	0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x09, 0x53, 0x65, 0x6c, 0x66, 0x43,
+	0x68, 0x65, 0x63, 0x6b, 0x12, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x65, 0x6c,
+	0x66, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x65, 0x6c, 0x66, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x09, 0x4f, 0x63, 0x63,
+	0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x12, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f,
+	0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x18, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63,
+	0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x46, 0x0a, 0x0b, 0x49,
+	0x73, 0x73, 0x75, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x12, 0x19, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x49, 0x73, 0x73, 0x75, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x73,
+	0x73, 0x75, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x42, 0x2c, 0x5a, 0x2a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x73, 0x69, 0x2d, 0x63, 0x6f, 0x2f, 0x76, 0x70, 0x69, 0x72, 0x2d, 0x63, 0x6f, 0x64,
+	0x65, 0x2f, 0x6c, 0x69, 0x62, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_lib_proto_vpir_proto_rawDescOnce sync.Once
+	file_lib_proto_vpir_proto_rawDescData = file_lib_proto_vpir_proto_rawDesc
+)
+
+func file_lib_proto_vpir_proto_rawDescGZIP() []byte {
+	file_lib_proto_vpir_proto_rawDescOnce.Do(func() {
+		file_lib_proto_vpir_proto_rawDescData = protoimpl.X.CompressGZIP(file_lib_proto_vpir_proto_rawDescData)
+	})
+	return file_lib_proto_vpir_proto_rawDescData
+}
+
+var file_lib_proto_vpir_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_lib_proto_vpir_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_lib_proto_vpir_proto_goTypes = []interface{}{
+	(Target)(0),                  // 0: proto.Target
+	(Aggregate)(0),               // 1: proto.Aggregate
+	(*QueryRequest)(nil),         // 2: proto.QueryRequest
+	(*QueryResponse)(nil),        // 3: proto.QueryResponse
+	(*DatabaseInfoRequest)(nil),  // 4: proto.DatabaseInfoRequest
+	(*DatabaseInfoResponse)(nil), // 5: proto.DatabaseInfoResponse
+	(*ReloadRequest)(nil),        // 6: proto.ReloadRequest
+	(*StatusRequest)(nil),        // 7: proto.StatusRequest
+	(*StatusResponse)(nil),       // 8: proto.StatusResponse
+	(*SelfCheckRequest)(nil),     // 9: proto.SelfCheckRequest
+	(*SelfCheckResponse)(nil),    // 10: proto.SelfCheckResponse
+	(*OccupancyRequest)(nil),     // 11: proto.OccupancyRequest
+	(*OccupancyResponse)(nil),    // 12: proto.OccupancyResponse
+	(*IssueTokensRequest)(nil),   // 13: proto.IssueTokensRequest
+	(*IssueTokensResponse)(nil),  // 14: proto.IssueTokensResponse
+	(*QueryInfo)(nil),            // 15: proto.QueryInfo
+	(*ClientFSS)(nil),            // 16: proto.ClientFSS
+	(*FSSKeyEq)(nil),             // 17: proto.FSSKeyEq
+	(*FSSKeyLt)(nil),             // 18: proto.FSSKeyLt
+	(*FSS)(nil),                  // 19: proto.FSS
+	(*Write)(nil),                // 20: proto.Write
+	(*Contribution)(nil),         // 21: proto.Contribution
+}
+var file_lib_proto_vpir_proto_depIdxs = []int32{
+	0,  // 0: proto.QueryInfo.target:type_name -> proto.Target
+	0,  // 1: proto.QueryInfo.targets:type_name -> proto.Target
+	1,  // 2: proto.QueryInfo.aggregates:type_name -> proto.Aggregate
+	15, // 3: proto.ClientFSS.info:type_name -> proto.QueryInfo
+	15, // 4: proto.FSS.info:type_name -> proto.QueryInfo
+	17, // 5: proto.FSS.key:type_name -> proto.FSSKeyEq
+	18, // 6: proto.FSS.keyLt:type_name -> proto.FSSKeyLt
+	17, // 7: proto.Write.key:type_name -> proto.FSSKeyEq
+	4,  // 8: proto.VPIR.DatabaseInfo:input_type -> proto.DatabaseInfoRequest
+	2,  // 9: proto.VPIR.Query:input_type -> proto.QueryRequest
+	6,  // 10: proto.Admin.Reload:input_type -> proto.ReloadRequest
+	7,  // 11: proto.Admin.Status:input_type -> proto.StatusRequest
+	9,  // 12: proto.Admin.SelfCheck:input_type -> proto.SelfCheckRequest
+	11, // 13: proto.Admin.Occupancy:input_type -> proto.OccupancyRequest
+	13, // 14: proto.Admin.IssueTokens:input_type -> proto.IssueTokensRequest
+	5,  // 15: proto.VPIR.DatabaseInfo:output_type -> proto.DatabaseInfoResponse
+	3,  // 16: proto.VPIR.Query:output_type -> proto.QueryResponse
+	8,  // 17: proto.Admin.Reload:output_type -> proto.StatusResponse
+	8,  // 18: proto.Admin.Status:output_type -> proto.StatusResponse
+	10, // 19: proto.Admin.SelfCheck:output_type -> proto.SelfCheckResponse
+	12, // 20: proto.Admin.Occupancy:output_type -> proto.OccupancyResponse
+	14, // 21: proto.Admin.IssueTokens:output_type -> proto.IssueTokensResponse
+	15, // [15:22] is the sub-list for method output_type
+	8,  // [8:15] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_lib_proto_vpir_proto_init() }
+func file_lib_proto_vpir_proto_init() {
+	if File_lib_proto_vpir_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_lib_proto_vpir_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DatabaseInfoRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DatabaseInfoResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReloadRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SelfCheckRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SelfCheckResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OccupancyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OccupancyResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IssueTokensRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IssueTokensResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClientFSS); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FSSKeyEq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FSSKeyLt); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FSS); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Write); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lib_proto_vpir_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Contribution); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -373,13 +2027,14 @@ func file_lib_proto_vpir_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_lib_proto_vpir_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      2,
+			NumMessages:   20,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_lib_proto_vpir_proto_goTypes,
 		DependencyIndexes: file_lib_proto_vpir_proto_depIdxs,
+		EnumInfos:         file_lib_proto_vpir_proto_enumTypes,
 		MessageInfos:      file_lib_proto_vpir_proto_msgTypes,
 	}.Build()
 	File_lib_proto_vpir_proto = out.File
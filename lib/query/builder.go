@@ -0,0 +1,258 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Builder builds a client query step by step, e.g.,
+//
+//	query.NewBuilder().TargetUserID().Suffix("epfl.ch").Aggregate(query.Count, query.SumYears).Encode()
+//	query.NewBuilder().TargetUserID().Not().Domain("example.org").Build()
+//
+// instead of setting the fields of Info and the input by hand. A query has
+// exactly one target and one match of the target, and the first invalid
+// step is returned by Build.
+type Builder struct {
+	info    Info
+	target  bool
+	matched bool
+	build   func(*Info) *ClientFSS
+	err     error
+}
+
+// NewBuilder returns an empty builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// TargetUserID selects the queries on the emails of the keys
+func (b *Builder) TargetUserID() *Builder {
+	return b.setTarget(UserId)
+}
+
+// TargetCreationTime selects the queries on the creation time of the keys
+func (b *Builder) TargetCreationTime() *Builder {
+	return b.setTarget(CreationTime)
+}
+
+// TargetPubKeyAlgo selects the queries on the public-key algorithm of the
+// keys
+func (b *Builder) TargetPubKeyAlgo() *Builder {
+	return b.setTarget(PubKeyAlgo)
+}
+
+// TargetKeySize selects the queries on the bit length of the keys
+func (b *Builder) TargetKeySize() *Builder {
+	return b.setTarget(KeySize)
+}
+
+// Email matches the keys of the email, normalized as in the db
+func (b *Builder) Email(email string) *Builder {
+	if email == "" {
+		return b.fail(errors.New("empty email"))
+	}
+	return b.match(UserId, func(i *Info) *ClientFSS {
+		return i.ToEmailClientFSS(email)
+	})
+}
+
+// Prefix matches the keys whose email starts with the prefix, taken as is
+func (b *Builder) Prefix(prefix string) *Builder {
+	if prefix == "" {
+		return b.fail(errors.New("empty prefix"))
+	}
+	return b.match(UserId, func(i *Info) *ClientFSS {
+		i.FromStart = len(prefix)
+		return i.ToEmailClientFSS(prefix)
+	})
+}
+
+// Suffix matches the keys whose email ends with the suffix, taken as is
+func (b *Builder) Suffix(suffix string) *Builder {
+	if suffix == "" {
+		return b.fail(errors.New("empty suffix"))
+	}
+	return b.match(UserId, func(i *Info) *ClientFSS {
+		i.FromEnd = len(suffix)
+		return i.ToEmailClientFSS(suffix)
+	})
+}
+
+// Domain matches the keys whose email is in the domain, e.g., example.org
+func (b *Builder) Domain(domain string) *Builder {
+	if strings.TrimPrefix(domain, "@") == "" {
+		return b.fail(errors.New("empty domain"))
+	}
+	return b.match(UserId, func(i *Info) *ClientFSS {
+		q := DomainClientFSS(domain)
+		i.FromEnd = q.FromEnd
+		q.Info = i
+		return q
+	})
+}
+
+// Algo matches the keys of the public-key algorithm, e.g., RSA, with any
+// case
+func (b *Builder) Algo(name string) *Builder {
+	algo, ok := pubKeyAlgoName(name)
+	if !ok {
+		return b.fail(fmt.Errorf("unknown algorithm %q", name))
+	}
+	return b.match(PubKeyAlgo, func(i *Info) *ClientFSS {
+		return i.ToPKAClientFSS(algo)
+	})
+}
+
+// Year matches the keys created in the year
+func (b *Builder) Year(year int) *Builder {
+	return b.match(CreationTime, func(i *Info) *ClientFSS {
+		return i.ToCreationTimeClientFSS(strconv.Itoa(year))
+	})
+}
+
+// Before matches the keys created before t
+func (b *Builder) Before(t time.Time) *Builder {
+	return b.match(CreationTime, func(i *Info) *ClientFSS {
+		i.Lt = true
+		return i.ToCreationTimeLtClientFSS(t)
+	})
+}
+
+// Value matches the keys whose numeric target is v, e.g., the bit length
+// or the algorithm ID. The creation time is matched by Year instead.
+func (b *Builder) Value(v uint64) *Builder {
+	if b.info.Target == CreationTime {
+		return b.fail(errors.New("creation time matched by year"))
+	}
+	return b.number(v, false)
+}
+
+// Below matches the keys whose numeric target is smaller than v, e.g., the
+// bit length, the algorithm ID or the seconds since the Unix epoch of the
+// creation time
+func (b *Builder) Below(v uint64) *Builder {
+	return b.number(v, true)
+}
+
+// number sets the match of the value of a numeric target, compared with a
+// less-than if lt
+func (b *Builder) number(v uint64, lt bool) *Builder {
+	if _, ok := b.info.Target.Width(); !ok && b.target {
+		return b.fail(errors.New("target not numeric"))
+	}
+	// check that the value fits the width of the target
+	if _, err := (&Info{Target: b.info.Target}).IdForNumber(v); err != nil && b.target {
+		return b.fail(err)
+	}
+	return b.match(b.info.Target, func(i *Info) *ClientFSS {
+		i.Lt = lt
+		q, _ := i.ToNumberClientFSS(v)
+		return q
+	})
+}
+
+// Not negates the match, e.g., to count the keys not in a domain
+func (b *Builder) Not() *Builder {
+	if b.info.Not {
+		return b.fail(errors.New("match negated twice"))
+	}
+	b.info.Not = true
+	return b
+}
+
+// Aggregate computes the aggregates over the matching keys instead of their
+// count, in the given order
+func (b *Builder) Aggregate(aggregates ...Aggregate) *Builder {
+	if b.info.Aggregates != nil {
+		return b.fail(errors.New("aggregates set twice"))
+	}
+	if len(aggregates) == 0 {
+		return b.fail(errors.New("no aggregate"))
+	}
+	for k, a := range aggregates {
+		if a > SumBitLength {
+			return b.fail(fmt.Errorf("unknown aggregate %d", a))
+		}
+		for _, other := range aggregates[:k] {
+			if a == other {
+				return b.fail(errors.New("aggregate selected twice"))
+			}
+		}
+	}
+	b.info.Aggregates = aggregates
+	return b
+}
+
+// Build returns the client query, or the first error of the steps
+func (b *Builder) Build() (*ClientFSS, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if !b.target {
+		return nil, errors.New("no target")
+	}
+	if !b.matched {
+		return nil, errors.New("no match of the target")
+	}
+	info := b.info
+	return b.build(&info), nil
+}
+
+// Encode returns the encoded client query, as given to the QueryBytes
+// method of the clients
+func (b *Builder) Encode() ([]byte, error) {
+	q, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return q.Encode()
+}
+
+func (b *Builder) setTarget(t Target) *Builder {
+	if b.target {
+		return b.fail(errors.New("target set twice"))
+	}
+	b.target = true
+	b.info.Target = t
+	return b
+}
+
+// match sets the match of the query, valid for the given target only
+func (b *Builder) match(t Target, build func(*Info) *ClientFSS) *Builder {
+	switch {
+	case b.err != nil:
+		return b
+	case !b.target:
+		return b.fail(errors.New("match before the target"))
+	case b.info.Target != t:
+		return b.fail(errors.New("match not valid for the target"))
+	case b.matched:
+		return b.fail(errors.New("target matched twice"))
+	}
+	b.matched = true
+	b.build = build
+	return b
+}
+
+// fail records the first error of the steps
+func (b *Builder) fail(err error) *Builder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// pubKeyAlgoName returns the name of the public-key algorithm known by
+// ToPKAClientFSS, with any case
+func pubKeyAlgoName(name string) (string, bool) {
+	for _, algo := range []string{"RSA", "ElGamal", "DSA", "ECDH", "ECDSA"} {
+		if strings.EqualFold(name, algo) {
+			return algo, true
+		}
+	}
+	return "", false
+}
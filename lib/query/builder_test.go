@@ -0,0 +1,115 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	before := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		b        *Builder
+		expected *ClientFSS
+	}{
+		{
+			NewBuilder().TargetUserID().Suffix("epfl.ch"),
+			(&Info{Target: UserId, FromEnd: 7}).ToEmailClientFSS("epfl.ch"),
+		},
+		{
+			NewBuilder().TargetUserID().Prefix("alice").Aggregate(Count, SumYears),
+			(&Info{Target: UserId, FromStart: 5, Aggregates: []Aggregate{Count, SumYears}}).ToEmailClientFSS("alice"),
+		},
+		{
+			NewBuilder().TargetUserID().Domain("example.org"),
+			DomainClientFSS("example.org"),
+		},
+		{
+			NewBuilder().Aggregate(SumBitLength).TargetPubKeyAlgo().Algo("ecdsa"),
+			(&Info{Target: PubKeyAlgo, Aggregates: []Aggregate{SumBitLength}}).ToPKAClientFSS("ECDSA"),
+		},
+		{
+			NewBuilder().TargetUserID().Not().Suffix("epfl.ch"),
+			(&Info{Target: UserId, FromEnd: 7, Not: true}).ToEmailClientFSS("epfl.ch"),
+		},
+		{
+			NewBuilder().TargetKeySize().Value(4096),
+			mustNumber(&Info{Target: KeySize}, 4096),
+		},
+		{
+			NewBuilder().TargetPubKeyAlgo().Below(17),
+			mustNumber(&Info{Target: PubKeyAlgo, Lt: true}, 17),
+		},
+		{
+			NewBuilder().TargetCreationTime().Below(uint64(before.Unix())),
+			(&Info{Target: CreationTime, Lt: true}).ToCreationTimeLtClientFSS(before),
+		},
+		{
+			NewBuilder().TargetCreationTime().Year(2019),
+			(&Info{Target: CreationTime}).ToCreationTimeClientFSS("2019"),
+		},
+		{
+			NewBuilder().TargetCreationTime().Before(before),
+			(&Info{Target: CreationTime, Lt: true}).ToCreationTimeLtClientFSS(before),
+		},
+	}
+	for _, test := range tests {
+		q, err := test.b.Build()
+		require.NoError(t, err)
+		require.Equal(t, test.expected, q)
+
+		encoded, err := test.b.Encode()
+		require.NoError(t, err)
+		decoded, err := DecodeClientFSS(encoded)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, decoded)
+	}
+
+	for _, b := range []*Builder{
+		NewBuilder(),
+		NewBuilder().TargetUserID(),
+		NewBuilder().Suffix("epfl.ch"),
+		NewBuilder().TargetUserID().TargetPubKeyAlgo().Algo("RSA"),
+		NewBuilder().TargetUserID().Algo("RSA"),
+		NewBuilder().TargetUserID().Suffix("epfl.ch").Prefix("alice"),
+		NewBuilder().TargetUserID().Suffix(""),
+		NewBuilder().TargetPubKeyAlgo().Algo("AES"),
+		NewBuilder().TargetPubKeyAlgo().Not().Not().Algo("RSA"),
+		NewBuilder().TargetUserID().Below(3),
+		NewBuilder().TargetKeySize().Value(1 << 16),
+		NewBuilder().TargetPubKeyAlgo().Below(256),
+		NewBuilder().TargetCreationTime().Value(2019),
+		NewBuilder().Value(2019),
+		NewBuilder().TargetCreationTime().Year(2019).Aggregate(),
+		NewBuilder().TargetCreationTime().Year(2019).Aggregate(Count, Count),
+		NewBuilder().TargetCreationTime().Year(2019).Aggregate(Count).Aggregate(SumYears),
+		NewBuilder().TargetCreationTime().Year(2019).Aggregate(Aggregate(9)),
+	} {
+		_, err := b.Build()
+		require.Error(t, err)
+	}
+}
+
+func TestIdForNumber(t *testing.T) {
+	info := &Info{Target: KeySize}
+	id, err := info.IdForNumber(0x1234)
+	require.NoError(t, err)
+	require.Len(t, id, 16)
+	require.Equal(t, []bool{false, false, false, true, false, false, true, false}, id[:8])
+
+	// the numeric encodings are the ones of the existing inputs
+	info.Target = PubKeyAlgo
+	id, err = info.IdForNumber(1)
+	require.NoError(t, err)
+	require.Equal(t, info.IdForPubKeyAlgo(1), id)
+	info.Target = CreationTime
+	now := time.Unix(1600000000, 0)
+	id, err = info.IdForNumber(uint64(now.Unix()))
+	require.NoError(t, err)
+	require.Equal(t, info.IdForCreationTimeLt(now), id)
+
+	info.Target = UserId
+	_, err = info.IdForNumber(1)
+	require.Error(t, err)
+}
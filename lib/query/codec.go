@@ -0,0 +1,96 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The encodings of the queries, writes and contributions start with a magic
+// header and the version of the encoding, followed by the protobuf message.
+// The decoders reject the other versions, the fields unknown to the version
+// and the values beyond the size limits, so that the servers can decode the
+// messages of untrusted clients.
+
+// Version is the version of the encodings
+const Version = 2
+
+var magic = []byte("VQ")
+
+const (
+	// MaxEncodedLen bounds the length in bytes of an encoding
+	MaxEncodedLen = 1 << 24
+	// MaxInputBits bounds the number of bits of the input of a query, i.e.,
+	// the depth of its FSS keys
+	MaxInputBits = 4096
+	// MaxValues bounds the number of values of an FSS key, i.e., the data
+	// and the tags, and of MAC shares of a query
+	MaxValues = 64
+	// MaxStatisticLength bounds the length of the vectors of the
+	// contributions to the aggregate statistics
+	MaxStatisticLength = 1 << 20
+)
+
+var (
+	// ErrMalformed is returned when an encoding is not the one of a valid
+	// message
+	ErrMalformed = errors.New("malformed encoding")
+	// ErrVersion is returned when an encoding is of another version
+	ErrVersion = errors.New("unsupported encoding version")
+	// ErrTooLarge is returned when an encoding is beyond the size limits
+	ErrTooLarge = errors.New("encoding too large")
+)
+
+// marshal returns the encoding of the message, with the header
+func marshal(m gproto.Message) ([]byte, error) {
+	out := append(append([]byte{}, magic...), Version)
+	return gproto.MarshalOptions{}.MarshalAppend(out, m)
+}
+
+// unmarshal decodes the encoding of the message, checking the header and
+// that all the fields are known
+func unmarshal(in []byte, m gproto.Message) error {
+	if len(in) > MaxEncodedLen {
+		return fmt.Errorf("%w: %d bytes", ErrTooLarge, len(in))
+	}
+	if len(in) < len(magic)+1 || string(in[:len(magic)]) != string(magic) {
+		return fmt.Errorf("%w: missing header", ErrMalformed)
+	}
+	if v := in[len(magic)]; v != Version {
+		return fmt.Errorf("%w: %d", ErrVersion, v)
+	}
+	if err := gproto.Unmarshal(in[len(magic)+1:], m); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return checkUnknown(m.ProtoReflect())
+}
+
+// checkUnknown returns an error if the message or one of its submessages
+// has fields unknown to the version
+func checkUnknown(m protoreflect.Message) error {
+	if len(m.GetUnknown()) > 0 {
+		return fmt.Errorf("%w: unknown fields in %s", ErrMalformed, m.Descriptor().Name())
+	}
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		if fd.IsList() {
+			for i := 0; i < v.List().Len() && err == nil; i++ {
+				err = checkUnknown(v.List().Get(i).Message())
+			}
+		} else {
+			err = checkUnknown(v.Message())
+		}
+		return err == nil
+	})
+	return err
+}
+
+// malformed returns the error of a malformed message
+func malformed(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrMalformed, fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,31 @@
+package query
+
+// ContributionIDLen is the length in bytes of the identifier of a
+// contribution, from which the servers derive the randomness of its
+// verification
+const ContributionIDLen = 16
+
+// Statistic describes the contributions to an aggregate of the private
+// aggregate statistics: vectors of Length bits, exactly one of which is set
+// if OneHot, e.g., the bucket of a histogram, or the binary decomposition of
+// an integer otherwise. The aggregate is the sum of the contributions.
+type Statistic struct {
+	Length int
+	OneHot bool
+}
+
+// Contribution is the share of a contribution of a client sent to one of
+// the two servers of the aggregate statistics, in the style of Prio: the
+// share of the vector of bits, and the shares of the proof that every
+// element is a bit, i.e., of the polynomials f and g interpolating the
+// inputs of the multiplication gates x * (x - 1) at 1, ..., Length, their
+// random values F0 and G0 at 0, the evaluations H of the product h = f * g
+// at 0, ..., 2 Length, and the Beaver triple with which the servers check
+// that h = f * g at a random point
+type Contribution struct {
+	ID     []byte
+	Shares []uint32
+	F0, G0 uint32
+	H      []uint32
+	Triple [3]uint32
+}
@@ -0,0 +1,377 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Parse returns the query of a statement in the SQL-like syntax
+//
+//	SELECT aggregate [, aggregate ...] WHERE [NOT] condition
+//
+// where an aggregate is COUNT(*), SUM(years) or SUM(bitlength), and the
+// condition is one of
+//
+//	email = 'alice@example.org'
+//	email STARTS WITH 'alice'
+//	email ENDS WITH 'epfl.ch'
+//	domain = 'example.org'
+//	algo = 'RSA'
+//	created = 2019
+//	created < '2010-01-01'
+//	size = 4096
+//	size < 2048
+//
+// The keywords and the names are case-insensitive. A full email and a domain
+// are normalized as in the db, whereas prefixes and suffixes are taken as
+// is. A statement selecting COUNT(*) only returns a plain count query, the
+// others return a query with the given aggregates.
+func Parse(statement string) (*ClientFSS, error) {
+	tokens, err := lex(statement)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+
+	if err := p.keyword("SELECT"); err != nil {
+		return nil, err
+	}
+	aggregates, err := p.aggregates()
+	if err != nil {
+		return nil, err
+	}
+	b := NewBuilder()
+	if len(aggregates) > 1 || aggregates[0] != Count {
+		b.Aggregate(aggregates...)
+	}
+	if err := p.keyword("WHERE"); err != nil {
+		return nil, err
+	}
+	if p.isKeyword("NOT") {
+		p.pos++
+		b.Not()
+	}
+	if err := p.condition(b); err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q after the condition", p.peek().text)
+	}
+	return b.Build()
+}
+
+type tokenKind uint8
+
+const (
+	tokenWord tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex splits the statement in words, numbers, quoted strings, in which a
+// quote is escaped by doubling it, and the symbols ( ) * , = <
+func lex(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("()*,=<", r):
+			tokens = append(tokens, token{tokenSymbol, string(r)})
+			i++
+		case r == '\'':
+			var b strings.Builder
+			i++
+			for {
+				if i == len(runes) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						b.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenString, b.String()})
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{tokenWord, string(runes[i:j])})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos == len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{tokenSymbol, "end of statement"}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if !p.done() {
+		p.pos++
+	}
+	return t
+}
+
+// isKeyword reports whether the next token is the given keyword
+func (p *parser) isKeyword(k string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, k)
+}
+
+func (p *parser) keyword(k string) error {
+	if !p.isKeyword(k) {
+		return fmt.Errorf("expected %s, got %q", k, p.peek().text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) symbol(s string) error {
+	t := p.next()
+	if t.kind != tokenSymbol || t.text != s {
+		return fmt.Errorf("expected %q, got %q", s, t.text)
+	}
+	return nil
+}
+
+func (p *parser) word() (string, error) {
+	t := p.next()
+	if t.kind != tokenWord {
+		return "", fmt.Errorf("expected a name, got %q", t.text)
+	}
+	return strings.ToLower(t.text), nil
+}
+
+func (p *parser) str() (string, error) {
+	t := p.next()
+	if t.kind != tokenString {
+		return "", fmt.Errorf("expected a quoted string, got %q", t.text)
+	}
+	return t.text, nil
+}
+
+// aggregates parses the comma-separated aggregates of the statement
+func (p *parser) aggregates() ([]Aggregate, error) {
+	var out []Aggregate
+	for {
+		a, err := p.aggregate()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+		if p.peek().kind != tokenSymbol || p.peek().text != "," {
+			return out, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) aggregate() (Aggregate, error) {
+	fn, err := p.word()
+	if err != nil {
+		return 0, err
+	}
+	if err := p.symbol("("); err != nil {
+		return 0, err
+	}
+	var a Aggregate
+	switch fn {
+	case "count":
+		if err := p.symbol("*"); err != nil {
+			return 0, err
+		}
+		a = Count
+	case "sum":
+		arg, err := p.word()
+		if err != nil {
+			return 0, err
+		}
+		switch arg {
+		case "years":
+			a = SumYears
+		case "bitlength":
+			a = SumBitLength
+		default:
+			return 0, fmt.Errorf("unknown sum of %q", arg)
+		}
+	default:
+		return 0, fmt.Errorf("unknown aggregate %q", fn)
+	}
+	return a, p.symbol(")")
+}
+
+// condition parses the condition of the statement into the target and the
+// match of the builder
+func (p *parser) condition(b *Builder) error {
+	field, err := p.word()
+	if err != nil {
+		return err
+	}
+	switch field {
+	case "email":
+		b.TargetUserID()
+		return p.email(b)
+	case "domain":
+		domain, err := p.equals()
+		if err != nil {
+			return err
+		}
+		b.TargetUserID().Domain(domain)
+	case "algo":
+		algo, err := p.equals()
+		if err != nil {
+			return err
+		}
+		b.TargetPubKeyAlgo().Algo(algo)
+	case "created":
+		b.TargetCreationTime()
+		return p.created(b)
+	case "size":
+		b.TargetKeySize()
+		return p.size(b)
+	default:
+		return fmt.Errorf("unknown field %q", field)
+	}
+	return nil
+}
+
+// equals parses the quoted string of an equality
+func (p *parser) equals() (string, error) {
+	if err := p.symbol("="); err != nil {
+		return "", err
+	}
+	return p.str()
+}
+
+func (p *parser) email(b *Builder) error {
+	if !p.isKeyword("STARTS") && !p.isKeyword("ENDS") {
+		email, err := p.equals()
+		if err != nil {
+			return err
+		}
+		b.Email(email)
+		return nil
+	}
+
+	starts := p.isKeyword("STARTS")
+	p.pos++
+	if err := p.keyword("WITH"); err != nil {
+		return err
+	}
+	s, err := p.str()
+	if err != nil {
+		return err
+	}
+	if starts {
+		b.Prefix(s)
+	} else {
+		b.Suffix(s)
+	}
+	return nil
+}
+
+// size parses the bit length of the keys, or the one below which they are
+func (p *parser) size(b *Builder) error {
+	op := p.next()
+	if op.kind != tokenSymbol || (op.text != "=" && op.text != "<") {
+		return fmt.Errorf("expected \"=\" or \"<\", got %q", op.text)
+	}
+	t := p.next()
+	if t.kind != tokenNumber {
+		return fmt.Errorf("expected a bit length, got %q", t.text)
+	}
+	v, err := strconv.ParseUint(t.text, 10, 64)
+	if err != nil {
+		return err
+	}
+	if op.text == "=" {
+		b.Value(v)
+	} else {
+		b.Below(v)
+	}
+	return nil
+}
+
+// created parses the year of creation of the keys or the date before which
+// they are created, either a year or a quoted date YYYY-MM-DD
+func (p *parser) created(b *Builder) error {
+	op := p.next()
+	if op.kind != tokenSymbol || (op.text != "=" && op.text != "<") {
+		return fmt.Errorf("expected \"=\" or \"<\", got %q", op.text)
+	}
+	t := p.next()
+	if op.text == "=" {
+		if t.kind != tokenNumber {
+			return fmt.Errorf("expected a year, got %q", t.text)
+		}
+		year, err := strconv.Atoi(t.text)
+		if err != nil {
+			return err
+		}
+		b.Year(year)
+		return nil
+	}
+
+	var before time.Time
+	switch t.kind {
+	case tokenNumber:
+		year, err := strconv.Atoi(t.text)
+		if err != nil {
+			return err
+		}
+		before = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	case tokenString:
+		var err error
+		before, err = time.Parse("2006-01-02", t.text)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("expected a year or a date, got %q", t.text)
+	}
+	b.Before(before)
+	return nil
+}
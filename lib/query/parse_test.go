@@ -0,0 +1,64 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	before := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]*ClientFSS{
+		"SELECT COUNT(*) WHERE email = 'Alice@Example.org'": (&Info{Target: UserId}).ToEmailClientFSS("Alice@Example.org"),
+		"select count(*) where email ends with 'epfl.ch'":   (&Info{Target: UserId, FromEnd: 7}).ToEmailClientFSS("epfl.ch"),
+		"SELECT COUNT(*) WHERE email STARTS WITH 'o''neil'": (&Info{Target: UserId, FromStart: 6}).ToEmailClientFSS("o'neil"),
+		"SELECT COUNT(*) WHERE domain = 'example.org'":      DomainClientFSS("example.org"),
+		"SELECT COUNT(*) WHERE size < 3072":                 mustNumber(&Info{Target: KeySize, Lt: true}, 3072),
+		"SELECT COUNT(*) WHERE NOT algo = 'RSA'":            (&Info{Target: PubKeyAlgo, Not: true}).ToPKAClientFSS("RSA"),
+		"SELECT COUNT(*) WHERE created = 2019":              (&Info{Target: CreationTime}).ToCreationTimeClientFSS("2019"),
+		"SELECT COUNT(*) WHERE created < '2010-01-01'":      (&Info{Target: CreationTime, Lt: true}).ToCreationTimeLtClientFSS(before),
+		"SELECT COUNT(*), SUM(bitlength) WHERE algo = 'rsa'": (&Info{
+			Target:     PubKeyAlgo,
+			Aggregates: []Aggregate{Count, SumBitLength},
+		}).ToPKAClientFSS("RSA"),
+		"SELECT SUM(years) WHERE created < 2010": (&Info{
+			Target:     CreationTime,
+			Lt:         true,
+			Aggregates: []Aggregate{SumYears},
+		}).ToCreationTimeLtClientFSS(before),
+	}
+	for statement, expected := range tests {
+		q, err := Parse(statement)
+		require.NoError(t, err, statement)
+		require.Equal(t, expected, q, statement)
+	}
+
+	for _, statement := range []string{
+		"",
+		"SELECT COUNT(*)",
+		"SELECT COUNT(email) WHERE algo = 'RSA'",
+		"SELECT AVG(years) WHERE algo = 'RSA'",
+		"SELECT COUNT(*), COUNT(*) WHERE algo = 'RSA'",
+		"SELECT COUNT(*) WHERE algo = 'AES'",
+		"SELECT COUNT(*) WHERE email = 'alice",
+		"SELECT COUNT(*) WHERE email ENDS WITH ''",
+		"SELECT COUNT(*) WHERE created = '2019'",
+		"SELECT COUNT(*) WHERE created < '2010'",
+		"SELECT COUNT(*) WHERE name = 'alice'",
+		"SELECT COUNT(*) WHERE NOT NOT algo = 'RSA'",
+		"SELECT COUNT(*) WHERE size = 65536",
+		"SELECT COUNT(*) WHERE algo = 'RSA' AND created = 2019",
+	} {
+		_, err := Parse(statement)
+		require.Error(t, err, statement)
+	}
+}
+
+func mustNumber(i *Info, v uint64) *ClientFSS {
+	q, err := i.ToNumberClientFSS(v)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
@@ -0,0 +1,417 @@
+package query
+
+import (
+	"crypto/aes"
+
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/proto"
+)
+
+// The queries are encoded with the protobuf messages of lib/proto, so that
+// clients in other languages can query the servers. The fields added to the
+// messages come with a new Version, since the decoders reject the fields
+// they do not know.
+
+// Proto returns the protobuf message of the query function
+func (i *Info) Proto() *proto.QueryInfo {
+	p := &proto.QueryInfo{
+		Target:     proto.Target(i.Target),
+		FromStart:  uint32(i.FromStart),
+		FromEnd:    uint32(i.FromEnd),
+		And:        i.And,
+		Avg:        i.Avg,
+		Sum:        i.Sum,
+		Lt:         i.Lt,
+		Not:        i.Not,
+		BucketSize: uint32(i.BucketSize),
+		Page:       i.Page,
+	}
+	for _, t := range i.Targets {
+		p.Targets = append(p.Targets, proto.Target(t))
+	}
+	for _, a := range i.Aggregates {
+		p.Aggregates = append(p.Aggregates, proto.Aggregate(a))
+	}
+	return p
+}
+
+// InfoFromProto returns the query function of the protobuf message, after
+// checking that its fields are consistent
+func InfoFromProto(p *proto.QueryInfo) (*Info, error) {
+	if p == nil {
+		return nil, malformed("missing query info")
+	}
+	target, err := targetFromProto(p.Target)
+	if err != nil {
+		return nil, err
+	}
+	i := &Info{
+		Target:     target,
+		FromStart:  int(p.FromStart),
+		FromEnd:    int(p.FromEnd),
+		And:        p.And,
+		Avg:        p.Avg,
+		Sum:        p.Sum,
+		Lt:         p.Lt,
+		Not:        p.Not,
+		BucketSize: int(p.BucketSize),
+		Page:       p.Page,
+	}
+	for _, t := range p.Targets {
+		target, err := targetFromProto(t)
+		if err != nil {
+			return nil, err
+		}
+		i.Targets = append(i.Targets, target)
+	}
+	for k, a := range p.Aggregates {
+		if _, ok := proto.Aggregate_name[int32(a)]; !ok {
+			return nil, malformed("unknown aggregate %d", a)
+		}
+		for _, other := range p.Aggregates[:k] {
+			if a == other {
+				return nil, malformed("aggregate selected twice")
+			}
+		}
+		i.Aggregates = append(i.Aggregates, Aggregate(a))
+	}
+
+	switch {
+	case len(i.Targets) > len(proto.Target_name):
+		return nil, malformed("too many targets")
+	case i.FromStart != 0 && i.FromEnd != 0:
+		return nil, malformed("substring from both the start and the end")
+	case 8*i.FromStart > MaxInputBits || 8*i.FromEnd > MaxInputBits:
+		return nil, ErrTooLarge
+	case i.Page && i.BucketSize == 0:
+		return nil, malformed("page without buckets")
+	case i.Sum:
+		return nil, malformed("sum not implemented")
+	case i.Avg && !i.And:
+		return nil, malformed("average without conjunction")
+	case i.And && (len(i.Aggregates) > 0 || i.Lt || i.Not || i.BucketSize != 0):
+		return nil, malformed("conjunction with other statistics")
+	}
+	if _, numeric := i.Target.Width(); i.Lt && !numeric {
+		return nil, malformed("comparison on a non-numeric target")
+	}
+	return i, nil
+}
+
+func targetFromProto(t proto.Target) (Target, error) {
+	if _, ok := proto.Target_name[int32(t)]; !ok {
+		return 0, malformed("unknown target %d", t)
+	}
+	return Target(t), nil
+}
+
+// Proto returns the protobuf message of the client query
+func (q *ClientFSS) Proto() *proto.ClientFSS {
+	return &proto.ClientFSS{
+		Info:      q.Info.Proto(),
+		Input:     packBits(q.Input),
+		InputBits: uint32(len(q.Input)),
+	}
+}
+
+// ClientFSSFromProto returns the client query of the protobuf message
+func ClientFSSFromProto(p *proto.ClientFSS) (*ClientFSS, error) {
+	info, err := InfoFromProto(p.GetInfo())
+	if err != nil {
+		return nil, err
+	}
+	if p.InputBits > MaxInputBits {
+		return nil, ErrTooLarge
+	}
+	input, err := unpackBits(p.Input, int(p.InputBits))
+	if err != nil {
+		return nil, err
+	}
+	if bits, ok := info.InputBits(); ok && len(input) != bits {
+		return nil, malformed("input of %d bits instead of %d", len(input), bits)
+	}
+	return &ClientFSS{Info: info, Input: input}, nil
+}
+
+// Proto returns the protobuf message of the query
+func (q *FSS) Proto() *proto.FSS {
+	p := &proto.FSS{
+		Info:      q.Info.Proto(),
+		Nonce:     q.Nonce,
+		MacShares: q.MACShares,
+	}
+	if q.Lt {
+		p.KeyLt = keyLtProto(&q.FssKeyLt)
+	} else {
+		p.Key = keyEqProto(&q.FssKey)
+	}
+	return p
+}
+
+// FSSFromProto returns the query of the protobuf message, whose FSS keys
+// must be as deep as the input of the query
+func FSSFromProto(p *proto.FSS) (*FSS, error) {
+	info, err := InfoFromProto(p.GetInfo())
+	if err != nil {
+		return nil, err
+	}
+	if len(p.MacShares) > MaxValues {
+		return nil, ErrTooLarge
+	}
+	q := &FSS{Info: info, Nonce: p.Nonce, MACShares: p.MacShares}
+	var depth int
+	if info.Lt {
+		if p.Key != nil {
+			return nil, malformed("point function key in a comparison query")
+		}
+		key, err := keyLtFromProto(p.KeyLt)
+		if err != nil {
+			return nil, err
+		}
+		q.FssKeyLt, depth = *key, len(key.CW)
+	} else {
+		if p.KeyLt != nil {
+			return nil, malformed("comparison key in an equality query")
+		}
+		key, err := keyEqFromProto(p.Key)
+		if err != nil {
+			return nil, err
+		}
+		q.FssKey, depth = *key, len(key.CW)
+		// only the queries matching the target, possibly with aggregates,
+		// are implemented in the 64-bit field
+		if len(key.FinalCW64) > 0 && (info.And || info.Avg || info.Not || info.BucketSize != 0) {
+			return nil, malformed("query not implemented for the 64-bit field")
+		}
+	}
+	if bits, ok := info.InputBits(); ok && depth != bits {
+		return nil, malformed("FSS key of depth %d instead of %d", depth, bits)
+	}
+	return q, nil
+}
+
+// Encode returns the encoding of the query
+func (q *FSS) Encode() ([]byte, error) {
+	return marshal(q.Proto())
+}
+
+// DecodeFSS returns the query of its encoding
+func DecodeFSS(in []byte) (*FSS, error) {
+	p := new(proto.FSS)
+	if err := unmarshal(in, p); err != nil {
+		return nil, err
+	}
+	return FSSFromProto(p)
+}
+
+// Proto returns the protobuf message of the write
+func (w *Write) Proto() *proto.Write {
+	return &proto.Write{
+		Id:      w.ID,
+		Key:     keyEqProto(&w.FssKey),
+		Triples: append(append([]uint32{}, w.Triples[0][:]...), w.Triples[1][:]...),
+	}
+}
+
+// WriteFromProto returns the write of the protobuf message
+func WriteFromProto(p *proto.Write) (*Write, error) {
+	if len(p.Id) != WriteIDLen {
+		return nil, malformed("write ID of %d bytes", len(p.Id))
+	}
+	key, err := keyEqFromProto(p.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Triples) != 6 {
+		return nil, malformed("Beaver triples of %d elements", len(p.Triples))
+	}
+	w := &Write{ID: p.Id, FssKey: *key}
+	copy(w.Triples[0][:], p.Triples[:3])
+	copy(w.Triples[1][:], p.Triples[3:])
+	return w, nil
+}
+
+// Encode returns the encoding of the write
+func (w *Write) Encode() ([]byte, error) {
+	return marshal(w.Proto())
+}
+
+// DecodeWrite returns the write of its encoding
+func DecodeWrite(in []byte) (*Write, error) {
+	p := new(proto.Write)
+	if err := unmarshal(in, p); err != nil {
+		return nil, err
+	}
+	return WriteFromProto(p)
+}
+
+// Proto returns the protobuf message of the contribution
+func (c *Contribution) Proto() *proto.Contribution {
+	return &proto.Contribution{
+		Id:     c.ID,
+		Shares: c.Shares,
+		F0:     c.F0,
+		G0:     c.G0,
+		H:      c.H,
+		Triple: append([]uint32{}, c.Triple[:]...),
+	}
+}
+
+// ContributionFromProto returns the contribution of the protobuf message
+func ContributionFromProto(p *proto.Contribution) (*Contribution, error) {
+	switch {
+	case len(p.Id) != ContributionIDLen:
+		return nil, malformed("contribution ID of %d bytes", len(p.Id))
+	case len(p.Shares) > MaxStatisticLength:
+		return nil, ErrTooLarge
+	case len(p.H) != 2*len(p.Shares)+1:
+		return nil, malformed("%d evaluations of h for %d shares", len(p.H), len(p.Shares))
+	case len(p.Triple) != 3:
+		return nil, malformed("Beaver triple of %d elements", len(p.Triple))
+	}
+	c := &Contribution{ID: p.Id, Shares: p.Shares, F0: p.F0, G0: p.G0, H: p.H}
+	copy(c.Triple[:], p.Triple)
+	return c, nil
+}
+
+// Encode returns the encoding of the contribution
+func (c *Contribution) Encode() ([]byte, error) {
+	return marshal(c.Proto())
+}
+
+// DecodeContribution returns the contribution of its encoding
+func DecodeContribution(in []byte) (*Contribution, error) {
+	p := new(proto.Contribution)
+	if err := unmarshal(in, p); err != nil {
+		return nil, err
+	}
+	return ContributionFromProto(p)
+}
+
+func keyEqProto(k *fss.FssKeyEq2P) *proto.FSSKeyEq {
+	return &proto.FSSKeyEq{
+		SInit:     k.SInit,
+		TInit:     uint32(k.TInit),
+		Cw:        k.CW,
+		FinalCW:   k.FinalCW,
+		FinalCW64: k.FinalCW64,
+		Epoch:     k.Epoch,
+	}
+}
+
+// keyEqFromProto returns the key of the protobuf message, whose values are
+// either in the 32-bit or in the 64-bit field
+func keyEqFromProto(p *proto.FSSKeyEq) (*fss.FssKeyEq2P, error) {
+	if p == nil {
+		return nil, malformed("missing FSS key")
+	}
+	if err := checkKey(p.SInit, p.TInit, p.Cw); err != nil {
+		return nil, err
+	}
+	if (len(p.FinalCW) == 0) == (len(p.FinalCW64) == 0) {
+		return nil, malformed("FSS key values in none or both fields")
+	}
+	if len(p.FinalCW) > MaxValues || len(p.FinalCW64) > MaxValues {
+		return nil, ErrTooLarge
+	}
+	return &fss.FssKeyEq2P{
+		SInit:     p.SInit,
+		TInit:     byte(p.TInit),
+		CW:        p.Cw,
+		FinalCW:   p.FinalCW,
+		FinalCW64: p.FinalCW64,
+		Epoch:     p.Epoch,
+	}, nil
+}
+
+func keyLtProto(k *fss.FssKeyLt2P) *proto.FSSKeyLt {
+	p := &proto.FSSKeyLt{
+		SInit:   k.SInit,
+		TInit:   uint32(k.TInit),
+		Cw:      k.CW,
+		FinalCW: k.FinalCW,
+		Epoch:   k.Epoch,
+	}
+	for _, v := range k.VCW {
+		p.Vcw = append(p.Vcw, v...)
+	}
+	return p
+}
+
+// keyLtFromProto returns the key of the protobuf message, whose value
+// correction words are split in one block per level
+func keyLtFromProto(p *proto.FSSKeyLt) (*fss.FssKeyLt2P, error) {
+	if p == nil {
+		return nil, malformed("missing FSS key")
+	}
+	if err := checkKey(p.SInit, p.TInit, p.Cw); err != nil {
+		return nil, err
+	}
+	if len(p.FinalCW) > MaxValues {
+		return nil, ErrTooLarge
+	}
+	if len(p.Cw) == 0 || len(p.FinalCW) == 0 || len(p.Vcw) != len(p.Cw)*len(p.FinalCW) {
+		return nil, malformed("value correction words of a comparison key")
+	}
+	k := &fss.FssKeyLt2P{
+		SInit:   p.SInit,
+		TInit:   byte(p.TInit),
+		CW:      p.Cw,
+		VCW:     make([][]uint32, len(p.Cw)),
+		FinalCW: p.FinalCW,
+		Epoch:   p.Epoch,
+	}
+	n := len(p.FinalCW)
+	for l := range k.VCW {
+		k.VCW[l] = p.Vcw[l*n : (l+1)*n]
+	}
+	return k, nil
+}
+
+// packBits returns the bits packed in bytes, most significant bit first, as
+// utils.ByteToBits unpacks them
+func packBits(bits []bool) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// checkKey checks the seed, the control bit and the correction words of
+// the levels of an FSS key
+func checkKey(sInit []byte, tInit uint32, cw [][]byte) error {
+	if len(cw) > MaxInputBits {
+		return ErrTooLarge
+	}
+	if len(sInit) != aes.BlockSize || tInit > 1 {
+		return malformed("FSS key seed")
+	}
+	for _, c := range cw {
+		if len(c) != aes.BlockSize+2 {
+			return malformed("FSS key correction word of %d bytes", len(c))
+		}
+	}
+	return nil
+}
+
+// unpackBits returns the n bits packed by packBits, whose padding must be
+// zero so that every input has a single encoding
+func unpackBits(in []byte, n int) ([]bool, error) {
+	if n == 0 && len(in) == 0 {
+		return nil, nil
+	}
+	if n > 8*len(in) || 8*len(in)-n >= 8 {
+		return nil, malformed("input of %d bits in %d bytes", n, len(in))
+	}
+	if n%8 != 0 && in[len(in)-1]&(0xff>>(n%8)) != 0 {
+		return nil, malformed("input padding")
+	}
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = in[i/8]&(0x80>>(i%8)) != 0
+	}
+	return out, nil
+}
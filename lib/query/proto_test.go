@@ -0,0 +1,169 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	f := fss.ClientInitialize(3)
+	// the epoch of the PRF keys is carried with the keys
+	f.Epoch = 7
+	values := []uint32{1, 2, 3}
+
+	info := &Info{Target: UserId, FromEnd: 7, Aggregates: []Aggregate{Count, SumBitLength}, Not: true}
+	in := info.ToEmailClientFSS("epfl.ch")
+	encoded, err := in.Encode()
+	require.NoError(t, err)
+	decoded, err := DecodeClientFSS(encoded)
+	require.NoError(t, err)
+	require.Equal(t, in, decoded)
+
+	keys := f.GenerateTreePF(in.Input, values)
+	q := &FSS{Info: info, FssKey: keys[0], Nonce: []byte{1, 2}, MACShares: []uint32{4, 5}}
+	encoded, err = q.Encode()
+	require.NoError(t, err)
+	q2, err := DecodeFSS(encoded)
+	require.NoError(t, err)
+	require.Equal(t, q, q2)
+
+	page := (&Info{Target: PubKeyAlgo, BucketSize: 64}).ToPKAClientFSS("DSA").PageClientFSS(3)
+	encoded, err = page.Encode()
+	require.NoError(t, err)
+	decoded, err = DecodeClientFSS(encoded)
+	require.NoError(t, err)
+	require.Equal(t, page, decoded)
+	require.Len(t, decoded.Input, BucketBits+8)
+	require.Equal(t, []Aggregate{Count}, decoded.Aggregates)
+
+	lt := &Info{Target: CreationTime, Lt: true}
+	in = lt.ToCreationTimeLtClientFSS(time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC))
+	q = &FSS{Info: lt, FssKeyLt: f.GenerateTreeLt(in.Input, values)[1]}
+	encoded, err = q.Encode()
+	require.NoError(t, err)
+	q2, err = DecodeFSS(encoded)
+	require.NoError(t, err)
+	require.Equal(t, q, q2)
+
+	w := &Write{ID: make([]byte, WriteIDLen), FssKey: keys[1], Triples: [2][3]uint32{{1, 2, 3}, {4, 5, 6}}}
+	encoded, err = w.Encode()
+	require.NoError(t, err)
+	w2, err := DecodeWrite(encoded)
+	require.NoError(t, err)
+	require.Equal(t, w, w2)
+
+	c := &Contribution{ID: make([]byte, ContributionIDLen), Shares: []uint32{1, 0}, F0: 7, G0: 8, H: []uint32{1, 2, 3, 4, 5}, Triple: [3]uint32{1, 2, 3}}
+	encoded, err = c.Encode()
+	require.NoError(t, err)
+	c2, err := DecodeContribution(encoded)
+	require.NoError(t, err)
+	require.Equal(t, c, c2)
+}
+
+func TestProtoMalformed(t *testing.T) {
+	// unknown target
+	p := (&Info{Target: 7}).Proto()
+	_, err := InfoFromProto(p)
+	require.Error(t, err)
+
+	// missing key
+	q := (&FSS{Info: &Info{Target: PubKeyAlgo}}).Proto()
+	q.Key = nil
+	_, err = FSSFromProto(q)
+	require.Error(t, err)
+
+	// more bits than the input holds
+	in := (&Info{Target: PubKeyAlgo}).ToPKAClientFSS("RSA").Proto()
+	in.InputBits = 9
+	_, err = ClientFSSFromProto(in)
+	require.Error(t, err)
+
+	_, err = DecodeFSS([]byte{0xff})
+	require.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestDecodeStrict(t *testing.T) {
+	f := fss.ClientInitialize(2)
+	info := &Info{Target: PubKeyAlgo}
+	in := info.ToPKAClientFSS("RSA")
+	keys := f.GenerateTreePF(in.Input, []uint32{1, 2})
+	encoded, err := (&FSS{Info: info, FssKey: keys[0]}).Encode()
+	require.NoError(t, err)
+	_, err = DecodeFSS(encoded)
+	require.NoError(t, err)
+
+	// header and version
+	_, err = DecodeFSS(encoded[len(magic)+1:])
+	require.ErrorIs(t, err, ErrMalformed)
+	other := append([]byte{}, encoded...)
+	other[len(magic)] = Version + 1
+	_, err = DecodeFSS(other)
+	require.ErrorIs(t, err, ErrVersion)
+
+	// unknown field 99, with varint value 1
+	_, err = DecodeFSS(append(append([]byte{}, encoded...), 0x98, 0x06, 0x01))
+	require.ErrorIs(t, err, ErrMalformed)
+
+	// size limits
+	_, err = DecodeFSS(make([]byte, MaxEncodedLen+1))
+	require.ErrorIs(t, err, ErrTooLarge)
+	p := (&FSS{Info: info, FssKey: keys[0], MACShares: make([]uint32, MaxValues+1)}).Proto()
+	_, err = FSSFromProto(p)
+	require.ErrorIs(t, err, ErrTooLarge)
+
+	// key shallower than the input of the target, which the servers could
+	// not evaluate
+	p = (&FSS{Info: &Info{Target: KeySize}, FssKey: keys[0]}).Proto()
+	_, err = FSSFromProto(p)
+	require.ErrorIs(t, err, ErrMalformed)
+
+	// truncated correction word and seed
+	p = (&FSS{Info: info, FssKey: keys[0]}).Proto()
+	p.Key.Cw[3] = p.Key.Cw[3][:5]
+	_, err = FSSFromProto(p)
+	require.ErrorIs(t, err, ErrMalformed)
+	p = (&FSS{Info: info, FssKey: keys[0]}).Proto()
+	p.Key.SInit = nil
+	_, err = FSSFromProto(p)
+	require.ErrorIs(t, err, ErrMalformed)
+
+	// inconsistent query functions
+	for _, i := range []*Info{
+		{Target: UserId, FromStart: 3, FromEnd: 3},
+		{Target: UserId, Lt: true},
+		{Target: PubKeyAlgo, Page: true},
+		{Target: PubKeyAlgo, Aggregates: []Aggregate{Count, Count}},
+		{Target: UserId, Sum: true},
+		{Target: UserId, Avg: true},
+		{Target: UserId, And: true, Sum: true},
+		{Target: UserId, And: true, Avg: true, Sum: true},
+		{Target: UserId, And: true, Not: true},
+		{Target: PubKeyAlgo, And: true, Aggregates: []Aggregate{Count}},
+	} {
+		_, err = InfoFromProto(i.Proto())
+		require.ErrorIs(t, err, ErrMalformed)
+	}
+	_, err = InfoFromProto((&Info{Target: UserId, And: true, Avg: true}).Proto())
+	require.NoError(t, err)
+
+	// negation in the 64-bit field
+	keys64 := f.GenerateTreePF64(in.Input, []uint64{1, 2})
+	p = (&FSS{Info: &Info{Target: PubKeyAlgo}, FssKey: keys64[0]}).Proto()
+	_, err = FSSFromProto(p)
+	require.NoError(t, err)
+	p = (&FSS{Info: &Info{Target: PubKeyAlgo, Not: true}, FssKey: keys64[0]}).Proto()
+	_, err = FSSFromProto(p)
+	require.ErrorIs(t, err, ErrMalformed)
+
+	// input of another length than the one of the target, and non-zero
+	// padding of the input
+	c := in.Proto()
+	c.InputBits = 7
+	_, err = ClientFSSFromProto(c)
+	require.ErrorIs(t, err, ErrMalformed)
+	_, err = unpackBits([]byte{0x01}, 7)
+	require.ErrorIs(t, err, ErrMalformed)
+}
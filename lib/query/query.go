@@ -1,15 +1,17 @@
 package query
 
 import (
-	"bytes"
 	"encoding/binary"
-	"encoding/gob"
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nikirill/go-crypto/openpgp/packet"
 	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/pgp"
+	"github.com/si-co/vpir-code/lib/proto"
 	"github.com/si-co/vpir-code/lib/utils"
 	"golang.org/x/crypto/blake2b"
 )
@@ -25,8 +27,68 @@ const (
 
 	// RSA, ED25519, ...
 	PubKeyAlgo
+
+	// KeySize is the bit length of the key
+	KeySize
+)
+
+// Width returns the number of bits of the fixed-width big-endian encoding
+// of the values of a numeric target, i.e., the input of the comparison
+// queries on the target: the seconds since the Unix epoch for CreationTime,
+// the algorithm ID for PubKeyAlgo and the bit length for KeySize. It
+// returns false for the other targets.
+func (t Target) Width() (int, bool) {
+	switch t {
+	case CreationTime:
+		return 64, true
+	case PubKeyAlgo:
+		return 8, true
+	case KeySize:
+		return 16, true
+	default:
+		return 0, false
+	}
+}
+
+// Aggregate defines a statistic computed by the servers over all the
+// entries matching the query
+type Aggregate uint8
+
+const (
+	// Count is the number of matching keys
+	Count Aggregate = iota
+
+	// SumYears is the sum of the ages, in years, of the matching keys
+	SumYears
+
+	// SumBitLength is the sum of the bit lengths of the matching keys
+	SumBitLength
 )
 
+const (
+	// MaxYears bounds the age of a key in the noisy sums of years
+	MaxYears = 64
+	// MaxBitLength bounds the bit length of a key in the noisy sums of bit
+	// lengths
+	MaxBitLength = 16384
+)
+
+// Sensitivity returns the largest change of the aggregate when a key is
+// added to or removed from the db, with the values of the keys clamped to
+// MaxYears and MaxBitLength
+func (a Aggregate) Sensitivity() uint64 {
+	switch a {
+	case Count:
+		return 1
+	case SumYears:
+		return MaxYears
+	case SumBitLength:
+		return MaxBitLength
+	default:
+		panic("aggregate not recognized")
+	}
+}
+
 // ClientFSS is used by the client to prepare an FSS
 type ClientFSS struct {
 	*Info
@@ -36,7 +98,24 @@ type ClientFSS struct {
 // FSS is what is sent to the server, one by server
 type FSS struct {
 	*Info
-	FssKey fss.FssKeyEq2P
+	FssKey   fss.FssKeyEq2P
+	FssKeyLt fss.FssKeyLt2P // only used for comparison queries
+
+	// only used for the noisy answers of a db with a positive NoiseEpsilon:
+	// the nonce of the query, which selects the noise of the servers
+	Nonce []byte
+	// only used for the noisy answers and the negated queries: the share of
+	// the MAC keys of the client, with which the server authenticates the
+	// values it adds to the answer, i.e., the noise or the totals
+	MACShares []uint32
+}
+
+// Epoch returns the epoch of the PRF keys of the FSS key of the query
+func (q *FSS) Epoch() uint64 {
+	if q.Lt {
+		return q.FssKeyLt.Epoch
+	}
+	return q.FssKey.Epoch
 }
 
 // Info defines the query function
@@ -57,29 +136,85 @@ type Info struct {
 	// to perform SUM query
 	// TODO: not implemented yet, but implicitely used in AVG
 	Sum bool
+
+	// to compute several statistics over the entries matching Target in a
+	// single pass. The answer contains one result per aggregate, in the
+	// given order.
+	Aggregates []Aggregate
+
+	// to perform a less-than comparison on the target instead of an
+	// equality, e.g., to count the keys created before a given time
+	Lt bool
+
+	// to negate the match, e.g., to count the keys not in a domain. The
+	// aggregates of the negated query are the totals over all the keys
+	// minus the aggregates over the matching ones.
+	Not bool
+
+	// to answer per bucket of BucketSize consecutive keys instead of over
+	// all the keys, for the cursors over the matching keys. The answer holds
+	// the count of matching keys of every bucket, or, for a Page query, the
+	// aggregates of every key of a single bucket, selected by the first
+	// BucketBits bits of the input so that the servers do not learn it.
+	BucketSize int
+	Page       bool
 }
 
-func (q *ClientFSS) Encode() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	enc := gob.NewEncoder(buf)
-	if err := enc.Encode(q); err != nil {
-		return nil, err
+// InputBits returns the number of bits of the input of the query, i.e., the
+// depth of its FSS keys, when it does not depend on the keys of the db. It
+// returns false for the equalities on the creation time, whose encoding
+// depends on the time zone, and for the conjunctions and averages.
+func (i *Info) InputBits() (int, bool) {
+	if i.And || i.Avg || i.Sum {
+		return 0, false
+	}
+	var bits int
+	width, numeric := i.Target.Width()
+	switch {
+	case i.Lt && numeric:
+		bits = width
+	case i.Target == UserId && i.FromStart != 0:
+		bits = 8 * i.FromStart
+	case i.Target == UserId && i.FromEnd != 0:
+		bits = 8 * i.FromEnd
+	case i.Target == UserId:
+		// truncated hash of the email
+		bits = 128
+	case i.Target == PubKeyAlgo || i.Target == KeySize:
+		bits = width
+	default:
+		return 0, false
+	}
+	if i.Page {
+		bits += BucketBits
 	}
-	return buf.Bytes(), nil
+	return bits, true
+}
+
+// BucketBits is the number of bits of the index of the bucket in the input
+// of the page queries
+const BucketBits = 32
+
+// Encode returns the encoding of the client query
+func (q *ClientFSS) Encode() ([]byte, error) {
+	return marshal(q.Proto())
 }
 
+// DecodeClientFSS returns the client query of its encoding
 func DecodeClientFSS(in []byte) (*ClientFSS, error) {
-	dec := gob.NewDecoder(bytes.NewBuffer(in))
-	v := &ClientFSS{}
-	err := dec.Decode(v)
-	if err != nil {
+	p := new(proto.ClientFSS)
+	if err := unmarshal(in, p); err != nil {
 		return nil, err
 	}
-
-	return v, nil
+	return ClientFSSFromProto(p)
 }
 
+// ToEmailClientFSS returns the query of the given email. A full email is
+// normalized as in the db, whereas prefixes and suffixes are taken as is.
 func (i *Info) ToEmailClientFSS(in string) *ClientFSS {
+	if i.FromStart == 0 && i.FromEnd == 0 {
+		in = pgp.NormalizeEmail(in)
+	}
 	id, _ := i.IdForEmail(in)
 	return &ClientFSS{
 		Info:  i,
@@ -87,6 +222,14 @@ func (i *Info) ToEmailClientFSS(in string) *ClientFSS {
 	}
 }
 
+// DomainClientFSS returns the query matching all the keys whose email is in
+// the given domain, e.g., example.org
+func DomainClientFSS(domain string) *ClientFSS {
+	suffix := "@" + pgp.NormalizeDomain(strings.TrimPrefix(domain, "@"))
+	info := &Info{Target: UserId, FromEnd: len(suffix)}
+	return info.ToEmailClientFSS(suffix)
+}
+
 func (i *Info) ToPKAClientFSS(in string) *ClientFSS {
 	var pka packet.PublicKeyAlgorithm
 	switch in {
@@ -124,6 +267,15 @@ func (i *Info) ToCreationTimeClientFSS(in string) *ClientFSS {
 	}
 }
 
+// ToCreationTimeLtClientFSS returns the query matching all the keys created
+// before t. The info must have Lt set.
+func (i *Info) ToCreationTimeLtClientFSS(t time.Time) *ClientFSS {
+	return &ClientFSS{
+		Info:  i,
+		Input: i.IdForCreationTimeLt(t),
+	}
+}
+
 // TODO: hardcoded for the moment, FIX
 func (i *Info) ToAndClientFSS(in string) *ClientFSS {
 	idYear, err := i.IdForYearCreationTime(time.Date(2019, 0, 0, 0, 0, 0, 0, time.UTC))
@@ -166,28 +318,47 @@ func (q *FSS) IdForYearCreationTime(t time.Time) ([]bool, error) {
 	return q.Info.IdForYearCreationTime(t)
 }
 
+func (q *FSS) IdForCreationTimeLt(t time.Time) []bool {
+	return q.Info.IdForCreationTimeLt(t)
+}
+
+func (q *FSS) IdForNumber(v uint64) ([]bool, error) {
+	return q.Info.IdForNumber(v)
+}
+
+func (q *FSS) AppendIdForEmail(dst []bool, email string) ([]bool, bool) {
+	return q.Info.AppendIdForEmail(dst, email)
+}
+
+func (q *FSS) AppendIdForNumber(dst []bool, v uint64) ([]bool, error) {
+	return q.Info.AppendIdForNumber(dst, v)
+}
+
 func (i *Info) IdForEmail(email string) ([]bool, bool) {
-	var id []bool
+	return i.AppendIdForEmail(nil, email)
+}
+
+// AppendIdForEmail appends the input for the email to dst, as IdForEmail
+// does, so that the servers reuse the slice of the inputs across the keys
+func (i *Info) AppendIdForEmail(dst []bool, email string) ([]bool, bool) {
 	if i.FromStart != 0 {
 		if i.FromStart > len(email) {
-			return nil, false
+			return dst, false
 		}
-		id = utils.ByteToBits([]byte(email[:i.FromStart]))
+		return utils.AppendBits(dst, []byte(email[:i.FromStart])), true
 	} else if i.FromEnd != 0 {
 		if i.FromEnd > len(email) {
-			return nil, false
+			return dst, false
 		}
-		id = utils.ByteToBits([]byte(email[len(email)-i.FromEnd:]))
-	} else {
-		h := blake2b.Sum256([]byte(email))
-		id = utils.ByteToBits(h[:16])
+		return utils.AppendBits(dst, []byte(email[len(email)-i.FromEnd:])), true
 	}
-
-	return id, true
+	h := blake2b.Sum256([]byte(email))
+	return utils.AppendBits(dst, h[:16]), true
 }
 
 func (i *Info) IdForPubKeyAlgo(pka packet.PublicKeyAlgorithm) []bool {
-	return utils.ByteToBits([]byte{uint8(pka)})
+	id, _ := i.IdForNumber(uint64(pka))
+	return id
 }
 
 func (i *Info) IdForCreationTime(t time.Time) ([]bool, error) {
@@ -204,3 +375,76 @@ func (i *Info) IdForYearCreationTime(t time.Time) ([]bool, error) {
 	binary.BigEndian.PutUint32(b, y)
 	return utils.ByteToBits(b), nil
 }
+
+// IdForCreationTimeLt returns the input for comparison queries on the
+// creation time, i.e., the seconds since the Unix epoch as a 64-bit unsigned
+// integer, most significant bit first.
+func (i *Info) IdForCreationTimeLt(t time.Time) []bool {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.Unix()))
+	return utils.ByteToBits(b)
+}
+
+// IdForNumber returns the input for the value of the numeric target of the
+// query, in big-endian order on the width of the target. It returns an
+// error if the target is not numeric or the value does not fit its width.
+func (i *Info) IdForNumber(v uint64) ([]bool, error) {
+	return i.AppendIdForNumber(nil, v)
+}
+
+// AppendIdForNumber appends the input for the value of the numeric target
+// to dst, as IdForNumber does
+func (i *Info) AppendIdForNumber(dst []bool, v uint64) ([]bool, error) {
+	width, ok := i.Target.Width()
+	if !ok {
+		return dst, fmt.Errorf("target %d is not numeric", i.Target)
+	}
+	if width < 64 && v >= 1<<width {
+		return dst, fmt.Errorf("value %d does not fit in %d bits", v, width)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return utils.AppendBits(dst, b[8-width/8:]), nil
+}
+
+// PageClientFSS returns the page query of the given bucket, matching the
+// same keys as the query, whose info must have BucketSize set. The page
+// aggregates are the count, i.e., whether the key matches, followed by the
+// aggregates of the query.
+func (q *ClientFSS) PageClientFSS(bucket int) *ClientFSS {
+	info := *q.Info
+	info.Page = true
+	info.Aggregates = append([]Aggregate{Count}, q.Aggregates...)
+	return &ClientFSS{
+		Info:  &info,
+		Input: append(IdForBucket(bucket), q.Input...),
+	}
+}
+
+// IdForBucket returns the input selecting the bucket in a page query, i.e.,
+// the index of the bucket as a BucketBits-bit unsigned integer, most
+// significant bit first
+func IdForBucket(bucket int) []bool {
+	return AppendIdForBucket(nil, bucket)
+}
+
+// AppendIdForBucket appends the input selecting the bucket to dst, as
+// IdForBucket does
+func AppendIdForBucket(dst []bool, bucket int) []bool {
+	var b [BucketBits / 8]byte
+	binary.BigEndian.PutUint32(b[:], uint32(bucket))
+	return utils.AppendBits(dst, b[:])
+}
+
+// ToNumberClientFSS returns the query matching the keys whose numeric
+// target is v, or is smaller than v if the info has Lt set
+func (i *Info) ToNumberClientFSS(v uint64) (*ClientFSS, error) {
+	id, err := i.IdForNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientFSS{
+		Info:  i,
+		Input: id,
+	}, nil
+}
@@ -0,0 +1,17 @@
+package query
+
+import "github.com/si-co/vpir-code/lib/fss"
+
+// WriteIDLen is the length in bytes of the identifier of a private write,
+// from which the servers derive the randomness of its audit
+const WriteIDLen = 16
+
+// Write is a private write sent to one of the two servers of the write
+// scheme: the DPF key of the point function that is the message at its slot,
+// and the shares of the two Beaver triples used by the servers to audit the
+// write without learning its slot
+type Write struct {
+	ID      []byte
+	FssKey  fss.FssKeyEq2P
+	Triples [2][3]uint32
+}
@@ -0,0 +1,339 @@
+// Package rlwe implements the ring-LWE (BFV) encryption used by the
+// single-server lattice PIR scheme: encryption of the queries from a seed,
+// oblivious expansion of the queries by the server, products by the
+// plaintexts of the db and modulus switching of the answers.
+package rlwe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+const (
+	// LogT is the number of bits of the plaintext modulus T = 2^LogT, so that
+	// every coefficient of a plaintext stores a byte
+	LogT = 8
+	// LogQAnswer is the number of bits of the modulus the answers are
+	// switched to before being sent to the client
+	LogQAnswer = 32
+)
+
+// Params are the parameters of the lattice PIR scheme
+type Params struct {
+	LogN int    // degree of the ring, 2^LogN
+	Q    uint64 // prime modulus of the ciphertexts
+	LogB int    // bits of the base of the gadget decomposition
+	// bits of the modulus the intermediate ciphertexts of the recursion are
+	// switched to before being decomposed in plaintexts, a multiple of LogT
+	LogQ1 int
+	// number of dimensions of the db, 1 or 2
+	Dimensions int
+
+	ring *Ring
+}
+
+// DefaultParams returns the parameters of the scheme with rings of degree
+// 4096 and a 60-bit modulus, over a 2-dimensional db. With ternary secrets,
+// the rings of degree 4096 give 128 bits of security for the moduli of up to
+// 109 bits, following the homomorphic encryption standard.
+func DefaultParams() *Params {
+	p, err := NewParams(12, 0x0ffffffffffc0001, 12, 24, 2)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// NewParams returns the parameters, after checking their consistency
+func NewParams(logN int, q uint64, logB, logQ1, dimensions int) (*Params, error) {
+	if logQ1 <= LogT || logQ1%LogT != 0 || logQ1 > LogQAnswer {
+		return nil, fmt.Errorf("invalid intermediate modulus 2^%d", logQ1)
+	}
+	if logB <= 0 || logB > 30 {
+		return nil, fmt.Errorf("invalid decomposition base 2^%d", logB)
+	}
+	if dimensions != 1 && dimensions != 2 {
+		return nil, fmt.Errorf("invalid number of dimensions %d", dimensions)
+	}
+	r, err := NewRing(logN, q)
+	if err != nil {
+		return nil, err
+	}
+	return &Params{LogN: logN, Q: q, LogB: logB, LogQ1: logQ1, Dimensions: dimensions, ring: r}, nil
+}
+
+// N returns the degree of the ring, i.e., the number of bytes of a plaintext
+func (p *Params) N() int {
+	return 1 << p.LogN
+}
+
+// Ring returns the ring of the ciphertexts
+func (p *Params) Ring() *Ring {
+	return p.ring
+}
+
+// delta returns the scaling factor of the plaintexts, floor(Q / T)
+func (p *Params) delta() uint64 {
+	return p.Q >> LogT
+}
+
+// digits returns the number of digits of the gadget decomposition
+func (p *Params) digits() int {
+	return (bits.Len64(p.Q) + p.LogB - 1) / p.LogB
+}
+
+// Ciphertext is a BFV ciphertext (C0, C1), decrypted as C0 + C1 * s
+type Ciphertext struct {
+	C0, C1 Poly
+}
+
+// SeededCiphertext is a fresh ciphertext whose uniformly random C1 is
+// expanded from a seed, which halves its size
+type SeededCiphertext struct {
+	Seed utils.PRGKey
+	C0   Poly
+}
+
+// Expand returns the ciphertext, in the coefficient domain
+func (ct *SeededCiphertext) Expand(p *Params) *Ciphertext {
+	return &Ciphertext{C0: ct.C0, C1: p.ring.Uniform(utils.NewPRG(&ct.Seed))}
+}
+
+// Bytes returns the encoding of the ciphertext
+func (ct *SeededCiphertext) Bytes() []byte {
+	return append(append([]byte{}, ct.Seed[:]...), utils.Uint64SliceToByteSlice(ct.C0)...)
+}
+
+// SeededCiphertextLen returns the length of an encoded seeded ciphertext
+func (p *Params) SeededCiphertextLen() int {
+	return len(utils.PRGKey{}) + 8*p.N()
+}
+
+// DecodeSeededCiphertext decodes a seeded ciphertext, rejecting the
+// coefficients out of range
+func (p *Params) DecodeSeededCiphertext(data []byte) (*SeededCiphertext, error) {
+	if len(data) != p.SeededCiphertextLen() {
+		return nil, errors.New("invalid length of ciphertext")
+	}
+	ct := new(SeededCiphertext)
+	copy(ct.Seed[:], data)
+	ct.C0 = utils.ByteSliceToUint64Slice(data[len(ct.Seed):])
+	for _, c := range ct.C0 {
+		if c >= p.Q {
+			return nil, errors.New("non-canonical ciphertext")
+		}
+	}
+	return ct, nil
+}
+
+// EncodeCiphertexts returns the encoding of ciphertexts whose coefficients
+// are smaller than 2^LogQAnswer
+func EncodeCiphertexts(cts []*Ciphertext) []byte {
+	out := make([]byte, 0)
+	for _, ct := range cts {
+		for _, poly := range []Poly{ct.C0, ct.C1} {
+			c := make([]uint32, len(poly))
+			for i := range poly {
+				c[i] = uint32(poly[i])
+			}
+			out = append(out, utils.Uint32SliceToByteSlice(c)...)
+		}
+	}
+	return out
+}
+
+// DecodeCiphertexts decodes the given number of ciphertexts encoded by
+// EncodeCiphertexts
+func (p *Params) DecodeCiphertexts(data []byte, num int) ([]*Ciphertext, error) {
+	polyLen := 4 * p.N()
+	if len(data) != 2*num*polyLen {
+		return nil, fmt.Errorf("%d bytes for %d ciphertexts", len(data), num)
+	}
+	cts := make([]*Ciphertext, num)
+	for i := range cts {
+		polys := make([]Poly, 2)
+		for k := range polys {
+			c := utils.ByteSliceToUint32Slice(data[(2*i+k)*polyLen : (2*i+k+1)*polyLen])
+			polys[k] = make(Poly, len(c))
+			for j := range c {
+				polys[k][j] = uint64(c[j])
+			}
+		}
+		cts[i] = &Ciphertext{C0: polys[0], C1: polys[1]}
+	}
+	return cts, nil
+}
+
+// SecretKey is a ternary secret key
+type SecretKey struct {
+	params *Params
+	s      Poly // coefficient domain
+	sNTT   Poly
+}
+
+// NewSecretKey returns a random secret key
+func NewSecretKey(p *Params, rnd io.Reader) *SecretKey {
+	s := p.ring.Ternary(rnd)
+	sNTT := append(Poly{}, s...)
+	p.ring.NTT(sNTT)
+	return &SecretKey{params: p, s: s, sNTT: sNTT}
+}
+
+// mulS returns a * s, for a in the coefficient domain
+func (sk *SecretKey) mulS(a Poly) Poly {
+	r := sk.params.ring
+	aNTT := append(Poly{}, a...)
+	r.NTT(aNTT)
+	out := r.NewPoly()
+	r.MulCoeffsAdd(aNTT, sk.sNTT, out)
+	r.InvNTT(out)
+	return out
+}
+
+// encrypt returns a fresh encryption of m, i.e., -a * s + e + m, with a
+// expanded from a fresh seed
+func (sk *SecretKey) encrypt(m Poly, rnd io.Reader) *SeededCiphertext {
+	r := sk.params.ring
+	ct := new(SeededCiphertext)
+	if _, err := io.ReadFull(rnd, ct.Seed[:]); err != nil {
+		panic(err)
+	}
+	a := r.Uniform(utils.NewPRG(&ct.Seed))
+	ct.C0 = r.Gauss()
+	r.Sub(ct.C0, sk.mulS(a), ct.C0)
+	r.Add(ct.C0, m, ct.C0)
+	return ct
+}
+
+// EncryptIndex returns the query selecting the index i among 2^logM
+// elements, i.e., an encryption of X^i scaled by 1 / 2^logM, which the
+// expansion by the server scales back
+func (sk *SecretKey) EncryptIndex(i, logM int, rnd io.Reader) *SeededCiphertext {
+	m := sk.params.ring.NewPoly()
+	m[i] = (sk.params.delta() + (1<<logM)/2) >> logM
+	return sk.encrypt(m, rnd)
+}
+
+// DecryptPow2 returns the plaintext bytes of a ciphertext modulo 2^logQ,
+// with logQ at most 32
+func (sk *SecretKey) DecryptPow2(ct *Ciphertext, logQ int) []byte {
+	r := sk.params.ring
+	// the product c1 * s is exact in the ring, since its coefficients are
+	// smaller than N * 2^32 < Q / 2
+	c1s := sk.mulS(ct.C1)
+	mask := uint64(1)<<logQ - 1
+	shift := logQ - LogT
+	out := make([]byte, r.N)
+	for i := range out {
+		v := (ct.C0[i] + uint64(r.Center(c1s[i]))) & mask
+		out[i] = byte(((v + 1<<(shift-1)) >> shift) & (1<<LogT - 1))
+	}
+	return out
+}
+
+// Plaintext is a plaintext in the NTT domain, along with the Shoup
+// companions of its coefficients
+type Plaintext struct {
+	Poly, Shoup Poly
+}
+
+// EncodePlaintext returns the plaintext of N bytes, with the bytes
+// represented by their centered value modulo T to reduce the noise of the
+// products
+func (p *Params) EncodePlaintext(data []byte) *Plaintext {
+	r := p.ring
+	poly := r.NewPoly()
+	for i, b := range data {
+		v := int64(b)
+		if v >= 1<<(LogT-1) {
+			v -= 1 << LogT
+		}
+		poly[i] = r.FromInt(v)
+	}
+	r.NTT(poly)
+	return &Plaintext{Poly: poly, Shoup: r.Shoup(poly)}
+}
+
+// NewCiphertext returns the encryption of zero without noise
+func (p *Params) NewCiphertext() *Ciphertext {
+	return &Ciphertext{C0: p.ring.NewPoly(), C1: p.ring.NewPoly()}
+}
+
+// NTT maps the ciphertext to the NTT domain in place
+func (p *Params) NTT(ct *Ciphertext) {
+	p.ring.NTT(ct.C0)
+	p.ring.NTT(ct.C1)
+}
+
+// InvNTT maps the ciphertext back from the NTT domain in place
+func (p *Params) InvNTT(ct *Ciphertext) {
+	p.ring.InvNTT(ct.C0)
+	p.ring.InvNTT(ct.C1)
+}
+
+// MulPlainAdd adds the product of the ciphertext and the plaintext, both in
+// the NTT domain, to acc
+func (p *Params) MulPlainAdd(ct *Ciphertext, pt *Plaintext, acc *Ciphertext) {
+	p.ring.MulCoeffsShoupAdd(ct.C0, pt.Poly, pt.Shoup, acc.C0)
+	p.ring.MulCoeffsShoupAdd(ct.C1, pt.Poly, pt.Shoup, acc.C1)
+}
+
+// ModSwitch returns the ciphertext modulo 2^logQ, with logQ at most 32, by
+// scaling and rounding its coefficients
+func (p *Params) ModSwitch(ct *Ciphertext, logQ int) *Ciphertext {
+	out := &Ciphertext{C0: make(Poly, len(ct.C0)), C1: make(Poly, len(ct.C1))}
+	for k, poly := range []Poly{ct.C0, ct.C1} {
+		res := out.C0
+		if k == 1 {
+			res = out.C1
+		}
+		for i, c := range poly {
+			hi, lo := bits.Mul64(c, 1<<logQ)
+			var carry uint64
+			lo, carry = bits.Add64(lo, p.Q/2, 0)
+			quo, _ := bits.Div64(hi+carry, lo, p.Q)
+			res[i] = quo & (1<<logQ - 1)
+		}
+	}
+	return out
+}
+
+// Decompose returns the 2 * logQ / LogT plaintexts of the base-T digits of
+// the coefficients of a ciphertext modulo 2^logQ: first the ones of C0, from
+// the least significant digit, then the ones of C1
+func (p *Params) Decompose(ct *Ciphertext, logQ int) [][]byte {
+	digits := logQ / LogT
+	out := make([][]byte, 2*digits)
+	for k, poly := range []Poly{ct.C0, ct.C1} {
+		for d := 0; d < digits; d++ {
+			pt := make([]byte, len(poly))
+			for i, c := range poly {
+				pt[i] = byte(c >> (d * LogT))
+			}
+			out[k*digits+d] = pt
+		}
+	}
+	return out
+}
+
+// Recompose returns the ciphertext modulo 2^logQ of the plaintexts returned
+// by Decompose
+func (p *Params) Recompose(pts [][]byte, logQ int) (*Ciphertext, error) {
+	digits := logQ / LogT
+	if len(pts) != 2*digits {
+		return nil, fmt.Errorf("%d plaintexts for a ciphertext of %d digits", len(pts), 2*digits)
+	}
+	ct := p.NewCiphertext()
+	for k, poly := range []Poly{ct.C0, ct.C1} {
+		for d := 0; d < digits; d++ {
+			for i, b := range pts[k*digits+d] {
+				poly[i] |= uint64(b) << (d * LogT)
+			}
+		}
+	}
+	return ct, nil
+}
@@ -0,0 +1,242 @@
+package rlwe
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// KeyIDLen is the length of the identifiers of the evaluation keys
+const KeyIDLen = 8
+
+// switchingKey switches the ciphertexts under the secret s(X^k) back to
+// ciphertexts under s. Its ciphertexts (b_j, a_j) encrypt B^j * s(X^k) and
+// are kept in the NTT domain, along with their Shoup companions.
+type switchingKey struct {
+	seed       utils.PRGKey
+	b, a       []Poly
+	bShoup     []Poly
+	aShoup     []Poly
+	galoisElem int
+}
+
+// EvaluationKeys are the switching keys of the automorphisms of the
+// expansion of the queries, generated by the client and stored by the
+// server. They support the expansion of queries in 2^Levels ciphertexts.
+type EvaluationKeys struct {
+	params *Params
+	Levels int
+	keys   []*switchingKey
+}
+
+// galoisElement returns the element k of the automorphism X -> X^k of the
+// j-th level of the expansion
+func (p *Params) galoisElement(j int) int {
+	return p.N()>>j + 1
+}
+
+// NewEvaluationKeys returns the evaluation keys supporting the expansion of
+// queries in 2^levels ciphertexts
+func (sk *SecretKey) NewEvaluationKeys(levels int, rnd io.Reader) *EvaluationKeys {
+	p := sk.params
+	r := p.ring
+	ek := &EvaluationKeys{params: p, Levels: levels, keys: make([]*switchingKey, levels)}
+	for j := range ek.keys {
+		key := &switchingKey{galoisElem: p.galoisElement(j)}
+		if _, err := io.ReadFull(rnd, key.seed[:]); err != nil {
+			panic(err)
+		}
+		sk2 := r.Automorphism(sk.s, key.galoisElem)
+		prg := utils.NewPRG(&key.seed)
+		for d := 0; d < p.digits(); d++ {
+			// b_j = -a_j * s + e_j + B^j * s(X^k)
+			a := r.Uniform(prg)
+			b := r.Gauss()
+			r.Sub(b, sk.mulS(a), b)
+			gadget := r.FromInt(1)
+			for k := 0; k < d; k++ {
+				gadget = mulMod(gadget, uint64(1)<<p.LogB, p.Q)
+			}
+			for i := range b {
+				b[i] = addMod(b[i], mulMod(sk2[i], gadget, p.Q), p.Q)
+			}
+			key.a = append(key.a, a)
+			key.b = append(key.b, b)
+		}
+		key.prepare(p)
+		ek.keys[j] = key
+	}
+	return ek
+}
+
+// prepare maps the key to the NTT domain and computes its Shoup companions
+func (key *switchingKey) prepare(p *Params) {
+	r := p.ring
+	for d := range key.a {
+		r.NTT(key.a[d])
+		r.NTT(key.b[d])
+		key.aShoup = append(key.aShoup, r.Shoup(key.a[d]))
+		key.bShoup = append(key.bShoup, r.Shoup(key.b[d]))
+	}
+}
+
+// Bytes returns the encoding of the keys, where the a_j are expanded from
+// their seed
+func (ek *EvaluationKeys) Bytes() []byte {
+	out := []byte{byte(ek.Levels)}
+	for _, key := range ek.keys {
+		out = append(out, key.seed[:]...)
+		for _, b := range key.b {
+			c := append(Poly{}, b...)
+			ek.params.ring.InvNTT(c)
+			out = append(out, utils.Uint64SliceToByteSlice(c)...)
+		}
+	}
+	return out
+}
+
+// DecodeEvaluationKeys decodes the evaluation keys encoded by Bytes
+func (p *Params) DecodeEvaluationKeys(data []byte) (*EvaluationKeys, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty evaluation keys")
+	}
+	levels := int(data[0])
+	if levels > p.LogN {
+		return nil, fmt.Errorf("evaluation keys of %d levels for a ring of degree %d", levels, p.N())
+	}
+	polyLen := 8 * p.N()
+	keyLen := len(utils.PRGKey{}) + p.digits()*polyLen
+	if len(data) != 1+levels*keyLen {
+		return nil, errors.New("invalid length of evaluation keys")
+	}
+
+	r := p.ring
+	ek := &EvaluationKeys{params: p, Levels: levels, keys: make([]*switchingKey, levels)}
+	data = data[1:]
+	for j := range ek.keys {
+		key := &switchingKey{galoisElem: p.galoisElement(j)}
+		copy(key.seed[:], data)
+		prg := utils.NewPRG(&key.seed)
+		for d := 0; d < p.digits(); d++ {
+			start := len(key.seed) + d*polyLen
+			b := Poly(utils.ByteSliceToUint64Slice(data[start : start+polyLen]))
+			for _, c := range b {
+				if c >= p.Q {
+					return nil, errors.New("non-canonical evaluation keys")
+				}
+			}
+			key.a = append(key.a, r.Uniform(prg))
+			key.b = append(key.b, b)
+		}
+		key.prepare(p)
+		ek.keys[j] = key
+		data = data[keyLen:]
+	}
+	return ek, nil
+}
+
+// KeyID returns the identifier of the encoded evaluation keys, which the
+// queries carry so that the server expands them with the right keys
+func KeyID(keys []byte) []byte {
+	h := sha256.Sum256(keys)
+	return h[:KeyIDLen]
+}
+
+// substitute returns the ciphertext of p(X^k) from the ciphertext of p, both
+// in the coefficient domain, switched back to the secret s
+func (ek *EvaluationKeys) substitute(ct *Ciphertext, key *switchingKey) *Ciphertext {
+	p := ek.params
+	r := p.ring
+	c0 := r.Automorphism(ct.C0, key.galoisElem)
+	c1 := r.Automorphism(ct.C1, key.galoisElem)
+
+	out := p.NewCiphertext()
+	for d, digit := range p.decompose(c1) {
+		r.NTT(digit)
+		r.MulCoeffsShoupAdd(digit, key.b[d], key.bShoup[d], out.C0)
+		r.MulCoeffsShoupAdd(digit, key.a[d], key.aShoup[d], out.C1)
+	}
+	p.InvNTT(out)
+	r.Add(out.C0, c0, out.C0)
+	return out
+}
+
+// decompose returns the signed base-B digits of the coefficients of p,
+// such that p = sum_j B^j * digit_j
+func (p *Params) decompose(poly Poly) []Poly {
+	r := p.ring
+	digits := make([]Poly, p.digits())
+	for d := range digits {
+		digits[d] = r.NewPoly()
+	}
+	base := int64(1) << p.LogB
+	for i, c := range poly {
+		v := r.Center(c)
+		for d := range digits {
+			digit := v & (base - 1)
+			if digit >= base/2 {
+				digit -= base
+			}
+			v = (v - digit) >> p.LogB
+			if d == len(digits)-1 {
+				// the carry of the last digit
+				digit += v << p.LogB
+			}
+			digits[d][i] = r.FromInt(digit)
+		}
+	}
+	return digits
+}
+
+// Expand returns the first count ciphertexts of the expansion of a query
+// encrypting X^i, in the NTT domain: the i-th one encrypts 1 and the others
+// 0. The expansion needs 2^levels >= count.
+func (ek *EvaluationKeys) Expand(query *Ciphertext, count int) ([]*Ciphertext, error) {
+	p := ek.params
+	r := p.ring
+	levels := 0
+	for 1<<levels < count {
+		levels++
+	}
+	if levels > ek.Levels {
+		return nil, fmt.Errorf("expansion in %d ciphertexts with keys of %d levels", count, ek.Levels)
+	}
+
+	cts := []*Ciphertext{query}
+	for j := 0; j < levels; j++ {
+		key := ek.keys[j]
+		next := make([]*Ciphertext, 1<<(j+1))
+		for b := 0; b < 1<<j && b < count; b++ {
+			// the coefficients of the even powers of X^(2^j) double and the
+			// others cancel out, while the shift by X^(-2^j) brings the odd
+			// ones to the even powers
+			c := cts[b]
+			s := ek.substitute(c, key)
+			next[b] = p.NewCiphertext()
+			r.Add(c.C0, s.C0, next[b].C0)
+			r.Add(c.C1, s.C1, next[b].C1)
+
+			if b+1<<j >= count {
+				continue
+			}
+			shifted := &Ciphertext{
+				C0: r.MulMonomial(c.C0, 2*p.N()-1<<j),
+				C1: r.MulMonomial(c.C1, 2*p.N()-1<<j),
+			}
+			s = ek.substitute(shifted, key)
+			next[b+1<<j] = p.NewCiphertext()
+			r.Add(shifted.C0, s.C0, next[b+1<<j].C0)
+			r.Add(shifted.C1, s.C1, next[b+1<<j].C1)
+		}
+		cts = next
+	}
+
+	cts = cts[:count]
+	for _, ct := range cts {
+		p.NTT(ct)
+	}
+	return cts, nil
+}
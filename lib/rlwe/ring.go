@@ -0,0 +1,316 @@
+package rlwe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// Poly is a polynomial of the ring, as the vector of its N coefficients or
+// of its N evaluations in the NTT domain
+type Poly []uint64
+
+// Ring is the ring Z_Q[X]/(X^N + 1) of the ciphertexts, along with the
+// tables of its negacyclic NTT. Q must be a prime equal to 1 mod 2N and
+// smaller than 2^62.
+type Ring struct {
+	N    int
+	logN int
+	Q    uint64
+
+	// powers of a primitive 2N-th root of unity psi and of its inverse in
+	// bit-reversed order, along with their Shoup companions
+	psi         []uint64
+	psiShoup    []uint64
+	psiInv      []uint64
+	psiInvShoup []uint64
+
+	nInv      uint64
+	nInvShoup uint64
+}
+
+// NewRing returns the ring of degree 2^logN modulo q
+func NewRing(logN int, q uint64) (*Ring, error) {
+	n := 1 << logN
+	if q >= 1<<62 || q%uint64(2*n) != 1 {
+		return nil, fmt.Errorf("the modulus %d does not support the NTT of degree %d", q, n)
+	}
+
+	// psi is a primitive 2N-th root of unity if psi^N = -1
+	var psi uint64
+	for x := uint64(2); psi == 0; x++ {
+		if x == q {
+			return nil, fmt.Errorf("no primitive %d-th root of unity modulo %d", 2*n, q)
+		}
+		if c := powMod(x, (q-1)/uint64(2*n), q); powMod(c, uint64(n), q) == q-1 {
+			psi = c
+		}
+	}
+	psiInv := powMod(psi, uint64(2*n-1), q)
+
+	r := &Ring{
+		N:           n,
+		logN:        logN,
+		Q:           q,
+		psi:         make([]uint64, n),
+		psiShoup:    make([]uint64, n),
+		psiInv:      make([]uint64, n),
+		psiInvShoup: make([]uint64, n),
+		nInv:        powMod(uint64(n), q-2, q),
+	}
+	r.nInvShoup = shoup(r.nInv, q)
+	for i := 0; i < n; i++ {
+		e := uint64(bitReverse(i, logN))
+		r.psi[i] = powMod(psi, e, q)
+		r.psiShoup[i] = shoup(r.psi[i], q)
+		r.psiInv[i] = powMod(psiInv, e, q)
+		r.psiInvShoup[i] = shoup(r.psiInv[i], q)
+	}
+
+	return r, nil
+}
+
+// NewPoly returns the zero polynomial
+func (r *Ring) NewPoly() Poly {
+	return make(Poly, r.N)
+}
+
+// NTT maps the polynomial to the NTT domain in place. The evaluations are
+// in bit-reversed order, which is irrelevant to the coefficient-wise
+// products.
+func (r *Ring) NTT(p Poly) {
+	q := r.Q
+	t := r.N
+	for m := 1; m < r.N; m <<= 1 {
+		t >>= 1
+		for i := 0; i < m; i++ {
+			w, ws := r.psi[m+i], r.psiShoup[m+i]
+			for j := 2 * i * t; j < 2*i*t+t; j++ {
+				u, v := p[j], mulShoup(p[j+t], w, ws, q)
+				p[j] = addMod(u, v, q)
+				p[j+t] = subMod(u, v, q)
+			}
+		}
+	}
+}
+
+// InvNTT maps the polynomial back from the NTT domain in place
+func (r *Ring) InvNTT(p Poly) {
+	q := r.Q
+	t := 1
+	for m := r.N; m > 1; m >>= 1 {
+		h := m >> 1
+		for i := 0; i < h; i++ {
+			w, ws := r.psiInv[h+i], r.psiInvShoup[h+i]
+			for j := 2 * i * t; j < 2*i*t+t; j++ {
+				u, v := p[j], p[j+t]
+				p[j] = addMod(u, v, q)
+				p[j+t] = mulShoup(subMod(u, v, q), w, ws, q)
+			}
+		}
+		t <<= 1
+	}
+	for j := range p {
+		p[j] = mulShoup(p[j], r.nInv, r.nInvShoup, q)
+	}
+}
+
+// Add sets out to a + b
+func (r *Ring) Add(a, b, out Poly) {
+	for i := range out {
+		out[i] = addMod(a[i], b[i], r.Q)
+	}
+}
+
+// Sub sets out to a - b
+func (r *Ring) Sub(a, b, out Poly) {
+	for i := range out {
+		out[i] = subMod(a[i], b[i], r.Q)
+	}
+}
+
+// MulCoeffsAdd adds the coefficient-wise product of a and b to out
+func (r *Ring) MulCoeffsAdd(a, b, out Poly) {
+	for i := range out {
+		out[i] = addMod(out[i], mulMod(a[i], b[i], r.Q), r.Q)
+	}
+}
+
+// MulCoeffsShoupAdd adds the coefficient-wise product of a and b to out,
+// where bs holds the Shoup companions of the coefficients of b
+func (r *Ring) MulCoeffsShoupAdd(a, b, bs, out Poly) {
+	for i := range out {
+		out[i] = addMod(out[i], mulShoup(a[i], b[i], bs[i], r.Q), r.Q)
+	}
+}
+
+// Shoup returns the Shoup companions of the coefficients of p, which speed
+// up the products by p
+func (r *Ring) Shoup(p Poly) Poly {
+	out := make(Poly, len(p))
+	for i := range p {
+		out[i] = shoup(p[i], r.Q)
+	}
+	return out
+}
+
+// MulMonomial returns p * X^e, for e in [0, 2N)
+func (r *Ring) MulMonomial(p Poly, e int) Poly {
+	out := r.NewPoly()
+	for i, c := range p {
+		j := (i + e) % (2 * r.N)
+		if j < r.N {
+			out[j] = c
+		} else {
+			out[j-r.N] = negMod(c, r.Q)
+		}
+	}
+	return out
+}
+
+// Automorphism returns p(X^k), for an odd k, of a polynomial in the
+// coefficient domain
+func (r *Ring) Automorphism(p Poly, k int) Poly {
+	out := r.NewPoly()
+	for i, c := range p {
+		j := (i * k) % (2 * r.N)
+		if j < r.N {
+			out[j] = c
+		} else {
+			out[j-r.N] = negMod(c, r.Q)
+		}
+	}
+	return out
+}
+
+// Center returns the representative of the coefficient in (-Q/2, Q/2]
+func (r *Ring) Center(c uint64) int64 {
+	if c > r.Q/2 {
+		return -int64(r.Q - c)
+	}
+	return int64(c)
+}
+
+// FromInt returns the coefficient of the integer
+func (r *Ring) FromInt(v int64) uint64 {
+	if v < 0 {
+		return r.Q - uint64(-v)%r.Q
+	}
+	return uint64(v) % r.Q
+}
+
+// Uniform returns a uniformly random polynomial, expanded from the reader
+func (r *Ring) Uniform(rnd io.Reader) Poly {
+	p := r.NewPoly()
+	mask := uint64(1)<<bits.Len64(r.Q) - 1
+	buf := make([]byte, 8*r.N)
+	for i := 0; i < r.N; {
+		if _, err := io.ReadFull(rnd, buf); err != nil {
+			panic(err)
+		}
+		for k := 0; k+8 <= len(buf) && i < r.N; k += 8 {
+			// rejection sampling keeps the distribution uniform
+			if c := binary.LittleEndian.Uint64(buf[k:]) & mask; c < r.Q {
+				p[i] = c
+				i++
+			}
+		}
+	}
+	return p
+}
+
+// Ternary returns a polynomial with uniformly random coefficients in
+// {-1, 0, 1}
+func (r *Ring) Ternary(rnd io.Reader) Poly {
+	p := r.NewPoly()
+	buf := make([]byte, r.N)
+	for i := 0; i < r.N; {
+		if _, err := io.ReadFull(rnd, buf); err != nil {
+			panic(err)
+		}
+		for _, b := range buf {
+			if b >= 255 || i == r.N {
+				continue
+			}
+			p[i] = r.FromInt(int64(b%3) - 1)
+			i++
+		}
+	}
+	return p
+}
+
+// Gauss returns a polynomial with coefficients from the discrete Gaussian
+// error distribution of the LWE schemes
+func (r *Ring) Gauss() Poly {
+	p := r.NewPoly()
+	for i := range p {
+		p[i] = r.FromInt(utils.GaussSample())
+	}
+	return p
+}
+
+func addMod(a, b, q uint64) uint64 {
+	c := a + b
+	if c >= q {
+		c -= q
+	}
+	return c
+}
+
+func subMod(a, b, q uint64) uint64 {
+	if a >= b {
+		return a - b
+	}
+	return a + q - b
+}
+
+func negMod(a, q uint64) uint64 {
+	if a == 0 {
+		return 0
+	}
+	return q - a
+}
+
+func mulMod(a, b, q uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	_, rem := bits.Div64(hi, lo, q)
+	return rem
+}
+
+// shoup returns floor(w * 2^64 / q), for w < q
+func shoup(w, q uint64) uint64 {
+	quo, _ := bits.Div64(w, 0, q)
+	return quo
+}
+
+// mulShoup returns a * w mod q, for a, w < q and ws the Shoup companion of w
+func mulShoup(a, w, ws, q uint64) uint64 {
+	hi, _ := bits.Mul64(a, ws)
+	c := a*w - hi*q
+	if c >= q {
+		c -= q
+	}
+	return c
+}
+
+func powMod(x, e, q uint64) uint64 {
+	res := uint64(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			res = mulMod(res, x, q)
+		}
+		x = mulMod(x, x, q)
+	}
+	return res
+}
+
+func bitReverse(i, bits int) int {
+	r := 0
+	for k := 0; k < bits; k++ {
+		r = r<<1 | (i>>k)&1
+	}
+	return r
+}
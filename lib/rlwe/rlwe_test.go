@@ -0,0 +1,89 @@
+package rlwe
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNTTProduct(t *testing.T) {
+	p := DefaultParams()
+	r, err := NewRing(5, p.Q)
+	require.NoError(t, err)
+	rnd := utils.RandomPRG()
+	a, b := r.Uniform(rnd), r.Uniform(rnd)
+
+	// schoolbook negacyclic product
+	expected := r.NewPoly()
+	for i := range a {
+		for j := range b {
+			c := mulMod(a[i], b[j], r.Q)
+			if i+j < r.N {
+				expected[i+j] = addMod(expected[i+j], c, r.Q)
+			} else {
+				expected[i+j-r.N] = subMod(expected[i+j-r.N], c, r.Q)
+			}
+		}
+	}
+
+	aNTT, bNTT := append(Poly{}, a...), append(Poly{}, b...)
+	r.NTT(aNTT)
+	r.NTT(bNTT)
+	out := r.NewPoly()
+	r.MulCoeffsAdd(aNTT, bNTT, out)
+	r.InvNTT(out)
+	require.Equal(t, expected, out)
+
+	r.InvNTT(aNTT)
+	require.Equal(t, a, aNTT)
+}
+
+func TestExpand(t *testing.T) {
+	p := DefaultParams()
+	rnd := utils.RandomPRG()
+	sk := NewSecretKey(p, rnd)
+	ek, err := p.DecodeEvaluationKeys(sk.NewEvaluationKeys(3, rnd).Bytes())
+	require.NoError(t, err)
+
+	for count, logM := range map[int]int{1: 0, 5: 3, 8: 3} {
+		for i := 0; i < count; i++ {
+			cts, err := ek.Expand(sk.EncryptIndex(i, logM, rnd).Expand(p), count)
+			require.NoError(t, err)
+			require.Len(t, cts, count)
+			for k, ct := range cts {
+				p.InvNTT(ct)
+				pt := sk.DecryptPow2(p.ModSwitch(ct, LogQAnswer), LogQAnswer)
+				expected := make([]byte, p.N())
+				if k == i {
+					expected[0] = 1
+				}
+				require.Equal(t, expected, pt, "ciphertext %d of the expansion of %d", k, i)
+			}
+		}
+	}
+
+	_, err = ek.Expand(sk.EncryptIndex(0, 4, rnd).Expand(p), 16)
+	require.Error(t, err)
+}
+
+func TestDecompose(t *testing.T) {
+	p := DefaultParams()
+	rnd := utils.RandomPRG()
+	sk := NewSecretKey(p, rnd)
+
+	m := p.ring.NewPoly()
+	data := make([]byte, p.N())
+	_, err := rnd.Read(data)
+	require.NoError(t, err)
+	for i, b := range data {
+		m[i] = uint64(b) * p.delta()
+	}
+	ct := sk.encrypt(m, rnd).Expand(p)
+
+	switched := p.ModSwitch(ct, p.LogQ1)
+	require.Equal(t, data, sk.DecryptPow2(switched, p.LogQ1))
+	recomposed, err := p.Recompose(p.Decompose(switched, p.LogQ1), p.LogQ1)
+	require.NoError(t, err)
+	require.Equal(t, switched, recomposed)
+}
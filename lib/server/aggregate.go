@@ -0,0 +1,233 @@
+package server
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// ErrInvalidContribution is returned when the verification of a contribution
+// shows that it is not a vector of bits, or not one-hot when required
+var ErrInvalidContribution = errors.New("invalid contribution")
+
+// Aggregator is a server of the two-server private aggregate statistics, in
+// the style of Prio, complementing the predicate queries of a single client
+// with the collection of the contributions of many clients. The server holds
+// an additive share of the aggregate, to which it adds the share of every
+// contribution. Before that, the two servers verify the proof of the
+// contribution in two rounds: they evaluate their shares of f, g and h at a
+// random point derived from a shared seed, multiply the first two with the
+// Beaver triple of the contribution, and check that h = f * g there and that
+// h is zero at the gates, without learning the contribution. As for the
+// private writes, the servers are trusted to follow the verification, which
+// protects the aggregate against malformed contributions of the clients.
+// The proof of a contribution of n bits has 2n + 6 elements and fails to
+// reject an invalid one with probability about 2n / p.
+type Aggregator struct {
+	id   byte
+	stat *query.Statistic
+	fl   *field.Field
+	seed *utils.PRGKey
+
+	mu     sync.Mutex
+	shares []uint32
+	count  int
+}
+
+// ContributionCheck is the state of the verification of a contribution by a
+// server
+type ContributionCheck struct {
+	s      *Aggregator
+	shares []uint32
+	triple [3]uint32
+	// shares of f(r) - a and g(r) - b, of h(r) and of the linear checks
+	openings [2]uint32
+	h        uint32
+	linear   [2]uint32
+	sigma    uint32
+	done     bool
+}
+
+// NewAggregator returns the server with the given id, 0 or 1, of the
+// aggregate of the statistic, sharing the seed of the verifications with the
+// other server
+func NewAggregator(stat *query.Statistic, id byte, seed *utils.PRGKey) (*Aggregator, error) {
+	if id > 1 {
+		return nil, fmt.Errorf("invalid server %d out of 2", id)
+	}
+	if stat.Length <= 0 {
+		return nil, errors.New("empty statistic")
+	}
+	return &Aggregator{
+		id:     id,
+		stat:   stat,
+		fl:     field.Default(),
+		seed:   seed,
+		shares: make([]uint32, stat.Length),
+	}, nil
+}
+
+// VerifyBytes starts the verification of an encoded contribution. It returns
+// the state of the verification and the first message to send to the other
+// server, i.e., the shares of the openings of the Beaver triple.
+func (s *Aggregator) VerifyBytes(c []byte) (*ContributionCheck, []byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	ct, err := query.DecodeContribution(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.End()
+	return s.Verify(ct)
+}
+
+// Verify starts the verification of a contribution, as VerifyBytes
+func (s *Aggregator) Verify(c *query.Contribution) (*ContributionCheck, []byte, error) {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+	fl := s.fl
+	n := s.stat.Length
+	if len(c.ID) != query.ContributionIDLen || len(c.Shares) != n || len(c.H) != 2*n+1 {
+		return nil, nil, errors.New("malformed contribution")
+	}
+	elements := append(append(append([]uint32{c.F0, c.G0}, c.Shares...), c.H...), c.Triple[:]...)
+	for _, e := range elements {
+		if e >= fl.Modulus() {
+			return nil, nil, errors.New("malformed contribution")
+		}
+	}
+
+	r, rho := s.randomness(c.ID)
+	l, err := fl.RangeLagrangeCoefficients(n, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	lh, err := fl.RangeLagrangeCoefficients(2*n, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// shares of f(r) and g(r), where g(t) = x_t - 1 is shifted by server 0
+	fr, gr := fl.Mul(l[0], c.F0), fl.Mul(l[0], c.G0)
+	for t, x := range c.Shares {
+		fr = fl.Add(fr, fl.Mul(l[t+1], x))
+		g := x
+		if s.id == 0 {
+			g = fl.Sub(x, 1)
+		}
+		gr = fl.Add(gr, fl.Mul(l[t+1], g))
+	}
+
+	a := &ContributionCheck{s: s, shares: c.Shares, triple: c.Triple}
+	for x, hx := range c.H {
+		a.h = fl.Add(a.h, fl.Mul(lh[x], hx))
+	}
+	// the outputs of the gates are zero, and exactly one bit is set if the
+	// statistic is one-hot
+	for t := 1; t <= n; t++ {
+		a.linear[0] = fl.Add(a.linear[0], fl.Mul(rho[t-1], c.H[t]))
+	}
+	if s.stat.OneHot {
+		for _, x := range c.Shares {
+			a.linear[1] = fl.Add(a.linear[1], x)
+		}
+		if s.id == 0 {
+			a.linear[1] = fl.Sub(a.linear[1], 1)
+		}
+	}
+	a.openings[0] = fl.Sub(fr, c.Triple[0])
+	a.openings[1] = fl.Sub(gr, c.Triple[1])
+
+	return a, fl.EncodeElements(a.openings[:]), nil
+}
+
+// CheckBytes answers the first message of the other server with the second
+// one, i.e., the shares of f(r) g(r) - h(r) and of the linear checks, which
+// are all zero for a valid contribution
+func (a *ContributionCheck) CheckBytes(peer []byte) ([]byte, error) {
+	fl := a.s.fl
+	openings, err := fl.DecodeElements(peer)
+	if err != nil {
+		return nil, err
+	}
+	if len(openings) != len(a.openings) {
+		return nil, errors.New("malformed verification message")
+	}
+
+	d := fl.Add(a.openings[0], openings[0])
+	e := fl.Add(a.openings[1], openings[1])
+	// [xy] = [c] + d [b] + e [a] + de, the last term added by server 0
+	p := fl.Add(a.triple[2], fl.Mul(d, a.triple[1]))
+	p = fl.Add(p, fl.Mul(e, a.triple[0]))
+	if a.s.id == 0 {
+		p = fl.Add(p, fl.Mul(d, e))
+	}
+	a.sigma = fl.Sub(p, a.h)
+
+	return fl.EncodeElements([]uint32{a.sigma, a.linear[0], a.linear[1]}), nil
+}
+
+// Commit ends the verification with the second message of the other server
+// and adds the contribution to the share of the aggregate if it is valid.
+// ErrInvalidContribution is returned otherwise.
+func (a *ContributionCheck) Commit(peer []byte) error {
+	fl := a.s.fl
+	checks, err := fl.DecodeElements(peer)
+	if err != nil {
+		return err
+	}
+	if len(checks) != 3 {
+		return errors.New("malformed verification message")
+	}
+	if a.done {
+		return errors.New("contribution already committed")
+	}
+	a.done = true
+	own := []uint32{a.sigma, a.linear[0], a.linear[1]}
+	for k := range own {
+		if fl.Add(own[k], checks[k]) != 0 {
+			return ErrInvalidContribution
+		}
+	}
+
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	fl.AddVectors(a.s.shares, a.s.shares, a.shares)
+	a.s.count++
+	return nil
+}
+
+// Publish returns the encoded share of the aggregate and the number of
+// contributions added to it, and empties the aggregate for the next epoch
+func (s *Aggregator) Publish() ([]byte, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out, count := s.fl.EncodeElements(s.shares), s.count
+	s.shares = make([]uint32, len(s.shares))
+	s.count = 0
+	return out, count
+}
+
+// randomness returns the random point of the polynomial identity test and
+// the coefficients of the check of the gates, expanded from the shared seed
+// and the identifier of the contribution, which the client cannot predict
+func (s *Aggregator) randomness(id []byte) (uint32, []uint32) {
+	h := sha256.New()
+	h.Write([]byte("aggregate"))
+	h.Write(s.seed[:])
+	h.Write(id)
+	var key utils.PRGKey
+	copy(key[:], h.Sum(nil))
+	prg := utils.NewPRG(&key)
+
+	r := s.fl.RandElementWithPRG(prg)
+	rho := make([]uint32, s.stat.Length)
+	for t := range rho {
+		rho[t] = s.fl.RandElementWithPRG(prg)
+	}
+	return r, rho
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
 )
 
 type Amplify struct {
@@ -20,6 +21,8 @@ func (a *Amplify) DBInfo() *database.Info {
 }
 
 func (a *Amplify) Answer(qq []*matrix.Matrix) []*matrix.Matrix {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+
 	ans := make([]*matrix.Matrix, len(qq))
 	for i, q := range qq {
 		ans[i] = matrix.BinaryMul(q, a.lwe.db.Matrix)
@@ -29,8 +32,16 @@ func (a *Amplify) Answer(qq []*matrix.Matrix) []*matrix.Matrix {
 }
 
 func (a *Amplify) AnswerBytes(qq []byte) ([]byte, error) {
-	ans := a.Answer(matrix.BytesToMatrices(qq))
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	query := matrix.BytesToMatrices(qq)
+	t.End()
+
+	ans := a.Answer(query)
 
 	// encode
-	return matrix.MatricesToBytes(ans), nil
+	t = monitor.StartPhase(monitor.PhaseEncode)
+	out := matrix.MatricesToBytes(ans)
+	t.End()
+	monitor.CountAnswer(out)
+	return out, nil
 }
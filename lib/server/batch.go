@@ -0,0 +1,56 @@
+package server
+
+import (
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// Batch is a server of a db encoded with a cuckoo batch code. It answers a
+// batch query, made of a query per bucket, with the servers of the scheme
+// over the dbs of the buckets, so that answering a batch scans every block
+// of the db database.NumBatchHashes times whatever the size of the batch.
+type Batch struct {
+	info    *database.Info
+	servers []Server
+}
+
+// NewBatch returns the server of the db for batches of the given number of
+// blocks, answering the queries of every bucket with the server returned by
+// newServer for the db of the bucket
+func NewBatch(db *database.Bytes, batchSize int, newServer func(*database.Bytes) Server) (*Batch, error) {
+	layout, err := database.NewBatchLayout(&db.Info, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	buckets := layout.Encode(db)
+	servers := make([]Server, len(buckets))
+	for b := range buckets {
+		servers[b] = newServer(buckets[b])
+	}
+	return &Batch{info: &db.Info, servers: servers}, nil
+}
+
+// DBInfo returns the info of the db before its encoding
+func (s *Batch) DBInfo() *database.Info {
+	return s.info
+}
+
+// AnswerBytes answers a batch query, i.e., the length-prefixed queries of
+// all the buckets, with the length-prefixed answers of the buckets
+func (s *Batch) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	queries, err := utils.SplitMessages(q, len(s.servers))
+	if err != nil {
+		return nil, err
+	}
+	t.End()
+
+	answers := make([][]byte, len(queries))
+	for b, query := range queries {
+		if answers[b], err = s.servers[b].AnswerBytes(query); err != nil {
+			return nil, err
+		}
+	}
+	return utils.JoinMessages(answers), nil
+}
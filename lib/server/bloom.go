@@ -0,0 +1,104 @@
+package server
+
+import (
+	"errors"
+	"math/bits"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// Bloom is a server of the private membership test in a Bloom filter. Every
+// DPF key of a query selects a bit of the filter, and the server answers
+// with the share of the bit, i.e., the dot product in GF(2) of the filter
+// with the expansion of the key over all its bits.
+type Bloom struct {
+	filter *database.Bloom
+	fss    *fss.Fss
+}
+
+// NewBloom returns a server of the Bloom filter
+func NewBloom(filter *database.Bloom) *Bloom {
+	return &Bloom{
+		filter: filter,
+		fss:    fss.ServerInitialize(1),
+	}
+}
+
+// DBInfo returns database info
+func (s *Bloom) DBInfo() *database.Info {
+	return &s.filter.Info
+}
+
+// AnswerBytes answers the encoded DPF keys of the bits of a keyword with a
+// byte per key, the share of its bit
+func (s *Bloom) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	keys, err := fss.DecodeKeys(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != s.filter.NumHashes {
+		return nil, errors.New("malformed query")
+	}
+	f, err := s.forEpoch(keys)
+	if err != nil {
+		return nil, err
+	}
+	t.End()
+
+	a := s.answer(f, keys)
+	monitor.CountAnswer(a)
+	return a, nil
+}
+
+// Answer returns the shares of the bits of the filter selected by the keys.
+// It panics if the keys are of an unknown or expired epoch.
+func (s *Bloom) Answer(keys []fss.FssKeyEq2P) []byte {
+	f, err := s.forEpoch(keys)
+	if err != nil {
+		panic(err)
+	}
+	return s.answer(f, keys)
+}
+
+// forEpoch returns the evaluator of the keys, which are all generated in the
+// same epoch
+func (s *Bloom) forEpoch(keys []fss.FssKeyEq2P) (fss.Fss, error) {
+	if len(keys) == 0 {
+		return *s.fss, nil
+	}
+	for _, k := range keys[1:] {
+		if k.Epoch != keys[0].Epoch {
+			return fss.Fss{}, fss.ErrEpoch
+		}
+	}
+	return s.fss.ForEpoch(keys[0].Epoch)
+}
+
+// answer returns the shares of the bits of the filter selected by the keys,
+// evaluated with f
+func (s *Bloom) answer(f fss.Fss, keys []fss.FssKeyEq2P) []byte {
+	m := s.filter.NumColumns
+	numBits := fss.NumBitsForDomain(m)
+	out := make([]byte, len(keys))
+	q := make([]byte, m/8+1)
+	for i, k := range keys {
+		t := monitor.StartPhase(monitor.PhaseExpand)
+		for j := range q {
+			q[j] = 0
+		}
+		f.EvaluateFullDomainBits(k, numBits, m, q)
+		t.End()
+
+		t = monitor.StartPhase(monitor.PhaseScan)
+		ones := 0
+		for j := range q {
+			ones += bits.OnesCount8(q[j] & s.filter.Bits[j])
+		}
+		out[i] = byte(ones % 2)
+		t.End()
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/si-co/vpir-code/lib/fss"
+)
+
+// answerBuffers are the vectors used by the FSS servers to compute an
+// answer. The answers encoded in bytes take their buffers from a pool, so
+// that the buffers are reused across the answers, while the answers returned
+// as vectors allocate new buffers, which are then owned by the caller.
+type answerBuffers struct {
+	out, tmp []uint32 // a block of the answer and the output of the key
+	res      []uint32 // blocks of all the aggregates or buckets
+	totals   []uint32
+	values   []uint64
+	in, id   []bool // inputs of the key for an entry
+
+	// same as tmp and res for the 64-bit field
+	tmp64, res64 []uint64
+
+	// evaluator with the PRF keys of the epoch of the query
+	f fss.Fss
+}
+
+var answerPool = sync.Pool{
+	New: func() interface{} { return new(answerBuffers) },
+}
+
+// newAnswerBuffers returns new buffers with a block of blockLen elements
+func newAnswerBuffers(blockLen int) *answerBuffers {
+	b := new(answerBuffers)
+	b.reset(blockLen)
+	return b
+}
+
+// getAnswerBuffers returns buffers of the pool with a block of blockLen
+// elements, which must be put back with putAnswerBuffers once the answer is
+// encoded
+func getAnswerBuffers(blockLen int) *answerBuffers {
+	b := answerPool.Get().(*answerBuffers)
+	b.reset(blockLen)
+	return b
+}
+
+func putAnswerBuffers(b *answerBuffers) {
+	answerPool.Put(b)
+}
+
+func (b *answerBuffers) reset(blockLen int) {
+	b.out = zeroed(b.out, blockLen)
+	b.tmp = zeroed(b.tmp, blockLen)
+}
+
+// zeroed returns a vector of n zeros, reusing buf if large enough
+func zeroed(buf []uint32, n int) []uint32 {
+	if cap(buf) < n {
+		return make([]uint32, n)
+	}
+	buf = buf[:n]
+	for i := range buf {
+		buf[i] = 0
+	}
+	return buf
+}
+
+// zeroed64 is the same as zeroed for the 64-bit field
+func zeroed64(buf []uint64, n int) []uint64 {
+	if cap(buf) < n {
+		return make([]uint64, n)
+	}
+	buf = buf[:n]
+	for i := range buf {
+		buf[i] = 0
+	}
+	return buf
+}
+
+// grow extends dst by n zero bytes, reallocating it only if its capacity is
+// too small
+func grow(dst []byte, n int) []byte {
+	if len(dst)+n > cap(dst) {
+		return append(dst, make([]byte, n)...)
+	}
+	dst = dst[:len(dst)+n]
+	tail := dst[len(dst)-n:]
+	for i := range tail {
+		tail[i] = 0
+	}
+	return dst
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/query"
+)
+
+// checkBuckets checks a query answered per bucket, for the cursors of the
+// clients. The count of every bucket and the aggregates of every key are
+// exact, so that they are not answered for a db with noisy answers.
+func (s *serverFSS) checkBuckets(q *query.FSS) error {
+	switch {
+	case q.BucketSize < 0 || q.BucketSize > s.db.NumColumns:
+		return errors.New("invalid bucket size")
+	case q.And || q.Avg || q.Sum || q.Not:
+		return errors.New("buckets not implemented for this query")
+	case s.db.NoiseEpsilon > 0:
+		return errors.New("buckets not implemented for noisy answers")
+	case !q.Page && len(q.Aggregates) > 0:
+		return errors.New("only the counts of the buckets are answered")
+	case q.Page && q.Lt:
+		return errors.New("pages not implemented for comparison queries")
+	}
+	return nil
+}
+
+// answerBuckets answers the query per bucket of q.BucketSize consecutive
+// keys. The answer to the first query of a cursor holds one block of
+// len(b.out) elements per bucket, with the count of its matching keys. The
+// answer to a page query holds, for every position in a bucket, one block
+// per aggregate with the aggregate of the key at the position, times
+// whether it matches the query: the input of a key is prefixed with the
+// index of its bucket, so that only the keys of the bucket selected by the
+// client match.
+func (s *serverFSS) answerBuckets(q *query.FSS, b *answerBuffers) []uint32 {
+	blockLen := len(b.out)
+	tmp := b.tmp
+	fl := s.fss.Field
+
+	if !q.Page {
+		numBuckets := (s.db.NumColumns + q.BucketSize - 1) / q.BucketSize
+		b.res = zeroed(b.res, numBuckets*blockLen)
+		res := b.res
+		for i := 0; i < s.db.NumColumns; i++ {
+			var valid bool
+			b.in, valid = appendInputForTarget(b.in[:0], q, s.db.KeysInfo[i])
+			if !valid {
+				continue
+			}
+			if q.Lt {
+				b.f.EvaluateLt(s.serverNum, q.FssKeyLt, b.in, tmp)
+			} else {
+				b.f.EvaluatePF(s.serverNum, q.FssKey, b.in, tmp)
+			}
+			block := res[(i/q.BucketSize)*blockLen : (i/q.BucketSize+1)*blockLen]
+			fl.AddVectors(block, block, tmp)
+		}
+		return res
+	}
+
+	aggregates := blockAggregates(q)
+	positionLen := len(aggregates) * blockLen
+	b.res = zeroed(b.res, q.BucketSize*positionLen)
+	b.values = zeroed64(b.values, len(aggregates))
+	res, values := b.res, b.values
+	now := time.Now().Year()
+	for i := 0; i < s.db.NumColumns; i++ {
+		k := s.db.KeysInfo[i]
+		var valid bool
+		b.id, valid = appendInputForTarget(b.id[:0], q, k)
+		if !valid {
+			continue
+		}
+		b.in = append(query.AppendIdForBucket(b.in[:0], i/q.BucketSize), b.id...)
+		b.f.EvaluatePF(s.serverNum, q.FssKey, b.in, tmp)
+		aggregateValues(aggregates, k, now, values)
+
+		position := res[(i%q.BucketSize)*positionLen : (i%q.BucketSize+1)*positionLen]
+		for a := range values {
+			v := uint32(values[a] % uint64(fl.Modulus()))
+			fl.AddMulVector(position[a*blockLen:(a+1)*blockLen], tmp, v)
+		}
+	}
+	return res
+}
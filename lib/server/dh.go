@@ -5,6 +5,7 @@ import (
 
 	"github.com/cloudflare/circl/group"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
 )
 
 // A DH server for the single-server DL-based tag retrieval
@@ -17,10 +18,12 @@ func NewDH(db *database.Elliptic) *DH {
 }
 
 func (s *DH) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
 	query, err := database.UnmarshalGroupElements(q, s.db.Group, s.db.ElementSize)
 	if err != nil {
 		return nil, err
 	}
+	t = t.Next(monitor.PhaseScan)
 
 	NGoRoutines := 1
 	// make sure that we do not need up with routines processing 0 elements
@@ -48,11 +51,15 @@ func (s *DH) AnswerBytes(q []byte) ([]byte, error) {
 		close(replies[i])
 	}
 
+	t = t.Next(monitor.PhaseEncode)
+
 	// Encode the answer into binary
 	encoded, err := database.MarshalGroupElements(answer, s.db.ElementSize)
 	if err != nil {
 		return nil, err
 	}
+	t.End()
+	monitor.CountAnswer(encoded)
 
 	return encoded, nil
 }
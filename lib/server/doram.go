@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// DORAM is a server of the two-server distributed ORAM scheme over a db
+// created by database.NewDORAM. The server answers the reads as the server
+// of the DPF-based classical PIR scheme, and holds a share of the writes of
+// the current epoch as the server of the private write scheme, auditing
+// them with the other server. At the end of an epoch, the servers exchange
+// their shares and overwrite the blocks of the db in place, without
+// rebuilding it. The reads do not reveal the blocks they retrieve, and the
+// writes do not reveal the blocks they overwrite until the end of the
+// epoch, when the servers learn which blocks changed but not which writes
+// changed them. The writes of an epoch are read from the next one only.
+type DORAM struct {
+	mu     sync.RWMutex
+	db     *database.Bytes
+	pir    *PIRDPF
+	writes *Writes
+	// share of the writes of the ended epoch, until it is merged
+	share []byte
+}
+
+// NewDORAM returns the server with the given id, 0 or 1, of the db, sharing
+// the seed of the audits of the writes with the other server
+func NewDORAM(db *database.Bytes, id byte, seed *utils.PRGKey, cores ...int) (*DORAM, error) {
+	w, err := NewWrites(database.DORAMWriteInfo(&db.Info), id, seed)
+	if err != nil {
+		return nil, err
+	}
+	return &DORAM{
+		db:     db,
+		pir:    NewPIRDPF(db, cores...),
+		writes: w,
+	}, nil
+}
+
+// DBInfo returns database info
+func (s *DORAM) DBInfo() *database.Info {
+	return &s.db.Info
+}
+
+// AnswerBytes answers a read, i.e., a DPF key encoded in bytes
+func (s *DORAM) AnswerBytes(q []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pir.AnswerBytes(q)
+}
+
+// AuditBytes starts the audit of an encoded write, as Writes.AuditBytes. The
+// write is added to the share of the epoch by the commit of the audit.
+func (s *DORAM) AuditBytes(w []byte) (*WriteAudit, []byte, error) {
+	return s.writes.AuditBytes(w)
+}
+
+// EndEpoch ends the epoch of the writes and returns the share of the server
+// to send to the other server
+func (s *DORAM) EndEpoch() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.share = s.writes.Publish()
+	return s.share
+}
+
+// Merge overwrites the blocks of the db with the writes of the ended epoch,
+// given the share of the other server. The blocks in which several writes
+// collided are left unchanged. It returns the number of overwritten blocks
+// and of collisions.
+func (s *DORAM) Merge(peer []byte) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.share == nil {
+		return 0, 0, errors.New("no ended epoch")
+	}
+	fl := s.writes.fss.Field
+	own, err := fl.DecodeElements(s.share)
+	if err != nil {
+		return 0, 0, err
+	}
+	other, err := fl.DecodeElements(peer)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(other) != len(own) {
+		return 0, 0, errors.New("malformed share")
+	}
+	s.share = nil
+	fl.AddVectors(own, own, other)
+
+	bl := s.writes.dbInfo.BlockSize
+	written, collisions := 0, 0
+	for j := 0; j < len(own)/bl; j++ {
+		block, err := database.DecodeDORAMWrite(own[j*bl:(j+1)*bl], &s.db.Info)
+		if err != nil {
+			collisions++
+			continue
+		}
+		if block != nil {
+			copy(s.db.Block(j), block)
+			written++
+		}
+	}
+	return written, collisions, nil
+}
@@ -1,13 +1,14 @@
 package server
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/field"
 	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/query"
 	"github.com/si-co/vpir-code/lib/utils"
 )
@@ -18,75 +19,159 @@ type serverFSS struct {
 
 	serverNum byte
 	fss       *fss.Fss
+
+	// seed of the noise shared with the other server, for the dbs with
+	// noisy answers
+	noiseSeed *utils.PRGKey
+	mu        sync.Mutex
+	// nonces of the noisy queries already answered
+	nonces map[string]struct{}
 }
 
 func (s *serverFSS) dbInfo() *database.Info {
 	return &s.db.Info
 }
 
-func (s *serverFSS) answerBytes(q []byte, out, tmp []uint32) ([]byte, error) {
+// appendAnswer appends the encoded answer to the query encoded in bytes to
+// dst, with executions elements per result. The vectors of the answer are
+// taken from the pool and put back once the answer is encoded.
+func (s *serverFSS) appendAnswer(dst, q []byte, executions int) ([]byte, error) {
+	if s.db.UseField64() {
+		return s.appendAnswer64(dst, q, executions)
+	}
+
 	// decode query
-	buf := bytes.NewBuffer(q)
-	dec := gob.NewDecoder(buf)
-	var query *query.FSS
-	if err := dec.Decode(&query); err != nil {
-		return nil, err
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	query, err := query.DecodeFSS(q)
+	if err != nil {
+		return dst, err
 	}
+	t = t.Next(monitor.PhaseScan)
 
 	// get answer
-	a := s.answer(query, out, tmp)
+	b := getAnswerBuffers(executions)
+	defer putAnswerBuffers(b)
+	a, err := s.answerQuery(query, b)
+	if err != nil {
+		return dst, err
+	}
+	t = t.Next(monitor.PhaseEncode)
+
+	n := len(dst)
+	dst = s.fss.Field.AppendElements(dst, a)
+	t.End()
+	monitor.CountAnswer(dst[n:])
+	return dst, nil
+}
+
+// appendAnswer64 is the same as appendAnswer for databases working in the
+// 64-bit field
+func (s *serverFSS) appendAnswer64(dst, q []byte, executions int) ([]byte, error) {
+	if s.db.NoiseEpsilon > 0 {
+		return dst, errors.New("noisy answers not implemented for the 64-bit field")
+	}
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	query, err := query.DecodeFSS(q)
+	if err != nil {
+		return dst, err
+	}
+	t = t.Next(monitor.PhaseScan)
 
-	return utils.Uint32SliceToByteSlice(a), nil
+	b := getAnswerBuffers(0)
+	defer putAnswerBuffers(b)
+	a, err := s.answer64(query, executions, b)
+	if err != nil {
+		return dst, err
+	}
+	t = t.Next(monitor.PhaseEncode)
+
+	n := len(dst)
+	dst = field.AppendElements64(dst, a)
+	t.End()
+	monitor.CountAnswer(dst[n:])
+	return dst, nil
 }
 
-func (s *serverFSS) answer(q *query.FSS, out, tmp []uint32) []uint32 {
+// answer64 computes the answer in the 64-bit field in the buffers. Only
+// queries matching the target, possibly with aggregates, are supported.
+func (s *serverFSS) answer64(q *query.FSS, executions int, b *answerBuffers) ([]uint64, error) {
+	if q.And || q.Avg || q.Sum || q.Lt || q.Not || q.BucketSize != 0 {
+		return nil, errors.New("query not implemented for the 64-bit field")
+	}
+	if len(q.FssKey.FinalCW64) != executions {
+		return nil, errors.New("malformed FSS key for the 64-bit field")
+	}
+	f, err := s.fss.ForEpoch(q.Epoch())
+	if err != nil {
+		return nil, err
+	}
+	b.f = f
+	aggregates := blockAggregates(q)
+
+	b.res64 = zeroed64(b.res64, executions*len(aggregates))
+	b.tmp64 = zeroed64(b.tmp64, executions)
+	b.values = zeroed64(b.values, len(aggregates))
+	res, tmp, values := b.res64, b.tmp64, b.values
+	now := time.Now().Year()
+	for i := 0; i < s.db.NumColumns; i++ {
+		k := s.db.KeysInfo[i]
+		var valid bool
+		b.in, valid = appendInputForTarget(b.in[:0], q, k)
+		if !valid {
+			continue
+		}
+		b.f.EvaluatePF64(s.serverNum, q.FssKey, b.in, tmp)
+		aggregateValues(aggregates, k, now, values)
+
+		for a := range values {
+			block := res[a*executions : (a+1)*executions]
+			for j := range block {
+				block[j] = field.Add64(block[j], field.Mul64(tmp[j], values[a]))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// answer computes the answer to the query in the buffers, whose block has
+// one element per result
+func (s *serverFSS) answer(q *query.FSS, b *answerBuffers) ([]uint32, error) {
+	f, err := s.fss.ForEpoch(q.Epoch())
+	if err != nil {
+		return nil, err
+	}
+	b.f = f
 	numIdentifiers := s.db.NumColumns
+	out, tmp := b.out, b.tmp
+
+	if !q.Lt && len(q.FssKey.FinalCW) != len(out) {
+		return nil, errors.New("malformed FSS key")
+	}
+	if q.BucketSize > 0 {
+		return s.answerBuckets(q, b), nil
+	}
+	if len(q.Aggregates) > 0 || q.Lt || q.Not {
+		return s.answerAggregates(q, b), nil
+	}
 
 	if !q.And && !q.Avg && !q.Sum {
-		switch q.Target {
-		case query.UserId:
-			for i := 0; i < numIdentifiers; i++ {
-				email := s.db.KeysInfo[i].UserId.Email
-				id, valid := q.IdForEmail(email)
-				if !valid {
-					continue
-				}
-				s.fss.EvaluatePF(s.serverNum, q.FssKey, id, tmp)
-				for j := range out {
-					out[j] = (out[j] + tmp[j]) % field.ModP
-				}
-			}
-			return out
-		case query.PubKeyAlgo:
-			for i := 0; i < numIdentifiers; i++ {
-				id := q.IdForPubKeyAlgo(s.db.KeysInfo[i].PubKeyAlgo)
-				s.fss.EvaluatePF(s.serverNum, q.FssKey, id, tmp)
-				for j := range out {
-					out[j] = (out[j] + tmp[j]) % field.ModP
-				}
-			}
-			return out
-		case query.CreationTime:
-			for i := 0; i < numIdentifiers; i++ {
-				id, err := q.IdForCreationTime(s.db.KeysInfo[i].CreationTime)
-				if err != nil {
-					panic("impossible to marshal creation date")
-				}
-				s.fss.EvaluatePF(s.serverNum, q.FssKey, id, tmp)
-				for j := range out {
-					out[j] = (out[j] + tmp[j]) % field.ModP
-				}
+		for i := 0; i < numIdentifiers; i++ {
+			var valid bool
+			b.in, valid = appendInputForTarget(b.in[:0], q, s.db.KeysInfo[i])
+			if !valid {
+				continue
 			}
-			return out
-		default:
-			panic("not yet implemented")
+			b.f.EvaluatePF(s.serverNum, q.FssKey, b.in, tmp)
+			s.fss.Field.AddVectors(out, out, tmp)
 		}
+		return out, nil
 	} else if q.And && !q.Avg && !q.Sum { // conjunction
 		for i := 0; i < numIdentifiers; i++ {
 			// year
 			yearMatch, err := q.IdForYearCreationTime(s.db.KeysInfo[i].CreationTime)
 			if err != nil {
-				panic(err)
+				return nil, err
 			}
 			// edu
 			email := s.db.KeysInfo[i].UserId.Email
@@ -95,15 +180,14 @@ func (s *serverFSS) answer(q *query.FSS, out, tmp []uint32) []uint32 {
 				continue
 			}
 			in := append(yearMatch, id...)
-			s.fss.EvaluatePF(s.serverNum, q.FssKey, in, tmp)
-			for j := range out {
-				out[j] = (out[j] + tmp[j]) % field.ModP
+			if len(in) != len(q.FssKey.CW) {
+				return nil, errors.New("FSS key of the wrong depth")
 			}
+			b.f.EvaluatePF(s.serverNum, q.FssKey, in, tmp)
+			s.fss.Field.AddVectors(out, out, tmp)
 		}
-		return out
+		return out, nil
 
-	} else if q.And && q.Sum && !q.Avg { // sum
-		panic("sum not implemented")
 	} else if q.And && q.Avg && !q.Sum { // avg
 		sum := make([]uint32, len(out))
 		for i := 0; i < numIdentifiers; i++ {
@@ -112,8 +196,11 @@ func (s *serverFSS) answer(q *query.FSS, out, tmp []uint32) []uint32 {
 			if !valid {
 				continue
 			}
+			if len(in) != len(q.FssKey.CW) {
+				return nil, errors.New("FSS key of the wrong depth")
+			}
 
-			s.fss.EvaluatePF(s.serverNum, q.FssKey, in, tmp)
+			b.f.EvaluatePF(s.serverNum, q.FssKey, in, tmp)
 
 			// compute difference in years between now and creation time
 			diffYears := time.Now().Year() - s.db.KeysInfo[i].CreationTime.Year()
@@ -121,18 +208,167 @@ func (s *serverFSS) answer(q *query.FSS, out, tmp []uint32) []uint32 {
 				// some keys are malformed (creation time 2040, 2106, 2031), remove them
 				continue
 			}
+			if s.db.NoiseEpsilon > 0 && diffYears > query.MaxYears {
+				diffYears = query.MaxYears
+			}
 
 			for j := range out {
 				// COUNT
-				out[j] = (out[j] + tmp[j]) % field.ModP
+				out[j] = s.fss.Field.Add(out[j], tmp[j])
 
 				// SUM
-				tmpYears := (uint64(tmp[j]) * uint64(diffYears)) % uint64(field.ModP)
-				sum[j] = (sum[j] + uint32(tmpYears)) % field.ModP
+				sum[j] = s.fss.Field.Reduce(uint64(sum[j]) + field.MulNoReduce(tmp[j], uint32(diffYears)))
+			}
+		}
+		return append(out, sum...), nil
+	}
+	return nil, errors.New("query not recognized")
+}
+
+// answerAggregates evaluates the FSS key once per database entry and
+// accumulates all the aggregates requested by the query. The output contains
+// one block of len(out) elements per aggregate, in the order of q.Aggregates.
+// Comparison and negated queries without aggregates return the count of
+// matching entries. The answer to a negated query is the authenticated
+// total of every aggregate over all the entries minus the answer to the
+// query, whose MAC shares must be checked by the caller.
+func (s *serverFSS) answerAggregates(q *query.FSS, b *answerBuffers) []uint32 {
+	aggregates := blockAggregates(q)
+	blockLen := len(b.out)
+	b.res = zeroed(b.res, blockLen*len(aggregates))
+	b.values = zeroed64(b.values, len(aggregates))
+	b.totals = zeroed(b.totals, len(aggregates))
+	res, tmp, values, totals := b.res, b.tmp, b.values, b.totals
+	now := time.Now().Year()
+	fl := s.fss.Field
+
+	for i := 0; i < s.db.NumColumns; i++ {
+		k := s.db.KeysInfo[i]
+		var valid bool
+		b.in, valid = appendInputForTarget(b.in[:0], q, k)
+		in := b.in
+		if !valid && !q.Not {
+			continue
+		}
+
+		aggregateValues(aggregates, k, now, values)
+		if s.db.NoiseEpsilon > 0 {
+			clampValues(aggregates, values)
+		}
+		if q.Not {
+			for a := range values {
+				totals[a] = fl.Add(totals[a], fl.Reduce(values[a]))
 			}
 		}
-		return append(out, sum...)
-	} else {
-		panic("query not recognized")
+		// the entries that cannot be evaluated do not match the query, but
+		// count in the totals of the negated query
+		if !valid {
+			continue
+		}
+
+		if q.Lt {
+			b.f.EvaluateLt(s.serverNum, q.FssKeyLt, in, tmp)
+		} else {
+			b.f.EvaluatePF(s.serverNum, q.FssKey, in, tmp)
+		}
+		for a := range values {
+			v := uint32(values[a] % uint64(fl.Modulus()))
+			fl.AddMulVector(res[a*blockLen:(a+1)*blockLen], tmp, v)
+		}
+	}
+
+	if q.Not {
+		for a := range aggregates {
+			block := res[a*blockLen : (a+1)*blockLen]
+			for j := range block {
+				block[j] = fl.Neg(block[j])
+			}
+			s.addAuthenticated(block, q.MACShares, totals[a])
+		}
+	}
+
+	return res
+}
+
+// aggregateValues stores in values the contribution of key k to each of the
+// aggregates
+func aggregateValues(aggregates []query.Aggregate, k *database.KeyInfo, now int, values []uint64) {
+	for a, agg := range aggregates {
+		switch agg {
+		case query.Count:
+			values[a] = 1
+		case query.SumYears:
+			// some keys are malformed (creation time 2040, 2106, 2031),
+			// they do not contribute to the sum
+			values[a] = 0
+			if diffYears := now - k.CreationTime.Year(); diffYears > 0 {
+				values[a] = uint64(diffYears)
+			}
+		case query.SumBitLength:
+			values[a] = uint64(k.BitLength)
+		default:
+			panic("aggregate not recognized")
+		}
+	}
+}
+
+// appendInputForTarget appends the FSS input corresponding to the query
+// target for the given key to dst. It returns false if the key cannot be
+// evaluated, e.g., when the email is shorter than the substring selected by
+// the query or the input is not as deep as the FSS key of the query. The
+// comparison queries take the fixed-width encoding of the numeric targets.
+func appendInputForTarget(dst []bool, q *query.FSS, k *database.KeyInfo) ([]bool, bool) {
+	depth := len(q.FssKey.CW)
+	if q.Lt {
+		depth = len(q.FssKeyLt.CW)
+	}
+	if q.Page {
+		// the input of the page queries is prefixed by the bucket
+		depth -= query.BucketBits
+	}
+	in, valid := inputForTarget(dst, q, k)
+	return in, valid && len(in)-len(dst) == depth
+}
+
+func inputForTarget(dst []bool, q *query.FSS, k *database.KeyInfo) ([]bool, bool) {
+	if q.Lt {
+		id, err := q.AppendIdForNumber(dst, numericValue(q.Target, k))
+		if err != nil {
+			panic("comparison not implemented for this target")
+		}
+		return id, true
+	}
+
+	switch q.Target {
+	case query.UserId:
+		return q.AppendIdForEmail(dst, k.UserId.Email)
+	case query.PubKeyAlgo:
+		id, _ := q.AppendIdForNumber(dst, uint64(k.PubKeyAlgo))
+		return id, true
+	case query.CreationTime:
+		id, err := q.IdForCreationTime(k.CreationTime)
+		if err != nil {
+			panic("impossible to marshal creation date")
+		}
+		return append(dst, id...), true
+	case query.KeySize:
+		id, _ := q.AppendIdForNumber(dst, numericValue(q.Target, k))
+		return id, true
+	default:
+		panic("not yet implemented")
+	}
+}
+
+// numericValue returns the value of the numeric target for the given key
+func numericValue(t query.Target, k *database.KeyInfo) uint64 {
+	switch t {
+	case query.CreationTime:
+		return uint64(k.CreationTime.Unix())
+	case query.PubKeyAlgo:
+		return uint64(k.PubKeyAlgo)
+	case query.KeySize:
+		return uint64(k.BitLength)
+	default:
+		return 0
 	}
 }
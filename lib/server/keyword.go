@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// Keyword is a server of the keyword-PIR scheme based on constant-weight
+// codes. For every record, the server multiplies its shares of the bits of
+// the queried codeword at the ones of the codeword of the record, which
+// checks their equality, and answers with the sum of the records weighted by
+// the checks. The answer is as long as a single record, whatever the number
+// of records sharing a bucket of a hash table would be.
+type Keyword struct {
+	db    *database.Keyword
+	field *field.Field
+	// evaluation point of the shares of the server
+	point uint32
+}
+
+// NewKeyword returns the server with the given id, out of CodeWeight+1
+// servers, of the keyword db
+func NewKeyword(db *database.Keyword, id int) (*Keyword, error) {
+	if id < 0 || id > db.CodeWeight {
+		return nil, fmt.Errorf("invalid server %d out of %d", id, db.CodeWeight+1)
+	}
+	return &Keyword{db: db, field: db.Field(), point: uint32(id + 1)}, nil
+}
+
+// DBInfo returns database info
+func (s *Keyword) DBInfo() *database.Info {
+	return &s.db.Info
+}
+
+// AnswerBytes answers the encoded shares of a codeword
+func (s *Keyword) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	shares, err := s.field.DecodeElements(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) != s.db.CodeLength {
+		return nil, errors.New("malformed query")
+	}
+	t.End()
+
+	a := s.field.EncodeElements(s.Answer(shares))
+	monitor.CountAnswer(a)
+	return a, nil
+}
+
+// Answer returns the sum of the records weighted by the products of the
+// shares at the ones of their codewords
+func (s *Keyword) Answer(shares []uint32) []uint32 {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+	bs := s.db.BlockSize
+	out := make([]uint32, bs)
+	for i, codeword := range s.db.Codewords {
+		check := uint32(1)
+		for _, p := range codeword {
+			check = s.field.Mul(check, shares[p])
+		}
+		s.field.AddMulVector(out, s.db.Entries[i*bs:(i+1)*bs], check)
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/rlwe"
+)
+
+// LatticeSingle is the single server of the lattice PIR scheme. It stores
+// the evaluation keys of its clients, which expand their compressed queries.
+type LatticeSingle struct {
+	db     *database.LatticeSingle
+	params *rlwe.Params
+	// blocks of the db encoded as plaintexts, in row-major order
+	plaintexts []*rlwe.Plaintext
+
+	mu   sync.RWMutex
+	keys map[string]*rlwe.EvaluationKeys
+}
+
+// NewLatticeSingle returns the server of the db, encoding its blocks as
+// plaintexts in the NTT domain
+func NewLatticeSingle(db *database.LatticeSingle, params *rlwe.Params) *LatticeSingle {
+	pts := make([]*rlwe.Plaintext, db.NumRows*db.NumColumns)
+	for i := range pts {
+		pts[i] = params.EncodePlaintext(db.Block(i))
+	}
+	return &LatticeSingle{
+		db:         db,
+		params:     params,
+		plaintexts: pts,
+		keys:       make(map[string]*rlwe.EvaluationKeys),
+	}
+}
+
+func (s *LatticeSingle) DBInfo() *database.Info {
+	return &s.db.Info
+}
+
+// AddKeys stores the encoded evaluation keys of a client, which its queries
+// refer to by their identifier
+func (s *LatticeSingle) AddKeys(keys []byte) error {
+	ek, err := s.params.DecodeEvaluationKeys(keys)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.keys[string(rlwe.KeyID(keys))] = ek
+	s.mu.Unlock()
+	return nil
+}
+
+// AnswerBytes answers a query made of the identifier of the evaluation keys
+// of the client followed by a seeded ciphertext per dimension of the db
+func (s *LatticeSingle) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	dims := database.LatticeDimensions(&s.db.Info)
+	ctLen := s.params.SeededCiphertextLen()
+	if len(q) != rlwe.KeyIDLen+len(dims)*ctLen {
+		return nil, errors.New("malformed query")
+	}
+	s.mu.RLock()
+	ek, ok := s.keys[string(q[:rlwe.KeyIDLen])]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("unknown evaluation keys")
+	}
+	query := make([]*rlwe.Ciphertext, len(dims))
+	for j := range query {
+		start := rlwe.KeyIDLen + j*ctLen
+		ct, err := s.params.DecodeSeededCiphertext(q[start : start+ctLen])
+		if err != nil {
+			return nil, err
+		}
+		query[j] = ct.Expand(s.params)
+	}
+	t.End()
+
+	a, err := s.Answer(ek, query)
+	if err != nil {
+		return nil, err
+	}
+
+	t = monitor.StartPhase(monitor.PhaseEncode)
+	out := rlwe.EncodeCiphertexts(a)
+	t.End()
+	monitor.CountAnswer(out)
+	return out, nil
+}
+
+// Answer returns the answer to a query of a ciphertext per dimension of the
+// db, modulus switched to 2^LogQAnswer. The db is folded one dimension at a
+// time: the ciphertexts selecting the rows are multiplied by the plaintexts
+// of the db, then the resulting ciphertexts are decomposed in plaintexts,
+// which the ciphertexts selecting the column multiply in turn.
+func (s *LatticeSingle) Answer(ek *rlwe.EvaluationKeys, query []*rlwe.Ciphertext) ([]*rlwe.Ciphertext, error) {
+	p := s.params
+	dims := database.LatticeDimensions(&s.db.Info)
+	if len(query) != len(dims) {
+		return nil, errors.New("malformed query")
+	}
+
+	t := monitor.StartPhase(monitor.PhaseExpand)
+	selectors := make([][]*rlwe.Ciphertext, len(dims))
+	for j, ct := range query {
+		var err error
+		if selectors[j], err = ek.Expand(ct, dims[j]); err != nil {
+			return nil, err
+		}
+	}
+	t.End()
+
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+	// every entry of the hypercube holds the plaintexts of an element, the
+	// first dimension being the slowest one
+	entries := make([][]*rlwe.Plaintext, len(s.plaintexts))
+	for i, pt := range s.plaintexts {
+		entries[i] = []*rlwe.Plaintext{pt}
+	}
+	var out []*rlwe.Ciphertext
+	for j, dim := range dims {
+		rest := len(entries) / dim
+		folded := make([][]*rlwe.Ciphertext, rest)
+		for x := range folded {
+			folded[x] = make([]*rlwe.Ciphertext, len(entries[x]))
+			for k := range folded[x] {
+				acc := p.NewCiphertext()
+				for i, sel := range selectors[j] {
+					p.MulPlainAdd(sel, entries[i*rest+x][k], acc)
+				}
+				p.InvNTT(acc)
+				folded[x][k] = acc
+			}
+		}
+
+		if j == len(dims)-1 {
+			out = folded[0]
+			break
+		}
+		entries = make([][]*rlwe.Plaintext, rest)
+		for x, cts := range folded {
+			for _, ct := range cts {
+				for _, pt := range p.Decompose(p.ModSwitch(ct, p.LogQ1), p.LogQ1) {
+					entries[x] = append(entries[x], p.EncodePlaintext(pt))
+				}
+			}
+		}
+	}
+
+	for k := range out {
+		out[k] = p.ModSwitch(out[k], rlwe.LogQAnswer)
+	}
+	return out, nil
+}
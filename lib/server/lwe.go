@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
 )
 
 type LWE struct {
@@ -18,12 +19,22 @@ func (s *LWE) DBInfo() *database.Info {
 }
 
 func (s *LWE) AnswerBytes(q []byte) ([]byte, error) {
-	a := s.Answer(matrix.BytesToMatrix(q))
-	return matrix.MatrixToBytes(a), nil
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	query := matrix.BytesToMatrix(q)
+	t.End()
+
+	a := s.Answer(query)
+
+	t = monitor.StartPhase(monitor.PhaseEncode)
+	out := matrix.MatrixToBytes(a)
+	t.End()
+	monitor.CountAnswer(out)
+	return out, nil
 }
 
 // Answer function for the LWE-based scheme. The query is represented as a
 // vector
 func (s *LWE) Answer(q *matrix.Matrix) *matrix.Matrix {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
 	return matrix.BinaryMul(q, s.db.Matrix)
 }
@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
 )
 
 type LWE128 struct {
@@ -18,12 +19,22 @@ func (s *LWE128) DBInfo() *database.Info {
 }
 
 func (s *LWE128) AnswerBytes(q []byte) ([]byte, error) {
-	a := s.Answer(matrix.BytesToMatrix128(q))
-	return matrix.Matrix128ToBytes(a), nil
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	query := matrix.BytesToMatrix128(q)
+	t.End()
+
+	a := s.Answer(query)
+
+	t = monitor.StartPhase(monitor.PhaseEncode)
+	out := matrix.Matrix128ToBytes(a)
+	t.End()
+	monitor.CountAnswer(out)
+	return out, nil
 }
 
 // Answer function for the LWE-based scheme. The query is represented as a
 // vector
 func (s *LWE128) Answer(q *matrix.Matrix128) *matrix.Matrix128 {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
 	return matrix.BinaryMul128(q, s.db.Matrix)
 }
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// answerQuery checks the query and returns the answer, with the noise of
+// the servers added to every aggregate if the db has a positive
+// NoiseEpsilon. The servers share a seed, from which they expand the same
+// noise per query nonce and aggregate: a two-sided geometric variable of
+// parameter exp(-epsilon / sensitivity), so that every aggregate of an
+// answer is epsilon-differentially private with respect to the keys of the
+// db. The noise is authenticated as the totals of the negated queries, so
+// that the tags authenticate the noisy data. A nonce is answered once, since
+// two answers with the same noise would cancel it out.
+//
+// The privacy holds against clients that generate their FSS keys honestly:
+// a client that scales the data in its keys scales it with respect to the
+// noise too.
+func (s *serverFSS) answerQuery(q *query.FSS, b *answerBuffers) ([]uint32, error) {
+	executions := len(b.out)
+	if _, numeric := q.Target.Width(); q.Lt && !numeric {
+		return nil, errors.New("comparison on a non-numeric target")
+	}
+	if q.BucketSize != 0 {
+		if err := s.checkBuckets(q); err != nil {
+			return nil, err
+		}
+	}
+	if q.Not {
+		if q.And || q.Avg || q.Sum {
+			return nil, errors.New("negation not implemented for this query")
+		}
+		if len(q.MACShares) != executions-1 {
+			return nil, errors.New("malformed negated query")
+		}
+	}
+	if s.db.NoiseEpsilon <= 0 {
+		return s.answer(q, b)
+	}
+	if s.noiseSeed == nil {
+		return nil, errors.New("missing seed of the noise")
+	}
+	if len(q.Nonce) != database.NoiseNonceLen || len(q.MACShares) != executions-1 {
+		return nil, errors.New("malformed noisy query")
+	}
+	s.mu.Lock()
+	if _, ok := s.nonces[string(q.Nonce)]; ok {
+		s.mu.Unlock()
+		return nil, errors.New("nonce already answered")
+	}
+	s.nonces[string(q.Nonce)] = struct{}{}
+	s.mu.Unlock()
+
+	a, err := s.answer(q, b)
+	if err != nil {
+		return nil, err
+	}
+	for k, agg := range blockAggregates(q) {
+		block := a[k*executions : (k+1)*executions]
+		s.addAuthenticated(block, q.MACShares, s.noise(q.Nonce, k, agg.Sensitivity()))
+	}
+	return a, nil
+}
+
+// addAuthenticated adds the value, known to both servers, to the block of
+// the answer of a single aggregate. The first server adds the value to the
+// data, and every server adds the value times its share of the MAC keys of
+// the client to the tags, so that the reconstructed tags are the ones of the
+// data plus the value.
+func (s *serverFSS) addAuthenticated(block, shares []uint32, v uint32) {
+	fl := s.fss.Field
+	if s.serverNum == 0 {
+		block[0] = fl.Add(block[0], v)
+	}
+	fl.AddMulVector(block[1:], shares, v)
+}
+
+// noise returns the noise of the k-th aggregate of the answer to the query
+// with the nonce, as a field element
+func (s *serverFSS) noise(nonce []byte, k int, sensitivity uint64) uint32 {
+	h := sha256.New()
+	h.Write(s.noiseSeed[:])
+	h.Write(nonce)
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(k))
+	h.Write(buf)
+	var key utils.PRGKey
+	copy(key[:], h.Sum(nil))
+	prg := utils.NewPRG(&key)
+
+	alpha := math.Exp(-s.db.NoiseEpsilon / float64(sensitivity))
+	n := geometric(prg, alpha) - geometric(prg, alpha)
+
+	fl := s.fss.Field
+	if n < 0 {
+		return fl.Neg(fl.Reduce(uint64(-n)))
+	}
+	return fl.Reduce(uint64(n))
+}
+
+// geometric returns a geometric variable G with P(G >= k) = alpha^k
+func geometric(rnd io.Reader, alpha float64) int64 {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(rnd, buf); err != nil {
+		panic(err)
+	}
+	// uniform in (0, 1]
+	u := float64(binary.LittleEndian.Uint64(buf)>>11+1) / (1 << 53)
+	return int64(math.Floor(math.Log(u) / math.Log(alpha)))
+}
+
+// blockAggregates returns the aggregates of the answer to the query, in the
+// order of the blocks of the answer
+func blockAggregates(q *query.FSS) []query.Aggregate {
+	switch {
+	case len(q.Aggregates) > 0:
+		return q.Aggregates
+	case q.And && q.Avg:
+		return []query.Aggregate{query.Count, query.SumYears}
+	default:
+		return countAggregate
+	}
+}
+
+// countAggregate are the aggregates of the queries without aggregates,
+// shared by their answers
+var countAggregate = []query.Aggregate{query.Count}
+
+// clampValues bounds the contribution of a key to each of the aggregates by
+// the sensitivity of the aggregate
+func clampValues(aggregates []query.Aggregate, values []uint64) {
+	for a, agg := range aggregates {
+		if max := agg.Sensitivity(); values[a] > max {
+			values[a] = max
+		}
+	}
+}
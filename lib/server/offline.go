@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/lukechampine/fastxor"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// OfflineOnline is a server of the two-server offline/online PIR scheme of
+// Corrigan-Gibbs and Kogan. In the offline phase, the first server streams
+// the hints of the sets of a client in a single pass over the db. In the
+// online phase, both servers answer the punctured sets of the queries in
+// time linear in the number of rows of the db, i.e., sublinear in its size.
+type OfflineOnline struct {
+	db *database.Bytes
+}
+
+// NewOfflineOnline returns a server of the db, whose blocks have the same
+// size
+func NewOfflineOnline(db *database.Bytes) *OfflineOnline {
+	return &OfflineOnline{db: db}
+}
+
+func (s *OfflineOnline) DBInfo() *database.Info {
+	return &s.db.Info
+}
+
+// HintsBytes answers the offline query of a client, made of the key of its
+// sets and their number, with the parities of the blocks of all the sets
+func (s *OfflineOnline) HintsBytes(q []byte) ([]byte, error) {
+	var key utils.PRGKey
+	if len(q) != len(key)+4 {
+		return nil, errors.New("malformed offline query")
+	}
+	copy(key[:], q)
+	numHints := int(binary.BigEndian.Uint32(q[len(key):]))
+	if numHints == 0 {
+		return nil, errors.New("no hints requested")
+	}
+
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+	sets := utils.NewSetPRF(&key, s.db.NumColumns)
+	bs := s.db.BlockSize
+	hints := make([]byte, numHints*bs)
+	// a pass over the rows of the db updates all the hints
+	for r := 0; r < s.db.NumRows; r++ {
+		for id := 0; id < numHints; id++ {
+			fastxor.Bytes(hints[id*bs:(id+1)*bs], hints[id*bs:(id+1)*bs], s.block(r, sets.Offset(uint32(id), r)))
+		}
+	}
+	monitor.CountAnswer(hints)
+	return hints, nil
+}
+
+// AnswerBytes answers a punctured set, i.e., the columns of its blocks in all
+// the rows but one, which the server does not know
+func (s *OfflineOnline) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	if len(q) != 4*(s.db.NumRows-1) {
+		return nil, errors.New("malformed query")
+	}
+	offsets := make([]int, s.db.NumRows-1)
+	for i := range offsets {
+		offsets[i] = int(binary.BigEndian.Uint32(q[4*i:]))
+		if offsets[i] >= s.db.NumColumns {
+			return nil, errors.New("column out of range")
+		}
+	}
+	t.End()
+
+	a := s.Answer(offsets)
+	monitor.CountAnswer(a)
+	return a, nil
+}
+
+// Answer returns, for every row, the parity of the punctured set if the
+// punctured row was that one: the columns before it are in the previous rows
+// and the following ones in the next rows
+func (s *OfflineOnline) Answer(offsets []int) []byte {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+	rows, bs := s.db.NumRows, s.db.BlockSize
+
+	// prefix[r] is the parity of the columns before r in their own rows,
+	// suffix[r] the one of the columns from r on in the next rows
+	prefix := make([]byte, rows*bs)
+	suffix := make([]byte, rows*bs)
+	for r := 1; r < rows; r++ {
+		fastxor.Bytes(prefix[r*bs:(r+1)*bs], prefix[(r-1)*bs:r*bs], s.block(r-1, offsets[r-1]))
+	}
+	for r := rows - 2; r >= 0; r-- {
+		fastxor.Bytes(suffix[r*bs:(r+1)*bs], suffix[(r+1)*bs:(r+2)*bs], s.block(r+1, offsets[r]))
+	}
+
+	fastxor.Bytes(prefix, prefix, suffix)
+	return prefix
+}
+
+// block returns the block of the db at the given row and column
+func (s *OfflineOnline) block(row, column int) []byte {
+	return s.db.Block(row*s.db.NumColumns + column)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/lukechampine/fastxor"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// Piano is the single server of the client-preprocessing PIR scheme in the
+// style of Piano. The server keeps no state about its clients: it streams the
+// rows of the db to the clients that preprocess it and answers every query,
+// a set with a block per row, with the parity of its blocks. The epoch of the
+// db is identified by its digest, which the clients send with their queries
+// to detect that their hints are stale.
+type Piano struct {
+	mu     sync.RWMutex
+	db     *database.Bytes
+	digest []byte
+}
+
+// NewPiano returns the server of the db, whose blocks have the same size
+func NewPiano(db *database.Bytes) *Piano {
+	return &Piano{db: db, digest: db.Digest()}
+}
+
+func (s *Piano) DBInfo() *database.Info {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &s.db.Info
+}
+
+// Digest returns the digest of the current epoch of the db
+func (s *Piano) Digest() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.digest
+}
+
+// Update replaces the db with a new epoch of the same size. The hints of the
+// clients of the previous epoch are stale, so that their queries are
+// answered with the digest of the new epoch only.
+func (s *Piano) Update(db *database.Bytes) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if db.NumRows != s.db.NumRows || db.NumColumns != s.db.NumColumns || db.BlockSize != s.db.BlockSize {
+		return errors.New("the layout of the db cannot change")
+	}
+	s.db, s.digest = db, db.Digest()
+	return nil
+}
+
+// ChunkBytes answers a request of the preprocessing of a client, i.e., the
+// index of a row of the db, with the digest of the db followed by the row
+func (s *Piano) ChunkBytes(q []byte) ([]byte, error) {
+	if len(q) != 4 {
+		return nil, errors.New("malformed chunk request")
+	}
+	row := int(binary.BigEndian.Uint32(q))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if row >= s.db.NumRows {
+		return nil, errors.New("row out of range")
+	}
+	a := make([]byte, 0, len(s.digest)+s.db.NumColumns*s.db.BlockSize)
+	a = append(a, s.digest...)
+	for j := 0; j < s.db.NumColumns; j++ {
+		a = append(a, s.db.Block(row*s.db.NumColumns+j)...)
+	}
+	return a, nil
+}
+
+// AnswerBytes answers a query made of the digest of the db preprocessed by
+// the client followed by the column of a block in every row. The answer is
+// the digest of the db followed by the parity of the blocks, or the digest
+// alone if the hints of the client are stale.
+func (s *Piano) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(q) != sha256.Size+4*s.db.NumRows {
+		return nil, errors.New("malformed query")
+	}
+	a := append([]byte{}, s.digest...)
+	if !bytes.Equal(q[:sha256.Size], s.digest) {
+		t.End()
+		monitor.CountAnswer(a)
+		return a, nil
+	}
+	offsets := make([]int, s.db.NumRows)
+	for r := range offsets {
+		offsets[r] = int(binary.BigEndian.Uint32(q[sha256.Size+4*r:]))
+		if offsets[r] >= s.db.NumColumns {
+			return nil, errors.New("column out of range")
+		}
+	}
+	t.End()
+
+	a = append(a, s.Answer(offsets)...)
+	monitor.CountAnswer(a)
+	return a, nil
+}
+
+// Answer returns the parity of the blocks at the given column of every row
+func (s *Piano) Answer(offsets []int) []byte {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+	parity := make([]byte, s.db.BlockSize)
+	for r, o := range offsets {
+		fastxor.Bytes(parity, parity, s.db.Block(r*s.db.NumColumns+o))
+	}
+	return parity
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/aes"
+	"errors"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// PIRDPF is the server for the two-server computational classical PIR scheme
+// working in GF(2). The server expands the DPF key received from the client
+// into the query vector of the PIR scheme and answers it as the
+// information-theoretic server does.
+type PIRDPF struct {
+	pir *PIR
+	fss *fss.Fss
+}
+
+// NewPIRDPF returns a server for the DPF-based classical PIR scheme
+func NewPIRDPF(db *database.Bytes, cores ...int) *PIRDPF {
+	return &PIRDPF{
+		pir: NewPIR(db, cores...),
+		fss: fss.ServerInitialize(1),
+	}
+}
+
+// DBInfo returns database info
+func (s *PIRDPF) DBInfo() *database.Info {
+	return s.pir.DBInfo()
+}
+
+// AnswerBytes computes the answer for the given DPF key encoded in bytes
+func (s *PIRDPF) AnswerBytes(q []byte) ([]byte, error) {
+	return s.AppendAnswer(nil, q)
+}
+
+// AppendAnswer appends the answer for the given DPF key encoded in bytes to
+// dst
+func (s *PIRDPF) AppendAnswer(dst, q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	key, err := fss.DecodeKey(q)
+	if err != nil {
+		return dst, err
+	}
+	// the key is expanded over the whole domain of the columns
+	if len(key.SInit) != aes.BlockSize || len(key.CW) != int(fss.NumBitsForDomain(s.pir.db.NumColumns)) {
+		return dst, errors.New("DPF key of the wrong depth for the db")
+	}
+	f, err := s.fss.ForEpoch(key.Epoch)
+	if err != nil {
+		return dst, err
+	}
+	t.End()
+
+	n := len(dst)
+	dst = s.appendAnswer(dst, f, key)
+	monitor.CountAnswer(dst[n:])
+	return dst, nil
+}
+
+// Answer computes the answer for the given DPF key. It panics if the key is of
+// an unknown or expired epoch.
+func (s *PIRDPF) Answer(key fss.FssKeyEq2P) []byte {
+	f, err := s.fss.ForEpoch(key.Epoch)
+	if err != nil {
+		panic(err)
+	}
+	return s.appendAnswer(nil, f, key)
+}
+
+// appendAnswer appends the answer to the query vector expanded from the key
+// with f, taken from the pool, to dst
+func (s *PIRDPF) appendAnswer(dst []byte, f fss.Fss, key fss.FssKeyEq2P) []byte {
+	t := monitor.StartPhase(monitor.PhaseExpand)
+	numColumns := s.pir.db.NumColumns
+	q := utils.GetBuffer(numColumns/8 + 1)
+	defer q.Release()
+	f.EvaluateFullDomainBits(key, fss.NumBitsForDomain(numColumns), numColumns, q.B)
+	t.End()
+
+	return s.pir.appendAnswer(dst, q.B)
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// PIRReplicated is a server of the honest-majority three-server PIR scheme
+// working in GF(2). A query holds two shares of the query vector, each
+// answered as in the classical PIR scheme.
+type PIRReplicated struct {
+	pir *PIR
+}
+
+// NewPIRReplicated returns a server for the replicated PIR scheme, working
+// both with the vector and the rebalanced representation of the database.
+func NewPIRReplicated(db *database.Bytes, cores ...int) *PIRReplicated {
+	return &PIRReplicated{pir: NewPIR(db, cores...)}
+}
+
+// DBInfo returns database info
+func (s *PIRReplicated) DBInfo() *database.Info {
+	return s.pir.DBInfo()
+}
+
+// AnswerBytes answers both shares of the query, one after the other
+func (s *PIRReplicated) AnswerBytes(q []byte) ([]byte, error) {
+	return s.AppendAnswer(nil, q)
+}
+
+// AppendAnswer appends the answers to both shares of the query to dst
+func (s *PIRReplicated) AppendAnswer(dst, q []byte) ([]byte, error) {
+	vectorLen := s.pir.db.NumColumns/8 + 1
+	if len(q) != 2*vectorLen {
+		return dst, errors.New("malformed query")
+	}
+	n := len(dst)
+	dst = s.pir.appendAnswer(dst, q[:vectorLen])
+	dst = s.pir.appendAnswer(dst, q[vectorLen:])
+	monitor.CountAnswer(dst[n:])
+	return dst, nil
+}
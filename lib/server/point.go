@@ -5,6 +5,7 @@ import (
 
 	"github.com/lukechampine/fastxor"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
 )
 
 // PIR is the server for the information theoretic classical PIR scheme
@@ -35,16 +36,39 @@ func (s *PIR) DBInfo() *database.Info {
 
 // AnswerBytes computes the answer for the given query encoded in bytes
 func (s *PIR) AnswerBytes(q []byte) ([]byte, error) {
-	return s.Answer(q), nil
+	return s.AppendAnswer(nil, q)
+}
+
+// AppendAnswer appends the answer for the given query encoded in bytes to dst
+func (s *PIR) AppendAnswer(dst, q []byte) ([]byte, error) {
+	n := len(dst)
+	dst = s.appendAnswer(dst, q)
+	monitor.CountAnswer(dst[n:])
+	return dst, nil
 }
 
 // Answer computes the answer for the given query
 func (s *PIR) Answer(q []byte) []byte {
+	return s.appendAnswer(nil, q)
+}
+
+// appendAnswer appends the XOR of the blocks selected by the query, one per
+// row, to dst
+func (s *PIR) appendAnswer(dst, q []byte) []byte {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+
 	nRows := s.db.NumRows
 	nCols := s.db.NumColumns
 
 	var prevPos, nextPos int
-	out := make([]byte, nRows*s.db.BlockSize)
+	start := len(dst)
+	dst = grow(dst, nRows*s.db.BlockSize)
+	out := dst[start:]
+
+	if s.db.Layout != database.RowMajor {
+		xorBands(s.db, q, out)
+		return dst
+	}
 
 	for i := 0; i < nRows; i++ {
 		for j := 0; j < nCols; j++ {
@@ -58,7 +82,7 @@ func (s *PIR) Answer(q []byte) []byte {
 			out[i*s.db.BlockSize:(i+1)*s.db.BlockSize])
 		prevPos = nextPos
 	}
-	return out
+	return dst
 }
 
 // XORs entries and q block by block of size bl
@@ -71,3 +95,24 @@ func xorValues(entries []byte, blockLens []int, q []byte, bl int, out []byte) {
 		pos += blockLens[j]
 	}
 }
+
+// xorBands XORs the blocks selected by q into out for the column-major and
+// tiled layouts, where the blocks of a column of a band of rows are
+// contiguous, as are their rows of the answer
+func xorBands(db *database.Bytes, q []byte, out []byte) {
+	bs := db.BlockSize
+	band := db.BandRows()
+	for start := 0; start < db.NumRows; start += band {
+		rows := band
+		if start+rows > db.NumRows {
+			rows = db.NumRows - start
+		}
+		entries := db.Entries[start*db.NumColumns*bs : (start+rows)*db.NumColumns*bs]
+		outBand := out[start*bs : (start+rows)*bs]
+		for j := 0; j < db.NumColumns; j++ {
+			if (q[j/8]>>(j%8))&1 == byte(1) {
+				fastxor.Bytes(outBand, outBand, entries[j*rows*bs:(j+1)*rows*bs])
+			}
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package server
 
 import (
+	"errors"
 	"runtime"
 
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/field"
 	"github.com/si-co/vpir-code/lib/fss"
 	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/utils"
 )
 
 // PredicateAPIR represent the server for the FSS-based complex-queries authenticated PIR
@@ -21,31 +23,72 @@ func NewPredicateAPIR(db *database.DB, serverNum byte, cores ...int) *PredicateA
 		numCores = cores[0]
 	}
 
+	// one value for the data, four values for the info-theoretic MAC
+	f := fss.ServerInitialize(1 + field.ConcurrentExecutions)
+	f.SetField(db.Field())
+
 	return &PredicateAPIR{
 		&serverFSS{
 			db:        db,
 			cores:     numCores,
 			serverNum: serverNum,
-			// one value for the data, four values for the info-theoretic MAC
-			fss: fss.ServerInitialize(1 + field.ConcurrentExecutions),
+			fss:       f,
 		},
 	}
 }
 
+// NewNoisyPredicateAPIR returns the server of a db with a positive
+// NoiseEpsilon, which shares the seed of the noise of its answers with the
+// other server
+func NewNoisyPredicateAPIR(db *database.DB, serverNum byte, seed *utils.PRGKey, cores ...int) (*PredicateAPIR, error) {
+	if db.NoiseEpsilon <= 0 {
+		return nil, errors.New("the db has no noisy answers")
+	}
+	s := NewPredicateAPIR(db, serverNum, cores...)
+	s.noiseSeed = seed
+	s.nonces = make(map[string]struct{})
+	return s, nil
+}
+
 func (s *PredicateAPIR) DBInfo() *database.Info {
 	return s.serverFSS.dbInfo()
 }
 
 func (s *PredicateAPIR) AnswerBytes(q []byte) ([]byte, error) {
-	out := make([]uint32, 1+field.ConcurrentExecutions)
-	tmp := make([]uint32, 1+field.ConcurrentExecutions)
+	return s.AppendAnswer(nil, q)
+}
 
-	return s.serverFSS.answerBytes(q, out, tmp)
+// AppendAnswer appends the answer for the given query encoded in bytes to
+// dst
+func (s *PredicateAPIR) AppendAnswer(dst, q []byte) ([]byte, error) {
+	return s.serverFSS.appendAnswer(dst, q, s.executions())
 }
 
-func (s *PredicateAPIR) Answer(q *query.FSS) []uint32 {
-	out := make([]uint32, 1+field.ConcurrentExecutions)
-	tmp := make([]uint32, 1+field.ConcurrentExecutions)
+// executions returns the number of elements per result: one value for the
+// data and the values of the info-theoretic MAC
+func (s *PredicateAPIR) executions() int {
+	if s.db.UseField64() {
+		return 1 + field.ConcurrentExecutions64
+	}
+	return 1 + field.ConcurrentExecutions
+}
+
+// Answer64 computes the answer for the given query in the 64-bit field. It
+// panics if the query is malformed.
+func (s *PredicateAPIR) Answer64(q *query.FSS) []uint64 {
+	a, err := s.serverFSS.answer64(q, 1+field.ConcurrentExecutions64, newAnswerBuffers(0))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
 
-	return s.serverFSS.answer(q, out, tmp)
+// Answer computes the answer for the given query, with the noise of the
+// server for a db with noisy answers. It panics if the query is malformed.
+func (s *PredicateAPIR) Answer(q *query.FSS) []uint32 {
+	a, err := s.serverFSS.answerQuery(q, newAnswerBuffers(1+field.ConcurrentExecutions))
+	if err != nil {
+		panic(err)
+	}
+	return a
 }
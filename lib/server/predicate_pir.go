@@ -20,12 +20,15 @@ func NewPredicatePIR(db *database.DB, serverNum byte, cores ...int) *PredicatePI
 		numCores = cores[0]
 	}
 
+	f := fss.ServerInitialize(1) // only one value for data
+	f.SetField(db.Field())
+
 	return &PredicatePIR{
 		&serverFSS{
 			db:        db,
 			cores:     numCores,
 			serverNum: serverNum,
-			fss:       fss.ServerInitialize(1), // only one value for data
+			fss:       f,
 		},
 	}
 }
@@ -37,16 +40,31 @@ func (s *PredicatePIR) DBInfo() *database.Info {
 
 // AnswerBytes computes the answer for the given query encoded in bytes
 func (s *PredicatePIR) AnswerBytes(q []byte) ([]byte, error) {
-	out := make([]uint32, 1)
-	tmp := make([]uint32, 1)
+	return s.AppendAnswer(nil, q)
+}
 
-	return s.serverFSS.answerBytes(q, out, tmp)
+// AppendAnswer appends the answer for the given query encoded in bytes to
+// dst
+func (s *PredicatePIR) AppendAnswer(dst, q []byte) ([]byte, error) {
+	return s.serverFSS.appendAnswer(dst, q, 1)
 }
 
-// Answer computes the answer for the given query
-func (s *PredicatePIR) Answer(q *query.FSS) []uint32 {
-	out := []uint32{0}
-	tmp := []uint32{0}
+// Answer64 computes the answer for the given query in the 64-bit field. It
+// panics if the query is malformed.
+func (s *PredicatePIR) Answer64(q *query.FSS) []uint64 {
+	a, err := s.serverFSS.answer64(q, 1, newAnswerBuffers(0))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
 
-	return s.serverFSS.answer(q, out, tmp)
+// Answer computes the answer for the given query. It panics if the query is
+// malformed.
+func (s *PredicatePIR) Answer(q *query.FSS) []uint32 {
+	a, err := s.serverFSS.answer(q, newAnswerBuffers(1))
+	if err != nil {
+		panic(err)
+	}
+	return a
 }
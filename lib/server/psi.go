@@ -0,0 +1,69 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// LabeledPSI is the server of the labeled private set intersection protocol
+// based on the DH oblivious PRF. The server evaluates its PRF on the blinded
+// identifiers of the clients, without learning them, and sends its table of
+// encrypted labels once to every client. The number of identifiers per query
+// is bounded, since every evaluation lets a client test one identifier
+// against the table.
+type LabeledPSI struct {
+	db         *database.LabeledPSI
+	maxQueries int
+}
+
+// NewLabeledPSI returns the server of the db, answering queries of at most
+// maxQueries identifiers, or of any number with zero
+func NewLabeledPSI(db *database.LabeledPSI, maxQueries int) *LabeledPSI {
+	return &LabeledPSI{db: db, maxQueries: maxQueries}
+}
+
+// DBInfo returns database info
+func (s *LabeledPSI) DBInfo() *database.Info {
+	return &s.db.Info
+}
+
+// TableBytes returns the table of the encrypted labels
+func (s *LabeledPSI) TableBytes() []byte {
+	return s.db.Table
+}
+
+// AnswerBytes answers the blinded identifiers of a query with their PRF
+// evaluations, in the same order
+func (s *LabeledPSI) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	size := s.db.ElementSize
+	if len(q) == 0 || len(q)%size != 0 {
+		return nil, errors.New("malformed query")
+	}
+	if s.maxQueries > 0 && len(q)/size > s.maxQueries {
+		return nil, fmt.Errorf("more than %d identifiers", s.maxQueries)
+	}
+	blinded, err := database.UnmarshalGroupElements(q, s.db.Group, size)
+	if err != nil {
+		return nil, err
+	}
+	t = t.Next(monitor.PhaseScan)
+
+	out := make([]group.Element, len(blinded))
+	for i, e := range blinded {
+		out[i] = s.db.Group.NewElement().Mul(e, s.db.Key)
+	}
+	t = t.Next(monitor.PhaseEncode)
+
+	a, err := database.MarshalGroupElements(out, size)
+	if err != nil {
+		return nil, err
+	}
+	t.End()
+	monitor.CountAnswer(a)
+	return a, nil
+}
@@ -10,3 +10,30 @@ type Server interface {
 	AnswerBytes([]byte) ([]byte, error)
 	DBInfo() *database.Info
 }
+
+// Appender is implemented by the servers that append their answers to a
+// buffer of the caller, so that answering into a reused buffer with enough
+// capacity does not allocate
+type Appender interface {
+	AppendAnswer(dst, q []byte) ([]byte, error)
+}
+
+// AppendAnswer appends the answer of the server to the query encoded in bytes
+// to dst, without copying it if the server is an Appender
+func AppendAnswer(s Server, dst, q []byte) ([]byte, error) {
+	if a, ok := s.(Appender); ok {
+		return a.AppendAnswer(dst, q)
+	}
+	a, err := s.AnswerBytes(q)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, a...), nil
+}
+
+// Digest returns the short digest of the db of the server, which the owner
+// of the db publishes for the clients to hold, so that they verify the
+// answers even if all the servers are malicious
+func Digest(s Server) ([]byte, error) {
+	return database.ClientDigest(s.DBInfo())
+}
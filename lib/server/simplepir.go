@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/proof"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// SimplePIR is the single server of the SimplePIR scheme
+type SimplePIR struct {
+	db   *database.SimplePIR
+	hint *matrix.Matrix
+	// proves the answers if not nil
+	prover proof.Prover
+}
+
+// NewSimplePIR returns the server of the db, computing the hint downloaded by
+// the clients
+func NewSimplePIR(db *database.SimplePIR, params *utils.ParamsLWE) *SimplePIR {
+	return &SimplePIR{db: db, hint: db.Hint(params)}
+}
+
+func (s *SimplePIR) DBInfo() *database.Info {
+	return &s.db.Info
+}
+
+// SetProver makes the server append to every answer the proof that it is
+// correct with respect to the digest of the prover, which the clients
+// retrieve with the hint
+func (s *SimplePIR) SetProver(p proof.Prover) {
+	s.prover = p
+}
+
+// HintBytes returns the encoded hint downloaded by the clients
+func (s *SimplePIR) HintBytes() []byte {
+	return matrix.MatrixToBytes(s.hint)
+}
+
+func (s *SimplePIR) AnswerBytes(q []byte) ([]byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	if len(q) < 8 {
+		return nil, errors.New("truncated query")
+	}
+	query := matrix.BytesToMatrix(q)
+	t.End()
+	if query.Rows() != 1 || query.Cols() != s.db.NumRows || query.Len() != s.db.NumRows {
+		return nil, errors.New("malformed query")
+	}
+
+	a := s.Answer(query)
+
+	t = monitor.StartPhase(monitor.PhaseEncode)
+	out := matrix.MatrixToBytes(a)
+	if s.prover != nil {
+		p, err := s.prover.Prove(q, out)
+		if err != nil {
+			return nil, err
+		}
+		out = utils.JoinMessages([][]byte{out, p})
+	}
+	t.End()
+	monitor.CountAnswer(out)
+	return out, nil
+}
+
+// Answer function for the SimplePIR scheme. The query is represented as a
+// vector
+func (s *SimplePIR) Answer(q *matrix.Matrix) *matrix.Matrix {
+	defer monitor.StartPhase(monitor.PhaseScan).End()
+	return matrix.BytesMul(q, s.db.Matrix)
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lukechampine/fastxor"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// SPIR is a server of the classical PIR scheme in GF(2) in the symmetric
+// mode. The servers share a seed, from which they expand a mask per query
+// nonce: the masks of all the servers sum to zero, so that every answer alone
+// is random. The client learns the retrieved block only if it is honest but
+// curious: the servers cannot check that the shares of a query sum to a unit
+// vector, so a malicious client learns the XOR of any blocks it selects. A
+// nonce is answered once, since the masks of two answers with the same nonce
+// cancel out.
+type SPIR struct {
+	pir        *PIR
+	seed       *utils.PRGKey
+	id         int
+	numServers int
+
+	mu sync.Mutex
+	// nonces already answered, by epoch, for the epochs still answered
+	nonces map[uint64]map[string]struct{}
+}
+
+// NewSPIR returns the server with the given id, out of numServers servers
+// sharing the seed, of a db in the symmetric mode
+func NewSPIR(db *database.Bytes, seed *utils.PRGKey, id, numServers int, cores ...int) (*SPIR, error) {
+	if !db.SPIR {
+		return nil, errors.New("the db is not in the symmetric mode")
+	}
+	if db.NumRows != 1 {
+		return nil, errors.New("the symmetric mode needs the vector representation")
+	}
+	if numServers < 2 || id < 0 || id >= numServers {
+		return nil, fmt.Errorf("invalid server %d out of %d", id, numServers)
+	}
+	return &SPIR{
+		pir:        NewPIR(db, cores...),
+		seed:       seed,
+		id:         id,
+		numServers: numServers,
+		nonces:     make(map[uint64]map[string]struct{}),
+	}, nil
+}
+
+// DBInfo returns database info
+func (s *SPIR) DBInfo() *database.Info {
+	return s.pir.DBInfo()
+}
+
+// AnswerBytes answers a query made of a nonce followed by the query vector
+// of the classical scheme with the nonce followed by the masked answer
+func (s *SPIR) AnswerBytes(q []byte) ([]byte, error) {
+	vectorLen := s.pir.db.NumColumns/8 + 1
+	if len(q) != database.SPIRNonceLen+vectorLen {
+		return nil, errors.New("malformed query")
+	}
+	nonce := q[:database.SPIRNonceLen]
+	if err := s.useNonce(nonce, database.SPIREpoch(time.Now())); err != nil {
+		return nil, err
+	}
+
+	a := s.pir.Answer(q[database.SPIRNonceLen:])
+	fastxor.Bytes(a, a, s.mask(nonce, len(a)))
+	out := append(append([]byte{}, nonce...), a...)
+	monitor.CountAnswer(out)
+	return out, nil
+}
+
+// useNonce records the nonce as answered in its epoch, and drops the nonces
+// of the epochs no longer answered. It returns an error if the nonce was
+// already answered or is not of the current or an adjacent epoch.
+func (s *SPIR) useNonce(nonce []byte, current uint64) error {
+	epoch := binary.BigEndian.Uint64(nonce)
+	if epoch+1 < current || epoch > current+1 {
+		return errors.New("nonce of an epoch not answered")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for e := range s.nonces {
+		if e+1 < current {
+			delete(s.nonces, e)
+		}
+	}
+	answered, ok := s.nonces[epoch]
+	if !ok {
+		answered = make(map[string]struct{})
+		s.nonces[epoch] = answered
+	}
+	if _, ok := answered[string(nonce)]; ok {
+		return errors.New("nonce already answered")
+	}
+	answered[string(nonce)] = struct{}{}
+	return nil
+}
+
+// mask returns the mask of the server for the nonce. The last server masks
+// its answer with the sum of the masks of the others.
+func (s *SPIR) mask(nonce []byte, length int) []byte {
+	if s.id < s.numServers-1 {
+		return s.expand(nonce, s.id, length)
+	}
+	mask := make([]byte, length)
+	for k := 0; k < s.numServers-1; k++ {
+		fastxor.Bytes(mask, mask, s.expand(nonce, k, length))
+	}
+	return mask
+}
+
+// expand returns the mask of server k for the nonce
+func (s *SPIR) expand(nonce []byte, k, length int) []byte {
+	h := sha256.New()
+	h.Write(s.seed[:])
+	h.Write(nonce)
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(k))
+	h.Write(buf)
+	var key utils.PRGKey
+	copy(key[:], h.Sum(nil))
+
+	mask := make([]byte, length)
+	if _, err := utils.NewPRG(&key).Read(mask); err != nil {
+		panic(err)
+	}
+	return mask
+}
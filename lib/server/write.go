@@ -0,0 +1,230 @@
+package server
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// ErrMalformedWrite is returned when the audit of a write shows that it is
+// not a point function, i.e., that it would overwrite more than one slot
+var ErrMalformedWrite = errors.New("malformed write")
+
+// Writes is a server of the two-server private write scheme in the style of
+// Riposte. The server holds an additive share of a db of slots of BlockSize
+// field elements, to which it adds the expansion of the DPF key of every
+// write. Before that, the two servers audit the write in two rounds: they
+// compress their expansions into shares of three sketches with randomness
+// derived from a shared seed, and check with the Beaver triples of the write
+// that the sketches satisfy the quadratic relation of a point function,
+// without learning its slot. The servers are trusted to follow the audit,
+// which protects the db against malformed writes of the clients only.
+type Writes struct {
+	id     byte
+	dbInfo *database.Info
+	fss    *fss.Fss
+	seed   *utils.PRGKey
+
+	mu     sync.Mutex
+	shares []uint32
+}
+
+// WriteAudit is the state of the audit of a write by a server
+type WriteAudit struct {
+	s      *Writes
+	vector []uint32
+	// shares of the Beaver triples of the products z1 * z1 and z0 * z2 and
+	// of the openings of their factors masked by the triples
+	triples  [2][3]uint32
+	openings [4]uint32
+	sigma    uint32
+	done     bool
+}
+
+// NewWrites returns the server with the given id, 0 or 1, of an empty db
+// with the given info, sharing the seed of the audits with the other server
+func NewWrites(info *database.Info, id byte, seed *utils.PRGKey) (*Writes, error) {
+	if id > 1 {
+		return nil, fmt.Errorf("invalid server %d out of 2", id)
+	}
+	f := fss.ServerInitialize(info.BlockSize)
+	f.SetField(info.Field())
+	return &Writes{
+		id:     id,
+		dbInfo: info,
+		fss:    f,
+		seed:   seed,
+		shares: make([]uint32, info.NumRows*info.NumColumns*info.BlockSize),
+	}, nil
+}
+
+// DBInfo returns database info
+func (s *Writes) DBInfo() *database.Info {
+	return s.dbInfo
+}
+
+// AuditBytes starts the audit of an encoded write. It returns the state of
+// the audit and the first message to send to the other server, i.e., the
+// shares of the openings of the Beaver triples.
+func (s *Writes) AuditBytes(w []byte) (*WriteAudit, []byte, error) {
+	t := monitor.StartPhase(monitor.PhaseDecode)
+	write, err := query.DecodeWrite(w)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.End()
+	return s.Audit(write)
+}
+
+// Audit starts the audit of a write, as AuditBytes
+func (s *Writes) Audit(w *query.Write) (*WriteAudit, []byte, error) {
+	fl := s.fss.Field
+	if len(w.ID) != query.WriteIDLen || len(w.FssKey.FinalCW) != s.dbInfo.BlockSize {
+		return nil, nil, errors.New("malformed write")
+	}
+	for _, triple := range w.Triples {
+		for _, e := range triple {
+			if e >= fl.Modulus() {
+				return nil, nil, errors.New("malformed write")
+			}
+		}
+	}
+
+	f, err := s.fss.ForEpoch(w.FssKey.Epoch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a := &WriteAudit{s: s, triples: w.Triples}
+	a.vector = s.expand(f, w.FssKey)
+	z := s.sketch(w.ID, a.vector)
+	factors := [2][2]uint32{{z[1], z[1]}, {z[0], z[2]}}
+	for t, f := range factors {
+		a.openings[2*t] = fl.Sub(f[0], a.triples[t][0])
+		a.openings[2*t+1] = fl.Sub(f[1], a.triples[t][1])
+	}
+
+	return a, fl.EncodeElements(a.openings[:]), nil
+}
+
+// CheckBytes answers the first message of the other server with the second
+// one, i.e., the share of z1 * z1 - z0 * z2, which is zero for a point
+// function
+func (a *WriteAudit) CheckBytes(peer []byte) ([]byte, error) {
+	fl := a.s.fss.Field
+	openings, err := fl.DecodeElements(peer)
+	if err != nil {
+		return nil, err
+	}
+	if len(openings) != len(a.openings) {
+		return nil, errors.New("malformed audit message")
+	}
+
+	var products [2]uint32
+	for t := range products {
+		d := fl.Add(a.openings[2*t], openings[2*t])
+		e := fl.Add(a.openings[2*t+1], openings[2*t+1])
+		// [xy] = [c] + d [b] + e [a] + de, the last term added by server 0
+		p := fl.Add(a.triples[t][2], fl.Mul(d, a.triples[t][1]))
+		p = fl.Add(p, fl.Mul(e, a.triples[t][0]))
+		if a.s.id == 0 {
+			p = fl.Add(p, fl.Mul(d, e))
+		}
+		products[t] = p
+	}
+	a.sigma = fl.Sub(products[0], products[1])
+
+	return fl.EncodeElements([]uint32{a.sigma}), nil
+}
+
+// Commit ends the audit with the second message of the other server and
+// adds the write to the share of the db if it is a point function.
+// ErrMalformedWrite is returned otherwise.
+func (a *WriteAudit) Commit(peer []byte) error {
+	fl := a.s.fss.Field
+	sigma, err := fl.DecodeElements(peer)
+	if err != nil {
+		return err
+	}
+	if len(sigma) != 1 {
+		return errors.New("malformed audit message")
+	}
+	if a.done {
+		return errors.New("write already committed")
+	}
+	a.done = true
+	if fl.Add(a.sigma, sigma[0]) != 0 {
+		return ErrMalformedWrite
+	}
+
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	fl.AddVectors(a.s.shares, a.s.shares, a.vector)
+	return nil
+}
+
+// Publish returns the encoded share of the db at the end of an epoch and
+// empties the db for the next one
+func (s *Writes) Publish() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.fss.Field.EncodeElements(s.shares)
+	s.shares = make([]uint32, len(s.shares))
+	return out
+}
+
+// expand returns the share of the full domain of the DPF key, evaluated with
+// f, slot by slot
+func (s *Writes) expand(f fss.Fss, key fss.FssKeyEq2P) []uint32 {
+	defer monitor.StartPhase(monitor.PhaseExpand).End()
+	numSlots := s.dbInfo.NumRows * s.dbInfo.NumColumns
+	bl := s.dbInfo.BlockSize
+	numBits := fss.NumBitsForDomain(numSlots)
+	out := make([]uint32, numSlots*bl)
+	for j := 0; j < numSlots; j++ {
+		f.EvaluatePF(s.id, key, fss.IndexToBits(j, numBits), out[j*bl:(j+1)*bl])
+	}
+	return out
+}
+
+// sketch returns the shares of the sketches z0 = sum v_j, z1 = sum r_j v_j
+// and z2 = sum r_j^2 v_j of the expansion, where v_j is the random linear
+// combination of the elements of slot j. The randomness is expanded from the
+// shared seed and the identifier of the write, which the client cannot
+// predict.
+func (s *Writes) sketch(id []byte, vector []uint32) [3]uint32 {
+	fl := s.fss.Field
+	h := sha256.New()
+	h.Write(s.seed[:])
+	h.Write(id)
+	var key utils.PRGKey
+	copy(key[:], h.Sum(nil))
+	prg := utils.NewPRG(&key)
+
+	bl := s.dbInfo.BlockSize
+	coeffs := make([]uint32, bl)
+	for l := range coeffs {
+		coeffs[l] = fl.RandElementWithPRG(prg)
+	}
+
+	var z [3]uint32
+	for j := 0; j < len(vector)/bl; j++ {
+		var vj uint32
+		for l, c := range coeffs {
+			vj = fl.Add(vj, fl.Mul(c, vector[j*bl+l]))
+		}
+		r := fl.RandElementWithPRG(prg)
+		rv := fl.Mul(r, vj)
+		z[0] = fl.Add(z[0], vj)
+		z[1] = fl.Add(z[1], rv)
+		z[2] = fl.Add(z[2], fl.Mul(r, rv))
+	}
+	return z
+}
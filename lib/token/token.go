@@ -0,0 +1,471 @@
+// Package token implements anonymous tokens in the style of Privacy Pass,
+// with which the servers authorize the queries without learning who sends
+// them. A token is a random nonce and the evaluation of the DH oblivious PRF
+// of the issuer on the hash of the nonce to the group. The client blinds the
+// hashes of its nonces, so that the issuer evaluates the PRF without seeing
+// them and cannot link the tokens it issued to the tokens redeemed, and the
+// issuer proves with a batched DLEQ proof that it evaluated the PRF with the
+// key of its public key, so that it cannot tag a client with a distinct key.
+// The redeemer checks the PRF evaluation of a token with the key, and
+// rejects the tokens already spent.
+package token
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/cloudflare/circl/group"
+)
+
+// NonceLen is the length in bytes of the nonce of a token
+const NonceLen = 32
+
+// MaxSpent bounds the number of tokens redeemed with the key of an issuer,
+// after which the key must be replaced, so that the spent nonces fit in
+// memory
+const MaxSpent = 1 << 20
+
+// MetadataKey is the gRPC metadata key of the token redeemed by a query. The
+// suffix lets the value be binary.
+const MetadataKey = "vpir-token-bin"
+
+// Group is the group of the tokens
+var Group = group.P256
+
+var (
+	// ErrInvalidToken is returned when the PRF evaluation of a token is wrong
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrSpentToken is returned when a token is redeemed twice
+	ErrSpentToken = errors.New("token already spent")
+	// ErrTooManySpent is returned when MaxSpent tokens were redeemed with
+	// the key of the issuer
+	ErrTooManySpent = errors.New("too many tokens spent with the key of the issuer")
+)
+
+var (
+	hashDST  = []byte("vpir-token-hash")
+	proofDST = []byte("vpir-token-dleq")
+)
+
+// Issuer issues the tokens with its PRF key
+type Issuer struct {
+	key group.Scalar
+	pub group.Element
+}
+
+// NewIssuer returns an issuer with a fresh key
+func NewIssuer(rnd io.Reader) *Issuer {
+	return newIssuer(Group.RandomScalar(rnd))
+}
+
+// LoadOrCreateIssuer returns the issuer of the hex-encoded key stored in the
+// file, which is created with a fresh key if it does not exist. The file
+// must not be accessible by other users than its owner.
+func LoadOrCreateIssuer(path string) (*Issuer, error) {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		i := NewIssuer(rand.Reader)
+		key, err := i.key.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return i, ioutil.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("the token key file %s is accessible by other users", path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, err
+	}
+	k := Group.NewScalar()
+	if err := k.UnmarshalBinary(key); err != nil {
+		return nil, err
+	}
+	return newIssuer(k), nil
+}
+
+func newIssuer(key group.Scalar) *Issuer {
+	return &Issuer{key: key, pub: Group.NewElement().MulGen(key)}
+}
+
+// PublicKey returns the encoded public key of the issuer
+func (i *Issuer) PublicKey() []byte {
+	pub, err := i.pub.MarshalBinaryCompress()
+	if err != nil {
+		panic(err)
+	}
+	return pub
+}
+
+// Issue evaluates the PRF on the encoded blinded elements of a request and
+// returns the encoded evaluations with the proof that they are computed
+// with the key of the issuer
+func (i *Issuer) Issue(rnd io.Reader, blinded []byte) ([]byte, []byte, error) {
+	in, err := decodeElements(blinded)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make([]group.Element, len(in))
+	for k, b := range in {
+		out[k] = Group.NewElement().Mul(b, i.key)
+	}
+	evaluated, err := encodeElements(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, err := proveDLEQ(rnd, i.key, i.pub, in, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return evaluated, proof, nil
+}
+
+// Request is a request of tokens, whose state the client keeps until the
+// issuer answers
+type Request struct {
+	nonces   [][]byte
+	blinded  []group.Element
+	inverses []group.Scalar
+	encoded  []byte
+}
+
+// NewRequest returns the request of n tokens
+func NewRequest(rnd io.Reader, n int) (*Request, error) {
+	if n <= 0 {
+		return nil, errors.New("no tokens requested")
+	}
+	r := &Request{
+		nonces:   make([][]byte, n),
+		blinded:  make([]group.Element, n),
+		inverses: make([]group.Scalar, n),
+	}
+	for k := range r.nonces {
+		r.nonces[k] = make([]byte, NonceLen)
+		if _, err := io.ReadFull(rnd, r.nonces[k]); err != nil {
+			return nil, err
+		}
+		s := Group.RandomScalar(rnd)
+		r.inverses[k] = Group.NewScalar().Inv(s)
+		r.blinded[k] = Group.NewElement().Mul(hashNonce(r.nonces[k]), s)
+	}
+	var err error
+	r.encoded, err = encodeElements(r.blinded)
+	return r, err
+}
+
+// Bytes returns the encoded blinded elements of the request, sent to the
+// issuer
+func (r *Request) Bytes() []byte {
+	return r.encoded
+}
+
+// Finalize returns the tokens issued by the issuer with the given public key
+// in answer to the request, after checking the proof of the issuer
+func (r *Request) Finalize(publicKey, evaluated, proof []byte) ([][]byte, error) {
+	pub := Group.NewElement()
+	if err := pub.UnmarshalBinary(publicKey); err != nil {
+		return nil, err
+	}
+	out, err := decodeElements(evaluated)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != len(r.blinded) {
+		return nil, errors.New("wrong number of evaluations")
+	}
+	if err := verifyDLEQ(pub, r.blinded, out, proof); err != nil {
+		return nil, err
+	}
+
+	tokens := make([][]byte, len(out))
+	for k, e := range out {
+		w, err := Group.NewElement().Mul(e, r.inverses[k]).MarshalBinaryCompress()
+		if err != nil {
+			return nil, err
+		}
+		tokens[k] = append(append([]byte{}, r.nonces[k]...), w...)
+	}
+	return tokens, nil
+}
+
+// Redeemer checks the tokens of the queries with the key of the issuer and
+// records the spent ones. At most max tokens are redeemed with a key, since
+// the spent nonces are never forgotten, which would let them be spent again.
+type Redeemer struct {
+	issuer *Issuer
+	max    int
+
+	mu    sync.Mutex
+	spent map[string]struct{}
+	log   *os.File // append-only log of the spent nonces, if any
+	err   error    // error of the log, after which no token is accepted
+}
+
+// NewRedeemer returns the redeemer of the tokens of the issuer, which keeps
+// the spent nonces in memory only
+func NewRedeemer(i *Issuer) *Redeemer {
+	return &Redeemer{issuer: i, max: MaxSpent, spent: make(map[string]struct{})}
+}
+
+// OpenRedeemer returns the redeemer of the tokens of the issuer, which
+// appends the spent nonces to the log in the file, so that they are not
+// spent again after a restart. The log starts with the public key of the
+// issuer and is reset if it is the one of another key. The file must not be
+// accessible by other users than its owner.
+func OpenRedeemer(i *Issuer, path string) (*Redeemer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r, err := loadRedeemer(i, f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not load the spent tokens of %s: %v", path, err)
+	}
+	return r, nil
+}
+
+// loadRedeemer reads the spent nonces of the log, and positions it for the
+// next ones
+func loadRedeemer(i *Issuer, f *os.File) (*Redeemer, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return nil, errors.New("the file is accessible by other users")
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewRedeemer(i)
+	r.log = f
+	header := i.PublicKey()
+	if !bytes.HasPrefix(data, header) {
+		// a new log, or the one of a previous key
+		if err := f.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := f.WriteAt(header, 0); err != nil {
+			return nil, err
+		}
+		data = header
+	}
+	n := (len(data) - len(header)) / NonceLen
+	if n > r.max {
+		return nil, ErrTooManySpent
+	}
+	for k := 0; k < n; k++ {
+		off := len(header) + k*NonceLen
+		r.spent[string(data[off:off+NonceLen])] = struct{}{}
+	}
+
+	// a nonce partially written was not accepted
+	end := int64(len(header) + n*NonceLen)
+	if err := f.Truncate(end); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return r, f.Sync()
+}
+
+// Close closes the log of the spent nonces, if any
+func (r *Redeemer) Close() error {
+	if r.log == nil {
+		return nil
+	}
+	return r.log.Close()
+}
+
+// Redeem checks the token and marks it as spent. It returns ErrInvalidToken
+// or ErrSpentToken if the token is rejected, and ErrTooManySpent once the
+// key must be replaced. With a log, the nonce is written before the token is
+// accepted.
+func (r *Redeemer) Redeem(token []byte) error {
+	if len(token) <= NonceLen {
+		return ErrInvalidToken
+	}
+	nonce := token[:NonceLen]
+	w := Group.NewElement()
+	if err := w.UnmarshalBinary(token[NonceLen:]); err != nil {
+		return ErrInvalidToken
+	}
+	if !w.IsEqual(Group.NewElement().Mul(hashNonce(nonce), r.issuer.key)) {
+		return ErrInvalidToken
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.spent[string(nonce)]; ok {
+		return ErrSpentToken
+	}
+	if len(r.spent) >= r.max {
+		return ErrTooManySpent
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if r.log != nil {
+		if _, err := r.log.Write(nonce); err != nil {
+			// the log may end with a part of the nonce, after which the
+			// next ones would be misaligned
+			r.err = fmt.Errorf("could not record the spent token: %v", err)
+			return r.err
+		}
+	}
+	r.spent[string(nonce)] = struct{}{}
+	return nil
+}
+
+func hashNonce(nonce []byte) group.Element {
+	return Group.HashToElement(nonce, hashDST)
+}
+
+// proveDLEQ returns the proof that the outputs are the inputs multiplied by
+// the key of the public key. The pairs are first combined with coefficients
+// hashed from all of them, then the combination is proved with a Schnorr
+// proof made non-interactive with the hash of the transcript.
+func proveDLEQ(rnd io.Reader, key group.Scalar, pub group.Element, in, out []group.Element) ([]byte, error) {
+	m, z, err := combine(pub, in, out)
+	if err != nil {
+		return nil, err
+	}
+	r := Group.RandomScalar(rnd)
+	a1 := Group.NewElement().MulGen(r)
+	a2 := Group.NewElement().Mul(m, r)
+	c, err := challenge(pub, m, z, a1, a2)
+	if err != nil {
+		return nil, err
+	}
+	// s = r - c * key
+	s := Group.NewScalar().Sub(r, Group.NewScalar().Mul(c, key))
+
+	cb, err := c.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sb, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(cb, sb...), nil
+}
+
+// verifyDLEQ checks a proof of proveDLEQ
+func verifyDLEQ(pub group.Element, in, out []group.Element, proof []byte) error {
+	size := scalarSize()
+	if len(proof) != 2*size {
+		return errors.New("malformed proof")
+	}
+	c, s := Group.NewScalar(), Group.NewScalar()
+	if err := c.UnmarshalBinary(proof[:size]); err != nil {
+		return err
+	}
+	if err := s.UnmarshalBinary(proof[size:]); err != nil {
+		return err
+	}
+	m, z, err := combine(pub, in, out)
+	if err != nil {
+		return err
+	}
+	// a1 = s G + c pub, a2 = s m + c z
+	a1 := Group.NewElement().Add(Group.NewElement().MulGen(s), Group.NewElement().Mul(pub, c))
+	a2 := Group.NewElement().Add(Group.NewElement().Mul(m, s), Group.NewElement().Mul(z, c))
+	expected, err := challenge(pub, m, z, a1, a2)
+	if err != nil {
+		return err
+	}
+	if !expected.IsEqual(c) {
+		return errors.New("invalid proof of the issuer")
+	}
+	return nil
+}
+
+// combine returns the combinations of the inputs and of the outputs with
+// the coefficients hashed from the public key and all the pairs
+func combine(pub group.Element, in, out []group.Element) (group.Element, group.Element, error) {
+	transcript, err := encodeElements(append(append([]group.Element{pub}, in...), out...))
+	if err != nil {
+		return nil, nil, err
+	}
+	m, z := Group.Identity(), Group.Identity()
+	index := make([]byte, 4)
+	for k := range in {
+		binary.BigEndian.PutUint32(index, uint32(k))
+		c := Group.HashToScalar(append(append([]byte{}, transcript...), index...), proofDST)
+		m.Add(m, Group.NewElement().Mul(in[k], c))
+		z.Add(z, Group.NewElement().Mul(out[k], c))
+	}
+	return m, z, nil
+}
+
+// challenge returns the challenge of the Schnorr proof
+func challenge(elements ...group.Element) (group.Scalar, error) {
+	transcript, err := encodeElements(elements)
+	if err != nil {
+		return nil, err
+	}
+	return Group.HashToScalar(transcript, proofDST), nil
+}
+
+func encodeElements(elements []group.Element) ([]byte, error) {
+	out := make([]byte, 0, len(elements)*ElementSize())
+	for _, e := range elements {
+		b, err := e.MarshalBinaryCompress()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+// decodeElements returns the elements, none of which is the identity
+func decodeElements(in []byte) ([]group.Element, error) {
+	size := ElementSize()
+	if len(in) == 0 || len(in)%size != 0 {
+		return nil, errors.New("malformed elements")
+	}
+	out := make([]group.Element, len(in)/size)
+	for k := range out {
+		out[k] = Group.NewElement()
+		if err := out[k].UnmarshalBinary(in[k*size : (k+1)*size]); err != nil {
+			return nil, err
+		}
+		if out[k].IsIdentity() {
+			return nil, errors.New("identity element")
+		}
+	}
+	return out, nil
+}
+
+// ElementSize is the length in bytes of an encoded blinded element of a request
+func ElementSize() int {
+	b, _ := Group.Generator().MarshalBinaryCompress()
+	return len(b)
+}
+
+func scalarSize() int {
+	b, _ := Group.NewScalar().MarshalBinary()
+	return len(b)
+}
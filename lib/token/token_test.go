@@ -0,0 +1,141 @@
+package token
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueRedeem(t *testing.T) {
+	issuer := NewIssuer(rand.Reader)
+	r, err := NewRequest(rand.Reader, 5)
+	require.NoError(t, err)
+	evaluated, proof, err := issuer.Issue(rand.Reader, r.Bytes())
+	require.NoError(t, err)
+	tokens, err := r.Finalize(issuer.PublicKey(), evaluated, proof)
+	require.NoError(t, err)
+	require.Len(t, tokens, 5)
+
+	redeemer := NewRedeemer(issuer)
+	for _, tok := range tokens {
+		require.NoError(t, redeemer.Redeem(tok))
+	}
+	require.ErrorIs(t, redeemer.Redeem(tokens[2]), ErrSpentToken)
+
+	// forged tokens and tokens of another issuer are invalid
+	forged := append([]byte{}, tokens[0]...)
+	forged[0] ^= 1
+	require.ErrorIs(t, redeemer.Redeem(forged), ErrInvalidToken)
+	require.ErrorIs(t, redeemer.Redeem(tokens[0][:NonceLen]), ErrInvalidToken)
+	other := NewIssuer(rand.Reader)
+	evaluated, proof, err = other.Issue(rand.Reader, r.Bytes())
+	require.NoError(t, err)
+	tokens, err = r.Finalize(other.PublicKey(), evaluated, proof)
+	require.NoError(t, err)
+	require.ErrorIs(t, redeemer.Redeem(tokens[0]), ErrInvalidToken)
+}
+
+func TestIssuerProof(t *testing.T) {
+	issuer := NewIssuer(rand.Reader)
+	other := NewIssuer(rand.Reader)
+	r, err := NewRequest(rand.Reader, 3)
+	require.NoError(t, err)
+
+	// the evaluations with another key do not match the public key
+	evaluated, proof, err := other.Issue(rand.Reader, r.Bytes())
+	require.NoError(t, err)
+	_, err = r.Finalize(issuer.PublicKey(), evaluated, proof)
+	require.Error(t, err)
+
+	// a single evaluation with another key is detected
+	evaluated, proof, err = issuer.Issue(rand.Reader, r.Bytes())
+	require.NoError(t, err)
+	tagged, _, err := other.Issue(rand.Reader, r.Bytes())
+	require.NoError(t, err)
+	size := ElementSize()
+	copy(evaluated[size:2*size], tagged[size:2*size])
+	_, err = r.Finalize(issuer.PublicKey(), evaluated, proof)
+	require.Error(t, err)
+	_, err = r.Finalize(issuer.PublicKey(), evaluated[size:], proof)
+	require.Error(t, err)
+}
+
+func TestLoadOrCreateIssuer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.key")
+	created, err := LoadOrCreateIssuer(path)
+	require.NoError(t, err)
+	loaded, err := LoadOrCreateIssuer(path)
+	require.NoError(t, err)
+	require.Equal(t, created.PublicKey(), loaded.PublicKey())
+
+	require.NoError(t, os.Chmod(path, 0644))
+	_, err = LoadOrCreateIssuer(path)
+	require.Error(t, err)
+}
+
+func TestRedeemerLog(t *testing.T) {
+	issuer := NewIssuer(rand.Reader)
+	r, err := NewRequest(rand.Reader, 4)
+	require.NoError(t, err)
+	evaluated, proof, err := issuer.Issue(rand.Reader, r.Bytes())
+	require.NoError(t, err)
+	tokens, err := r.Finalize(issuer.PublicKey(), evaluated, proof)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "token.key.spent")
+	redeemer, err := OpenRedeemer(issuer, path)
+	require.NoError(t, err)
+	require.NoError(t, redeemer.Redeem(tokens[0]))
+	require.NoError(t, redeemer.Redeem(tokens[1]))
+	require.NoError(t, redeemer.Close())
+
+	// the tokens spent before a restart are still spent, and a nonce
+	// partially written is dropped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write(tokens[2][:NonceLen/2])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	redeemer, err = OpenRedeemer(issuer, path)
+	require.NoError(t, err)
+	require.ErrorIs(t, redeemer.Redeem(tokens[1]), ErrSpentToken)
+	require.NoError(t, redeemer.Redeem(tokens[2]))
+	require.NoError(t, redeemer.Close())
+	redeemer, err = OpenRedeemer(issuer, path)
+	require.NoError(t, err)
+	for _, tok := range tokens[:3] {
+		require.ErrorIs(t, redeemer.Redeem(tok), ErrSpentToken)
+	}
+	require.NoError(t, redeemer.Close())
+
+	// the log of another key is reset
+	redeemer, err = OpenRedeemer(NewIssuer(rand.Reader), path)
+	require.NoError(t, err)
+	require.Empty(t, redeemer.spent)
+	require.NoError(t, redeemer.Close())
+
+	require.NoError(t, os.Chmod(path, 0644))
+	_, err = OpenRedeemer(issuer, path)
+	require.Error(t, err)
+}
+
+func TestRedeemerMaxSpent(t *testing.T) {
+	issuer := NewIssuer(rand.Reader)
+	r, err := NewRequest(rand.Reader, 3)
+	require.NoError(t, err)
+	evaluated, proof, err := issuer.Issue(rand.Reader, r.Bytes())
+	require.NoError(t, err)
+	tokens, err := r.Finalize(issuer.PublicKey(), evaluated, proof)
+	require.NoError(t, err)
+
+	redeemer := NewRedeemer(issuer)
+	redeemer.max = 2
+	require.NoError(t, redeemer.Redeem(tokens[0]))
+	require.NoError(t, redeemer.Redeem(tokens[1]))
+	require.ErrorIs(t, redeemer.Redeem(tokens[2]), ErrTooManySpent)
+	require.ErrorIs(t, redeemer.Redeem(tokens[0]), ErrSpentToken)
+	require.Len(t, redeemer.spent, 2)
+}
@@ -0,0 +1,129 @@
+package transparency
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// headDomain separates the signatures of the heads from the other signatures
+// of the keys of the operators
+const headDomain = "vpir transparency log head v1\x00"
+
+// TreeHead is the head of the log signed by the operator of a server
+type TreeHead struct {
+	Size      uint64
+	Root      []byte
+	Timestamp int64 // Unix time
+	Signature []byte
+}
+
+// message returns the signed bytes of the head
+func (h *TreeHead) message() []byte {
+	out := make([]byte, 0, len(headDomain)+16+len(h.Root))
+	out = append(out, headDomain...)
+	out = append(out, make([]byte, 16)...)
+	binary.BigEndian.PutUint64(out[len(headDomain):], h.Size)
+	binary.BigEndian.PutUint64(out[len(headDomain)+8:], uint64(h.Timestamp))
+	return append(out, h.Root...)
+}
+
+// SignHead returns the head of the log at time t signed with the key of the
+// operator, either ECDSA or Ed25519
+func SignHead(l *Log, signer crypto.Signer, t time.Time) (*TreeHead, error) {
+	h := &TreeHead{Size: l.Size(), Root: l.Root(), Timestamp: t.Unix()}
+	var err error
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		h.Signature, err = signer.Sign(rand.Reader, h.message(), crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(h.message())
+		h.Signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported operator key %T", signer.Public())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Verify checks the signature of the head with the key of the operator
+func (h *TreeHead) Verify(pub crypto.PublicKey) error {
+	ok := false
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, h.message(), h.Signature)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(h.message())
+		ok = ecdsa.VerifyASN1(k, digest[:], h.Signature)
+	default:
+		return fmt.Errorf("unsupported operator key %T", pub)
+	}
+	if !ok {
+		return errors.New("invalid signature of the log head")
+	}
+	return nil
+}
+
+// messageVersion is the version of the layout of the encoded heads and
+// responses
+const messageVersion = 1
+
+// Encode encodes the head to be sent to the clients, in the fixed layout of
+// utils.Reader: the version, the size, the timestamp, the root and the
+// signature
+func (h *TreeHead) Encode() ([]byte, error) {
+	return h.append(make([]byte, 0, 1+16+8+len(h.Root)+len(h.Signature))), nil
+}
+
+func (h *TreeHead) append(dst []byte) []byte {
+	dst = append(dst, messageVersion)
+	dst = utils.AppendUint64(dst, h.Size)
+	dst = utils.AppendUint64(dst, uint64(h.Timestamp))
+	dst = utils.AppendPrefixed(dst, h.Root)
+	return utils.AppendPrefixed(dst, h.Signature)
+}
+
+// DecodeTreeHead decodes a head encoded with Encode
+func DecodeTreeHead(in []byte) (*TreeHead, error) {
+	r := utils.NewReader(in)
+	h, err := readHead(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Done(); err != nil {
+		return nil, fmt.Errorf("malformed log head: %v", err)
+	}
+	return h, nil
+}
+
+func readHead(r *utils.Reader) (*TreeHead, error) {
+	if v := r.Uint8(); r.Err() == nil && v != messageVersion {
+		return nil, fmt.Errorf("unknown version %d of the log head", v)
+	}
+	h := &TreeHead{
+		Size:      r.Uint64(),
+		Timestamp: int64(r.Uint64()),
+		Root:      copyBytes(r.Prefixed()),
+		Signature: copyBytes(r.Prefixed()),
+	}
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("malformed log head: %v", err)
+	}
+	return h, nil
+}
+
+// copyBytes returns a copy of b, so that the decoded messages do not alias
+// their input
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
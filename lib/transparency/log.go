@@ -0,0 +1,157 @@
+// Package transparency implements an append-only transparency log of the
+// epochs of the db, i.e., of the digests of the successive dbs served by the
+// servers. The heads of the log are signed by the operators of the servers,
+// and the clients check that every new head extends the last one they
+// trusted, so that serving different dbs to different clients, i.e., a
+// split view, is detected.
+package transparency
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Epoch is an entry of the log, i.e., the digest of a db. The epochs carry
+// no time, so that the logs kept by the operators of the servers serving
+// the same dbs are identical.
+type Epoch struct {
+	Number uint64
+	Digest []byte
+}
+
+// Encode returns the leaf data of the epoch in the tree of the log
+func (e *Epoch) Encode() []byte {
+	out := make([]byte, 8, 8+len(e.Digest))
+	binary.BigEndian.PutUint64(out, e.Number)
+	return append(out, e.Digest...)
+}
+
+// DecodeEpoch decodes the leaf data of an epoch
+func DecodeEpoch(in []byte) (*Epoch, error) {
+	if len(in) < 8 {
+		return nil, errors.New("truncated epoch")
+	}
+	return &Epoch{
+		Number: binary.BigEndian.Uint64(in),
+		Digest: append([]byte{}, in[8:]...),
+	}, nil
+}
+
+// Log is the append-only log of the epochs, stored as one JSON epoch per line
+// in a file
+type Log struct {
+	path   string
+	epochs []*Epoch
+	leaves [][]byte
+}
+
+// OpenLog opens the log stored in the file, which is created at the first
+// append if it does not exist
+func OpenLog(path string) (*Log, error) {
+	l := &Log{path: path}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		e := new(Epoch)
+		if err := json.Unmarshal(s.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("epoch %d of the log: %v", len(l.epochs), err)
+		}
+		if e.Number != uint64(len(l.epochs)) {
+			return nil, fmt.Errorf("epoch %d of the log has number %d", len(l.epochs), e.Number)
+		}
+		l.add(e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Log) add(e *Epoch) {
+	l.epochs = append(l.epochs, e)
+	l.leaves = append(l.leaves, leafHash(e.Encode()))
+}
+
+// Append appends a new epoch with the digest of the db, unless the digest is
+// the one of the last epoch. It returns true if an epoch was appended.
+func (l *Log) Append(digest []byte) (bool, error) {
+	if n := len(l.epochs); n > 0 && bytes.Equal(l.epochs[n-1].Digest, digest) {
+		return false, nil
+	}
+	e := &Epoch{Number: uint64(len(l.epochs)), Digest: digest}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+	l.add(e)
+
+	return true, nil
+}
+
+// Size returns the number of epochs of the log
+func (l *Log) Size() uint64 {
+	return uint64(len(l.epochs))
+}
+
+// Root returns the root of the tree of the log
+func (l *Log) Root() []byte {
+	return rootHash(l.leaves)
+}
+
+// Epoch returns the epoch of the given number
+func (l *Log) Epoch(number uint64) (*Epoch, error) {
+	if number >= l.Size() {
+		return nil, fmt.Errorf("no epoch %d in a log of size %d", number, l.Size())
+	}
+	return l.epochs[number], nil
+}
+
+// InclusionProof returns the proof that the epoch of the given number is in
+// the tree of the current log
+func (l *Log) InclusionProof(number uint64) ([][]byte, error) {
+	if number >= l.Size() {
+		return nil, fmt.Errorf("no epoch %d in a log of size %d", number, l.Size())
+	}
+	return inclusionPath(number, l.leaves), nil
+}
+
+// ConsistencyProof returns the proof that the log of the given size is a
+// prefix of the current log
+func (l *Log) ConsistencyProof(size uint64) ([][]byte, error) {
+	if size > l.Size() {
+		return nil, fmt.Errorf("no prefix of size %d in a log of size %d", size, l.Size())
+	}
+	if size == 0 {
+		return [][]byte{}, nil
+	}
+	return consistencyPath(size, l.leaves, true), nil
+}
@@ -0,0 +1,175 @@
+package transparency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// The tree of the log is the Merkle hash tree of RFC 6962, whose proofs
+// are checked with the algorithms of Section 2.1 of RFC 9162
+
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// HashSize is the byte size of the hashes of the tree
+const HashSize = sha256.Size
+
+func leafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// split returns the largest power of two smaller than n, for n > 1
+func split(n uint64) uint64 {
+	k := uint64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// rootHash returns the root of the tree of the given leaf hashes
+func rootHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		return leaves[0]
+	}
+	k := split(uint64(len(leaves)))
+	return nodeHash(rootHash(leaves[:k]), rootHash(leaves[k:]))
+}
+
+// inclusionPath returns the audit path of leaf m in the tree of the leaves
+func inclusionPath(m uint64, leaves [][]byte) [][]byte {
+	n := uint64(len(leaves))
+	if n <= 1 {
+		return [][]byte{}
+	}
+	k := split(n)
+	if m < k {
+		return append(inclusionPath(m, leaves[:k]), rootHash(leaves[k:]))
+	}
+	return append(inclusionPath(m-k, leaves[k:]), rootHash(leaves[:k]))
+}
+
+// consistencyPath returns the proof that the tree of the first m leaves is a
+// prefix of the tree of the leaves
+func consistencyPath(m uint64, leaves [][]byte, complete bool) [][]byte {
+	n := uint64(len(leaves))
+	if m == n {
+		if complete {
+			return [][]byte{}
+		}
+		return [][]byte{rootHash(leaves)}
+	}
+	k := split(n)
+	if m <= k {
+		return append(consistencyPath(m, leaves[:k], complete), rootHash(leaves[k:]))
+	}
+	return append(consistencyPath(m-k, leaves[k:], false), rootHash(leaves[:k]))
+}
+
+// VerifyInclusion checks that the leaf data is at the given index of the
+// tree of the given size and root
+func VerifyInclusion(index, size uint64, data, root []byte, proof [][]byte) error {
+	if index >= size {
+		return errors.New("leaf index beyond the tree size")
+	}
+	fn, sn := index, size-1
+	r := leafHash(data)
+	for _, p := range proof {
+		if sn == 0 {
+			return errors.New("inclusion proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("inclusion proof too short")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.New("inclusion proof does not match the root")
+	}
+	return nil
+}
+
+// VerifyConsistency checks that the tree of size first and root firstRoot is
+// a prefix of the tree of size second and root secondRoot, i.e., that the log
+// only appended leaves in between
+func VerifyConsistency(first, second uint64, firstRoot, secondRoot []byte, proof [][]byte) error {
+	switch {
+	case first > second:
+		return errors.New("the first tree is larger than the second")
+	case first == second:
+		if len(proof) != 0 {
+			return errors.New("non-empty consistency proof for trees of the same size")
+		}
+		if !bytes.Equal(firstRoot, secondRoot) {
+			return errors.New("different roots for trees of the same size")
+		}
+		return nil
+	case first == 0:
+		// the empty tree is a prefix of all the trees
+		return nil
+	case len(proof) == 0:
+		return errors.New("empty consistency proof")
+	}
+
+	if first&(first-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	fn, sn := first-1, second-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return errors.New("consistency proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("consistency proof too short")
+	}
+	if !bytes.Equal(fr, firstRoot) || !bytes.Equal(sr, secondRoot) {
+		return errors.New("consistency proof does not match the roots")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package transparency
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = leafHash([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+	return leaves
+}
+
+func TestProofs(t *testing.T) {
+	for n := 1; n <= 20; n++ {
+		leaves := testLeaves(n)
+		root := rootHash(leaves)
+		for m := 0; m < n; m++ {
+			data := []byte(fmt.Sprintf("leaf %d", m))
+			proof := inclusionPath(uint64(m), leaves)
+			require.NoError(t, VerifyInclusion(uint64(m), uint64(n), data, root, proof), "inclusion %d/%d", m, n)
+			require.Error(t, VerifyInclusion(uint64(m), uint64(n), []byte("other"), root, proof))
+
+			size := uint64(m + 1)
+			firstRoot := rootHash(leaves[:size])
+			proof = consistencyPath(size, leaves, true)
+			require.NoError(t, VerifyConsistency(size, uint64(n), firstRoot, root, proof), "consistency %d/%d", size, n)
+			require.Error(t, VerifyConsistency(size, uint64(n), leaves[0][:1], root, proof))
+			for i := range proof {
+				proof[i] = append([]byte{proof[i][0] ^ 1}, proof[i][1:]...)
+				require.Error(t, VerifyConsistency(size, uint64(n), firstRoot, root, proof), "tampered %d/%d", size, n)
+			}
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	l, err := OpenLog(path)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		appended, err := l.Append([]byte{byte(i)})
+		require.NoError(t, err)
+		require.True(t, appended)
+	}
+	// the same db is not a new epoch
+	appended, err := l.Append([]byte{4})
+	require.NoError(t, err)
+	require.False(t, appended)
+
+	reopened, err := OpenLog(path)
+	require.NoError(t, err)
+	require.Equal(t, l.Size(), reopened.Size())
+	require.Equal(t, l.Root(), reopened.Root())
+
+	e, err := reopened.Epoch(3)
+	require.NoError(t, err)
+	decoded, err := DecodeEpoch(e.Encode())
+	require.NoError(t, err)
+	require.Equal(t, e, decoded)
+}
+
+func TestVerifier(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signers := []crypto.Signer{edKey, ecKey}
+	keys := []crypto.PublicKey{edKey.Public(), ecKey.Public()}
+
+	l, err := OpenLog(filepath.Join(dir, "log"))
+	require.NoError(t, err)
+	// a fork of the log, with the same first epoch
+	fork, err := OpenLog(filepath.Join(dir, "fork"))
+	require.NoError(t, err)
+	for _, log := range []*Log{l, fork} {
+		_, err = log.Append([]byte("db 0"))
+		require.NoError(t, err)
+	}
+
+	responses := func(l *Log, known uint64) []*Response {
+		rs := make([]*Response, len(signers))
+		for i, s := range signers {
+			h, err := SignHead(l, s, time.Now())
+			require.NoError(t, err)
+			r, err := NewResponse(l, h, known)
+			require.NoError(t, err)
+			encoded, err := r.Encode()
+			require.NoError(t, err)
+			rs[i], err = DecodeResponse(encoded)
+			require.NoError(t, err)
+			_, err = DecodeResponse(encoded[:len(encoded)-1])
+			require.Error(t, err)
+			_, err = DecodeResponse(append(encoded, 0))
+			require.Error(t, err)
+		}
+		return rs
+	}
+
+	statePath := filepath.Join(dir, "trusted")
+	v, err := NewVerifier(statePath)
+	require.NoError(t, err)
+	epoch, err := v.Update(responses(l, v.KnownSize()), keys)
+	require.NoError(t, err)
+	require.Equal(t, []byte("db 0"), epoch.Digest)
+
+	for i := 1; i < 4; i++ {
+		_, err = l.Append([]byte(fmt.Sprintf("db %d", i)))
+		require.NoError(t, err)
+	}
+	_, err = fork.Append([]byte("other db"))
+	require.NoError(t, err)
+
+	// the trusted head is kept across the runs of the client
+	v, err = NewVerifier(statePath)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), v.KnownSize())
+	epoch, err = v.Update(responses(l, v.KnownSize()), keys)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), epoch.Number)
+	require.Equal(t, uint64(4), v.KnownSize())
+
+	// a fork not extending the trusted log is a split view
+	_, err = v.Update(responses(fork, v.KnownSize()), keys)
+	require.True(t, errors.Is(err, ErrSplitView), err)
+
+	// servers serving different logs are a split view
+	rs := responses(l, v.KnownSize())
+	rs[1] = responses(fork, 0)[1]
+	_, err = v.Update(rs, keys)
+	require.True(t, errors.Is(err, ErrSplitView), err)
+
+	// forged signatures are rejected
+	rs = responses(l, v.KnownSize())
+	rs[0].Head.Signature[0] ^= 1
+	_, err = v.Update(rs, keys)
+	require.Error(t, err)
+}
+
+// BenchmarkResponseCodec compares the fixed layout of the responses to gob,
+// which was used before, and reports the length of the encoding of a response
+func BenchmarkResponseCodec(b *testing.B) {
+	l, err := OpenLog(filepath.Join(b.TempDir(), "log"))
+	require.NoError(b, err)
+	for i := 0; i < 1000; i++ {
+		_, err = l.Append([]byte(fmt.Sprintf("db %d", i)))
+		require.NoError(b, err)
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(b, err)
+	h, err := SignHead(l, key, time.Now())
+	require.NoError(b, err)
+	r, err := NewResponse(l, h, 500)
+	require.NoError(b, err)
+
+	gobEncode := func() []byte {
+		buf := new(bytes.Buffer)
+		if err := gob.NewEncoder(buf).Encode(r); err != nil {
+			b.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	gobEncoded := gobEncode()
+	encoded, err := r.Encode()
+	require.NoError(b, err)
+
+	b.Run("EncodeGob", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			gobEncode()
+		}
+		b.ReportMetric(float64(len(gobEncoded)), "B/msg")
+	})
+	b.Run("EncodeBinary", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := r.Encode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(len(encoded)), "B/msg")
+	})
+	b.Run("DecodeGob", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var r Response
+			if err := gob.NewDecoder(bytes.NewReader(gobEncoded)).Decode(&r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("DecodeBinary", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := DecodeResponse(encoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package transparency
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// ErrSplitView is returned when the servers, or the same servers over time,
+// do not serve the same log
+var ErrSplitView = errors.New("split view of the transparency log")
+
+// Response is the state of the log sent by a server to a client that already
+// trusts a prefix of the log
+type Response struct {
+	Head *TreeHead
+	// proof that the prefix trusted by the client is a prefix of the log
+	Consistency [][]byte
+	// last epoch of the log, i.e., the one of the db served, and its proof
+	// of inclusion
+	Epoch     []byte
+	Inclusion [][]byte
+}
+
+// NewResponse returns the response to a client trusting the prefix of the
+// given size of the log, whose head is signed by the operator
+func NewResponse(l *Log, head *TreeHead, knownSize uint64) (*Response, error) {
+	if head.Size != l.Size() {
+		return nil, errors.New("the head is not the one of the log")
+	}
+	if head.Size == 0 {
+		return nil, errors.New("empty log")
+	}
+	if knownSize > head.Size {
+		// the client will detect the rollback
+		knownSize = 0
+	}
+	consistency, err := l.ConsistencyProof(knownSize)
+	if err != nil {
+		return nil, err
+	}
+	epoch, err := l.Epoch(head.Size - 1)
+	if err != nil {
+		return nil, err
+	}
+	inclusion, err := l.InclusionProof(head.Size - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Head: head, Consistency: consistency, Epoch: epoch.Encode(), Inclusion: inclusion}, nil
+}
+
+// Encode encodes the response to be sent to a client: the head, followed by
+// the hashes of the consistency proof, the epoch and the hashes of the
+// inclusion proof
+func (r *Response) Encode() ([]byte, error) {
+	if r.Head == nil {
+		return nil, errors.New("no head in the response")
+	}
+	out := r.Head.append(nil)
+	out = appendHashes(out, r.Consistency)
+	out = utils.AppendPrefixed(out, r.Epoch)
+	return appendHashes(out, r.Inclusion), nil
+}
+
+// DecodeResponse decodes a response encoded with Encode
+func DecodeResponse(in []byte) (*Response, error) {
+	rd := utils.NewReader(in)
+	head, err := readHead(rd)
+	if err != nil {
+		return nil, err
+	}
+	r := &Response{Head: head}
+	r.Consistency = readHashes(rd)
+	r.Epoch = copyBytes(rd.Prefixed())
+	r.Inclusion = readHashes(rd)
+	if err := rd.Done(); err != nil {
+		return nil, fmt.Errorf("malformed transparency response: %v", err)
+	}
+	return r, nil
+}
+
+// appendHashes appends the count of the hashes and the hashes, each prefixed
+// by its length
+func appendHashes(dst []byte, hashes [][]byte) []byte {
+	dst = utils.AppendUint32(dst, uint32(len(hashes)))
+	for _, h := range hashes {
+		dst = utils.AppendPrefixed(dst, h)
+	}
+	return dst
+}
+
+func readHashes(r *utils.Reader) [][]byte {
+	// every hash takes at least its length
+	n := r.Count(4)
+	if n == 0 {
+		return nil
+	}
+	hashes := make([][]byte, n)
+	for i := range hashes {
+		hashes[i] = copyBytes(r.Prefixed())
+	}
+	return hashes
+}
+
+// Verifier keeps the last head of the log trusted by a client, stored in a
+// file across the runs of the client
+type Verifier struct {
+	path    string
+	trusted *TreeHead
+}
+
+// NewVerifier returns the verifier of the head stored in the file, which
+// trusts no head if the file does not exist
+func NewVerifier(path string) (*Verifier, error) {
+	v := &Verifier{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.trusted = new(TreeHead)
+	if err := json.Unmarshal(data, v.trusted); err != nil {
+		return nil, fmt.Errorf("could not parse the trusted head: %v", err)
+	}
+	return v, nil
+}
+
+// KnownSize returns the size of the log trusted by the client
+func (v *Verifier) KnownSize() uint64 {
+	if v.trusted == nil {
+		return 0
+	}
+	return v.trusted.Size
+}
+
+// Update checks the responses of all the servers, signed with the keys of
+// their operators in the same order, and trusts their head. The servers must
+// serve the same log, which must extend the one trusted so far. It returns
+// the last epoch of the log, i.e., the one of the db served.
+func (v *Verifier) Update(responses []*Response, keys []crypto.PublicKey) (*Epoch, error) {
+	if len(responses) == 0 || len(responses) != len(keys) {
+		return nil, errors.New("one response per server key is required")
+	}
+
+	head := responses[0].Head
+	for i, r := range responses {
+		if err := r.Head.Verify(keys[i]); err != nil {
+			return nil, fmt.Errorf("server %d: %v", i, err)
+		}
+		if r.Head.Size != head.Size || !bytes.Equal(r.Head.Root, head.Root) {
+			return nil, fmt.Errorf("%w: servers 0 and %d serve different logs", ErrSplitView, i)
+		}
+		if !bytes.Equal(r.Epoch, responses[0].Epoch) {
+			return nil, fmt.Errorf("servers 0 and %d serve different epochs", i)
+		}
+	}
+	if head.Size == 0 {
+		return nil, errors.New("empty log")
+	}
+
+	if v.trusted != nil {
+		for i, r := range responses {
+			if err := VerifyConsistency(v.trusted.Size, head.Size, v.trusted.Root, head.Root, r.Consistency); err != nil {
+				return nil, fmt.Errorf("%w: server %d does not extend the trusted log of size %d: %v",
+					ErrSplitView, i, v.trusted.Size, err)
+			}
+		}
+	}
+
+	epoch, err := DecodeEpoch(responses[0].Epoch)
+	if err != nil {
+		return nil, err
+	}
+	if epoch.Number != head.Size-1 {
+		return nil, fmt.Errorf("epoch %d is not the last one of the log of size %d", epoch.Number, head.Size)
+	}
+	for i, r := range responses {
+		if err := VerifyInclusion(epoch.Number, head.Size, r.Epoch, head.Root, r.Inclusion); err != nil {
+			return nil, fmt.Errorf("server %d: epoch %d: %v", i, epoch.Number, err)
+		}
+	}
+
+	if err := v.trust(head); err != nil {
+		return nil, err
+	}
+
+	return epoch, nil
+}
+
+// trust stores the head as the trusted one
+func (v *Verifier) trust(head *TreeHead) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, v.path); err != nil {
+		return err
+	}
+	v.trusted = head
+	return nil
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// AdminTokenKey is the gRPC metadata key of the token authenticating the
+// calls to the admin service of a server
+const AdminTokenKey = "vpir-admin-token"
+
+// ReadAdminToken reads the admin token of a server from the file, which must
+// not be accessible by other users than its owner
+func ReadAdminToken(path string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("the admin token file %s is accessible by other users", path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	token := bytes.TrimSpace(data)
+	if len(token) == 0 {
+		return nil, errors.New("empty admin token")
+	}
+	return token, nil
+}
+
+// CheckAdminToken returns true if the token is the expected one. The tokens
+// are compared in constant time.
+func CheckAdminToken(expected []byte, token string) bool {
+	e, t := sha256.Sum256(expected), sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(e[:], t[:]) == 1
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The fixed-layout encoding of the protocol messages: the integers are
+// big-endian and the variable-length fields are prefixed by their length, so
+// that the messages carry no type metadata and are decoded without
+// reflection.
+
+// ErrTruncated is returned when a message is shorter than its layout
+var ErrTruncated = errors.New("truncated message")
+
+// AppendUint16 appends the big-endian encoding of v to dst
+func AppendUint16(dst []byte, v uint16) []byte {
+	return append(dst, byte(v>>8), byte(v))
+}
+
+// AppendUint32 appends the big-endian encoding of v to dst
+func AppendUint32(dst []byte, v uint32) []byte {
+	return append(dst, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// AppendUint64 appends the big-endian encoding of v to dst
+func AppendUint64(dst []byte, v uint64) []byte {
+	return AppendUint32(AppendUint32(dst, uint32(v>>32)), uint32(v))
+}
+
+// AppendPrefixed appends b prefixed by its length as a 32-bit integer
+func AppendPrefixed(dst, b []byte) []byte {
+	return append(AppendUint32(dst, uint32(len(b))), b...)
+}
+
+// Reader reads the fields of a message in the fixed layout. The reads after
+// an error return zero values, so that the error is checked once at the end
+// with Err or Done.
+type Reader struct {
+	in  []byte
+	err error
+}
+
+// NewReader returns a reader of the message
+func NewReader(in []byte) *Reader {
+	return &Reader{in: in}
+}
+
+// Bytes returns the next n bytes, without copying them
+func (r *Reader) Bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.in) {
+		r.err = ErrTruncated
+		return nil
+	}
+	b := r.in[:n:n]
+	r.in = r.in[n:]
+	return b
+}
+
+// Prefixed returns the next field prefixed by its length, without copying it
+func (r *Reader) Prefixed() []byte {
+	n := r.Uint32()
+	if uint64(n) > uint64(len(r.in)) {
+		r.fail(ErrTruncated)
+		return nil
+	}
+	return r.Bytes(int(n))
+}
+
+// Uint8 returns the next byte
+func (r *Reader) Uint8() byte {
+	if b := r.Bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// Uint16 returns the next 16-bit integer
+func (r *Reader) Uint16() uint16 {
+	if b := r.Bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+// Uint32 returns the next 32-bit integer
+func (r *Reader) Uint32() uint32 {
+	if b := r.Bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// Uint64 returns the next 64-bit integer
+func (r *Reader) Uint64() uint64 {
+	if b := r.Bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// Count returns the next 32-bit number of elements of size bytes each,
+// checking that the rest of the message holds them so that a forged count
+// does not allocate
+func (r *Reader) Count(size int) int {
+	n := r.Uint32()
+	if r.err == nil && uint64(n)*uint64(size) > uint64(len(r.in)) {
+		r.fail(ErrTruncated)
+		return 0
+	}
+	return int(n)
+}
+
+// Len returns the number of bytes left
+func (r *Reader) Len() int {
+	return len(r.in)
+}
+
+// Err returns the first error of the reads
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Done returns the first error of the reads, or an error if bytes are left
+// after the message
+func (r *Reader) Done() error {
+	if r.err == nil && len(r.in) > 0 {
+		return fmt.Errorf("%d trailing bytes after the message", len(r.in))
+	}
+	return r.err
+}
+
+func (r *Reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
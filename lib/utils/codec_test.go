@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	msg := []byte{7}
+	msg = AppendUint16(msg, 0x0102)
+	msg = AppendUint32(msg, 0x03040506)
+	msg = AppendUint64(msg, 0x0708090a0b0c0d0e)
+	msg = AppendPrefixed(msg, []byte("field"))
+
+	r := NewReader(msg)
+	require.Equal(t, byte(7), r.Uint8())
+	require.Equal(t, uint16(0x0102), r.Uint16())
+	require.Equal(t, uint32(0x03040506), r.Uint32())
+	require.Equal(t, uint64(0x0708090a0b0c0d0e), r.Uint64())
+	require.Equal(t, []byte("field"), r.Prefixed())
+	require.NoError(t, r.Done())
+
+	// every truncation is detected, and the reads after it are zero
+	for n := 0; n < len(msg); n++ {
+		r := NewReader(msg[:n])
+		r.Uint8()
+		r.Uint16()
+		r.Uint32()
+		r.Uint64()
+		r.Prefixed()
+		require.True(t, errors.Is(r.Done(), ErrTruncated), n)
+		require.Equal(t, uint64(0), r.Uint64())
+	}
+
+	r = NewReader(append(msg, 0))
+	r.Bytes(len(msg))
+	require.Error(t, r.Done())
+
+	// a forged count does not allocate the elements
+	r = NewReader(AppendUint32(nil, 1<<31))
+	require.Equal(t, 0, r.Count(8))
+	require.True(t, errors.Is(r.Err(), ErrTruncated))
+}
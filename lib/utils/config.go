@@ -1,43 +1,335 @@
 package utils
 
 import (
-	"fmt"
+	"encoding/hex"
+	"flag"
+	"net"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"golang.org/x/xerrors"
 )
 
+// The config is layered: the values of the file are overridden by the ones
+// of the environment variables, themselves overridden by the ones of the
+// flags, so that a containerized deployment can be configured without
+// mounting a file.
+const (
+	// ServersEnvKey is the environment variable of the comma-separated
+	// addresses host[:port] of the servers, in the order of their index,
+	// replacing the servers of the file
+	ServersEnvKey = "VPIR_SERVERS"
+	// FssSeedEnvKey is the environment variable of Config.FssSeed
+	FssSeedEnvKey = "VPIR_FSS_SEED"
+	// FssEpochEnvKey is the environment variable of Config.FssEpoch
+	FssEpochEnvKey = "VPIR_FSS_EPOCH"
+	// FssRotationEnvKey is the environment variable of Config.FssRotation
+	FssRotationEnvKey = "VPIR_FSS_ROTATION"
+
+	// DefaultPort is the port of the server of index 0 when none is given,
+	// the server of index i listening on DefaultPort+i
+	DefaultPort = 50050
+	// DefaultServerName is the name checked in the certificates of the
+	// servers when none is given, the one of the built-in certificates
+	DefaultServerName = "127.0.0.1"
+)
+
+// The roles of the servers in their replica group
+const (
+	// RolePrimary servers answer the queries of their group, in proportion
+	// to their weight
+	RolePrimary = "primary"
+	// RoleStandby servers answer the queries of their group only when none
+	// of the primary ones does, e.g., the servers not yet upgraded
+	RoleStandby = "standby"
+)
+
 type Config struct {
 	Servers map[string]Server
 
 	Addresses []string
+
+	// hex-encoded seed shared by clients and servers to derive the FSS PRF
+	// keys. If empty, the default keys are used.
+	FssSeed string
+	// epoch of the FSS PRF keys, ignored if FssRotation is set
+	FssEpoch uint64
+	// period after which the FSS PRF keys are rotated, e.g. "24h"
+	FssRotation string
+
+	// indices of the servers of every replica group, set by resolve
+	groups [][]int
 }
 
+// Server is the entry of a server in the config. Only the address is
+// required, so that the servers of a deployment may differ, e.g., during an
+// upgrade in which the replicas of the new version join their group as
+// standby servers and are then promoted.
 type Server struct {
 	Index int
 	IP    string
 	Port  int
+
+	// PEM file of the certificates trusted by the clients to authenticate
+	// the server, e.g., the one of its CA, and the built-in ones if empty
+	CAFile string
+	// name checked in the certificate of the server, DefaultServerName if
+	// empty
+	ServerName string
+	// PEM file of the certificate of the server and reference of its key,
+	// e.g., an encrypted key file (see lib/keys), the
+	// built-in ones if empty, overridden by the settings of the server
+	CertFile string
+	KeyFile  string
+
+	// Group is the replica group of the server, i.e., the share of the
+	// queries of the scheme it answers, and its index if unset. The servers
+	// of a group load the same db.
+	Group *int
+	// Role of the server in its group, RolePrimary if empty
+	Role string
+	// Weight is the share of the queries of its group answered by the
+	// server relative to the other servers of the same role, 1 if zero
+	Weight int
+}
+
+// ConfigFlags are the flags overriding the config, which take precedence over
+// the file and the environment variables when they are set
+type ConfigFlags struct {
+	fs          *flag.FlagSet
+	servers     string
+	fssSeed     string
+	fssEpoch    uint64
+	fssRotation string
+}
+
+// RegisterConfigFlags defines the flags overriding the config in the flag
+// set, to be parsed before loading the config
+func RegisterConfigFlags(fs *flag.FlagSet) *ConfigFlags {
+	f := &ConfigFlags{fs: fs}
+	fs.StringVar(&f.servers, "servers", "", "comma-separated addresses host[:port] of the servers, overriding the config")
+	fs.StringVar(&f.fssSeed, "fss-seed", "", "hex-encoded seed of the FSS PRF keys, overriding the config")
+	fs.Uint64Var(&f.fssEpoch, "fss-epoch", 0, "epoch of the FSS PRF keys, overriding the config")
+	fs.StringVar(&f.fssRotation, "fss-rotation", "", "rotation period of the FSS PRF keys, e.g., 24h, overriding the config")
+	return f
 }
 
+// LoadConfig returns the config of the file, which must exist
 func LoadConfig(configFile string) (*Config, error) {
-	// load config file
 	c := new(Config)
-	_, err := toml.DecodeFile(configFile, c)
-	if err != nil {
+	if _, err := toml.DecodeFile(configFile, c); err != nil {
 		return nil, xerrors.Errorf("toml decoding: %v", err)
 	}
+	if err := c.resolve(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadLayeredConfig returns the config of the file, if it exists, overridden
+// by the environment variables and then by the flags set, if any
+func LoadLayeredConfig(configFile string, flags *ConfigFlags) (*Config, error) {
+	return loadLayeredConfig(configFile, os.LookupEnv, flags)
+}
+
+func loadLayeredConfig(configFile string, lookup func(string) (string, bool), flags *ConfigFlags) (*Config, error) {
+	c := new(Config)
+	if configFile != "" {
+		_, err := toml.DecodeFile(configFile, c)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, xerrors.Errorf("toml decoding: %v", err)
+		}
+	}
+
+	// environment variables
+	if v, ok := lookup(ServersEnvKey); ok {
+		servers, err := parseServers(v)
+		if err != nil {
+			return nil, xerrors.Errorf("%s: %v", ServersEnvKey, err)
+		}
+		c.Servers = servers
+	}
+	if v, ok := lookup(FssSeedEnvKey); ok {
+		c.FssSeed = v
+	}
+	if v, ok := lookup(FssEpochEnvKey); ok {
+		epoch, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, xerrors.Errorf("%s: %v", FssEpochEnvKey, err)
+		}
+		c.FssEpoch = epoch
+	}
+	if v, ok := lookup(FssRotationEnvKey); ok {
+		c.FssRotation = v
+	}
+
+	// flags
+	if flags != nil {
+		var err error
+		flags.fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "servers":
+				var servers map[string]Server
+				if servers, err = parseServers(flags.servers); err == nil {
+					c.Servers = servers
+				}
+			case "fss-seed":
+				c.FssSeed = flags.fssSeed
+			case "fss-epoch":
+				c.FssEpoch = flags.fssEpoch
+			case "fss-rotation":
+				c.FssRotation = flags.fssRotation
+			}
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("-servers: %v", err)
+		}
+	}
+
+	if err := c.resolve(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Server returns the entry of the server of index i
+func (c *Config) Server(i int) Server {
+	s := c.Servers[strconv.Itoa(i)]
+	s.Index = i
+	return s
+}
+
+// Groups returns the indices of the servers of every replica group, in the
+// order of the groups, i.e., of the shares of the queries. Every server is
+// its own group in the configs not loaded from a file.
+func (c *Config) Groups() [][]int {
+	if c.groups != nil {
+		return c.groups
+	}
+	groups := make([][]int, len(c.Addresses))
+	for i := range groups {
+		groups[i] = []int{i}
+	}
+	return groups
+}
+
+// parseServers returns the servers of the comma-separated addresses
+func parseServers(s string) (map[string]Server, error) {
+	servers := make(map[string]Server)
+	for i, addr := range strings.Split(s, ",") {
+		addr = strings.TrimSpace(addr)
+		server := Server{Index: i, IP: addr}
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, xerrors.Errorf("invalid port of %q", addr)
+			}
+			server.IP, server.Port = host, p
+		}
+		servers[strconv.Itoa(i)] = server
+	}
+	return servers, nil
+}
 
-	// parse and store server addresses
+// resolve validates the config, sets the default ports and stores the
+// addresses of the servers
+func (c *Config) resolve() error {
+	if len(c.Servers) == 0 {
+		return xerrors.New("no server in the config")
+	}
 	addresses := make([]string, len(c.Servers))
 	for index, server := range c.Servers {
 		i, err := strconv.Atoi(index)
 		if err != nil {
-			return nil, xerrors.Errorf("could not convert server index to integer: %v", err)
+			return xerrors.Errorf("could not convert server index to integer: %v", err)
+		}
+		if i < 0 || i >= len(c.Servers) || addresses[i] != "" {
+			return xerrors.Errorf("server indices not consecutive from 0: %d", i)
+		}
+		if server.IP == "" {
+			return xerrors.Errorf("no host for server %d", i)
+		}
+		if server.Port == 0 {
+			server.Port = DefaultPort + i
+		}
+		if server.Port < 0 || server.Port > 65535 {
+			return xerrors.Errorf("invalid port of server %d: %d", i, server.Port)
+		}
+		if (server.CertFile == "") != (server.KeyFile == "") {
+			return xerrors.Errorf("certificate file without key file or vice versa for server %d", i)
+		}
+		if server.Group == nil {
+			group := i
+			server.Group = &group
+		}
+		if server.Role == "" {
+			server.Role = RolePrimary
+		}
+		if server.Role != RolePrimary && server.Role != RoleStandby {
+			return xerrors.Errorf("unknown role of server %d: %q", i, server.Role)
+		}
+		if server.Weight < 0 {
+			return xerrors.Errorf("negative weight of server %d: %d", i, server.Weight)
 		}
-		addresses[i] = fmt.Sprintf("%s:%d", server.IP, server.Port)
+		if server.Weight == 0 {
+			server.Weight = 1
+		}
+		server.Index = i
+		c.Servers[index] = server
+		addresses[i] = net.JoinHostPort(server.IP, strconv.Itoa(server.Port))
 	}
 	c.Addresses = addresses
 
-	return c, nil
+	groups, err := c.resolveGroups()
+	if err != nil {
+		return err
+	}
+	c.groups = groups
+
+	if _, err := hex.DecodeString(c.FssSeed); err != nil {
+		return xerrors.Errorf("could not decode FSS seed: %v", err)
+	}
+	if c.FssRotation != "" {
+		period, err := time.ParseDuration(c.FssRotation)
+		if err != nil {
+			return xerrors.Errorf("could not parse FSS rotation period: %v", err)
+		}
+		if period <= 0 {
+			return xerrors.Errorf("non-positive FSS rotation period: %v", period)
+		}
+	}
+
+	return nil
+}
+
+// resolveGroups returns the indices of the servers of every group, which
+// must be consecutive from 0 and have a primary server each
+func (c *Config) resolveGroups() ([][]int, error) {
+	var groups [][]int
+	for i := range c.Addresses {
+		g := *c.Servers[strconv.Itoa(i)].Group
+		if g < 0 || g >= len(c.Addresses) {
+			return nil, xerrors.Errorf("invalid group of server %d: %d", i, g)
+		}
+		for len(groups) <= g {
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	for g, indices := range groups {
+		if len(indices) == 0 {
+			return nil, xerrors.Errorf("groups not consecutive from 0: no server in group %d", g)
+		}
+		primary := false
+		for _, i := range indices {
+			primary = primary || c.Servers[strconv.Itoa(i)].Role == RolePrimary
+		}
+		if !primary {
+			return nil, xerrors.Errorf("no primary server in group %d", g)
+		}
+	}
+	return groups, nil
 }
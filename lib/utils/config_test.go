@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+fssSeed = "00ff"
+fssEpoch = 3
+
+[servers]
+  [servers.0]
+  ip = "10.0.0.1"
+  port = 50050
+
+  [servers.1]
+  ip = "10.0.0.2"
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	c, err := LoadConfig(writeTestConfig(t, testConfig))
+	require.NoError(t, err)
+	// the port of the second server is the default one
+	require.Equal(t, []string{"10.0.0.1:50050", "10.0.0.2:50051"}, c.Addresses)
+	require.Equal(t, "00ff", c.FssSeed)
+	require.Equal(t, uint64(3), c.FssEpoch)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.toml"))
+	require.Error(t, err)
+}
+
+func TestLoadLayeredConfig(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	env := map[string]string{
+		ServersEnvKey:  "server0:6000, server1",
+		FssEpochEnvKey: "5",
+	}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	// the environment overrides the file
+	c, err := loadLayeredConfig(path, lookup, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"server0:6000", "server1:50051"}, c.Addresses)
+	require.Equal(t, "00ff", c.FssSeed)
+	require.Equal(t, uint64(5), c.FssEpoch)
+
+	// the flags set override the environment, the others are ignored
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterConfigFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-servers=[::1]:7000", "-fss-rotation=24h"}))
+	c, err = loadLayeredConfig(path, lookup, flags)
+	require.NoError(t, err)
+	require.Equal(t, []string{"[::1]:7000"}, c.Addresses)
+	require.Equal(t, uint64(5), c.FssEpoch)
+	require.Equal(t, "24h", c.FssRotation)
+
+	// no file at all
+	c, err = loadLayeredConfig(filepath.Join(t.TempDir(), "missing.toml"), lookup, nil)
+	require.NoError(t, err)
+	require.Len(t, c.Addresses, 2)
+	require.Equal(t, "", c.FssSeed)
+}
+
+func TestConfigValidation(t *testing.T) {
+	for name, content := range map[string]string{
+		"no servers": `fssSeed = "00"`,
+		"gap":        "[servers.0]\nip = \"a\"\n[servers.2]\nip = \"b\"",
+		"duplicate":  "[servers.0]\nip = \"a\"\n[servers.00]\nip = \"b\"",
+		"no host":    "[servers.0]\nport = 1",
+		"bad port":   "[servers.0]\nip = \"a\"\nport = 70000",
+		"bad seed":   "fssSeed = \"xyz\"\n[servers.0]\nip = \"a\"",
+		"bad period": "fssRotation = \"-1h\"\n[servers.0]\nip = \"a\"",
+		"bad index":  "[servers.a]\nip = \"a\"",
+		"cert only":  "[servers.0]\nip = \"a\"\ncertFile = \"c.pem\"",
+		"bad role":   "[servers.0]\nip = \"a\"\nrole = \"leader\"",
+		"bad weight": "[servers.0]\nip = \"a\"\nweight = -1",
+		"bad group":  "[servers.0]\nip = \"a\"\ngroup = 1",
+		"group gap":  "[servers.0]\nip = \"a\"\n[servers.1]\nip = \"b\"\ngroup = 2\n[servers.2]\nip = \"c\"\ngroup = 2",
+		"standby":    "[servers.0]\nip = \"a\"\nrole = \"standby\"",
+	} {
+		_, err := LoadConfig(writeTestConfig(t, content))
+		require.Error(t, err, name)
+	}
+
+	lookup := func(k string) (string, bool) {
+		if k == ServersEnvKey {
+			return "a:port", true
+		}
+		return "", false
+	}
+	_, err := loadLayeredConfig("", lookup, nil)
+	require.Error(t, err)
+}
+
+const testHeterogeneousConfig = `
+[servers]
+  [servers.0]
+  ip = "10.0.0.1"
+  caFile = "ca.pem"
+  serverName = "server0.example.org"
+
+  [servers.1]
+  ip = "10.0.0.2"
+
+  [servers.2]
+  ip = "10.0.0.3"
+  group = 1
+  role = "standby"
+
+  [servers.3]
+  ip = "10.0.0.4"
+  group = 0
+  weight = 3
+`
+
+func TestHeterogeneousConfig(t *testing.T) {
+	c, err := LoadConfig(writeTestConfig(t, testHeterogeneousConfig))
+	require.NoError(t, err)
+	require.Len(t, c.Addresses, 4)
+	require.Equal(t, [][]int{{0, 3}, {1, 2}}, c.Groups())
+
+	s := c.Server(0)
+	require.Equal(t, "ca.pem", s.CAFile)
+	require.Equal(t, "server0.example.org", s.ServerName)
+	require.Equal(t, RolePrimary, s.Role)
+	require.Equal(t, 1, s.Weight)
+	require.Equal(t, RoleStandby, c.Server(2).Role)
+	require.Equal(t, 3, c.Server(3).Weight)
+
+	// every server is its own group in the configs not loaded from a file
+	static := &Config{Addresses: []string{"a:1", "b:2"}}
+	require.Equal(t, [][]int{{0}, {1}}, static.Groups())
+}
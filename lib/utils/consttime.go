@@ -0,0 +1,44 @@
+package utils
+
+import "crypto/subtle"
+
+// The helpers below run in a time independent of the values of their
+// arguments, so that the clients do not leak the secret indices of their
+// queries through branches or memory accesses. The conditions are ints
+// equal to 1 or 0, as in crypto/subtle.
+
+// ConstantTimeCompare returns 1 if x == y and 0 otherwise
+func ConstantTimeCompare(x, y int) int {
+	z := uint64(x ^ y)
+	// the top bit of z|-z is set iff z != 0
+	return int(((z | -z) >> 63) ^ 1)
+}
+
+// ConstantTimeSelect returns x if v == 1 and y if v == 0
+func ConstantTimeSelect(v, x, y int) int {
+	return ^(v-1)&x | (v-1)&y
+}
+
+// ConstantTimeSelectByte returns x if v == 1 and y if v == 0
+func ConstantTimeSelectByte(v int, x, y byte) byte {
+	m := byte(-v)
+	return m&x | ^m&y
+}
+
+// ConstantTimeSelectBytes sets out to x if v == 1 and to y if v == 0. The
+// slices must have the same length.
+func ConstantTimeSelectBytes(v int, out, x, y []byte) {
+	if len(out) != len(x) || len(x) != len(y) {
+		panic("utils: slices of different lengths")
+	}
+	copy(out, y)
+	subtle.ConstantTimeCopy(v, out, x)
+}
+
+// ConstantTimeSetByte writes x to the byte at the secret index i of out and
+// 0 to the others, accessing all the bytes
+func ConstantTimeSetByte(out []byte, i int, x byte) {
+	for j := range out {
+		out[j] = ConstantTimeSelectByte(ConstantTimeCompare(i, j), x, 0)
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstantTimeCompare(t *testing.T) {
+	values := []int{0, 1, -1, 7, 1 << 40, math.MaxInt64, math.MinInt64}
+	for _, x := range values {
+		for _, y := range values {
+			expected := 0
+			if x == y {
+				expected = 1
+			}
+			require.Equal(t, expected, ConstantTimeCompare(x, y), "%d %d", x, y)
+		}
+	}
+}
+
+func TestConstantTimeSelect(t *testing.T) {
+	require.Equal(t, 3, ConstantTimeSelect(1, 3, -5))
+	require.Equal(t, -5, ConstantTimeSelect(0, 3, -5))
+	require.Equal(t, byte(0xab), ConstantTimeSelectByte(1, 0xab, 0xcd))
+	require.Equal(t, byte(0xcd), ConstantTimeSelectByte(0, 0xab, 0xcd))
+
+	out := make([]byte, 2)
+	ConstantTimeSelectBytes(1, out, []byte{1, 2}, []byte{3, 4})
+	require.Equal(t, []byte{1, 2}, out)
+	ConstantTimeSelectBytes(0, out, []byte{1, 2}, []byte{3, 4})
+	require.Equal(t, []byte{3, 4}, out)
+	require.Panics(t, func() { ConstantTimeSelectBytes(0, out, []byte{1}, []byte{3, 4}) })
+
+	out = []byte{9, 9, 9, 9}
+	ConstantTimeSetByte(out, 2, 0x10)
+	require.Equal(t, []byte{0, 0, 0x10, 0}, out)
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/cpu"
+)
+
+// CPUDisableEnvKey is the environment variable of the comma-separated CPU
+// features not used by the optimized kernels, e.g., avx2,pclmul, or all, to
+// run and compare the pure-Go fallbacks on any machine
+const CPUDisableEnvKey = "VPIR_CPU_DISABLE"
+
+// CPUFeatures are the features of the CPU for which the packages select
+// optimized kernels at initialization, e.g., the AVX2 vector operations of
+// lib/field. The pure-Go kernels are used when a feature is missing, on the
+// other architectures, e.g., arm64, whose NEON kernels are deferred, and
+// with the build tag purego.
+type CPUFeatures struct {
+	// AES instructions, AES-NI on amd64
+	AES bool
+	// AVX2 vector instructions of amd64
+	AVX2 bool
+	// carry-less multiplication, PCLMULQDQ on amd64 and PMULL on arm64
+	PCLMUL bool
+}
+
+// CPU are the features of the CPU running the process, without the ones
+// disabled by CPUDisableEnvKey
+var CPU = detectCPU(os.Getenv(CPUDisableEnvKey))
+
+func detectCPU(disabled string) CPUFeatures {
+	f := CPUFeatures{
+		AES:    cpu.X86.HasAES || cpu.ARM64.HasAES,
+		AVX2:   cpu.X86.HasAVX2,
+		PCLMUL: cpu.X86.HasPCLMULQDQ || cpu.ARM64.HasPMULL,
+	}
+	for _, name := range strings.Split(disabled, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "all":
+			f = CPUFeatures{}
+		case "aes":
+			f.AES = false
+		case "avx2":
+			f.AVX2 = false
+		case "pclmul":
+			f.PCLMUL = false
+		}
+	}
+	return f
+}
+
+// String returns the names of the features, e.g., "aes avx2", or "none"
+func (f CPUFeatures) String() string {
+	var names []string
+	for _, feature := range []struct {
+		name string
+		has  bool
+	}{{"aes", f.AES}, {"avx2", f.AVX2}, {"pclmul", f.PCLMUL}} {
+		if feature.has {
+			names = append(names, feature.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, " ")
+}
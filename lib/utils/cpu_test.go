@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCPU(t *testing.T) {
+	all := detectCPU("")
+	require.Equal(t, CPUFeatures{}, detectCPU("all"))
+	require.Equal(t, "none", CPUFeatures{}.String())
+
+	f := detectCPU("AVX2, pclmul")
+	require.False(t, f.AVX2)
+	require.False(t, f.PCLMUL)
+	require.Equal(t, all.AES, f.AES)
+
+	require.Equal(t, "aes pclmul", CPUFeatures{AES: true, PCLMUL: true}.String())
+}
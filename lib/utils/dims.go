@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// ErrDimensions is returned when the dimensions of a db are invalid, e.g.,
+// negative or overflowing, instead of silently truncating them
+var ErrDimensions = errors.New("invalid db dimensions")
+
+// CheckedMul returns the product of the non-negative factors, or an error
+// if one is negative or if the product overflows an int
+func CheckedMul(factors ...int) (int, error) {
+	p := 1
+	for _, f := range factors {
+		if f < 0 {
+			return 0, fmt.Errorf("%w: negative factor %d", ErrDimensions, f)
+		}
+		hi, lo := bits.Mul64(uint64(p), uint64(f))
+		if hi != 0 || lo > uint64(maxInt) {
+			return 0, fmt.Errorf("%w: product of %v overflows", ErrDimensions, factors)
+		}
+		p = int(lo)
+	}
+	return p, nil
+}
+
+// CheckedAdd returns the sum of the non-negative terms, or an error if one
+// is negative or if the sum overflows an int
+func CheckedAdd(terms ...int) (int, error) {
+	s := 0
+	for _, t := range terms {
+		if t < 0 {
+			return 0, fmt.Errorf("%w: negative term %d", ErrDimensions, t)
+		}
+		if s > maxInt-t {
+			return 0, fmt.Errorf("%w: sum of %v overflows", ErrDimensions, terms)
+		}
+		s += t
+	}
+	return s, nil
+}
+
+// CeilDiv returns a/b rounded up, for a non-negative and b positive
+func CeilDiv(a, b int) (int, error) {
+	if a < 0 || b <= 0 {
+		return 0, fmt.Errorf("%w: %d / %d", ErrDimensions, a, b)
+	}
+	q := a / b
+	if a%b != 0 {
+		q++
+	}
+	return q, nil
+}
+
+// ExactDiv returns a/b, or an error if b does not divide a, for a
+// non-negative and b positive
+func ExactDiv(a, b int) (int, error) {
+	if a < 0 || b <= 0 {
+		return 0, fmt.Errorf("%w: %d / %d", ErrDimensions, a, b)
+	}
+	if a%b != 0 {
+		return 0, fmt.Errorf("%w: %d is not a multiple of %d", ErrDimensions, a, b)
+	}
+	return a / b, nil
+}
+
+// ISqrt returns the integer square root of n, rounded down
+func ISqrt(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	r := int(math.Sqrt(float64(n)))
+	// correct the rounding of the float square root of the large n
+	for r > 0 && r > n/r {
+		r--
+	}
+	for r+1 <= n/(r+1) {
+		r++
+	}
+	return r
+}
+
+// NumBlocks returns the number of blocks of blockLen bytes holding dbLen
+// bits, the last one possibly partial, and at least one
+func NumBlocks(dbLen, blockLen int) (int, error) {
+	blockBits, err := CheckedMul(8, blockLen)
+	if err != nil {
+		return 0, err
+	}
+	if dbLen < 0 || blockLen <= 0 {
+		return 0, fmt.Errorf("%w: %d bits in blocks of %d bytes", ErrDimensions, dbLen, blockLen)
+	}
+	n, err := CeilDiv(dbLen, blockBits)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		n = 1
+	}
+	return n, nil
+}
+
+// NumColumns returns the number of columns of the matrix of numRows rows
+// holding numBlocks blocks, the last column possibly partial, and checks that
+// the size in bytes of the matrix of blocks of blockLen bytes fits an int
+func NumColumns(numBlocks, numRows, blockLen int) (int, error) {
+	numColumns, err := CeilDiv(numBlocks, numRows)
+	if err != nil {
+		return 0, err
+	}
+	if numColumns == 0 {
+		numColumns = 1
+	}
+	if _, err := CheckedMul(numRows, numColumns, blockLen); err != nil {
+		return 0, err
+	}
+	return numColumns, nil
+}
+
+// SquareRows returns the number of rows of the square matrix holding
+// numBlocks blocks, the square root of the next perfect square
+func SquareRows(numBlocks int) (int, error) {
+	if numBlocks < 0 {
+		return 0, fmt.Errorf("%w: %d blocks", ErrDimensions, numBlocks)
+	}
+	r := ISqrt(numBlocks)
+	if r*r < numBlocks {
+		r++
+	}
+	if _, err := CheckedMul(r, r); err != nil {
+		return 0, err
+	}
+	return r, nil
+}
+
+const maxInt = int(^uint(0) >> 1)
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckedArithmetic(t *testing.T) {
+	p, err := CheckedMul(8, 16, 1<<20)
+	require.NoError(t, err)
+	require.Equal(t, 1<<27, p)
+	_, err = CheckedMul(1<<32, 1<<32)
+	require.True(t, errors.Is(err, ErrDimensions))
+	_, err = CheckedMul(2, -1)
+	require.True(t, errors.Is(err, ErrDimensions))
+
+	s, err := CheckedAdd(1, 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, 6, s)
+	_, err = CheckedAdd(math.MaxInt64, 1)
+	require.True(t, errors.Is(err, ErrDimensions))
+
+	q, err := CeilDiv(10, 3)
+	require.NoError(t, err)
+	require.Equal(t, 4, q)
+	q, err = CeilDiv(9, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, q)
+	_, err = CeilDiv(1, 0)
+	require.True(t, errors.Is(err, ErrDimensions))
+
+	q, err = ExactDiv(9, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, q)
+	_, err = ExactDiv(10, 3)
+	require.True(t, errors.Is(err, ErrDimensions))
+}
+
+func TestISqrt(t *testing.T) {
+	for n := 0; n < 1000; n++ {
+		r := ISqrt(n)
+		require.True(t, r*r <= n && (r+1)*(r+1) > n, "%d", n)
+	}
+	// beyond the precision of the float square root
+	r := 3037000499
+	require.Equal(t, r, ISqrt(r*r))
+	require.Equal(t, r-1, ISqrt(r*r-1))
+}
+
+func TestDimensions(t *testing.T) {
+	// the last partial block is kept
+	n, err := NumBlocks(8*160*78+8, 160)
+	require.NoError(t, err)
+	require.Equal(t, 79, n)
+	n, err = NumBlocks(0, 160)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = NumBlocks(1024, 0)
+	require.True(t, errors.Is(err, ErrDimensions))
+
+	// the last column is partial
+	c, err := NumColumns(50, 8, 16)
+	require.NoError(t, err)
+	require.Equal(t, 7, c)
+	_, err = NumColumns(1<<40, 1, 1<<40)
+	require.True(t, errors.Is(err, ErrDimensions))
+
+	for blocks, rows := range map[int]int{1: 1, 4: 2, 50: 8, 64: 8, 65: 9} {
+		r, err := SquareRows(blocks)
+		require.NoError(t, err)
+		require.Equal(t, rows, r, "%d", blocks)
+		next := blocks
+		IncreaseToNextSquare(&next)
+		require.Equal(t, rows*rows, next)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/binary"
+	"errors"
 	"math/bits"
 )
 
@@ -24,18 +25,72 @@ func ByteSliceToUint32Slice(in []byte) []uint32 {
 	return out
 }
 
+func Uint64SliceToByteSlice(in []uint64) []byte {
+	nb := 8
+	out := make([]byte, len(in)*nb)
+	for i := range in {
+		binary.BigEndian.PutUint64(out[i*nb:(i+1)*nb], in[i])
+	}
+
+	return out
+}
+
+func ByteSliceToUint64Slice(in []byte) []uint64 {
+	nb := 8
+	out := make([]uint64, len(in)/nb)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint64(in[i*nb:])
+	}
+	return out
+}
+
 func ByteToBits(data []byte) []bool {
-	out := make([]bool, len(data)*8)
-	for i, d := range data {
+	return AppendBits(make([]bool, 0, len(data)*8), data)
+}
+
+// AppendBits appends the bits of data to dst, most significant bit first, so
+// that the inputs of the FSS keys are computed in a reused slice
+func AppendBits(dst []bool, data []byte) []bool {
+	for _, d := range data {
 		for j := 0; j < 8; j++ {
-			if bits.LeadingZeros8(d) == 0 {
-				// No leading 0 means that it is a 1
-				out[i*8+j] = true
-			} else {
-				out[i*8+j] = false
-			}
+			// No leading 0 means that it is a 1
+			dst = append(dst, bits.LeadingZeros8(d) == 0)
 			d = d << 1
 		}
 	}
+	return dst
+}
+
+// JoinMessages returns the concatenation of the length-prefixed messages
+func JoinMessages(messages [][]byte) []byte {
+	n := 0
+	for _, m := range messages {
+		n += 4 + len(m)
+	}
+	out := make([]byte, 0, n)
+	buf := make([]byte, 4)
+	for _, m := range messages {
+		binary.BigEndian.PutUint32(buf, uint32(len(m)))
+		out = append(append(out, buf...), m...)
+	}
 	return out
 }
+
+// SplitMessages returns the given number of messages joined by JoinMessages
+func SplitMessages(in []byte, numMessages int) ([][]byte, error) {
+	out := make([][]byte, numMessages)
+	for b := range out {
+		if len(in) < 4 {
+			return nil, errors.New("malformed joined messages")
+		}
+		n := int(binary.BigEndian.Uint32(in))
+		if len(in)-4 < n {
+			return nil, errors.New("malformed joined messages")
+		}
+		out[b], in = in[4:4+n], in[4+n:]
+	}
+	if len(in) != 0 {
+		return nil, errors.New("malformed joined messages")
+	}
+	return out, nil
+}
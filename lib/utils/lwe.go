@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/aes"
+	"fmt"
 	"math"
 )
 
@@ -21,14 +22,80 @@ type ParamsLWE struct {
 	BytesMod int     // bytes of the modulo
 }
 
-func ParamsDefault() *ParamsLWE {
+// DefaultSecurity is the security level in bits of the default parameters
+const DefaultSecurity = 128
+
+// lweEstimate is a parameter set of LWE along with its security level, as
+// estimated by the lattice estimator of Albrecht et al., for at most
+// MaxSamples samples
+type lweEstimate struct {
+	BytesMod   int
+	N          int
+	Sigma      float64
+	Bits       int
+	MaxSamples int
+}
+
+// lweEstimates are the parameter sets of the schemes, by increasing modulus
+// and then dimension. The clients of the schemes give one sample per row of
+// the db.
+var lweEstimates = []lweEstimate{
+	{BytesMod: 4, N: 1100, Sigma: 6.4, Bits: 128, MaxSamples: 1 << 20},
+	{BytesMod: 16, N: 4800, Sigma: 6.4, Bits: 128, MaxSamples: 1 << 20},
+}
+
+// ParamsForSecurity returns the parameters of the smallest modulus and
+// dimension of the estimates reaching the security level for a db of the
+// given size, and whose bound B leaves the answers decodable. It returns an
+// error if none of them does, i.e., if the dimensions are insecure.
+func ParamsForSecurity(bits, rows, columns int) (*ParamsLWE, error) {
+	if bits <= 0 || rows <= 0 || columns <= 0 {
+		return nil, fmt.Errorf("invalid security level %d or db dimensions %dx%d", bits, rows, columns)
+	}
+	for _, e := range lweEstimates {
+		if e.Bits < bits || rows > e.MaxSamples {
+			continue
+		}
+		// the answers around 0 and around the message are told apart only
+		// if B is below a fourth of the modulus
+		b := uint64(rows) * 12 * uint64(math.Ceil(e.Sigma))
+		if b > math.MaxUint32 || (e.BytesMod < 8 && b >= 1<<(8*e.BytesMod-2)) {
+			continue
+		}
+		p := paramsOf(e)
+		p.L = rows
+		p.M = columns
+		p.B = uint32(b)
+		return p, nil
+	}
+	return nil, fmt.Errorf("no LWE parameters of %d bits of security for %d rows", bits, rows)
+}
+
+// paramsOf returns the parameters of the estimate, without the dimensions of
+// the db
+func paramsOf(e lweEstimate) *ParamsLWE {
 	return &ParamsLWE{
 		P:        2,
-		N:        1100,
-		Sigma:    6.4,
+		N:        e.N,
+		Sigma:    e.Sigma,
 		SeedA:    GetDefaultSeedMatrixA(),
-		BytesMod: 4,
+		BytesMod: e.BytesMod,
+	}
+}
+
+// defaultParams returns the parameters of the estimate of the default
+// security level with the given modulus
+func defaultParams(bytesMod int) *ParamsLWE {
+	for _, e := range lweEstimates {
+		if e.BytesMod == bytesMod && e.Bits >= DefaultSecurity {
+			return paramsOf(e)
+		}
 	}
+	panic(fmt.Sprintf("no LWE estimate for a modulus of %d bytes", bytesMod))
+}
+
+func ParamsDefault() *ParamsLWE {
+	return defaultParams(4)
 }
 
 func ParamsWithDatabaseSize(rows, columns int) *ParamsLWE {
@@ -40,17 +107,26 @@ func ParamsWithDatabaseSize(rows, columns int) *ParamsLWE {
 	return p
 }
 
+// ParamsSimplePIR returns the parameters of the single-server SimplePIR
+// scheme for a db of bytes of the given size. The plaintext modulus is 2^8,
+// so that every entry of the db stores a byte, and the answers are decoded by
+// rounding instead of being checked against the bound B.
+func ParamsSimplePIR(rows, columns int) *ParamsLWE {
+	p := ParamsDefault()
+	p.P = 1 << 8
+	p.L = rows
+	p.M = columns
+
+	return p
+}
+
 func GetDefaultSeedMatrixA() *PRGKey {
 	key := PRGKey(SeedMatrixA)
 	return &key
 }
 
 func ParamsDefault128() *ParamsLWE {
-	p := ParamsDefault()
-	p.N = 4800
-	p.BytesMod = 16
-
-	return p
+	return defaultParams(16)
 }
 
 func ParamsWithDatabaseSize128(rows, columns int) *ParamsLWE {
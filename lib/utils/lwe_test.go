@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamsForSecurity(t *testing.T) {
+	// the defaults are the ones of the estimates
+	require.Equal(t, 1100, ParamsDefault().N)
+	require.Equal(t, 4, ParamsDefault().BytesMod)
+	require.Equal(t, 4800, ParamsDefault128().N)
+	require.Equal(t, 16, ParamsDefault128().BytesMod)
+
+	p, err := ParamsForSecurity(DefaultSecurity, 1024, 2048)
+	require.NoError(t, err)
+	require.Equal(t, ParamsWithDatabaseSize(1024, 2048), p)
+
+	p, err = ParamsForSecurity(80, 1024, 2048)
+	require.NoError(t, err)
+	require.Equal(t, 4, p.BytesMod)
+
+	// beyond the estimates
+	_, err = ParamsForSecurity(256, 1024, 2048)
+	require.Error(t, err)
+	_, err = ParamsForSecurity(DefaultSecurity, 1<<21, 2048)
+	require.Error(t, err)
+	_, err = ParamsForSecurity(DefaultSecurity, 0, 2048)
+	require.Error(t, err)
+}
+
+func TestParamsForSecurityModulus(t *testing.T) {
+	defer func(old []lweEstimate) { lweEstimates = old }(lweEstimates)
+	lweEstimates = []lweEstimate{
+		{BytesMod: 4, N: 1100, Sigma: 6.4, Bits: 128, MaxSamples: 1 << 30},
+		{BytesMod: 16, N: 4800, Sigma: 6.4, Bits: 128, MaxSamples: 1 << 30},
+	}
+
+	// B no longer fits a fourth of the 32-bit modulus
+	p, err := ParamsForSecurity(DefaultSecurity, 1<<24, 1)
+	require.NoError(t, err)
+	require.Equal(t, 16, p.BytesMod)
+	require.Equal(t, ParamsWithDatabaseSize128(1<<24, 1), p)
+}
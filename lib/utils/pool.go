@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Buffer is a vector of bytes taken from a pool, so that the large temporary
+// vectors of the queries and of the evaluations of the keys are reused
+// instead of being collected. A buffer that is not released is collected as
+// any other vector.
+type Buffer struct {
+	B     []byte
+	class int
+}
+
+// the pools of the buffers of capacity 1<<class, for every class
+var bufferPools [bits.UintSize]sync.Pool
+
+// GetBuffer returns a buffer of n zero bytes, which is put back to the pool
+// by Release
+func GetBuffer(n int) *Buffer {
+	class := 0
+	if n > 1 {
+		class = bits.Len(uint(n - 1))
+	}
+	b, ok := bufferPools[class].Get().(*Buffer)
+	if !ok {
+		return &Buffer{B: make([]byte, n, 1<<class), class: class}
+	}
+	b.B = b.B[:n]
+	for i := range b.B {
+		b.B[i] = 0
+	}
+	return b
+}
+
+// Release puts the buffer back to the pool. Neither the buffer nor the slices
+// of its bytes must be used afterwards.
+func (b *Buffer) Release() {
+	bufferPools[b.class].Put(b)
+}
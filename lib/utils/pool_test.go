@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuffer(t *testing.T) {
+	b := GetBuffer(100)
+	require.Len(t, b.B, 100)
+	require.Equal(t, 128, cap(b.B))
+	for i := range b.B {
+		b.B[i] = 0xff
+	}
+	b.Release()
+
+	// the reused buffers are zeroed
+	for i := 0; i < 10; i++ {
+		b = GetBuffer(65 + i)
+		require.Equal(t, make([]byte, 65+i), b.B)
+		b.B[0] = 1
+		b.Release()
+	}
+
+	require.Len(t, GetBuffer(0).B, 0)
+	require.Len(t, GetBuffer(1).B, 1)
+}
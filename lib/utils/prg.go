@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// PRG is a pseudorandom generator whose output is determined by its key, so
+// that the same key always expands to the same stream
+type PRG interface {
+	io.Reader
+	// Seed returns the key of the generator
+	Seed() PRGKey
+}
+
+// PRGKind is a construction of the PRGs
+type PRGKind uint8
+
+const (
+	// AESCTR is AES-128 in counter mode, the fastest with the AES
+	// instructions
+	AESCTR PRGKind = iota
+	// ChaCha20 is the ChaCha20 stream cipher, the fastest without them
+	ChaCha20
+)
+
+// PRGKindEnvKey is the environment variable of the kind of the PRGs returned
+// by RandomPRG, aes or chacha20
+const PRGKindEnvKey = "VPIR_PRG"
+
+var chachaDST = []byte("vpir-chacha20-prg")
+
+func (k PRGKind) String() string {
+	switch k {
+	case AESCTR:
+		return "aes"
+	case ChaCha20:
+		return "chacha20"
+	default:
+		return fmt.Sprintf("PRGKind(%d)", k)
+	}
+}
+
+// ParsePRGKind returns the kind of the given name, aes or chacha20
+func ParsePRGKind(s string) (PRGKind, error) {
+	switch s {
+	case "aes":
+		return AESCTR, nil
+	case "chacha20":
+		return ChaCha20, nil
+	default:
+		return 0, fmt.Errorf("unknown PRG %q", s)
+	}
+}
+
+// NewPRGOfKind returns the PRG of the kind with the given key. The PRGs
+// expanding a key shared with other parties, e.g., the seeds of the LWE
+// matrices, must use NewPRG instead, whose construction is the same on all
+// the platforms.
+func NewPRGOfKind(kind PRGKind, key *PRGKey) PRG {
+	switch kind {
+	case AESCTR:
+		return NewPRG(key)
+	case ChaCha20:
+		return NewChaChaPRG(key)
+	default:
+		panic("unknown PRG kind")
+	}
+}
+
+// ChaChaPRG is a PRG expanding its key with ChaCha20, keyed with the hash
+// of the key
+type ChaChaPRG struct {
+	key    PRGKey
+	stream *chacha20.Cipher
+}
+
+// NewChaChaPRG returns the ChaCha20 PRG of the key
+func NewChaChaPRG(key *PRGKey) *ChaChaPRG {
+	h := sha256.New()
+	h.Write(chachaDST)
+	h.Write(key[:])
+	var nonce [chacha20.NonceSize]byte
+	stream, err := chacha20.NewUnauthenticatedCipher(h.Sum(nil), nonce[:])
+	if err != nil {
+		panic(err)
+	}
+	return &ChaChaPRG{key: *key, stream: stream}
+}
+
+func (c *ChaChaPRG) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	c.stream.XORKeyStream(p, p)
+	return len(p), nil
+}
+
+// Seed returns the key of the PRG
+func (c *ChaChaPRG) Seed() PRGKey {
+	return c.key
+}
+
+// Seed returns the key of the PRG
+func (s *PRGReader) Seed() PRGKey {
+	return s.Key
+}
+
+// prgConfig is the kind and the seed, if any, of the PRGs returned by
+// RandomPRG
+var prgConfig struct {
+	sync.Mutex
+	kind    PRGKind
+	seeded  bool
+	seed    int64
+	counter uint64
+}
+
+// SetPRGKind sets the kind of the PRGs returned by RandomPRG
+func SetPRGKind(kind PRGKind) {
+	prgConfig.Lock()
+	defer prgConfig.Unlock()
+	prgConfig.kind = kind
+}
+
+// SeedPRGs derives the keys of the PRGs returned by RandomPRG from the seed
+// and the number of PRGs returned before, instead of sampling them, so that
+// the dbs and the randomness of the clients can be regenerated from the
+// seed. The sequence is reproducible only if the PRGs are requested in the
+// same order. The buffered PRG of MathRand and RandInt, e.g., of the LWE
+// errors, is reseeded too. It makes all the randomness of the process
+// predictable, and is only called by the tests and the simulations.
+func SeedPRGs(seed int64) {
+	prgConfig.Lock()
+	prgConfig.seeded = true
+	prgConfig.seed = seed
+	prgConfig.counter = 0
+	kind := prgConfig.kind
+	prgConfig.Unlock()
+
+	// the key of the buffered PRG has its own index, so that the keys of the
+	// PRGs returned by RandomPRG are unchanged
+	prg := NewPRGOfKind(kind, DerivePRGKey(seed, bufPRGIndex))
+	prgMutex.Lock()
+	bufPrgReader = NewBufPRG(prg)
+	prgMutex.Unlock()
+}
+
+// bufPRGIndex is the index of the key of the buffered PRG derived from the
+// seed of SeedPRGs
+const bufPRGIndex = ^uint64(0)
+
+// DerivePRGKey returns the key of index derived from the seed
+func DerivePRGKey(seed int64, index uint64) *PRGKey {
+	var in [16]byte
+	binary.LittleEndian.PutUint64(in[:8], uint64(seed))
+	binary.LittleEndian.PutUint64(in[8:], index)
+	h := sha256.Sum256(in[:])
+
+	var key PRGKey
+	copy(key[:], h[:])
+	return &key
+}
+
+// RandomPRG returns a PRG of the configured kind, AES-CTR by default, with a
+// random key or one derived from the seed of SeedPRGs
+func RandomPRG() PRG {
+	prgConfig.Lock()
+	kind := prgConfig.kind
+	var key *PRGKey
+	if prgConfig.seeded {
+		key = DerivePRGKey(prgConfig.seed, prgConfig.counter)
+		prgConfig.counter++
+	}
+	prgConfig.Unlock()
+
+	if key == nil {
+		key = RandomPRGKey()
+	}
+	return NewPRGOfKind(kind, key)
+}
+
+// RandomPRGKey returns a key sampled from the system randomness
+func RandomPRGKey() *PRGKey {
+	var key PRGKey
+	_, err := io.ReadFull(rand.Reader, key[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return &key
+}
+
+// configurePRGs applies the environment variables of the PRGs
+func configurePRGs() {
+	if v := os.Getenv(PRGKindEnvKey); v != "" {
+		kind, err := ParsePRGKind(v)
+		if err != nil {
+			log.Fatalf("%s: %v", PRGKindEnvKey, err)
+		}
+		SetPRGKind(kind)
+	}
+}
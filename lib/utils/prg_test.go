@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPRGKinds(t *testing.T) {
+	key := RandomPRGKey()
+	for _, kind := range []PRGKind{AESCTR, ChaCha20} {
+		a, b := NewPRGOfKind(kind, key), NewPRGOfKind(kind, key)
+		require.Equal(t, *key, a.Seed())
+
+		// the same key expands to the same stream
+		outA := make([]byte, 100)
+		outB := make([]byte, 100)
+		_, err := io.ReadFull(a, outA)
+		require.NoError(t, err)
+		_, err = io.ReadFull(b, outB)
+		require.NoError(t, err)
+		require.Equal(t, outA, outB)
+		require.NotEqual(t, make([]byte, 100), outA)
+
+		parsed, err := ParsePRGKind(kind.String())
+		require.NoError(t, err)
+		require.Equal(t, kind, parsed)
+	}
+
+	aes := make([]byte, 32)
+	chacha := make([]byte, 32)
+	NewPRGOfKind(AESCTR, key).Read(aes)
+	NewPRGOfKind(ChaCha20, key).Read(chacha)
+	require.NotEqual(t, aes, chacha)
+
+	_, err := ParsePRGKind("rc4")
+	require.Error(t, err)
+}
+
+func TestSeedPRGs(t *testing.T) {
+	defer func() {
+		prgConfig.Lock()
+		prgConfig.kind, prgConfig.seeded = AESCTR, false
+		prgConfig.Unlock()
+	}()
+
+	read := func() [][]byte {
+		out := make([][]byte, 3)
+		for i := range out {
+			out[i] = make([]byte, 16)
+			RandomPRG().Read(out[i])
+		}
+		return out
+	}
+	for _, kind := range []PRGKind{AESCTR, ChaCha20} {
+		SetPRGKind(kind)
+		SeedPRGs(42)
+		first := read()
+		SeedPRGs(42)
+		require.Equal(t, first, read())
+		require.NotEqual(t, first[0], first[1])
+		SeedPRGs(43)
+		require.NotEqual(t, first, read())
+	}
+
+	// the keys of the simulations of a seed are unchanged
+	SetPRGKind(AESCTR)
+	SeedPRGs(7)
+	require.Equal(t, *DerivePRGKey(7, 0), RandomPRG().Seed())
+	require.Equal(t, *DerivePRGKey(7, 1), RandomPRG().Seed())
+}
+
+func TestSeedGaussSample(t *testing.T) {
+	defer func() {
+		prgConfig.Lock()
+		prgConfig.seeded = false
+		prgConfig.Unlock()
+		bufPrgReader = NewBufPRG(RandomPRG())
+	}()
+
+	// the LWE errors and the random integers follow the seed
+	sample := func() ([]int64, *big.Int) {
+		out := make([]int64, 100)
+		for i := range out {
+			out[i] = GaussSample()
+		}
+		return out, RandInt(big.NewInt(1 << 62))
+	}
+	SeedPRGs(42)
+	first, n := sample()
+	SeedPRGs(42)
+	second, m := sample()
+	require.Equal(t, first, second)
+	require.Equal(t, n, m)
+	SeedPRGs(43)
+	third, _ := sample()
+	require.NotEqual(t, first, third)
+}
@@ -8,6 +8,8 @@ import (
 	"os/signal"
 	"runtime"
 	"runtime/pprof"
+
+	"github.com/si-co/vpir-code/lib/logging"
 )
 
 func StartProfiling(filename string) {
@@ -40,7 +42,7 @@ func writeMemProfile(filename string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Printf("Writing memory profile")
+	logging.Default().Info("writing memory profile")
 	pprof.WriteHeapProfile(f)
 	f.Close()
 }
@@ -63,7 +65,7 @@ func writeBlockProfile(filename string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Printf("Writing block profile")
+	logging.Default().Info("writing block profile")
 	pprof.Lookup("block").WriteTo(f, 0)
 	f.Close()
 }
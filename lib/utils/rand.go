@@ -25,7 +25,6 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/binary"
-	"io"
 	"math/big"
 	mrand "math/rand"
 	"sync"
@@ -84,20 +83,6 @@ func NewPRG(key *PRGKey) *PRGReader {
 	return out
 }
 
-func RandomPRGKey() *PRGKey {
-	var key PRGKey
-	_, err := io.ReadFull(rand.Reader, key[:])
-	if err != nil {
-		panic(err)
-	}
-
-	return &key
-}
-
-func RandomPRG() *PRGReader {
-	return NewPRG(RandomPRGKey())
-}
-
 func (s *PRGReader) Read(p []byte) (int, error) {
 	if len(p) < aes.BlockSize {
 		var buf [aes.BlockSize]byte
@@ -109,9 +94,9 @@ func (s *PRGReader) Read(p []byte) (int, error) {
 	return len(p), nil
 }
 
-func NewBufPRG(prg *PRGReader) *BufPRGReader {
+func NewBufPRG(prg PRG) *BufPRGReader {
 	out := new(BufPRGReader)
-	out.Key = prg.Key
+	out.Key = prg.Seed()
 	out.stream = bufio.NewReaderSize(prg, bufSize)
 	return out
 }
@@ -152,5 +137,6 @@ func (b *BufPRGReader) Seed(int64) {
 }
 
 func init() {
+	configurePRGs()
 	bufPrgReader = NewBufPRG(RandomPRG())
 }
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+// SetPRF expands the pseudorandom sets of the offline/online PIR schemes.
+// A set holds one block in every row of the db, at a pseudorandom column
+// derived from the key and the identifier of the set, so that a set is
+// stored as its identifier and its membership is checked in constant time.
+type SetPRF struct {
+	block   cipher.Block
+	columns uint64
+}
+
+// NewSetPRF returns the PRF of the sets with the given key, over a db with
+// the given number of columns
+func NewSetPRF(key *PRGKey, columns int) *SetPRF {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err)
+	}
+	return &SetPRF{block: block, columns: uint64(columns)}
+}
+
+// Offset returns the column of the block of the set in the given row
+func (s *SetPRF) Offset(id uint32, row int) int {
+	var buf [aes.BlockSize]byte
+	binary.BigEndian.PutUint32(buf[:4], id)
+	binary.BigEndian.PutUint64(buf[4:12], uint64(row))
+	s.block.Encrypt(buf[:], buf[:])
+	// the bias of the reduction is negligible for less than 2^32 columns
+	return int(binary.BigEndian.Uint64(buf[:8]) % s.columns)
+}
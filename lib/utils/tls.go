@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"log"
+	"os"
 
+	"github.com/si-co/vpir-code/lib/keys"
 	"google.golang.org/grpc/credentials"
 )
 
@@ -174,6 +178,19 @@ func init() {
 	}
 }
 
+// ServerPublicKey returns the public key of the certificate of server i, also
+// the key of its operator
+func ServerPublicKey(i int) (crypto.PublicKey, error) {
+	if i < 0 || i >= len(ServerCertificates) {
+		return nil, fmt.Errorf("no certificate for server %d", i)
+	}
+	cert, err := x509.ParseCertificate(ServerCertificates[i].Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	return cert.PublicKey, nil
+}
+
 func LoadServersCertificates() (credentials.TransportCredentials, error) {
 	cp := x509.NewCertPool()
 	for _, cert := range ServerPublicKeys {
@@ -181,7 +198,50 @@ func LoadServersCertificates() (credentials.TransportCredentials, error) {
 			return nil, errors.New("credentials: failed to append certificates")
 		}
 	}
-	creds := credentials.NewClientTLSFromCert(cp, "127.0.0.1")
+	creds := credentials.NewClientTLSFromCert(cp, DefaultServerName)
 
 	return creds, nil
 }
+
+// ServerCredentials returns the credentials authenticating the server of the
+// config entry, with the certificates of its CA file, or the built-in ones,
+// and its server name
+func ServerCredentials(s Server) (credentials.TransportCredentials, error) {
+	if s.CAFile == "" && s.ServerName == "" {
+		return LoadServersCertificates()
+	}
+	cp := x509.NewCertPool()
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if !cp.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("credentials: no certificate in %s", s.CAFile)
+		}
+	} else {
+		for _, cert := range ServerPublicKeys {
+			if !cp.AppendCertsFromPEM([]byte(cert)) {
+				return nil, errors.New("credentials: failed to append certificates")
+			}
+		}
+	}
+	name := s.ServerName
+	if name == "" {
+		name = DefaultServerName
+	}
+
+	return credentials.NewClientTLSFromCert(cp, name), nil
+}
+
+// ServerCertificate returns the certificate of the server of the config
+// entry, loaded from its files or the built-in one of its index
+func ServerCertificate(s Server) (tls.Certificate, error) {
+	if s.CertFile != "" || s.KeyFile != "" {
+		return keys.LoadX509KeyPair(s.CertFile, s.KeyFile)
+	}
+	if s.Index < 0 || s.Index >= len(ServerCertificates) {
+		return tls.Certificate{}, fmt.Errorf("no built-in certificate for server %d", s.Index)
+	}
+	return ServerCertificates[s.Index], nil
+}
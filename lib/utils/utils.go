@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"math"
 	"math/rand"
 	"time"
 )
@@ -28,11 +27,11 @@ func MaxBytesLength(in map[int][]byte) int {
 // If the square root is a whole number, do not modify anything.
 // Otherwise, return the square of the square root + 1.
 func IncreaseToNextSquare(num *int) {
-	i, f := math.Modf(math.Sqrt(float64(*num)))
-	if f == 0 {
+	r := ISqrt(*num)
+	if r*r == *num {
 		return
 	}
-	*num = int(math.Pow(i+1, 2))
+	*num = (r + 1) * (r + 1)
 }
 
 // source: https://stackoverflow.com/questions/43495745/how-to-generate-random-date-in-go-lang/43497333
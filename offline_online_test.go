@@ -0,0 +1,63 @@
+package main
+
+// Test suite for the two-server offline/online PIR scheme
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineOnline(t *testing.T) {
+	blockLen := 16
+	numRows, _ := database.CalculateNumRowsAndColumns(oneKB*16/(8*blockLen), true)
+	db := database.CreateRandomBytes(utils.RandomPRG(), oneKB*16, numRows, blockLen)
+	offline, online := server.NewOfflineOnline(db), server.NewOfflineOnline(db)
+	c := client.NewOfflineOnline(utils.RandomPRG(), &db.Info, 0)
+
+	// no query before the offline phase
+	in := make([]byte, 4)
+	_, err := c.QueryBytes(in, 2)
+	require.Error(t, err)
+
+	hints, err := offline.HintsBytes(c.OfflineQuery())
+	require.NoError(t, err)
+	require.NoError(t, c.SetHints(hints))
+
+	// every block is retrieved twice, the second time with a refreshed hint
+	numBlocks := db.NumRows * db.NumColumns
+	for k := 0; k < 2*numBlocks; k++ {
+		i := k % numBlocks
+		binary.BigEndian.PutUint32(in, uint32(i))
+		queries, err := c.QueryBytes(in, 2)
+		require.NoError(t, err)
+		a0, err := offline.AnswerBytes(queries[0])
+		require.NoError(t, err)
+		a1, err := online.AnswerBytes(queries[1])
+		require.NoError(t, err)
+		res, err := c.ReconstructBytes([][]byte{a0, a1})
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i*blockLen:(i+1)*blockLen], res)
+	}
+
+	// a clone keeps its own hints
+	clone := c.Clone()
+	binary.BigEndian.PutUint32(in, 3)
+	queries, err := clone.QueryBytes(in, 2)
+	require.NoError(t, err)
+	_, err = c.ReconstructBytes([][]byte{queries[0], queries[1]})
+	require.Error(t, err)
+
+	// malformed offline queries, queries and answers are rejected
+	_, err = offline.HintsBytes(c.OfflineQuery()[1:])
+	require.Error(t, err)
+	_, err = online.AnswerBytes(queries[1][4:])
+	require.Error(t, err)
+	_, err = clone.ReconstructBytes([][]byte{queries[0], queries[1]})
+	require.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package main
+
+// Test suite for the single-server client-preprocessing PIR scheme
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPiano(t *testing.T) {
+	blockLen := 16
+	numRows, _ := database.CalculateNumRowsAndColumns(oneKB*16/(8*blockLen), true)
+	db := database.CreateRandomBytes(utils.RandomPRG(), oneKB*16, numRows, blockLen)
+	s := server.NewPiano(db)
+	numBackup := 4
+	c := client.NewPiano(utils.RandomPRG(), &db.Info, 0, numBackup)
+
+	// no query before the preprocessing
+	_, err := c.QueryBytes(0)
+	require.Error(t, err)
+	require.NoError(t, c.Preprocess(s.ChunkBytes))
+
+	// every row is queried as many times as it has backup hints, for the
+	// same blocks, which are then in the refreshed hints
+	for k := 0; k < numBackup; k++ {
+		for r := 0; r < db.NumRows; r++ {
+			i := r*db.NumColumns + k%2
+			retrievePiano(t, c, s, db, i)
+		}
+	}
+	_, err = c.QueryBytes(0)
+	require.ErrorIs(t, err, client.ErrHintsExhausted)
+
+	// a new epoch of the db makes the hints stale
+	require.NoError(t, c.Preprocess(s.ChunkBytes))
+	next := database.CreateRandomBytes(utils.RandomPRG(), oneKB*16, numRows, blockLen)
+	require.NoError(t, s.Update(next))
+	q, err := c.QueryBytes(5)
+	require.NoError(t, err)
+	a, err := s.AnswerBytes(q)
+	require.NoError(t, err)
+	_, err = c.ReconstructBytes(a)
+	require.ErrorIs(t, err, client.ErrStaleHints)
+	require.NoError(t, c.Preprocess(s.ChunkBytes))
+	retrievePiano(t, c, s, next, 5)
+
+	// malformed chunk requests, queries and answers are rejected
+	_, err = s.ChunkBytes([]byte{0, 0, 1, 0})
+	require.Error(t, err)
+	_, err = s.AnswerBytes(q[1:])
+	require.Error(t, err)
+	q, err = c.QueryBytes(6)
+	require.NoError(t, err)
+	a, err = s.AnswerBytes(q)
+	require.NoError(t, err)
+	_, err = c.ReconstructBytes(a[1:])
+	require.Error(t, err)
+	require.Error(t, s.Update(database.CreateRandomBytes(utils.RandomPRG(), oneKB*16, 1, blockLen)))
+}
+
+func retrievePiano(t *testing.T, c *client.Piano, s *server.Piano, db *database.Bytes, i int) {
+	q, err := c.QueryBytes(i)
+	require.NoError(t, err)
+	a, err := s.AnswerBytes(q)
+	require.NoError(t, err)
+	block, err := c.ReconstructBytes(a)
+	require.NoError(t, err)
+	require.Equal(t, db.Entries[i*db.BlockSize:(i+1)*db.BlockSize], block)
+}
@@ -0,0 +1,306 @@
+package main
+
+// Test suite for the classical PIR schemes working in GF(2), used as baseline
+// for the experiments.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/bench"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIRDPF(t *testing.T) {
+	dbLen := oneKB * 64
+	blockLen := testBlockLength
+	nRows := 16
+
+	db := database.CreateRandomBytes(utils.RandomPRG(), dbLen, nRows, blockLen)
+
+	c := client.NewPIRDPF(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPIRDPF(db)
+	s1 := server.NewPIRDPF(db)
+
+	numBlocks := db.NumRows * db.NumColumns
+	in := make([]byte, 4)
+	for i := 0; i < numBlocks; i += 7 {
+		binary.BigEndian.PutUint32(in, uint32(i))
+		queries, err := c.QueryBytes(in, 2)
+		require.NoError(t, err)
+
+		a0, err := s0.AnswerBytes(queries[0])
+		require.NoError(t, err)
+		a1, err := s1.AnswerBytes(queries[1])
+		require.NoError(t, err)
+
+		res, err := c.ReconstructBytes([][]byte{a0, a1})
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i*blockLen:(i+1)*blockLen], res)
+	}
+
+	// a key for a smaller domain is rejected instead of being expanded
+	small := database.CreateRandomBytes(utils.RandomPRG(), dbLen/4, nRows, blockLen)
+	queries, err := client.NewPIRDPF(utils.RandomPRG(), &small.Info).QueryBytes(in, 2)
+	require.NoError(t, err)
+	_, err = s0.AnswerBytes(queries[0])
+	require.Error(t, err)
+}
+
+func TestPIRReplicated(t *testing.T) {
+	dbLen := oneKB * 64
+	blockLen := testBlockLength
+	nRows := 16
+
+	db := database.CreateRandomBytes(utils.RandomPRG(), dbLen, nRows, blockLen)
+
+	c := client.NewPIRReplicated(utils.RandomPRG(), &db.Info)
+	servers := make([]*server.PIRReplicated, client.ReplicatedServers)
+	for k := range servers {
+		servers[k] = server.NewPIRReplicated(db)
+	}
+
+	numBlocks := db.NumRows * db.NumColumns
+	in := make([]byte, 4)
+	for i := 0; i < numBlocks; i += 7 {
+		binary.BigEndian.PutUint32(in, uint32(i))
+		queries, err := c.QueryBytes(in, client.ReplicatedServers)
+		require.NoError(t, err)
+		answers := make([][]byte, len(servers))
+		for k, s := range servers {
+			answers[k], err = s.AnswerBytes(queries[k])
+			require.NoError(t, err)
+		}
+
+		res, err := c.ReconstructBytes(answers)
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i*blockLen:(i+1)*blockLen], res)
+
+		// any two servers reconstruct the block
+		missing := i % len(servers)
+		partial := append([][]byte{}, answers...)
+		partial[missing] = nil
+		res, err = c.ReconstructBytes(partial)
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i*blockLen:(i+1)*blockLen], res)
+
+		// a corrupted server is detected by the others
+		answers[missing][len(answers[missing])-1] ^= 1
+		_, err = c.ReconstructBytes(answers)
+		require.Error(t, err)
+	}
+
+	// a single server is not enough
+	_, err := c.ReconstructBytes([][]byte{nil, nil, make([]byte, 2*db.NumRows*blockLen)})
+	require.Error(t, err)
+	_, err = c.QueryBytes(in, 2)
+	require.Error(t, err)
+}
+
+func TestPIRLayouts(t *testing.T) {
+	// the blocks of 1KiB make bands of 32 rows in the tiled layout, the last
+	// band of the db holding the remaining 4 rows
+	blockLen := 1024
+	nRows := 100
+	rowMajor := database.CreateRandomBytes(utils.RandomPRG(), nRows*5*blockLen*8, nRows, blockLen)
+
+	for _, layout := range []database.Layout{database.ColumnMajor, database.Tiled} {
+		db := &database.Bytes{Entries: append([]byte{}, rowMajor.Entries...), Info: rowMajor.Info}
+		require.NoError(t, db.SetLayout(layout))
+		require.NotEqual(t, rowMajor.Entries, db.Entries, layout)
+		require.Equal(t, rowMajor.Digest(), db.Digest(), layout)
+
+		c := client.NewPIR(utils.RandomPRG(), &db.Info)
+		dc := client.NewPIRDPF(utils.RandomPRG(), &db.Info)
+		rc := client.NewPIRReplicated(utils.RandomPRG(), &db.Info)
+		s, ref := server.NewPIR(db), server.NewPIR(rowMajor)
+		ds := server.NewPIRDPF(db)
+		rs := server.NewPIRReplicated(db)
+
+		in := make([]byte, 4)
+		for i := 0; i < db.NumRows*db.NumColumns; i += 37 {
+			binary.BigEndian.PutUint32(in, uint32(i))
+			require.Equal(t, rowMajor.Block(i), db.Block(i), layout)
+
+			// the answers do not depend on the layout
+			queries, err := c.QueryBytes(in, 2)
+			require.NoError(t, err)
+			answers := make([][]byte, 2)
+			for k := range answers {
+				answers[k], err = s.AnswerBytes(queries[k])
+				require.NoError(t, err)
+				require.Equal(t, ref.Answer(queries[k]), answers[k], layout)
+			}
+			res, err := c.ReconstructBytes(answers)
+			require.NoError(t, err)
+			require.Equal(t, rowMajor.Block(i), res, layout)
+
+			queries, err = dc.QueryBytes(in, 2)
+			require.NoError(t, err)
+			for k := range answers {
+				answers[k], err = ds.AnswerBytes(queries[k])
+				require.NoError(t, err)
+			}
+			res, err = dc.ReconstructBytes(answers)
+			require.NoError(t, err)
+			require.Equal(t, rowMajor.Block(i), res, layout)
+
+			queries, err = rc.QueryBytes(in, client.ReplicatedServers)
+			require.NoError(t, err)
+			answers = make([][]byte, client.ReplicatedServers)
+			for k := range answers {
+				answers[k], err = rs.AnswerBytes(queries[k])
+				require.NoError(t, err)
+			}
+			res, err = rc.ReconstructBytes(answers)
+			require.NoError(t, err)
+			require.Equal(t, rowMajor.Block(i), res, layout)
+		}
+	}
+}
+
+// BenchmarkPIRLayouts measures the retrievals from a db of 1GiB in all the
+// layouts, with small and large blocks
+func BenchmarkPIRLayouts(b *testing.B) {
+	if testing.Short() {
+		b.Skip("db of 1GiB")
+	}
+	for _, blockLen := range []int{16, 1024} {
+		for _, layout := range []database.Layout{database.RowMajor, database.ColumnMajor, database.Tiled} {
+			runtime.GC()
+			p := bench.Params{DBLen: oneGB, BlockLen: blockLen, NumServers: 2, Rebalanced: true, Layout: layout}
+			b.Run(fmt.Sprintf("%v-%db", layout, blockLen), func(b *testing.B) {
+				benchmarkParams(b, "pir-classic", p)
+			})
+		}
+	}
+}
+
+func TestPIRAppendAnswer(t *testing.T) {
+	db := database.CreateRandomBytes(utils.RandomPRG(), oneKB*64, 16, testBlockLength)
+	c := client.NewPIR(utils.RandomPRG(), &db.Info)
+	s := server.NewPIR(db)
+
+	queries, err := c.QueryBytes([]byte{0, 0, 0, 5}, 2)
+	require.NoError(t, err)
+	a, err := s.AnswerBytes(queries[0])
+	require.NoError(t, err)
+
+	// the answer is appended to the buffer, which is reused once large
+	// enough (see TestPIRAppendAnswerAllocs)
+	dst, err := server.AppendAnswer(s, []byte{1}, queries[0])
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{1}, a...), dst)
+	dst, err = s.AppendAnswer(dst[:0], queries[0])
+	require.NoError(t, err)
+	require.Equal(t, a, dst)
+
+	r := server.NewPIRReplicated(db)
+	rc := client.NewPIRReplicated(utils.RandomPRG(), &db.Info)
+	queries, err = rc.QueryBytes([]byte{0, 0, 0, 5}, client.ReplicatedServers)
+	require.NoError(t, err)
+	a, err = r.AnswerBytes(queries[0])
+	require.NoError(t, err)
+	dst, err = r.AppendAnswer(dst[:0], queries[0])
+	require.NoError(t, err)
+	require.Equal(t, a, dst)
+}
+
+func TestPIRRelease(t *testing.T) {
+	db := database.CreateRandomBytes(utils.RandomPRG(), oneKB*64, 16, testBlockLength)
+	c := client.NewPIR(utils.RandomPRG(), &db.Info)
+	s0, s1 := server.NewPIR(db), server.NewPIR(db)
+
+	// the vectors of the released queries are reused by the next ones
+	in := make([]byte, 4)
+	for i := 0; i < db.NumRows*db.NumColumns; i += 13 {
+		binary.BigEndian.PutUint32(in, uint32(i))
+		queries, err := c.QueryBytes(in, 2)
+		require.NoError(t, err)
+		a0, err := s0.AnswerBytes(queries[0])
+		require.NoError(t, err)
+		a1, err := s1.AnswerBytes(queries[1])
+		require.NoError(t, err)
+		c.Release()
+
+		res, err := c.ReconstructBytes([][]byte{a0, a1})
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i*testBlockLength:(i+1)*testBlockLength], res)
+	}
+}
+
+func TestSPIR(t *testing.T) {
+	for _, numServers := range []int{2, 3} {
+		retrieveSPIR(t, numServers)
+	}
+}
+
+func retrieveSPIR(t *testing.T, numServers int) {
+	dbLen := oneKB * 8
+	blockLen := testBlockLength
+
+	db := database.CreateRandomBytes(utils.RandomPRG(), dbLen, 1, blockLen)
+	_, err := server.NewSPIR(db, utils.RandomPRGKey(), 0, numServers)
+	require.Error(t, err)
+	db.SPIR = true
+
+	seed := utils.RandomPRGKey()
+	c := client.NewPIR(utils.RandomPRG(), &db.Info)
+	servers := make([]*server.SPIR, numServers)
+	plain := server.NewPIR(db)
+	for k := range servers {
+		servers[k], err = server.NewSPIR(db, seed, k, numServers)
+		require.NoError(t, err)
+	}
+
+	numBlocks := db.NumRows * db.NumColumns
+	in := make([]byte, 4)
+	for i := 0; i < numBlocks; i += 7 {
+		binary.BigEndian.PutUint32(in, uint32(i))
+		queries, err := c.QueryBytes(in, numServers)
+		require.NoError(t, err)
+		answers := make([][]byte, numServers)
+		for k, s := range servers {
+			answers[k], err = s.AnswerBytes(queries[k])
+			require.NoError(t, err)
+			// every answer alone is masked
+			require.NotEqual(t, plain.Answer(queries[k][database.SPIRNonceLen:]), answers[k][database.SPIRNonceLen:])
+		}
+
+		res, err := c.ReconstructBytes(answers)
+		require.NoError(t, err)
+		require.Equal(t, db.Entries[i*blockLen:(i+1)*blockLen], res)
+
+		// a nonce is answered once
+		_, err = servers[0].AnswerBytes(queries[0])
+		require.Error(t, err)
+	}
+
+	// the nonces of an expired epoch are not answered
+	queries, err := c.QueryBytes(in, numServers)
+	require.NoError(t, err)
+	binary.BigEndian.PutUint64(queries[0], database.SPIREpoch(time.Now())-2)
+	_, err = servers[0].AnswerBytes(queries[0])
+	require.Error(t, err)
+
+	// the answers to another nonce are rejected
+	binary.BigEndian.PutUint32(in, 0)
+	queries, err = c.QueryBytes(in, numServers)
+	require.NoError(t, err)
+	answers := make([][]byte, numServers)
+	for k, s := range servers {
+		answers[k], err = s.AnswerBytes(queries[k])
+		require.NoError(t, err)
+	}
+	_, err = c.QueryBytes(in, numServers)
+	require.NoError(t, err)
+	_, err = c.ReconstructBytes(answers)
+	require.Error(t, err)
+}
@@ -0,0 +1,462 @@
+package main
+
+// Test suite for the FSS-based predicate (A)PIR schemes.
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nikirill/go-crypto/openpgp/packet"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/query"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+const testNumIdentifiers = 1000
+
+func TestPredicateAPIRAggregates(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	for i, k := range db.KeysInfo {
+		k.BitLength = uint16(1024 * (1 + i%4))
+	}
+
+	info := &query.Info{
+		Target:     query.PubKeyAlgo,
+		Aggregates: []query.Aggregate{query.Count, query.SumYears, query.SumBitLength},
+	}
+
+	// compute expected results in clear
+	expected := make([]uint32, len(info.Aggregates))
+	now := time.Now().Year()
+	for _, k := range db.KeysInfo {
+		if k.PubKeyAlgo != packet.PubKeyAlgoRSA {
+			continue
+		}
+		expected[0]++
+		if diff := now - k.CreationTime.Year(); diff > 0 {
+			expected[1] += uint32(diff)
+		}
+		expected[2] += uint32(k.BitLength)
+	}
+
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+
+	queries, err := c.Query(info.ToPKAClientFSS("RSA"), 2)
+	require.NoError(t, err)
+	a0 := s0.Answer(queries[0])
+	a1 := s1.Answer(queries[1])
+
+	res, err := c.ReconstructAggregates([][]uint32{a0, a1})
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+
+	// tampering with any of the aggregates must be detected
+	a0[len(a0)-1]++
+	_, err = c.ReconstructAggregates([][]uint32{a0, a1})
+	require.Error(t, err)
+}
+
+func TestPredicateAPIRModulus(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	db.Info.Modulus = 2147483629
+
+	expected := uint32(0)
+	for _, k := range db.KeysInfo {
+		if k.PubKeyAlgo == packet.PubKeyAlgoRSA {
+			expected++
+		}
+	}
+
+	info := &query.Info{Target: query.PubKeyAlgo}
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+
+	queries, err := c.Query(info.ToPKAClientFSS("RSA"), 2)
+	require.NoError(t, err)
+	a0 := s0.Answer(queries[0])
+	a1 := s1.Answer(queries[1])
+
+	res, err := c.Reconstruct([][]uint32{a0, a1})
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+
+	a0[1]++
+	_, err = c.Reconstruct([][]uint32{a0, a1})
+	require.Error(t, err)
+}
+
+func TestPredicateAPIRComparison(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+
+	threshold := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := uint32(0)
+	for _, k := range db.KeysInfo {
+		if k.CreationTime.Before(threshold) {
+			expected++
+		}
+	}
+
+	info := &query.Info{Target: query.CreationTime, Lt: true}
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+
+	queries, err := c.Query(info.ToCreationTimeLtClientFSS(threshold), 2)
+	require.NoError(t, err)
+	a0 := s0.Answer(queries[0])
+	a1 := s1.Answer(queries[1])
+
+	res, err := c.Reconstruct([][]uint32{a0, a1})
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+
+	// tampering with the count must be detected
+	a1[0]++
+	_, err = c.Reconstruct([][]uint32{a0, a1})
+	require.Error(t, err)
+}
+
+func TestPredicateAPIRDomain(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	for i, k := range db.KeysInfo[:10] {
+		domain := "example.org"
+		if i%2 == 0 {
+			// only the full domain matches, not its suffixes
+			domain = "sub-example.org"
+		}
+		k.UserId = packet.NewUserId("", "", fmt.Sprintf("user%d@%s", i, domain))
+	}
+
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+
+	queries, err := c.Query(query.DomainClientFSS("Example.org"), 2)
+	require.NoError(t, err)
+	a0 := s0.Answer(queries[0])
+	a1 := s1.Answer(queries[1])
+
+	res, err := c.Reconstruct([][]uint32{a0, a1})
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), res)
+}
+
+func TestPredicateAPIRNot(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	for i, k := range db.KeysInfo {
+		k.BitLength = uint16(1024 * (1 + i%4))
+		if i < 10 {
+			k.UserId = packet.NewUserId("", "", fmt.Sprintf("user%d@example.org", i))
+		}
+	}
+	// an email shorter than the domain does not match, and counts in the
+	// negation
+	db.KeysInfo[10].UserId = packet.NewUserId("", "", "a@b.c")
+
+	expected := []uint32{0, 0}
+	threshold := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	notBefore := uint32(0)
+	for i, k := range db.KeysInfo {
+		if i >= 10 {
+			expected[0]++
+			expected[1] += uint32(k.BitLength)
+		}
+		if !k.CreationTime.Before(threshold) {
+			notBefore++
+		}
+	}
+
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+
+	in, err := query.NewBuilder().TargetUserID().Not().Domain("example.org").
+		Aggregate(query.Count, query.SumBitLength).Build()
+	require.NoError(t, err)
+	queries, err := c.Query(in, 2)
+	require.NoError(t, err)
+	a0 := s0.Answer(queries[0])
+	a1 := s1.Answer(queries[1])
+
+	res, err := c.ReconstructAggregates([][]uint32{a0, a1})
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+
+	// tampering with the negated aggregates must be detected
+	a1[len(a1)-1]++
+	_, err = c.ReconstructAggregates([][]uint32{a0, a1})
+	require.Error(t, err)
+
+	// keys created in or after 2010, through the encoded queries
+	encoded, err := query.NewBuilder().TargetCreationTime().Not().Before(threshold).Encode()
+	require.NoError(t, err)
+	queriesBytes, err := c.QueryBytes(encoded, 2)
+	require.NoError(t, err)
+	b0, err := s0.AnswerBytes(queriesBytes[0])
+	require.NoError(t, err)
+	b1, err := s1.AnswerBytes(queriesBytes[1])
+	require.NoError(t, err)
+	count, err := c.ReconstructBytes([][]byte{b0, b1})
+	require.NoError(t, err)
+	require.Equal(t, notBefore, count)
+}
+
+func TestPredicateAPIRKeySize(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	equal, below := uint32(0), uint32(0)
+	for i, k := range db.KeysInfo {
+		k.BitLength = uint16(1024 * (1 + i%4))
+		if k.BitLength == 2048 {
+			equal++
+		}
+		if k.BitLength < 3072 {
+			below++
+		}
+	}
+
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+
+	for statement, expected := range map[string]uint32{
+		"SELECT COUNT(*) WHERE size = 2048": equal,
+		"SELECT COUNT(*) WHERE size < 3072": below,
+	} {
+		in, err := query.Parse(statement)
+		require.NoError(t, err)
+		queriesBytes, err := c.QueryBytes(mustEncodeClientFSS(t, in), 2)
+		require.NoError(t, err)
+		a0, err := s0.AnswerBytes(queriesBytes[0])
+		require.NoError(t, err)
+		a1, err := s1.AnswerBytes(queriesBytes[1])
+		require.NoError(t, err)
+		count, err := c.ReconstructBytes([][]byte{a0, a1})
+		require.NoError(t, err)
+		require.Equal(t, expected, count, statement)
+	}
+}
+
+func TestPredicateAPIRCursor(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	var expected []int
+	for i, k := range db.KeysInfo {
+		k.BitLength = uint16(1024 * (1 + i%4))
+		if k.PubKeyAlgo == packet.PubKeyAlgoDSA {
+			expected = append(expected, i)
+		}
+	}
+
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+	rounds := 0
+	exchange := func(queries [][]byte) ([][]byte, error) {
+		rounds++
+		a0, err := s0.AnswerBytes(queries[0])
+		if err != nil {
+			return nil, err
+		}
+		a1, err := s1.AnswerBytes(queries[1])
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{a0, a1}, nil
+	}
+
+	in, err := query.NewBuilder().TargetPubKeyAlgo().Algo("DSA").Aggregate(query.SumBitLength).Build()
+	require.NoError(t, err)
+	cur, err := c.NewCursor(in, 64, exchange)
+	require.NoError(t, err)
+	require.Equal(t, len(expected), cur.Count())
+
+	var matched []int
+	for {
+		m, err := cur.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, []uint32{uint32(db.KeysInfo[m.Index].BitLength)}, m.Values)
+		matched = append(matched, m.Index)
+	}
+	require.Equal(t, expected, matched)
+	// one round for the counts and one per non-empty bucket
+	require.LessOrEqual(t, rounds, 1+(testNumIdentifiers+63)/64)
+
+	// tampering with a page must be detected
+	cur, err = c.NewCursor(in, 64, func(queries [][]byte) ([][]byte, error) {
+		answers, err := exchange(queries)
+		if err == nil && len(answers[0]) > 100*field.Bytes {
+			answers[0][0] ^= 1
+		}
+		return answers, err
+	})
+	require.NoError(t, err)
+	_, err = cur.Next()
+	require.Error(t, err)
+
+	// the comparison queries have no pages
+	before, err := query.NewBuilder().TargetKeySize().Below(2048).Build()
+	require.NoError(t, err)
+	_, err = c.NewCursor(before, 64, exchange)
+	require.Error(t, err)
+}
+
+func TestPredicateAPIRField64(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers, field.Bits64)
+	require.NoError(t, err)
+
+	expected := uint64(0)
+	for _, k := range db.KeysInfo {
+		if k.PubKeyAlgo == packet.PubKeyAlgoDSA {
+			expected++
+		}
+	}
+
+	info := &query.Info{Target: query.PubKeyAlgo}
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0 := server.NewPredicateAPIR(db, 0)
+	s1 := server.NewPredicateAPIR(db, 1)
+
+	queries, err := c.Query(info.ToPKAClientFSS("DSA"), 2)
+	require.NoError(t, err)
+	a0 := s0.Answer64(queries[0])
+	a1 := s1.Answer64(queries[1])
+	// one value for the data and two tags
+	require.Len(t, a0, 1+field.ConcurrentExecutions64)
+
+	res, err := c.Reconstruct64([][]uint64{a0, a1})
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+
+	a0[1]++
+	_, err = c.Reconstruct64([][]uint64{a0, a1})
+	require.Error(t, err)
+
+	// the queries not implemented in the 64-bit field are rejected by the
+	// client, and the keys in the 32-bit field by the servers
+	_, err = c.Query((&query.Info{Target: query.PubKeyAlgo, Not: true}).ToPKAClientFSS("DSA"), 2)
+	require.Error(t, err)
+	other := db.Info
+	other.FieldBits = field.Bits
+	queries, err = client.NewPredicateAPIR(utils.RandomPRG(), &other).Query(info.ToPKAClientFSS("DSA"), 2)
+	require.NoError(t, err)
+	encoded, err := queries[0].Encode()
+	require.NoError(t, err)
+	_, err = s0.AnswerBytes(encoded)
+	require.Error(t, err)
+}
+
+func TestPredicateAPIRNoise(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	db.NoiseEpsilon = 1
+
+	expected := []int64{0, 0}
+	for _, k := range db.KeysInfo {
+		if k.PubKeyAlgo == packet.PubKeyAlgoRSA {
+			expected[0]++
+			expected[1] += int64(k.BitLength)
+		}
+	}
+
+	info := &query.Info{
+		Target:     query.PubKeyAlgo,
+		Aggregates: []query.Aggregate{query.Count, query.SumBitLength},
+	}
+	seed := utils.RandomPRGKey()
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s0, err := server.NewNoisyPredicateAPIR(db, 0, seed)
+	require.NoError(t, err)
+	s1, err := server.NewNoisyPredicateAPIR(db, 1, seed)
+	require.NoError(t, err)
+
+	queries, err := c.QueryBytes(mustEncodeClientFSS(t, info.ToPKAClientFSS("RSA")), 2)
+	require.NoError(t, err)
+	a0, err := s0.AnswerBytes(queries[0])
+	require.NoError(t, err)
+	a1, err := s1.AnswerBytes(queries[1])
+	require.NoError(t, err)
+
+	// the noise of the servers passes the check of the tags, and is within
+	// 40 times its scale with overwhelming probability
+	answers := decodeFieldAnswers(t, a0, a1)
+	res, err := c.ReconstructNoisyAggregates(answers)
+	require.NoError(t, err)
+	for a, agg := range info.Aggregates {
+		bound := 40 * int64(agg.Sensitivity())
+		require.InDelta(t, expected[a], res[a], float64(bound))
+	}
+
+	// tampering with the noisy aggregates must still be detected
+	answers[0][0]++
+	_, err = c.ReconstructNoisyAggregates(answers)
+	require.Error(t, err)
+
+	// a nonce is answered once
+	_, err = s0.AnswerBytes(queries[0])
+	require.Error(t, err)
+
+	// a server without the seed does not answer without noise
+	_, err = server.NewPredicateAPIR(db, 0).AnswerBytes(queries[0])
+	require.Error(t, err)
+}
+
+func TestPredicateAPIRAppendAnswer(t *testing.T) {
+	db, err := database.CreateRandomKeysDB(utils.RandomPRG(), testNumIdentifiers)
+	require.NoError(t, err)
+	c := client.NewPredicateAPIR(utils.RandomPRG(), &db.Info)
+	s := server.NewPredicateAPIR(db, 0)
+
+	for _, b := range []*query.Builder{
+		query.NewBuilder().TargetUserID().Domain("example.org"),
+		query.NewBuilder().TargetUserID().Domain("example.org").Aggregate(query.Count, query.SumBitLength),
+		query.NewBuilder().TargetKeySize().Below(4096),
+	} {
+		encoded, err := b.Encode()
+		require.NoError(t, err)
+		queries, err := c.QueryBytes(encoded, 2)
+		require.NoError(t, err)
+		a, err := s.AnswerBytes(queries[0])
+		require.NoError(t, err)
+
+		dst, err := s.AppendAnswer([]byte{1}, queries[0])
+		require.NoError(t, err)
+		require.Equal(t, append([]byte{1}, a...), dst)
+		dst, err = s.AppendAnswer(dst[:0], queries[0])
+		require.NoError(t, err)
+		require.Equal(t, a, dst)
+	}
+}
+
+func mustEncodeClientFSS(t *testing.T, q *query.ClientFSS) []byte {
+	in, err := q.Encode()
+	require.NoError(t, err)
+	return in
+}
+
+func decodeFieldAnswers(t *testing.T, answers ...[]byte) [][]uint32 {
+	out := make([][]uint32, len(answers))
+	for i, a := range answers {
+		var err error
+		out[i], err = field.Default().DecodeElements(a)
+		require.NoError(t, err)
+	}
+	return out
+}
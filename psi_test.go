@@ -0,0 +1,63 @@
+package main
+
+// Test suite for the labeled private set intersection protocol
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabeledPSI(t *testing.T) {
+	rnd := utils.RandomPRG()
+	labels := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		label := make([]byte, 10+i%20)
+		_, err := rnd.Read(label)
+		require.NoError(t, err)
+		labels[fmt.Sprintf("user%d@example.org", i)] = label
+	}
+	db, err := database.NewLabeledPSI(rnd, labels, group.P256)
+	require.NoError(t, err)
+	maxQueries := 8
+	s := server.NewLabeledPSI(db, maxQueries)
+	c := client.NewLabeledPSI(utils.RandomPRG(), &db.Info)
+
+	// no reconstruction without the table
+	ids := []string{"user3@example.org", "nobody@example.org", "user42@example.org"}
+	q, err := c.QueryBytes(ids)
+	require.NoError(t, err)
+	a, err := s.AnswerBytes(q)
+	require.NoError(t, err)
+	_, err = c.ReconstructBytes(a)
+	require.Error(t, err)
+
+	require.NoError(t, c.SetTable(s.TableBytes()))
+	q, err = c.QueryBytes(ids)
+	require.NoError(t, err)
+	require.Len(t, q, len(ids)*db.ElementSize)
+	a, err = s.AnswerBytes(q)
+	require.NoError(t, err)
+	res, err := c.ReconstructBytes(a)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{
+		"user3@example.org":  labels["user3@example.org"],
+		"user42@example.org": labels["user42@example.org"],
+	}, res)
+
+	// the server bounds the number of identifiers of a query
+	many := make([]string, maxQueries+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("user%d@example.org", i)
+	}
+	q, err = c.QueryBytes(many)
+	require.NoError(t, err)
+	_, err = s.AnswerBytes(q)
+	require.Error(t, err)
+}
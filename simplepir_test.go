@@ -0,0 +1,131 @@
+package main
+
+// Test suite for the single-server SimplePIR scheme
+
+import (
+	"testing"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/matrix"
+	"github.com/si-co/vpir-code/lib/proof"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplePIR(t *testing.T) {
+	data := make([]byte, oneKB*64)
+	_, err := utils.RandomPRG().Read(data)
+	require.NoError(t, err)
+	db := database.NewSimplePIR(data)
+	params := utils.ParamsSimplePIR(db.NumRows, db.NumColumns)
+
+	s := server.NewSimplePIR(db, params)
+	c, err := client.NewSimplePIR(utils.RandomPRG(), &db.Info, params, s.HintBytes())
+	require.NoError(t, err)
+
+	for i := 0; i < len(data); i += 997 {
+		q, err := c.QueryBytes(i)
+		require.NoError(t, err)
+		a, err := s.AnswerBytes(q)
+		require.NoError(t, err)
+		res, err := c.ReconstructBytes(a)
+		require.NoError(t, err)
+		require.Equal(t, data[i], res)
+	}
+
+	// a query retrieves a whole row
+	row, err := c.Reconstruct(s.Answer(c.Query(3, 0)))
+	require.NoError(t, err)
+	require.Equal(t, data[3*db.NumColumns:4*db.NumColumns], row)
+
+	// malformed hints and queries are rejected
+	_, err = client.NewSimplePIR(utils.RandomPRG(), &db.Info, params, s.HintBytes()[:16])
+	require.Error(t, err)
+	_, err = s.AnswerBytes(matrix.MatrixToBytes(matrix.New(1, db.NumRows+1)))
+	require.Error(t, err)
+}
+
+func TestSimplePIRVerifiable(t *testing.T) {
+	data := make([]byte, oneKB*2)
+	_, err := utils.RandomPRG().Read(data)
+	require.NoError(t, err)
+	db := database.NewSimplePIR(data)
+	params := utils.ParamsSimplePIR(db.NumRows, db.NumColumns)
+
+	s := server.NewSimplePIR(db, params)
+	p, err := proof.NewLinearCheckProver(group.Ristretto255, db.Matrix)
+	require.NoError(t, err)
+	s.SetProver(p)
+
+	c, err := client.NewSimplePIR(utils.RandomPRG(), &db.Info, params, s.HintBytes())
+	require.NoError(t, err)
+	v, err := proof.NewLinearCheckVerifier(group.Ristretto255, db.NumRows, db.NumColumns, p.Digest())
+	require.NoError(t, err)
+	c.SetVerifier(v)
+
+	for i := 0; i < len(data); i += 331 {
+		q, err := c.QueryBytes(i)
+		require.NoError(t, err)
+		a, err := s.AnswerBytes(q)
+		require.NoError(t, err)
+		res, err := c.ReconstructBytes(a)
+		require.NoError(t, err)
+		require.Equal(t, data[i], res)
+	}
+
+	// an answer that does not match the digest is rejected
+	q, err := c.QueryBytes(0)
+	require.NoError(t, err)
+	a, err := s.AnswerBytes(q)
+	require.NoError(t, err)
+	a[20] ^= 1
+	_, err = c.ReconstructBytes(a)
+	require.EqualError(t, err, "REJECT!")
+}
+
+func TestSimplePIRHybrid(t *testing.T) {
+	data := make([]byte, oneKB*8)
+	_, err := utils.RandomPRG().Read(data)
+	require.NoError(t, err)
+	db := database.NewSimplePIR(data)
+	params := utils.ParamsSimplePIR(db.NumRows, db.NumColumns)
+
+	// both servers hold the db, only the hint of the second one is needed
+	servers := []*server.SimplePIR{server.NewSimplePIR(db, params), server.NewSimplePIR(db, params)}
+	c, err := client.NewHybrid(utils.RandomPRG(), &db.Info, params, servers[1].HintBytes())
+	require.NoError(t, err)
+
+	for i := 0; i < len(data); i += 523 {
+		everlasting := (i/523)%2 == 0
+		queries, err := c.QueryBytes(i, everlasting)
+		require.NoError(t, err)
+		require.Equal(t, everlasting, queries[0] != nil)
+
+		answers := make([][]byte, 2)
+		for k, q := range queries {
+			if q == nil {
+				continue
+			}
+			answers[k], err = servers[k].AnswerBytes(q)
+			require.NoError(t, err)
+		}
+		res, err := c.ReconstructBytes(answers)
+		require.NoError(t, err)
+		require.Equal(t, data[i], res)
+	}
+
+	// the share of the first server alone does not select the row
+	queries, err := c.QueryBytes(0, true)
+	require.NoError(t, err)
+	share := matrix.BytesToMatrix(queries[0])
+	zeros := 0
+	for k := 0; k < share.Cols(); k++ {
+		if share.Get(0, k) == 0 {
+			zeros++
+		}
+	}
+	require.Less(t, zeros, share.Cols()/2)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/rand"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// baselineBlockLength returns the length in bytes of the blocks downloaded by
+// the baseline: BlockLength, or one byte for the schemes retrieving bits
+func (s *Simulation) baselineBlockLength() int {
+	if s.BlockLength > 0 {
+		return s.BlockLength
+	}
+	return 1
+}
+
+// baselineDB returns the database of the baseline for the given db length.
+// The real dataset is used as is, if any.
+func (s *Simulation) baselineDB(dbLen int, realDB *database.Bytes) *database.Bytes {
+	if realDB != nil {
+		return realDB
+	}
+	return database.CreateRandomBytes(utils.RandomPRG(), dbLen, 1, s.baselineBlockLength())
+}
+
+// plainDownload retrieves a block without any privacy, i.e., the client sends
+// the index of the block and the server answers with the block. It is the
+// reference point of the overhead of the private schemes.
+func plainDownload(db *database.Bytes, r *runner, results []*Chunk) {
+	numBlocks := len(db.BlockLengths)
+	// the server indexes the blocks once, as part of the setup
+	offsets := make([]int, numBlocks+1)
+	for i, l := range db.BlockLengths {
+		offsets[i+1] = offsets[i] + l
+	}
+
+	r.run(results, func(j int) *Chunk {
+		res := initChunk(1)
+		res.Bandwidth[0] = initBlock(1)
+
+		mp := r.newPhases(res, 0, 1)
+		query := make([]byte, 4)
+		binary.BigEndian.PutUint32(query, uint32(rand.Intn(numBlocks)))
+		mp.query()
+
+		i := binary.BigEndian.Uint32(query)
+		answer := make([]byte, offsets[i+1]-offsets[i])
+		copy(answer, db.Entries[offsets[i]:offsets[i+1]])
+		mp.answer(0)
+		// the answer is the block itself
+		mp.reconstruct()
+
+		res.Bandwidth[0].Query = float64(len(query))
+		res.Bandwidth[0].Answers[0] = float64(len(answer))
+
+		return res
+	})
+}
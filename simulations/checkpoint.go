@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// checkpoint persists the results of an experiment after every repetition,
+// so that a long simulation interrupted by a crash can be resumed without
+// running again the completed repetitions.
+type checkpoint struct {
+	path       string
+	experiment *Experiment
+}
+
+// newCheckpoint returns a checkpoint writing to the given file. If resume is
+// true, the results already stored in the file are loaded.
+func newCheckpoint(path string, resume bool) (*checkpoint, error) {
+	cp := &checkpoint{
+		path:       path,
+		experiment: &Experiment{Results: make(map[int][]*Chunk)},
+	}
+	if !resume {
+		return cp, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cp.experiment); err != nil {
+		return nil, err
+	}
+	if cp.experiment.Results == nil {
+		cp.experiment.Results = make(map[int][]*Chunk)
+	}
+
+	return cp, nil
+}
+
+// results returns the slice of nRepeat results for the given db length, where
+// the repetitions not completed yet are nil
+func (cp *checkpoint) results(dbLen, nRepeat int) []*Chunk {
+	return repetitions(cp.experiment.Results, dbLen, nRepeat)
+}
+
+// point returns the sweep point with the given number of servers and
+// threshold, adding it to the experiment if not present
+func (cp *checkpoint) point(numServers, threshold int) *SweepPoint {
+	for _, p := range cp.experiment.Sweep {
+		if p.NumServers == numServers && p.Threshold == threshold {
+			if p.Results == nil {
+				p.Results = make(map[int][]*Chunk)
+			}
+			return p
+		}
+	}
+	p := &SweepPoint{NumServers: numServers, Threshold: threshold, Results: make(map[int][]*Chunk)}
+	cp.experiment.Sweep = append(cp.experiment.Sweep, p)
+	return p
+}
+
+// repetitions returns the slice of nRepeat results for the given db length
+// stored in m, where the repetitions not completed yet are nil
+func repetitions(m map[int][]*Chunk, dbLen, nRepeat int) []*Chunk {
+	res := m[dbLen]
+	if len(res) < nRepeat {
+		res = append(res, make([]*Chunk, nRepeat-len(res))...)
+	}
+	m[dbLen] = res[:nRepeat]
+
+	return m[dbLen]
+}
+
+// save writes all the results to the file. The file is replaced atomically,
+// so that a crash while saving does not corrupt the previous checkpoint.
+func (cp *checkpoint) save() error {
+	data, err := json.Marshal(cp.experiment)
+	if err != nil {
+		return err
+	}
+	tmp := cp.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, cp.path)
+}
+
+// completed returns true if all the repetitions have a result
+func completed(results []*Chunk) bool {
+	for _, r := range results {
+		if r == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"math/rand"
+	"sync"
+)
+
+const (
+	// flip one random bit of the answer
+	corruptBitFlip = "bitflip"
+	// answer with the answer to a previous query, i.e., a wrong block
+	corruptReplay = "replay"
+	// all the servers answer from a forged db, along with its info, i.e., a
+	// malicious majority that only the digest held by the client detects.
+	// Only for the multi-server schemes with Merkle proofs.
+	corruptForge = "forge"
+)
+
+// Corruption makes the simulated server corrupt a fraction of its answers,
+// to measure how often the client detects it
+type Corruption struct {
+	Rate float64 // fraction of the answers to corrupt
+	Mode string  // corruptBitFlip, corruptReplay or corruptForge
+}
+
+// Detection summarizes the corrupted answers of the repetitions of one db
+// length
+type Detection struct {
+	Corrupted int
+	Detected  int
+	Rate      float64 // Detected / Corrupted
+}
+
+func (c *Corruption) valid() bool {
+	return c.Rate >= 0 && c.Rate <= 1 &&
+		(c.Mode == corruptBitFlip || c.Mode == corruptReplay || c.Mode == corruptForge)
+}
+
+// corrupt returns true if the next answer must be corrupted
+func (c *Corruption) corrupt() bool {
+	return c != nil && rand.Float64() < c.Rate
+}
+
+func flipBit(b []byte) {
+	i := rand.Intn(len(b) * 8)
+	b[i/8] ^= 1 << (i % 8)
+}
+
+// length of the rows and columns prefix of an encoded matrix
+const matrixHeaderLen = 8
+
+// flipMatricesBit flips one random bit of the entries of one of the matrices
+// encoded by matrix.MatricesToBytes, leaving the dimensions untouched
+func flipMatricesBit(b []byte) {
+	length := int(binary.BigEndian.Uint32(b[:4]))
+	m := b[4:]
+	i := rand.Intn(len(m) / length)
+	flipBit(m[i*length+matrixHeaderLen : (i+1)*length])
+}
+
+// detectionRate counts the corrupted and detected answers in results
+func detectionRate(results []*Chunk) *Detection {
+	d := new(Detection)
+	for _, r := range results {
+		if r != nil && r.Corrupted {
+			d.Corrupted++
+			if r.Detected {
+				d.Detected++
+			}
+		}
+	}
+	if d.Corrupted > 0 {
+		d.Rate = float64(d.Detected) / float64(d.Corrupted)
+	}
+	return d
+}
+
+// replayer stores the encoding of the last answer of the server, to be
+// replayed by a corrupted server
+type replayer struct {
+	mu   sync.Mutex
+	last []byte
+}
+
+// swap stores the encoded answer and returns the previous one, nil if none
+func (p *replayer) swap(answer []byte) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prev := p.last
+	p.last = answer
+	return prev
+}
+
+// checkRejection returns true if the client rejected a corrupted answer, and
+// aborts the simulation if it rejected an honest one
+func checkRejection(res *Chunk, err error) bool {
+	if !res.Corrupted {
+		log.Fatal(err)
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/pgp"
+)
+
+const (
+	// SKS PGP dump, parsed into gob files of pgp.Key
+	datasetPGP = "pgp"
+	// Certificate Transparency log snapshot, JSON files of get-entries
+	datasetCT = "ct"
+)
+
+// Dataset is a real dataset loaded in place of a random database, so that the
+// block sizes and the load of the hash table buckets are realistic
+type Dataset struct {
+	Type       string // datasetPGP or datasetCT
+	Path       string // directory containing the data files
+	NumFiles   int    // number of files to load, all if zero
+	Rebalanced bool   // matrix instead of vector representation
+	// packets stripped from the PGP keys before embedding, as a
+	// comma-separated list of photos, thirdparty, expired or all
+	Filters string
+}
+
+func (d *Dataset) valid() bool {
+	if _, err := pgp.ParseFilter(d.Filters); err != nil {
+		return false
+	}
+	return (d.Type == datasetPGP || d.Type == datasetCT) && d.Path != "" && d.NumFiles >= 0
+}
+
+// load returns the bytes db of the dataset, with Merkle proofs if merkle is
+// true
+func (d *Dataset) load(merkle bool) (*database.Bytes, error) {
+	files, err := pgp.GetAllFiles(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	if d.NumFiles > 0 {
+		if d.NumFiles > len(files) {
+			return nil, fmt.Errorf("%d files requested, only %d in %s", d.NumFiles, len(files), d.Path)
+		}
+		files = files[:d.NumFiles]
+	}
+
+	// valid() checked the filters
+	filter, _ := pgp.ParseFilter(d.Filters)
+
+	var db *database.Bytes
+	switch {
+	case d.Type == datasetPGP && merkle:
+		db, err = database.GenerateRealKeyMerkle(files, d.Rebalanced, filter)
+	case d.Type == datasetPGP:
+		db, err = database.GenerateRealKeyBytes(files, d.Rebalanced, filter)
+	case merkle:
+		db, err = database.GenerateCTMerkle(files, d.Rebalanced)
+	default:
+		db, err = database.GenerateCTBytes(files, d.Rebalanced)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the distribution of the blocks sizes determines the padding overhead
+	total := 0
+	for _, l := range db.BlockLengths {
+		total += l
+	}
+	log.Printf("%s dataset: %d blocks, max block length %d bytes, mean %.1f bytes",
+		d.Type, len(db.BlockLengths), db.BlockSize, float64(total)/float64(len(db.BlockLengths)))
+
+	return db, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/si-co/vpir-code/cmd/grpc/client/manager"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/logging"
+	"github.com/si-co/vpir-code/lib/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// GRPC runs the actual gRPC servers of cmd/grpc/server and queries them
+// through the manager, so that the serialization and the transport are
+// included in the measurements
+type GRPC struct {
+	Server string // path to the server binary
+	// gRPC config file with the addresses of the servers, and their TLS
+	// material, replica groups, roles and weights
+	Config string
+	Scheme string // pointPIR, pointVPIR, pointPIRDPF or pointPIRReplicated
+	Files  int    // number of key files loaded by the servers
+	// optional SSH host of each server, in the order of the config. The
+	// server binary and the config must be available at the same paths on
+	// the host. The servers with no host are run locally.
+	Hosts []string
+	// seconds to wait for the servers to load their db, 60 if zero
+	StartupTimeout int
+}
+
+func (g *GRPC) valid() bool {
+	return g.Server != "" && g.Config != "" && g.Files >= 0 &&
+		(g.Scheme == "pointPIR" || g.Scheme == "pointVPIR" || g.Scheme == "pointPIRDPF" ||
+			g.Scheme == "pointPIRReplicated")
+}
+
+// start launches the servers and returns the commands running them
+func (g *GRPC) start(numServers int) ([]*exec.Cmd, error) {
+	config, err := filepath.Abs(g.Config)
+	if err != nil {
+		return nil, err
+	}
+	files := g.Files
+	if files == 0 {
+		files = 1
+	}
+
+	cmds := make([]*exec.Cmd, numServers)
+	for i := range cmds {
+		args := []string{fmt.Sprintf("-id=%d", i), fmt.Sprintf("-files=%d", files), "-scheme=" + g.Scheme}
+		var cmd *exec.Cmd
+		if i < len(g.Hosts) && g.Hosts[i] != "" {
+			// -tt forwards the termination of ssh to the remote server
+			remote := fmt.Sprintf("VPIR_CONFIG=%s %s %s", config, g.Server, strings.Join(args, " "))
+			cmd = exec.Command("ssh", "-tt", g.Hosts[i], remote)
+		} else {
+			cmd = exec.Command(g.Server, args...)
+			cmd.Env = append(os.Environ(), "VPIR_CONFIG="+config)
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			stopServers(cmds[:i])
+			return nil, fmt.Errorf("starting server %d: %v", i, err)
+		}
+		cmds[i] = cmd
+	}
+
+	return cmds, nil
+}
+
+// stopServers interrupts the servers and waits for them to shut down
+func stopServers(cmds []*exec.Cmd) {
+	for _, cmd := range cmds {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			log.Printf("could not interrupt server: %v", err)
+		}
+	}
+	for _, cmd := range cmds {
+		cmd.Wait()
+	}
+}
+
+// connect connects to all the servers, retrying until they have loaded their
+// db or the startup timeout expires
+func (g *GRPC) connect(config *utils.Config) (manager.Actor, error) {
+	opts := []grpc.CallOption{
+		grpc.UseCompressor(gzip.Name),
+		grpc.MaxCallRecvMsgSize(1024 * 1024 * 1024),
+		grpc.MaxCallSendMsgSize(1024 * 1024 * 1024),
+	}
+	m := manager.NewManager(*config, opts, logging.Default())
+
+	timeout := time.Duration(g.StartupTimeout) * time.Second
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		actor, err := m.Connect()
+		if err == nil {
+			return actor, nil
+		}
+		if time.Now().After(deadline) {
+			return manager.Actor{}, fmt.Errorf("servers not ready after %v: %v", timeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// runGRPC runs the repetitions against the gRPC servers. The results are
+// stored under the length in bits of the db loaded by the servers.
+func (s *Simulation) runGRPC(cp *checkpoint, r *runner) {
+	// the environment overrides the config file for the local servers too,
+	// which inherit it
+	config, err := utils.LoadLayeredConfig(s.GRPC.Config, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// every server of the config is started, the queries are shared among
+	// the replica groups
+	numServers := len(config.Groups())
+
+	cmds, err := s.GRPC.start(len(config.Addresses))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stopServers(cmds)
+
+	actor, err := s.GRPC.connect(config)
+	if err != nil {
+		stopServers(cmds)
+		log.Fatal(err)
+	}
+	infos, err := actor.GetDBInfos()
+	if err != nil {
+		stopServers(cmds)
+		log.Fatal(err)
+	}
+	info := infos[0]
+	dbLen := 8 * info.NumRows * info.NumColumns * info.BlockSize
+	log.Printf("servers ready, db of %d bits", dbLen)
+
+	results := cp.results(dbLen, s.Repetitions)
+	pirGRPC(&actor, &info, s.GRPC.Scheme, numServers, r, results)
+	// only the client side is counted, the servers run in other processes
+	cp.experiment.Traffic = recordTraffic(cp.experiment.Traffic, dbLen)
+	cp.save()
+}
+
+// pirGRPC retrieves random blocks from the gRPC servers. The answer phase is
+// the time between sending the queries and receiving all the answers,
+// including the transport.
+func pirGRPC(actor *manager.Actor, info *database.Info, scheme string, numServers int, r *runner, results []*Chunk) {
+	numRetrievedBlocks := 1
+
+	r.run(results, func(j int) *Chunk {
+		var c client.Client
+		if scheme == "pointPIRDPF" {
+			c = client.NewPIRDPF(utils.RandomPRG(), info)
+		} else if scheme == "pointPIRReplicated" {
+			c = client.NewPIRReplicated(utils.RandomPRG(), info)
+		} else {
+			c = client.NewPIR(utils.RandomPRG(), info)
+		}
+		res := initChunk(numRetrievedBlocks)
+		res.Digest = float64(len(info.Root))
+
+		in := make([]byte, 4)
+		binary.BigEndian.PutUint32(in, uint32(rand.Intn(info.NumRows*info.NumColumns)))
+		res.Bandwidth[0] = initBlock(numServers)
+
+		mp := r.newPhases(res, 0, numServers)
+		queries, err := c.QueryBytes(in, numServers)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.query()
+
+		// the servers answer in parallel, the time is the one of the slowest.
+		// The replicated scheme retrieves the block if a server fails.
+		var answers [][]byte
+		if scheme == "pointPIRReplicated" {
+			if answers, err = actor.RunReplicatedQueries(queries); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			answers = actor.RunQueries(queries)
+		}
+		mp.answer(0)
+		for k := range queries {
+			res.Bandwidth[0].Query += float64(len(queries[k]))
+			res.Bandwidth[0].Answers[k] = float64(len(answers[k]))
+		}
+
+		mp.skip()
+		if _, err := c.ReconstructBytes(answers); err != nil {
+			log.Fatal(err)
+		}
+		mp.reconstruct()
+
+		return res
+	})
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/si-co/vpir-code/lib/client"
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
 	"github.com/si-co/vpir-code/lib/proto"
 	"github.com/si-co/vpir-code/lib/query"
 	"github.com/si-co/vpir-code/lib/utils"
@@ -34,7 +35,7 @@ type localClient struct {
 	callOptions []grpc.CallOption
 	connections map[string]*grpc.ClientConn
 
-	prg        *utils.PRGReader
+	prg        utils.PRG
 	config     *utils.Config
 	flags      *flags
 	dbInfo     *database.Info
@@ -54,6 +55,9 @@ type flags struct {
 
 	// flags for complex queries
 	inputSize int
+
+	// overrides of the config
+	config *utils.ConfigFlags
 }
 
 func parseFlags() *flags {
@@ -73,6 +77,9 @@ func parseFlags() *flags {
 	// flag for complex queries
 	flag.IntVar(&f.inputSize, "inputSize", -1, "input of string to search of")
 
+	// config flags, overriding the config file and the environment
+	f.config = utils.RegisterConfigFlags(flag.CommandLine)
+
 	flag.Parse()
 
 	return f
@@ -97,9 +104,12 @@ func newLocalClient() *localClient {
 		configPath = defaultConfigFile
 	}
 
-	config, err := utils.LoadConfig(configPath)
+	config, err := utils.LoadLayeredConfig(configPath, lc.flags.config)
 	if err != nil {
-		log.Fatalf("could not load the config file: %v", err)
+		log.Fatalf("could not load the config: %v", err)
+	}
+	if err := fss.SetPrfKeysFromConfig(config); err != nil {
+		log.Fatalf("could not set the FSS keys: %v", err)
 	}
 	lc.config = config
 
@@ -143,6 +153,9 @@ func (lc *localClient) exec() (string, error) {
 		// get and store db info.
 		lc.vpirClient = client.NewPIR(lc.prg, lc.dbInfo)
 		lc.retrievePointPIR()
+	case "pir-dpf":
+		lc.vpirClient = client.NewPIRDPF(lc.prg, lc.dbInfo)
+		lc.retrievePointPIR()
 	case "fss-classic":
 		lc.vpirClient = client.NewPredicatePIR(lc.prg, lc.dbInfo)
 		lc.retrieveComplexPIR()
@@ -321,11 +334,12 @@ func dbInfo(ctx context.Context, conn *grpc.ClientConn, opts []grpc.CallOption)
 	log.Printf("sent databaseInfo request to %s", conn.Target())
 
 	dbInfo := &database.Info{
-		NumRows:    int(answer.GetNumRows()),
-		NumColumns: int(answer.GetNumColumns()),
-		BlockSize:  int(answer.GetBlockLength()),
-		PIRType:    answer.GetPirType(),
-		Merkle:     &database.Merkle{Root: answer.GetRoot(), ProofLen: int(answer.GetProofLen())},
+		NumRows:      int(answer.GetNumRows()),
+		NumColumns:   int(answer.GetNumColumns()),
+		BlockSize:    int(answer.GetBlockLength()),
+		PIRType:      answer.GetPirType(),
+		HashTableLen: int(answer.GetHashTableLen()),
+		Merkle:       &database.Merkle{Root: answer.GetRoot(), ProofLen: int(answer.GetProofLen())},
 	}
 
 	return dbInfo
@@ -6,7 +6,6 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"net"
 	"net/http"
 	"os"
@@ -15,6 +14,7 @@ import (
 	"syscall"
 
 	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/fss"
 	"github.com/si-co/vpir-code/lib/proto"
 	"github.com/si-co/vpir-code/lib/server"
 	"github.com/si-co/vpir-code/lib/utils"
@@ -34,11 +34,12 @@ const (
 func main() {
 	sid := readServerID()
 	logFile := flag.String("logFile", "", "write log to file instead of stdout/stderr")
-	scheme := flag.String("scheme", "", "scheme to use: pir-classic, pir-merkle")
+	scheme := flag.String("scheme", "", "scheme to use: pir-classic, pir-merkle, pir-dpf")
 	elemBitSize := flag.Int("elemBitSize", -1, "bit size of element, in which block lengtht is specified")
 	dbLen := flag.Int("dbLen", -1, "DB length in bits")
 	nRows := flag.Int("nRows", -1, "number of rows in the DB representation")
 	blockLen := flag.Int("blockLen", -1, "block size for DB")
+	configFlags := utils.RegisterConfigFlags(flag.CommandLine)
 
 	flag.Parse()
 
@@ -62,9 +63,12 @@ func main() {
 		configPath = defaultConfigFile
 	}
 
-	config, err := utils.LoadConfig(configPath)
+	config, err := utils.LoadLayeredConfig(configPath, configFlags)
 	if err != nil {
-		log.Fatalf("could not load the server config file: %v", err)
+		log.Fatalf("could not load the server config: %v", err)
+	}
+	if err := fss.SetPrfKeysFromConfig(config); err != nil {
+		log.Fatalf("could not set the FSS keys: %v", err)
 	}
 	addr := config.Addresses[sid]
 
@@ -91,19 +95,27 @@ func main() {
 	// Find the total number of blocks in the db
 	numBlocks := *dbLen
 	if (*scheme)[:3] != "cmp" {
-		numBlocks = *dbLen / ((*elemBitSize) * (*blockLen))
+		blockBits, err := utils.CheckedMul(*elemBitSize, *blockLen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if numBlocks, err = utils.CeilDiv(*dbLen, blockBits); err != nil {
+			log.Fatal(err)
+		}
 	}
 	// matrix db
 	if *nRows != 1 {
-		utils.IncreaseToNextSquare(&numBlocks)
-		*nRows = int(math.Sqrt(float64(numBlocks)))
+		var err error
+		if *nRows, err = utils.SquareRows(numBlocks); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// initialize db
 	var dbBytes *database.Bytes
 	var dbFSS *database.DB
 	switch *scheme {
-	case "pir-classic":
+	case "pir-classic", "pir-dpf":
 		dbBytes = database.CreateRandomBytes(dbPRG, *dbLen, *nRows, *blockLen)
 	case "pir-merkle":
 		dbBytes = database.CreateRandomMerkle(dbPRG, *dbLen, *nRows, *blockLen)
@@ -125,6 +137,8 @@ func main() {
 	switch *scheme {
 	case "pir-classic", "pir-merkle":
 		s = server.NewPIR(dbBytes)
+	case "pir-dpf":
+		s = server.NewPIRDPF(dbBytes)
 	case "fss-classic":
 		s = server.NewPredicatePIR(dbFSS, byte(sid))
 	case "fss-auth":
@@ -198,12 +212,13 @@ func (s *vpirServer) DatabaseInfo(ctx context.Context, r *proto.DatabaseInfoRequ
 	}
 
 	resp := &proto.DatabaseInfoResponse{
-		NumRows:     uint32(dbInfo.NumRows),
-		NumColumns:  uint32(dbInfo.NumColumns),
-		BlockLength: uint32(dbInfo.BlockSize),
-		PirType:     dbInfo.PIRType,
-		Root:        dbInfo.Root,
-		ProofLen:    uint32(dbInfo.ProofLen),
+		NumRows:      uint32(dbInfo.NumRows),
+		NumColumns:   uint32(dbInfo.NumColumns),
+		BlockLength:  uint32(dbInfo.BlockSize),
+		PirType:      dbInfo.PIRType,
+		Root:         dbInfo.Root,
+		ProofLen:     uint32(dbInfo.ProofLen),
+		HashTableLen: uint32(dbInfo.HashTableLen),
 	}
 
 	return resp, nil
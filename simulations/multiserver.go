@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"math/rand"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// SweepPoint holds the results of a multi-server scheme for one number of
+// servers and collusion threshold
+type SweepPoint struct {
+	NumServers int
+	Threshold  int // maximum number of colluding servers
+	Results    map[int][]*Chunk
+
+	// detection of the corrupted answers, by db length
+	Detection map[int]*Detection `json:",omitempty"`
+
+	// bytes counted by the clients and servers, by db length
+	Traffic map[int]*monitor.Bandwidth `json:",omitempty"`
+}
+
+// multiServer returns true if the primitive runs with several servers, so
+// that the simulation sweeps over NumServers and Thresholds
+func (s *Simulation) multiServer() bool {
+	return s.Primitive == "pir-classic" || s.Primitive == "pir-merkle"
+}
+
+// sweepPoints returns the points of the experiment for all the (number of
+// servers, threshold) pairs of the sweep. The schemes in this tree share the
+// query additively among all the servers, i.e., they tolerate up to n-1
+// colluding servers: other thresholds are skipped until a t-of-n sharing is
+// available.
+func (s *Simulation) sweepPoints(cp *checkpoint) []*SweepPoint {
+	points := make([]*SweepPoint, 0)
+	for _, n := range s.NumServers {
+		if len(s.Thresholds) == 0 {
+			points = append(points, cp.point(n, n-1))
+			continue
+		}
+		for _, t := range s.Thresholds {
+			if t != n-1 {
+				log.Printf("skipping threshold %d for %d servers, only n-1 is supported", t, n)
+				continue
+			}
+			points = append(points, cp.point(n, t))
+		}
+	}
+	return points
+}
+
+// sweepCompleted returns true if all the repetitions of all the points are
+// completed for the given db length
+func sweepCompleted(points []*SweepPoint, dbLen, nRepeat int) bool {
+	for _, p := range points {
+		if !completed(repetitions(p.Results, dbLen, nRepeat)) {
+			return false
+		}
+	}
+	return true
+}
+
+// pirMultiServer runs the classical PIR, with or without Merkle proofs
+// depending on the database, with numServers servers. If forged is not nil,
+// the corrupted repetitions are answered by all the servers from the forged
+// db, whose info the client checks against the digest it holds.
+func pirMultiServer(db, forged *database.Bytes, numServers int, r *runner, results []*Chunk) {
+	numRetrievedBlocks := 1
+	previous := new(replayer)
+	var held client.HeldDigest
+	if forged != nil {
+		digest, err := database.ClientDigest(&db.Info)
+		if err != nil {
+			log.Fatal(err)
+		}
+		held = digest
+	}
+
+	r.run(results, func(j int) *Chunk {
+		res := initChunk(numRetrievedBlocks)
+
+		// a malicious majority serves the forged db, detected by the client
+		// before querying it. The query is still run, to measure the
+		// repetition as the others.
+		served := db
+		if forged != nil && r.corruption.corrupt() {
+			served, res.Corrupted = forged, true
+		}
+		if held != nil {
+			if err := held.Pin(&served.Info); err != nil {
+				res.Detected = checkRejection(res, err)
+			}
+		}
+
+		// every repetition has its own client and servers
+		c := client.NewPIR(utils.RandomPRG(), &served.Info)
+		servers := make([]*server.PIR, numServers)
+		for k := range servers {
+			servers[k] = server.NewPIR(served)
+		}
+
+		// the digest is the Merkle root, if any
+		res.Digest = float64(len(db.Root))
+
+		// pick a random block index to retrieve
+		in := make([]byte, 4)
+		binary.BigEndian.PutUint32(in, uint32(rand.Intn(db.NumRows*db.NumColumns)))
+		res.Bandwidth[0] = initBlock(numServers)
+
+		mp := r.newPhases(res, 0, numServers)
+		queries, err := c.QueryBytes(in, numServers)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.query()
+
+		answers := make([][]byte, numServers)
+		for k, s := range servers {
+			mp.skip()
+			answers[k], err = s.AnswerBytes(queries[k])
+			if err != nil {
+				log.Fatal(err)
+			}
+			mp.answer(k)
+			res.Bandwidth[0].Query += float64(len(queries[k]))
+			res.Bandwidth[0].Answers[k] = float64(len(answers[k]))
+		}
+
+		// without forging, only the first server is corrupted
+		var prev []byte
+		if r.replaying() {
+			prev = previous.swap(append([]byte(nil), answers[0]...))
+		}
+		switch {
+		case r.forging() || !r.corruption.corrupt():
+		case r.corruption.Mode == corruptBitFlip:
+			flipBit(answers[0])
+			res.Corrupted = true
+		case prev != nil:
+			answers[0], res.Corrupted = prev, true
+		}
+		mp.skip()
+		if _, err := c.ReconstructBytes(answers); err != nil {
+			res.Detected = checkRejection(res, err)
+		}
+		mp.reconstruct()
+
+		return res
+	})
+}
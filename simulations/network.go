@@ -0,0 +1,73 @@
+package main
+
+import "time"
+
+// Network models the links between the client and the servers. Transfer
+// times are computed from the byte counts of each phase rather than slept,
+// so that the CPU measurements are not affected.
+type Network struct {
+	RTTs         []float64 // round-trip time to each server, in milliseconds
+	UploadMbps   float64   // client upload throughput, zero for unlimited
+	DownloadMbps float64   // client download throughput, zero for unlimited
+}
+
+// rtt returns the round-trip time to server i. If fewer RTTs than servers
+// are given, the last one is used for the remaining servers.
+func (n *Network) rtt(i int) time.Duration {
+	if len(n.RTTs) == 0 {
+		return 0
+	}
+	if i >= len(n.RTTs) {
+		i = len(n.RTTs) - 1
+	}
+	return time.Duration(n.RTTs[i] * float64(time.Millisecond))
+}
+
+// transferTime returns the time in seconds to send numBytes over a link with
+// the given throughput in Mbit/s and one-way latency
+func transferTime(numBytes, mbps float64, latency time.Duration) float64 {
+	t := latency.Seconds()
+	if mbps > 0 {
+		t += numBytes * 8 / (mbps * 1e6)
+	}
+	return t
+}
+
+// apply computes the network times of every retrieved block of the chunk from
+// its bandwidth measurements, and the resulting end-to-end time. The query to
+// every server is sent in parallel, so the slowest server determines the time
+// of each phase.
+func (n *Network) apply(c *Chunk) {
+	c.Network = make([]*Block, len(c.Bandwidth))
+	c.EndToEnd = 0
+	for b, bw := range c.Bandwidth {
+		nb := initBlock(len(bw.Answers))
+		slowest := 0.0
+		for i, a := range bw.Answers {
+			// the query bandwidth sums the queries to all the servers
+			up := transferTime(bw.Query/float64(len(bw.Answers)), n.UploadMbps, n.rtt(i)/2)
+			down := transferTime(a, n.DownloadMbps, n.rtt(i)/2)
+			if up > nb.Query {
+				nb.Query = up
+			}
+			nb.Answers[i] = down
+			if up+down > slowest {
+				slowest = up + down
+			}
+		}
+		c.Network[b] = nb
+
+		wall := c.Wall[b]
+		c.EndToEnd += wall.Query + maxFloat(wall.Answers) + wall.Reconstruct + slowest
+	}
+}
+
+func maxFloat(in []float64) float64 {
+	m := 0.0
+	for _, v := range in {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// overrides are the config values given on the command line as Key=Value,
+// where Value uses the TOML syntax and the quotes of strings can be omitted.
+// Fields of tables are set with Table.Key=Value.
+type overrides []string
+
+func (o *overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *overrides) Set(value string) error {
+	*o = append(*o, value)
+	return nil
+}
+
+// apply sets the overridden values in the simulation config
+func (o overrides) apply(s *Simulation) error {
+	for _, kv := range o {
+		if err := s.override(kv); err != nil {
+			return fmt.Errorf("invalid override %q: %v", kv, err)
+		}
+	}
+	return nil
+}
+
+// override sets a single Key=Value in the general or individual config
+func (s *Simulation) override(kv string) error {
+	i := strings.Index(kv, "=")
+	if i < 0 {
+		return fmt.Errorf("missing =")
+	}
+	key, value := strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:])
+	path := strings.Split(key, ".")
+	doc := ""
+	if len(path) > 1 {
+		doc = "[" + strings.Join(path[:len(path)-1], ".") + "]\n"
+	}
+	doc += path[len(path)-1] + " = "
+	// unquoted strings are not valid TOML values
+	if _, err := toml.Decode("v = "+value, new(struct{ V interface{} })); err != nil {
+		value = strconv.Quote(value)
+	}
+	doc += value
+
+	for _, conf := range []interface{}{&s.generalParam, &s.individualParam} {
+		md, err := toml.Decode(doc, conf)
+		if err != nil {
+			return err
+		}
+		if len(md.Undecoded()) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown field %s", key)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// Memory is the memory usage of the phases of a retrieval, in bytes
+type Memory struct {
+	Alloc   *Block // allocated during each phase
+	PeakRSS *Block // peak resident set size of the process during each phase
+}
+
+// phases measures the phases of one retrieval: CPU and wall-clock time in
+// seconds and, if enabled, memory usage and energy
+type phases struct {
+	m         *monitor.Monitor
+	cpu, wall *Block
+	mem       *Memory // nil if memory is not measured
+	energy    *Block  // nil if energy is not measured
+	e         *monitor.EnergyMonitor
+	answers   []*monitor.AnswerBreakdown // nil if the answer phases are not timed
+}
+
+// newPhases initializes the measurements of the b-th retrieved block of the
+// chunk and starts measuring
+func (r *runner) newPhases(c *Chunk, b, numAnswers int) *phases {
+	c.CPU[b] = initBlock(numAnswers)
+	c.Wall[b] = initBlock(numAnswers)
+	p := &phases{cpu: c.CPU[b], wall: c.Wall[b]}
+	p.m = r.newMonitor()
+	if r.memStats && b == 0 {
+		c.Memory = &Memory{Alloc: initBlock(numAnswers), PeakRSS: initBlock(numAnswers)}
+		p.mem = c.Memory
+		p.m.TrackMemory(monitor.DefaultSamplingInterval)
+	}
+	// the phases of concurrent answers cannot be told apart
+	if r.parallel <= 1 && b == 0 {
+		if r.energy != nil {
+			c.Energy = initBlock(numAnswers)
+			p.energy, p.e = c.Energy, r.energy
+			p.e.Reset()
+		}
+		c.AnswerPhases = make([]*monitor.AnswerBreakdown, numAnswers)
+		p.answers = c.AnswerPhases
+		monitor.ResetAnswerPhases()
+	}
+
+	return p
+}
+
+// record returns the CPU time, wall-clock time, allocated memory and peak RSS
+// since the last call
+func (p *phases) record() (cpu, wall, alloc, rss float64) {
+	alloc, rss = p.m.RecordAllocs(), p.m.RecordPeakRSS()
+	cpu, wall = p.m.RecordAndResetAll()
+	// the monitor measures milliseconds
+	return cpu / 1000, wall / 1000, alloc, rss
+}
+
+// skip resets the measurements, excluding the time since the last call from
+// all the phases
+func (p *phases) skip() {
+	p.m.Reset()
+	if p.energy != nil {
+		p.e.Reset()
+	}
+	if p.answers != nil {
+		monitor.ResetAnswerPhases()
+	}
+}
+
+func (p *phases) query() {
+	cpu, wall, alloc, rss := p.record()
+	p.cpu.Query, p.wall.Query = cpu, wall
+	if p.energy != nil {
+		p.energy.Query = p.e.RecordAndReset()
+	}
+	if p.mem != nil {
+		p.mem.Alloc.Query, p.mem.PeakRSS.Query = alloc, rss
+	}
+}
+
+func (p *phases) answer(i int) {
+	cpu, wall, alloc, rss := p.record()
+	p.cpu.Answers[i], p.wall.Answers[i] = cpu, wall
+	if p.energy != nil {
+		p.energy.Answers[i] = p.e.RecordAndReset()
+	}
+	if p.mem != nil {
+		p.mem.Alloc.Answers[i], p.mem.PeakRSS.Answers[i] = alloc, rss
+	}
+	if p.answers != nil {
+		b := monitor.ResetAnswerPhases()
+		p.answers[i] = &b
+	}
+}
+
+// reconstruct records the last phase and stops the measurements
+func (p *phases) reconstruct() {
+	cpu, wall, alloc, rss := p.record()
+	p.m.StopTracking()
+	p.cpu.Reconstruct, p.wall.Reconstruct = cpu, wall
+	if p.energy != nil {
+		p.energy.Reconstruct = p.e.RecordAndReset()
+	}
+	if p.mem != nil {
+		p.mem.Alloc.Reconstruct, p.mem.PeakRSS.Reconstruct = alloc, rss
+	}
+}
@@ -8,13 +8,9 @@ import (
 
 	"github.com/si-co/vpir-code/lib/database"
 	"github.com/si-co/vpir-code/lib/merkle"
-	"github.com/si-co/vpir-code/lib/monitor"
 )
 
-func RandomMerkleDB(rnd io.Reader, dbLen, numRows, blockLen, nRepeat int) []*Chunk {
-	// run the experiment nRepeat times
-	results := make([]*Chunk, nRepeat)
-
+func RandomMerkleDB(rnd io.Reader, dbLen, numRows, blockLen int, r *runner, results []*Chunk) {
 	// generate random data only once
 	numBlocks := dbLen / (8 * blockLen)
 	// generate random numBlocks blocks
@@ -33,14 +29,9 @@ func RandomMerkleDB(rnd io.Reader, dbLen, numRows, blockLen, nRepeat int) []*Chu
 	// clean memory since data is not needed anymore
 	runtime.GC()
 
-	m := monitor.NewMonitor()
-
-	for j := 0; j < nRepeat; j++ {
-		log.Printf("start repetition %d out of %d", j+1, nRepeat)
-		results[j] = initChunk(1)
-		results[j].CPU[0] = initBlock(1)
-
-		m.Reset()
+	r.run(results, func(j int) *Chunk {
+		res := initChunk(1)
+		mp := r.newPhases(res, 0, 1)
 
 		// generate tree
 		tree, err := merkle.New(blocks)
@@ -52,25 +43,24 @@ func RandomMerkleDB(rnd io.Reader, dbLen, numRows, blockLen, nRepeat int) []*Chu
 		numColumns := numBlocks / numRows
 		proofLen := tree.EncodedProofLength()
 		// +1 is for storing the padding signal byte
-		blockLen = blockLen + proofLen + 1
+		entryLen := blockLen + proofLen + 1
 		blockLens := make([]int, numRows*numColumns)
 		for b := 0; b < numRows*numColumns; b++ {
-			blockLens[b] = blockLen
+			blockLens[b] = entryLen
 		}
 
-		_ = generateMerkleProofs(blocks, tree, blockLen)
-
-		results[j].CPU[0].Answers[0] = m.RecordAndReset()
+		_ = generateMerkleProofs(blocks, tree, entryLen)
 
-		// GC after each repetition
-		runtime.GC()
+		// the preprocessing is done by the server
+		mp.answer(0)
 
 		// sleep after every iteration
-		time.Sleep(2 * time.Second)
-	}
-
-	return results
+		if r.parallel <= 1 {
+			time.Sleep(2 * time.Second)
+		}
 
+		return res
+	})
 }
 
 func generateMerkleProofs(data [][]byte, t *merkle.MerkleTree, blockLen int) []byte {
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"runtime/trace"
+	"sync"
+
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// runner executes the repetitions of an experiment, either one after the
+// other or concurrently on several goroutines, and saves the results after
+// each of them.
+type runner struct {
+	parallel   int // number of repetitions run concurrently
+	warmUp     int // number of repetitions run before the measured ones
+	save       func()
+	corruption *Corruption // nil if the server is honest
+	memStats   bool        // measure the memory usage of each phase
+	// measures the energy of each phase, nil if not measured
+	energy *monitor.EnergyMonitor
+
+	mu sync.Mutex // protects the results and the checkpoint
+}
+
+// run calls rep for every repetition without a result, after the warm-up
+// repetitions whose results are discarded (j is -1 for them). The bandwidth
+// counters of the monitor are reset after the warm-up. Concurrent
+// repetitions are locked to their own OS thread, so that rep can measure its
+// CPU time with a thread monitor.
+func (r *runner) run(results []*Chunk, rep func(j int) *Chunk) {
+	nRepeat := len(results)
+	if completed(results) {
+		return
+	}
+	rep = traced(rep)
+
+	// warm up the caches and the allocator, discarding the results
+	for w := 0; w < r.warmUp; w++ {
+		log.Printf("start warm-up repetition %d out of %d", w+1, r.warmUp)
+		rep(-1)
+	}
+	monitor.ResetBandwidth()
+	runtime.GC()
+
+	if r.parallel <= 1 {
+		for j := range results {
+			if results[j] != nil {
+				continue // completed in a previous run
+			}
+			log.Printf("start repetition %d out of %d", j+1, nRepeat)
+			results[j] = rep(j)
+			r.save()
+
+			// GC after each repetition
+			runtime.GC()
+		}
+		return
+	}
+
+	todo := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < r.parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			for j := range todo {
+				log.Printf("start repetition %d out of %d", j+1, nRepeat)
+				res := rep(j)
+				r.mu.Lock()
+				results[j] = res
+				r.save()
+				r.mu.Unlock()
+			}
+		}()
+	}
+	for j := range results {
+		if results[j] == nil {
+			todo <- j
+		}
+	}
+	close(todo)
+	wg.Wait()
+}
+
+// traced runs every repetition as a task of the execution trace, if tracing
+func traced(rep func(j int) *Chunk) func(j int) *Chunk {
+	return func(j int) *Chunk {
+		name := "repetition"
+		if j < 0 {
+			name = "warm-up"
+		}
+		_, task := trace.NewTask(context.Background(), name)
+		defer task.End()
+		return rep(j)
+	}
+}
+
+// replaying returns true if the server replays previous answers
+func (r *runner) replaying() bool {
+	return r.corruption != nil && r.corruption.Mode == corruptReplay
+}
+
+// forging returns true if all the servers answer from a forged db
+func (r *runner) forging() bool {
+	return r.corruption != nil && r.corruption.Mode == corruptForge
+}
+
+// newMonitor returns the monitor to use in a repetition: a thread monitor when
+// repetitions run concurrently
+func (r *runner) newMonitor() *monitor.Monitor {
+	if r.parallel > 1 {
+		return monitor.NewThreadMonitor()
+	}
+	return monitor.NewMonitor()
+}
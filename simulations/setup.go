@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/merkle"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// Setup holds the one-time costs of an experiment, to be amortized over the
+// queries
+type Setup struct {
+	// time in seconds to create the database, including its authentication
+	DBCPU  float64
+	DBWall float64
+
+	// time in seconds to compute the authentication of the database alone,
+	// i.e., the digest or the Merkle tree. Zero if it cannot be computed
+	// separately from the creation of the database.
+	DigestCPU  float64 `json:",omitempty"`
+	DigestWall float64 `json:",omitempty"`
+
+	// memory usage of the database creation, only set when measured
+	DBAlloc   float64 `json:",omitempty"` // bytes allocated to create the database
+	DBPeakRSS float64 `json:",omitempty"` // peak resident set size after creating the database
+}
+
+// measureSetup returns the CPU and wall-clock time in seconds of compute
+func measureSetup(compute func()) (cpu, wall float64) {
+	m := monitor.NewMonitor()
+	compute()
+	cpu, wall = m.RecordAndResetAll()
+	// the monitor measures milliseconds
+	return cpu / 1000, wall / 1000
+}
+
+// merkleLeaves returns the blocks of a db with Merkle proofs, without the
+// proofs
+func merkleLeaves(db *database.Bytes) [][]byte {
+	leaves := make([][]byte, len(db.BlockLengths))
+	begin := 0
+	for i, l := range db.BlockLengths {
+		// the proof is followed by the padding signal byte
+		leaves[i] = db.Entries[begin : begin+l-db.ProofLen-1]
+		begin += l
+	}
+	return leaves
+}
+
+// measureMerkleTree returns the time to build the Merkle tree of db
+func measureMerkleTree(db *database.Bytes) (cpu, wall float64) {
+	leaves := merkleLeaves(db)
+	return measureSetup(func() {
+		if _, err := merkle.New(leaves); err != nil {
+			log.Fatalf("impossible to create Merkle tree: %v", err)
+		}
+	})
+}
@@ -1,24 +1,25 @@
 package main
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"flag"
-	"io/ioutil"
 	"log"
-	"math"
 	"math/rand"
 	"os"
 	"path"
 	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
 	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/cloudflare/circl/group"
+	"github.com/si-co/vpir-code/lib/bench"
 	"github.com/si-co/vpir-code/lib/client"
 	"github.com/si-co/vpir-code/lib/database"
-	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/monitor"
 	"github.com/si-co/vpir-code/lib/server"
 	"github.com/si-co/vpir-code/lib/utils"
 )
@@ -29,16 +30,39 @@ type generalParam struct {
 	DBBitLengths   []int
 	BitsToRetrieve int
 	Repetitions    int
+
+	// seed of all the randomness of the simulation, random if zero
+	Seed int64
+	// known entries retrieved and checked before the measurements, 5 if
+	// zero, negative to disable the validation
+	Validation int
+	// repetitions run before the measured ones and excluded from the results
+	WarmUp int
+	// reject from the summary statistics the values further from the median
+	// than this many scaled median absolute deviations, zero to keep all
+	OutlierThreshold float64
 }
 
 type individualParam struct {
 	Name           string
 	Primitive      string
 	NumServers     []int
+	Thresholds     []int // collusion thresholds of the multi-server schemes, n-1 if empty
 	NumRows        int
 	BlockLength    int
 	ElementBitSize int
 	InputSizes     []int // FSS input sizes in bytes
+	TECC           int   // repetitions of the integrity amplification, zero for the tuned values
+
+	// optional real dataset replacing the random database, only for the
+	// multi-server schemes
+	Dataset *Dataset
+	// gRPC servers, only for the grpc primitive
+	GRPC *GRPC
+	// optional network emulation
+	Network *Network
+	// optional corruption of the answers by the server
+	Corruption *Corruption
 }
 
 type Simulation struct {
@@ -47,9 +71,6 @@ type Simulation struct {
 }
 
 func main() {
-	// seed non-cryptographic randomness
-	rand.Seed(time.Now().UnixNano())
-
 	// create results directory if not presenc
 	folderPath := "results"
 	if _, err := os.Stat(folderPath); errors.Is(err, os.ErrNotExist) {
@@ -61,7 +82,15 @@ func main() {
 
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
 	memprofile := flag.String("memprofile", "", "write mem profile to file")
+	traceFile := flag.String("trace", "", "write an execution trace to file")
 	indivConfigFile := flag.String("config", "", "config file for simulation")
+	resume := flag.Bool("resume", false, "skip the repetitions already stored in the results file")
+	parallel := flag.Int("parallel", 1, "number of repetitions run concurrently")
+	memStats := flag.Bool("memstats", false, "measure the memory usage of each phase")
+	energy := flag.Bool("energy", false, "measure the energy consumed by each phase with RAPL, requires -parallel=1")
+	var set overrides
+	flag.Var(&set, "set", "override a config value, e.g., -set BlockLength=32, can be repeated")
+	clients := flag.Int("clients", 0, "if positive, measure the throughput of this many concurrent clients, each running Repetitions queries")
 	flag.Parse()
 
 	// CPU profiling
@@ -77,6 +106,20 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	// execution trace, with a task per repetition and the phases of the
+	// protocol as regions
+	if *traceFile != "" {
+		f, err := os.Create(*traceFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := trace.Start(f); err != nil {
+			log.Fatal(err)
+		}
+		defer trace.Stop()
+	}
+
 	// make sure cfg file is specified
 	if *indivConfigFile == "" {
 		panic("simulation's config file not provided")
@@ -88,25 +131,85 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := set.apply(s); err != nil {
+		log.Fatal(err)
+	}
 	// check simulation
 	if !s.validSimulation() {
 		log.Fatal("invalid simulation")
 	}
 
+	// seed the non-cryptographic randomness and the PRGs. The seed is stored
+	// with the results, so that an unseeded simulation can be regenerated too
+	if s.Seed == 0 {
+		s.Seed = time.Now().UnixNano()
+	}
+	rand.Seed(s.Seed)
+	utils.SeedPRGs(s.Seed)
+	log.Printf("seed %d", s.Seed)
+
 	log.Printf("running simulation %#v\n", s)
-	// initialize experiment
-	experiment := &Experiment{Results: make(map[int][]*Chunk, 0)}
+	// initialize experiment, results are saved after every repetition
+	cp, err := newCheckpoint(path.Join("results", s.Name+".json"), *resume)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// record the effective config, including the overrides
+	cp.experiment.Config = s
+	cp.experiment.Metadata = bench.NewMetadata()
+	save := func() {
+		if err := cp.save(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// count the bytes of the queries and answers marshaled by the schemes
+	monitor.EnableBandwidth(true)
+	// break down the answer time, if the answers are not concurrent
+	monitor.EnableAnswerPhases(*parallel <= 1)
+	// credit the CPU time of parallel answers to the thread monitors
+	monitor.EnableWorkerCPU(*parallel > 1)
+	r := &runner{parallel: *parallel, warmUp: s.WarmUp, save: save, corruption: s.Corruption, memStats: *memStats}
+	if *energy {
+		// the counters cover the whole CPU packages
+		if *parallel > 1 {
+			log.Fatal("the energy of concurrent repetitions cannot be told apart, use -parallel=1")
+		}
+		if r.energy, err = monitor.NewEnergyMonitor(); err != nil {
+			log.Fatalf("cannot measure the energy: %v", err)
+		}
+	}
+
+	// amplification parameters, shared with the benchmarks
+	tECC := bench.TunedTECC
 
-	// amplification parameters (found via script in /scripts/integrity_amplification.py)
-	// KiB, MiB, GiB
-	tECC := map[int]int{
-		1 << 13: 3,
-		1 << 23: 4,
-		1 << 33: 7,
+	// multi-server schemes are run for every number of servers and threshold
+	var points []*SweepPoint
+	if s.multiServer() {
+		points = s.sweepPoints(cp)
+	}
+
+	// a real dataset has a single db length
+	dbLens := s.DBBitLengths
+	var realDB *database.Bytes
+	var loadCPU, loadWall float64
+	if s.Dataset != nil {
+		loadCPU, loadWall = measureSetup(func() {
+			realDB, err = s.Dataset.load(s.Primitive == "pir-merkle")
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		dbLens = []int{8 * len(realDB.Entries)}
+	}
+
+	// the gRPC servers load their own db
+	if s.Primitive == "grpc" {
+		s.runGRPC(cp, r)
+		dbLens = nil
 	}
 
 	// range over all the DB lengths specified in the general simulation config
-	for _, dl := range s.DBBitLengths {
+	for _, dl := range dbLens {
 		// compute database data
 		dbLen := dl
 		blockLen := s.BlockLength
@@ -118,17 +221,34 @@ func main() {
 			continue
 		}
 
+		var results []*Chunk
+		if s.multiServer() {
+			if sweepCompleted(points, dbLen, s.Repetitions) {
+				log.Printf("skipping %d db, all repetitions completed", dbLen)
+				continue
+			}
+		} else if results = cp.results(dbLen, s.Repetitions); *clients > 0 && cp.experiment.Throughput[dbLen] != nil ||
+			*clients <= 0 && completed(results) {
+			log.Printf("skipping %d db, all repetitions completed", dbLen)
+			continue
+		}
+
 		// matrix db
 		if nRows != 1 {
-			utils.IncreaseToNextSquare(&numBlocks)
-			nRows = int(math.Sqrt(float64(numBlocks)))
+			if nRows, err = utils.SquareRows(numBlocks); err != nil {
+				log.Fatal(err)
+			}
 		}
 
 		// setup db
+		memDB := monitor.NewMemMonitor()
+		mDB := monitor.NewMonitor()
 		dbPRG := utils.RandomPRG()
 		dbElliptic := new(database.Elliptic)
 		dbLWE := new(database.LWE)
 		dbLWE128 := new(database.LWE128)
+		dbSimple := new(database.SimplePIR)
+		dbBytes := new(database.Bytes)
 		switch s.Primitive[:3] {
 		case "cmp":
 			if s.Primitive == "cmp-vpir-dh" {
@@ -140,51 +260,222 @@ func main() {
 			} else if s.Primitive == "cmp-vpir-lwe-128" {
 				log.Printf("Generating LWE128 db of size %d\n", dbLen)
 				dbLWE128 = database.CreateRandomBinaryLWEWithLength128(dbPRG, dbLen)
+			} else if s.Primitive == "cmp-pir-simple" {
+				log.Printf("Generating SimplePIR db of size %d\n", dbLen)
+				dbSimple = database.CreateRandomSimplePIR(dbPRG, dbLen)
 			} else {
 				log.Fatal("unknow primitive type:", s.Primitive)
 			}
+		case "amp":
+			log.Printf("Generating LWE db of size %d\n", dbLen)
+			dbLWE = database.CreateRandomBinaryLWEWithLength(dbPRG, dbLen)
+		case "bas":
+			dbBytes = s.baselineDB(dbLen, realDB)
+		case "pir":
+			if realDB != nil {
+				dbBytes = realDB
+				break
+			}
+			// blocks of BlockLength bytes
+			if numBlocks, err = utils.NumBlocks(dbLen, blockLen); err != nil {
+				log.Fatal(err)
+			}
+			nRows = s.NumRows
+			if nRows != 1 {
+				if nRows, err = utils.SquareRows(numBlocks); err != nil {
+					log.Fatal(err)
+				}
+			}
+			if s.Primitive == "pir-classic" || s.Primitive == "pir-offline-online" || s.Primitive == "pir-piano" {
+				log.Printf("Generating bytes db of size %d\n", dbLen)
+				dbBytes = database.CreateRandomBytes(dbPRG, dbLen, nRows, blockLen)
+			} else {
+				log.Printf("Generating Merkle db of size %d\n", dbLen)
+				dbBytes = database.CreateRandomMerkle(dbPRG, dbLen, nRows, blockLen)
+			}
+		}
+
+		dbCPU, dbWall := mDB.RecordAndResetAll()
+		setup := &Setup{DBCPU: dbCPU / 1000, DBWall: dbWall / 1000}
+		if realDB != nil {
+			setup.DBCPU, setup.DBWall = loadCPU, loadWall
+		}
+		if *memStats {
+			setup.DBAlloc = memDB.RecordAndReset()
+			setup.DBPeakRSS = monitor.PeakRSS()
+		}
+		// the digests of the DH scheme are computed while creating the db
+		switch s.Primitive {
+		case "cmp-vpir-lwe", "amplify":
+			setup.DigestCPU, setup.DigestWall = measureSetup(func() { database.Digest(dbLWE, dbLWE.NumRows) })
+		case "cmp-vpir-lwe-128":
+			setup.DigestCPU, setup.DigestWall = measureSetup(func() { database.Digest128(dbLWE128, dbLWE128.NumRows) })
+		case "cmp-pir-simple":
+			// the hint plays the role of the digest
+			p := utils.ParamsSimplePIR(dbSimple.NumRows, dbSimple.NumColumns)
+			setup.DigestCPU, setup.DigestWall = measureSetup(func() { dbSimple.Hint(p) })
+		case "pir-merkle":
+			setup.DigestCPU, setup.DigestWall = measureMerkleTree(dbBytes)
+		case "pir-offline-online":
+			// the hints of the offline phase play the role of the digest
+			c := client.NewOfflineOnline(utils.RandomPRG(), &dbBytes.Info, 0)
+			srv := server.NewOfflineOnline(dbBytes)
+			setup.DigestCPU, setup.DigestWall = measureSetup(func() {
+				if _, err := srv.HintsBytes(c.OfflineQuery()); err != nil {
+					log.Fatal(err)
+				}
+			})
+		case "pir-piano":
+			// so does the preprocessing of the db by the client
+			c := client.NewPiano(utils.RandomPRG(), &dbBytes.Info, 0, 0)
+			srv := server.NewPiano(dbBytes)
+			setup.DigestCPU, setup.DigestWall = measureSetup(func() {
+				if err := c.Preprocess(srv.ChunkBytes); err != nil {
+					log.Fatal(err)
+				}
+			})
 		}
+		if cp.experiment.Setup == nil {
+			cp.experiment.Setup = make(map[int]*Setup)
+		}
+		cp.experiment.Setup[dbLen] = setup
 
 		// GC after DB creation
 		runtime.GC()
-		time.Sleep(3)
+
+		// check the correctness of the scheme before the measurements
+		if n := s.numValidation(); n > 0 {
+			log.Printf("validating %d retrievals", n)
+			if err := s.validate(n, dbLen, tECC, points, dbElliptic, dbLWE, dbLWE128, dbSimple, dbBytes); err != nil {
+				log.Fatalf("validation failed for %d db: %v", dbLen, err)
+			}
+		}
+
+		// multi-client mode
+		if *clients > 0 {
+			var newClient newClientFunc
+			switch s.Primitive {
+			case "cmp-vpir-dh":
+				newClient = ellipticClients(dbElliptic)
+			case "cmp-vpir-lwe", "amplify":
+				newClient = amplifyClients(dbLWE, s.amplificationRepetitions(dbLen, tECC))
+			case "cmp-vpir-lwe-128":
+				newClient = lwe128Clients(dbLWE128)
+			default:
+				log.Fatal("multi-client mode not supported for primitive ", s.Primitive)
+			}
+			log.Printf("running %d clients with %d queries each", *clients, s.Repetitions)
+			tp := runThroughput(newClient, *clients, s.Repetitions)
+			log.Printf("%.2f queries/s, latency percentiles 50: %.4fs, 99: %.4fs, 99.9: %.4fs",
+				tp.QueriesPerSec, tp.Percentiles["50"], tp.Percentiles["99"], tp.Percentiles["99.9"])
+			if cp.experiment.Throughput == nil {
+				cp.experiment.Throughput = make(map[int]*Throughput)
+			}
+			cp.experiment.Throughput[dbLen] = tp
+			save()
+			runtime.GC()
+			continue
+		}
+
+		// reference point without privacy, with the same db length
+		if s.Primitive != "baseline" {
+			if cp.experiment.Baseline == nil {
+				cp.experiment.Baseline = make(map[int][]*Chunk)
+			}
+			base := repetitions(cp.experiment.Baseline, dbLen, s.Repetitions)
+			if !completed(base) {
+				log.Printf("running the baseline for %d db", dbLen)
+				plainDownload(s.baselineDB(dbLen, realDB), r, base)
+				runtime.GC()
+			}
+		}
+
+		// sweep over the number of servers and threshold
+		if s.multiServer() {
+			log.Printf("db info: %#v", dbBytes.Info)
+			for _, p := range points {
+				results := repetitions(p.Results, dbLen, s.Repetitions)
+				if completed(results) {
+					continue
+				}
+				log.Printf("running with %d servers, threshold %d", p.NumServers, p.Threshold)
+				var forged *database.Bytes
+				if r.forging() {
+					forged = database.CreateRandomMerkle(utils.RandomPRG(), dbLen, nRows, blockLen)
+				}
+				pirMultiServer(dbBytes, forged, p.NumServers, r, results)
+				p.Traffic = recordTraffic(p.Traffic, dbLen)
+				if s.Network != nil {
+					for _, r := range results {
+						s.Network.apply(r)
+					}
+				}
+				if s.Corruption != nil {
+					if p.Detection == nil {
+						p.Detection = make(map[int]*Detection)
+					}
+					p.Detection[dbLen] = detectionRate(results)
+				}
+				save()
+				runtime.GC()
+			}
+			continue
+		}
 
 		// run experiment
-		var results []*Chunk
 		switch s.Primitive {
 		case "cmp-vpir-dh":
 			log.Printf("db info: %#v", dbElliptic.Info)
-			results = pirElliptic(dbElliptic, s.Repetitions)
-		case "cmp-vpir-lwe": // LWE uses Amplify
+			pirElliptic(dbElliptic, r, results)
+		case "cmp-vpir-lwe", "amplify": // LWE uses Amplify
 			log.Printf("db info: %#v", dbLWE.Info)
-			rep, ok := tECC[dbLen]
-			if !ok {
-				panic("tECC not defined for this db length")
-			}
-			results = pirLWE(dbLWE, s.Repetitions, rep)
+			pirLWE(dbLWE, s.amplificationRepetitions(dbLen, tECC), r, results)
 		case "cmp-vpir-lwe-128":
 			log.Printf("db info: %#v", dbLWE128.Info)
-			results = pirLWE128(dbLWE128, s.Repetitions)
+			pirLWE128(dbLWE128, r, results)
+		case "cmp-pir-simple":
+			log.Printf("db info: %#v", dbSimple.Info)
+			pirSimple(dbSimple, r, results)
+		case "pir-offline-online":
+			log.Printf("db info: %#v", dbBytes.Info)
+			pirOfflineOnline(dbBytes, r, results)
+		case "pir-piano":
+			log.Printf("db info: %#v", dbBytes.Info)
+			pirPiano(dbBytes, r, results)
+		case "baseline":
+			log.Printf("db info: %#v", dbBytes.Info)
+			plainDownload(dbBytes, r, results)
 		case "preprocessing":
 			log.Printf("Merkle preprocessing evaluation for dbLen %d bits\n", dbLen)
-			results = RandomMerkleDB(dbPRG, dbLen, nRows, blockLen, s.Repetitions)
+			RandomMerkleDB(dbPRG, dbLen, nRows, blockLen, r, results)
 		default:
 			log.Fatal("unknown primitive type:", s.Primitive)
 		}
-		experiment.Results[dbLen] = results
+		cp.experiment.Traffic = recordTraffic(cp.experiment.Traffic, dbLen)
+		if s.Network != nil {
+			for _, r := range results {
+				s.Network.apply(r)
+			}
+		}
+		if s.Corruption != nil {
+			d := detectionRate(results)
+			log.Printf("detected %d corrupted answers out of %d", d.Detected, d.Corrupted)
+			if cp.experiment.Detection == nil {
+				cp.experiment.Detection = make(map[int]*Detection)
+			}
+			cp.experiment.Detection[dbLen] = d
+		}
+		save()
 
 		// GC at the end of the iteration
 		runtime.GC()
 	}
 
-	// print results
-	res, err := json.Marshal(experiment)
-	if err != nil {
-		panic(err)
-	}
-	fileName := s.Name + ".json"
-	if err = ioutil.WriteFile(path.Join("results", fileName), res, 0644); err != nil {
-		panic(err)
+	// export raw measurements and summary statistics for the plots
+	csvFile := path.Join("results", s.Name+".csv")
+	summaryFile := path.Join("results", s.Name+"_summary.csv")
+	if err := cp.experiment.writeCSV(csvFile, summaryFile, s.OutlierThreshold); err != nil {
+		log.Fatal(err)
 	}
 
 	// mem profiling
@@ -202,155 +493,392 @@ func main() {
 	log.Println("simulation terminated successfully")
 }
 
-func pirLWE128(db *database.LWE128, nRepeat int) []*Chunk {
+func pirLWE128(db *database.LWE128, r *runner, results []*Chunk) {
 	numRetrievedBlocks := 1
-	results := make([]*Chunk, nRepeat)
-
 	p := utils.ParamsWithDatabaseSize128(db.Info.NumRows, db.Info.NumColumns)
-	c := client.NewLWE128(utils.RandomPRG(), &db.Info, p)
-	s := server.NewLWE128(db)
+	previous := new(replayer)
 
-	for j := 0; j < nRepeat; j++ {
-		log.Printf("start repetition %d out of %d", j+1, nRepeat)
-		results[j] = initChunk(numRetrievedBlocks)
+	r.run(results, func(j int) *Chunk {
+		// every repetition has its own client and server
+		c := client.NewLWE128(utils.RandomPRG(), &db.Info, p)
+		s := server.NewLWE128(db)
+		res := initChunk(numRetrievedBlocks)
 
 		// store digest size
-		results[j].Digest = db.Auth.DigestLWE128.BytesSize()
+		res.Digest = db.Auth.DigestLWE128.BytesSize()
 
 		// pick a random block index to start the retrieval
 		ii := rand.Intn(db.NumRows)
 		jj := rand.Intn(db.NumColumns)
-		results[j].CPU[0] = initBlock(1)
-		results[j].Bandwidth[0] = initBlock(1)
+		res.Bandwidth[0] = initBlock(1)
 
-		t := time.Now()
+		mp := r.newPhases(res, 0, 1)
 
-		query := c.Query(ii, jj)
-		answer := s.Answer(query)
-		if _, err := c.Reconstruct(answer); err != nil {
+		query, err := c.QueryBytes(ii*db.NumColumns + jj)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.query()
+		answer, err := s.AnswerBytes(query)
+		if err != nil {
 			log.Fatal(err)
 		}
+		mp.answer(0)
+		res.Bandwidth[0].Query = float64(len(query))
+		res.Bandwidth[0].Answers[0] = float64(len(answer))
 
-		// store eval results
-		results[j].CPU[0].Reconstruct = time.Since(t).Seconds()
-		results[j].Bandwidth[0].Query = query.BytesSize()
-		results[j].Bandwidth[0].Answers[0] = answer.BytesSize()
+		var prev []byte
+		if r.replaying() {
+			prev = previous.swap(append([]byte(nil), answer...))
+		}
+		switch {
+		case !r.corruption.corrupt():
+		case r.corruption.Mode == corruptBitFlip:
+			// the first bytes encode the dimensions of the matrix
+			flipBit(answer[matrixHeaderLen:])
+			res.Corrupted = true
+		case prev != nil:
+			answer, res.Corrupted = prev, true
+		}
+		mp.skip()
+		if _, err := c.ReconstructBytes(answer); err != nil {
+			res.Detected = checkRejection(res, err)
+		}
+		mp.reconstruct()
 
-		// GC after each repetition
-		runtime.GC()
-		time.Sleep(2)
+		return res
+	})
+}
+
+// pirSimple runs the SimplePIR scheme, whose hint is downloaded once by the
+// client and reported as the digest. The answers are not authenticated, so
+// that the corrupted ones are never detected.
+func pirSimple(db *database.SimplePIR, r *runner, results []*Chunk) {
+	numRetrievedBlocks := 1
+	p := utils.ParamsSimplePIR(db.Info.NumRows, db.Info.NumColumns)
+	s := server.NewSimplePIR(db, p)
+	hint := s.HintBytes()
+	previous := new(replayer)
+
+	r.run(results, func(j int) *Chunk {
+		// every repetition has its own client
+		c, err := client.NewSimplePIR(utils.RandomPRG(), &db.Info, p, hint)
+		if err != nil {
+			log.Fatal(err)
+		}
+		res := initChunk(numRetrievedBlocks)
+
+		// store hint size
+		res.Digest = float64(len(hint))
+
+		// pick a random block index to start the retrieval
+		ii := rand.Intn(db.NumRows)
+		jj := rand.Intn(db.NumColumns)
+		res.Bandwidth[0] = initBlock(1)
+
+		mp := r.newPhases(res, 0, 1)
+
+		query, err := c.QueryBytes(ii*db.NumColumns + jj)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.query()
+		answer, err := s.AnswerBytes(query)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.answer(0)
+		res.Bandwidth[0].Query = float64(len(query))
+		res.Bandwidth[0].Answers[0] = float64(len(answer))
+
+		var prev []byte
+		if r.replaying() {
+			prev = previous.swap(append([]byte(nil), answer...))
+		}
+		switch {
+		case !r.corruption.corrupt():
+		case r.corruption.Mode == corruptBitFlip:
+			// the first bytes encode the dimensions of the matrix
+			flipBit(answer[matrixHeaderLen:])
+			res.Corrupted = true
+		case prev != nil:
+			answer, res.Corrupted = prev, true
+		}
+		mp.skip()
+		if _, err := c.ReconstructBytes(answer); err != nil {
+			res.Detected = checkRejection(res, err)
+		}
+		mp.reconstruct()
+
+		return res
+	})
+}
+
+// pirOfflineOnline runs the two-server offline/online scheme. The hints are
+// streamed once by the first server, reported as the digest, and every
+// repetition starts from a copy of them. Only the answers of the second
+// server, which retrieve the block, are corrupted; they are not
+// authenticated, so that the corruption is never detected.
+func pirOfflineOnline(db *database.Bytes, r *runner, results []*Chunk) {
+	numRetrievedBlocks := 1
+	numServers := 2
+	servers := []*server.OfflineOnline{server.NewOfflineOnline(db), server.NewOfflineOnline(db)}
+	offline := client.NewOfflineOnline(utils.RandomPRG(), &db.Info, 0)
+	hints, err := servers[0].HintsBytes(offline.OfflineQuery())
+	if err != nil {
+		log.Fatal(err)
 	}
+	if err := offline.SetHints(hints); err != nil {
+		log.Fatal(err)
+	}
+	previous := new(replayer)
+
+	r.run(results, func(j int) *Chunk {
+		// every repetition has its own copy of the hints
+		c := offline.Clone()
+		res := initChunk(numRetrievedBlocks)
+
+		// store hints size
+		res.Digest = float64(len(hints))
+
+		// pick a random block index to retrieve
+		in := make([]byte, 4)
+		binary.BigEndian.PutUint32(in, uint32(rand.Intn(db.NumRows*db.NumColumns)))
+		res.Bandwidth[0] = initBlock(numServers)
+
+		mp := r.newPhases(res, 0, numServers)
+		queries, err := c.QueryBytes(in, numServers)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.query()
+
+		answers := make([][]byte, numServers)
+		for k, s := range servers {
+			mp.skip()
+			answers[k], err = s.AnswerBytes(queries[k])
+			if err != nil {
+				log.Fatal(err)
+			}
+			mp.answer(k)
+			res.Bandwidth[0].Query += float64(len(queries[k]))
+			res.Bandwidth[0].Answers[k] = float64(len(answers[k]))
+		}
+
+		var prev []byte
+		if r.replaying() {
+			prev = previous.swap(append([]byte(nil), answers[1]...))
+		}
+		switch {
+		case !r.corruption.corrupt():
+		case r.corruption.Mode == corruptBitFlip:
+			flipBit(answers[1])
+			res.Corrupted = true
+		case prev != nil:
+			answers[1], res.Corrupted = prev, true
+		}
+		mp.skip()
+		if _, err := c.ReconstructBytes(answers); err != nil {
+			res.Detected = checkRejection(res, err)
+		}
+		mp.reconstruct()
 
-	return results
+		return res
+	})
 }
 
-// LWE uses Amplify
-func pirLWE(db *database.LWE, nRepeat, tECC int) []*Chunk {
+// pirPiano runs the client-preprocessing scheme. The db is preprocessed once,
+// the hints kept by the client are reported as the digest and every
+// repetition starts from a copy of them. The answers are not authenticated,
+// so that the corrupted ones are never detected.
+func pirPiano(db *database.Bytes, r *runner, results []*Chunk) {
 	numRetrievedBlocks := 1
-	results := make([]*Chunk, nRepeat)
+	s := server.NewPiano(db)
+	preprocessed := client.NewPiano(utils.RandomPRG(), &db.Info, 0, 0)
+	if err := preprocessed.Preprocess(s.ChunkBytes); err != nil {
+		log.Fatal(err)
+	}
+	previous := new(replayer)
+
+	r.run(results, func(j int) *Chunk {
+		// every repetition has its own copy of the hints
+		c := preprocessed.Clone()
+		res := initChunk(numRetrievedBlocks)
+
+		// store hints size
+		res.Digest = float64(c.StorageBytes())
+
+		// pick a random block index to retrieve
+		index := rand.Intn(db.NumRows * db.NumColumns)
+		res.Bandwidth[0] = initBlock(1)
+
+		mp := r.newPhases(res, 0, 1)
+		query, err := c.QueryBytes(index)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.query()
+		answer, err := s.AnswerBytes(query)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.answer(0)
+		res.Bandwidth[0].Query = float64(len(query))
+		res.Bandwidth[0].Answers[0] = float64(len(answer))
+
+		var prev []byte
+		if r.replaying() {
+			prev = previous.swap(append([]byte(nil), answer...))
+		}
+		switch {
+		case !r.corruption.corrupt():
+		case r.corruption.Mode == corruptBitFlip:
+			// the first bytes are the digest of the db
+			flipBit(answer[sha256.Size:])
+			res.Corrupted = true
+		case prev != nil:
+			answer, res.Corrupted = prev, true
+		}
+		mp.skip()
+		if _, err := c.ReconstructBytes(answer); err != nil {
+			res.Detected = checkRejection(res, err)
+		}
+		mp.reconstruct()
+
+		return res
+	})
+}
 
+// LWE uses Amplify
+func pirLWE(db *database.LWE, tECC int, r *runner, results []*Chunk) {
+	numRetrievedBlocks := 1
 	p := utils.ParamsWithDatabaseSize(db.Info.NumRows, db.Info.NumColumns)
-	c := client.NewAmplify(utils.RandomPRG(), &db.Info, p, tECC)
-	s := server.NewAmplify(db)
+	previous := new(replayer)
 
-	for j := 0; j < nRepeat; j++ {
-		log.Printf("start repetition %d out of %d", j+1, nRepeat)
-		results[j] = initChunk(numRetrievedBlocks)
+	r.run(results, func(j int) *Chunk {
+		// every repetition has its own client and server
+		c := client.NewAmplify(utils.RandomPRG(), &db.Info, p, tECC)
+		s := server.NewAmplify(db)
+		res := initChunk(numRetrievedBlocks)
 
 		// store digest size
-		results[j].Digest = db.Auth.DigestLWE.BytesSize()
+		res.Digest = db.Auth.DigestLWE.BytesSize()
 		// pick a random block index to start the retrieval
 		ii := rand.Intn(db.NumRows)
 		jj := rand.Intn(db.NumColumns)
-		results[j].CPU[0] = initBlock(1)
-		results[j].Bandwidth[0] = initBlock(1)
+		res.Bandwidth[0] = initBlock(1)
 
-		t := time.Now()
+		mp := r.newPhases(res, 0, 1)
 
-		query := c.Query(ii, jj)
-		answer := s.Answer(query)
-		if _, err := c.Reconstruct(answer); err != nil {
+		query, err := c.QueryBytes(ii*db.NumColumns + jj)
+		if err != nil {
 			log.Fatal(err)
 		}
+		mp.query()
+		answer, err := s.AnswerBytes(query)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mp.answer(0)
+		res.Bandwidth[0].Query = float64(len(query))
+		res.Bandwidth[0].Answers[0] = float64(len(answer))
 
-		results[j].CPU[0].Reconstruct = time.Since(t).Seconds()
-		results[j].Bandwidth[0].Query = query[0].BytesSize() * float64(len(query))        // all matrices equal
-		results[j].Bandwidth[0].Answers[0] = float64(len(answer)) * answer[0].BytesSize() // all matrices equal
-
-		// GC after each repetition
-		runtime.GC()
-		time.Sleep(2)
-	}
+		var prev []byte
+		if r.replaying() {
+			prev = previous.swap(append([]byte(nil), answer...))
+		}
+		switch {
+		case !r.corruption.corrupt():
+		case r.corruption.Mode == corruptBitFlip:
+			flipMatricesBit(answer)
+			res.Corrupted = true
+		case prev != nil:
+			answer, res.Corrupted = prev, true
+		}
+		mp.skip()
+		if _, err := c.ReconstructBytes(answer); err != nil {
+			res.Detected = checkRejection(res, err)
+		}
+		mp.reconstruct()
 
-	return results
+		return res
+	})
 }
 
-func pirElliptic(db *database.Elliptic, nRepeat int) []*Chunk {
+func pirElliptic(db *database.Elliptic, r *runner, results []*Chunk) {
 	numRetrievedBlocks := 1
-	results := make([]*Chunk, nRepeat)
-
-	prg := utils.RandomPRG()
-	c := client.NewDH(prg, &db.Info)
-	s := server.NewDH(db)
+	previous := new(replayer)
 
-	for j := 0; j < nRepeat; j++ {
-		log.Printf("start repetition %d out of %d", j+1, nRepeat)
-		results[j] = initChunk(numRetrievedBlocks)
+	r.run(results, func(j int) *Chunk {
+		// every repetition has its own client and server
+		c := client.NewDH(utils.RandomPRG(), &db.Info)
+		s := server.NewDH(db)
+		res := initChunk(numRetrievedBlocks)
 
 		// store digest size
-		results[j].Digest = float64(len(db.SubDigests)) + float64(len(db.Digest))
+		res.Digest = float64(len(db.SubDigests)) + float64(len(db.Digest))
 
 		// pick a random block index to start the retrieval
 		index := rand.Intn(db.NumRows * db.NumColumns)
-		results[j].CPU[0] = initBlock(1)
-		results[j].Bandwidth[0] = initBlock(1)
+		res.Bandwidth[0] = initBlock(1)
 
-		//m.Reset()
-		t := time.Now()
+		mp := r.newPhases(res, 0, 1)
 		query, err := c.QueryBytes(index)
 		if err != nil {
 			log.Fatal(err)
 		}
-		//results[j].CPU[0].Query = m.RecordAndReset()
-		results[j].CPU[0].Query = 0
-		results[j].Bandwidth[0].Query += float64(len(query))
+		mp.query()
+		res.Bandwidth[0].Query += float64(len(query))
 
 		// get server's answer
 		answer, err := s.AnswerBytes(query)
 		if err != nil {
 			log.Fatal(err)
 		}
-		//results[j].CPU[0].Answers[0] = m.RecordAndReset()
-		results[j].CPU[0].Answers[0] = 0
-		results[j].Bandwidth[0].Answers[0] = float64(len(answer))
+		mp.answer(0)
+		res.Bandwidth[0].Answers[0] = float64(len(answer))
 
+		var prev []byte
+		if r.replaying() {
+			prev = previous.swap(append([]byte(nil), answer...))
+		}
+		switch {
+		case !r.corruption.corrupt():
+		case r.corruption.Mode == corruptBitFlip:
+			flipBit(answer)
+			res.Corrupted = true
+		case prev != nil:
+			answer, res.Corrupted = prev, true
+		}
+		mp.skip()
 		_, err = c.ReconstructBytes(answer)
 		if err != nil {
-			log.Fatal(err)
+			res.Detected = checkRejection(res, err)
 		}
-		results[j].CPU[0].Reconstruct = time.Since(t).Seconds()
-		results[j].Bandwidth[0].Reconstruct = 0
+		mp.reconstruct()
+		res.Bandwidth[0].Reconstruct = 0
 
-		// GC after each repetition
-		runtime.GC()
-		time.Sleep(2)
-	}
-
-	return results
+		return res
+	})
 }
 
 // Converts number of bits to retrieve into the number of db blocks
 func bitsToBlocks(blockSize, elemSize, numBits int) int {
-	return int(math.Ceil(float64(numBits) / float64(blockSize*elemSize)))
-}
-
-func fieldVectorByteLength(vec []uint32) float64 {
-	return float64(len(vec) * field.Bytes)
+	blockBits, err := utils.CheckedMul(blockSize, elemSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n, err := utils.CeilDiv(numBits, blockBits)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return n
 }
 
 func initChunk(numRetrieveBlocks int) *Chunk {
 	return &Chunk{
 		CPU:       make([]*Block, numRetrieveBlocks),
+		Wall:      make([]*Block, numRetrieveBlocks),
 		Bandwidth: make([]*Block, numRetrieveBlocks),
 		Digest:    0,
 	}
@@ -379,9 +907,53 @@ func loadSimulationConfigs(genFile, indFile string) (*Simulation, error) {
 	return &Simulation{generalParam: *genConfig, individualParam: *indConfig}, nil
 }
 
+// amplificationRepetitions returns the number of repetitions of the
+// integrity amplification: TECC if set, the tuned value for dbLen otherwise
+func (s *Simulation) amplificationRepetitions(dbLen int, tuned map[int]int) int {
+	if s.TECC > 0 {
+		return s.TECC
+	}
+	rep, ok := tuned[dbLen]
+	if !ok {
+		log.Fatalf("tECC not defined for db length %d, set TECC in the config", dbLen)
+	}
+	return rep
+}
+
 func (s *Simulation) validSimulation() bool {
+	if s.WarmUp < 0 || s.OutlierThreshold < 0 {
+		return false
+	}
+	if s.Corruption != nil && !s.Corruption.valid() {
+		return false
+	}
+	if s.Corruption != nil && s.Corruption.Mode == corruptForge && (s.Primitive != "pir-merkle" || s.Dataset != nil) {
+		return false
+	}
+	if s.Dataset != nil && (!s.multiServer() && s.Primitive != "baseline" || !s.Dataset.valid()) {
+		return false
+	}
+	if s.Primitive == "grpc" {
+		return s.GRPC != nil && s.GRPC.valid()
+	}
+	if s.multiServer() {
+		if len(s.NumServers) == 0 {
+			return false
+		}
+		for _, n := range s.NumServers {
+			if n < 2 {
+				return false
+			}
+		}
+		return true
+	}
 	return s.Primitive == "cmp-vpir-dh" ||
 		s.Primitive == "cmp-vpir-lwe" ||
 		s.Primitive == "cmp-vpir-lwe-128" ||
+		s.Primitive == "cmp-pir-simple" ||
+		s.Primitive == "pir-offline-online" && s.NumRows != 1 ||
+		s.Primitive == "pir-piano" && s.NumRows != 1 ||
+		s.Primitive == "amplify" ||
+		s.Primitive == "baseline" ||
 		s.Primitive == "preprocessing"
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/si-co/vpir-code/lib/bench"
+)
+
+// phases of a retrieval, in the order of the CSV output
+var phaseNames = []string{"query", "answer", "reconstruct"}
+
+// phaseValue returns the value of a phase of a chunk, summed over all the
+// retrieved blocks. Answers are computed in parallel by the servers, so the
+// time of the slowest server is used, while the bandwidth of all the answers
+// is summed.
+func phaseValue(blocks []*Block, phase string, maxAnswers bool) float64 {
+	var v float64
+	for _, b := range blocks {
+		switch phase {
+		case "query":
+			v += b.Query
+		case "answer":
+			if maxAnswers {
+				v += maxFloat(b.Answers)
+			} else {
+				for _, a := range b.Answers {
+					v += a
+				}
+			}
+		case "reconstruct":
+			v += b.Reconstruct
+		}
+	}
+	return v
+}
+
+// writeCSV writes the raw per-repetition measurements to fileName and their
+// summary statistics per scheme, number of servers, threshold, db length,
+// metric and phase to summaryFileName. The single-server results and the
+// baseline have one server and threshold zero. The outliers are excluded from
+// the summary statistics only, see bench.FilterOutliers.
+func (e *Experiment) writeCSV(fileName, summaryFileName string, outlierThreshold float64) error {
+	raw := [][]string{{"scheme", "servers", "threshold", "dbLen", "repetition", "metric", "phase", "value"}}
+	summary := [][]string{{"scheme", "servers", "threshold", "dbLen", "metric", "phase", "mean", "stddev", "median", "p95", "outliers"}}
+	scheme := ""
+	if e.Config != nil {
+		scheme = e.Config.Primitive
+	}
+	schemes := []string{scheme, "baseline"}
+	points := []*SweepPoint{{NumServers: 1, Results: e.Results}, {NumServers: 1, Results: e.Baseline}}
+	for _, p := range e.Sweep {
+		schemes = append(schemes, scheme)
+		points = append(points, p)
+	}
+	for k, p := range points {
+		dbLens := make([]int, 0, len(p.Results))
+		for dbLen := range p.Results {
+			dbLens = append(dbLens, dbLen)
+		}
+		sort.Ints(dbLens)
+
+		for _, dbLen := range dbLens {
+			key := []string{schemes[k], strconv.Itoa(p.NumServers), strconv.Itoa(p.Threshold), strconv.Itoa(dbLen)}
+			for _, metric := range []string{"cpu", "wall", "bandwidth"} {
+				for _, phase := range phaseNames {
+					values := make([]float64, 0, len(p.Results[dbLen]))
+					for j, c := range p.Results[dbLen] {
+						if c == nil {
+							continue
+						}
+						blocks := c.CPU
+						switch metric {
+						case "wall":
+							blocks = c.Wall
+						case "bandwidth":
+							blocks = c.Bandwidth
+						}
+						v := phaseValue(blocks, phase, metric != "bandwidth")
+						values = append(values, v)
+						raw = append(raw, append(key[:4:4], strconv.Itoa(j), metric, phase, formatFloat(v)))
+					}
+					kept, outliers := bench.FilterOutliers(values, outlierThreshold)
+					s := bench.ComputeStats(kept)
+					summary = append(summary, append(key[:4:4], metric, phase,
+						formatFloat(s.Mean), formatFloat(s.StdDev), formatFloat(s.Median), formatFloat(s.P95),
+						strconv.Itoa(outliers)))
+				}
+			}
+		}
+	}
+
+	if err := writeRecords(fileName, raw); err != nil {
+		return err
+	}
+	return writeRecords(summaryFileName, summary)
+}
+
+func writeRecords(fileName string, records [][]string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(records); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %v", fileName, err)
+	}
+	return f.Close()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/si-co/vpir-code/lib/bench"
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/monitor"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// Throughput is the result of the multi-client mode, where concurrent clients
+// query the same server instance
+type Throughput struct {
+	Clients       int
+	Queries       int     // total number of queries
+	Seconds       float64 // wall-clock time of the whole run
+	QueriesPerSec float64
+	Latency       bench.Stats // per-query latency in seconds
+
+	// per-query latency in seconds at the percentiles of latencyPercentiles,
+	// with three significant figures
+	Percentiles map[string]float64
+}
+
+// percentiles of the latency reported by the multi-client mode
+var latencyPercentiles = []float64{50, 90, 95, 99, 99.9, 100}
+
+// newClientFunc returns a function executing a full retrieval for a new
+// client. All the clients share the server instance.
+type newClientFunc func() func() error
+
+func lwe128Clients(db *database.LWE128) newClientFunc {
+	p := utils.ParamsWithDatabaseSize128(db.Info.NumRows, db.Info.NumColumns)
+	s := server.NewLWE128(db)
+	return func() func() error {
+		c := client.NewLWE128(utils.RandomPRG(), &db.Info, p)
+		return func() error {
+			query := c.Query(rand.Intn(db.NumRows), rand.Intn(db.NumColumns))
+			_, err := c.Reconstruct(s.Answer(query))
+			return err
+		}
+	}
+}
+
+func amplifyClients(db *database.LWE, tECC int) newClientFunc {
+	p := utils.ParamsWithDatabaseSize(db.Info.NumRows, db.Info.NumColumns)
+	s := server.NewAmplify(db)
+	return func() func() error {
+		c := client.NewAmplify(utils.RandomPRG(), &db.Info, p, tECC)
+		return func() error {
+			query := c.Query(rand.Intn(db.NumRows), rand.Intn(db.NumColumns))
+			_, err := c.Reconstruct(s.Answer(query))
+			return err
+		}
+	}
+}
+
+func ellipticClients(db *database.Elliptic) newClientFunc {
+	s := server.NewDH(db)
+	return func() func() error {
+		c := client.NewDH(utils.RandomPRG(), &db.Info)
+		return func() error {
+			query, err := c.QueryBytes(rand.Intn(db.NumRows * db.NumColumns))
+			if err != nil {
+				return err
+			}
+			answer, err := s.AnswerBytes(query)
+			if err != nil {
+				return err
+			}
+			_, err = c.ReconstructBytes(answer)
+			return err
+		}
+	}
+}
+
+// runThroughput runs numClients concurrent clients, each executing
+// queriesPerClient retrievals back to back, and returns the aggregate
+// throughput and the latency distribution, recorded in a histogram so that
+// long runs use a fixed amount of memory
+func runThroughput(newClient newClientFunc, numClients, queriesPerClient int) *Throughput {
+	latencies := monitor.NewDefaultLatencyHistogram()
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < numClients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			retrieve := newClient()
+			for q := 0; q < queriesPerClient; q++ {
+				t := time.Now()
+				if err := retrieve(); err != nil {
+					log.Fatal(err)
+				}
+				latencies.Record(time.Since(t))
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	queries := int(latencies.Count())
+	ps := latencies.Percentiles(latencyPercentiles...)
+	percentiles := make(map[string]float64, len(ps))
+	for i, p := range latencyPercentiles {
+		percentiles[strconv.FormatFloat(p, 'f', -1, 64)] = ps[i].Seconds()
+	}
+
+	return &Throughput{
+		Clients:       numClients,
+		Queries:       queries,
+		Seconds:       elapsed,
+		QueriesPerSec: float64(queries) / elapsed,
+		Latency: bench.Stats{
+			Mean:   latencies.Mean().Seconds(),
+			StdDev: latencies.StdDev().Seconds(),
+			Median: percentiles["50"],
+			P95:    percentiles["95"],
+		},
+		Percentiles: percentiles,
+	}
+}
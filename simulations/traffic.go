@@ -0,0 +1,22 @@
+package main
+
+import (
+	"log"
+
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
+// recordTraffic stores the bytes counted by the clients and servers since the
+// last reset under the db length and resets the counters. The counters
+// include the exact marshaled messages of all the repetitions run by this
+// process, i.e., not the ones loaded from a previous run when resuming.
+func recordTraffic(traffic map[int]*monitor.Bandwidth, dbLen int) map[int]*monitor.Bandwidth {
+	b := monitor.ResetBandwidth()
+	log.Printf("traffic: %d query bytes, %d answer bytes, %d bytes reconstructed",
+		b.Query, b.Answer, b.Reconstruct)
+	if traffic == nil {
+		traffic = make(map[int]*monitor.Bandwidth)
+	}
+	traffic[dbLen] = &b
+	return traffic
+}
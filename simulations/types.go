@@ -1,5 +1,10 @@
 package main
 
+import (
+	"github.com/si-co/vpir-code/lib/bench"
+	"github.com/si-co/vpir-code/lib/monitor"
+)
+
 type Block struct {
 	Query       float64
 	Answers     []float64
@@ -7,13 +12,60 @@ type Block struct {
 }
 
 type Chunk struct {
-	CPU       []*Block
-	Bandwidth []*Block
+	CPU       []*Block // CPU time in seconds
+	Wall      []*Block // wall-clock time in seconds
+	Bandwidth []*Block // in bytes
 	Digest    float64
+
+	// emulated transfer times in seconds and total time including them, only
+	// set when the simulation defines a network
+	Network  []*Block `json:",omitempty"`
+	EndToEnd float64  `json:",omitempty"`
+
+	// whether the answer was corrupted by the server and the client rejected
+	// it, only set when the simulation defines a corruption
+	Corrupted bool `json:",omitempty"`
+	Detected  bool `json:",omitempty"`
+
+	// memory usage of the first retrieved block, only set when measured
+	Memory *Memory `json:",omitempty"`
+
+	// energy in joules consumed by each phase of the first retrieved block,
+	// only set when measured
+	Energy *Block `json:",omitempty"`
+
+	// time of the phases of each answer of the first retrieved block, only
+	// set when the repetitions run one after the other
+	AnswerPhases []*monitor.AnswerBreakdown `json:",omitempty"`
 }
 
 type Experiment struct {
+	// effective config of the simulation that produced the results
+	Config *Simulation `json:",omitempty"`
+	// code and machine that produced the results
+	Metadata *bench.Metadata `json:",omitempty"`
+
 	Results map[int][]*Chunk
+
+	// plain download of the same db lengths without privacy, by db length
+	Baseline map[int][]*Chunk `json:",omitempty"`
+
+	// results of the multi-server schemes, by number of servers and
+	// collusion threshold
+	Sweep []*SweepPoint `json:",omitempty"`
+
+	// results of the multi-client mode, by db length
+	Throughput map[int]*Throughput `json:",omitempty"`
+
+	// detection of the corrupted answers, by db length
+	Detection map[int]*Detection `json:",omitempty"`
+
+	// one-time costs of each db length, e.g., the database creation
+	Setup map[int]*Setup `json:",omitempty"`
+
+	// bytes counted by the clients and servers during the measured
+	// repetitions, by db length
+	Traffic map[int]*monitor.Bandwidth `json:",omitempty"`
 }
 
 const (
@@ -74,6 +74,9 @@ def parseResults(file):
                     if param == "Digest":
                         digest = repetition
                         continue
+                    # only lists of blocks are per-phase measurements
+                    if not isinstance(repetition, list):
+                        continue
                     for block in repetition:
                         client[param].append(block['Query'] + block['Reconstruct'])
                         # sum the values for all the servers
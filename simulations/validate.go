@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+)
+
+// default number of known indices retrieved before the measurements
+const defaultValidation = 5
+
+// numValidation returns the number of indices to check before the
+// measurements, zero if the validation is disabled
+func (s *Simulation) numValidation() int {
+	switch {
+	case s.Validation < 0:
+		return 0
+	case s.Validation == 0:
+		return defaultValidation
+	}
+	return s.Validation
+}
+
+// validate retrieves n random entries of the db of the primitive and
+// compares them with the expected values
+func (s *Simulation) validate(n, dbLen int, tuned map[int]int, points []*SweepPoint,
+	dbElliptic *database.Elliptic, dbLWE *database.LWE, dbLWE128 *database.LWE128, dbSimple *database.SimplePIR,
+	dbBytes *database.Bytes) error {
+	switch s.Primitive {
+	case "cmp-vpir-dh":
+		return validateElliptic(dbElliptic, n)
+	case "cmp-vpir-lwe", "amplify":
+		return validateLWE(dbLWE, s.amplificationRepetitions(dbLen, tuned), n)
+	case "cmp-vpir-lwe-128":
+		return validateLWE128(dbLWE128, n)
+	case "cmp-pir-simple":
+		return validateSimplePIR(dbSimple, n)
+	case "pir-offline-online":
+		return validateOfflineOnline(dbBytes, n)
+	case "pir-piano":
+		return validatePiano(dbBytes, n)
+	case "pir-classic", "pir-merkle":
+		for _, p := range points {
+			if err := validateBytes(dbBytes, p.NumServers, n); err != nil {
+				return err
+			}
+		}
+	}
+	// nothing is retrieved privately by the other primitives
+	return nil
+}
+
+// validateLWE128 retrieves n random entries and compares them with the db
+func validateLWE128(db *database.LWE128, n int) error {
+	p := utils.ParamsWithDatabaseSize128(db.Info.NumRows, db.Info.NumColumns)
+	s := server.NewLWE128(db)
+	for k := 0; k < n; k++ {
+		c := client.NewLWE128(utils.RandomPRG(), &db.Info, p)
+		i, j := rand.Intn(db.NumRows), rand.Intn(db.NumColumns)
+		res, err := c.Reconstruct(s.Answer(c.Query(i, j)))
+		if err != nil {
+			return fmt.Errorf("entry (%d, %d): %v", i, j, err)
+		}
+		if expected := uint32(db.Matrix.Get(i, j)); res != expected {
+			return fmt.Errorf("entry (%d, %d): got %d, expected %d", i, j, res, expected)
+		}
+	}
+	return nil
+}
+
+// validateSimplePIR retrieves n random rows and compares them with the db
+func validateSimplePIR(db *database.SimplePIR, n int) error {
+	p := utils.ParamsSimplePIR(db.Info.NumRows, db.Info.NumColumns)
+	s := server.NewSimplePIR(db, p)
+	hint := s.HintBytes()
+	for k := 0; k < n; k++ {
+		c, err := client.NewSimplePIR(utils.RandomPRG(), &db.Info, p, hint)
+		if err != nil {
+			return err
+		}
+		i := rand.Intn(db.NumRows)
+		row, err := c.Reconstruct(s.Answer(c.Query(i, 0)))
+		if err != nil {
+			return fmt.Errorf("row %d: %v", i, err)
+		}
+		for j := range row {
+			if expected := db.Matrix.Get(i, j); row[j] != expected {
+				return fmt.Errorf("entry (%d, %d): got %d, expected %d", i, j, row[j], expected)
+			}
+		}
+	}
+	return nil
+}
+
+// validateOfflineOnline retrieves n random blocks with the same hints and
+// compares them with the db
+func validateOfflineOnline(db *database.Bytes, n int) error {
+	offline, online := server.NewOfflineOnline(db), server.NewOfflineOnline(db)
+	c := client.NewOfflineOnline(utils.RandomPRG(), &db.Info, 0)
+	hints, err := offline.HintsBytes(c.OfflineQuery())
+	if err != nil {
+		return err
+	}
+	if err := c.SetHints(hints); err != nil {
+		return err
+	}
+	for k := 0; k < n; k++ {
+		i := rand.Intn(db.NumRows * db.NumColumns)
+		queries, err := c.Query(i)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", i, err)
+		}
+		answers := make([][]byte, 2)
+		if answers[0], err = offline.AnswerBytes(queries[0]); err != nil {
+			return fmt.Errorf("block %d: %v", i, err)
+		}
+		if answers[1], err = online.AnswerBytes(queries[1]); err != nil {
+			return fmt.Errorf("block %d: %v", i, err)
+		}
+		block, err := c.Reconstruct(answers)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", i, err)
+		}
+		if expected := db.Entries[i*db.BlockSize : (i+1)*db.BlockSize]; !bytes.Equal(block, expected) {
+			return fmt.Errorf("block %d: got %x, expected %x", i, block, expected)
+		}
+	}
+	return nil
+}
+
+// validatePiano retrieves n random blocks with the same preprocessing and
+// compares them with the db
+func validatePiano(db *database.Bytes, n int) error {
+	s := server.NewPiano(db)
+	c := client.NewPiano(utils.RandomPRG(), &db.Info, 0, 0)
+	if err := c.Preprocess(s.ChunkBytes); err != nil {
+		return err
+	}
+	for k := 0; k < n; k++ {
+		i := rand.Intn(db.NumRows * db.NumColumns)
+		q, err := c.Query(i)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", i, err)
+		}
+		a, err := s.AnswerBytes(q)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", i, err)
+		}
+		block, err := c.Reconstruct(a)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", i, err)
+		}
+		if expected := db.Entries[i*db.BlockSize : (i+1)*db.BlockSize]; !bytes.Equal(block, expected) {
+			return fmt.Errorf("block %d: got %x, expected %x", i, block, expected)
+		}
+	}
+	return nil
+}
+
+// validateLWE retrieves n random entries with the integrity amplification
+// and compares them with the db
+func validateLWE(db *database.LWE, tECC, n int) error {
+	p := utils.ParamsWithDatabaseSize(db.Info.NumRows, db.Info.NumColumns)
+	s := server.NewAmplify(db)
+	for k := 0; k < n; k++ {
+		c := client.NewAmplify(utils.RandomPRG(), &db.Info, p, tECC)
+		i, j := rand.Intn(db.NumRows), rand.Intn(db.NumColumns)
+		res, err := c.Reconstruct(s.Answer(c.Query(i, j)))
+		if err != nil {
+			return fmt.Errorf("entry (%d, %d): %v", i, j, err)
+		}
+		if expected := uint32(db.Matrix.Get(i, j)); res != expected {
+			return fmt.Errorf("entry (%d, %d): got %d, expected %d", i, j, res, expected)
+		}
+	}
+	return nil
+}
+
+// validateElliptic retrieves n random entries and compares them with the db
+func validateElliptic(db *database.Elliptic, n int) error {
+	s := server.NewDH(db)
+	for k := 0; k < n; k++ {
+		c := client.NewDH(utils.RandomPRG(), &db.Info)
+		index := rand.Intn(db.NumRows * db.NumColumns)
+		query, err := c.QueryBytes(index)
+		if err != nil {
+			return err
+		}
+		answer, err := s.AnswerBytes(query)
+		if err != nil {
+			return err
+		}
+		res, err := c.ReconstructBytes(answer)
+		if err != nil {
+			return fmt.Errorf("entry %d: %v", index, err)
+		}
+		if res.(byte) != db.Entries[index] {
+			return fmt.Errorf("entry %d: got %d, expected %d", index, res, db.Entries[index])
+		}
+	}
+	return nil
+}
+
+// validateBytes retrieves n random blocks with numServers servers and
+// compares them with the db. The Merkle proofs are verified by the client,
+// which returns the block without them.
+func validateBytes(db *database.Bytes, numServers, n int) error {
+	offsets := make([]int, len(db.BlockLengths)+1)
+	for i, l := range db.BlockLengths {
+		offsets[i+1] = offsets[i] + l
+	}
+	servers := make([]*server.PIR, numServers)
+	for k := range servers {
+		servers[k] = server.NewPIR(db)
+	}
+
+	for k := 0; k < n; k++ {
+		c := client.NewPIR(utils.RandomPRG(), &db.Info)
+		index := rand.Intn(db.NumRows * db.NumColumns)
+		in := make([]byte, 4)
+		binary.BigEndian.PutUint32(in, uint32(index))
+		queries, err := c.QueryBytes(in, numServers)
+		if err != nil {
+			return err
+		}
+		answers := make([][]byte, numServers)
+		for i, s := range servers {
+			if answers[i], err = s.AnswerBytes(queries[i]); err != nil {
+				return err
+			}
+		}
+		res, err := c.Reconstruct(answers)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", index, err)
+		}
+
+		var expected []byte
+		if db.PIRType == "merkle" {
+			expected = db.Entries[offsets[index] : offsets[index+1]-db.ProofLen-1]
+		} else {
+			// the blocks are padded with zeros to the block size
+			expected = make([]byte, db.BlockSize)
+			copy(expected, db.Entries[offsets[index]:offsets[index+1]])
+		}
+		if !bytes.Equal(res, expected) {
+			return fmt.Errorf("block %d: wrong value with %d servers", index, numServers)
+		}
+	}
+	return nil
+}
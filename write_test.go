@@ -0,0 +1,93 @@
+package main
+
+// Test suite for the two-server private write scheme
+
+import (
+	"testing"
+
+	"github.com/si-co/vpir-code/lib/client"
+	"github.com/si-co/vpir-code/lib/database"
+	"github.com/si-co/vpir-code/lib/field"
+	"github.com/si-co/vpir-code/lib/server"
+	"github.com/si-co/vpir-code/lib/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivateWrites(t *testing.T) {
+	info := &database.Info{NumRows: 1, NumColumns: 100, BlockSize: testBlockLength}
+	seed := new(utils.PRGKey)
+	_, err := utils.RandomPRG().Read(seed[:])
+	require.NoError(t, err)
+	servers := make([]*server.Writes, 2)
+	for k := range servers {
+		servers[k], err = server.NewWrites(info, byte(k), seed)
+		require.NoError(t, err)
+	}
+	auditors := []auditor{servers[0], servers[1]}
+	c := client.NewWriter(utils.RandomPRG(), info)
+
+	// every client writes a random message to a distinct slot
+	expected := make([]uint32, info.NumColumns*info.BlockSize)
+	for _, slot := range []int{0, 7, 42, 99} {
+		message := field.RandVectorWithPRG(info.BlockSize, utils.RandomPRG())
+		writes, err := c.WriteBytes(slot, message)
+		require.NoError(t, err)
+		require.NoError(t, audit(auditors, writes))
+		copy(expected[slot*info.BlockSize:], message)
+	}
+
+	// the keys of two writes do not form a point function
+	first, err := c.WriteBytes(3, field.RandVectorWithPRG(info.BlockSize, utils.RandomPRG()))
+	require.NoError(t, err)
+	second, err := c.WriteBytes(5, field.RandVectorWithPRG(info.BlockSize, utils.RandomPRG()))
+	require.NoError(t, err)
+	require.ErrorIs(t, audit(auditors, [][]byte{first[0], second[1]}), server.ErrMalformedWrite)
+
+	// malformed writes are rejected before the audit
+	_, _, err = servers[0].AuditBytes(first[0][1:])
+	require.Error(t, err)
+	_, err = c.WriteBytes(info.NumColumns, expected[:info.BlockSize])
+	require.Error(t, err)
+
+	db, err := c.Reconstruct([][]byte{servers[0].Publish(), servers[1].Publish()})
+	require.NoError(t, err)
+	require.Equal(t, expected, db)
+
+	// the next epoch starts from an empty db
+	db, err = c.Reconstruct([][]byte{servers[0].Publish(), servers[1].Publish()})
+	require.NoError(t, err)
+	require.Equal(t, make([]uint32, len(expected)), db)
+}
+
+// auditor is a server auditing the private writes
+type auditor interface {
+	AuditBytes(w []byte) (*server.WriteAudit, []byte, error)
+}
+
+// audit runs the audit of the writes between the two servers, which apply
+// them if it succeeds
+func audit(servers []auditor, writes [][]byte) error {
+	audits := make([]*server.WriteAudit, 2)
+	first := make([][]byte, 2)
+	for k, s := range servers {
+		var err error
+		audits[k], first[k], err = s.AuditBytes(writes[k])
+		if err != nil {
+			return err
+		}
+	}
+	second := make([][]byte, 2)
+	for k, a := range audits {
+		var err error
+		second[k], err = a.CheckBytes(first[1-k])
+		if err != nil {
+			return err
+		}
+	}
+	for k, a := range audits {
+		if err := a.Commit(second[1-k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}